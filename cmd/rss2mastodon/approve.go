@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/toozej/rss2mastodon/internal/rss2mastodon"
+)
+
+var approveCmd = &cobra.Command{
+	Use:   "approve [id|url]...",
+	Short: "Approves posts awaiting approval",
+	Long:  `Approves posts queued by --require-approval so they are announced on the next cycle`,
+	Args:  cobra.ArbitraryArgs,
+	Run:   rss2mastodon.Approve,
+}
+
+var rejectCmd = &cobra.Command{
+	Use:   "reject id|url...",
+	Short: "Rejects posts awaiting approval",
+	Long:  `Rejects posts queued by --require-approval, adding them to the blocklist so they are never announced`,
+	Args:  cobra.MinimumNArgs(1),
+	Run:   rss2mastodon.Reject,
+}
+
+func init() {
+	approveCmd.Flags().BoolP("list", "l", false, "List posts awaiting approval")
+
+	rootCmd.AddCommand(approveCmd, rejectCmd)
+}
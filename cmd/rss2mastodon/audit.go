@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/toozej/rss2mastodon/internal/rss2mastodon"
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Lists the requests rss2mastodon sent on the account's behalf",
+	Long:  `Lists the outbound requests acting on other servers, e.g. posted statuses, media uploads, boosts, deletions and notifications, with their target, endpoint, feed item and outcome, as recorded in the audit log of the database`,
+	Args:  cobra.NoArgs,
+	Run:   rss2mastodon.ShowAuditLog,
+}
+
+func init() {
+	auditCmd.Flags().String("since", "", "Only list requests from this time on, e.g. 2024-03-05 or \"2024-03-05 14:00\" in local time, or an RFC 3339 timestamp")
+	auditCmd.Flags().String("until", "", "Only list requests before this time, in the format of --since")
+	auditCmd.Flags().String("item", "", "Only list requests sent for the feed item with this link")
+	auditCmd.Flags().IntP("limit", "n", 0, "List at most this many of the most recent requests, all if 0")
+
+	rootCmd.AddCommand(auditCmd)
+}
@@ -0,0 +1,22 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/toozej/rss2mastodon/internal/rss2mastodon"
+)
+
+var blockCmd = &cobra.Command{
+	Use:   "block [url|guid]...",
+	Short: "Blocks feed items from ever being announced",
+	Long:  `Adds item URLs or GUIDs to the blocklist so they are never announced regardless of feed contents, e.g. for retracted or embargoed posts`,
+	Args:  cobra.ArbitraryArgs,
+	Run:   rss2mastodon.Block,
+}
+
+func init() {
+	blockCmd.Flags().BoolP("list", "l", false, "List blocked item URLs and GUIDs")
+	blockCmd.Flags().BoolP("remove", "r", false, "Remove the given item URLs or GUIDs from the blocklist")
+
+	rootCmd.AddCommand(blockCmd)
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/toozej/rss2mastodon/internal/rss2mastodon"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspects the configuration",
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Shows the effective configuration",
+	Long:  `Shows the effective configuration, with secrets masked, and whether each value came from a flag, the environment, the config directory, the .env file or a default`,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		// the daemon's flags were added to the command, and bound along with its own
+		if err := rss2mastodon.ShowConfig(cmd.OutOrStdout(), cmd.Flags()); err != nil {
+			log.Fatal("Error loading configuration: ", err)
+		}
+	},
+}
+
+var configMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Renames deprecated settings in the .env file",
+	Long:  `Rewrites the .env file (or the profile's .env.NAME file) to the current setting names, e.g. MASTODON_TOKEN to MASTODON_ACCESS_TOKEN and feed-url to FEED_URL, keeping the original as a .bak file`,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		if err := rss2mastodon.MigrateConfig(dryRun); err != nil {
+			log.Fatal("Error migrating configuration: ", err)
+		}
+	},
+}
+
+func init() {
+	configMigrateCmd.Flags().Bool("dry-run", false, "Only print what would be changed")
+	configCmd.AddCommand(configShowCmd)
+	configCmd.AddCommand(configMigrateCmd)
+
+	rootCmd.AddCommand(configCmd)
+}
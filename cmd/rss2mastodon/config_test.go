@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// Test config show accepts the daemon's flags and reports the values given with them
+func TestConfigShow_Flags(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	wd, _ := os.Getwd()
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+	defer func() { _ = os.Chdir(wd) }()
+	if err := os.WriteFile(".env", nil, 0600); err != nil {
+		t.Fatalf("Failed to write .env: %v", err)
+	}
+
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	rootCmd.SetArgs([]string{"config", "show", "--interval", "5"})
+	defer rootCmd.SetArgs(nil)
+	defer rootCmd.SetOut(nil)
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := map[string][]string{
+		"INTERVAL":               {"5", "flag"},
+		"HIGH_PRIORITY_INTERVAL": {"5", "default"},
+	}
+	for _, line := range strings.Split(out.String(), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 3 && expected[fields[0]] != nil {
+			if want := expected[fields[0]]; fields[1] != want[0] || fields[2] != want[1] {
+				t.Errorf("Expected %s=%s from %s, got %s from %s", fields[0], want[0], want[1], fields[1], fields[2])
+			}
+			delete(expected, fields[0])
+		}
+	}
+	if len(expected) != 0 {
+		t.Errorf("Expected %v to be shown, got %s", expected, out.String())
+	}
+}
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/toozej/rss2mastodon/internal/rss2mastodon"
+)
+
+var deadletterCmd = &cobra.Command{
+	Use:   "deadletter",
+	Short: "Manages posts which exhausted their retry budget",
+	Long:  `Lists and requeues posts which failed to toot more often, or for longer, than the retry budget allows`,
+}
+
+var deadletterListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists dead-lettered posts",
+	Args:  cobra.NoArgs,
+	Run:   rss2mastodon.ListDeadLetters,
+}
+
+var deadletterRetryCmd = &cobra.Command{
+	Use:   "retry id|url...",
+	Short: "Requeues dead-lettered posts",
+	Long:  `Moves dead-lettered posts back into the pending queue so they are announced on the next cycle`,
+	Args:  cobra.MinimumNArgs(1),
+	Run:   rss2mastodon.RetryDeadLetters,
+}
+
+func init() {
+	deadletterCmd.AddCommand(deadletterListCmd, deadletterRetryCmd)
+
+	rootCmd.AddCommand(deadletterCmd)
+}
@@ -0,0 +1,22 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/toozej/rss2mastodon/internal/rss2mastodon"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Exports the announcement history as an ActivityPub outbox",
+	Long:  `Writes every tooted post as a Create activity to an ActivityPub outbox.json, in the format of a Mastodon account archive, e.g. to migrate the announcement history to another system`,
+	Args:  cobra.NoArgs,
+	Run:   rss2mastodon.Export,
+}
+
+func init() {
+	exportCmd.Flags().StringP("output", "o", "outbox.json", "File to write the outbox to, - for stdout")
+	exportCmd.Flags().String("actor", "", "ActivityPub actor ID of the bot account, e.g. https://example.social/users/bot")
+
+	rootCmd.AddCommand(exportCmd)
+}
@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/toozej/rss2mastodon/internal/rss2mastodon"
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import outbox.json",
+	Short: "Marks posts found in a Mastodon account archive as tooted",
+	Long:  `Reads the outbox.json of a Mastodon account archive and marks the current feed items linked from its statuses as tooted, so switching an established account to rss2mastodon doesn't announce them again`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		// the feeds are configured with the root command's flags
+		if err := bindFlags(rootCmd.Flags()); err != nil {
+			log.Fatal("Error binding flags: ", err)
+		}
+		rss2mastodon.ImportArchive(cmd, args)
+	},
+}
+
+func init() {
+	importCmd.Flags().BoolP("dry-run", "n", false, "List the posts which would be marked as tooted without changing the database")
+
+	rootCmd.AddCommand(importCmd)
+}
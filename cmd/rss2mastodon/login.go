@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/toozej/rss2mastodon/internal/rss2mastodon"
+)
+
+var loginCmd = &cobra.Command{
+	Use:   "login [instance]",
+	Short: "Authorizes rss2mastodon to post as the bot account",
+	Long:  `Registers rss2mastodon as an app with the Mastodon instance (MASTODON_URL if not given), shows the page to authorize it as the bot account and writes the instance URL and the resulting access token to the .env file (or the profile's .env.NAME file), or to a credentials directory read with --config-dir`,
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var instance string
+		if len(args) > 0 {
+			instance = args[0]
+		}
+		credentialsDir, _ := cmd.Flags().GetString("credentials-dir")
+		if err := rss2mastodon.Login(instance, credentialsDir, os.Stdin, os.Stdout); err != nil {
+			log.Fatal("Error logging in: ", err)
+		}
+	},
+}
+
+func init() {
+	loginCmd.Flags().String("credentials-dir", "", "Write MASTODON_URL and MASTODON_ACCESS_TOKEN as files to this directory, e.g. the --config-dir, instead of the .env file")
+
+	rootCmd.AddCommand(loginCmd)
+}
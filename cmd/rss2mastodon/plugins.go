@@ -0,0 +1,19 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/toozej/rss2mastodon/internal/rss2mastodon"
+)
+
+var pluginsCmd = &cobra.Command{
+	Use:   "plugins",
+	Short: "Lists the sources, publishers and notifiers compiled into this build",
+	Long:  `Lists the sources, publishers and notifiers compiled into this build, along with their capabilities, feed options and the configuration keys they read`,
+	Args:  cobra.NoArgs,
+	Run:   rss2mastodon.ListPlugins,
+}
+
+func init() {
+	rootCmd.AddCommand(pluginsCmd)
+}
@@ -3,9 +3,12 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 	"go.uber.org/automaxprocs/maxprocs"
 
@@ -24,12 +27,27 @@ var rootCmd = &cobra.Command{
 }
 
 func rootCmdPreRun(cmd *cobra.Command, args []string) {
-	if err := viper.BindPFlags(cmd.Flags()); err != nil {
+	if err := bindFlags(cmd.Flags()); err != nil {
 		return
 	}
 	if viper.GetBool("debug") {
 		log.SetLevel(log.DebugLevel)
 	}
+	if err := rss2mastodon.UseProfile(viper.GetString("profile")); err != nil {
+		log.Fatal("Error selecting profile: ", err)
+	}
+}
+
+// bindFlags binds flags using underscored keys (feed-url -> feed_url) so they share
+// the same configuration keys as environment variables and the .env file
+func bindFlags(flags *pflag.FlagSet) error {
+	var bindErr error
+	flags.VisitAll(func(f *pflag.Flag) {
+		if err := viper.BindPFlag(strings.ReplaceAll(f.Name, "-", "_"), f); err != nil {
+			bindErr = err
+		}
+	})
+	return bindErr
 }
 
 func Execute() {
@@ -47,8 +65,99 @@ func init() {
 
 	// create rootCmd-level flags
 	rootCmd.PersistentFlags().BoolP("debug", "d", false, "Enable debug-level logging")
-	rootCmd.Flags().StringP("feed-url", "f", "", "RSS feed URL to watch")
+	rootCmd.PersistentFlags().StringP("profile", "p", "", "Named profile reading .env.NAME and keeping its own database, for running several bots from one directory")
+	rootCmd.Flags().Bool("strict-config", false, "Refuse to start if the .env file, config directory or environment contain settings which aren't recognized, e.g. typos like MASTADON_URL")
+	rootCmd.Flags().Bool("log-changes-only", false, "Only log cycle summaries when something changed or failed")
+	rootCmd.Flags().StringArrayP("feed-url", "f", nil, "RSS feed URL to watch, repeatable to watch several feeds")
 	rootCmd.Flags().IntP("interval", "i", 60, "Interval in minutes to check the RSS feed")
+	rootCmd.Flags().Int("high-priority-interval", 5, "Interval in minutes for checking feeds with priority=high")
+	rootCmd.Flags().Int("low-priority-interval", 240, "Interval in minutes for checking feeds with priority=low")
+	rootCmd.Flags().Duration("mastodon-timeout", 10*time.Second, "Timeout of Mastodon API requests")
+	rootCmd.Flags().Duration("mastodon-upload-timeout", 60*time.Second, "Timeout of media and avatar uploads to Mastodon")
+	rootCmd.Flags().StringArray("mastodon-account", nil, "Further Mastodon account to cross-post new posts to, e.g. \"url=https://fosstodon.org,token=TOKEN,name=project\" (repeatable)")
+	rootCmd.Flags().Int("mastodon-rate-limit-reserve", 0, "How many requests of the Mastodon instance's rate limits to leave unused; new posts are held until the limit resets instead")
+	rootCmd.Flags().Duration("host-request-interval", 0, "Space feed requests to the same host at least this far apart (e.g. 2s), 0 for no limit")
+	rootCmd.Flags().String("fetch-retry", "", "Retry policy of feed fetches, e.g. attempts=3,base_delay=1s,max_delay=30s,jitter=0.2 (default a single attempt)")
+	rootCmd.Flags().String("toot-retry", "", "Retry policy of Mastodon API requests, like --fetch-retry")
+	rootCmd.Flags().String("upload-retry", "", "Retry policy of media uploads to Mastodon, like --fetch-retry")
+	rootCmd.Flags().String("notify-retry", "", "Retry policy of Gotify and ntfy notifications, like --fetch-retry")
+	rootCmd.Flags().Bool("conditional-get", true, "Send the ETag and Last-Modified of a feed's last response, skipping feeds which haven't changed")
+	rootCmd.Flags().String("dedup", "link", "What tells feed items apart: link, or title_date for feeds changing their links on every build (overridable per --feed)")
+	rootCmd.Flags().StringArray("feed", nil, "Feed definition with per-feed options, e.g. \"url=https://example.com/rss,category=go,visibility=unlisted\" (repeatable)")
+	rootCmd.Flags().String("planet-feeds", "", "Comma-separated member feed URLs whose posts are tooted prefixed with their blog's name")
+	rootCmd.Flags().Duration("resurfaced-after", 0, "Treat new items first seen this long after their pubDate (e.g. 720h) as resurfaced old posts, 0 disables")
+	rootCmd.Flags().String("resurfaced-action", "label", "What to do with resurfaced posts: skip them or label them \"From the archive:\"")
+	rootCmd.Flags().Bool("thread-thoughts", true, "Toot \"Thoughts\" posts exceeding the character limit as a numbered thread of replies instead of truncating them")
+	rootCmd.Flags().Int("daily-toot-budget", 0, "Toot at most this many statuses a day across all feeds, holding further statuses until the next day, new posts in order; 0 for no limit")
+	rootCmd.Flags().Int("burst-threshold", 0, "Toot a single digest instead of announcing each new item when a feed has more than this many new items at once, e.g. after it was rebuilt; 0 disables")
+	rootCmd.Flags().Bool("delete-removed", false, "Delete the toots of items removed from their feed, e.g. retracted posts")
+	rootCmd.Flags().Int("post-queue-size", 100, "How many fetched items can wait to be posted before fetching further feeds blocks")
+	rootCmd.Flags().Int("feed-concurrency", 8, "How many feeds are fetched at the same time")
+	rootCmd.Flags().Bool("low-memory", false, "Use less memory, for routers and Raspberry Pi Zero class devices, at the cost of speed and bandwidth")
+	rootCmd.Flags().String("link-params", "", "Query parameters appended to the links in toots, e.g. \"utm_source=mastodon&utm_medium=social\" (overridable per --feed)")
+	rootCmd.Flags().Int("category-hashtags", 0, "Append up to this many of a new post's categories to its toot as CamelCased hashtags, e.g. #GoGenerics, none if 0")
+	rootCmd.Flags().String("footer", "", "Template of a line appended to every toot (e.g. \"🤖 via rss2mastodon\"), with the post's {{.Link}}, {{.Title}} and {{.Source}}; dropped from toots it would make too long")
+	rootCmd.Flags().String("server-flavor", "", "Server software of the instance: mastodon, gotosocial, pleroma or akkoma, detected from the instance at startup if empty")
+	rootCmd.Flags().Int("max-characters", 0, "How many characters toots may have, asked from the Mastodon instance at startup if 0 (500 if it doesn't say)")
+	rootCmd.Flags().String("language", "", "ISO 639 code of the language toots are written in, e.g. de, the account's default posting language if empty (overridable per --feed)")
+	rootCmd.Flags().Bool("local-only", false, "Keep toots on the instance instead of federating them, on glitch-soc, Hometown, GoToSocial, Pleroma and Akkoma (overridable per --feed)")
+	rootCmd.Flags().String("content-warning", "", "Content warning (spoiler text) to post every toot behind, overrides CW_TEXT")
+	rootCmd.Flags().StringArray("content-warning-rule", nil, "Content warning for posts whose link contains a keyword or which have it as category, e.g. \"politics=US politics\" (repeatable)")
+	rootCmd.Flags().StringArray("poll-rule", nil, "Announce posts whose link contains a keyword or which have it as category as a poll with the given options, e.g. \"ideas=Go|Rust|Zig\" (repeatable)")
+	rootCmd.Flags().String("poll-template", "{{.Title}}\n{{.Link}}\n\nWhich topic should I write about next?", "Template of poll announcements, with the post's {{.Title}}, {{.Link}} and {{.Source}}")
+	rootCmd.Flags().Duration("poll-duration", 24*time.Hour, "How long polls are open for, at least 5m")
+	rootCmd.Flags().Bool("poll-multiple", false, "Allow voting for more than one option of polls")
+	rootCmd.Flags().Bool("attach-images", false, "Attach images from new posts to their toots")
+	rootCmd.Flags().Bool("upload-enclosures", false, "Upload video and audio enclosures from new posts as attachments instead of just linking them")
+	rootCmd.Flags().Bool("verify-link-card", false, "Verify Mastodon resolved a link preview card for new toots, attaching an image from the post if not")
+	rootCmd.Flags().Bool("boost-author-posts", false, "Boost the author's own fediverse post of new posts, found via fediverse:creator or rel=me links, instead of tooting a link")
+	rootCmd.Flags().Bool("link-check", false, "Only announce new posts once their page is live, i.e. doesn't respond with 404 or a server error")
+	rootCmd.Flags().Duration("link-check-grace", 30*time.Minute, "How long after a new post is first seen to wait for its page to go live before giving up")
+	rootCmd.Flags().Bool("attribute-author", false, "Mention the author's fediverse account from the post's fediverse:creator meta tag in new toots")
+	rootCmd.Flags().Int("schedule-delay", 0, "Post new items as Mastodon scheduled statuses this many minutes ahead (at least 6), leaving time to delete bad announcements from the scheduled posts; 0 posts right away")
+	rootCmd.Flags().Bool("schedule-embargoed", false, "Post future-dated posts as Mastodon scheduled statuses instead of holding them until their pubDate")
+	rootCmd.Flags().Bool("require-approval", false, "Queue new posts until they are approved with the approve command or a notification action")
+	rootCmd.Flags().String("listen-addr", "", "Address for the admin listener serving approval endpoints (e.g. :8080), disabled if empty")
+	rootCmd.Flags().Bool("enable-pprof", false, "Serve net/http/pprof profiles on the admin listener under /debug/pprof/")
+	rootCmd.Flags().Bool("streaming", false, "Listen to mentions on the Mastodon streaming API and answer commands like latest, or pause and resume from --command-accounts")
+	rootCmd.Flags().String("command-accounts", "", "Comma-separated accounts (user@domain) allowed to run admin commands like pause <feed> in mentions")
+	rootCmd.Flags().String("auto-reply", "", "Reply once to accounts replying to the bot's toots with this message, e.g. \"This is an automated mirror, reach the author at @me@example.social\" (implies --streaming)")
+	rootCmd.Flags().Duration("auto-reply-cooldown", 30*24*time.Hour, "Don't send the auto-reply to the same account again within this long")
+	rootCmd.Flags().Int("auto-reply-max-per-hour", 10, "Send the auto-reply to at most this many accounts per hour, 0 for no limit")
+	rootCmd.Flags().String("admin-account-ids", "", "Comma-separated IDs of accounts allowed to run admin commands like status or poll now in direct messages, answered privately (implies --streaming)")
+	rootCmd.Flags().Duration("archive-repost-interval", 0, "Repost a random old post from the archive this often (e.g. 168h), 0 disables")
+	rootCmd.Flags().Duration("archive-repost-min-age", 180*24*time.Hour, "Only repost posts from the archive which were tooted at least this long ago")
+	rootCmd.Flags().Duration("archive-repost-cooldown", 365*24*time.Hour, "Don't repost a post from the archive again within this long")
+	rootCmd.Flags().String("archive-repost-template", "From the archive: {{.Link}}", "Template of archive reposts, with {{.Link}} and {{.Tooted}} available")
+	rootCmd.Flags().String("digest-day", "", "Toot a digest of the week's posts on this day of the week (e.g. sunday), unset disables")
+	rootCmd.Flags().String("digest-time", "09:00", "Local time of day the weekly digest is tooted")
+	rootCmd.Flags().String("digest-template", "This week on the blog:\n{{range .Posts}}\n{{.Link}}{{end}}", "Template of the weekly digest, with {{.Posts}} (each with {{.Link}} and {{.Tooted}}), {{.Start}} and {{.End}} available")
+	rootCmd.Flags().Duration("account-sync-interval", 0, "How often to update the bot account's profile fields (e.g. 24h), 0 disables")
+	rootCmd.Flags().Bool("account-sync-avatar", false, "Also set the bot account's avatar to the feed's channel image when syncing the profile")
+	rootCmd.Flags().Duration("engagement-interval", 0, "How often to record the bot account's follower count and the engagement of recent statuses (e.g. 6h), 0 disables")
+	rootCmd.Flags().Duration("engagement-digest-interval", 0, "How often to send a notification summarizing the favourites, boosts and replies received and the change in followers (e.g. 24h), 0 disables, requires --engagement-interval")
+	rootCmd.Flags().Bool("optimize-posting-time", false, "Hold new posts until the hour their statuses historically get the most engagement, requires --engagement-interval")
+	rootCmd.Flags().String("posting-hours", "", "Hours of the day new posts may be shifted into by --optimize-posting-time, e.g. 8-22")
+	rootCmd.Flags().String("posting-schedule", "", "Days and hours new posts are announced at, others are held until then, e.g. \"weekdays 8-22|sat 10-14\" (overridable per --feed)")
+	rootCmd.Flags().Duration("max-posting-delay", 12*time.Hour, "Longest time --optimize-posting-time holds a new post")
+	rootCmd.Flags().Duration("stale-after", 0, "Notify when the feed has produced no new items for this long (e.g. 720h), 0 disables")
+	rootCmd.Flags().Duration("stale-build-after", 0, "Notify when the feed's lastBuildDate hasn't changed for this long (e.g. 336h), 0 disables")
+	rootCmd.Flags().Duration("latency-alert-after", 0, "Notify when a feed's new item is first seen this long after its pubDate (e.g. 2h), 0 disables")
+	rootCmd.Flags().Int("notify-failure-threshold", 3, "Notify when fetching the feed or tooting fails this many times in a row")
+	rootCmd.Flags().Int("retry-max-attempts", 5, "Move new posts to the dead letters after failing to toot this many times")
+	rootCmd.Flags().Duration("retry-max-age", 24*time.Hour, "Move new posts to the dead letters after failing to toot for this long")
+	rootCmd.Flags().String("state-file", "", "Path to a JSON health state file updated every cycle for external monitoring, disabled if empty")
+	rootCmd.Flags().String("history-feed", "", "Path to write an RSS feed of the bot's recent statuses to after every cycle")
+	rootCmd.Flags().Duration("audit-log-retention", 90*24*time.Hour, "How long the audit log keeps outbound requests, forever if 0")
+	rootCmd.Flags().String("db-encryption-key", "", "Key to encrypt the post contents and error messages in the database with, 32 random bytes in base64 (e.g. from openssl rand -base64 32); required to open the database from then on")
+	rootCmd.Flags().String("config-dir", "", "Directory with one file per config key (e.g. a mounted ConfigMap), reloaded automatically on change")
+	rootCmd.Flags().String("k8s-lease", "", "Name of a Kubernetes Lease to hold so only one replica posts, disabled if empty")
+	rootCmd.Flags().Duration("k8s-lease-duration", 30*time.Second, "How long the Kubernetes Lease is held without being renewed")
+	rootCmd.Flags().Bool("leader-election", false, "Elect a leader through the shared state database so only one instance posts")
+	rootCmd.Flags().Duration("leader-lease-duration", 30*time.Second, "How long the leader lock is held without being renewed")
+
+	// config show reports where every daemon setting comes from, including the daemon's flags
+	configShowCmd.Flags().AddFlagSet(rootCmd.Flags())
 
 	// add sub-commands
 	rootCmd.AddCommand(
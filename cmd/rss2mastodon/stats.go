@@ -0,0 +1,19 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/toozej/rss2mastodon/internal/rss2mastodon"
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Shows follower growth and engagement per announcement style",
+	Long:  `Shows the bot account's follower growth and the average favourites, boosts and replies of its statuses per announcement style, as recorded with --engagement-interval`,
+	Args:  cobra.NoArgs,
+	Run:   rss2mastodon.ShowStats,
+}
+
+func init() {
+	rootCmd.AddCommand(statsCmd)
+}
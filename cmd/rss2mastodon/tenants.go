@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/toozej/rss2mastodon/internal/rss2mastodon"
+)
+
+var tenantsCmd = &cobra.Command{
+	Use:   "tenants file",
+	Short: "Runs an isolated bot for every tenant configured in a file",
+	Long:  `Runs a bot for every tenant configured in a YAML file, each in its own process and directory with its own settings, accounts, feeds, notifiers and database, restarting bots which exit`,
+	Args:  cobra.ExactArgs(1),
+	Run:   rss2mastodon.RunTenants,
+}
+
+func init() {
+	tenantsCmd.Flags().String("dir", "tenants", "Directory holding a directory with the database and state files of every tenant")
+
+	rootCmd.AddCommand(tenantsCmd)
+}
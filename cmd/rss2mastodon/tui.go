@@ -0,0 +1,21 @@
+//go:build !no_tui
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/toozej/rss2mastodon/internal/tui"
+)
+
+var tuiCmd = &cobra.Command{
+	Use:   "tui",
+	Short: "Interactive terminal UI for the queue and history",
+	Long:  `Shows feeds, the pending queue, recent toots and errors, with keybindings to approve, retry and forget posts and to force a poll`,
+	Args:  cobra.NoArgs,
+	Run:   tui.Run,
+}
+
+func init() {
+	rootCmd.AddCommand(tuiCmd)
+}
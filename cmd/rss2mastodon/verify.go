@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/toozej/rss2mastodon/internal/release"
+	"github.com/toozej/rss2mastodon/pkg/version"
+)
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Verifies the binary against the signed checksums of its release",
+	Long:  `Downloads the checksums of the binary's release from GitHub, verifies their cosign signature with the project's public key and checks that the release archive for this platform matches them and holds a binary identical to the running one`,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		releaseVersion, _ := cmd.Flags().GetString("version")
+		binary, _ := cmd.Flags().GetString("binary")
+		publicKeyFile, _ := cmd.Flags().GetString("public-key")
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		if binary == "" {
+			executable, err := os.Executable()
+			if err != nil {
+				log.Fatal("Error finding the running binary: ", err)
+			}
+			binary = executable
+		}
+
+		verifier := release.Verifier{Timeout: timeout}
+		if publicKeyFile != "" {
+			publicKey, err := os.ReadFile(publicKeyFile)
+			if err != nil {
+				log.Fatal("Error reading the public key: ", err)
+			}
+			verifier.PublicKey = string(publicKey)
+		}
+		result, err := verifier.Verify(releaseVersion, runtime.GOOS, runtime.GOARCH, binary)
+		if err != nil {
+			log.Fatal("Verification failed: ", err)
+		}
+		fmt.Printf("%s is the signed release %s (%s, sha256 %s)\n", binary, releaseVersion, result.Archive, result.SHA256)
+	},
+}
+
+func init() {
+	verifyCmd.Flags().String("version", version.Version, "Version the binary was released as, the one it was built with by default")
+	verifyCmd.Flags().String("binary", "", "Binary to verify instead of the running one")
+	verifyCmd.Flags().Duration("timeout", 5*time.Minute, "Timeout of every download")
+	verifyCmd.Flags().String("public-key", "", "Cosign public key file to verify the signature with instead of the built-in rss2mastodon.pub")
+
+	rootCmd.AddCommand(verifyCmd)
+}
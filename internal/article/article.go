@@ -0,0 +1,180 @@
+package article
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/toozej/rss2mastodon/internal/httpclient"
+)
+
+// Page holds metadata scraped from an article's HTML page
+type Page struct {
+	URL string
+	// Meta maps <meta> property/name attributes (e.g. og:image) to their content
+	Meta map[string]string
+	// Images lists the absolute URLs of <img> tags on the page in document order
+	Images []string
+	// RelMe lists the absolute URLs of rel="me" links, e.g. the author's fediverse profile
+	RelMe []string
+}
+
+// maxPageBytes caps how much of an article page is read
+const maxPageBytes = 5 * 1024 * 1024
+
+var (
+	metaTagRegex = regexp.MustCompile(`(?is)<meta\s[^>]*>`)
+	imgTagRegex  = regexp.MustCompile(`(?is)<img\s[^>]*>`)
+	linkTagRegex = regexp.MustCompile(`(?is)<(?:a|link)\s[^>]*>`)
+	attrRegex    = regexp.MustCompile(`(?is)\b([a-z:_-]+)\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+)
+
+// Fetch downloads and parses the article page at the given URL
+func Fetch(pageURL string) (*Page, error) {
+	client := httpclient.New("article", 10*time.Second)
+
+	resp, err := client.Get(pageURL)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected HTTP status: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxPageBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read page: %w", err)
+	}
+
+	return Parse(pageURL, string(body)), nil
+}
+
+// Parse extracts metadata from the HTML of the page at pageURL
+func Parse(pageURL string, body string) *Page {
+	page := &Page{
+		URL:  pageURL,
+		Meta: make(map[string]string),
+	}
+	base, _ := url.Parse(pageURL)
+
+	for _, tag := range metaTagRegex.FindAllString(body, -1) {
+		attrs := parseAttrs(tag)
+		key := attrs["property"]
+		if key == "" {
+			key = attrs["name"]
+		}
+		if key == "" {
+			continue
+		}
+		if _, ok := page.Meta[strings.ToLower(key)]; !ok {
+			page.Meta[strings.ToLower(key)] = attrs["content"]
+		}
+	}
+
+	for _, tag := range imgTagRegex.FindAllString(body, -1) {
+		if src := resolve(base, parseAttrs(tag)["src"]); src != "" {
+			page.Images = append(page.Images, src)
+		}
+	}
+
+	for _, tag := range linkTagRegex.FindAllString(body, -1) {
+		attrs := parseAttrs(tag)
+		if !hasRel(attrs["rel"], "me") {
+			continue
+		}
+		if href := resolve(base, attrs["href"]); href != "" {
+			page.RelMe = append(page.RelMe, href)
+		}
+	}
+
+	return page
+}
+
+// FediverseAccounts returns the page author's fediverse accounts as user@host, from the
+// fediverse:creator meta tag first and then rel="me" links to Mastodon style profiles (/@user)
+func (p *Page) FediverseAccounts() []string {
+	var accounts []string
+	seen := make(map[string]bool)
+	add := func(acct string) {
+		acct = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(acct), "@"))
+		if strings.Count(acct, "@") == 1 && !seen[acct] {
+			seen[acct] = true
+			accounts = append(accounts, acct)
+		}
+	}
+
+	add(p.Meta["fediverse:creator"])
+	for _, link := range p.RelMe {
+		u, err := url.Parse(link)
+		if err != nil || u.Host == "" || !strings.HasPrefix(u.Path, "/@") {
+			continue
+		}
+		user := strings.TrimSuffix(strings.TrimPrefix(u.Path, "/@"), "/")
+		if user != "" && !strings.Contains(user, "/") {
+			add(user + "@" + u.Host)
+		}
+	}
+	return accounts
+}
+
+// hasRel reports whether a space-separated rel attribute contains the given link type
+func hasRel(rel string, linkType string) bool {
+	for _, value := range strings.Fields(rel) {
+		if strings.EqualFold(value, linkType) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasOpenGraph reports whether the page provides OpenGraph tags Mastodon can build a preview card from
+func (p *Page) HasOpenGraph() bool {
+	return p.Meta["og:title"] != "" || p.Meta["og:image"] != ""
+}
+
+// PreviewImage returns the best image to represent the page: its og:image or
+// twitter:image if set, otherwise the first image on the page
+func (p *Page) PreviewImage() string {
+	base, _ := url.Parse(p.URL)
+	for _, key := range []string{"og:image", "twitter:image"} {
+		if image := resolve(base, p.Meta[key]); image != "" {
+			return image
+		}
+	}
+	if len(p.Images) > 0 {
+		return p.Images[0]
+	}
+	return ""
+}
+
+// parseAttrs returns the lowercased attribute names and unescaped values of an HTML tag
+func parseAttrs(tag string) map[string]string {
+	attrs := make(map[string]string)
+	for _, match := range attrRegex.FindAllStringSubmatch(tag, -1) {
+		attrs[strings.ToLower(match[1])] = html.UnescapeString(match[2] + match[3])
+	}
+	return attrs
+}
+
+// resolve turns a possibly relative reference into an absolute URL
+func resolve(base *url.URL, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return ""
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return ""
+	}
+	if base == nil {
+		return u.String()
+	}
+	return base.ResolveReference(u).String()
+}
@@ -0,0 +1,120 @@
+package article
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Test finding the author's fediverse accounts from fediverse:creator and rel=me links
+func TestFediverseAccounts(t *testing.T) {
+	body := `<html><head>
+		<meta name="fediverse:creator" content="@Me@example.social">
+		<link rel="me" href="https://example.social/@me">
+		</head><body>
+		<a rel="nofollow me" href="https://other.example/@alt/">Mastodon</a>
+		<a rel="me" href="https://github.com/me">GitHub</a>
+		<a href="https://third.example/@ignored">Not me</a>
+		</body></html>`
+
+	page := Parse("https://example.com/posts/hello/", body)
+
+	expected := []string{"me@example.social", "alt@other.example"}
+	accounts := page.FediverseAccounts()
+	if len(accounts) != len(expected) {
+		t.Fatalf("Expected accounts %v, got %v", expected, accounts)
+	}
+	for i := range expected {
+		if accounts[i] != expected[i] {
+			t.Errorf("Expected account '%s', got '%s'", expected[i], accounts[i])
+		}
+	}
+}
+
+// Test parsing meta tags and images from an article page
+func TestParse(t *testing.T) {
+	body := `<html><head>
+		<meta property="og:title" content="Hello &amp; welcome">
+		<meta name="fediverse:creator" content="@me@example.social" />
+		</head><body>
+		<img src="/images/hero.jpg" alt="Hero">
+		<img src='https://cdn.example.com/second.png'>
+		</body></html>`
+
+	page := Parse("https://example.com/posts/hello/", body)
+
+	if page.Meta["og:title"] != "Hello & welcome" {
+		t.Errorf("Expected og:title 'Hello & welcome', got '%s'", page.Meta["og:title"])
+	}
+	if page.Meta["fediverse:creator"] != "@me@example.social" {
+		t.Errorf("Expected fediverse:creator '@me@example.social', got '%s'", page.Meta["fediverse:creator"])
+	}
+	if !page.HasOpenGraph() {
+		t.Errorf("Expected page to have OpenGraph tags")
+	}
+
+	expected := []string{"https://example.com/images/hero.jpg", "https://cdn.example.com/second.png"}
+	if len(page.Images) != len(expected) {
+		t.Fatalf("Expected %d images, got %d: %v", len(expected), len(page.Images), page.Images)
+	}
+	for i := range expected {
+		if page.Images[i] != expected[i] {
+			t.Errorf("Expected image %d to be '%s', got '%s'", i, expected[i], page.Images[i])
+		}
+	}
+}
+
+// Table-driven test for choosing a preview image
+func TestPreviewImage(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{
+			name:     "OpenGraph image",
+			body:     `<meta property="og:image" content="/og.png"><img src="/first.png">`,
+			expected: "https://example.com/og.png",
+		},
+		{
+			name:     "Twitter image",
+			body:     `<meta name="twitter:image" content="https://example.com/tw.png"><img src="/first.png">`,
+			expected: "https://example.com/tw.png",
+		},
+		{
+			name:     "First image",
+			body:     `<p>Hi</p><img src="first.png"><img src="second.png">`,
+			expected: "https://example.com/posts/first.png",
+		},
+		{
+			name:     "No images",
+			body:     `<p>Hi</p>`,
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := Parse("https://example.com/posts/", tt.body)
+			if result := page.PreviewImage(); result != tt.expected {
+				t.Errorf("Expected '%s', got '%s'", tt.expected, result)
+			}
+		})
+	}
+}
+
+// Test fetching an article page
+func TestFetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<meta property="og:title" content="Fetched">`))
+	}))
+	defer server.Close()
+
+	page, err := Fetch(server.URL)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if page.Meta["og:title"] != "Fetched" {
+		t.Errorf("Expected og:title 'Fetched', got '%s'", page.Meta["og:title"])
+	}
+}
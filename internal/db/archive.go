@@ -0,0 +1,67 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+)
+
+// createArchiveRepostsTable creates the archive_reposts table if it does not exist
+func createArchiveRepostsTable() error {
+	query := `CREATE TABLE IF NOT EXISTS archive_reposts (
+		link TEXT PRIMARY KEY,
+		reposted_at TEXT
+	)`
+	_, err := db.Exec(query)
+	return err
+}
+
+// ArchiveRepostCandidates returns the tooted posts which were last tooted before olderThan
+// and haven't been reposted from the archive since notRepostedSince
+func ArchiveRepostCandidates(olderThan time.Time, notRepostedSince time.Time) ([]TootedPost, error) {
+	query := `SELECT t.link, t.timestamp, r.reposted_at FROM tooted_posts t LEFT JOIN archive_reposts r ON r.link = t.link`
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var posts []TootedPost
+	for rows.Next() {
+		var post TootedPost
+		var timestamp string
+		var repostedAt sql.NullString
+		if err := rows.Scan(&post.Link, &timestamp, &repostedAt); err != nil {
+			return nil, err
+		}
+		// timestamps are compared as times, they weren't always stored in UTC
+		if post.Timestamp, err = time.Parse(time.RFC3339, timestamp); err != nil || !post.Timestamp.Before(olderThan) {
+			continue
+		}
+		if repostedAt.Valid {
+			if t, err := time.Parse(time.RFC3339, repostedAt.String); err == nil && t.After(notRepostedSince) {
+				continue
+			}
+		}
+		posts = append(posts, post)
+	}
+	return posts, rows.Err()
+}
+
+// RecordArchiveRepost remembers that a post was reposted from the archive
+func RecordArchiveRepost(link string) error {
+	_, err := db.Exec(`INSERT OR REPLACE INTO archive_reposts(link, reposted_at) VALUES (?, ?)`, link, time.Now().UTC().Format(time.RFC3339))
+	return err
+}
+
+// LastArchiveRepost returns when a post was last reposted from the archive, and false if never
+func LastArchiveRepost() (time.Time, bool, error) {
+	var repostedAt sql.NullString
+	if err := db.QueryRow(`SELECT MAX(reposted_at) FROM archive_reposts`).Scan(&repostedAt); err != nil {
+		return time.Time{}, false, err
+	}
+	if !repostedAt.Valid {
+		return time.Time{}, false, nil
+	}
+	t, err := time.Parse(time.RFC3339, repostedAt.String)
+	return t, err == nil, err
+}
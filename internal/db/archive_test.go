@@ -0,0 +1,44 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+// Test selecting old tooted posts which haven't been reposted recently
+func TestArchiveRepostCandidates(t *testing.T) {
+	InitDB()
+	defer CloseDB()
+
+	if _, ok, err := LastArchiveRepost(); err != nil || ok {
+		t.Errorf("Expected no archive reposts yet, got %v, %v", ok, err)
+	}
+
+	old := time.Now().AddDate(-1, 0, 0).Format(time.RFC3339)
+	for _, link := range []string{"https://example.com/old", "https://example.com/reposted"} {
+		if _, err := db.Exec(`INSERT OR REPLACE INTO tooted_posts(link, content_hash, timestamp) VALUES (?, '', ?)`, link, old); err != nil {
+			t.Fatalf("Failed to insert post: %v", err)
+		}
+	}
+	if err := StoreTootedPost("https://example.com/recent", "content"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := RecordArchiveRepost("https://example.com/reposted"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	candidates, err := ArchiveRepostCandidates(time.Now().AddDate(0, -6, 0), time.Now().AddDate(0, -1, 0))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(candidates) != 1 || candidates[0].Link != "https://example.com/old" {
+		t.Errorf("Expected only the old post, got %v", candidates)
+	}
+
+	last, ok, err := LastArchiveRepost()
+	if err != nil || !ok || time.Since(last) > time.Minute {
+		t.Errorf("Expected a recent archive repost, got %v, %v, %v", last, ok, err)
+	}
+	_, _ = db.Exec(`DELETE FROM archive_reposts`)
+	_, _ = db.Exec(`DELETE FROM tooted_posts WHERE link IN ('https://example.com/old', 'https://example.com/reposted')`)
+}
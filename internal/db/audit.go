@@ -0,0 +1,120 @@
+package db
+
+import (
+	"time"
+)
+
+// AuditEntry is a recorded outbound request acting on another server, e.g. posting a status
+type AuditEntry struct {
+	Timestamp time.Time
+	// Client is the name of the client which sent the request, e.g. "mastodon"
+	Client   string
+	Method   string
+	Target   string
+	Endpoint string
+	// Item is the link of the feed item the request was sent for, empty if none
+	Item    string
+	Status  int
+	Outcome string
+}
+
+// AuditFilter selects audit log entries, the zero value selecting all of them
+type AuditFilter struct {
+	// Since and Until bound the time of the entries, unbounded if zero
+	Since time.Time
+	Until time.Time
+	// Item selects the entries of a feed item, all entries if empty
+	Item string
+	// Limit bounds the number of entries, the most recent ones, unbounded if 0
+	Limit int
+}
+
+// createAuditLogTable creates the audit_log table if it does not exist. Its entries can't be
+// changed, only dropped once they are older than the retention.
+func createAuditLogTable() error {
+	query := `CREATE TABLE IF NOT EXISTS audit_log (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		timestamp TEXT,
+		client TEXT,
+		method TEXT,
+		target TEXT,
+		endpoint TEXT,
+		item TEXT,
+		status INTEGER,
+		outcome TEXT
+	)`
+	if _, err := db.Exec(query); err != nil {
+		return err
+	}
+	if _, err := db.Exec(`CREATE INDEX IF NOT EXISTS audit_log_timestamp ON audit_log(timestamp)`); err != nil {
+		return err
+	}
+	query = `CREATE TRIGGER IF NOT EXISTS audit_log_append_only BEFORE UPDATE ON audit_log
+		BEGIN SELECT RAISE(ABORT, 'the audit log is append-only'); END`
+	_, err := db.Exec(query)
+	return err
+}
+
+// RecordAudit appends an entry to the audit log, dropping entries older than the retention,
+// unless it is 0
+func RecordAudit(entry AuditEntry, retention time.Duration) error {
+	query := `INSERT INTO audit_log(timestamp, client, method, target, endpoint, item, status, outcome) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	if _, err := db.Exec(query, entry.Timestamp.UTC().Format(time.RFC3339), entry.Client, entry.Method, entry.Target,
+		entry.Endpoint, entry.Item, entry.Status, entry.Outcome); err != nil {
+		return err
+	}
+	if retention <= 0 {
+		return nil
+	}
+	_, err := db.Exec(`DELETE FROM audit_log WHERE timestamp < ?`, time.Now().Add(-retention).UTC().Format(time.RFC3339))
+	return err
+}
+
+// AuditLog returns the audit log entries selected by the filter, oldest first
+func AuditLog(filter AuditFilter) ([]AuditEntry, error) {
+	query := `SELECT timestamp, client, method, target, endpoint, item, status, outcome FROM audit_log WHERE 1 = 1`
+	var args []interface{}
+	if !filter.Since.IsZero() {
+		query += ` AND timestamp >= ?`
+		args = append(args, filter.Since.UTC().Format(time.RFC3339))
+	}
+	if !filter.Until.IsZero() {
+		query += ` AND timestamp < ?`
+		args = append(args, filter.Until.UTC().Format(time.RFC3339))
+	}
+	if filter.Item != "" {
+		query += ` AND item = ?`
+		args = append(args, filter.Item)
+	}
+	query += ` ORDER BY id DESC`
+	if filter.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []AuditEntry
+	for rows.Next() {
+		var entry AuditEntry
+		var timestamp string
+		if err := rows.Scan(&timestamp, &entry.Client, &entry.Method, &entry.Target, &entry.Endpoint, &entry.Item,
+			&entry.Status, &entry.Outcome); err != nil {
+			return nil, err
+		}
+		entry.Timestamp, _ = time.Parse(time.RFC3339, timestamp)
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// the most recent entries were selected, shown in the order they happened
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries, nil
+}
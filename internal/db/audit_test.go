@@ -0,0 +1,70 @@
+package db
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Test recording, filtering and rotating the audit log, whose entries can't be changed
+func TestAuditLog(t *testing.T) {
+	path := Path
+	Path = filepath.Join(t.TempDir(), "audit.db")
+	defer func() { Path = path }()
+	InitDB()
+	defer CloseDB()
+
+	now := time.Now().Truncate(time.Second)
+	entries := []AuditEntry{
+		{Timestamp: now.Add(-100 * 24 * time.Hour), Client: "mastodon", Method: "POST", Target: "mastodon.social", Endpoint: "/api/v1/statuses", Item: "https://example.com/old", Status: 200, Outcome: "200 OK"},
+		{Timestamp: now.Add(-2 * time.Hour), Client: "mastodon", Method: "POST", Target: "mastodon.social", Endpoint: "/api/v1/statuses", Item: "https://example.com/1", Status: 503, Outcome: "503 Service Unavailable"},
+		{Timestamp: now.Add(-time.Hour), Client: "mastodon", Method: "POST", Target: "mastodon.social", Endpoint: "/api/v1/statuses", Item: "https://example.com/1", Status: 200, Outcome: "200 OK"},
+		{Timestamp: now, Client: "notify", Method: "POST", Target: "ntfy.sh", Endpoint: "/alerts", Outcome: "dial tcp: connection refused"},
+	}
+	for _, entry := range entries {
+		if err := RecordAudit(entry, 0); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		filter   AuditFilter
+		expected []AuditEntry
+	}{
+		{"All", AuditFilter{}, entries},
+		{"Since", AuditFilter{Since: now.Add(-90 * time.Minute)}, entries[2:]},
+		{"Until", AuditFilter{Until: now.Add(-time.Hour)}, entries[:2]},
+		{"Item", AuditFilter{Item: "https://example.com/1"}, entries[1:3]},
+		{"Limit", AuditFilter{Limit: 2}, entries[2:]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := AuditLog(tt.filter)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if len(got) != len(tt.expected) {
+				t.Fatalf("Expected %d entries, got %+v", len(tt.expected), got)
+			}
+			for i := range got {
+				if !got[i].Timestamp.Equal(tt.expected[i].Timestamp) || got[i].Item != tt.expected[i].Item || got[i].Outcome != tt.expected[i].Outcome {
+					t.Errorf("Expected %+v, got %+v", tt.expected[i], got[i])
+				}
+			}
+		})
+	}
+
+	if _, err := db.Exec(`UPDATE audit_log SET outcome = '200 OK'`); err == nil {
+		t.Errorf("Expected updating the audit log to fail")
+	}
+
+	if err := RecordAudit(AuditEntry{Timestamp: now, Client: "mastodon", Method: "DELETE"}, 90*24*time.Hour); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	got, err := AuditLog(AuditFilter{})
+	if err != nil || len(got) != 4 || got[0].Item != "https://example.com/1" {
+		t.Errorf("Expected the entry older than the retention dropped, got %+v, %v", got, err)
+	}
+}
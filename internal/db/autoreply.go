@@ -0,0 +1,44 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+)
+
+// createAutoRepliesTable creates the auto_replies table if it does not exist
+func createAutoRepliesTable() error {
+	query := `CREATE TABLE IF NOT EXISTS auto_replies (
+		acct TEXT PRIMARY KEY,
+		status_id TEXT,
+		timestamp TEXT
+	)`
+	_, err := db.Exec(query)
+	return err
+}
+
+// RecordAutoReply remembers that an account was auto-replied to, in reply to the given status
+func RecordAutoReply(acct string, statusID string) error {
+	query := `INSERT OR REPLACE INTO auto_replies(acct, status_id, timestamp) VALUES (?, ?, ?)`
+	_, err := db.Exec(query, acct, statusID, time.Now().UTC().Format(time.RFC3339))
+	return err
+}
+
+// LastAutoReply returns when an account was last auto-replied to, if ever
+func LastAutoReply(acct string) (time.Time, bool, error) {
+	var timestamp string
+	err := db.QueryRow(`SELECT timestamp FROM auto_replies WHERE acct = ?`, acct).Scan(&timestamp)
+	if err == sql.ErrNoRows {
+		return time.Time{}, false, nil
+	} else if err != nil {
+		return time.Time{}, false, err
+	}
+	replied, err := time.Parse(time.RFC3339, timestamp)
+	return replied, err == nil, err
+}
+
+// CountAutoRepliesSince returns how many accounts were auto-replied to since the given time
+func CountAutoRepliesSince(since time.Time) (int, error) {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM auto_replies WHERE timestamp >= ?`, since.UTC().Format(time.RFC3339)).Scan(&count)
+	return count, err
+}
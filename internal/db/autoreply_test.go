@@ -0,0 +1,33 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+// Test remembering which accounts were auto-replied to, and how many recently
+func TestAutoReplies(t *testing.T) {
+	InitDB()
+	defer CloseDB()
+	defer func() { _, _ = db.Exec(`DELETE FROM auto_replies`) }()
+
+	if _, ok, err := LastAutoReply("fan@example.social"); ok || err != nil {
+		t.Fatalf("Expected no auto-reply yet, got %v, %v", ok, err)
+	}
+	for _, acct := range []string{"fan@example.social", "critic@example.net"} {
+		if err := RecordAutoReply(acct, "42"); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	replied, ok, err := LastAutoReply("fan@example.social")
+	if !ok || err != nil || time.Since(replied) > time.Minute {
+		t.Errorf("Expected a recent auto-reply, got %v, %v, %v", replied, ok, err)
+	}
+	if count, err := CountAutoRepliesSince(time.Now().Add(-time.Hour)); count != 2 || err != nil {
+		t.Errorf("Expected 2 auto-replies in the last hour, got %d, %v", count, err)
+	}
+	if count, err := CountAutoRepliesSince(time.Now().Add(time.Hour)); count != 0 || err != nil {
+		t.Errorf("Expected no auto-replies after now, got %d, %v", count, err)
+	}
+}
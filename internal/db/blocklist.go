@@ -0,0 +1,67 @@
+package db
+
+import (
+	"time"
+)
+
+// createBlockedItemsTable creates the blocked_items table if it does not exist
+func createBlockedItemsTable() error {
+	query := `CREATE TABLE IF NOT EXISTS blocked_items (
+		id TEXT PRIMARY KEY,
+		timestamp TEXT
+	)`
+	_, err := db.Exec(query)
+	return err
+}
+
+// BlockItem adds an item URL or GUID to the blocklist so it is never announced
+func BlockItem(id string) error {
+	query := `INSERT OR REPLACE INTO blocked_items(id, timestamp) VALUES (?, ?)`
+	_, err := db.Exec(query, id, time.Now().Format(time.RFC3339))
+	return err
+}
+
+// UnblockItem removes an item URL or GUID from the blocklist
+func UnblockItem(id string) error {
+	query := `DELETE FROM blocked_items WHERE id = ?`
+	_, err := db.Exec(query, id)
+	return err
+}
+
+// IsBlocked checks whether any of the given item URLs or GUIDs are on the blocklist
+func IsBlocked(ids ...string) (bool, error) {
+	query := `SELECT COUNT(*) FROM blocked_items WHERE id = ?`
+	for _, id := range ids {
+		if id == "" {
+			continue
+		}
+
+		var count int
+		if err := db.QueryRow(query, id).Scan(&count); err != nil {
+			return false, err
+		}
+		if count > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ListBlockedItems returns all blocked item URLs and GUIDs
+func ListBlockedItems() ([]string, error) {
+	rows, err := db.Query(`SELECT id FROM blocked_items ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
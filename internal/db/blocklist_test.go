@@ -0,0 +1,42 @@
+package db
+
+import (
+	"testing"
+)
+
+// Test blocking, listing and unblocking items
+func TestBlockItem(t *testing.T) {
+	InitDB()
+	defer CloseDB()
+
+	if err := BlockItem("https://example.com/retracted"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	blocked, err := IsBlocked("", "https://example.com/retracted")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !blocked {
+		t.Errorf("Expected item to be blocked")
+	}
+
+	ids, err := ListBlockedItems()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(ids) != 1 || ids[0] != "https://example.com/retracted" {
+		t.Errorf("Expected blocklist [https://example.com/retracted], got %v", ids)
+	}
+
+	if err := UnblockItem("https://example.com/retracted"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	blocked, err = IsBlocked("https://example.com/retracted")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if blocked {
+		t.Errorf("Expected item to be unblocked")
+	}
+}
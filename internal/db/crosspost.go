@@ -0,0 +1,140 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+)
+
+// CrossPost is an announcement to repeat on another Mastodon account, and how that went
+type CrossPost struct {
+	Link string
+	// Account is the name of the account to toot it on
+	Account        string
+	Content        string
+	Visibility     string
+	SpoilerText    string
+	Language       string
+	IdempotencyKey string
+	// ScheduledAt publishes the status as a scheduled status, if set
+	ScheduledAt time.Time
+	// StatusID is the ID of the status tooted on the account, empty until it was tooted
+	StatusID  string
+	Attempts  int
+	LastError string
+}
+
+// createCrossPostsTable creates the cross_posts table if it does not exist
+func createCrossPostsTable() error {
+	query := `CREATE TABLE IF NOT EXISTS cross_posts (
+		link TEXT,
+		account TEXT,
+		content TEXT,
+		visibility TEXT,
+		spoiler_text TEXT,
+		language TEXT,
+		idempotency_key TEXT,
+		scheduled_at TEXT,
+		status_id TEXT,
+		attempts INTEGER DEFAULT 0,
+		last_error TEXT,
+		timestamp TEXT,
+		PRIMARY KEY (link, account)
+	)`
+	_, err := db.Exec(query)
+	return err
+}
+
+// QueueCrossPost records an announcement to toot on another account. Announcements already
+// recorded for the account are kept as they are.
+func QueueCrossPost(post CrossPost) error {
+	var scheduledAt string
+	if !post.ScheduledAt.IsZero() {
+		scheduledAt = post.ScheduledAt.UTC().Format(time.RFC3339)
+	}
+	content, err := encryptValue(post.Content)
+	if err != nil {
+		return err
+	}
+	spoilerText, err := encryptValue(post.SpoilerText)
+	if err != nil {
+		return err
+	}
+	query := `INSERT INTO cross_posts(link, account, content, visibility, spoiler_text, language, idempotency_key, scheduled_at, status_id, attempts, last_error, timestamp)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, '', 0, '', ?) ON CONFLICT(link, account) DO NOTHING`
+	_, err = db.Exec(query, post.Link, post.Account, content, post.Visibility, spoilerText, post.Language,
+		post.IdempotencyKey, scheduledAt, time.Now().UTC().Format(time.RFC3339))
+	return err
+}
+
+// RecordCrossPostSuccess records the status an announcement was tooted as on an account
+func RecordCrossPostSuccess(link string, account string, statusID string) error {
+	query := `UPDATE cross_posts SET status_id = ?, last_error = '', timestamp = ? WHERE link = ? AND account = ?`
+	_, err := db.Exec(query, statusID, time.Now().UTC().Format(time.RFC3339), link, account)
+	return err
+}
+
+// RecordCrossPostFailure counts a failed attempt to toot an announcement on an account,
+// returning how many attempts failed
+func RecordCrossPostFailure(link string, account string, lastError string) (int, error) {
+	lastError, err := encryptValue(lastError)
+	if err != nil {
+		return 0, err
+	}
+	query := `UPDATE cross_posts SET attempts = attempts + 1, last_error = ?, timestamp = ? WHERE link = ? AND account = ?`
+	if _, err := db.Exec(query, lastError, time.Now().UTC().Format(time.RFC3339), link, account); err != nil {
+		return 0, err
+	}
+	var attempts int
+	err = db.QueryRow(`SELECT attempts FROM cross_posts WHERE link = ? AND account = ?`, link, account).Scan(&attempts)
+	return attempts, err
+}
+
+// UnsentCrossPosts returns the announcements not tooted on their account yet which failed fewer
+// than maxAttempts times, in the order they were queued
+func UnsentCrossPosts(maxAttempts int) ([]CrossPost, error) {
+	query := `SELECT link, account, content, visibility, spoiler_text, language, idempotency_key, scheduled_at, status_id, attempts, last_error
+		FROM cross_posts WHERE status_id = '' AND attempts < ? ORDER BY rowid`
+	return queryCrossPosts(query, maxAttempts)
+}
+
+// LinkCrossPosts returns the announcements of a post on every other account, in the order
+// they were queued
+func LinkCrossPosts(link string) ([]CrossPost, error) {
+	query := `SELECT link, account, content, visibility, spoiler_text, language, idempotency_key, scheduled_at, status_id, attempts, last_error
+		FROM cross_posts WHERE link = ? ORDER BY rowid`
+	return queryCrossPosts(query, link)
+}
+
+func queryCrossPosts(query string, args ...interface{}) ([]CrossPost, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var posts []CrossPost
+	for rows.Next() {
+		var post CrossPost
+		var scheduledAt sql.NullString
+		if err := rows.Scan(&post.Link, &post.Account, &post.Content, &post.Visibility, &post.SpoilerText, &post.Language,
+			&post.IdempotencyKey, &scheduledAt, &post.StatusID, &post.Attempts, &post.LastError); err != nil {
+			return nil, err
+		}
+		if post.Content, err = decryptValue(post.Content); err != nil {
+			return nil, err
+		}
+		if post.SpoilerText, err = decryptValue(post.SpoilerText); err != nil {
+			return nil, err
+		}
+		if post.LastError, err = decryptValue(post.LastError); err != nil {
+			return nil, err
+		}
+		if scheduledAt.String != "" {
+			if post.ScheduledAt, err = time.Parse(time.RFC3339, scheduledAt.String); err != nil {
+				return nil, err
+			}
+		}
+		posts = append(posts, post)
+	}
+	return posts, rows.Err()
+}
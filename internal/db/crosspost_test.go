@@ -0,0 +1,59 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+// Test recording announcements on other accounts and whether they were tooted
+func TestCrossPosts(t *testing.T) {
+	InitDB()
+	t.Cleanup(CloseDB)
+	// the announcements would be found queued already by the next run
+	t.Cleanup(func() {
+		if _, err := db.Exec(`DELETE FROM cross_posts WHERE link = ?`, "https://example.com/crosspost"); err != nil {
+			t.Errorf("Failed to delete the announcements: %v", err)
+		}
+	})
+
+	scheduled := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, account := range []string{"project", "personal"} {
+		post := CrossPost{Link: "https://example.com/crosspost", Account: account, Content: "New post", Visibility: "unlisted", ScheduledAt: scheduled}
+		if err := QueueCrossPost(post); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	// queueing again keeps the recorded announcement
+	if err := QueueCrossPost(CrossPost{Link: "https://example.com/crosspost", Account: "project", Content: "Changed"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if err := RecordCrossPostSuccess("https://example.com/crosspost", "project", "42"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for i := 1; i <= 2; i++ {
+		attempts, err := RecordCrossPostFailure("https://example.com/crosspost", "personal", "timeout")
+		if err != nil || attempts != i {
+			t.Fatalf("Expected %d attempts, got %d, %v", i, attempts, err)
+		}
+	}
+
+	unsent, err := UnsentCrossPosts(3)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(unsent) != 1 || unsent[0].Account != "personal" || unsent[0].LastError != "timeout" || !unsent[0].ScheduledAt.Equal(scheduled) {
+		t.Errorf("Expected the failed announcement, got %+v", unsent)
+	}
+	if unsent, err := UnsentCrossPosts(2); err != nil || len(unsent) != 0 {
+		t.Errorf("Expected no announcements left to retry, got %+v, %v", unsent, err)
+	}
+
+	posts, err := LinkCrossPosts("https://example.com/crosspost")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(posts) != 2 || posts[0].StatusID != "42" || posts[0].Content != "New post" || posts[1].StatusID != "" {
+		t.Errorf("Expected both announcements, got %+v", posts)
+	}
+}
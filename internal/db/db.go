@@ -13,24 +13,120 @@ import (
 
 var db *sql.DB
 
+// Path is the SQLite database file, each profile uses its own
+var Path = "./tooted_posts.db"
+
+// CacheSizeKiB limits SQLite's page cache per connection, 0 keeps SQLite's default of 2 MiB
+var CacheSizeKiB = 0
+
 // InitDB initializes the SQLite database
 func InitDB() {
 	var err error
-	db, err = sql.Open("sqlite3", "./tooted_posts.db")
+	// wait for locks rather than failing when fetching and posting write concurrently
+	dsn := Path + "?_busy_timeout=5000"
+	if CacheSizeKiB > 0 {
+		dsn += fmt.Sprintf("&_cache_size=-%d", CacheSizeKiB)
+	}
+	if EncryptionKey != "" {
+		// overwrite deleted and replaced values, so no plaintext is left behind in free pages
+		dsn += "&_secure_delete=on"
+	}
+	db, err = sql.Open("sqlite3", dsn)
 	if err != nil {
 		log.Fatal("Failed to open database:", err)
 	}
+	if CacheSizeKiB > 0 {
+		// every idle connection keeps its own page cache
+		db.SetMaxIdleConns(1)
+	}
 
 	// Create table if not exists
 	query := `CREATE TABLE IF NOT EXISTS tooted_posts (
 		link TEXT PRIMARY KEY,
 		content_hash TEXT,
-		timestamp TEXT
+		timestamp TEXT,
+		feed_url TEXT
 	)`
 	_, err = db.Exec(query)
 	if err != nil {
 		log.Fatal("Failed to create table:", err)
 	}
+	// databases of older releases don't record which feed a post came from
+	if err = addColumn("tooted_posts", "feed_url", "TEXT"); err != nil {
+		log.Fatal("Failed to add feed column:", err)
+	}
+
+	if err = createFeedHealthTable(); err != nil {
+		log.Fatal("Failed to create feed health table:", err)
+	}
+
+	if err = createBlockedItemsTable(); err != nil {
+		log.Fatal("Failed to create blocked items table:", err)
+	}
+
+	if err = createPendingPostsTable(); err != nil {
+		log.Fatal("Failed to create pending posts table:", err)
+	}
+
+	if err = createErrorLogTable(); err != nil {
+		log.Fatal("Failed to create error log table:", err)
+	}
+
+	if err = createLeaderLockTable(); err != nil {
+		log.Fatal("Failed to create leader lock table:", err)
+	}
+
+	if err = createDeadLetterTables(); err != nil {
+		log.Fatal("Failed to create dead letter tables:", err)
+	}
+
+	if err = createSeenItemsTable(); err != nil {
+		log.Fatal("Failed to create seen items table:", err)
+	}
+
+	if err = createArchiveRepostsTable(); err != nil {
+		log.Fatal("Failed to create archive reposts table:", err)
+	}
+
+	if err = createStateTable(); err != nil {
+		log.Fatal("Failed to create state table:", err)
+	}
+
+	if err = createEngagementTables(); err != nil {
+		log.Fatal("Failed to create engagement tables:", err)
+	}
+
+	if err = createDedupKeysTable(); err != nil {
+		log.Fatal("Failed to create dedup keys table:", err)
+	}
+
+	if err = createItemPresenceTable(); err != nil {
+		log.Fatal("Failed to create item presence table:", err)
+	}
+
+	if err = createFeedValidatorsTable(); err != nil {
+		log.Fatal("Failed to create feed validators table:", err)
+	}
+
+	if err = createPausedFeedsTable(); err != nil {
+		log.Fatal("Failed to create paused feeds table:", err)
+	}
+
+	if err = createAutoRepliesTable(); err != nil {
+		log.Fatal("Failed to create auto replies table:", err)
+	}
+
+	if err = createCrossPostsTable(); err != nil {
+		log.Fatal("Failed to create cross posts table:", err)
+	}
+
+	if err = createAuditLogTable(); err != nil {
+		log.Fatal("Failed to create audit log table:", err)
+	}
+
+	if err = setUpEncryption(); err != nil {
+		log.Fatal("Failed to set up database encryption:", err)
+	}
 }
 
 // CloseDB closes the SQLite database connection
@@ -41,11 +137,42 @@ func CloseDB() {
 	}
 }
 
+// addColumn adds a column to a table created by an older release, if it is missing
+func addColumn(table string, column string, definition string) error {
+	rows, err := db.Query(`SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	_, err = db.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, column, definition))
+	return err
+}
+
 // StoreTootedPost stores the link, content hash, and timestamp in the database
 func StoreTootedPost(link string, content string) error {
-	query := `INSERT OR REPLACE INTO tooted_posts(link, content_hash, timestamp) VALUES (?, ?, ?)`
+	return StoreFeedPost("", link, content)
+}
+
+// StoreFeedPost stores a post like StoreTootedPost, along with the URL of the feed it came
+// from. An empty feed URL keeps the one already stored for the link.
+func StoreFeedPost(feedURL string, link string, content string) error {
+	query := `INSERT INTO tooted_posts(link, content_hash, timestamp, feed_url) VALUES (?, ?, ?, NULLIF(?, ''))
+		ON CONFLICT(link) DO UPDATE SET content_hash = excluded.content_hash, timestamp = excluded.timestamp,
+		feed_url = COALESCE(excluded.feed_url, feed_url)`
 	contentHash := rss.HashContent(content)
-	_, err := db.Exec(query, link, fmt.Sprintf("%x", contentHash), time.Now().Format(time.RFC3339))
+	_, err := db.Exec(query, link, fmt.Sprintf("%x", contentHash), time.Now().Format(time.RFC3339), feedURL)
 	return err
 }
 
@@ -1,7 +1,9 @@
 package db
 
 import (
+	"database/sql"
 	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -98,6 +100,59 @@ func TestHasPostChanged_UnchangedPost(t *testing.T) {
 	}
 }
 
+// Table-driven test for recording which feed a post came from
+func TestStoreFeedPost(t *testing.T) {
+	InitDB()
+	defer CloseDB()
+
+	tests := []struct {
+		name     string
+		feedURL  string
+		expected string
+	}{
+		{name: "New post", feedURL: "https://example.com/rss", expected: "https://example.com/rss"},
+		{name: "Updated without a feed", feedURL: "", expected: "https://example.com/rss"},
+		{name: "Updated from another feed", feedURL: "https://example.org/rss", expected: "https://example.org/rss"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := StoreFeedPost(tt.feedURL, "https://example.com/feed-post", tt.name); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			var feedURL string
+			if err := db.QueryRow(`SELECT feed_url FROM tooted_posts WHERE link = ?`, "https://example.com/feed-post").Scan(&feedURL); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if feedURL != tt.expected {
+				t.Errorf("Expected feed %q, got %q", tt.expected, feedURL)
+			}
+		})
+	}
+}
+
+// Test databases of older releases get the feed column
+func TestInitDB_AddsFeedColumn(t *testing.T) {
+	path := Path
+	Path = filepath.Join(t.TempDir(), "old.db")
+	defer func() { Path = path }()
+
+	old, err := sql.Open("sqlite3", Path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := old.Exec(`CREATE TABLE tooted_posts (link TEXT PRIMARY KEY, content_hash TEXT, timestamp TEXT)`); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	old.Close()
+
+	InitDB()
+	defer CloseDB()
+	if err := StoreFeedPost("https://example.com/rss", "https://example.com/old-post", "content"); err != nil {
+		t.Errorf("Expected the feed to be stored, got %v", err)
+	}
+}
+
 // Clean up test database
 func TestMain(m *testing.M) {
 	// Run tests
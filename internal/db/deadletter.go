@@ -0,0 +1,178 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// DeadLetter is a post which exhausted its retry budget and is no longer retried automatically
+type DeadLetter struct {
+	ID           int64
+	Item         rss.RSSItem
+	Attempts     int
+	FirstFailure time.Time
+	LastError    string
+	Timestamp    time.Time
+}
+
+// createDeadLetterTables creates the delivery_attempts and dead_letters tables if they do not exist
+func createDeadLetterTables() error {
+	query := `CREATE TABLE IF NOT EXISTS delivery_attempts (
+		link TEXT PRIMARY KEY,
+		attempts INTEGER,
+		first_failure TEXT,
+		last_error TEXT
+	)`
+	if _, err := db.Exec(query); err != nil {
+		return err
+	}
+
+	query = `CREATE TABLE IF NOT EXISTS dead_letters (
+		link TEXT PRIMARY KEY,
+		item TEXT,
+		attempts INTEGER,
+		first_failure TEXT,
+		last_error TEXT,
+		timestamp TEXT
+	)`
+	_, err := db.Exec(query)
+	return err
+}
+
+// RecordDeliveryFailure counts a failed attempt to announce a post, returning how many attempts
+// failed in a row and when the first of them failed
+func RecordDeliveryFailure(link string, lastError string) (int, time.Time, error) {
+	lastError, err := encryptValue(lastError)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	query := `INSERT INTO delivery_attempts(link, attempts, first_failure, last_error) VALUES (?, 1, ?, ?)
+		ON CONFLICT(link) DO UPDATE SET attempts = attempts + 1, last_error = excluded.last_error`
+	if _, err := db.Exec(query, link, time.Now().UTC().Format(time.RFC3339), lastError); err != nil {
+		return 0, time.Time{}, err
+	}
+
+	var attempts int
+	var firstFailure string
+	err = db.QueryRow(`SELECT attempts, first_failure FROM delivery_attempts WHERE link = ?`, link).Scan(&attempts, &firstFailure)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	t, err := time.Parse(time.RFC3339, firstFailure)
+	return attempts, t, err
+}
+
+// HasDeliveryFailures reports whether failed attempts to announce a post have been recorded
+// since it was last announced
+func HasDeliveryFailures(link string) (bool, error) {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM delivery_attempts WHERE link = ?`, link).Scan(&count)
+	return count > 0, err
+}
+
+// ClearDeliveryAttempts forgets the failed attempts of a post once it has been announced
+func ClearDeliveryAttempts(link string) error {
+	_, err := db.Exec(`DELETE FROM delivery_attempts WHERE link = ?`, link)
+	return err
+}
+
+// AddDeadLetter moves a post which exhausted its retry budget to the dead letters,
+// removing it from the pending queue
+func AddDeadLetter(post rss.RSSItem, attempts int, firstFailure time.Time, lastError string) error {
+	data, err := json.Marshal(post)
+	if err != nil {
+		return err
+	}
+	item, err := encryptValue(string(data))
+	if err != nil {
+		return err
+	}
+	if lastError, err = encryptValue(lastError); err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	query := `INSERT OR REPLACE INTO dead_letters(link, item, attempts, first_failure, last_error, timestamp) VALUES (?, ?, ?, ?, ?, ?)`
+	_, err = tx.Exec(query, post.Link, item, attempts, firstFailure.UTC().Format(time.RFC3339), lastError, time.Now().Format(time.RFC3339))
+	if err != nil {
+		return err
+	}
+	if _, err = tx.Exec(`DELETE FROM delivery_attempts WHERE link = ?`, post.Link); err != nil {
+		return err
+	}
+	if _, err = tx.Exec(`DELETE FROM pending_posts WHERE link = ?`, post.Link); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// IsDeadLettered reports whether a post is in the dead letters
+func IsDeadLettered(link string) (bool, error) {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM dead_letters WHERE link = ?`, link).Scan(&count)
+	return count > 0, err
+}
+
+// ListDeadLetters returns all dead letters, most recent first
+func ListDeadLetters() ([]DeadLetter, error) {
+	return queryDeadLetters(`SELECT rowid, item, attempts, first_failure, last_error, timestamp FROM dead_letters ORDER BY timestamp DESC`)
+}
+
+// GetDeadLetter returns the dead letter with the given ID or link, or nil if there is none
+func GetDeadLetter(idOrLink string) (*DeadLetter, error) {
+	query := `SELECT rowid, item, attempts, first_failure, last_error, timestamp FROM dead_letters WHERE CAST(rowid AS TEXT) = ? OR link = ?`
+	letters, err := queryDeadLetters(query, idOrLink, idOrLink)
+	if err != nil || len(letters) == 0 {
+		return nil, err
+	}
+	return &letters[0], nil
+}
+
+// RemoveDeadLetter removes a post from the dead letters
+func RemoveDeadLetter(link string) error {
+	_, err := db.Exec(`DELETE FROM dead_letters WHERE link = ?`, link)
+	return err
+}
+
+func queryDeadLetters(query string, args ...interface{}) ([]DeadLetter, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var letters []DeadLetter
+	for rows.Next() {
+		var item, firstFailure, timestamp string
+		var lastError sql.NullString
+		var letter DeadLetter
+		if err := rows.Scan(&letter.ID, &item, &letter.Attempts, &firstFailure, &lastError, &timestamp); err != nil {
+			return nil, err
+		}
+		if item, err = decryptValue(item); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(item), &letter.Item); err != nil {
+			return nil, err
+		}
+		if letter.FirstFailure, err = time.Parse(time.RFC3339, firstFailure); err != nil {
+			return nil, err
+		}
+		if letter.Timestamp, err = time.Parse(time.RFC3339, timestamp); err != nil {
+			return nil, err
+		}
+		if letter.LastError, err = decryptValue(lastError.String); err != nil {
+			return nil, err
+		}
+		letters = append(letters, letter)
+	}
+	return letters, rows.Err()
+}
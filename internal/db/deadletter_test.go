@@ -0,0 +1,69 @@
+package db
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// Test counting delivery failures and moving posts to the dead letters
+func TestDeadLetters(t *testing.T) {
+	InitDB()
+	defer CloseDB()
+
+	post := rss.RSSItem{Title: "Failing", Link: "https://example.com/failing"}
+	for i := 1; i <= 2; i++ {
+		attempts, firstFailure, err := RecordDeliveryFailure(post.Link, "timeout")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if attempts != i {
+			t.Errorf("Expected %d attempts, got %d", i, attempts)
+		}
+		if time.Since(firstFailure) > time.Minute {
+			t.Errorf("Expected first failure to be recent, got %v", firstFailure)
+		}
+	}
+
+	if err := QueuePendingPost(post, time.Now(), PendingEmbargo); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := AddDeadLetter(post, 2, time.Now(), "timeout"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if deadLettered, err := IsDeadLettered(post.Link); err != nil || !deadLettered {
+		t.Errorf("Expected post to be dead-lettered, got %v, %v", deadLettered, err)
+	}
+	if pending, err := GetPendingPost(post.Link); err != nil || pending != nil {
+		t.Errorf("Expected post to be removed from the pending queue, got %v, %v", pending, err)
+	}
+	// the retry budget starts over if the post is retried
+	if attempts, _, err := RecordDeliveryFailure(post.Link, "timeout"); err != nil || attempts != 1 {
+		t.Errorf("Expected attempts to be reset, got %d, %v", attempts, err)
+	}
+	if err := ClearDeliveryAttempts(post.Link); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	letter, err := GetDeadLetter(post.Link)
+	if err != nil || letter == nil {
+		t.Fatalf("Expected dead letter, got %v, %v", letter, err)
+	}
+	if letter.Item.Title != "Failing" || letter.Attempts != 2 || letter.LastError != "timeout" {
+		t.Errorf("Unexpected dead letter %+v", letter)
+	}
+	if byID, err := GetDeadLetter(fmt.Sprint(letter.ID)); err != nil || byID == nil {
+		t.Errorf("Expected dead letter by ID, got %v, %v", byID, err)
+	}
+
+	if err := RemoveDeadLetter(post.Link); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	letters, err := ListDeadLetters()
+	if err != nil || len(letters) != 0 {
+		t.Errorf("Expected no dead letters, got %v, %v", letters, err)
+	}
+}
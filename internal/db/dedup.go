@@ -0,0 +1,26 @@
+package db
+
+// createDedupKeysTable creates the dedup_keys table if it does not exist
+func createDedupKeysTable() error {
+	query := `CREATE TABLE IF NOT EXISTS dedup_keys (
+		dedup_key TEXT PRIMARY KEY,
+		link TEXT
+	)`
+	_, err := db.Exec(query)
+	return err
+}
+
+// CanonicalLink returns the link an item was first seen with under the given dedup key,
+// recording link if the key is new. Items of feeds changing their links on every build are
+// identified by another key, e.g. their title and date, and keep the link they were first seen with.
+func CanonicalLink(dedupKey string, link string) (string, error) {
+	if _, err := db.Exec(`INSERT OR IGNORE INTO dedup_keys(dedup_key, link) VALUES (?, ?)`, dedupKey, link); err != nil {
+		return "", err
+	}
+
+	var canonical string
+	if err := db.QueryRow(`SELECT link FROM dedup_keys WHERE dedup_key = ?`, dedupKey).Scan(&canonical); err != nil {
+		return "", err
+	}
+	return canonical, nil
+}
@@ -0,0 +1,34 @@
+package db
+
+import (
+	"testing"
+)
+
+// Test items keep the link they were first seen with under their dedup key
+func TestCanonicalLink(t *testing.T) {
+	InitDB()
+	defer CloseDB()
+
+	tests := []struct {
+		name     string
+		key      string
+		link     string
+		expected string
+	}{
+		{"First seen", "title-a", "https://example.com/a?v=1", "https://example.com/a?v=1"},
+		{"Seen again with another link", "title-a", "https://example.com/a?v=2", "https://example.com/a?v=1"},
+		{"Other item", "title-b", "https://example.com/b?v=2", "https://example.com/b?v=2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			link, err := CanonicalLink(tt.key, tt.link)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if link != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, link)
+			}
+		})
+	}
+}
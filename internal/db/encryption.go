@@ -0,0 +1,183 @@
+package db
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// EncryptionKey is the AES-256-GCM key the columns holding post contents and error messages are
+// encrypted with, 32 random bytes in base64, unencrypted if empty. Set it before InitDB.
+//
+// The toot history (tooted_posts) holds no contents, only the links, content hashes and times
+// posts are looked up by, and the audit log only the requests' endpoints and links, which it is
+// filtered by and which can't be rewritten in its append-only table, so both stay unencrypted.
+var EncryptionKey = ""
+
+// encryptedPrefix marks encrypted values, which are base64 of the nonce and the sealed value
+const encryptedPrefix = "enc:v1:"
+
+// encryptionCheckKey is the state key of a known value encrypted with the key the database was
+// first encrypted with, telling a wrong key apart from a database which isn't encrypted
+const encryptionCheckKey = "encryption_check"
+
+// encryptionCheckValue is the value stored encrypted under encryptionCheckKey
+const encryptionCheckValue = "rss2mastodon"
+
+// ErrEncrypted is returned reading encrypted values without the key
+var ErrEncrypted = errors.New("the database is encrypted, set the database encryption key")
+
+// ErrInvalidKey is returned for an EncryptionKey which isn't 32 bytes in base64
+var ErrInvalidKey = errors.New("the database encryption key must be 32 random bytes in base64, e.g. from openssl rand -base64 32")
+
+// encryptedColumns are the columns which are encrypted, as table and column
+var encryptedColumns = [][2]string{
+	{"pending_posts", "item"},
+	{"delivery_attempts", "last_error"},
+	{"dead_letters", "item"},
+	{"dead_letters", "last_error"},
+	{"cross_posts", "content"},
+	{"cross_posts", "spoiler_text"},
+	{"cross_posts", "last_error"},
+	{"error_log", "message"},
+}
+
+// aead encrypts and decrypts values, nil if the database isn't encrypted
+var aead cipher.AEAD
+
+// setUpEncryption prepares encrypting values with EncryptionKey. Once a database was encrypted
+// the key is required, and must be the same. Values stored before encryption was enabled are
+// encrypted in place, and the database is vacuumed so their plaintext doesn't linger in free pages
+// or the write-ahead log.
+func setUpEncryption() error {
+	aead = nil
+	if EncryptionKey != "" {
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(EncryptionKey))
+		if err != nil || len(key) != 32 {
+			return ErrInvalidKey
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return err
+		}
+		if aead, err = cipher.NewGCM(block); err != nil {
+			return err
+		}
+	}
+
+	check, ok, err := GetState(encryptionCheckKey)
+	if err != nil {
+		return err
+	}
+	switch {
+	case ok && aead == nil:
+		return ErrEncrypted
+	case ok:
+		if value, err := decryptValue(check); err != nil || value != encryptionCheckValue {
+			return fmt.Errorf("wrong encryption key, the database was encrypted with another one")
+		}
+	case aead == nil:
+		return nil
+	default:
+		if check, err = encryptValue(encryptionCheckValue); err != nil {
+			return err
+		}
+		if err := SetState(encryptionCheckKey, check); err != nil {
+			return err
+		}
+	}
+	encrypted, err := encryptStoredValues()
+	if err != nil || encrypted == 0 {
+		return err
+	}
+	// InitDB opens encrypted databases with secure_delete, so the pages the plaintext is
+	// freed from are zeroed by the vacuum rather than just rewritten elsewhere
+	if _, err := db.Exec(`VACUUM`); err != nil {
+		return fmt.Errorf("failed to vacuum the database: %w", err)
+	}
+	if _, err := db.Exec(`PRAGMA wal_checkpoint(TRUNCATE)`); err != nil {
+		return fmt.Errorf("failed to checkpoint the database: %w", err)
+	}
+	return nil
+}
+
+// encryptStoredValues encrypts the values of the encrypted columns stored unencrypted, returning
+// how many it encrypted
+func encryptStoredValues() (int, error) {
+	count := 0
+	for _, column := range encryptedColumns {
+		table, name := column[0], column[1]
+		query := fmt.Sprintf(`SELECT rowid, %s FROM %s WHERE %s != '' AND %s NOT LIKE ?`, name, table, name, name)
+		rows, err := db.Query(query, encryptedPrefix+"%")
+		if err != nil {
+			return 0, err
+		}
+		values := make(map[int64]string)
+		for rows.Next() {
+			var rowid int64
+			var value string
+			if err := rows.Scan(&rowid, &value); err != nil {
+				rows.Close()
+				return 0, err
+			}
+			values[rowid] = value
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return 0, err
+		}
+
+		for rowid, value := range values {
+			encrypted, err := encryptValue(value)
+			if err != nil {
+				return 0, err
+			}
+			if _, err := db.Exec(fmt.Sprintf(`UPDATE %s SET %s = ? WHERE rowid = ?`, table, name), encrypted, rowid); err != nil {
+				return 0, err
+			}
+			count++
+		}
+	}
+	return count, nil
+}
+
+// encryptValue encrypts a value of an encrypted column, if the database is encrypted
+func encryptValue(value string) (string, error) {
+	if aead == nil || value == "" {
+		return value, nil
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(value), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decryptValue decrypts a value of an encrypted column, returning values stored unencrypted as
+// they are
+func decryptValue(value string) (string, error) {
+	encoded, ok := strings.CutPrefix(value, encryptedPrefix)
+	if !ok {
+		return value, nil
+	}
+	if aead == nil {
+		return "", ErrEncrypted
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("encrypted value is too short")
+	}
+	plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value: %w", err)
+	}
+	return string(plain), nil
+}
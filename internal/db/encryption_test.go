@@ -0,0 +1,88 @@
+package db
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// Test encrypting a database, including the values stored before, and opening it again
+func TestEncryption(t *testing.T) {
+	path := Path
+	Path = filepath.Join(t.TempDir(), "encrypted.db")
+	defer func() { Path, EncryptionKey, aead = path, "", nil }()
+
+	post := rss.RSSItem{Title: "Secret plans", Link: "https://example.com/plans", Content: strings.Repeat("Secret plans ", 400)}
+	InitDB()
+	if err := QueuePendingPost(post, time.Now(), PendingApproval); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := RecordError("https://example.com/rss", "fetch failed"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, _, err := RecordDeliveryFailure(post.Link, "rejected Secret plans"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	CloseDB()
+
+	EncryptionKey = base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))
+	InitDB()
+	defer CloseDB()
+	for _, column := range [][2]string{{"pending_posts", "item"}, {"error_log", "message"}, {"delivery_attempts", "last_error"}} {
+		var value string
+		if err := db.QueryRow(`SELECT ` + column[1] + ` FROM ` + column[0]).Scan(&value); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if !strings.HasPrefix(value, encryptedPrefix) || strings.Contains(value, "plans") {
+			t.Errorf("Expected %s.%s to be encrypted, got %q", column[0], column[1], value)
+		}
+	}
+	// the plaintext mustn't linger in the file's free pages either
+	for _, file := range []string{Path, Path + "-wal"} {
+		data, err := os.ReadFile(file)
+		if err != nil && !os.IsNotExist(err) {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if bytes.Contains(data, []byte("Secret plans")) {
+			t.Errorf("Expected no plaintext left in %s", file)
+		}
+	}
+
+	if err := AddDeadLetter(rss.RSSItem{Title: "Failed", Link: "https://example.com/failed"}, 3, time.Now(), "timeout"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	pending, err := GetPendingPost(post.Link)
+	if err != nil || pending == nil || pending.Item.Title != post.Title {
+		t.Errorf("Expected the pending post, got %+v, %v", pending, err)
+	}
+	letters, err := ListDeadLetters()
+	if err != nil || len(letters) != 1 || letters[0].Item.Title != "Failed" || letters[0].LastError != "timeout" {
+		t.Errorf("Expected the dead letter, got %+v, %v", letters, err)
+	}
+	entries, err := RecentErrors(10)
+	if err != nil || len(entries) != 1 || entries[0].Message != "fetch failed" {
+		t.Errorf("Expected the error log, got %+v, %v", entries, err)
+	}
+
+	EncryptionKey = base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{2}, 32))
+	if err := setUpEncryption(); err == nil || errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Expected an error for a wrong key, got %v", err)
+	}
+	for _, key := range []string{"a random secret of enough length", base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 16))} {
+		EncryptionKey = key
+		if err := setUpEncryption(); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Expected ErrInvalidKey for %q, got %v", key, err)
+		}
+	}
+	EncryptionKey = ""
+	if err := setUpEncryption(); !errors.Is(err, ErrEncrypted) {
+		t.Errorf("Expected ErrEncrypted without a key, got %v", err)
+	}
+}
@@ -0,0 +1,277 @@
+package db
+
+import (
+	"time"
+)
+
+// Status is a status the bot tooted, e.g. to announce a post
+type Status struct {
+	ID        string
+	URL       string
+	Link      string
+	Style     string
+	CreatedAt time.Time
+}
+
+// FollowerCount is the bot account's follower count at a point in time
+type FollowerCount struct {
+	Followers int
+	Timestamp time.Time
+}
+
+// StyleEngagement is the average engagement of the statuses tooted in one announcement style
+type StyleEngagement struct {
+	Style      string
+	Statuses   int
+	Favourites float64
+	Reblogs    float64
+	Replies    float64
+}
+
+// StatusEngagement is when a status was tooted and its total favourites, reblogs and replies
+type StatusEngagement struct {
+	CreatedAt  time.Time
+	Engagement int
+}
+
+// EngagementChange is how many favourites, reblogs and replies a status received since the
+// last engagement digest
+type EngagementChange struct {
+	StatusID   string
+	Link       string
+	Favourites int
+	Reblogs    int
+	Replies    int
+}
+
+// createEngagementTables creates the statuses and follower_counts tables if they do not exist
+func createEngagementTables() error {
+	query := `CREATE TABLE IF NOT EXISTS statuses (
+		status_id TEXT PRIMARY KEY,
+		url TEXT,
+		link TEXT,
+		style TEXT,
+		created_at TEXT,
+		favourites INTEGER DEFAULT 0,
+		reblogs INTEGER DEFAULT 0,
+		replies INTEGER DEFAULT 0,
+		checked_at TEXT,
+		digest_favourites INTEGER DEFAULT 0,
+		digest_reblogs INTEGER DEFAULT 0,
+		digest_replies INTEGER DEFAULT 0,
+		deleted_at TEXT
+	)`
+	if _, err := db.Exec(query); err != nil {
+		return err
+	}
+	// databases of older releases don't remember the engagement of the last digest
+	for _, column := range []string{"digest_favourites", "digest_reblogs", "digest_replies"} {
+		if err := addColumn("statuses", column, "INTEGER DEFAULT 0"); err != nil {
+			return err
+		}
+	}
+	// nor the statuses deleted since
+	if err := addColumn("statuses", "deleted_at", "TEXT"); err != nil {
+		return err
+	}
+
+	query = `CREATE TABLE IF NOT EXISTS follower_counts (
+		timestamp TEXT PRIMARY KEY,
+		followers INTEGER
+	)`
+	_, err := db.Exec(query)
+	return err
+}
+
+// RecordStatus remembers a status tooted for a post, and the announcement style it used
+func RecordStatus(statusID string, statusURL string, link string, style string) error {
+	query := `INSERT OR IGNORE INTO statuses(status_id, url, link, style, created_at) VALUES (?, ?, ?, ?, ?)`
+	_, err := db.Exec(query, statusID, statusURL, link, style, time.Now().UTC().Format(time.RFC3339))
+	return err
+}
+
+// HasStatus reports whether the bot tooted the status with the given ID
+func HasStatus(statusID string) (bool, error) {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM statuses WHERE status_id = ?`, statusID).Scan(&count)
+	return count > 0, err
+}
+
+// RecentStatusIDs returns the IDs of the statuses tooted since the given time, which weren't
+// deleted since
+func RecentStatusIDs(since time.Time) ([]string, error) {
+	rows, err := db.Query(`SELECT status_id FROM statuses WHERE created_at >= ? AND deleted_at IS NULL ORDER BY created_at`, since.UTC().Format(time.RFC3339))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// LinkStatuses returns the statuses tooted for a post which weren't deleted yet, oldest first
+func LinkStatuses(link string) ([]Status, error) {
+	return queryStatuses(`SELECT status_id, COALESCE(url, ''), link, style, created_at FROM statuses
+		WHERE link = ? AND deleted_at IS NULL ORDER BY created_at`, link)
+}
+
+// MarkStatusDeleted records that a status was deleted from Mastodon
+func MarkStatusDeleted(statusID string) error {
+	_, err := db.Exec(`UPDATE statuses SET deleted_at = ? WHERE status_id = ?`, time.Now().UTC().Format(time.RFC3339), statusID)
+	return err
+}
+
+// CountStatusesSince returns how many statuses were tooted since the given time
+func CountStatusesSince(since time.Time) (int, error) {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM statuses WHERE created_at >= ?`, since.UTC().Format(time.RFC3339)).Scan(&count)
+	return count, err
+}
+
+// RecentStatuses returns the most recently tooted statuses, newest first
+func RecentStatuses(limit int) ([]Status, error) {
+	return queryStatuses(`SELECT status_id, COALESCE(url, ''), link, style, created_at FROM statuses ORDER BY created_at DESC LIMIT ?`, limit)
+}
+
+// ListStatuses returns all tooted statuses, oldest first
+func ListStatuses() ([]Status, error) {
+	return queryStatuses(`SELECT status_id, COALESCE(url, ''), link, style, created_at FROM statuses ORDER BY created_at`)
+}
+
+func queryStatuses(query string, args ...interface{}) ([]Status, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var statuses []Status
+	for rows.Next() {
+		var status Status
+		var createdAt string
+		if err := rows.Scan(&status.ID, &status.URL, &status.Link, &status.Style, &createdAt); err != nil {
+			return nil, err
+		}
+		status.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+		statuses = append(statuses, status)
+	}
+	return statuses, rows.Err()
+}
+
+// UpdateStatusEngagement stores the current favourites, reblogs and replies of a status
+func UpdateStatusEngagement(statusID string, favourites int, reblogs int, replies int) error {
+	query := `UPDATE statuses SET favourites = ?, reblogs = ?, replies = ?, checked_at = ? WHERE status_id = ?`
+	_, err := db.Exec(query, favourites, reblogs, replies, time.Now().UTC().Format(time.RFC3339), statusID)
+	return err
+}
+
+// EngagementByStyle returns the average engagement per announcement style, best performing first
+func EngagementByStyle() ([]StyleEngagement, error) {
+	query := `SELECT style, COUNT(*), AVG(favourites), AVG(reblogs), AVG(replies) FROM statuses
+		WHERE checked_at IS NOT NULL GROUP BY style ORDER BY AVG(favourites + reblogs + replies) DESC`
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var styles []StyleEngagement
+	for rows.Next() {
+		var style StyleEngagement
+		if err := rows.Scan(&style.Style, &style.Statuses, &style.Favourites, &style.Reblogs, &style.Replies); err != nil {
+			return nil, err
+		}
+		styles = append(styles, style)
+	}
+	return styles, rows.Err()
+}
+
+// CheckedStatusEngagement returns the engagement of all statuses checked at least once
+func CheckedStatusEngagement() ([]StatusEngagement, error) {
+	rows, err := db.Query(`SELECT created_at, favourites + reblogs + replies FROM statuses WHERE checked_at IS NOT NULL`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var statuses []StatusEngagement
+	for rows.Next() {
+		var status StatusEngagement
+		var createdAt string
+		if err := rows.Scan(&createdAt, &status.Engagement); err != nil {
+			return nil, err
+		}
+		if status.CreatedAt, err = time.Parse(time.RFC3339, createdAt); err != nil {
+			continue
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, rows.Err()
+}
+
+// EngagementSinceDigest returns the statuses which received favourites, reblogs or replies since
+// the last engagement digest, most engaged first
+func EngagementSinceDigest() ([]EngagementChange, error) {
+	// favourites and reblogs can be withdrawn, which doesn't count as negative engagement
+	query := `SELECT status_id, link, MAX(favourites - digest_favourites, 0) AS f, MAX(reblogs - digest_reblogs, 0) AS b,
+		MAX(replies - digest_replies, 0) AS r
+		FROM statuses WHERE checked_at IS NOT NULL AND f + b + r > 0
+		ORDER BY f + b + r DESC, created_at DESC`
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []EngagementChange
+	for rows.Next() {
+		var change EngagementChange
+		if err := rows.Scan(&change.StatusID, &change.Link, &change.Favourites, &change.Reblogs, &change.Replies); err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	return changes, rows.Err()
+}
+
+// MarkEngagementDigested remembers the current engagement of every status as reported by a
+// digest, so the next digest only reports what was received after it
+func MarkEngagementDigested() error {
+	_, err := db.Exec(`UPDATE statuses SET digest_favourites = favourites, digest_reblogs = reblogs, digest_replies = replies`)
+	return err
+}
+
+// RecordFollowerCount stores the bot account's current follower count
+func RecordFollowerCount(followers int) error {
+	_, err := db.Exec(`INSERT OR REPLACE INTO follower_counts(timestamp, followers) VALUES (?, ?)`, time.Now().UTC().Format(time.RFC3339), followers)
+	return err
+}
+
+// FollowerCounts returns the follower counts recorded since the given time, oldest first
+func FollowerCounts(since time.Time) ([]FollowerCount, error) {
+	rows, err := db.Query(`SELECT followers, timestamp FROM follower_counts WHERE timestamp >= ? ORDER BY timestamp`, since.UTC().Format(time.RFC3339))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []FollowerCount
+	for rows.Next() {
+		var count FollowerCount
+		var timestamp string
+		if err := rows.Scan(&count.Followers, &timestamp); err != nil {
+			return nil, err
+		}
+		count.Timestamp, _ = time.Parse(time.RFC3339, timestamp)
+		counts = append(counts, count)
+	}
+	return counts, rows.Err()
+}
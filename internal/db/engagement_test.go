@@ -0,0 +1,178 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Test recording statuses and averaging their engagement per style
+func TestEngagementByStyle(t *testing.T) {
+	InitDB()
+	defer CloseDB()
+	defer func() { _, _ = db.Exec(`DELETE FROM statuses`) }()
+
+	statuses := []struct {
+		id         string
+		style      string
+		favourites int
+		reblogs    int
+	}{
+		{"1", "new post", 2, 0},
+		{"2", "new post", 4, 2},
+		{"3", "thoughts", 10, 5},
+		{"4", "update", -1, -1},
+	}
+	for _, status := range statuses {
+		if err := RecordStatus(status.id, "https://example.social/@bot/"+status.id, "https://example.com/"+status.id, status.style); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if status.favourites < 0 {
+			// never checked, so left out of the averages
+			continue
+		}
+		if err := UpdateStatusEngagement(status.id, status.favourites, status.reblogs, 0); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	ids, err := RecentStatusIDs(time.Now().Add(-time.Hour))
+	if err != nil || len(ids) != len(statuses) {
+		t.Errorf("Expected %d recent statuses, got %v, %v", len(statuses), ids, err)
+	}
+	for id, expected := range map[string]bool{"3": true, "5": false} {
+		if has, err := HasStatus(id); has != expected || err != nil {
+			t.Errorf("Expected HasStatus(%s) to be %v, got %v, %v", id, expected, has, err)
+		}
+	}
+
+	recent, err := RecentStatuses(2)
+	if err != nil || len(recent) != 2 {
+		t.Fatalf("Expected 2 recent statuses, got %v, %v", recent, err)
+	}
+	if recent[0].URL != "https://example.social/@bot/"+recent[0].ID || recent[0].Link != "https://example.com/"+recent[0].ID {
+		t.Errorf("Unexpected status: %+v", recent[0])
+	}
+
+	if all, err := ListStatuses(); err != nil || len(all) != len(statuses) {
+		t.Errorf("Expected %d statuses, got %v, %v", len(statuses), all, err)
+	}
+
+	styles, err := EngagementByStyle()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(styles) != 2 {
+		t.Fatalf("Expected 2 styles, got %v", styles)
+	}
+	if styles[0].Style != "thoughts" || styles[1].Style != "new post" {
+		t.Errorf("Expected thoughts to perform best, got %v", styles)
+	}
+	if styles[1].Statuses != 2 || styles[1].Favourites != 3 || styles[1].Reblogs != 1 {
+		t.Errorf("Unexpected averages for new posts: %+v", styles[1])
+	}
+
+	checked, err := CheckedStatusEngagement()
+	if err != nil || len(checked) != 3 {
+		t.Fatalf("Expected 3 checked statuses, got %v, %v", checked, err)
+	}
+	total := 0
+	for _, status := range checked {
+		total += status.Engagement
+	}
+	if total != 23 {
+		t.Errorf("Expected a total engagement of 23, got %d", total)
+	}
+}
+
+// Test recording follower counts
+func TestFollowerCounts(t *testing.T) {
+	InitDB()
+	defer CloseDB()
+	defer func() { _, _ = db.Exec(`DELETE FROM follower_counts`) }()
+
+	if err := RecordFollowerCount(42); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	counts, err := FollowerCounts(time.Now().Add(-time.Hour))
+	if err != nil || len(counts) != 1 || counts[0].Followers != 42 {
+		t.Errorf("Expected a single count of 42, got %v, %v", counts, err)
+	}
+	if counts, _ := FollowerCounts(time.Now().Add(time.Hour)); len(counts) != 0 {
+		t.Errorf("Expected no counts in the future, got %v", counts)
+	}
+}
+
+// Test the engagement received since the last digest
+func TestEngagementSinceDigest(t *testing.T) {
+	InitDB()
+	defer CloseDB()
+	defer func() { _, _ = db.Exec(`DELETE FROM statuses`) }()
+
+	for _, id := range []string{"1", "2", "3"} {
+		if err := RecordStatus(id, "https://example.social/@bot/"+id, "https://example.com/"+id, "new post"); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	if err := UpdateStatusEngagement("1", 2, 1, 0); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := MarkEngagementDigested(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// a withdrawn favourite and a new reply, a new status's first engagement, and nothing new
+	updates := map[string][3]int{"1": {1, 1, 1}, "2": {3, 2, 0}, "3": {0, 0, 0}}
+	for id, counts := range updates {
+		if err := UpdateStatusEngagement(id, counts[0], counts[1], counts[2]); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	expected := []EngagementChange{
+		{StatusID: "2", Link: "https://example.com/2", Favourites: 3, Reblogs: 2},
+		{StatusID: "1", Link: "https://example.com/1", Replies: 1},
+	}
+	changes, err := EngagementSinceDigest()
+	if err != nil || !reflect.DeepEqual(changes, expected) {
+		t.Errorf("Expected %+v, got %+v (%v)", expected, changes, err)
+	}
+
+	if err := MarkEngagementDigested(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if changes, err := EngagementSinceDigest(); len(changes) != 0 || err != nil {
+		t.Errorf("Expected no engagement after the digest, got %+v (%v)", changes, err)
+	}
+}
+
+// Test looking up a post's statuses and marking them deleted
+func TestLinkStatuses(t *testing.T) {
+	InitDB()
+	defer CloseDB()
+	defer func() { _, _ = db.Exec(`DELETE FROM statuses`) }()
+
+	link := "https://example.com/retracted"
+	for _, id := range []string{"11", "12"} {
+		if err := RecordStatus(id, "https://example.social/@bot/"+id, link, "new post"); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	if err := RecordStatus("13", "", "https://example.com/other", "new post"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	statuses, err := LinkStatuses(link)
+	if err != nil || len(statuses) != 2 {
+		t.Fatalf("Expected the post's 2 statuses, got %+v, %v", statuses, err)
+	}
+	if err := MarkStatusDeleted("11"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if statuses, err := LinkStatuses(link); err != nil || len(statuses) != 1 || statuses[0].ID != "12" {
+		t.Errorf("Expected only status 12 left, got %+v, %v", statuses, err)
+	}
+	if ids, err := RecentStatusIDs(time.Now().Add(-time.Hour)); err != nil || len(ids) != 2 {
+		t.Errorf("Expected the deleted status to be left out of the recent ones, got %v, %v", ids, err)
+	}
+}
@@ -0,0 +1,83 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+)
+
+// FeedHealth tracks when a feed last produced new items and last changed its build date
+type FeedHealth struct {
+	FeedURL          string
+	LastNewItem      time.Time
+	LastBuildDate    string
+	LastBuildChanged time.Time
+	SilentAlerted    bool
+	BuildAlerted     bool
+}
+
+// createFeedHealthTable creates the feed_health table if it does not exist
+func createFeedHealthTable() error {
+	query := `CREATE TABLE IF NOT EXISTS feed_health (
+		feed_url TEXT PRIMARY KEY,
+		last_new_item TEXT,
+		last_build_date TEXT,
+		last_build_changed TEXT,
+		silent_alerted INTEGER DEFAULT 0,
+		build_alerted INTEGER DEFAULT 0
+	)`
+	_, err := db.Exec(query)
+	return err
+}
+
+// RecordFeedCheck updates a feed's health after it has been polled. A feed seen for the first
+// time is treated as healthy from now on; producing new items or changing its build date
+// resets the respective timestamp and alert.
+func RecordFeedCheck(feedURL string, newItems int, lastBuildDate string) error {
+	now := time.Now().Format(time.RFC3339)
+
+	query := `INSERT OR IGNORE INTO feed_health(feed_url, last_new_item, last_build_date, last_build_changed) VALUES (?, ?, ?, ?)`
+	if _, err := db.Exec(query, feedURL, now, lastBuildDate, now); err != nil {
+		return err
+	}
+
+	if newItems > 0 {
+		query = `UPDATE feed_health SET last_new_item = ?, silent_alerted = 0 WHERE feed_url = ?`
+		if _, err := db.Exec(query, now, feedURL); err != nil {
+			return err
+		}
+	}
+
+	query = `UPDATE feed_health SET last_build_date = ?, last_build_changed = ?, build_alerted = 0 WHERE feed_url = ? AND last_build_date != ?`
+	_, err := db.Exec(query, lastBuildDate, now, feedURL, lastBuildDate)
+	return err
+}
+
+// GetFeedHealth returns the recorded health of a feed, or nil if it has never been checked
+func GetFeedHealth(feedURL string) (*FeedHealth, error) {
+	query := `SELECT last_new_item, last_build_date, last_build_changed, silent_alerted, build_alerted FROM feed_health WHERE feed_url = ?`
+	row := db.QueryRow(query, feedURL)
+
+	health := FeedHealth{FeedURL: feedURL}
+	var lastNewItem, lastBuildChanged string
+	err := row.Scan(&lastNewItem, &health.LastBuildDate, &lastBuildChanged, &health.SilentAlerted, &health.BuildAlerted)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if health.LastNewItem, err = time.Parse(time.RFC3339, lastNewItem); err != nil {
+		return nil, err
+	}
+	if health.LastBuildChanged, err = time.Parse(time.RFC3339, lastBuildChanged); err != nil {
+		return nil, err
+	}
+	return &health, nil
+}
+
+// SetFeedAlerted records that a silent-feed and/or stuck-build-date alert was sent for a feed
+func SetFeedAlerted(feedURL string, silent bool, build bool) error {
+	query := `UPDATE feed_health SET silent_alerted = silent_alerted OR ?, build_alerted = build_alerted OR ? WHERE feed_url = ?`
+	_, err := db.Exec(query, silent, build, feedURL)
+	return err
+}
@@ -0,0 +1,73 @@
+package db
+
+import (
+	"testing"
+)
+
+// Test recording feed checks updates health timestamps and resets alerts
+func TestRecordFeedCheck(t *testing.T) {
+	InitDB()
+	defer CloseDB()
+
+	feedURL := "https://example.com/health.xml"
+	if err := RecordFeedCheck(feedURL, 0, "Mon, 01 Jan 2024 00:00:00 GMT"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	health, err := GetFeedHealth(feedURL)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if health == nil {
+		t.Fatalf("Expected feed health to be recorded")
+	}
+	if health.LastBuildDate != "Mon, 01 Jan 2024 00:00:00 GMT" {
+		t.Errorf("Expected last build date to be recorded, got '%s'", health.LastBuildDate)
+	}
+
+	if err := SetFeedAlerted(feedURL, true, true); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	health, _ = GetFeedHealth(feedURL)
+	if !health.SilentAlerted || !health.BuildAlerted {
+		t.Errorf("Expected both alerts to be recorded")
+	}
+
+	// a new item resets the silent alert but not the build alert
+	if err := RecordFeedCheck(feedURL, 1, "Mon, 01 Jan 2024 00:00:00 GMT"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	health, _ = GetFeedHealth(feedURL)
+	if health.SilentAlerted {
+		t.Errorf("Expected silent alert to be reset")
+	}
+	if !health.BuildAlerted {
+		t.Errorf("Expected build alert to remain")
+	}
+
+	// a changed build date resets the build alert
+	if err := RecordFeedCheck(feedURL, 0, "Tue, 02 Jan 2024 00:00:00 GMT"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	health, _ = GetFeedHealth(feedURL)
+	if health.BuildAlerted {
+		t.Errorf("Expected build alert to be reset")
+	}
+	if health.LastBuildDate != "Tue, 02 Jan 2024 00:00:00 GMT" {
+		t.Errorf("Expected last build date to be updated, got '%s'", health.LastBuildDate)
+	}
+}
+
+// Test reading health of a feed which was never checked
+func TestGetFeedHealth_Unknown(t *testing.T) {
+	InitDB()
+	defer CloseDB()
+
+	health, err := GetFeedHealth("https://example.com/unknown.xml")
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if health != nil {
+		t.Errorf("Expected no feed health, got %v", health)
+	}
+}
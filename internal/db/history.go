@@ -0,0 +1,165 @@
+package db
+
+import (
+	"sort"
+	"time"
+)
+
+// maxErrorLogEntries bounds the size of the error_log table
+const maxErrorLogEntries = 1000
+
+// TootedPost is a post which has been announced on Mastodon
+type TootedPost struct {
+	Link      string
+	Timestamp time.Time
+}
+
+// ErrorLogEntry is a recorded failure, e.g. a feed which could not be fetched
+type ErrorLogEntry struct {
+	Source    string
+	Message   string
+	Timestamp time.Time
+}
+
+// createErrorLogTable creates the error_log table if it does not exist
+func createErrorLogTable() error {
+	query := `CREATE TABLE IF NOT EXISTS error_log (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		source TEXT,
+		message TEXT,
+		timestamp TEXT
+	)`
+	_, err := db.Exec(query)
+	return err
+}
+
+// RecentTootedPosts returns the most recently tooted posts, newest first
+func RecentTootedPosts(limit int) ([]TootedPost, error) {
+	rows, err := db.Query(`SELECT link, timestamp FROM tooted_posts ORDER BY timestamp DESC LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var posts []TootedPost
+	for rows.Next() {
+		var post TootedPost
+		var timestamp string
+		if err := rows.Scan(&post.Link, &timestamp); err != nil {
+			return nil, err
+		}
+		post.Timestamp, _ = time.Parse(time.RFC3339, timestamp)
+		posts = append(posts, post)
+	}
+	return posts, rows.Err()
+}
+
+// TootedPostsBetween returns the posts last tooted after start and up to end, oldest first
+func TootedPostsBetween(start time.Time, end time.Time) ([]TootedPost, error) {
+	rows, err := db.Query(`SELECT link, timestamp FROM tooted_posts`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var posts []TootedPost
+	for rows.Next() {
+		var post TootedPost
+		var timestamp string
+		if err := rows.Scan(&post.Link, &timestamp); err != nil {
+			return nil, err
+		}
+		// timestamps are compared as times, they weren't always stored in UTC
+		if post.Timestamp, err = time.Parse(time.RFC3339, timestamp); err != nil {
+			continue
+		}
+		if post.Timestamp.After(start) && !post.Timestamp.After(end) {
+			posts = append(posts, post)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	sort.Slice(posts, func(i, j int) bool { return posts[i].Timestamp.Before(posts[j].Timestamp) })
+	return posts, nil
+}
+
+// ForgetTootedPost removes a post from the tooted posts, so it is announced again if still in the feed
+func ForgetTootedPost(link string) error {
+	_, err := db.Exec(`DELETE FROM tooted_posts WHERE link = ?`, link)
+	return err
+}
+
+// RecordError stores a failure in the error log, discarding the oldest entries beyond maxErrorLogEntries
+func RecordError(source string, message string) error {
+	message, err := encryptValue(message)
+	if err != nil {
+		return err
+	}
+	query := `INSERT INTO error_log(source, message, timestamp) VALUES (?, ?, ?)`
+	if _, err := db.Exec(query, source, message, time.Now().Format(time.RFC3339)); err != nil {
+		return err
+	}
+
+	query = `DELETE FROM error_log WHERE id <= (SELECT MAX(id) FROM error_log) - ?`
+	_, err = db.Exec(query, maxErrorLogEntries)
+	return err
+}
+
+// RecentErrors returns the most recently recorded failures, newest first
+func RecentErrors(limit int) ([]ErrorLogEntry, error) {
+	rows, err := db.Query(`SELECT source, message, timestamp FROM error_log ORDER BY id DESC LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []ErrorLogEntry
+	for rows.Next() {
+		var entry ErrorLogEntry
+		var timestamp string
+		if err := rows.Scan(&entry.Source, &entry.Message, &timestamp); err != nil {
+			return nil, err
+		}
+		if entry.Message, err = decryptValue(entry.Message); err != nil {
+			return nil, err
+		}
+		entry.Timestamp, _ = time.Parse(time.RFC3339, timestamp)
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// ListFeedHealth returns the recorded health of every feed which has been checked
+func ListFeedHealth() ([]FeedHealth, error) {
+	rows, err := db.Query(`SELECT feed_url FROM feed_health ORDER BY feed_url`)
+	if err != nil {
+		return nil, err
+	}
+
+	var feedURLs []string
+	for rows.Next() {
+		var feedURL string
+		if err := rows.Scan(&feedURL); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		feedURLs = append(feedURLs, feedURL)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var feeds []FeedHealth
+	for _, feedURL := range feedURLs {
+		health, err := GetFeedHealth(feedURL)
+		if err != nil {
+			return nil, err
+		}
+		if health != nil {
+			feeds = append(feeds, *health)
+		}
+	}
+	return feeds, nil
+}
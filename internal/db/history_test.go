@@ -0,0 +1,101 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test listing and forgetting tooted posts
+func TestRecentTootedPosts(t *testing.T) {
+	InitDB()
+	defer CloseDB()
+
+	if err := StoreTootedPost("https://example.com/recent", "content"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	posts, err := RecentTootedPosts(100)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	found := false
+	for _, post := range posts {
+		found = found || post.Link == "https://example.com/recent"
+	}
+	if !found {
+		t.Errorf("Expected recent post to be listed, got %v", posts)
+	}
+
+	if err := ForgetTootedPost("https://example.com/recent"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	exists, _, err := HasPostChanged("https://example.com/recent", "content")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if exists {
+		t.Errorf("Expected post to be forgotten")
+	}
+}
+
+// Test selecting tooted posts within a period
+func TestTootedPostsBetween(t *testing.T) {
+	InitDB()
+	defer CloseDB()
+	defer func() {
+		_, _ = db.Exec(`DELETE FROM tooted_posts WHERE link LIKE 'https://example.com/between/%'`)
+	}()
+
+	now := time.Now()
+	posts := map[string]time.Time{
+		"https://example.com/between/too-old": now.AddDate(0, 0, -8),
+		"https://example.com/between/first":   now.AddDate(0, 0, -6),
+		"https://example.com/between/second":  now.Add(-time.Hour).In(time.FixedZone("CET", 3600)),
+		"https://example.com/between/later":   now.Add(time.Hour),
+	}
+	for link, timestamp := range posts {
+		if _, err := db.Exec(`INSERT OR REPLACE INTO tooted_posts(link, content_hash, timestamp) VALUES (?, '', ?)`, link, timestamp.Format(time.RFC3339)); err != nil {
+			t.Fatalf("Failed to insert post: %v", err)
+		}
+	}
+
+	found, err := TootedPostsBetween(now.AddDate(0, 0, -7), now)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// other tests share the database
+	var between []TootedPost
+	for _, post := range found {
+		if strings.HasPrefix(post.Link, "https://example.com/between/") {
+			between = append(between, post)
+		}
+	}
+	if len(between) != 2 || between[0].Link != "https://example.com/between/first" || between[1].Link != "https://example.com/between/second" {
+		t.Errorf("Expected the first and second posts in order, got %v", between)
+	}
+}
+
+// Test recording errors keeps the newest entries
+func TestRecordError(t *testing.T) {
+	InitDB()
+	defer CloseDB()
+
+	for i := 0; i < maxErrorLogEntries+5; i++ {
+		if err := RecordError("feed", fmt.Sprintf("error %d", i)); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	entries, err := RecentErrors(maxErrorLogEntries * 2)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(entries) != maxErrorLogEntries {
+		t.Errorf("Expected %d entries, got %d", maxErrorLogEntries, len(entries))
+	}
+	if entries[0].Message != fmt.Sprintf("error %d", maxErrorLogEntries+4) {
+		t.Errorf("Expected newest entry first, got '%s'", entries[0].Message)
+	}
+}
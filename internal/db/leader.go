@@ -0,0 +1,40 @@
+package db
+
+import (
+	"time"
+)
+
+// createLeaderLockTable creates the leader_lock table if it does not exist
+func createLeaderLockTable() error {
+	query := `CREATE TABLE IF NOT EXISTS leader_lock (
+		name TEXT PRIMARY KEY,
+		holder TEXT,
+		expires_at TEXT
+	)`
+	_, err := db.Exec(query)
+	return err
+}
+
+// TryAcquireLeader takes or renews the named leader lock for holder, unless another holder's
+// lock has not expired yet. It returns whether holder now holds the lock.
+func TryAcquireLeader(name string, holder string, duration time.Duration) (bool, error) {
+	now := time.Now().UTC()
+	expiresAt := now.Add(duration).Format(time.RFC3339)
+
+	_, err := db.Exec(`INSERT OR IGNORE INTO leader_lock(name, holder, expires_at) VALUES (?, ?, ?)`, name, holder, expiresAt)
+	if err != nil {
+		return false, err
+	}
+
+	// a single conditional update so two instances can't both take over an expired lock
+	query := `UPDATE leader_lock SET holder = ?, expires_at = ? WHERE name = ? AND (holder = ? OR expires_at < ?)`
+	result, err := db.Exec(query, holder, expiresAt, name, holder, now.Format(time.RFC3339))
+	if err != nil {
+		return false, err
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return updated == 1, nil
+}
@@ -0,0 +1,38 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+// Test only one holder gets the leader lock until it expires or is released
+func TestTryAcquireLeader(t *testing.T) {
+	InitDB()
+	t.Cleanup(CloseDB)
+	// the lock outlives the test in the shared database, release it for the next run
+	t.Cleanup(func() {
+		if _, err := db.Exec(`DELETE FROM leader_lock WHERE name = ?`, "test"); err != nil {
+			t.Errorf("Failed to release the lock: %v", err)
+		}
+	})
+
+	acquire := func(holder string, duration time.Duration, expected bool) {
+		t.Helper()
+		held, err := TryAcquireLeader("test", holder, duration)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if held != expected {
+			t.Fatalf("Expected %s holding the lock to be %v", holder, expected)
+		}
+	}
+
+	acquire("a", time.Minute, true)
+	acquire("b", time.Minute, false)
+	acquire("a", time.Minute, true)
+
+	// an expired lock is taken over
+	acquire("a", -2*time.Second, true)
+	acquire("b", time.Minute, true)
+	acquire("a", time.Minute, false)
+}
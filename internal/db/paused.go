@@ -0,0 +1,52 @@
+package db
+
+import (
+	"time"
+)
+
+// createPausedFeedsTable creates the paused_feeds table if it does not exist
+func createPausedFeedsTable() error {
+	query := `CREATE TABLE IF NOT EXISTS paused_feeds (
+		feed_url TEXT PRIMARY KEY,
+		paused_by TEXT,
+		timestamp TEXT
+	)`
+	_, err := db.Exec(query)
+	return err
+}
+
+// PauseFeed stops a feed from being polled until it is resumed, recording who paused it
+func PauseFeed(feedURL string, pausedBy string) error {
+	query := `INSERT OR REPLACE INTO paused_feeds(feed_url, paused_by, timestamp) VALUES (?, ?, ?)`
+	_, err := db.Exec(query, feedURL, pausedBy, time.Now().Format(time.RFC3339))
+	return err
+}
+
+// ResumeFeed polls a paused feed again, returning whether it was paused
+func ResumeFeed(feedURL string) (bool, error) {
+	result, err := db.Exec(`DELETE FROM paused_feeds WHERE feed_url = ?`, feedURL)
+	if err != nil {
+		return false, err
+	}
+	removed, err := result.RowsAffected()
+	return removed > 0, err
+}
+
+// PausedFeeds returns the URLs of the paused feeds
+func PausedFeeds() (map[string]bool, error) {
+	rows, err := db.Query(`SELECT feed_url FROM paused_feeds`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	paused := make(map[string]bool)
+	for rows.Next() {
+		var feedURL string
+		if err := rows.Scan(&feedURL); err != nil {
+			return nil, err
+		}
+		paused[feedURL] = true
+	}
+	return paused, rows.Err()
+}
@@ -0,0 +1,151 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// Reasons a post can be held in the pending queue
+const (
+	PendingEmbargo   = "embargo"
+	PendingApproval  = "approval"
+	PendingApproved  = "approved"
+	PendingRetry     = "retry"
+	PendingTiming    = "timing"
+	PendingPushed    = "pushed"
+	PendingBudget    = "budget"
+	PendingRateLimit = "rate limit"
+)
+
+// PendingPost is a post held in the pending queue until it may be announced
+type PendingPost struct {
+	ID        int64
+	Item      rss.RSSItem
+	NotBefore time.Time
+	Reason    string
+}
+
+// createPendingPostsTable creates the pending_posts table if it does not exist
+func createPendingPostsTable() error {
+	query := `CREATE TABLE IF NOT EXISTS pending_posts (
+		link TEXT PRIMARY KEY,
+		item TEXT,
+		not_before TEXT,
+		reason TEXT,
+		timestamp TEXT
+	)`
+	_, err := db.Exec(query)
+	return err
+}
+
+// QueuePendingPost holds a post in the pending queue until notBefore, replacing any
+// previously queued version of the same post
+func QueuePendingPost(post rss.RSSItem, notBefore time.Time, reason string) error {
+	data, err := json.Marshal(post)
+	if err != nil {
+		return err
+	}
+	item, err := encryptValue(string(data))
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT OR REPLACE INTO pending_posts(link, item, not_before, reason, timestamp) VALUES (?, ?, ?, ?, ?)`
+	_, err = db.Exec(query, post.Link, item, notBefore.UTC().Format(time.RFC3339), reason, time.Now().Format(time.RFC3339))
+	return err
+}
+
+// DuePendingPosts returns the queued posts which may be announced at the given time, oldest first
+// and in the order they were queued if equally old. Posts awaiting approval are never due.
+func DuePendingPosts(now time.Time) ([]PendingPost, error) {
+	query := `SELECT rowid, item, not_before, reason FROM pending_posts WHERE not_before <= ? AND reason != ? ORDER BY not_before, rowid`
+	return queryPendingPosts(query, now.UTC().Format(time.RFC3339), PendingApproval)
+}
+
+// ListPendingPosts returns all queued posts, oldest first
+func ListPendingPosts() ([]PendingPost, error) {
+	return queryPendingPosts(`SELECT rowid, item, not_before, reason FROM pending_posts ORDER BY not_before`)
+}
+
+// GetPendingPost returns the queued post with the given queue ID or link, or nil if there is none
+func GetPendingPost(idOrLink string) (*PendingPost, error) {
+	posts, err := queryPendingPosts(`SELECT rowid, item, not_before, reason FROM pending_posts WHERE CAST(rowid AS TEXT) = ? OR link = ?`, idOrLink, idOrLink)
+	if err != nil || len(posts) == 0 {
+		return nil, err
+	}
+	return &posts[0], nil
+}
+
+// SetPendingReason changes why a queued post is being held, e.g. from awaiting approval to approved
+func SetPendingReason(link string, reason string) error {
+	_, err := db.Exec(`UPDATE pending_posts SET reason = ? WHERE link = ?`, reason, link)
+	return err
+}
+
+// NextPendingPostTime returns when the next queued post becomes due, and false if no queued
+// post can become due (the queue is empty or only holds posts awaiting approval)
+func NextPendingPostTime() (time.Time, bool, error) {
+	var notBefore sql.NullString
+	if err := db.QueryRow(`SELECT MIN(not_before) FROM pending_posts WHERE reason != ?`, PendingApproval).Scan(&notBefore); err != nil {
+		return time.Time{}, false, err
+	}
+	if !notBefore.Valid {
+		return time.Time{}, false, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, notBefore.String)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return t, true, nil
+}
+
+// RetryPendingPost makes a queued post due immediately, approving it if it was awaiting approval
+func RetryPendingPost(link string) error {
+	query := `UPDATE pending_posts SET not_before = ?, reason = CASE reason WHEN ? THEN ? ELSE reason END WHERE link = ?`
+	_, err := db.Exec(query, time.Now().UTC().Format(time.RFC3339), PendingApproval, PendingApproved, link)
+	return err
+}
+
+// DeferPendingPost holds a queued post until a later time, keeping why it is held
+func DeferPendingPost(link string, notBefore time.Time) error {
+	_, err := db.Exec(`UPDATE pending_posts SET not_before = ? WHERE link = ?`, notBefore.UTC().Format(time.RFC3339), link)
+	return err
+}
+
+// RemovePendingPost removes a post from the pending queue
+func RemovePendingPost(link string) error {
+	_, err := db.Exec(`DELETE FROM pending_posts WHERE link = ?`, link)
+	return err
+}
+
+func queryPendingPosts(query string, args ...interface{}) ([]PendingPost, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var posts []PendingPost
+	for rows.Next() {
+		var item, notBefore string
+		var pending PendingPost
+		if err := rows.Scan(&pending.ID, &item, &notBefore, &pending.Reason); err != nil {
+			return nil, err
+		}
+		if item, err = decryptValue(item); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(item), &pending.Item); err != nil {
+			return nil, err
+		}
+		if pending.NotBefore, err = time.Parse(time.RFC3339, notBefore); err != nil {
+			return nil, err
+		}
+		posts = append(posts, pending)
+	}
+	return posts, rows.Err()
+}
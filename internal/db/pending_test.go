@@ -0,0 +1,112 @@
+package db
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// Test queueing posts and retrieving those which are due
+func TestPendingPosts(t *testing.T) {
+	InitDB()
+	defer CloseDB()
+
+	now := time.Now()
+	soon := rss.RSSItem{Title: "Soon", Link: "https://example.com/soon"}
+	later := rss.RSSItem{Title: "Later", Link: "https://example.com/later"}
+
+	if err := QueuePendingPost(later, now.Add(2*time.Hour), PendingEmbargo); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := QueuePendingPost(soon, now.Add(time.Hour), PendingEmbargo); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	next, ok, err := NextPendingPostTime()
+	if err != nil || !ok {
+		t.Fatalf("Expected next pending post time, got %v, %v", ok, err)
+	}
+	if next.Unix() != now.Add(time.Hour).Unix() {
+		t.Errorf("Expected next pending post at %v, got %v", now.Add(time.Hour), next)
+	}
+
+	due, err := DuePendingPosts(now)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(due) != 0 {
+		t.Errorf("Expected no due posts, got %d", len(due))
+	}
+
+	due, err = DuePendingPosts(now.Add(90 * time.Minute))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(due) != 1 || due[0].Item.Title != "Soon" || due[0].Reason != PendingEmbargo {
+		t.Errorf("Expected only 'Soon' to be due, got %v", due)
+	}
+
+	if err := DeferPendingPost(soon.Link, now.Add(3*time.Hour)); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	due, err = DuePendingPosts(now.Add(90 * time.Minute))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(due) != 0 {
+		t.Errorf("Expected the deferred post not to be due, got %v", due)
+	}
+	due, err = DuePendingPosts(now.Add(3 * time.Hour))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(due) != 2 || due[0].Item.Title != "Later" || due[1].Reason != PendingEmbargo {
+		t.Errorf("Expected 'Later' and the deferred 'Soon' to be due, got %v", due)
+	}
+
+	for _, link := range []string{soon.Link, later.Link} {
+		if err := RemovePendingPost(link); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	if _, ok, _ := NextPendingPostTime(); ok {
+		t.Errorf("Expected pending queue to be empty")
+	}
+}
+
+// Test posts awaiting approval are only due once approved
+func TestPendingPosts_Approval(t *testing.T) {
+	InitDB()
+	defer CloseDB()
+
+	post := rss.RSSItem{Title: "Draft", Link: "https://example.com/draft"}
+	if err := QueuePendingPost(post, time.Now().Add(-time.Minute), PendingApproval); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer func() { _ = RemovePendingPost(post.Link) }()
+
+	if due, _ := DuePendingPosts(time.Now()); len(due) != 0 {
+		t.Errorf("Expected no due posts before approval, got %v", due)
+	}
+	if _, ok, _ := NextPendingPostTime(); ok {
+		t.Errorf("Expected no next pending post time before approval")
+	}
+
+	pending, err := GetPendingPost(post.Link)
+	if err != nil || pending == nil {
+		t.Fatalf("Expected pending post, got %v, %v", pending, err)
+	}
+	byID, err := GetPendingPost(fmt.Sprint(pending.ID))
+	if err != nil || byID == nil || byID.Item.Link != post.Link {
+		t.Fatalf("Expected pending post by ID, got %v, %v", byID, err)
+	}
+
+	if err := SetPendingReason(post.Link, PendingApproved); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if due, _ := DuePendingPosts(time.Now()); len(due) != 1 || due[0].Reason != PendingApproved {
+		t.Errorf("Expected approved post to be due, got %v", due)
+	}
+}
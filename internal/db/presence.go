@@ -0,0 +1,92 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+)
+
+// ItemPresence is when an item was first and last seen in its feed, and the link it was
+// first seen with
+type ItemPresence struct {
+	Link      string
+	FirstSeen time.Time
+	// LastSeen is when the item was seen before the current fetch, zero if it is new
+	LastSeen time.Time
+}
+
+// createItemPresenceTable creates the item_presence table if it does not exist
+func createItemPresenceTable() error {
+	query := `CREATE TABLE IF NOT EXISTS item_presence (
+		feed_url TEXT,
+		item_id TEXT,
+		link TEXT,
+		first_seen TEXT,
+		last_seen TEXT,
+		PRIMARY KEY (feed_url, item_id)
+	)`
+	_, err := db.Exec(query)
+	return err
+}
+
+// FeedLastSeen returns when an item of the feed was last seen, i.e. the feed's last successful
+// fetch, zero if none was seen yet
+func FeedLastSeen(feedURL string) (time.Time, error) {
+	var lastSeen sql.NullString
+	if err := db.QueryRow(`SELECT MAX(last_seen) FROM item_presence WHERE feed_url = ?`, feedURL).Scan(&lastSeen); err != nil {
+		return time.Time{}, err
+	}
+	if !lastSeen.Valid {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339Nano, lastSeen.String)
+}
+
+// RecordItemSeen records that an item, identified by its GUID (or link) within its feed, was
+// seen now, returning its presence before this fetch
+func RecordItemSeen(feedURL string, itemID string, link string, now time.Time) (ItemPresence, error) {
+	presence := ItemPresence{Link: link, FirstSeen: now}
+	var firstSeen, lastSeen string
+	err := db.QueryRow(`SELECT link, first_seen, last_seen FROM item_presence WHERE feed_url = ? AND item_id = ?`,
+		feedURL, itemID).Scan(&presence.Link, &firstSeen, &lastSeen)
+	seen := now.UTC().Format(time.RFC3339Nano)
+	if err == sql.ErrNoRows {
+		_, err = db.Exec(`INSERT INTO item_presence(feed_url, item_id, link, first_seen, last_seen) VALUES (?, ?, ?, ?, ?)`,
+			feedURL, itemID, link, seen, seen)
+		return presence, err
+	} else if err != nil {
+		return presence, err
+	}
+
+	if presence.FirstSeen, err = time.Parse(time.RFC3339Nano, firstSeen); err != nil {
+		return presence, err
+	}
+	if presence.LastSeen, err = time.Parse(time.RFC3339Nano, lastSeen); err != nil {
+		return presence, err
+	}
+	_, err = db.Exec(`UPDATE item_presence SET last_seen = ? WHERE feed_url = ? AND item_id = ?`, seen, feedURL, itemID)
+	return presence, err
+}
+
+// MissingItems returns the items of the feed which were seen in its previous fetch, at the
+// given time, but not since, i.e. those removed from the feed since then
+func MissingItems(feedURL string, previous time.Time) ([]ItemPresence, error) {
+	rows, err := db.Query(`SELECT link, first_seen, last_seen FROM item_presence WHERE feed_url = ? AND last_seen = ? ORDER BY first_seen`,
+		feedURL, previous.UTC().Format(time.RFC3339Nano))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []ItemPresence
+	for rows.Next() {
+		var item ItemPresence
+		var firstSeen, lastSeen string
+		if err := rows.Scan(&item.Link, &firstSeen, &lastSeen); err != nil {
+			return nil, err
+		}
+		item.FirstSeen, _ = time.Parse(time.RFC3339Nano, firstSeen)
+		item.LastSeen, _ = time.Parse(time.RFC3339Nano, lastSeen)
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
@@ -0,0 +1,105 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+// Table-driven test for recording when items were first and last seen in their feed
+func TestRecordItemSeen(t *testing.T) {
+	InitDB()
+	t.Cleanup(CloseDB)
+	// forget the items for the next run, which expects them to be new
+	t.Cleanup(func() {
+		if _, err := db.Exec(`DELETE FROM item_presence WHERE item_id = ?`, "guid-1"); err != nil {
+			t.Errorf("Failed to delete the items: %v", err)
+		}
+	})
+
+	start := time.Date(2024, 5, 6, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name              string
+		feedURL           string
+		itemID            string
+		link              string
+		now               time.Time
+		expectedLink      string
+		expectedFirstSeen time.Time
+		expectedLastSeen  time.Time
+	}{
+		{
+			name:    "New item",
+			feedURL: "https://example.com/presence.xml", itemID: "guid-1", link: "https://example.com/1",
+			now: start, expectedLink: "https://example.com/1", expectedFirstSeen: start,
+		},
+		{
+			name:    "Seen again under another link",
+			feedURL: "https://example.com/presence.xml", itemID: "guid-1", link: "https://example.com/1?v=2",
+			now: start.Add(time.Hour), expectedLink: "https://example.com/1", expectedFirstSeen: start, expectedLastSeen: start,
+		},
+		{
+			name:    "Seen again later",
+			feedURL: "https://example.com/presence.xml", itemID: "guid-1", link: "https://example.com/1",
+			now: start.Add(3 * time.Hour), expectedLink: "https://example.com/1", expectedFirstSeen: start, expectedLastSeen: start.Add(time.Hour),
+		},
+		{
+			name:    "Same GUID in another feed",
+			feedURL: "https://example.org/presence.xml", itemID: "guid-1", link: "https://example.org/1",
+			now: start, expectedLink: "https://example.org/1", expectedFirstSeen: start,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			presence, err := RecordItemSeen(tt.feedURL, tt.itemID, tt.link, tt.now)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if presence.Link != tt.expectedLink || !presence.FirstSeen.Equal(tt.expectedFirstSeen) || !presence.LastSeen.Equal(tt.expectedLastSeen) {
+				t.Errorf("Expected %s first seen %v last seen %v, got %+v", tt.expectedLink, tt.expectedFirstSeen, tt.expectedLastSeen, presence)
+			}
+		})
+	}
+
+	lastSeen, err := FeedLastSeen("https://example.com/presence.xml")
+	if err != nil || !lastSeen.Equal(start.Add(3*time.Hour)) {
+		t.Errorf("Expected the feed's last fetch, got %v (%v)", lastSeen, err)
+	}
+	if lastSeen, err := FeedLastSeen("https://example.net/never.xml"); err != nil || !lastSeen.IsZero() {
+		t.Errorf("Expected no fetch of an unknown feed, got %v (%v)", lastSeen, err)
+	}
+}
+
+// Test looking up the items removed from a feed since its previous fetch
+func TestMissingItems(t *testing.T) {
+	InitDB()
+	defer CloseDB()
+
+	feedURL := "https://example.com/missing.xml"
+	previous := time.Date(2024, 5, 6, 12, 0, 0, 500, time.UTC)
+	now := previous.Add(time.Hour)
+	for _, id := range []string{"kept", "removed", "gone before"} {
+		if _, err := RecordItemSeen(feedURL, id, "https://example.com/"+id, previous.Add(-time.Hour)); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	for _, id := range []string{"kept", "removed"} {
+		if _, err := RecordItemSeen(feedURL, id, "https://example.com/"+id, previous); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	if _, err := RecordItemSeen(feedURL, "kept", "https://example.com/kept", now); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	missing, err := MissingItems(feedURL, previous)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(missing) != 1 || missing[0].Link != "https://example.com/removed" || !missing[0].LastSeen.Equal(previous) {
+		t.Errorf("Expected only the item removed since the previous fetch, got %+v", missing)
+	}
+	if missing, err := MissingItems("https://example.org/other.xml", previous); err != nil || len(missing) != 0 {
+		t.Errorf("Expected no missing items of another feed, got %+v (%v)", missing, err)
+	}
+}
@@ -0,0 +1,29 @@
+package db
+
+import (
+	"time"
+)
+
+// createSeenItemsTable creates the seen_items table if it does not exist
+func createSeenItemsTable() error {
+	query := `CREATE TABLE IF NOT EXISTS seen_items (
+		link TEXT PRIMARY KEY,
+		first_seen TEXT
+	)`
+	_, err := db.Exec(query)
+	return err
+}
+
+// RecordFirstSeen returns when an item was first seen in its feed, recording now if it is new
+func RecordFirstSeen(link string) (time.Time, error) {
+	_, err := db.Exec(`INSERT OR IGNORE INTO seen_items(link, first_seen) VALUES (?, ?)`, link, time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var firstSeen string
+	if err := db.QueryRow(`SELECT first_seen FROM seen_items WHERE link = ?`, link).Scan(&firstSeen); err != nil {
+		return time.Time{}, err
+	}
+	return time.Parse(time.RFC3339, firstSeen)
+}
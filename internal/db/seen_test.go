@@ -0,0 +1,28 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+// Test the first time an item was seen is kept
+func TestRecordFirstSeen(t *testing.T) {
+	InitDB()
+	defer CloseDB()
+
+	first, err := RecordFirstSeen("https://example.com/seen")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if time.Since(first) > time.Minute {
+		t.Errorf("Expected first seen to be now, got %v", first)
+	}
+
+	again, err := RecordFirstSeen("https://example.com/seen")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !again.Equal(first) {
+		t.Errorf("Expected first seen to stay %v, got %v", first, again)
+	}
+}
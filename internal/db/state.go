@@ -0,0 +1,42 @@
+package db
+
+import (
+	"database/sql"
+)
+
+// createStateTable creates the state table, holding small values which must survive restarts
+func createStateTable() error {
+	query := `CREATE TABLE IF NOT EXISTS state (
+		key TEXT PRIMARY KEY,
+		value TEXT
+	)`
+	_, err := db.Exec(query)
+	return err
+}
+
+// GetState returns a stored value, and false if it was never set
+func GetState(key string) (string, bool, error) {
+	var value string
+	err := db.QueryRow(`SELECT value FROM state WHERE key = ?`, key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	return value, err == nil, err
+}
+
+// SetState stores a value
+func SetState(key string, value string) error {
+	_, err := db.Exec(`INSERT OR REPLACE INTO state(key, value) VALUES (?, ?)`, key, value)
+	return err
+}
+
+// DeleteState removes a stored value, returning false if it was not set, e.g. because another
+// caller removed it first
+func DeleteState(key string) (bool, error) {
+	result, err := db.Exec(`DELETE FROM state WHERE key = ?`, key)
+	if err != nil {
+		return false, err
+	}
+	deleted, err := result.RowsAffected()
+	return deleted > 0, err
+}
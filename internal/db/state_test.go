@@ -0,0 +1,33 @@
+package db
+
+import (
+	"testing"
+)
+
+// Test storing and reading state values
+func TestState(t *testing.T) {
+	InitDB()
+	defer CloseDB()
+
+	if _, ok, err := GetState("test_key"); err != nil || ok {
+		t.Errorf("Expected no value, got %v, %v", ok, err)
+	}
+	for _, value := range []string{"first", "second"} {
+		if err := SetState("test_key", value); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if got, ok, err := GetState("test_key"); err != nil || !ok || got != value {
+			t.Errorf("Expected '%s', got '%s', %v, %v", value, got, ok, err)
+		}
+	}
+
+	if deleted, err := DeleteState("test_key"); err != nil || !deleted {
+		t.Errorf("Expected the value to be deleted, got %v, %v", deleted, err)
+	}
+	if deleted, err := DeleteState("test_key"); err != nil || deleted {
+		t.Errorf("Expected nothing left to delete, got %v, %v", deleted, err)
+	}
+	if _, ok, err := GetState("test_key"); err != nil || ok {
+		t.Errorf("Expected no value after deleting, got %v, %v", ok, err)
+	}
+}
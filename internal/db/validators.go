@@ -0,0 +1,38 @@
+package db
+
+import (
+	"database/sql"
+
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// createFeedValidatorsTable creates the feed_validators table if it does not exist
+func createFeedValidatorsTable() error {
+	query := `CREATE TABLE IF NOT EXISTS feed_validators (
+		feed_url TEXT PRIMARY KEY,
+		request_url TEXT,
+		etag TEXT,
+		last_modified TEXT
+	)`
+	_, err := db.Exec(query)
+	return err
+}
+
+// GetFeedValidators returns the ETag and Last-Modified of a feed's last response, if it was
+// for the same request URL (feeds with date placeholders request a new URL every period)
+func GetFeedValidators(feedURL string, requestURL string) (rss.Validators, error) {
+	var validators rss.Validators
+	query := `SELECT etag, last_modified FROM feed_validators WHERE feed_url = ? AND request_url = ?`
+	err := db.QueryRow(query, feedURL, requestURL).Scan(&validators.ETag, &validators.LastModified)
+	if err == sql.ErrNoRows {
+		return validators, nil
+	}
+	return validators, err
+}
+
+// StoreFeedValidators stores the ETag and Last-Modified of a feed's response
+func StoreFeedValidators(feedURL string, requestURL string, validators rss.Validators) error {
+	query := `INSERT OR REPLACE INTO feed_validators(feed_url, request_url, etag, last_modified) VALUES (?, ?, ?, ?)`
+	_, err := db.Exec(query, feedURL, requestURL, validators.ETag, validators.LastModified)
+	return err
+}
@@ -0,0 +1,77 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// AuditEntry is an outbound request acting on another server, e.g. posting a status, and how
+// it went
+type AuditEntry struct {
+	Timestamp time.Time
+	// Client is the name of the client sending the request, e.g. "mastodon"
+	Client string
+	Method string
+	// Target is the host the request was sent to
+	Target string
+	// Endpoint is the path of the request, without its query, which may hold tokens
+	Endpoint string
+	// Item is the link of the feed item the request was sent for, if any
+	Item string
+	// Status is the HTTP status of the response, 0 if the request failed without one
+	Status int
+	// Outcome is the status line of the response or the error the request failed with
+	Outcome string
+}
+
+// Auditor records the outbound requests of every client, nil to not record them. Set it before
+// creating clients.
+var Auditor func(AuditEntry)
+
+type auditItemKey struct{}
+
+// WithAuditItem returns a context whose requests are audited as sent for the feed item
+func WithAuditItem(ctx context.Context, item string) context.Context {
+	return context.WithValue(ctx, auditItemKey{}, item)
+}
+
+// audited reports whether requests with the method act on the server, rather than read from it
+func audited(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// Audit passes every request of the named client acting on the server, every attempt of retried
+// ones included, to the Auditor
+func Audit(name string) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			auditor := Auditor
+			if auditor == nil || !audited(req.Method) {
+				return next.RoundTrip(req)
+			}
+
+			resp, err := next.RoundTrip(req)
+			entry := AuditEntry{
+				Timestamp: time.Now(),
+				Client:    name,
+				Method:    req.Method,
+				Target:    req.URL.Host,
+				Endpoint:  req.URL.Path,
+			}
+			entry.Item, _ = req.Context().Value(auditItemKey{}).(string)
+			if err != nil {
+				entry.Outcome = err.Error()
+			} else {
+				entry.Status = resp.StatusCode
+				entry.Outcome = resp.Status
+			}
+			auditor(entry)
+			return resp, err
+		})
+	}
+}
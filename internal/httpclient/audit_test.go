@@ -0,0 +1,64 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/toozej/rss2mastodon/internal/retry"
+)
+
+// Table-driven test for auditing the requests acting on the server, every attempt included
+func TestAudit(t *testing.T) {
+	tests := []struct {
+		name          string
+		method        string
+		item          string
+		failures      int
+		expectEntries []int
+	}{
+		{"GET not audited", "GET", "", 0, nil},
+		{"POST audited", "POST", "", 0, []int{http.StatusOK}},
+		{"POST audited for an item", "POST", "https://example.com/1", 0, []int{http.StatusOK}},
+		{"Retried DELETE audited per attempt", "DELETE", "https://example.com/1", 1, []int{http.StatusServiceUnavailable, http.StatusOK}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var entries []AuditEntry
+			Auditor = func(entry AuditEntry) { entries = append(entries, entry) }
+			defer func() { Auditor = nil }()
+
+			requests := 0
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				if requests <= tt.failures {
+					w.WriteHeader(http.StatusServiceUnavailable)
+				}
+			}))
+			defer mockServer.Close()
+
+			req, err := http.NewRequestWithContext(WithAuditItem(context.Background(), tt.item), tt.method, mockServer.URL+"/api/v1/statuses?token=secret", nil)
+			if err != nil {
+				t.Fatalf("Failed to create request: %v", err)
+			}
+			resp, err := New("test", time.Second, Retry(retry.Policy{Attempts: 2})).Do(req)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			resp.Body.Close()
+
+			if len(entries) != len(tt.expectEntries) {
+				t.Fatalf("Expected %d entries, got %+v", len(tt.expectEntries), entries)
+			}
+			for i, entry := range entries {
+				if entry.Status != tt.expectEntries[i] || entry.Client != "test" || entry.Method != tt.method ||
+					entry.Target != req.URL.Host || entry.Endpoint != "/api/v1/statuses" || entry.Item != tt.item {
+					t.Errorf("Unexpected entry %+v", entry)
+				}
+			}
+		})
+	}
+}
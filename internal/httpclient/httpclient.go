@@ -0,0 +1,70 @@
+package httpclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Middleware wraps a transport to act on every request sent through it and the response to it,
+// e.g. to log, time, retry or authenticate them
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc adapts a function to an http.RoundTripper
+type RoundTripperFunc func(req *http.Request) (*http.Response, error)
+
+// RoundTrip calls f(req)
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Chain composes middlewares onto a transport, the first one outermost, so it sees requests
+// first and responses last
+func Chain(transport http.RoundTripper, middlewares ...Middleware) http.RoundTripper {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		transport = middlewares[i](transport)
+	}
+	return transport
+}
+
+// New returns a client sending requests through the shared default transport, so clients
+// created for every call still reuse connections. Every request is logged and timed under the
+// client's name, e.g. "mastodon", then passed through the given middlewares. Every attempt is
+// audited. The timeout applies to every attempt of a retried request on its own, 0 for none.
+func New(name string, timeout time.Duration, middlewares ...Middleware) *http.Client {
+	chain := append([]Middleware{Logging(name), Metrics(name)}, middlewares...)
+	chain = append(chain, Audit(name), Timeout(timeout))
+	return &http.Client{Transport: Chain(http.DefaultTransport, chain...)}
+}
+
+// Timeout limits how long a request may take, including reading its response body
+func Timeout(timeout time.Duration) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if timeout <= 0 {
+				return next.RoundTrip(req)
+			}
+			ctx, cancel := context.WithTimeout(req.Context(), timeout)
+			resp, err := next.RoundTrip(req.WithContext(ctx))
+			if err != nil {
+				cancel()
+				return nil, err
+			}
+			resp.Body = cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+			return resp, nil
+		})
+	}
+}
+
+// cancelOnClose releases a request's timeout once its response body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
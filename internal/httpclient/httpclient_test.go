@@ -0,0 +1,79 @@
+package httpclient
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test middlewares are applied in order, the first one outermost
+func TestChain(t *testing.T) {
+	var calls []string
+	record := func(name string) Middleware {
+		return func(next http.RoundTripper) http.RoundTripper {
+			return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				calls = append(calls, name+" request")
+				resp, err := next.RoundTrip(req)
+				calls = append(calls, name+" response")
+				return resp, err
+			})
+		}
+	}
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		calls = append(calls, "transport")
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+
+	req := httptest.NewRequest("GET", "https://example.com/feed.xml", nil)
+	if _, err := Chain(transport, record("outer"), record("inner")).RoundTrip(req); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expected := "outer request, inner request, transport, inner response, outer response"
+	if got := strings.Join(calls, ", "); got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+}
+
+// Table-driven test for the timeout of requests, which covers reading their response
+func TestNew_Timeout(t *testing.T) {
+	tests := []struct {
+		name      string
+		timeout   time.Duration
+		delay     time.Duration
+		expectErr bool
+	}{
+		{"In time", time.Second, 0, false},
+		{"No timeout", 0, 50 * time.Millisecond, false},
+		{"Too slow", 20 * time.Millisecond, 200 * time.Millisecond, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.(http.Flusher).Flush()
+				select {
+				case <-time.After(tt.delay):
+				case <-r.Context().Done():
+				}
+				_, _ = w.Write([]byte("feed"))
+			}))
+			defer mockServer.Close()
+
+			resp, err := New("test", tt.timeout).Get(mockServer.URL)
+			if err == nil {
+				defer resp.Body.Close()
+				var body []byte
+				body, err = io.ReadAll(resp.Body)
+				if err == nil && string(body) != "feed" {
+					t.Errorf("Expected the response body, got %q", body)
+				}
+			}
+			if tt.expectErr != (err != nil) {
+				t.Errorf("Expected error %v, got %v", tt.expectErr, err)
+			}
+		})
+	}
+}
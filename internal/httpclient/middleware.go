@@ -0,0 +1,191 @@
+package httpclient
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/toozej/rss2mastodon/internal/metrics"
+	"github.com/toozej/rss2mastodon/internal/retry"
+)
+
+// requestURL returns the URL of a request for logs, without its query, which may hold tokens
+func requestURL(req *http.Request) string {
+	return req.URL.Scheme + "://" + req.URL.Host + req.URL.Path
+}
+
+// Logging logs every request of the named client, its response status and how long it took
+func Logging(name string) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next.RoundTrip(req)
+			if err != nil {
+				log.Debugf("%s: %s %s failed after %s: %v", name, req.Method, requestURL(req), time.Since(start).Round(time.Millisecond), err)
+				return nil, err
+			}
+			log.Debugf("%s: %s %s returned %d after %s", name, req.Method, requestURL(req), resp.StatusCode, time.Since(start).Round(time.Millisecond))
+			return resp, nil
+		})
+	}
+}
+
+// Metrics times every request of the named client, tagged with the client and response status,
+// and counts failed requests
+func Metrics(name string) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next.RoundTrip(req)
+			if err != nil {
+				metrics.Count("http.errors", 1, "client:"+name)
+				return nil, err
+			}
+			metrics.Timing("http.request", time.Since(start), "client:"+name, "status:"+strconv.Itoa(resp.StatusCode))
+			return resp, nil
+		})
+	}
+}
+
+// BearerAuth authenticates every request with a bearer token, unless it has its own
+// Authorization header
+func BearerAuth(token string) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if token == "" || req.Header.Get("Authorization") != "" {
+				return next.RoundTrip(req)
+			}
+			// transports mustn't modify the request they are given
+			req = req.Clone(req.Context())
+			req.Header.Set("Authorization", "Bearer "+token)
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// Retry sends requests which failed with a timeout, a refused or reset connection, a server
+// error or HTTP 429 again according to the policy, if that is safe: their method is idempotent or they carry an
+// Idempotency-Key header. Once out of attempts, the last response or error is returned.
+func Retry(policy retry.Policy) Middleware {
+	return retrying(policy, false)
+}
+
+// RetryAll retries requests like Retry, but regardless of their method, for requests which are
+// harmless to repeat, e.g. notifications
+func RetryAll(policy retry.Policy) Middleware {
+	return retrying(policy, true)
+}
+
+func retrying(policy retry.Policy, all bool) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			// bodies which can't be read again can't be sent again either
+			if (!all && !idempotent(req)) || (req.Body != nil && req.Body != http.NoBody && req.GetBody == nil) {
+				return next.RoundTrip(req)
+			}
+
+			var resp *http.Response
+			attempt := 0
+			err := retry.Do(policy, fmt.Sprintf("%s %s", req.Method, requestURL(req)), func() error {
+				attempt++
+				attemptReq := req
+				if attempt > 1 && req.GetBody != nil {
+					body, err := req.GetBody()
+					if err != nil {
+						return retry.Permanent(err)
+					}
+					attemptReq = req.Clone(req.Context())
+					attemptReq.Body = body
+				}
+
+				var err error
+				resp, err = next.RoundTrip(attemptReq)
+				if err != nil {
+					// the caller gave up on the request, or it won't succeed however often it is sent
+					if req.Context().Err() != nil || !retry.Transient(err) {
+						return retry.Permanent(err)
+					}
+					return err
+				}
+				if retry.RetryableStatus(resp.StatusCode) && attempt < policy.Attempts {
+					_, _ = io.Copy(io.Discard, resp.Body)
+					resp.Body.Close()
+					return fmt.Errorf("unexpected HTTP status: %d", resp.StatusCode)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+			return resp, nil
+		})
+	}
+}
+
+// idempotent reports whether sending a request twice has the same effect as sending it once
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case "GET", "HEAD", "OPTIONS", "TRACE", "PUT", "DELETE":
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != ""
+}
+
+// Limiter spaces requests to the same host at least an interval apart, shared by the clients
+// rate limited by it
+type Limiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     map[string]time.Time
+}
+
+// NewLimiter returns a limiter spacing requests to a host interval apart, 0 for no limit
+func NewLimiter(interval time.Duration) *Limiter {
+	return &Limiter{interval: interval, next: map[string]time.Time{}}
+}
+
+// SetInterval changes how far apart requests to the same host are spaced
+func (l *Limiter) SetInterval(interval time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.interval = interval
+}
+
+// reserve returns how long a request to the host has to wait for its turn, and takes it
+func (l *Limiter) reserve(host string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.interval <= 0 {
+		return 0
+	}
+	turn := now
+	if next := l.next[host]; next.After(now) {
+		turn = next
+	}
+	l.next[host] = turn.Add(l.interval)
+	return turn.Sub(now)
+}
+
+// RateLimit holds requests until the limiter gives their host's next turn
+func RateLimit(limiter *Limiter) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if wait := limiter.reserve(req.URL.Host, time.Now()); wait > 0 {
+				log.Debugf("Rate limiting %s, waiting %s", req.URL.Host, wait.Round(time.Millisecond))
+				timer := time.NewTimer(wait)
+				defer timer.Stop()
+				select {
+				case <-timer.C:
+				case <-req.Context().Done():
+					return nil, req.Context().Err()
+				}
+			}
+			return next.RoundTrip(req)
+		})
+	}
+}
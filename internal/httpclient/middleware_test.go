@@ -0,0 +1,141 @@
+package httpclient
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/toozej/rss2mastodon/internal/retry"
+)
+
+// Table-driven test for retrying failed requests which are safe to send again
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name           string
+		middleware     Middleware
+		method         string
+		idempotencyKey string
+		failures       int
+		failStatus     int
+		expectRequests int
+		expectStatus   int
+	}{
+		{"GET retried", Retry(retry.Policy{Attempts: 3}), "GET", "", 2, http.StatusServiceUnavailable, 3, http.StatusOK},
+		{"Rate limited retried", Retry(retry.Policy{Attempts: 3}), "GET", "", 1, http.StatusTooManyRequests, 2, http.StatusOK},
+		{"Out of attempts", Retry(retry.Policy{Attempts: 3}), "GET", "", 5, http.StatusBadGateway, 3, http.StatusBadGateway},
+		{"Client error not retried", Retry(retry.Policy{Attempts: 3}), "GET", "", 5, http.StatusNotFound, 1, http.StatusNotFound},
+		{"POST not retried", Retry(retry.Policy{Attempts: 3}), "POST", "", 2, http.StatusServiceUnavailable, 1, http.StatusServiceUnavailable},
+		{"POST with idempotency key retried", Retry(retry.Policy{Attempts: 3}), "POST", "key", 2, http.StatusServiceUnavailable, 3, http.StatusOK},
+		{"POST retried by RetryAll", RetryAll(retry.Policy{Attempts: 3}), "POST", "", 2, http.StatusServiceUnavailable, 3, http.StatusOK},
+		{"Zero policy", Retry(retry.Policy{}), "GET", "", 2, http.StatusServiceUnavailable, 1, http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var bodies []string
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				bodies = append(bodies, string(body))
+				if len(bodies) <= tt.failures {
+					w.WriteHeader(tt.failStatus)
+				}
+			}))
+			defer mockServer.Close()
+
+			req, err := http.NewRequest(tt.method, mockServer.URL, strings.NewReader("status=Hello"))
+			if err != nil {
+				t.Fatalf("Failed to create request: %v", err)
+			}
+			if tt.idempotencyKey != "" {
+				req.Header.Set("Idempotency-Key", tt.idempotencyKey)
+			}
+			resp, err := New("test", time.Second, tt.middleware).Do(req)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.expectStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectStatus, resp.StatusCode)
+			}
+			if len(bodies) != tt.expectRequests {
+				t.Errorf("Expected %d requests, got %d", tt.expectRequests, len(bodies))
+			}
+			for _, body := range bodies {
+				if body != "status=Hello" {
+					t.Errorf("Expected every attempt to send the body, got %q", body)
+				}
+			}
+		})
+	}
+}
+
+// Table-driven test for authenticating requests with a bearer token
+func TestBearerAuth(t *testing.T) {
+	tests := []struct {
+		name          string
+		token         string
+		authorization string
+		expected      string
+	}{
+		{"Token", "fake-token", "", "Bearer fake-token"},
+		{"No token", "", "", ""},
+		{"Own authorization", "fake-token", "Bearer other-token", "Bearer other-token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.Header.Get("Authorization")
+			}))
+			defer mockServer.Close()
+
+			req, _ := http.NewRequest("GET", mockServer.URL, nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			resp, err := New("test", time.Second, BearerAuth(tt.token)).Do(req)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			resp.Body.Close()
+			if got != tt.expected {
+				t.Errorf("Expected Authorization %q, got %q", tt.expected, got)
+			}
+			if tt.authorization == "" && req.Header.Get("Authorization") != "" {
+				t.Errorf("Expected the caller's request to be left as is")
+			}
+		})
+	}
+}
+
+// Table-driven test for spacing requests to the same host
+func TestLimiter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		interval time.Duration
+		host     string
+		at       time.Time
+		expected time.Duration
+	}{
+		{"First request", 2 * time.Second, "example.com", now, 0},
+		{"Same host", 2 * time.Second, "example.com", now, 2 * time.Second},
+		{"Queued behind", 2 * time.Second, "example.com", now.Add(time.Second), 3 * time.Second},
+		{"Other host", 2 * time.Second, "example.org", now, 0},
+		{"After the interval", 2 * time.Second, "example.org", now.Add(5 * time.Second), 0},
+		{"No limit", 0, "example.com", now, 0},
+	}
+
+	limiter := NewLimiter(0)
+	for _, tt := range tests {
+		limiter.SetInterval(tt.interval)
+		if got := limiter.reserve(tt.host, tt.at); got != tt.expected {
+			t.Errorf("%s: expected to wait %v, got %v", tt.name, tt.expected, got)
+		}
+	}
+}
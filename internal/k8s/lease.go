@@ -0,0 +1,224 @@
+package k8s
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// serviceAccountDir is where Kubernetes mounts the pod's service account credentials
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// microTimeFormat is the format of Kubernetes MicroTime fields
+const microTimeFormat = "2006-01-02T15:04:05.000000Z07:00"
+
+// Lease is a coordination.k8s.io/v1 Lease used as a lock, so only one replica posts
+type Lease struct {
+	Name      string
+	Namespace string
+	// Identity is this replica's holder identity, usually the pod name
+	Identity string
+	Duration time.Duration
+	// Labels are set on the Lease when it is created
+	Labels map[string]string
+
+	apiURL string
+	token  string
+	client *http.Client
+}
+
+// leaseObject is the subset of the Lease resource rss2mastodon uses
+type leaseObject struct {
+	APIVersion string        `json:"apiVersion"`
+	Kind       string        `json:"kind"`
+	Metadata   leaseMetadata `json:"metadata"`
+	Spec       leaseSpec     `json:"spec"`
+}
+
+type leaseMetadata struct {
+	Name            string            `json:"name"`
+	Namespace       string            `json:"namespace"`
+	ResourceVersion string            `json:"resourceVersion,omitempty"`
+	Labels          map[string]string `json:"labels,omitempty"`
+}
+
+type leaseSpec struct {
+	HolderIdentity       string `json:"holderIdentity,omitempty"`
+	LeaseDurationSeconds int    `json:"leaseDurationSeconds,omitempty"`
+	AcquireTime          string `json:"acquireTime,omitempty"`
+	RenewTime            string `json:"renewTime,omitempty"`
+	LeaseTransitions     int    `json:"leaseTransitions,omitempty"`
+}
+
+// NewInClusterLease returns a Lease talking to the Kubernetes API with the pod's service account.
+// The namespace defaults to the pod's own namespace.
+func NewInClusterLease(name string, namespace string, identity string, duration time.Duration) (*Lease, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, fmt.Errorf("not running in a Kubernetes cluster")
+	}
+
+	token, err := os.ReadFile(serviceAccountDir + "/token")
+	if err != nil {
+		return nil, fmt.Errorf("reading service account token: %w", err)
+	}
+	ca, err := os.ReadFile(serviceAccountDir + "/ca.crt")
+	if err != nil {
+		return nil, fmt.Errorf("reading service account CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("invalid service account CA")
+	}
+
+	if namespace == "" {
+		ns, err := os.ReadFile(serviceAccountDir + "/namespace")
+		if err != nil {
+			return nil, fmt.Errorf("reading service account namespace: %w", err)
+		}
+		namespace = strings.TrimSpace(string(ns))
+	}
+
+	return &Lease{
+		Name:      name,
+		Namespace: namespace,
+		Identity:  identity,
+		Duration:  duration,
+		apiURL:    "https://" + net.JoinHostPort(host, port),
+		token:     strings.TrimSpace(string(token)),
+		client: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}},
+		},
+	}, nil
+}
+
+// TryAcquire creates or renews the Lease for this replica, or takes it over once the current
+// holder has stopped renewing it. It returns whether this replica holds the Lease.
+func (l *Lease) TryAcquire() (bool, error) {
+	now := time.Now().UTC()
+	endpoint := fmt.Sprintf("%s/apis/coordination.k8s.io/v1/namespaces/%s/leases", l.apiURL, l.Namespace)
+
+	current, status, err := l.do(http.MethodGet, endpoint+"/"+l.Name, nil)
+	if err != nil {
+		return false, err
+	}
+
+	if status == http.StatusNotFound {
+		lease := leaseObject{
+			APIVersion: "coordination.k8s.io/v1",
+			Kind:       "Lease",
+			Metadata:   leaseMetadata{Name: l.Name, Namespace: l.Namespace, Labels: l.Labels},
+			Spec:       l.spec(now, now, 0),
+		}
+		_, status, err = l.do(http.MethodPost, endpoint, &lease)
+		if err != nil {
+			return false, err
+		}
+		// another replica created it first
+		if status == http.StatusConflict {
+			return false, nil
+		}
+		if status != http.StatusCreated {
+			return false, fmt.Errorf("creating lease: unexpected status %d", status)
+		}
+		return true, nil
+	}
+	if status != http.StatusOK {
+		return false, fmt.Errorf("getting lease: unexpected status %d", status)
+	}
+
+	spec := current.Spec
+	if spec.HolderIdentity != l.Identity && !expired(spec, now) {
+		return false, nil
+	}
+
+	acquireTime, transitions := now, spec.LeaseTransitions
+	if spec.HolderIdentity == l.Identity {
+		if t, err := time.Parse(microTimeFormat, spec.AcquireTime); err == nil {
+			acquireTime = t
+		}
+	} else {
+		transitions++
+	}
+	current.Spec = l.spec(acquireTime, now, transitions)
+
+	// the resourceVersion makes the update fail if another replica changed the lease meanwhile
+	_, status, err = l.do(http.MethodPut, endpoint+"/"+l.Name, current)
+	if err != nil {
+		return false, err
+	}
+	if status == http.StatusConflict {
+		return false, nil
+	}
+	if status != http.StatusOK {
+		return false, fmt.Errorf("updating lease: unexpected status %d", status)
+	}
+	return true, nil
+}
+
+// spec returns the Lease spec held by this replica
+func (l *Lease) spec(acquireTime time.Time, renewTime time.Time, transitions int) leaseSpec {
+	return leaseSpec{
+		HolderIdentity:       l.Identity,
+		LeaseDurationSeconds: int(l.Duration.Seconds()),
+		AcquireTime:          acquireTime.Format(microTimeFormat),
+		RenewTime:            renewTime.Format(microTimeFormat),
+		LeaseTransitions:     transitions,
+	}
+}
+
+// expired reports whether the holder of a Lease has stopped renewing it
+func expired(spec leaseSpec, now time.Time) bool {
+	if spec.HolderIdentity == "" {
+		return true
+	}
+	renewTime, err := time.Parse(microTimeFormat, spec.RenewTime)
+	if err != nil {
+		return true
+	}
+	return now.After(renewTime.Add(time.Duration(spec.LeaseDurationSeconds) * time.Second))
+}
+
+// do sends a request to the Kubernetes API, decoding a Lease from successful responses
+func (l *Lease) do(method string, endpoint string, body *leaseObject) (*leaseObject, int, error) {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return nil, 0, err
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequest(method, endpoint, reader)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Authorization", "Bearer "+l.token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return nil, resp.StatusCode, nil
+	}
+	var lease leaseObject
+	if err := json.NewDecoder(resp.Body).Decode(&lease); err != nil {
+		return nil, resp.StatusCode, fmt.Errorf("decoding lease: %w", err)
+	}
+	return &lease, resp.StatusCode, nil
+}
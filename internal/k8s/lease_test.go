@@ -0,0 +1,109 @@
+package k8s
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeAPI stores a single Lease like the Kubernetes API, including resourceVersion conflicts
+type fakeAPI struct {
+	mu      sync.Mutex
+	lease   *leaseObject
+	version int
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer fake-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var body leaseObject
+	if r.Method != http.MethodGet {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if f.lease == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+	case http.MethodPost:
+		if f.lease != nil {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		f.lease = &body
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodPut:
+		if body.Metadata.ResourceVersion != f.lease.Metadata.ResourceVersion {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		f.lease = &body
+	}
+	if r.Method != http.MethodGet {
+		f.version++
+		f.lease.Metadata.ResourceVersion = strconv.Itoa(f.version)
+	}
+	_ = json.NewEncoder(w).Encode(f.lease)
+}
+
+func newTestLease(server *httptest.Server, identity string) *Lease {
+	return &Lease{
+		Name:      "rss2mastodon",
+		Namespace: "default",
+		Identity:  identity,
+		Duration:  time.Minute,
+		apiURL:    server.URL,
+		token:     "fake-token",
+		client:    server.Client(),
+	}
+}
+
+// Test only one replica holds the lease until it expires
+func TestLease_TryAcquire(t *testing.T) {
+	api := &fakeAPI{}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	a := newTestLease(server, "pod-a")
+	b := newTestLease(server, "pod-b")
+
+	acquire := func(l *Lease, expected bool) {
+		t.Helper()
+		held, err := l.TryAcquire()
+		if err != nil {
+			t.Fatalf("TryAcquire failed: %v", err)
+		}
+		if held != expected {
+			t.Fatalf("Expected %s holding the lease to be %v", l.Identity, expected)
+		}
+	}
+
+	acquire(a, true)
+	acquire(b, false)
+	// renewing keeps the lease
+	acquire(a, true)
+	acquire(b, false)
+
+	// once pod-a stops renewing, pod-b takes over
+	api.mu.Lock()
+	api.lease.Spec.RenewTime = time.Now().Add(-2 * time.Minute).UTC().Format(microTimeFormat)
+	api.mu.Unlock()
+	acquire(b, true)
+	acquire(a, false)
+
+	if api.lease.Spec.HolderIdentity != "pod-b" || api.lease.Spec.LeaseTransitions != 1 {
+		t.Errorf("Expected pod-b to hold the lease after 1 transition, got %+v", api.lease.Spec)
+	}
+}
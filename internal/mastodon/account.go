@@ -0,0 +1,115 @@
+package mastodon
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+
+	"github.com/toozej/rss2mastodon/internal/media"
+)
+
+// Account is a Mastodon account as returned by the accounts and search APIs
+type Account struct {
+	ID   string `json:"id"`
+	Acct string `json:"acct"`
+	// Bot is set on accounts which declare themselves automated
+	Bot bool `json:"bot"`
+	// FollowersCount is how many accounts follow the account
+	FollowersCount int `json:"followers_count"`
+	// Source holds the plain text profile fields, only returned for the authenticated account
+	Source struct {
+		Fields []Field `json:"fields"`
+	} `json:"source"`
+}
+
+// Field is a name/value pair shown on an account's profile
+type Field struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// MaxProfileFields is how many profile fields Mastodon allows by default
+const MaxProfileFields = 4
+
+// avatarLimits keeps avatars within Mastodon's upload limit, they are resized to 400x400 anyway
+var avatarLimits = media.Limits{MaxBytes: 2 * 1024 * 1024, MaxPixels: 1024 * 1024}
+
+// VerifyCredentials returns the account the access token belongs to
+func (c *Client) VerifyCredentials() (*Account, error) {
+	var account Account
+	if err := c.getJSON("/api/v1/accounts/verify_credentials", nil, &account); err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// UpdateCredentials replaces the profile fields of the account the access token belongs to,
+// and its avatar if image data is given
+func (c *Client) UpdateCredentials(fields []Field, avatar []byte, avatarName string) error {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for i, field := range fields {
+		if err := writer.WriteField(fmt.Sprintf("fields_attributes[%d][name]", i), field.Name); err != nil {
+			return err
+		}
+		if err := writer.WriteField(fmt.Sprintf("fields_attributes[%d][value]", i), field.Value); err != nil {
+			return err
+		}
+	}
+	if avatar != nil {
+		data, contentType, err := media.Process(avatar, avatarLimits)
+		if err != nil {
+			return fmt.Errorf("failed to process avatar: %w", err)
+		}
+		part, err := writer.CreatePart(map[string][]string{
+			"Content-Disposition": {fmt.Sprintf(`form-data; name="avatar"; filename=%q`, avatarName)},
+			"Content-Type":        {contentType},
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := part.Write(data); err != nil {
+			return err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	req, err := c.newRequest("PATCH", "/api/v1/accounts/update_credentials", &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	resp, err := c.uploads.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected HTTP status: %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(&Account{})
+}
+
+// SetField sets the value of the named profile field, adding it if there is room.
+// It returns whether the fields changed.
+func SetField(fields []Field, name string, value string) ([]Field, bool) {
+	for i := range fields {
+		if fields[i].Name == name {
+			if fields[i].Value == value {
+				return fields, false
+			}
+			fields[i].Value = value
+			return fields, true
+		}
+	}
+	if len(fields) >= MaxProfileFields {
+		return fields, false
+	}
+	return append(fields, Field{Name: name, Value: value}), true
+}
@@ -0,0 +1,71 @@
+package mastodon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Table-driven test for setting profile fields
+func TestSetField(t *testing.T) {
+	full := []Field{{"A", "1"}, {"B", "2"}, {"C", "3"}, {"D", "4"}}
+
+	tests := []struct {
+		name            string
+		fields          []Field
+		field           string
+		value           string
+		expectedChanged bool
+		expectedLen     int
+	}{
+		{"Add to empty profile", nil, "Last post", "https://example.com", true, 1},
+		{"Unchanged value", []Field{{"Last post", "https://example.com"}}, "Last post", "https://example.com", false, 1},
+		{"Changed value", []Field{{"Last post", "https://example.com/old"}}, "Last post", "https://example.com", true, 1},
+		{"No room for a new field", full, "Last post", "https://example.com", false, MaxProfileFields},
+		{"Existing field in a full profile", full, "D", "5", true, MaxProfileFields},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields := append([]Field(nil), tt.fields...)
+			fields, changed := SetField(fields, tt.field, tt.value)
+			if changed != tt.expectedChanged {
+				t.Errorf("Expected changed %v, got %v", tt.expectedChanged, changed)
+			}
+			if len(fields) != tt.expectedLen {
+				t.Errorf("Expected %d fields, got %d", tt.expectedLen, len(fields))
+			}
+		})
+	}
+}
+
+// Test updating the profile fields of the bot account
+func TestUpdateCredentials(t *testing.T) {
+	var gotFields []Field
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PATCH" || r.URL.Path != "/api/v1/accounts/update_credentials" {
+			t.Errorf("Unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Fatalf("Failed to parse form: %v", err)
+		}
+		gotFields = append(gotFields, Field{
+			Name:  r.FormValue("fields_attributes[0][name]"),
+			Value: r.FormValue("fields_attributes[0][value]"),
+		})
+		if _, _, err := r.FormFile("avatar"); err == nil {
+			t.Errorf("Expected no avatar to be sent")
+		}
+		_, _ = w.Write([]byte(`{"id":"1","acct":"bot"}`))
+	}))
+	defer mockServer.Close()
+
+	client := NewClient(Config{URL: mockServer.URL, AccessToken: "fake-token"})
+
+	if err := client.UpdateCredentials([]Field{{"Powered by", "rss2mastodon"}}, nil, ""); err != nil {
+		t.Fatalf("UpdateCredentials failed: %v", err)
+	}
+	if len(gotFields) != 1 || gotFields[0] != (Field{"Powered by", "rss2mastodon"}) {
+		t.Errorf("Unexpected fields sent: %v", gotFields)
+	}
+}
@@ -0,0 +1,48 @@
+package mastodon
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"strings"
+)
+
+// authorStatusesLimit is how many of the author's recent statuses are searched for the post
+const authorStatusesLimit = 40
+
+// FindAuthorStatus looks for a status by the fediverse account acct (user@host) linking to
+// the given URL, returning nil if the account or such a status isn't found
+func (c *Client) FindAuthorStatus(acct string, link string) (*Status, error) {
+	var results struct {
+		Accounts []Account `json:"accounts"`
+	}
+	query := url.Values{"q": {"@" + acct}, "type": {"accounts"}, "resolve": {"true"}, "limit": {"1"}}
+	if err := c.getJSON("/api/v2/search", query, &results); err != nil {
+		return nil, fmt.Errorf("failed to look up %s: %w", acct, err)
+	}
+	if len(results.Accounts) == 0 || !strings.EqualFold(results.Accounts[0].Acct, acct) {
+		return nil, nil
+	}
+
+	var statuses []Status
+	query = url.Values{"exclude_replies": {"true"}, "exclude_reblogs": {"true"}, "limit": {fmt.Sprint(authorStatusesLimit)}}
+	if err := c.getJSON("/api/v1/accounts/"+results.Accounts[0].ID+"/statuses", query, &statuses); err != nil {
+		return nil, fmt.Errorf("failed to fetch statuses of %s: %w", acct, err)
+	}
+
+	for i := range statuses {
+		status := &statuses[i]
+		if status.Card != nil && status.Card.URL == link {
+			return status, nil
+		}
+		if strings.Contains(status.Content, link) || strings.Contains(status.Content, html.EscapeString(link)) {
+			return status, nil
+		}
+	}
+	return nil, nil
+}
+
+// Boost reblogs an existing status
+func (c *Client) Boost(id string) (*Status, error) {
+	return c.sendStatus("POST", "/api/v1/statuses/"+id+"/reblog", url.Values{}, "")
+}
@@ -0,0 +1,80 @@
+package mastodon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Table-driven test for finding an author's own post of an item
+func TestFindAuthorStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		acct       string
+		link       string
+		expectedID string
+	}{
+		{
+			name:       "Status linking the post",
+			acct:       "me@example.social",
+			link:       "https://example.com/posts/hello?a=1&b=2",
+			expectedID: "2",
+		},
+		{
+			name:       "Status with a card for the post",
+			acct:       "me@example.social",
+			link:       "https://example.com/posts/carded",
+			expectedID: "3",
+		},
+		{
+			name: "No status for the post",
+			acct: "me@example.social",
+			link: "https://example.com/posts/other",
+		},
+		{
+			name: "Unknown account",
+			acct: "nobody@example.social",
+			link: "https://example.com/posts/hello?a=1&b=2",
+		},
+	}
+
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v2/search":
+			if r.URL.Query().Get("q") == "@me@example.social" {
+				_, _ = w.Write([]byte(`{"accounts":[{"id":"42","acct":"me@example.social"}]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"accounts":[]}`))
+		case "/api/v1/accounts/42/statuses":
+			_, _ = w.Write([]byte(`[
+				{"id":"1","content":"<p>Something else</p>"},
+				{"id":"2","content":"<p>New post: <a href=\"https://example.com/posts/hello?a=1&amp;b=2\">link</a></p>"},
+				{"id":"3","content":"<p>Read it</p>","card":{"url":"https://example.com/posts/carded"}}
+			]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer mockServer.Close()
+
+	client := NewClient(Config{URL: mockServer.URL, AccessToken: "fake-token"})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, err := client.FindAuthorStatus(tt.acct, tt.link)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if tt.expectedID == "" {
+				if status != nil {
+					t.Errorf("Expected no status, got %s", status.ID)
+				}
+				return
+			}
+			if status == nil || status.ID != tt.expectedID {
+				t.Errorf("Expected status %s, got %v", tt.expectedID, status)
+			}
+		})
+	}
+}
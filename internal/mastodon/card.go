@@ -0,0 +1,67 @@
+package mastodon
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/toozej/rss2mastodon/internal/article"
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// Mastodon resolves preview cards asynchronously, so check a few times before giving up
+var (
+	cardCheckInterval = 5 * time.Second
+	cardCheckAttempts = 3
+)
+
+// VerifyLinkCard checks that Mastodon resolved a preview card for the post's link in the
+// given status. If it didn't and the linked page lacks OpenGraph tags, the status is edited
+// to attach an image scraped from the page so the announcement doesn't look bare. Statuses
+// without an ID, like scheduled ones, can't be checked and are skipped.
+func (c *Client) VerifyLinkCard(status *Status, content string, post rss.RSSItem) error {
+	if status == nil || status.ID == "" {
+		log.Debugf("Not verifying the link card for %s, its status has no ID", post.Link)
+		return nil
+	}
+	for attempt := 0; attempt < cardCheckAttempts; attempt++ {
+		time.Sleep(cardCheckInterval)
+
+		current, err := c.GetStatus(status.ID)
+		if err != nil {
+			return fmt.Errorf("failed to fetch status %s: %w", status.ID, err)
+		}
+		if current.Card != nil {
+			log.Debugf("Link card resolved for %s: %s", post.Link, current.Card.Title)
+			return nil
+		}
+		status.SpoilerText = current.SpoilerText
+	}
+
+	page, err := article.Fetch(post.Link)
+	if err != nil {
+		return fmt.Errorf("failed to fetch %s: %w", post.Link, err)
+	}
+	if page.HasOpenGraph() {
+		log.Infof("Link card for %s has not resolved yet, but the page has OpenGraph tags", post.Link)
+		return nil
+	}
+
+	image := page.PreviewImage()
+	if image == "" {
+		log.Infof("Link card for %s did not resolve and no fallback image was found", post.Link)
+		return nil
+	}
+
+	mediaIDs := c.UploadImages([]rss.Image{{URL: image, Alt: post.Title}})
+	if len(mediaIDs) == 0 {
+		return fmt.Errorf("failed to upload fallback image %s", image)
+	}
+
+	if _, err := c.EditStatus(status.ID, content, status.SpoilerText, mediaIDs...); err != nil {
+		return fmt.Errorf("failed to attach fallback image to status %s: %w", status.ID, err)
+	}
+	log.Infof("Attached fallback image %s to status for %s", image, post.Link)
+	return nil
+}
@@ -0,0 +1,109 @@
+package mastodon
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// Table-driven test for link card verification
+func TestVerifyLinkCard(t *testing.T) {
+	cardCheckInterval = time.Millisecond
+	defer func() { cardCheckInterval = 5 * time.Second }()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatalf("Failed to encode test image: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		status       string
+		page         string
+		spoilerText  string
+		expectedEdit bool
+	}{
+		{
+			name:         "Card resolved",
+			status:       `{"id":"1","card":{"title":"Post"}}`,
+			page:         `<img src="/hero.png">`,
+			expectedEdit: false,
+		},
+		{
+			name:         "Page has OpenGraph tags",
+			status:       `{"id":"1","card":null}`,
+			page:         `<meta property="og:title" content="Post"><img src="/hero.png">`,
+			expectedEdit: false,
+		},
+		{
+			name:         "Page lacks OpenGraph tags",
+			status:       `{"id":"1","card":null}`,
+			page:         `<img src="/hero.png">`,
+			expectedEdit: true,
+		},
+		{
+			name:         "Content warning kept",
+			status:       `{"id":"1","card":null,"spoiler_text":"Politics"}`,
+			page:         `<img src="/hero.png">`,
+			spoilerText:  "Politics",
+			expectedEdit: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			edited := false
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.URL.Path == "/api/v1/statuses/1" && r.Method == "PUT":
+					edited = r.FormValue("media_ids[]") == "9" && r.FormValue("status") == "content" && r.FormValue("spoiler_text") == tt.spoilerText
+					_, _ = w.Write([]byte(`{"id":"1"}`))
+				case r.URL.Path == "/api/v1/statuses/1":
+					_, _ = w.Write([]byte(tt.status))
+				case r.URL.Path == "/api/v1/instance":
+					_, _ = w.Write([]byte(`{}`))
+				case r.URL.Path == "/api/v2/media":
+					_, _ = w.Write([]byte(`{"id":"9","type":"image"}`))
+				case r.URL.Path == "/hero.png":
+					_, _ = w.Write(buf.Bytes())
+				default:
+					_, _ = w.Write([]byte(tt.page))
+				}
+			}))
+			defer mockServer.Close()
+
+			client := NewClient(Config{URL: mockServer.URL, AccessToken: "fake-token"})
+
+			post := rss.RSSItem{Title: "Post", Link: mockServer.URL + "/post"}
+			if err := client.VerifyLinkCard(&Status{ID: "1"}, "content", post); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if edited != tt.expectedEdit {
+				t.Errorf("Expected edited: %v, got: %v", tt.expectedEdit, edited)
+			}
+		})
+	}
+}
+
+// Test statuses without an ID, like scheduled ones, aren't checked
+func TestVerifyLinkCard_NoID(t *testing.T) {
+	requests := 0
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer mockServer.Close()
+
+	client := NewClient(Config{URL: mockServer.URL, AccessToken: "fake-token"})
+	if err := client.VerifyLinkCard(&Status{}, "content", rss.RSSItem{Link: mockServer.URL + "/post"}); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if requests != 0 {
+		t.Errorf("Expected no requests, got %d", requests)
+	}
+}
@@ -0,0 +1,117 @@
+package mastodon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/toozej/rss2mastodon/internal/httpclient"
+	"github.com/toozej/rss2mastodon/internal/retry"
+)
+
+// Default timeouts of API requests, and of media uploads which can take much longer
+const (
+	DefaultTimeout       = 10 * time.Second
+	DefaultUploadTimeout = 60 * time.Second
+)
+
+// Config holds what a Client needs to act as the bot's account
+type Config struct {
+	// URL is the instance's base URL, e.g. https://mastodon.social
+	URL         string
+	AccessToken string
+	// Timeout limits API requests, DefaultTimeout if zero
+	Timeout time.Duration
+	// UploadTimeout limits media and avatar uploads, DefaultUploadTimeout if zero
+	UploadTimeout time.Duration
+	// MaxCharacters is the instance's character limit, which longer statuses are truncated to,
+	// unlimited if zero
+	MaxCharacters int
+	// TootRetry retries failed API requests which are safe to send again, which excludes creating
+	// statuses without an idempotency key. The zero policy doesn't retry.
+	TootRetry retry.Policy
+	// UploadRetry retries failed media and avatar uploads. The zero policy doesn't retry.
+	UploadRetry retry.Policy
+	// Flavor is the instance's server software, one of Flavors, adjusting the requests to its
+	// quirks. Empty is taken for Mastodon.
+	Flavor string
+	// RateLimitReserve is how many requests of the instance's rate limits are left unused, as
+	// requests fail with a RateLimitError once no more remain until the limit resets
+	RateLimitReserve int
+}
+
+// Client calls the Mastodon API as the account the access token belongs to
+type Client struct {
+	config  Config
+	api     *http.Client
+	uploads *http.Client
+	// stream has no timeout, as the streaming API keeps the connection open indefinitely
+	stream *http.Client
+	// item is the link of the feed item the client's requests are audited as sent for
+	item string
+}
+
+// NewClient returns a client for the given configuration. Its HTTP clients share the default
+// transport, so clients created for every call still reuse connections, authenticate every
+// request with the access token and keep track of the account's rate limits.
+func NewClient(config Config) *Client {
+	config.URL = strings.TrimSuffix(config.URL, "/")
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultTimeout
+	}
+	if config.UploadTimeout <= 0 {
+		config.UploadTimeout = DefaultUploadTimeout
+	}
+	auth := httpclient.BearerAuth(config.AccessToken)
+	limited := rateLimited(config.URL+" "+config.AccessToken, config.RateLimitReserve)
+	return &Client{
+		config:  config,
+		api:     httpclient.New("mastodon", config.Timeout, auth, httpclient.Retry(config.TootRetry), limited),
+		uploads: httpclient.New("mastodon uploads", config.UploadTimeout, auth, httpclient.RetryAll(config.UploadRetry), limited),
+		stream:  httpclient.New("mastodon streaming", 0, auth),
+	}
+}
+
+// ForItem returns a copy of the client whose requests are audited as sent for the feed item
+// with the link
+func (c *Client) ForItem(link string) *Client {
+	client := *c
+	client.item = link
+	return &client
+}
+
+// newRequest creates a request against an API endpoint
+func (c *Client) newRequest(method string, endpoint string, body io.Reader) (*http.Request, error) {
+	if c.config.URL == "" || c.config.AccessToken == "" {
+		return nil, fmt.Errorf("mastodon URL and token must be set")
+	}
+	ctx := context.Background()
+	if c.item != "" {
+		ctx = httpclient.WithAuditItem(ctx, c.item)
+	}
+	return http.NewRequestWithContext(ctx, method, c.config.URL+endpoint, body)
+}
+
+// getJSON performs an authenticated GET request against the Mastodon API and decodes the response
+func (c *Client) getJSON(endpoint string, query url.Values, out interface{}) error {
+	req, err := c.newRequest("GET", endpoint+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.api.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected HTTP status: %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,75 @@
+package mastodon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/toozej/rss2mastodon/internal/httpclient"
+)
+
+// Table-driven test for the client's timeouts and required configuration
+func TestNewClient(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/accounts/verify_credentials" || r.Header.Get("Authorization") != "Bearer fake-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":"1","acct":"bot"}`))
+	}))
+	defer mockServer.Close()
+
+	tests := []struct {
+		name            string
+		config          Config
+		timeout         time.Duration
+		uploadTimeout   time.Duration
+		expectedAccount bool
+	}{
+		{"Defaults", Config{URL: mockServer.URL, AccessToken: "fake-token"}, DefaultTimeout, DefaultUploadTimeout, true},
+		{"Trailing slash", Config{URL: mockServer.URL + "/", AccessToken: "fake-token", Timeout: time.Second, UploadTimeout: time.Minute}, time.Second, time.Minute, true},
+		{"Missing token", Config{URL: mockServer.URL}, DefaultTimeout, DefaultUploadTimeout, false},
+		{"Wrong token", Config{URL: mockServer.URL, AccessToken: "other-token"}, DefaultTimeout, DefaultUploadTimeout, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient(tt.config)
+			if client.config.Timeout != tt.timeout || client.config.UploadTimeout != tt.uploadTimeout {
+				t.Errorf("Expected timeouts %s and %s, got %s and %s", tt.timeout, tt.uploadTimeout, client.config.Timeout, client.config.UploadTimeout)
+			}
+
+			account, err := client.VerifyCredentials()
+			if tt.expectedAccount && (err != nil || account.Acct != "bot") {
+				t.Errorf("Expected the bot account, got %+v (%v)", account, err)
+			}
+			if !tt.expectedAccount && err == nil {
+				t.Errorf("Expected an error, got %+v", account)
+			}
+		})
+	}
+}
+
+// Test auditing the requests of a client for a feed item as sent for it
+func TestForItem(t *testing.T) {
+	var items []string
+	httpclient.Auditor = func(entry httpclient.AuditEntry) { items = append(items, entry.Item) }
+	defer func() { httpclient.Auditor = nil }()
+
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"id":"1"}`))
+	}))
+	defer mockServer.Close()
+
+	client := NewClient(Config{URL: mockServer.URL, AccessToken: "fake-token"})
+	if _, err := client.ForItem("https://example.com/1").PostStatus("New post", TootOptions{}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := client.DeleteStatus("1"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(items) != 2 || items[0] != "https://example.com/1" || items[1] != "" {
+		t.Errorf("Expected the status posted for the item and the deletion for none, got %q", items)
+	}
+}
@@ -0,0 +1,81 @@
+package mastodon
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Server software implementing the Mastodon API, whose quirks the client adjusts to
+const (
+	FlavorMastodon   = "mastodon"
+	FlavorGoToSocial = "gotosocial"
+	FlavorPleroma    = "pleroma"
+	FlavorAkkoma     = "akkoma"
+)
+
+// Flavors are the server flavors the client knows
+var Flavors = []string{FlavorMastodon, FlavorGoToSocial, FlavorPleroma, FlavorAkkoma}
+
+// IsFlavor reports whether name is a known server flavor
+func IsFlavor(name string) bool {
+	for _, flavor := range Flavors {
+		if name == flavor {
+			return true
+		}
+	}
+	return false
+}
+
+// Flavor returns the server software of the instance, from the version it reports, e.g.
+// "2.7.2 (compatible; Akkoma 3.13.2)", or from settings only GoToSocial reports. Instances
+// which can't be told apart are taken for Mastodon.
+func (i *Instance) Flavor() string {
+	if i == nil {
+		return FlavorMastodon
+	}
+	version := strings.ToLower(i.Version)
+	switch {
+	case strings.Contains(version, "akkoma"):
+		return FlavorAkkoma
+	case strings.Contains(version, "pleroma"):
+		return FlavorPleroma
+	case strings.Contains(version, "gotosocial"), i.Configuration.Accounts.AllowCustomCSS != nil:
+		return FlavorGoToSocial
+	}
+	return FlavorMastodon
+}
+
+// statusContentType returns the content_type statuses are posted with, which tells Pleroma,
+// Akkoma and GoToSocial not to render them as Markdown or HTML, where e.g. underscores in links
+// would turn into emphasis. Mastodon doesn't support it.
+func statusContentType(flavor string) string {
+	switch flavor {
+	case FlavorGoToSocial, FlavorPleroma, FlavorAkkoma:
+		return "text/plain"
+	}
+	return ""
+}
+
+// setLocalOnly sets the parameter of a status keeping it from federating: Pleroma and Akkoma
+// have a local visibility, GoToSocial doesn't federate statuses with federated=false, and the
+// Mastodon forks glitch-soc and Hometown support local_only, which Mastodon itself ignores
+func setLocalOnly(formData url.Values, flavor string) {
+	switch flavor {
+	case FlavorPleroma, FlavorAkkoma:
+		formData.Set("visibility", "local")
+	case FlavorGoToSocial:
+		formData.Set("federated", "false")
+	default:
+		formData.Set("local_only", "true")
+	}
+}
+
+// mediaEndpoints returns the media upload endpoints to try in order. Pleroma and Akkoma process
+// uploads synchronously with the v1 API; others prefer v2, falling back to v1 if it is missing.
+func mediaEndpoints(flavor string) []string {
+	switch flavor {
+	case FlavorPleroma, FlavorAkkoma:
+		return []string{"/api/v1/media", "/api/v2/media"}
+	}
+	return []string{"/api/v2/media", "/api/v1/media"}
+}
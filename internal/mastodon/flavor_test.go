@@ -0,0 +1,104 @@
+package mastodon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Table-driven test for telling the server software from the instance information
+func TestInstanceFlavor(t *testing.T) {
+	allowCustomCSS := false
+	tests := []struct {
+		name     string
+		instance *Instance
+		expected string
+	}{
+		{"Unknown", nil, FlavorMastodon},
+		{"Mastodon", &Instance{Version: "4.3.2"}, FlavorMastodon},
+		{"Pleroma", &Instance{Version: "2.7.2 (compatible; Pleroma 2.8.0)"}, FlavorPleroma},
+		{"Akkoma", &Instance{Version: "2.7.2 (compatible; Akkoma 3.13.2)"}, FlavorAkkoma},
+		{"GoToSocial by version", &Instance{Version: "0.17.3 (compatible; GoToSocial)"}, FlavorGoToSocial},
+		{"GoToSocial by settings", func() *Instance {
+			instance := &Instance{Version: "0.17.3+git-4a4e7f0"}
+			instance.Configuration.Accounts.AllowCustomCSS = &allowCustomCSS
+			return instance
+		}(), FlavorGoToSocial},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.instance.Flavor(); got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+// Table-driven test for posting statuses as plain text on servers supporting content types
+func TestPostStatus_ContentType(t *testing.T) {
+	tests := []struct {
+		flavor   string
+		expected string
+	}{
+		{"", ""},
+		{FlavorMastodon, ""},
+		{FlavorGoToSocial, "text/plain"},
+		{FlavorPleroma, "text/plain"},
+		{FlavorAkkoma, "text/plain"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.flavor, func(t *testing.T) {
+			var contentType string
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				contentType = r.FormValue("content_type")
+				_, _ = w.Write([]byte(`{"id":"1"}`))
+			}))
+			defer mockServer.Close()
+
+			client := NewClient(Config{URL: mockServer.URL, AccessToken: "fake-token", Flavor: tt.flavor})
+			if _, err := client.PostStatus("https://example.com/some_post_title", TootOptions{}); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if contentType != tt.expected {
+				t.Errorf("Expected content type %q, got %q", tt.expected, contentType)
+			}
+		})
+	}
+}
+
+// Table-driven test for the parameters keeping local-only statuses from federating
+func TestPostStatus_LocalOnly(t *testing.T) {
+	tests := []struct {
+		flavor     string
+		localOnly  string
+		federated  string
+		visibility string
+	}{
+		{FlavorMastodon, "true", "", "unlisted"},
+		{FlavorGoToSocial, "", "false", "unlisted"},
+		{FlavorPleroma, "", "", "local"},
+		{FlavorAkkoma, "", "", "local"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.flavor, func(t *testing.T) {
+			var localOnly, federated, visibility string
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				localOnly, federated, visibility = r.FormValue("local_only"), r.FormValue("federated"), r.FormValue("visibility")
+				_, _ = w.Write([]byte(`{"id":"1"}`))
+			}))
+			defer mockServer.Close()
+
+			client := NewClient(Config{URL: mockServer.URL, AccessToken: "fake-token", Flavor: tt.flavor})
+			if _, err := client.PostStatus("New post", TootOptions{Visibility: "unlisted", LocalOnly: true}); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if localOnly != tt.localOnly || federated != tt.federated || visibility != tt.visibility {
+				t.Errorf("Expected local_only %q, federated %q and visibility %q, got %q, %q and %q",
+					tt.localOnly, tt.federated, tt.visibility, localOnly, federated, visibility)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package mastodon
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// urlLength is how many characters Mastodon counts every link as, however long it is
+const urlLength = 23
+
+var (
+	statusURLRegex = regexp.MustCompile(`https?://[^\s<>"]+`)
+	// mentions of remote accounts only count their username
+	remoteMentionRegex = regexp.MustCompile(`(@\w+)@[a-zA-Z0-9.-]+[a-zA-Z0-9]`)
+)
+
+// StatusLength returns how many characters Mastodon counts a status as, which is what its
+// character limit applies to: links count as 23 characters and remote mentions as @username
+func StatusLength(text string) int {
+	text = statusURLRegex.ReplaceAllString(text, strings.Repeat("x", urlLength))
+	text = remoteMentionRegex.ReplaceAllString(text, "$1")
+	return utf8.RuneCountInString(text)
+}
+
+// MaxCharacters returns the instance's character limit of toots, or 0 if it doesn't say
+func (i *Instance) MaxCharacters() int {
+	if i == nil {
+		return 0
+	}
+	if i.Configuration.Statuses.MaxCharacters > 0 {
+		return i.Configuration.Statuses.MaxCharacters
+	}
+	return i.MaxTootChars
+}
+
+// TruncateStatus shortens text to at most limit characters as counted by StatusLength, cutting
+// it after a word if possible and marking the cut with an ellipsis. A link ending the text,
+// usually the one the toot announces, is kept.
+func TruncateStatus(text string, limit int) string {
+	if StatusLength(text) <= limit {
+		return text
+	}
+
+	head, tail := text, ""
+	// keep the last link, if nothing but whitespace follows it and there is room for it
+	if locs := statusURLRegex.FindAllStringIndex(text, -1); len(locs) > 0 {
+		last := locs[len(locs)-1]
+		if strings.TrimSpace(text[last[1]:]) == "" && urlLength+2 <= limit {
+			head, tail = text[:last[0]], " "+text[last[0]:last[1]]
+		}
+	}
+
+	// links in the head may count as fewer or more characters than they have, so cut it until
+	// it fits along with the ellipsis and the tail
+	runes := []rune(head)
+	for n := min(len(runes), limit-StatusLength(tail)-1); n >= 0; n-- {
+		cut := string(runes[:n])
+		if n < len(runes) && !unicode.IsSpace(runes[n]) {
+			if i := strings.LastIndexFunc(cut, unicode.IsSpace); i > 0 {
+				cut = cut[:i]
+			}
+		}
+		candidate := strings.TrimRightFunc(cut, unicode.IsSpace) + "…" + tail
+		if StatusLength(candidate) <= limit {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// fitStatus truncates a status to the client's character limit, which the content warning
+// counts towards too
+func (c *Client) fitStatus(content string, spoilerText string) string {
+	if c.config.MaxCharacters <= 0 {
+		return content
+	}
+	limit := c.config.MaxCharacters - StatusLength(spoilerText)
+	if StatusLength(content) <= limit {
+		return content
+	}
+	log.Warnf("Truncating status of %d characters to the instance's limit of %d", StatusLength(content)+StatusLength(spoilerText), c.config.MaxCharacters)
+	return TruncateStatus(content, limit)
+}
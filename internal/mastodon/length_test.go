@@ -0,0 +1,126 @@
+package mastodon
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Table-driven test for counting characters the way Mastodon does
+func TestStatusLength(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected int
+	}{
+		{"Plain text", "Hello", 5},
+		{"Multibyte characters", "Grüße 🤖", 7},
+		{"Long link", "New blog post: https://example.com/2024/05/06/a-very-long-slug-indeed?utm_source=rss", 15 + 23},
+		{"Short link", "https://a.io", 23},
+		{"Remote mention", "by @me@example.social", 6},
+		{"Local mention", "by @me", 6},
+		{"Email address", "mail me@example.com", 19},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := StatusLength(tt.text); got != tt.expected {
+				t.Errorf("Expected %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+// Table-driven test for truncating statuses to a character limit, keeping a trailing link
+func TestTruncateStatus(t *testing.T) {
+	link := "https://example.com/2024/05/06/a-very-long-slug-indeed"
+	tests := []struct {
+		name     string
+		text     string
+		limit    int
+		expected string
+	}{
+		{"Fits", "Hello world", 11, "Hello world"},
+		{"Plain text", "Hello world", 8, "Hello…"},
+		{"Trailing link kept", "Thoughts on a long topic - " + link, 37, "Thoughts on…" + " " + link},
+		{"Trailing whitespace after link", "Thoughts on a long topic - " + link + "\n", 35, "Thoughts…" + " " + link},
+		{"Link in the middle", "Read " + link + " today, it is good", 30, "Read…"},
+		{"Single long word", "Supercalifragilistic", 6, "Super…"},
+		{"No room for the link", "Thoughts - " + link, 10, "Thoughts…"},
+		{"Multibyte characters", "Grüße aus Köln", 6, "Grüße…"},
+		{"No room at all", "Hello", 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := TruncateStatus(tt.text, tt.limit)
+			if got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+			if StatusLength(got) > tt.limit {
+				t.Errorf("Expected at most %d characters, got %d", tt.limit, StatusLength(got))
+			}
+		})
+	}
+}
+
+// Table-driven test for reading the character limit from Mastodon's and Pleroma's instance information
+func TestInstanceMaxCharacters(t *testing.T) {
+	tests := []struct {
+		name     string
+		instance string
+		expected int
+	}{
+		{"Mastodon", `{"configuration":{"statuses":{"max_characters":5000}}}`, 5000},
+		{"Pleroma", `{"max_toot_chars":20000}`, 20000},
+		{"Both", `{"max_toot_chars":1000,"configuration":{"statuses":{"max_characters":500}}}`, 500},
+		{"Unknown", `{}`, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var instance Instance
+			if err := json.Unmarshal([]byte(tt.instance), &instance); err != nil {
+				t.Fatalf("Failed to parse instance: %v", err)
+			}
+			if got := instance.MaxCharacters(); got != tt.expected {
+				t.Errorf("Expected %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+// Table-driven test for truncating posted statuses to the client's limit, counting the content warning
+func TestPostStatus_MaxCharacters(t *testing.T) {
+	tests := []struct {
+		name          string
+		maxCharacters int
+		spoilerText   string
+		expected      string
+	}{
+		{"Unlimited", 0, "", "Hello world"},
+		{"Fits", 11, "", "Hello world"},
+		{"Truncated", 8, "", "Hello…"},
+		{"Content warning counts", 11, "CW", "Hello…"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var status string
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				status = r.FormValue("status")
+				_, _ = w.Write([]byte(`{"id":"1"}`))
+			}))
+			defer mockServer.Close()
+
+			client := NewClient(Config{URL: mockServer.URL, AccessToken: "fake-token", MaxCharacters: tt.maxCharacters})
+			if _, err := client.PostStatus("Hello world", TootOptions{SpoilerText: tt.spoilerText}); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if status != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, status)
+			}
+		})
+	}
+}
@@ -1,52 +1,221 @@
 package mastodon
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/toozej/rss2mastodon/internal/rss"
 
-	"github.com/spf13/viper"
+	log "github.com/sirupsen/logrus"
 )
 
 // GetTootContent constructs the toot message depending on the post title
 func GetTootContent(post rss.RSSItem) string {
-	if strings.HasPrefix(post.Title, "Thoughts") {
-		return fmt.Sprintf("%s - %s", post.Content, post.Link)
+	if post.Announcement != "" {
+		return withSource(post, post.Announcement)
 	}
-	return fmt.Sprintf("New blog post: %s", post.Link)
+	if IsThought(post) {
+		return withSource(post, fmt.Sprintf("%s - %s", post.Content, post.Link))
+	}
+	return withSource(post, fmt.Sprintf("New blog post: %s", post.Link))
+}
+
+// IsThought reports whether a post is a short "Thoughts" post, which is tooted in full
+func IsThought(post rss.RSSItem) bool {
+	return strings.HasPrefix(post.Title, "Thoughts")
+}
+
+// GetUpdateTootContent constructs the toot message for a post which has been updated
+func GetUpdateTootContent(post rss.RSSItem) string {
+	return withSource(post, fmt.Sprintf("Blog post has been updated: %s", post.Link))
+}
+
+// withSource prefixes a toot with the name of the blog a planet member post came from
+func withSource(post rss.RSSItem, content string) string {
+	if post.Source == "" {
+		return content
+	}
+	return fmt.Sprintf("%s: %s", post.Source, content)
+}
+
+// Status is a Mastodon status as returned by the statuses API
+type Status struct {
+	ID      string `json:"id"`
+	URL     string `json:"url"`
+	Content string `json:"content"`
+	// Visibility is public, unlisted, private or direct
+	Visibility string `json:"visibility"`
+	// InReplyToID is the ID of the status this one replies to, if any
+	InReplyToID string `json:"in_reply_to_id"`
+	// SpoilerText is the status' content warning, shown instead of its content until expanded
+	SpoilerText string `json:"spoiler_text"`
+	// Account is the status' author
+	Account *Account `json:"account"`
+	Card    *Card    `json:"card"`
+	// engagement counts as of when the status was returned
+	FavouritesCount int `json:"favourites_count"`
+	ReblogsCount    int `json:"reblogs_count"`
+	RepliesCount    int `json:"replies_count"`
+}
+
+// Card is the link preview card Mastodon generates for the first link in a status
+type Card struct {
+	URL   string `json:"url"`
+	Title string `json:"title"`
+	Image string `json:"image"`
+}
+
+// TootOptions holds optional parameters for creating a status
+type TootOptions struct {
+	// MediaIDs are previously uploaded attachments to attach to the status
+	MediaIDs []string
+	// ScheduledAt publishes the status at the given time using Mastodon scheduled statuses
+	ScheduledAt time.Time
+	// Visibility of the status (public, unlisted, private or direct), the account default if empty
+	Visibility string
+	// InReplyToID makes the status a reply to the status with this ID
+	InReplyToID string
+	// SpoilerText hides the status behind this content warning
+	SpoilerText string
+	// Language is the ISO 639 code of the status' language, the account's default if empty
+	Language string
+	// IdempotencyKey makes Mastodon return the status created with the same key within the last
+	// hour instead of posting it again, e.g. when retrying after a crash
+	IdempotencyKey string
+	// LocalOnly keeps the status on the instance instead of federating it, on servers supporting
+	// it: glitch-soc, Hometown, GoToSocial, Pleroma and Akkoma
+	LocalOnly bool
+	// Poll attaches a poll to the status, which then can't have media attachments
+	Poll *Poll
+}
+
+// Poll is a poll attached to a status
+type Poll struct {
+	// Options are the choices to vote for, at most 4 on Mastodon by default
+	Options []string
+	// ExpiresIn is how long the poll is open for, at least 5 minutes
+	ExpiresIn time.Duration
+	// Multiple allows voting for more than one option
+	Multiple bool
+}
+
+// PostStatus sends a post to Mastodon with the given options and returns the created status.
+// For scheduled posts only the ID of the scheduled status is set on the result.
+func (c *Client) PostStatus(content string, opts TootOptions) (*Status, error) {
+	formData := url.Values{"status": {c.fitStatus(content, opts.SpoilerText)}}
+	if opts.Poll != nil {
+		for _, option := range opts.Poll.Options {
+			formData.Add("poll[options][]", option)
+		}
+		formData.Set("poll[expires_in]", strconv.Itoa(int(opts.Poll.ExpiresIn.Seconds())))
+		if opts.Poll.Multiple {
+			formData.Set("poll[multiple]", "true")
+		}
+	} else {
+		for _, id := range opts.MediaIDs {
+			formData.Add("media_ids[]", id)
+		}
+	}
+	if !opts.ScheduledAt.IsZero() {
+		formData.Set("scheduled_at", opts.ScheduledAt.UTC().Format(time.RFC3339))
+	}
+	if opts.Visibility != "" {
+		formData.Set("visibility", opts.Visibility)
+	}
+	if opts.InReplyToID != "" {
+		formData.Set("in_reply_to_id", opts.InReplyToID)
+	}
+	if opts.SpoilerText != "" {
+		formData.Set("spoiler_text", opts.SpoilerText)
+	}
+	if opts.Language != "" {
+		formData.Set("language", opts.Language)
+	}
+	if contentType := statusContentType(c.config.Flavor); contentType != "" {
+		formData.Set("content_type", contentType)
+	}
+	if opts.LocalOnly {
+		setLocalOnly(formData, c.config.Flavor)
+	}
+	return c.sendStatus("POST", "/api/v1/statuses", formData, opts.IdempotencyKey)
+}
+
+// EditStatus replaces the content, content warning and media attachments of an existing status.
+// Mastodon removes the content warning of statuses edited without one.
+func (c *Client) EditStatus(id string, content string, spoilerText string, mediaIDs ...string) (*Status, error) {
+	formData := url.Values{"status": {c.fitStatus(content, spoilerText)}}
+	if spoilerText != "" {
+		formData.Set("spoiler_text", spoilerText)
+	}
+	for _, mediaID := range mediaIDs {
+		formData.Add("media_ids[]", mediaID)
+	}
+	if contentType := statusContentType(c.config.Flavor); contentType != "" {
+		formData.Set("content_type", contentType)
+	}
+	return c.sendStatus("PUT", "/api/v1/statuses/"+id, formData, "")
 }
 
-// TootPost sends a post to Mastodon
-func TootPost(content string) error {
-	mastodonURL := viper.GetString("mastodon_url")
-	mastodonToken := viper.GetString("mastodon_token")
+// GetStatus fetches an existing status
+func (c *Client) GetStatus(id string) (*Status, error) {
+	return c.sendStatus("GET", "/api/v1/statuses/"+id, nil, "")
+}
 
-	if mastodonURL == "" || mastodonToken == "" {
-		return fmt.Errorf("mastodon URL and token must be set")
+// DeleteStatus deletes an existing status. Statuses which don't exist (anymore) count as
+// deleted, e.g. when a moderator or the account owner deleted them first.
+func (c *Client) DeleteStatus(id string) error {
+	req, err := c.newRequest("DELETE", "/api/v1/statuses/"+id, nil)
+	if err != nil {
+		return err
 	}
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	formData := fmt.Sprintf("status=%s", content)
-	req, err := http.NewRequest("POST", mastodonURL+"/api/v1/statuses", strings.NewReader(formData))
+	resp, err := c.api.Do(req)
 	if err != nil {
 		return err
 	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("unexpected HTTP status: %d", resp.StatusCode)
+	}
+	return nil
+}
 
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", mastodonToken))
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+// sendStatus performs a request against a statuses API endpoint and decodes the returned status,
+// sending the idempotency key if set. Only statuses created with an idempotency key are retried,
+// as others could be posted twice.
+func (c *Client) sendStatus(method string, endpoint string, formData url.Values, idempotencyKey string) (*Status, error) {
+	req, err := c.newRequest(method, endpoint, strings.NewReader(formData.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	if formData != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
 
-	resp, err := client.Do(req)
+	resp, err := c.api.Do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected HTTP status: %d", resp.StatusCode)
+		return nil, fmt.Errorf("unexpected HTTP status: %d", resp.StatusCode)
 	}
 
-	return nil
+	// the request succeeded at this point, so don't report an error (and risk a
+	// duplicate post) just because the response body couldn't be parsed
+	var status Status
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		log.Warn("Failed to parse status response: ", err)
+	}
+	return &status, nil
 }
@@ -3,9 +3,11 @@ package mastodon
 import (
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
+	"time"
 
-	"github.com/spf13/viper"
+	"github.com/toozej/rss2mastodon/internal/retry"
 	"github.com/toozej/rss2mastodon/internal/rss"
 )
 
@@ -40,6 +42,38 @@ func TestGetTootContent_NewPost(t *testing.T) {
 	}
 }
 
+// Test toot content of planet member posts is prefixed with the source blog
+func TestGetTootContent_Source(t *testing.T) {
+	post := rss.RSSItem{
+		Title:  "New Blog Post",
+		Link:   "https://example.com/blog",
+		Source: "Example Blog",
+	}
+
+	expected := "Example Blog: New blog post: https://example.com/blog"
+	if result := GetTootContent(post); result != expected {
+		t.Errorf("Expected '%s', got '%s'", expected, result)
+	}
+
+	expected = "Example Blog: Blog post has been updated: https://example.com/blog"
+	if result := GetUpdateTootContent(post); result != expected {
+		t.Errorf("Expected '%s', got '%s'", expected, result)
+	}
+}
+
+// Test toot content of items from other sources uses their announcement
+func TestGetTootContent_Announcement(t *testing.T) {
+	post := rss.RSSItem{
+		Title:        "Thoughts on v1.0.0",
+		Link:         "https://example.com/v1.0.0",
+		Announcement: "owner/repo v1.0.0 released\n\nhttps://example.com/v1.0.0",
+	}
+
+	if result := GetTootContent(post); result != post.Announcement {
+		t.Errorf("Expected '%s', got '%s'", post.Announcement, result)
+	}
+}
+
 // MockServer starts a new HTTP test server and returns the server URL along with a function to close the server
 func MockServer(statusCode int) (*httptest.Server, string) {
 	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -48,8 +82,8 @@ func MockServer(statusCode int) (*httptest.Server, string) {
 	return mockServer, mockServer.URL
 }
 
-// Table-driven test for TootPost
-func TestTootPost(t *testing.T) {
+// Table-driven test for PostStatus
+func TestPostStatus(t *testing.T) {
 	tests := []struct {
 		name          string
 		statusCode    int
@@ -73,16 +107,163 @@ func TestTootPost(t *testing.T) {
 			mockServer, mockServerURL := MockServer(tt.statusCode)
 			defer mockServer.Close()
 
-			// Set up environment variables
-			viper.Set("mastodon_url", mockServerURL)
-			viper.Set("mastodon_token", "fake-token")
+			// Set up the client
+			client := NewClient(Config{URL: mockServerURL, AccessToken: "fake-token"})
 
 			// Run the function to test
-			err := TootPost("Test toot content")
+			_, err := client.PostStatus("Test toot content", TootOptions{})
 
 			// Check if we expect an error or not
 			if (err != nil) != tt.expectedError {
-				t.Errorf("TestTootPost(%s) failed: expected error: %v, got: %v", tt.name, tt.expectedError, err)
+				t.Errorf("TestPostStatus(%s) failed: expected error: %v, got: %v", tt.name, tt.expectedError, err)
+			}
+		})
+	}
+}
+
+// Test that attached media IDs, the content warning, the language and the idempotency key are
+// sent with the status
+func TestPostStatus_MediaIDs(t *testing.T) {
+	var status, spoilerText, language, idempotencyKey string
+	var mediaIDs []string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		status = r.PostForm.Get("status")
+		mediaIDs = r.PostForm["media_ids[]"]
+		spoilerText = r.PostForm.Get("spoiler_text")
+		language = r.PostForm.Get("language")
+		idempotencyKey = r.Header.Get("Idempotency-Key")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer mockServer.Close()
+
+	client := NewClient(Config{URL: mockServer.URL, AccessToken: "fake-token"})
+
+	_, err := client.PostStatus("Photos & more", TootOptions{MediaIDs: []string{"1", "2"}, SpoilerText: "Eye contact", Language: "de", IdempotencyKey: "key"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if status != "Photos & more" {
+		t.Errorf("Expected status 'Photos & more', got '%s'", status)
+	}
+	if len(mediaIDs) != 2 || mediaIDs[0] != "1" || mediaIDs[1] != "2" {
+		t.Errorf("Expected media IDs [1 2], got %v", mediaIDs)
+	}
+	if spoilerText != "Eye contact" {
+		t.Errorf("Expected the content warning 'Eye contact', got '%s'", spoilerText)
+	}
+	if language != "de" {
+		t.Errorf("Expected the language 'de', got '%s'", language)
+	}
+	if idempotencyKey != "key" {
+		t.Errorf("Expected the idempotency key 'key', got '%s'", idempotencyKey)
+	}
+}
+
+// Test that a poll is sent with the status instead of its media
+func TestPostStatus_Poll(t *testing.T) {
+	var form url.Values
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		form = r.PostForm
+		_, _ = w.Write([]byte(`{"id":"1"}`))
+	}))
+	defer mockServer.Close()
+
+	client := NewClient(Config{URL: mockServer.URL, AccessToken: "fake-token"})
+	poll := &Poll{Options: []string{"Go", "Rust"}, ExpiresIn: 24 * time.Hour, Multiple: true}
+	if _, err := client.PostStatus("Which one?", TootOptions{MediaIDs: []string{"1"}, Poll: poll}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	options := form["poll[options][]"]
+	if len(options) != 2 || options[0] != "Go" || options[1] != "Rust" {
+		t.Errorf("Expected the options [Go Rust], got %v", options)
+	}
+	if form.Get("poll[expires_in]") != "86400" || form.Get("poll[multiple]") != "true" {
+		t.Errorf("Expected the poll to expire in 86400 seconds and allow multiple votes, got %v", form)
+	}
+	if len(form["media_ids[]"]) != 0 {
+		t.Errorf("Expected no media IDs with a poll, got %v", form["media_ids[]"])
+	}
+}
+
+// Table-driven test for retrying failed status requests
+func TestPostStatus_Retry(t *testing.T) {
+	tests := []struct {
+		name           string
+		idempotencyKey string
+		failures       int
+		failStatus     int
+		expectRequests int
+		expectErr      bool
+	}{
+		{"Retried with idempotency key", "key", 2, http.StatusServiceUnavailable, 3, false},
+		{"Out of attempts", "key", 5, http.StatusBadGateway, 3, true},
+		{"Not retried without idempotency key", "", 2, http.StatusServiceUnavailable, 1, true},
+		{"Rejected status not retried", "key", 2, http.StatusUnprocessableEntity, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				if requests <= tt.failures {
+					w.WriteHeader(tt.failStatus)
+					return
+				}
+				_, _ = w.Write([]byte(`{"id":"1"}`))
+			}))
+			defer mockServer.Close()
+
+			client := NewClient(Config{URL: mockServer.URL, AccessToken: "fake-token", TootRetry: retry.Policy{Attempts: 3}})
+			_, err := client.PostStatus("Hello world", TootOptions{IdempotencyKey: tt.idempotencyKey})
+			if tt.expectErr != (err != nil) {
+				t.Errorf("Expected error %v, got %v", tt.expectErr, err)
+			}
+			if requests != tt.expectRequests {
+				t.Errorf("Expected %d requests, got %d", tt.expectRequests, requests)
+			}
+		})
+	}
+}
+
+// Table-driven test for DeleteStatus
+func TestDeleteStatus(t *testing.T) {
+	tests := []struct {
+		name          string
+		statusCode    int
+		expectedError bool
+	}{
+		{"Deleted", http.StatusOK, false},
+		{"Already gone", http.StatusNotFound, false},
+		{"Forbidden", http.StatusForbidden, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var method, path string
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				method, path = r.Method, r.URL.Path
+				w.WriteHeader(tt.statusCode)
+			}))
+			defer mockServer.Close()
+
+			client := NewClient(Config{URL: mockServer.URL, AccessToken: "fake-token"})
+			err := client.DeleteStatus("42")
+			if (err != nil) != tt.expectedError {
+				t.Errorf("Expected error %v, got %v", tt.expectedError, err)
+			}
+			if method != "DELETE" || path != "/api/v1/statuses/42" {
+				t.Errorf("Expected DELETE /api/v1/statuses/42, got %s %s", method, path)
 			}
 		})
 	}
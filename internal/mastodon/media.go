@@ -0,0 +1,326 @@
+package mastodon
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/toozej/rss2mastodon/internal/media"
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// Fallback image limits used when the instance does not advertise its own (Mastodon 4.x defaults)
+const (
+	defaultImageSizeLimit   = 16 * 1024 * 1024
+	defaultImageMatrixLimit = 33177600
+	defaultMaxAttachments   = 4
+	defaultVideoSizeLimit   = 99 * 1024 * 1024
+)
+
+// imageDownloadFactor is how many times larger than the instance's image size limit images may
+// be downloaded, as they are re-encoded and downscaled to fit it before uploading
+const imageDownloadFactor = 4
+
+// maxDescriptionLength is how many characters Mastodon allows in a media description (alt text)
+const maxDescriptionLength = 1500
+
+// How often and for how long to wait for Mastodon to finish processing an uploaded attachment
+var (
+	mediaPollInterval      = 2 * time.Second
+	mediaProcessingTimeout = 5 * time.Minute
+)
+
+// Instance holds the parts of the /api/v1/instance response rss2mastodon cares about
+type Instance struct {
+	// Version is the server's version, which other servers than Mastodon suffix with their own
+	Version string `json:"version"`
+	URLs    struct {
+		// StreamingAPI is the base URL of the streaming API, e.g. wss://streaming.example.com
+		StreamingAPI string `json:"streaming_api"`
+	} `json:"urls"`
+	// MaxTootChars is the character limit of toots on Pleroma, Akkoma and glitch-soc instances
+	MaxTootChars  int `json:"max_toot_chars"`
+	Configuration struct {
+		Statuses struct {
+			MaxCharacters       int `json:"max_characters"`
+			MaxMediaAttachments int `json:"max_media_attachments"`
+		} `json:"statuses"`
+		MediaAttachments struct {
+			ImageSizeLimit   int64 `json:"image_size_limit"`
+			ImageMatrixLimit int64 `json:"image_matrix_limit"`
+			VideoSizeLimit   int64 `json:"video_size_limit"`
+		} `json:"media_attachments"`
+		Accounts struct {
+			// AllowCustomCSS is only reported by GoToSocial
+			AllowCustomCSS *bool `json:"allow_custom_css"`
+		} `json:"accounts"`
+	} `json:"configuration"`
+}
+
+// Attachment is a media attachment returned by the Mastodon media API
+type Attachment struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+// GetInstance fetches the Mastodon instance information
+func (c *Client) GetInstance() (*Instance, error) {
+	if c.config.URL == "" {
+		return nil, fmt.Errorf("mastodon URL must be set")
+	}
+
+	resp, err := c.api.Get(c.config.URL + "/api/v1/instance")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected HTTP status: %d", resp.StatusCode)
+	}
+
+	var instance Instance
+	if err := json.NewDecoder(resp.Body).Decode(&instance); err != nil {
+		return nil, fmt.Errorf("failed to parse instance information: %w", err)
+	}
+	return &instance, nil
+}
+
+// MediaLimits returns the instance's image upload limits, falling back to Mastodon defaults
+func (i *Instance) MediaLimits() media.Limits {
+	limits := media.Limits{
+		MaxBytes:  defaultImageSizeLimit,
+		MaxPixels: defaultImageMatrixLimit,
+	}
+	if i == nil {
+		return limits
+	}
+
+	if l := i.Configuration.MediaAttachments.ImageSizeLimit; l > 0 {
+		limits.MaxBytes = l
+	}
+	if l := i.Configuration.MediaAttachments.ImageMatrixLimit; l > 0 {
+		limits.MaxPixels = l
+	}
+	return limits
+}
+
+// MaxMediaAttachments returns how many attachments a single status may carry
+func (i *Instance) MaxMediaAttachments() int {
+	if i == nil || i.Configuration.Statuses.MaxMediaAttachments <= 0 {
+		return defaultMaxAttachments
+	}
+	return i.Configuration.Statuses.MaxMediaAttachments
+}
+
+// VideoSizeLimit returns the maximum size in bytes of video and audio uploads
+func (i *Instance) VideoSizeLimit() int64 {
+	if i == nil || i.Configuration.MediaAttachments.VideoSizeLimit <= 0 {
+		return defaultVideoSizeLimit
+	}
+	return i.Configuration.MediaAttachments.VideoSizeLimit
+}
+
+// getInstanceOrDefaults fetches the instance information, returning nil (meaning defaults) on failure
+func (c *Client) getInstanceOrDefaults() *Instance {
+	instance, err := c.GetInstance()
+	if err != nil {
+		log.Debug("Unable to fetch instance information, using default limits: ", err)
+		return nil
+	}
+	return instance
+}
+
+// UploadImages downloads and uploads up to the instance's maximum number of attachments
+// from the given images in order, returning the IDs of the uploaded attachments.
+// Images which fail to download or upload are skipped.
+func (c *Client) UploadImages(images []rss.Image) []string {
+	instance := c.getInstanceOrDefaults()
+	limits := instance.MediaLimits()
+
+	var mediaIDs []string
+	for _, image := range images {
+		if len(mediaIDs) >= instance.MaxMediaAttachments() {
+			break
+		}
+
+		data, err := media.Download(image.URL, limits.MaxBytes*imageDownloadFactor)
+		if err != nil {
+			log.Warnf("Failed to download image %s: %v", image.URL, err)
+			continue
+		}
+
+		attachment, err := c.uploadMedia(data, filenameFromURL(image.URL), image.Alt, limits)
+		if err != nil {
+			log.Warnf("Failed to upload image %s: %v", image.URL, err)
+			continue
+		}
+		mediaIDs = append(mediaIDs, attachment.ID)
+	}
+	return mediaIDs
+}
+
+// UploadEnclosures uploads the first video or audio enclosure which fits within the
+// instance's size limit, returning the ID of the uploaded attachment. Mastodon only
+// allows a single video or audio attachment per status, so at most one ID is returned.
+func (c *Client) UploadEnclosures(enclosures []rss.Enclosure, description string) []string {
+	instance := c.getInstanceOrDefaults()
+	sizeLimit := instance.VideoSizeLimit()
+
+	for _, enclosure := range enclosures {
+		if enclosure.Length > sizeLimit {
+			log.Infof("Skipping enclosure %s: %d bytes exceeds instance limit of %d bytes", enclosure.URL, enclosure.Length, sizeLimit)
+			continue
+		}
+
+		data, err := media.Download(enclosure.URL, sizeLimit)
+		if err != nil {
+			log.Warnf("Failed to download enclosure %s: %v", enclosure.URL, err)
+			continue
+		}
+		if int64(len(data)) > sizeLimit {
+			log.Infof("Skipping enclosure %s: %d bytes exceeds instance limit of %d bytes", enclosure.URL, len(data), sizeLimit)
+			continue
+		}
+
+		attachment, err := c.uploadMedia(data, filenameFromURL(enclosure.URL), description, instance.MediaLimits())
+		if err != nil {
+			log.Warnf("Failed to upload enclosure %s: %v", enclosure.URL, err)
+			continue
+		}
+		return []string{attachment.ID}
+	}
+	return nil
+}
+
+// filenameFromURL returns the last path element of a media URL, ignoring any query string
+func filenameFromURL(mediaURL string) string {
+	if u, err := url.Parse(mediaURL); err == nil && u.Path != "" {
+		return path.Base(u.Path)
+	}
+	return path.Base(mediaURL)
+}
+
+// UploadMedia strips metadata from and downscales the given image to fit the instance's limits,
+// then uploads it to Mastodon with the given description (alt text), returning the created attachment
+func (c *Client) UploadMedia(data []byte, filename string, description string) (*Attachment, error) {
+	return c.uploadMedia(data, filename, description, c.getInstanceOrDefaults().MediaLimits())
+}
+
+func (c *Client) uploadMedia(data []byte, filename string, description string, limits media.Limits) (*Attachment, error) {
+	data, contentType, err := media.Process(data, limits)
+	if err != nil {
+		return nil, fmt.Errorf("failed to process media %s: %w", filename, err)
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreatePart(map[string][]string{
+		"Content-Disposition": {fmt.Sprintf(`form-data; name="file"; filename=%q`, filename)},
+		"Content-Type":        {contentType},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write(data); err != nil {
+		return nil, err
+	}
+	if runes := []rune(description); len(runes) > maxDescriptionLength {
+		description = string(runes[:maxDescriptionLength-1]) + "…"
+	}
+	if description != "" {
+		if err := writer.WriteField("description", description); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	endpoints := mediaEndpoints(c.config.Flavor)
+	resp, err := c.postMedia(endpoints[0], body.Bytes(), writer.FormDataContentType())
+	if err == nil && resp.StatusCode == http.StatusNotFound {
+		// servers predating the asynchronous API (Mastodon < 3.1, some other implementations)
+		resp.Body.Close()
+		log.Debugf("Media API %s not found, uploading with %s", endpoints[0], endpoints[1])
+		resp, err = c.postMedia(endpoints[1], body.Bytes(), writer.FormDataContentType())
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// 202 Accepted means the upload is still being processed asynchronously
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+		return nil, fmt.Errorf("unexpected HTTP status: %d", resp.StatusCode)
+	}
+
+	var attachment Attachment
+	if err := json.NewDecoder(resp.Body).Decode(&attachment); err != nil {
+		return nil, fmt.Errorf("failed to parse media upload response: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusAccepted {
+		return c.waitForMedia(attachment.ID)
+	}
+	return &attachment, nil
+}
+
+// postMedia posts a multipart media upload to a media API endpoint
+func (c *Client) postMedia(endpoint string, body []byte, contentType string) (*http.Response, error) {
+	req, err := c.newRequest("POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	return c.uploads.Do(req)
+}
+
+// waitForMedia polls an asynchronously processed attachment until Mastodon has finished
+// processing it, since statuses cannot reference attachments which are still processing
+func (c *Client) waitForMedia(id string) (*Attachment, error) {
+	deadline := time.Now().Add(mediaProcessingTimeout)
+	for time.Now().Before(deadline) {
+		time.Sleep(mediaPollInterval)
+
+		req, err := c.newRequest("GET", "/api/v1/media/"+id, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := c.api.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		// 206 Partial Content means the attachment is still being processed
+		if resp.StatusCode == http.StatusPartialContent {
+			resp.Body.Close()
+			log.Debugf("Media %s is still processing", id)
+			continue
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("unexpected HTTP status: %d", resp.StatusCode)
+		}
+
+		var attachment Attachment
+		err = json.NewDecoder(resp.Body).Decode(&attachment)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse media response: %w", err)
+		}
+		return &attachment, nil
+	}
+
+	return nil, fmt.Errorf("media %s was not processed within %s", id, mediaProcessingTimeout)
+}
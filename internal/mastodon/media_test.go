@@ -0,0 +1,203 @@
+package mastodon
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// Test uploading media downscales images to the instance's limits and describes them
+func TestUploadMedia(t *testing.T) {
+	var uploaded []byte
+	var description string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/instance":
+			_, _ = w.Write([]byte(`{"configuration":{"media_attachments":{"image_size_limit":1048576,"image_matrix_limit":100}}}`))
+		case "/api/v2/media":
+			file, _, err := r.FormFile("file")
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			uploaded, _ = io.ReadAll(file)
+			description = r.FormValue("description")
+			_, _ = w.Write([]byte(`{"id":"42","type":"image"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer mockServer.Close()
+
+	client := NewClient(Config{URL: mockServer.URL, AccessToken: "fake-token"})
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 20, 20))); err != nil {
+		t.Fatalf("Failed to encode test image: %v", err)
+	}
+
+	attachment, err := client.UploadMedia(buf.Bytes(), "test.png", "A test image")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if attachment.ID != "42" {
+		t.Errorf("Expected attachment ID '42', got '%s'", attachment.ID)
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(uploaded))
+	if err != nil {
+		t.Fatalf("Failed to decode uploaded image: %v", err)
+	}
+	if cfg.Width*cfg.Height > 100 {
+		t.Errorf("Expected uploaded image to fit within 100 pixels, got %dx%d", cfg.Width, cfg.Height)
+	}
+	if description != "A test image" {
+		t.Errorf("Expected the description 'A test image', got %q", description)
+	}
+
+	// descriptions are cut to Mastodon's limit rather than failing the upload
+	if _, err := client.UploadMedia(buf.Bytes(), "test.png", strings.Repeat("é", 2000)); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if runes := []rune(description); len(runes) != maxDescriptionLength || runes[len(runes)-1] != '…' {
+		t.Errorf("Expected the description to be cut to %d characters, got %d", maxDescriptionLength, len(runes))
+	}
+}
+
+// Table-driven test for falling back to the v1 media API on servers without v2, and preferring
+// it on Pleroma and Akkoma
+func TestUploadMedia_V1Fallback(t *testing.T) {
+	tests := []struct {
+		name       string
+		flavor     string
+		v2         bool
+		expectedID string
+	}{
+		{"v2 available", "", true, "2"},
+		{"v1 only", "", false, "1"},
+		{"Akkoma prefers v1", FlavorAkkoma, true, "1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if _, _, err := r.FormFile("file"); err != nil && r.Method == "POST" {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				switch {
+				case r.URL.Path == "/api/v2/media" && tt.v2:
+					_, _ = w.Write([]byte(`{"id":"2","type":"image"}`))
+				case r.URL.Path == "/api/v1/media":
+					_, _ = w.Write([]byte(`{"id":"1","type":"image"}`))
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer mockServer.Close()
+
+			var buf bytes.Buffer
+			if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 2, 2))); err != nil {
+				t.Fatalf("Failed to encode test image: %v", err)
+			}
+
+			client := NewClient(Config{URL: mockServer.URL, AccessToken: "fake-token", Flavor: tt.flavor})
+			attachment, err := client.UploadMedia(buf.Bytes(), "test.png", "")
+			if err != nil || attachment.ID != tt.expectedID {
+				t.Errorf("Expected attachment %s, got %+v (%v)", tt.expectedID, attachment, err)
+			}
+		})
+	}
+}
+
+// Test uploading gallery images is capped at the instance's maximum number of attachments
+func TestUploadImages(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatalf("Failed to encode test image: %v", err)
+	}
+
+	var descriptions []string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/instance":
+			_, _ = w.Write([]byte(`{"configuration":{"statuses":{"max_media_attachments":2}}}`))
+		case "/api/v2/media":
+			descriptions = append(descriptions, r.FormValue("description"))
+			_, _ = w.Write([]byte(`{"id":"` + r.FormValue("description") + `","type":"image"}`))
+		case "/missing.png":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			_, _ = w.Write(buf.Bytes())
+		}
+	}))
+	defer mockServer.Close()
+
+	client := NewClient(Config{URL: mockServer.URL, AccessToken: "fake-token"})
+
+	mediaIDs := client.UploadImages([]rss.Image{
+		{URL: mockServer.URL + "/missing.png", Alt: "missing"},
+		{URL: mockServer.URL + "/one.png", Alt: "one"},
+		{URL: mockServer.URL + "/two.png", Alt: "two"},
+		{URL: mockServer.URL + "/three.png", Alt: "three"},
+	})
+
+	if len(mediaIDs) != 2 || mediaIDs[0] != "one" || mediaIDs[1] != "two" {
+		t.Errorf("Expected media IDs [one two], got %v", mediaIDs)
+	}
+	if len(descriptions) != 2 {
+		t.Errorf("Expected 2 uploads, got %d", len(descriptions))
+	}
+}
+
+// Test uploading a video enclosure waits for asynchronous processing to finish
+func TestUploadEnclosures(t *testing.T) {
+	mediaPollInterval = time.Millisecond
+	defer func() { mediaPollInterval = 2 * time.Second }()
+
+	polls := 0
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/instance":
+			_, _ = w.Write([]byte(`{"configuration":{"media_attachments":{"video_size_limit":1000}}}`))
+		case "/api/v2/media":
+			w.WriteHeader(http.StatusAccepted)
+			_, _ = w.Write([]byte(`{"id":"7","type":"video"}`))
+		case "/api/v1/media/7":
+			polls++
+			if polls < 3 {
+				w.WriteHeader(http.StatusPartialContent)
+				return
+			}
+			_, _ = w.Write([]byte(`{"id":"7","type":"video","url":"https://example.com/7.mp4"}`))
+		case "/large.mp4":
+			_, _ = w.Write(make([]byte, 2000))
+		default:
+			_, _ = w.Write([]byte("small video"))
+		}
+	}))
+	defer mockServer.Close()
+
+	client := NewClient(Config{URL: mockServer.URL, AccessToken: "fake-token"})
+
+	mediaIDs := client.UploadEnclosures([]rss.Enclosure{
+		{URL: mockServer.URL + "/declared-large.mp4", Type: "video/mp4", Length: 5000},
+		{URL: mockServer.URL + "/large.mp4", Type: "video/mp4"},
+		{URL: mockServer.URL + "/small.mp4?token=abc", Type: "video/mp4"},
+	}, "Episode 1")
+
+	if len(mediaIDs) != 1 || mediaIDs[0] != "7" {
+		t.Errorf("Expected media IDs [7], got %v", mediaIDs)
+	}
+	if polls != 3 {
+		t.Errorf("Expected 3 polls for processing status, got %d", polls)
+	}
+}
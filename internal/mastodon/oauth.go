@@ -0,0 +1,101 @@
+package mastodon
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/toozej/rss2mastodon/internal/httpclient"
+)
+
+// OutOfBandRedirect is the redirect URI of apps without a web server, for which the instance
+// shows the authorization code to copy instead of redirecting to the app
+const OutOfBandRedirect = "urn:ietf:wg:oauth:2.0:oob"
+
+// App is an OAuth application registered with an instance
+type App struct {
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	RedirectURI  string `json:"redirect_uri"`
+}
+
+// oauthClient returns an HTTP client for the unauthenticated requests registering an app and
+// obtaining an access token
+func oauthClient() *http.Client {
+	return httpclient.New("mastodon oauth", DefaultTimeout)
+}
+
+// postForm sends a form to an instance's endpoint and decodes the JSON response
+func postForm(instanceURL string, endpoint string, form url.Values, out interface{}) error {
+	resp, err := oauthClient().PostForm(strings.TrimSuffix(instanceURL, "/")+endpoint, form)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected HTTP status: %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// RegisterApp registers an OAuth application with the instance, which accounts can then
+// authorize with the given space-separated scopes
+func RegisterApp(instanceURL string, name string, website string, scopes string) (*App, error) {
+	form := url.Values{
+		"client_name":   {name},
+		"redirect_uris": {OutOfBandRedirect},
+		"scopes":        {scopes},
+	}
+	if website != "" {
+		form.Set("website", website)
+	}
+	var app App
+	if err := postForm(instanceURL, "/api/v1/apps", form, &app); err != nil {
+		return nil, err
+	}
+	if app.ClientID == "" || app.ClientSecret == "" {
+		return nil, fmt.Errorf("the instance returned no client credentials")
+	}
+	if app.RedirectURI == "" {
+		app.RedirectURI = OutOfBandRedirect
+	}
+	return &app, nil
+}
+
+// AuthorizeURL returns the page where the user logs in and authorizes the app, which then
+// shows the authorization code to exchange for an access token
+func AuthorizeURL(instanceURL string, app *App, scopes string) string {
+	query := url.Values{
+		"client_id":     {app.ClientID},
+		"redirect_uri":  {app.RedirectURI},
+		"response_type": {"code"},
+		"scopes":        {scopes},
+	}
+	return strings.TrimSuffix(instanceURL, "/") + "/oauth/authorize?" + query.Encode()
+}
+
+// ObtainToken exchanges an authorization code for an access token of the account which
+// authorized the app
+func ObtainToken(instanceURL string, app *App, code string, scopes string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {strings.TrimSpace(code)},
+		"client_id":     {app.ClientID},
+		"client_secret": {app.ClientSecret},
+		"redirect_uri":  {app.RedirectURI},
+		"scope":         {scopes},
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := postForm(instanceURL, "/oauth/token", form, &token); err != nil {
+		return "", err
+	}
+	if token.AccessToken == "" {
+		return "", fmt.Errorf("the instance returned no access token")
+	}
+	return token.AccessToken, nil
+}
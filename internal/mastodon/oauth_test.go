@@ -0,0 +1,58 @@
+package mastodon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// Test registering an app and exchanging an authorization code for an access token
+func TestOAuthFlow(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("Failed to parse form: %v", err)
+		}
+		switch r.URL.Path {
+		case "/api/v1/apps":
+			if r.FormValue("client_name") != "rss2mastodon" || r.FormValue("redirect_uris") != OutOfBandRedirect || r.FormValue("scopes") != "read write" {
+				t.Errorf("Unexpected app registration: %v", r.Form)
+			}
+			_, _ = w.Write([]byte(`{"client_id":"id","client_secret":"secret","redirect_uri":"` + OutOfBandRedirect + `"}`))
+		case "/oauth/token":
+			if r.FormValue("grant_type") != "authorization_code" || r.FormValue("code") != "code" || r.FormValue("client_secret") != "secret" {
+				t.Errorf("Unexpected token request: %v", r.Form)
+			}
+			_, _ = w.Write([]byte(`{"access_token":"token","token_type":"Bearer"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer mockServer.Close()
+
+	app, err := RegisterApp(mockServer.URL+"/", "rss2mastodon", "", "read write")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	authorize, err := url.Parse(AuthorizeURL(mockServer.URL, app, "read write"))
+	if err != nil {
+		t.Fatalf("Invalid authorization URL: %v", err)
+	}
+	if authorize.Path != "/oauth/authorize" || authorize.Query().Get("client_id") != "id" || authorize.Query().Get("response_type") != "code" {
+		t.Errorf("Unexpected authorization URL %s", authorize)
+	}
+
+	token, err := ObtainToken(mockServer.URL, app, " code\n", "read write")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if token != "token" {
+		t.Errorf("Expected token, got %q", token)
+	}
+
+	if _, err := ObtainToken(mockServer.URL+"/missing", app, "code", "read write"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Expected an HTTP status error, got %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+package mastodon
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/toozej/rss2mastodon/internal/httpclient"
+	"github.com/toozej/rss2mastodon/internal/retry"
+)
+
+// defaultRateLimitWait is how long requests are held after the instance refused one with HTTP
+// 429 without saying until when
+const defaultRateLimitWait = time.Minute
+
+// RateLimitError is returned for requests the instance's rate limit doesn't allow before Reset,
+// because it refused them, or because no more than the reserve of requests were left
+type RateLimitError struct {
+	Reset time.Time
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("Mastodon rate limit used up until %s", e.Reset.Format(time.RFC3339))
+}
+
+// rateLimit is what the instance last reported about the rate limit of a kind of request
+type rateLimit struct {
+	remaining int
+	reset     time.Time
+}
+
+// rateLimits holds the rate limits reported by instances, by account and kind of request. They
+// are shared by all clients, as one is created for every call.
+var rateLimits = struct {
+	sync.Mutex
+	byKey map[string]rateLimit
+}{byKey: map[string]rateLimit{}}
+
+// rateLimitKind returns which of the instance's rate limits a request counts against: creating
+// statuses and uploading media have their own, besides the one of all API requests
+func rateLimitKind(req *http.Request) string {
+	switch {
+	case req.Method == "POST" && req.URL.Path == "/api/v1/statuses":
+		return "statuses"
+	case req.Method == "POST" && (strings.HasPrefix(req.URL.Path, "/api/v1/media") || strings.HasPrefix(req.URL.Path, "/api/v2/media")):
+		return "media"
+	}
+	return "api"
+}
+
+// parseRateLimit returns the remaining requests and the reset time of the X-RateLimit-Remaining
+// and X-RateLimit-Reset headers, if the response has them
+func parseRateLimit(header http.Header) (rateLimit, bool) {
+	remaining, err := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return rateLimit{}, false
+	}
+	reset, err := time.Parse(time.RFC3339, header.Get("X-RateLimit-Reset"))
+	if err != nil {
+		return rateLimit{}, false
+	}
+	return rateLimit{remaining: remaining, reset: reset}, true
+}
+
+// retryAfter returns when a request refused with HTTP 429 may be sent again: the Retry-After
+// header's time, in seconds or as an HTTP date, or else the rate limit's reset time
+func retryAfter(header http.Header, now time.Time) time.Time {
+	if value := header.Get("Retry-After"); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil {
+			return now.Add(time.Duration(seconds) * time.Second)
+		}
+		if date, err := http.ParseTime(value); err == nil {
+			return date
+		}
+	}
+	if limit, ok := parseRateLimit(header); ok && limit.reset.After(now) {
+		return limit.reset
+	}
+	return now.Add(defaultRateLimitWait)
+}
+
+// rateLimited keeps track of the account's rate limits from the instance's responses. Requests
+// are refused with a RateLimitError instead of being sent while no more than reserve requests
+// are left until the limit resets, and when the instance refused them with HTTP 429, so they
+// aren't retried before then.
+func rateLimited(account string, reserve int) httpclient.Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return httpclient.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			key := account + " " + rateLimitKind(req)
+			now := time.Now()
+
+			rateLimits.Lock()
+			limit, ok := rateLimits.byKey[key]
+			rateLimits.Unlock()
+			if ok && limit.remaining <= reserve && now.Before(limit.reset) {
+				return nil, retry.Permanent(&RateLimitError{Reset: limit.reset})
+			}
+
+			resp, err := next.RoundTrip(req)
+			if err != nil {
+				return nil, err
+			}
+			if resp.StatusCode == http.StatusTooManyRequests {
+				limit = rateLimit{remaining: 0, reset: retryAfter(resp.Header, now)}
+			} else if limit, ok = parseRateLimit(resp.Header); !ok {
+				return resp, nil
+			}
+
+			rateLimits.Lock()
+			rateLimits.byKey[key] = limit
+			rateLimits.Unlock()
+			if limit.remaining <= reserve {
+				log.Warnf("Mastodon rate limit of %s requests used up until %s", rateLimitKind(req), limit.reset.Format(time.RFC1123))
+			}
+
+			if resp.StatusCode == http.StatusTooManyRequests {
+				_, _ = io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+				return nil, retry.Permanent(&RateLimitError{Reset: limit.reset})
+			}
+			return resp, nil
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package mastodon
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Table-driven test for when a request refused with HTTP 429 may be sent again
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 5, 6, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		header   http.Header
+		expected time.Time
+	}{
+		{"Seconds", http.Header{"Retry-After": {"120"}}, now.Add(2 * time.Minute)},
+		{"HTTP date", http.Header{"Retry-After": {"Mon, 06 May 2024 12:30:00 GMT"}}, now.Add(30 * time.Minute)},
+		{"Rate limit reset", http.Header{"X-Ratelimit-Remaining": {"0"}, "X-Ratelimit-Reset": {"2024-05-06T12:05:00.000Z"}}, now.Add(5 * time.Minute)},
+		{"Nothing", http.Header{}, now.Add(defaultRateLimitWait)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryAfter(tt.header, now); !got.Equal(tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+// Table-driven test for holding requests once the instance's rate limit is used up
+func TestRateLimited(t *testing.T) {
+	reset := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	tests := []struct {
+		name           string
+		remaining      string
+		statusCode     int
+		reserve        int
+		expectRequests int
+		expectLimited  bool
+	}{
+		{"Requests left", "10", http.StatusOK, 0, 2, false},
+		{"Used up", "0", http.StatusOK, 0, 1, true},
+		{"Reserve reached", "5", http.StatusOK, 5, 1, true},
+		{"Refused", "0", http.StatusTooManyRequests, 0, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				w.Header().Set("X-RateLimit-Remaining", tt.remaining)
+				w.Header().Set("X-RateLimit-Reset", reset.Format(time.RFC3339))
+				w.WriteHeader(tt.statusCode)
+				_, _ = w.Write([]byte(`{"id":"1"}`))
+			}))
+			defer mockServer.Close()
+
+			client := NewClient(Config{URL: mockServer.URL, AccessToken: "fake-token", RateLimitReserve: tt.reserve})
+			_, err := client.PostStatus("First", TootOptions{})
+			if tt.statusCode == http.StatusOK && err != nil {
+				t.Fatalf("Expected the first status to be posted, got %v", err)
+			}
+			_, err = client.PostStatus("Second", TootOptions{})
+
+			var limited *RateLimitError
+			if errors.As(err, &limited) != tt.expectLimited {
+				t.Fatalf("Expected rate limited: %v, got %v", tt.expectLimited, err)
+			}
+			if tt.expectLimited && !limited.Reset.Equal(reset) {
+				t.Errorf("Expected the limit to reset at %v, got %v", reset, limited.Reset)
+			}
+			if requests != tt.expectRequests {
+				t.Errorf("Expected %d requests, got %d", tt.expectRequests, requests)
+			}
+
+			// other kinds of requests have their own rate limit
+			if _, err := client.GetStatus("1"); tt.statusCode == http.StatusOK && err != nil {
+				t.Errorf("Expected fetching a status not to be rate limited, got %v", err)
+			}
+		})
+	}
+}
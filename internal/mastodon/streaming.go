@@ -0,0 +1,105 @@
+package mastodon
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"regexp"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Notification is a notification of the bot account, e.g. a mention
+type Notification struct {
+	ID      string  `json:"id"`
+	Type    string  `json:"type"`
+	Account Account `json:"account"`
+	// Status is the status mentioning the bot, for mentions
+	Status *Status `json:"status"`
+}
+
+var (
+	htmlBreakRegex = regexp.MustCompile(`(?i)<br\s*/?>|</p>`)
+	htmlTagRegex   = regexp.MustCompile(`<[^>]*>`)
+)
+
+// streamingURL returns the base URL of the instance's streaming API, which may be on another
+// host, as an HTTP URL for server-sent events
+func (c *Client) streamingURL() string {
+	base := c.config.URL
+	if instance, err := c.GetInstance(); err == nil && instance.URLs.StreamingAPI != "" {
+		base = instance.URLs.StreamingAPI
+	}
+	base = strings.Replace(base, "wss://", "https://", 1)
+	base = strings.Replace(base, "ws://", "http://", 1)
+	return strings.TrimSuffix(base, "/")
+}
+
+// StreamNotifications listens to the notifications of the bot account on the streaming API,
+// calling handle for each of them, until the connection is closed or ctx is cancelled
+func (c *Client) StreamNotifications(ctx context.Context, handle func(Notification)) error {
+	if c.config.URL == "" || c.config.AccessToken == "" {
+		return fmt.Errorf("mastodon URL and token must be set")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", c.streamingURL()+"/api/v1/streaming/user/notification", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := c.stream.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected HTTP status: %d", resp.StatusCode)
+	}
+
+	// server-sent events are "event:" and "data:" lines ended by an empty line, lines starting
+	// with a colon are heartbeats
+	var event string
+	var data strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if event == "notification" {
+				var notification Notification
+				if err := json.Unmarshal([]byte(data.String()), &notification); err != nil {
+					log.Warn("Failed to parse streamed notification: ", err)
+				} else {
+					handle(notification)
+				}
+			}
+			event = ""
+			data.Reset()
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("stream closed")
+}
+
+// PlainText returns the text of a status' HTML content, e.g. to parse commands
+func PlainText(content string) string {
+	text := htmlBreakRegex.ReplaceAllString(content, "\n")
+	text = htmlTagRegex.ReplaceAllString(text, "")
+	return strings.TrimSpace(html.UnescapeString(text))
+}
@@ -0,0 +1,75 @@
+package mastodon
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Test notifications are read from the server-sent events of the streaming API
+func TestStreamNotifications(t *testing.T) {
+	var streamingHost string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/instance":
+			_, _ = w.Write([]byte(`{"urls":{"streaming_api":"ws://` + streamingHost + `"}}`))
+		case "/api/v1/streaming/user/notification":
+			if r.Header.Get("Authorization") != "Bearer fake-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(":thump\n\n" +
+				"event: notification\n" +
+				`data: {"id":"1","type":"mention","account":{"id":"7","acct":"admin@example.social"},"status":{"id":"42","content":"<p>@bot latest</p>","visibility":"direct"}}` + "\n\n" +
+				"event: notification\ndata: not json\n\n" +
+				"event: delete\ndata: 41\n\n" +
+				"event: notification\n" +
+				`data: {"id":"2","type":"follow","account":{"id":"8","acct":"fan"}}` + "\n\n"))
+		}
+	}))
+	defer mockServer.Close()
+	streamingHost = mockServer.Listener.Addr().String()
+
+	// the streaming API is found through the instance, not the configured URL's path
+	client := NewClient(Config{URL: mockServer.URL, AccessToken: "fake-token"})
+
+	var notifications []Notification
+	err := client.StreamNotifications(context.Background(), func(n Notification) {
+		notifications = append(notifications, n)
+	})
+	if err == nil {
+		t.Errorf("Expected an error once the stream is closed")
+	}
+	if len(notifications) != 2 {
+		t.Fatalf("Expected 2 notifications, got %+v", notifications)
+	}
+	mention := notifications[0]
+	if mention.Type != "mention" || mention.Account.ID != "7" || mention.Status == nil || mention.Status.ID != "42" || mention.Status.Visibility != "direct" {
+		t.Errorf("Unexpected mention %+v", mention)
+	}
+	if notifications[1].Type != "follow" || notifications[1].Status != nil {
+		t.Errorf("Unexpected follow %+v", notifications[1])
+	}
+}
+
+// Table-driven test for extracting the text of a status' HTML content
+func TestPlainText(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{"Mention", `<p><span class="h-card"><a href="https://example.social/@bot" class="u-url mention">@<span>bot</span></a></span> pause example.com</p>`, "@bot pause example.com"},
+		{"Entities", "<p>Tom &amp; Jerry&#39;s</p>", "Tom & Jerry's"},
+		{"Line breaks", "<p>one<br>two</p><p>three</p>", "one\ntwo\nthree"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PlainText(tt.content); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package mastodon
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// threadWordRegex matches the words of a status along with the whitespace following them
+var threadWordRegex = regexp.MustCompile(`\S+\s*`)
+
+// SplitStatus splits text into numbered segments of at most limit characters each as counted by
+// StatusLength, e.g. "… 1/3", cutting it between words. Links are never cut. Text which fits
+// within limit is returned as is, as the only segment.
+func SplitStatus(text string, limit int) []string {
+	text = strings.TrimSpace(text)
+	if StatusLength(text) <= limit {
+		return []string{text}
+	}
+
+	// the numbering takes more room the more segments there are, so split again until it fits
+	for digits := 1; ; digits++ {
+		// " 12/12"
+		segments := splitWords(text, limit-2*digits-2)
+		if len(strconv.Itoa(len(segments))) <= digits {
+			for i := range segments {
+				segments[i] = fmt.Sprintf("%s %d/%d", segments[i], i+1, len(segments))
+			}
+			return segments
+		}
+	}
+}
+
+// splitWords packs the words of text into as few segments of at most limit characters as
+// possible, keeping their line breaks. Words too long for a segment of their own are cut.
+func splitWords(text string, limit int) []string {
+	limit = max(limit, 1)
+	var segments []string
+	var current string
+	for _, word := range threadWordRegex.FindAllString(text, -1) {
+		if current != "" && StatusLength(strings.TrimRightFunc(current+word, unicode.IsSpace)) > limit {
+			segments = append(segments, strings.TrimRightFunc(current, unicode.IsSpace))
+			current = ""
+		}
+		for current == "" && StatusLength(strings.TrimRightFunc(word, unicode.IsSpace)) > limit {
+			runes := []rune(word)
+			if len(runes) <= limit {
+				break
+			}
+			segments = append(segments, string(runes[:limit]))
+			word = string(runes[limit:])
+		}
+		current += word
+	}
+	if current = strings.TrimRightFunc(current, unicode.IsSpace); current != "" {
+		segments = append(segments, current)
+	}
+	return segments
+}
+
+// PostThread posts content, if it exceeds the client's character limit, as a thread of numbered
+// statuses each replying to the previous one instead of truncating it. Media and polls are attached
+// to the first status, and statuses scheduled for later aren't split, as they can't be replied to yet.
+// It returns the statuses posted, the first one starting the thread, along with the error which
+// stopped posting the rest of them.
+func (c *Client) PostThread(content string, opts TootOptions) ([]*Status, error) {
+	limit := c.config.MaxCharacters - StatusLength(opts.SpoilerText)
+	if c.config.MaxCharacters <= 0 || !opts.ScheduledAt.IsZero() || StatusLength(content) <= limit {
+		status, err := c.PostStatus(content, opts)
+		if err != nil {
+			return nil, err
+		}
+		return []*Status{status}, nil
+	}
+
+	segments := SplitStatus(content, limit)
+	idempotencyKey := opts.IdempotencyKey
+	var statuses []*Status
+	for i, segment := range segments {
+		if idempotencyKey != "" {
+			opts.IdempotencyKey = fmt.Sprintf("%s-%d", idempotencyKey, i+1)
+		}
+		status, err := c.PostStatus(segment, opts)
+		if err != nil {
+			return statuses, fmt.Errorf("failed to post status %d/%d of thread: %w", i+1, len(segments), err)
+		}
+		statuses = append(statuses, status)
+		opts.MediaIDs = nil
+		opts.Poll = nil
+		opts.InReplyToID = status.ID
+	}
+	return statuses, nil
+}
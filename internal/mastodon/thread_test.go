@@ -0,0 +1,88 @@
+package mastodon
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Table-driven test for splitting long statuses into numbered segments
+func TestSplitStatus(t *testing.T) {
+	link := "https://example.com/a-rather-long-link-to-a-post"
+	tests := []struct {
+		name     string
+		text     string
+		limit    int
+		expected []string
+	}{
+		{"Fits", "Hello world", 11, []string{"Hello world"}},
+		{"Split between words", "one two three four five", 13, []string{"one two 1/3", "three 2/3", "four five 3/3"}},
+		{"Line breaks kept", "one\ntwo three four", 14, []string{"one\ntwo 1/2", "three four 2/2"}},
+		{"Links not cut", "Read this " + link, 30, []string{"Read this 1/2", link + " 2/2"}},
+		{"Long word cut", "abcdefghij", 8, []string{"abcd 1/3", "efgh 2/3", "ij 3/3"}},
+		// numbering with two digits leaves room for just one word per segment
+		{"More segments", strings.Repeat("ab ", 10), 8, []string{
+			"ab 1/10", "ab 2/10", "ab 3/10", "ab 4/10", "ab 5/10", "ab 6/10", "ab 7/10", "ab 8/10", "ab 9/10", "ab 10/10",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SplitStatus(tt.text, tt.limit)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+// Table-driven test for posting long statuses as a reply chain
+func TestPostThread(t *testing.T) {
+	tests := []struct {
+		name          string
+		maxCharacters int
+		opts          TootOptions
+		expected      []string
+		replies       []string
+		keys          []string
+	}{
+		{"Fits", 100, TootOptions{IdempotencyKey: "key"}, []string{"one two three four five"}, []string{""}, []string{"key"}},
+		{"Unlimited", 0, TootOptions{}, []string{"one two three four five"}, []string{""}, []string{""}},
+		{"Thread", 13, TootOptions{IdempotencyKey: "key"},
+			[]string{"one two 1/3", "three 2/3", "four five 3/3"}, []string{"", "1", "2"}, []string{"key-1", "key-2", "key-3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var statuses, replies, keys []string
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				statuses = append(statuses, r.FormValue("status"))
+				replies = append(replies, r.FormValue("in_reply_to_id"))
+				keys = append(keys, r.Header.Get("Idempotency-Key"))
+				_, _ = fmt.Fprintf(w, `{"id":"%d"}`, len(statuses))
+			}))
+			defer mockServer.Close()
+
+			client := NewClient(Config{URL: mockServer.URL, AccessToken: "fake-token", MaxCharacters: tt.maxCharacters})
+			posted, err := client.PostThread("one two three four five", tt.opts)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if len(posted) != len(tt.expected) || posted[0].ID != "1" {
+				t.Errorf("Expected %d statuses starting with 1, got %v", len(tt.expected), posted)
+			}
+			if !reflect.DeepEqual(statuses, tt.expected) {
+				t.Errorf("Expected statuses %q, got %q", tt.expected, statuses)
+			}
+			if !reflect.DeepEqual(replies, tt.replies) {
+				t.Errorf("Expected replies to %q, got %q", tt.replies, replies)
+			}
+			if !reflect.DeepEqual(keys, tt.keys) {
+				t.Errorf("Expected idempotency keys %q, got %q", tt.keys, keys)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package media
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"math"
+	"net/http"
+	"time"
+
+	// register GIF decoder so image.DecodeConfig can identify GIFs
+	_ "image/gif"
+
+	"github.com/toozej/rss2mastodon/internal/httpclient"
+)
+
+// Limits describes the maximum size of an image attachment accepted by the Mastodon instance
+type Limits struct {
+	// MaxBytes is the maximum encoded file size in bytes, 0 means unlimited
+	MaxBytes int64
+	// MaxPixels is the maximum width*height of the image, 0 means unlimited
+	MaxPixels int64
+}
+
+// jpegQuality is the quality used when re-encoding JPEG images
+const jpegQuality = 85
+
+// maxShrinkSteps bounds how many times an image is shrunk to fit under Limits.MaxBytes
+const maxShrinkSteps = 8
+
+// maxPixelsFactor bounds how far above Limits.MaxPixels an image may be. Larger images are
+// rejected before decoding, as a small file can claim dimensions which take gigabytes to decode.
+const maxPixelsFactor = 16
+
+// maxDecodePixels bounds the size of images decoded when Limits.MaxPixels is unlimited
+const maxDecodePixels = 256 * 1024 * 1024
+
+// Process prepares an image for upload to Mastodon. JPEG and PNG images are decoded and
+// re-encoded, which drops any EXIF/GPS metadata after applying the EXIF orientation, and are
+// downscaled to fit within limits. Images far larger than limits.MaxPixels are rejected. Other formats (GIF, WebP, video, ...) are returned unchanged along with their detected content type.
+func Process(data []byte, limits Limits) ([]byte, string, error) {
+	contentType := http.DetectContentType(data)
+
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || (format != "jpeg" && format != "png") {
+		return data, contentType, nil
+	}
+
+	maxPixels := int64(maxDecodePixels)
+	if limits.MaxPixels > 0 {
+		maxPixels = limits.MaxPixels * maxPixelsFactor
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > maxPixels {
+		return nil, "", fmt.Errorf("%s image of %dx%d pixels is too large to process", format, cfg.Width, cfg.Height)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode %s image: %w", format, err)
+	}
+	if format == "jpeg" {
+		img = orient(img, exifOrientation(data))
+	}
+
+	bounds := img.Bounds()
+	width, height := fitPixels(bounds.Dx(), bounds.Dy(), limits.MaxPixels)
+	if width != bounds.Dx() || height != bounds.Dy() {
+		img = resize(img, width, height)
+	}
+
+	for step := 0; ; step++ {
+		out, err := encode(img, format)
+		if err != nil {
+			return nil, "", err
+		}
+
+		if limits.MaxBytes <= 0 || int64(len(out)) <= limits.MaxBytes {
+			return out, "image/" + format, nil
+		}
+		if step >= maxShrinkSteps {
+			return nil, "", fmt.Errorf("image is %d bytes after processing, exceeding limit of %d bytes", len(out), limits.MaxBytes)
+		}
+
+		// shrink each side by 25% and try again
+		bounds = img.Bounds()
+		img = resize(img, max(1, bounds.Dx()*3/4), max(1, bounds.Dy()*3/4))
+	}
+}
+
+// fitPixels scales width and height down, preserving aspect ratio, so width*height <= maxPixels
+func fitPixels(width, height int, maxPixels int64) (int, int) {
+	if maxPixels <= 0 || int64(width)*int64(height) <= maxPixels {
+		return width, height
+	}
+
+	scale := math.Sqrt(float64(maxPixels) / float64(int64(width)*int64(height)))
+	return max(1, int(float64(width)*scale)), max(1, int(float64(height)*scale))
+}
+
+// encode writes img in the given format without any metadata
+func encode(img image.Image, format string) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case "jpeg":
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+	case "png":
+		err = png.Encode(&buf, img)
+	default:
+		err = fmt.Errorf("unsupported image format: %s", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// resize downscales src to width x height by averaging the source pixels covered by each destination pixel
+func resize(src image.Image, width, height int) image.Image {
+	bounds := src.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), src, bounds.Min, draw.Src)
+
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := y * srcH / height
+		y1 := max(y0+1, (y+1)*srcH/height)
+		for x := 0; x < width; x++ {
+			x0 := x * srcW / width
+			x1 := max(x0+1, (x+1)*srcW/width)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				row := rgba.Pix[sy*rgba.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					r += uint64(p[0])
+					g += uint64(p[1])
+					b += uint64(p[2])
+					a += uint64(p[3])
+					n++
+				}
+			}
+
+			i := dst.PixOffset(x, y)
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(b / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// maxDownloadBytes caps how much data Download will read from a remote server when the caller
+// doesn't know a limit
+const maxDownloadBytes = 100 * 1024 * 1024
+
+// Download fetches the media file at the given URL, reading at most maxBytes of it, or
+// maxDownloadBytes if maxBytes is 0
+func Download(mediaURL string, maxBytes int64) ([]byte, error) {
+	if maxBytes <= 0 {
+		maxBytes = maxDownloadBytes
+	}
+	client := httpclient.New("media", 60*time.Second)
+
+	resp, err := client.Get(mediaURL)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected HTTP status: %d", resp.StatusCode)
+	}
+
+	if resp.ContentLength > maxBytes {
+		return nil, fmt.Errorf("media of %d bytes exceeds maximum download size of %d bytes", resp.ContentLength, maxBytes)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read media: %w", err)
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, fmt.Errorf("media exceeds maximum download size of %d bytes", maxBytes)
+	}
+	return data, nil
+}
@@ -0,0 +1,258 @@
+package media
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Helper function to build a solid colour test image
+func testImage(width, height int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	return img
+}
+
+// Helper function to insert an EXIF APP1 segment after the JPEG SOI marker
+func withExif(jpg []byte, exif []byte) []byte {
+	payload := append([]byte("Exif\x00\x00"), exif...)
+	segment := []byte{0xFF, 0xE1, byte((len(payload) + 2) >> 8), byte(len(payload) + 2)}
+	segment = append(segment, payload...)
+
+	out := append([]byte{}, jpg[:2]...)
+	out = append(out, segment...)
+	return append(out, jpg[2:]...)
+}
+
+// Test that EXIF metadata is stripped from JPEG images
+func TestProcess_StripsExif(t *testing.T) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, testImage(64, 48), nil); err != nil {
+		t.Fatalf("Failed to encode test image: %v", err)
+	}
+	input := withExif(buf.Bytes(), []byte("GPS 51.5007N 0.1246W"))
+	if !bytes.Contains(input, []byte("Exif")) {
+		t.Fatalf("Expected test image to contain EXIF data")
+	}
+
+	out, contentType, err := Process(input, Limits{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if contentType != "image/jpeg" {
+		t.Errorf("Expected content type 'image/jpeg', got '%s'", contentType)
+	}
+	if bytes.Contains(out, []byte("Exif")) || bytes.Contains(out, []byte("GPS")) {
+		t.Errorf("Expected EXIF data to be stripped")
+	}
+}
+
+// Table-driven test for downscaling images to fit limits
+func TestProcess_Downscale(t *testing.T) {
+	tests := []struct {
+		name       string
+		width      int
+		height     int
+		limits     Limits
+		wantWidth  int
+		wantHeight int
+	}{
+		{
+			name:       "Within limits",
+			width:      100,
+			height:     50,
+			limits:     Limits{MaxPixels: 10000},
+			wantWidth:  100,
+			wantHeight: 50,
+		},
+		{
+			name:       "Exceeds pixel limit",
+			width:      400,
+			height:     200,
+			limits:     Limits{MaxPixels: 20000},
+			wantWidth:  200,
+			wantHeight: 100,
+		},
+		{
+			name:       "No limits",
+			width:      400,
+			height:     200,
+			limits:     Limits{},
+			wantWidth:  400,
+			wantHeight: 200,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := png.Encode(&buf, testImage(tt.width, tt.height)); err != nil {
+				t.Fatalf("Failed to encode test image: %v", err)
+			}
+
+			out, contentType, err := Process(buf.Bytes(), tt.limits)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if contentType != "image/png" {
+				t.Errorf("Expected content type 'image/png', got '%s'", contentType)
+			}
+
+			cfg, _, err := image.DecodeConfig(bytes.NewReader(out))
+			if err != nil {
+				t.Fatalf("Failed to decode processed image: %v", err)
+			}
+			if cfg.Width != tt.wantWidth || cfg.Height != tt.wantHeight {
+				t.Errorf("Expected %dx%d, got %dx%d", tt.wantWidth, tt.wantHeight, cfg.Width, cfg.Height)
+			}
+		})
+	}
+}
+
+// Test that images are shrunk until they fit the byte limit
+func TestProcess_MaxBytes(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, testImage(256, 256)); err != nil {
+		t.Fatalf("Failed to encode test image: %v", err)
+	}
+
+	limit := int64(buf.Len() / 4)
+	out, _, err := Process(buf.Bytes(), Limits{MaxBytes: limit})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if int64(len(out)) > limit {
+		t.Errorf("Expected at most %d bytes, got %d", limit, len(out))
+	}
+}
+
+// Test that unsupported formats are passed through untouched
+func TestProcess_Passthrough(t *testing.T) {
+	input := []byte("not an image at all")
+
+	out, contentType, err := Process(input, Limits{MaxBytes: 1})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !bytes.Equal(out, input) {
+		t.Errorf("Expected data to be unchanged")
+	}
+	if contentType != "text/plain; charset=utf-8" {
+		t.Errorf("Expected content type 'text/plain; charset=utf-8', got '%s'", contentType)
+	}
+}
+
+// Test that the EXIF orientation is applied before the metadata is dropped
+func TestProcess_ExifOrientation(t *testing.T) {
+	// left half red, right half blue
+	img := image.NewRGBA(image.Rect(0, 0, 64, 48))
+	for y := 0; y < 48; y++ {
+		for x := 0; x < 64; x++ {
+			if x < 32 {
+				img.Set(x, y, color.RGBA{R: 255, A: 255})
+			} else {
+				img.Set(x, y, color.RGBA{B: 255, A: 255})
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatalf("Failed to encode test image: %v", err)
+	}
+
+	// big-endian TIFF header with a single IFD entry: orientation 6, turn a quarter clockwise
+	tiff := []byte{
+		'M', 'M', 0x00, 0x2A, 0x00, 0x00, 0x00, 0x08,
+		0x00, 0x01,
+		0x01, 0x12, 0x00, 0x03, 0x00, 0x00, 0x00, 0x01, 0x00, 0x06, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00,
+	}
+	out, _, err := Process(withExif(buf.Bytes(), tiff), Limits{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	rotated, _, err := image.Decode(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("Failed to decode processed image: %v", err)
+	}
+	if rotated.Bounds().Dx() != 48 || rotated.Bounds().Dy() != 64 {
+		t.Fatalf("Expected 48x64, got %dx%d", rotated.Bounds().Dx(), rotated.Bounds().Dy())
+	}
+	// the left half ends up on top once turned clockwise
+	if r, _, b, _ := rotated.At(24, 8).RGBA(); r < b {
+		t.Errorf("Expected the top of the image to be red")
+	}
+	if r, _, b, _ := rotated.At(24, 56).RGBA(); b < r {
+		t.Errorf("Expected the bottom of the image to be blue")
+	}
+	if bytes.Contains(out, []byte("Exif")) {
+		t.Errorf("Expected EXIF data to be stripped")
+	}
+}
+
+// Test that images far larger than the pixel limit are rejected without being decoded
+func TestProcess_TooManyPixels(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, testImage(64, 48)); err != nil {
+		t.Fatalf("Failed to encode test image: %v", err)
+	}
+
+	if _, _, err := Process(buf.Bytes(), Limits{MaxPixels: 100}); err == nil {
+		t.Errorf("Expected an error for an image 30 times the pixel limit")
+	}
+	if _, _, err := Process(buf.Bytes(), Limits{MaxPixels: 1000}); err != nil {
+		t.Errorf("Expected an image 3 times the pixel limit to be downscaled, got %v", err)
+	}
+}
+
+// Table-driven test for the size limit of downloads
+func TestDownload_MaxBytes(t *testing.T) {
+	body := strings.Repeat("x", 1000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/chunked" {
+			// no Content-Length, so the limit has to be enforced while reading
+			w.(http.Flusher).Flush()
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		path     string
+		maxBytes int64
+		wantErr  bool
+	}{
+		{name: "Within limit", path: "/file", maxBytes: 1000},
+		{name: "Default limit", path: "/file", maxBytes: 0},
+		{name: "Content-Length above limit", path: "/file", maxBytes: 999, wantErr: true},
+		{name: "Body above limit", path: "/chunked", maxBytes: 999, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := Download(server.URL+tt.path, tt.maxBytes)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected an error, got %d bytes", len(data))
+				}
+				return
+			}
+			if err != nil || string(data) != body {
+				t.Errorf("Expected the body, got %d bytes, %v", len(data), err)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/draw"
+)
+
+// exifOrientationTag is the EXIF tag holding how the camera was held, as a value 1-8
+const exifOrientationTag = 0x0112
+
+// exifOrientation returns the EXIF orientation of a JPEG image, or 1 (upright) when it has none
+func exifOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xFF {
+			return 1
+		}
+		marker := data[pos+1]
+		if marker == 0xFF {
+			// fill byte before a marker
+			pos++
+			continue
+		}
+		if marker == 0xDA || marker == 0xD9 {
+			// start of the image data, metadata segments come before it
+			return 1
+		}
+
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if length < 2 || pos+2+length > len(data) {
+			return 1
+		}
+		segment := data[pos+4 : pos+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return tiffOrientation(segment[6:])
+		}
+		pos += 2 + length
+	}
+	return 1
+}
+
+// tiffOrientation reads the orientation tag from the first IFD of EXIF's TIFF structure
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	if order.Uint16(tiff[2:]) != 42 {
+		return 1
+	}
+
+	ifd := int64(order.Uint32(tiff[4:]))
+	if ifd+2 > int64(len(tiff)) {
+		return 1
+	}
+	entries := int64(order.Uint16(tiff[ifd:]))
+	for i := int64(0); i < entries; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > int64(len(tiff)) {
+			break
+		}
+		if order.Uint16(tiff[entry:]) != exifOrientationTag {
+			continue
+		}
+		if orientation := int(order.Uint16(tiff[entry+8:])); orientation >= 1 && orientation <= 8 {
+			return orientation
+		}
+		break
+	}
+	return 1
+}
+
+// orient flips and rotates src so it displays upright once its EXIF orientation is dropped
+func orient(src image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+
+	bounds := src.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), src, bounds.Min, draw.Src)
+
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	width, height := srcW, srcH
+	if orientation >= 5 {
+		// orientations 5-8 swap the width and height
+		width, height = srcH, srcW
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // mirror
+				sx, sy = srcW-1-x, y
+			case 3: // turn a half turn
+				sx, sy = srcW-1-x, srcH-1-y
+			case 4: // flip vertically
+				sx, sy = x, srcH-1-y
+			case 5: // mirror along the top-left to bottom-right diagonal
+				sx, sy = y, x
+			case 6: // turn a quarter turn clockwise
+				sx, sy = y, srcH-1-x
+			case 7: // mirror along the top-right to bottom-left diagonal
+				sx, sy = srcW-1-y, srcH-1-x
+			case 8: // turn a quarter turn counter-clockwise
+				sx, sy = srcW-1-y, x
+			}
+			copy(dst.Pix[dst.PixOffset(x, y):][:4], rgba.Pix[rgba.PixOffset(sx, sy):][:4])
+		}
+	}
+	return dst
+}
@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// prefix is prepended to every metric name
+const prefix = "rss2mastodon."
+
+// defaultStatsdAddr is the standard StatsD agent address
+const defaultStatsdAddr = "localhost:8125"
+
+var (
+	mu     sync.Mutex
+	client *statsdClient
+)
+
+// statsdClient pushes metrics to a StatsD or DogStatsD agent over UDP
+type statsdClient struct {
+	conn net.Conn
+	// dogstatsd is set for DogStatsD agents, which support tags
+	dogstatsd bool
+	// tags are appended to every metric, only supported by DogStatsD
+	tags []string
+}
+
+// Init sets up the backend selected by metrics_backend ("statsd" or "dogstatsd").
+// Without a backend, metrics are silently dropped.
+func Init() error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if client != nil {
+		client.conn.Close()
+		client = nil
+	}
+
+	backend := strings.ToLower(viper.GetString("metrics_backend"))
+	switch backend {
+	case "", "none":
+		return nil
+	case "statsd", "dogstatsd":
+	default:
+		return fmt.Errorf("unknown metrics backend %q", backend)
+	}
+
+	addr := viper.GetString("statsd_addr")
+	if addr == "" {
+		addr = defaultStatsdAddr
+	}
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return fmt.Errorf("connecting to statsd at %s: %w", addr, err)
+	}
+
+	client = &statsdClient{conn: conn, dogstatsd: backend == "dogstatsd"}
+	if client.dogstatsd && viper.GetString("statsd_tags") != "" {
+		client.tags = []string{viper.GetString("statsd_tags")}
+	}
+	log.Debugf("Sending %s metrics to %s", backend, addr)
+	return nil
+}
+
+// Count adds value to the counter name, with optional DogStatsD tags like feed:URL
+func Count(name string, value int64, tags ...string) {
+	send(fmt.Sprintf("%s%s:%d|c", prefix, name, value), tags)
+}
+
+// Gauge sets the gauge name to value, with optional DogStatsD tags
+func Gauge(name string, value int64, tags ...string) {
+	send(fmt.Sprintf("%s%s:%d|g", prefix, name, value), tags)
+}
+
+// Timing records a duration for the timer name in milliseconds, with optional DogStatsD tags
+func Timing(name string, d time.Duration, tags ...string) {
+	send(fmt.Sprintf("%s%s:%d|ms", prefix, name, d.Milliseconds()), tags)
+}
+
+// send writes a single metric to the backend, UDP means errors are only logged. Tags are
+// dropped unless the backend is DogStatsD.
+func send(metric string, tags []string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if client == nil {
+		return
+	}
+	if all := append(append([]string{}, client.tags...), tags...); client.dogstatsd && len(all) > 0 {
+		metric += "|#" + strings.Join(all, ",")
+	}
+	if _, err := client.conn.Write([]byte(metric)); err != nil {
+		log.Debug("Failed to send metric: ", err)
+	}
+}
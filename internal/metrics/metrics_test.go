@@ -0,0 +1,91 @@
+package metrics
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Table-driven test for the StatsD and DogStatsD line formats
+func TestStatsd(t *testing.T) {
+	tests := []struct {
+		name     string
+		backend  string
+		tags     string
+		expected []string
+	}{
+		{
+			name:     "StatsD",
+			backend:  "statsd",
+			tags:     "env:prod",
+			expected: []string{"rss2mastodon.tooted:2|c", "rss2mastodon.cycle.duration:1500|ms", "rss2mastodon.post_queue.depth:3|g", "rss2mastodon.feed.latency:60000|ms"},
+		},
+		{
+			name:     "DogStatsD with tags",
+			backend:  "dogstatsd",
+			tags:     "env:prod,host:a",
+			expected: []string{"rss2mastodon.tooted:2|c|#env:prod,host:a", "rss2mastodon.cycle.duration:1500|ms|#env:prod,host:a", "rss2mastodon.post_queue.depth:3|g|#env:prod,host:a", "rss2mastodon.feed.latency:60000|ms|#env:prod,host:a,feed:https://example.com/rss"},
+		},
+		{
+			name:     "DogStatsD with metric tags only",
+			backend:  "dogstatsd",
+			expected: []string{"rss2mastodon.tooted:2|c", "rss2mastodon.cycle.duration:1500|ms", "rss2mastodon.post_queue.depth:3|g", "rss2mastodon.feed.latency:60000|ms|#feed:https://example.com/rss"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatalf("Failed to listen: %v", err)
+			}
+			defer listener.Close()
+
+			viper.Reset()
+			viper.Set("metrics_backend", tt.backend)
+			viper.Set("statsd_addr", listener.LocalAddr().String())
+			viper.Set("statsd_tags", tt.tags)
+			defer viper.Reset()
+
+			if err := Init(); err != nil {
+				t.Fatalf("Init failed: %v", err)
+			}
+
+			Count("tooted", 2)
+			Timing("cycle.duration", 1500*time.Millisecond)
+			Gauge("post_queue.depth", 3)
+			Timing("feed.latency", time.Minute, "feed:https://example.com/rss")
+
+			buf := make([]byte, 512)
+			for _, expected := range tt.expected {
+				_ = listener.SetReadDeadline(time.Now().Add(2 * time.Second))
+				n, _, err := listener.ReadFrom(buf)
+				if err != nil {
+					t.Fatalf("Failed to read metric: %v", err)
+				}
+				if string(buf[:n]) != expected {
+					t.Errorf("Expected metric '%s', got '%s'", expected, string(buf[:n]))
+				}
+			}
+		})
+	}
+}
+
+// Test metrics are dropped without a backend and unknown backends are rejected
+func TestInit(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	if err := Init(); err != nil {
+		t.Errorf("Expected no error without a backend, got %v", err)
+	}
+	// must not panic without a backend
+	Count("tooted", 1)
+
+	viper.Set("metrics_backend", "graphite")
+	if err := Init(); err == nil {
+		t.Errorf("Expected an error for an unknown backend")
+	}
+}
@@ -0,0 +1,160 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/httpclient"
+	"github.com/toozej/rss2mastodon/internal/retry"
+)
+
+// Action is a button attached to a notification which sends an HTTP request when pressed.
+// Only ntfy supports action buttons, other notifiers ignore them.
+type Action struct {
+	Label   string
+	URL     string
+	Method  string
+	Headers map[string]string
+}
+
+// Notifier is a notification service, enabled by setting its first config key
+type Notifier struct {
+	Name        string
+	Description string
+	// ConfigKeys are the configuration keys the notifier reads, starting with its URL
+	ConfigKeys []string
+	// Capabilities lists what the notifier supports
+	Capabilities []string
+	Send         func(title string, message string, actions []Action) error
+}
+
+// notifiers holds the available notifiers, in the order notifications are sent
+var notifiers = []Notifier{
+	{
+		Name:         "gotify",
+		Description:  "Gotify server application",
+		ConfigKeys:   []string{"gotify_url", "gotify_token"},
+		Capabilities: []string{"notify"},
+		Send: func(title string, message string, _ []Action) error {
+			return sendGotify(title, message)
+		},
+	},
+	{
+		Name:         "ntfy",
+		Description:  "ntfy topic",
+		ConfigKeys:   []string{"ntfy_url", "ntfy_token"},
+		Capabilities: []string{"notify", "actions"},
+		Send:         sendNtfy,
+	},
+}
+
+// Send delivers a notification to every configured notifier (Gotify and/or ntfy).
+// If no notifier is configured the notification is only logged.
+func Send(title string, message string) error {
+	return SendWithActions(title, message, nil)
+}
+
+// SendWithActions delivers a notification with action buttons to every configured notifier
+func SendWithActions(title string, message string, actions []Action) error {
+	log.Warnf("%s: %s", title, message)
+
+	var errs []error
+	for _, notifier := range notifiers {
+		if viper.GetString(notifier.ConfigKeys[0]) == "" {
+			continue
+		}
+		if err := notifier.Send(title, message, actions); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", notifier.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Notifiers returns the available notifiers
+func Notifiers() []Notifier {
+	return notifiers
+}
+
+// sendGotify posts a message to a Gotify server using an application token
+func sendGotify(title string, message string) error {
+	gotifyURL := viper.GetString("gotify_url")
+	gotifyToken := viper.GetString("gotify_token")
+	if gotifyToken == "" {
+		return fmt.Errorf("gotify token must be set")
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"title":    title,
+		"message":  message,
+		"priority": 5,
+	})
+	if err != nil {
+		return err
+	}
+
+	endpoint := strings.TrimSuffix(gotifyURL, "/") + "/message?token=" + url.QueryEscape(gotifyToken)
+	return post(endpoint, "application/json", payload, nil)
+}
+
+// sendNtfy publishes a message to an ntfy topic URL (e.g. https://ntfy.sh/my-topic)
+func sendNtfy(title string, message string, actions []Action) error {
+	headers := map[string]string{"Title": title}
+	if token := viper.GetString("ntfy_token"); token != "" {
+		headers["Authorization"] = "Bearer " + token
+	}
+	if len(actions) > 0 {
+		headers["Actions"] = ntfyActions(actions)
+	}
+	return post(viper.GetString("ntfy_url"), "text/plain", []byte(message), headers)
+}
+
+// ntfyActions formats actions using ntfy's short header format, see https://docs.ntfy.sh/publish/#action-buttons
+func ntfyActions(actions []Action) string {
+	var formatted []string
+	for _, action := range actions {
+		fields := []string{"http", action.Label, action.URL}
+		if action.Method != "" {
+			fields = append(fields, "method="+action.Method)
+		}
+		for key, value := range action.Headers {
+			fields = append(fields, fmt.Sprintf("headers.%s=%s", key, value))
+		}
+		fields = append(fields, "clear=true")
+		formatted = append(formatted, strings.Join(fields, ", "))
+	}
+	return strings.Join(formatted, "; ")
+}
+
+// post sends a notification request, retrying failed ones according to the notify_retry policy
+func post(endpoint string, contentType string, body []byte, headers map[string]string) error {
+	client := httpclient.New("notify", 10*time.Second, httpclient.RetryAll(retry.Configured(retry.NotifyKey)))
+	req, err := http.NewRequest("POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", contentType)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected HTTP status: %d", resp.StatusCode)
+	}
+	return nil
+}
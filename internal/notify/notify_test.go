@@ -0,0 +1,94 @@
+package notify
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// Test notifications are delivered to both Gotify and ntfy
+func TestSend(t *testing.T) {
+	var gotifyPayload map[string]interface{}
+	var ntfyTitle, ntfyBody, gotifyToken string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/message":
+			gotifyToken = r.URL.Query().Get("token")
+			_ = json.NewDecoder(r.Body).Decode(&gotifyPayload)
+		case "/rss2mastodon":
+			ntfyTitle = r.Header.Get("Title")
+			body, _ := io.ReadAll(r.Body)
+			ntfyBody = string(body)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer mockServer.Close()
+
+	viper.Reset()
+	viper.Set("gotify_url", mockServer.URL)
+	viper.Set("gotify_token", "gotify-token")
+	viper.Set("ntfy_url", mockServer.URL+"/rss2mastodon")
+	defer viper.Reset()
+
+	if err := Send("Feed is stale", "No new items"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if gotifyToken != "gotify-token" {
+		t.Errorf("Expected Gotify token 'gotify-token', got '%s'", gotifyToken)
+	}
+	if gotifyPayload["title"] != "Feed is stale" || gotifyPayload["message"] != "No new items" {
+		t.Errorf("Unexpected Gotify payload: %v", gotifyPayload)
+	}
+	if ntfyTitle != "Feed is stale" || ntfyBody != "No new items" {
+		t.Errorf("Unexpected ntfy notification: title '%s', body '%s'", ntfyTitle, ntfyBody)
+	}
+}
+
+// Test failures from a notifier are reported
+func TestSend_Error(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer mockServer.Close()
+
+	viper.Reset()
+	viper.Set("ntfy_url", mockServer.URL)
+	defer viper.Reset()
+
+	if err := Send("title", "message"); err == nil {
+		t.Errorf("Expected error but got none")
+	}
+}
+
+// Test action buttons are sent to ntfy
+func TestSendWithActions(t *testing.T) {
+	var actions string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actions = r.Header.Get("Actions")
+	}))
+	defer mockServer.Close()
+
+	viper.Reset()
+	viper.Set("ntfy_url", mockServer.URL)
+	defer viper.Reset()
+
+	err := SendWithActions("Approve post?", "New post", []Action{
+		{Label: "Approve", URL: "https://bot.example.com/approve/1", Method: "POST"},
+		{Label: "Reject", URL: "https://bot.example.com/reject/1", Method: "POST", Headers: map[string]string{"Authorization": "Bearer token"}},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := "http, Approve, https://bot.example.com/approve/1, method=POST, clear=true; " +
+		"http, Reject, https://bot.example.com/reject/1, method=POST, headers.Authorization=Bearer token, clear=true"
+	if actions != expected {
+		t.Errorf("Expected actions '%s', got '%s'", expected, actions)
+	}
+}
@@ -0,0 +1,73 @@
+package notify
+
+import (
+	"fmt"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// defaultFailureThreshold is how many consecutive failures trigger a notification when not configured
+const defaultFailureThreshold = 3
+
+// failureState tracks consecutive failures of one thing being monitored, e.g. a feed
+type failureState struct {
+	count   int
+	alerted bool
+}
+
+var (
+	failuresMu sync.Mutex
+	failures   = make(map[string]*failureState)
+)
+
+// Failure records a failure of key (e.g. a feed URL) and sends a notification once it has failed
+// notify_failure_threshold times in a row. Further failures are not notified until it recovers.
+func Failure(key string, err error) {
+	threshold := viper.GetInt("notify_failure_threshold")
+	if threshold <= 0 {
+		threshold = defaultFailureThreshold
+	}
+
+	failuresMu.Lock()
+	state, ok := failures[key]
+	if !ok {
+		state = &failureState{}
+		failures[key] = state
+	}
+	state.count++
+	shouldAlert := !state.alerted && state.count >= threshold
+	if shouldAlert {
+		state.alerted = true
+	}
+	count := state.count
+	failuresMu.Unlock()
+
+	if !shouldAlert {
+		return
+	}
+
+	message := fmt.Sprintf("%s has failed %d times in a row: %v", key, count, err)
+	if sendErr := Send("rss2mastodon: "+key+" is failing", message); sendErr != nil {
+		log.Error("Failed to send failure notification: ", sendErr)
+	}
+}
+
+// Success records a success of key, sending a single recovery notification if a failure
+// notification was sent for it
+func Success(key string) {
+	failuresMu.Lock()
+	state, ok := failures[key]
+	delete(failures, key)
+	failuresMu.Unlock()
+
+	if !ok || !state.alerted {
+		return
+	}
+
+	message := fmt.Sprintf("%s has recovered after %d consecutive failures", key, state.count)
+	if err := Send("rss2mastodon: "+key+" has recovered", message); err != nil {
+		log.Error("Failed to send recovery notification: ", err)
+	}
+}
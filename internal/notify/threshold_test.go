@@ -0,0 +1,54 @@
+package notify
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// Test failure notifications are only sent after consecutive failures, followed by one recovery
+func TestFailureThreshold(t *testing.T) {
+	var titles []string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		titles = append(titles, r.Header.Get("Title"))
+	}))
+	defer mockServer.Close()
+
+	viper.Reset()
+	viper.Set("ntfy_url", mockServer.URL)
+	viper.Set("notify_failure_threshold", 2)
+	defer viper.Reset()
+
+	key := "https://example.com/feed.xml"
+	timeout := errors.New("timeout")
+
+	// a single transient failure is suppressed
+	Failure(key, timeout)
+	Success(key)
+	if len(titles) != 0 {
+		t.Fatalf("Expected no notifications for a transient failure, got %v", titles)
+	}
+
+	// repeated failures notify once, then recovery notifies once
+	for i := 0; i < 5; i++ {
+		Failure(key, timeout)
+	}
+	Success(key)
+	Success(key)
+
+	expected := []string{
+		"rss2mastodon: " + key + " is failing",
+		"rss2mastodon: " + key + " has recovered",
+	}
+	if len(titles) != len(expected) {
+		t.Fatalf("Expected notifications %v, got %v", expected, titles)
+	}
+	for i := range expected {
+		if titles[i] != expected[i] {
+			t.Errorf("Expected notification '%s', got '%s'", expected[i], titles[i])
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package release
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/toozej/rss2mastodon/internal/httpclient"
+)
+
+// DefaultBaseURL is where the release artifacts are downloaded from, followed by the tag
+const DefaultBaseURL = "https://github.com/toozej/rss2mastodon/releases/download"
+
+// PublicKey is the cosign public key the release artifacts are signed with, as in
+// rss2mastodon.pub at the root of the repository
+const PublicKey = `-----BEGIN PUBLIC KEY-----
+MFkwEwYHKoZIzj0CAQYIKoZIzj0DAQcDQgAEbq2UZucELBql6l7kSAGj1v02gLoZ
+F7Fo6lnOgxgxUALabtRafOFN/zff/eKuxdkdGnuWhrcWHVc7op+eS6l3Hg==
+-----END PUBLIC KEY-----
+`
+
+// checksumsName is the name of the release's checksum file, signed as checksumsName.sig
+const checksumsName = "checksums.txt"
+
+// maxArtifactSize limits the size of downloaded artifacts
+const maxArtifactSize = 200 << 20
+
+// Verifier checks binaries against the signed checksums of a release
+type Verifier struct {
+	// BaseURL is where release artifacts are downloaded from, DefaultBaseURL if empty
+	BaseURL string
+	// PublicKey is the PEM encoded cosign public key, PublicKey if empty
+	PublicKey string
+	// Timeout limits every download, 0 for none
+	Timeout time.Duration
+}
+
+// Result describes a verified binary
+type Result struct {
+	// Archive is the release archive the binary was compared with
+	Archive string
+	// SHA256 is the checksum of the binary
+	SHA256 string
+}
+
+// Verify checks that the binary at binaryPath is the one released as the version, for the
+// operating system and architecture: the release's checksum file must carry a valid signature,
+// the archive for the platform must match its checksum there, and the binary in the archive
+// must be identical to binaryPath.
+func (v Verifier) Verify(version string, goos string, goarch string, binaryPath string) (Result, error) {
+	var result Result
+	if version == "" || version == "local" || version == "unknown" {
+		return result, fmt.Errorf("version %q is not a release, pass the version the binary was released as", version)
+	}
+	tag := "v" + strings.TrimPrefix(version, "v")
+	client := httpclient.New("release", v.Timeout)
+
+	checksums, err := v.download(client, tag, checksumsName)
+	if err != nil {
+		return result, err
+	}
+	signature, err := v.download(client, tag, checksumsName+".sig")
+	if err != nil {
+		return result, err
+	}
+	publicKey := v.PublicKey
+	if publicKey == "" {
+		publicKey = PublicKey
+	}
+	if err := VerifySignature(checksums, signature, publicKey); err != nil {
+		return result, fmt.Errorf("%s of %s: %w", checksumsName, tag, err)
+	}
+
+	result.Archive = ArchiveName(goos, goarch)
+	expected, ok := parseChecksums(checksums)[result.Archive]
+	if !ok {
+		return result, fmt.Errorf("%s of %s lists no %s", checksumsName, tag, result.Archive)
+	}
+	archive, err := v.download(client, tag, result.Archive)
+	if err != nil {
+		return result, err
+	}
+	if sum := sha256.Sum256(archive); hex.EncodeToString(sum[:]) != expected {
+		return result, fmt.Errorf("%s doesn't match its checksum", result.Archive)
+	}
+
+	released, err := extractBinary(result.Archive, archive, binaryName(goos))
+	if err != nil {
+		return result, err
+	}
+	binary, err := os.ReadFile(binaryPath)
+	if err != nil {
+		return result, err
+	}
+	sum := sha256.Sum256(binary)
+	result.SHA256 = hex.EncodeToString(sum[:])
+	if !bytes.Equal(binary, released) {
+		releasedSum := sha256.Sum256(released)
+		return result, fmt.Errorf("%s has checksum %s, the binary released as %s has %s", binaryPath, result.SHA256, tag, hex.EncodeToString(releasedSum[:]))
+	}
+	return result, nil
+}
+
+// download fetches a release artifact of the tag
+func (v Verifier) download(client *http.Client, tag string, name string) ([]byte, error) {
+	baseURL := v.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	resp, err := client.Get(strings.TrimSuffix(baseURL, "/") + "/" + tag + "/" + name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s of %s: %s", name, tag, resp.Status)
+	}
+	content, err := io.ReadAll(io.LimitReader(resp.Body, maxArtifactSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", name, err)
+	}
+	if len(content) > maxArtifactSize {
+		return nil, fmt.Errorf("%s is larger than %d bytes", name, maxArtifactSize)
+	}
+	return content, nil
+}
+
+// VerifySignature checks a signature made with cosign sign-blob, the base64 encoded ECDSA
+// signature of the content's SHA-256 digest, against the PEM encoded public key
+func VerifySignature(content []byte, signature []byte, publicKeyPEM string) error {
+	block, _ := pem.Decode([]byte(publicKeyPEM))
+	if block == nil {
+		return fmt.Errorf("invalid public key, expected PEM")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("invalid public key: %w", err)
+	}
+	publicKey, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("invalid public key, expected ECDSA")
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil {
+		return fmt.Errorf("invalid signature: %w", err)
+	}
+	digest := sha256.Sum256(content)
+	if !ecdsa.VerifyASN1(publicKey, digest[:], decoded) {
+		return fmt.Errorf("signature doesn't match the public key")
+	}
+	return nil
+}
+
+// ArchiveName returns the name of the release archive for the operating system and
+// architecture, following the name template in .goreleaser.yml
+func ArchiveName(goos string, goarch string) string {
+	arch := goarch
+	switch goarch {
+	case "amd64":
+		arch = "x86_64"
+	case "386":
+		arch = "i386"
+	}
+	extension := ".tar.gz"
+	if goos == "windows" {
+		extension = ".zip"
+	}
+	return "rss2mastodon_" + strings.ToUpper(goos[:1]) + goos[1:] + "_" + arch + extension
+}
+
+// binaryName returns the name of the binary in the release archives for the operating system
+func binaryName(goos string) string {
+	if goos == "windows" {
+		return "rss2mastodon.exe"
+	}
+	return "rss2mastodon"
+}
+
+// parseChecksums parses a checksum file of "checksum  name" lines into checksums by name
+func parseChecksums(content []byte) map[string]string {
+	checksums := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 {
+			checksums[strings.TrimPrefix(fields[1], "*")] = strings.ToLower(fields[0])
+		}
+	}
+	return checksums
+}
+
+// extractBinary returns the content of the named file at the root of a .tar.gz or .zip archive
+func extractBinary(archiveName string, archive []byte, name string) ([]byte, error) {
+	if strings.HasSuffix(archiveName, ".zip") {
+		reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range reader.File {
+			if path.Clean(file.Name) != name {
+				continue
+			}
+			content, err := file.Open()
+			if err != nil {
+				return nil, err
+			}
+			defer content.Close()
+			return io.ReadAll(io.LimitReader(content, maxArtifactSize))
+		}
+		return nil, fmt.Errorf("%s holds no %s", archiveName, name)
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	reader := tar.NewReader(gz)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%s holds no %s", archiveName, name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag == tar.TypeReg && path.Clean(header.Name) == name {
+			return io.ReadAll(io.LimitReader(reader, maxArtifactSize))
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package release
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Table-driven test for the release archive names of the platforms goreleaser builds for
+func TestArchiveName(t *testing.T) {
+	tests := []struct {
+		goos     string
+		goarch   string
+		expected string
+	}{
+		{"linux", "amd64", "rss2mastodon_Linux_x86_64.tar.gz"},
+		{"linux", "386", "rss2mastodon_Linux_i386.tar.gz"},
+		{"linux", "arm", "rss2mastodon_Linux_arm.tar.gz"},
+		{"darwin", "arm64", "rss2mastodon_Darwin_arm64.tar.gz"},
+		{"windows", "amd64", "rss2mastodon_Windows_x86_64.zip"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.goos+"/"+tt.goarch, func(t *testing.T) {
+			if got := ArchiveName(tt.goos, tt.goarch); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+// Test that the built-in public key is the one in rss2mastodon.pub
+func TestPublicKey(t *testing.T) {
+	content, err := os.ReadFile("../../rss2mastodon.pub")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if string(content) != PublicKey {
+		t.Errorf("Expected the built-in public key to match rss2mastodon.pub")
+	}
+}
+
+// Table-driven test for verifying a binary against the signed checksums of a release
+func TestVerify(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	publicKey := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	sign := func(key *ecdsa.PrivateKey, content []byte) []byte {
+		digest := sha256.Sum256(content)
+		signature, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		return []byte(base64.StdEncoding.EncodeToString(signature) + "\n")
+	}
+
+	released := []byte("released binary")
+	var archive bytes.Buffer
+	gz := gzip.NewWriter(&archive)
+	tw := tar.NewWriter(gz)
+	_ = tw.WriteHeader(&tar.Header{Name: "README.md", Mode: 0644, Size: 2, Typeflag: tar.TypeReg})
+	_, _ = tw.Write([]byte("hi"))
+	_ = tw.WriteHeader(&tar.Header{Name: "rss2mastodon", Mode: 0755, Size: int64(len(released)), Typeflag: tar.TypeReg})
+	_, _ = tw.Write(released)
+	_ = tw.Close()
+	_ = gz.Close()
+	archiveSum := sha256.Sum256(archive.Bytes())
+	checksums := []byte(hex.EncodeToString(archiveSum[:]) + "  rss2mastodon_Linux_x86_64.tar.gz\n")
+
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "rss2mastodon")
+	if err := os.WriteFile(binary, released, 0755); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	modified := filepath.Join(dir, "modified")
+	if err := os.WriteFile(modified, []byte("modified binary"), 0755); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		version       string
+		binary        string
+		signature     []byte
+		tamper        bool
+		expectedError bool
+	}{
+		{name: "Released binary", version: "1.2.3", binary: binary, signature: sign(privateKey, checksums)},
+		{name: "Tag version", version: "v1.2.3", binary: binary, signature: sign(privateKey, checksums)},
+		{name: "Modified binary", version: "1.2.3", binary: modified, signature: sign(privateKey, checksums), expectedError: true},
+		{name: "Signed with another key", version: "1.2.3", binary: binary, signature: sign(otherKey, checksums), expectedError: true},
+		{name: "Tampered archive", version: "1.2.3", binary: binary, signature: sign(privateKey, checksums), tamper: true, expectedError: true},
+		{name: "Unknown release", version: "1.2.4", binary: binary, signature: sign(privateKey, checksums), expectedError: true},
+		{name: "Local build", version: "local", binary: binary, signature: sign(privateKey, checksums), expectedError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/v1.2.3/checksums.txt":
+					_, _ = w.Write(checksums)
+				case "/v1.2.3/checksums.txt.sig":
+					_, _ = w.Write(tt.signature)
+				case "/v1.2.3/rss2mastodon_Linux_x86_64.tar.gz":
+					content := append([]byte(nil), archive.Bytes()...)
+					if tt.tamper {
+						content[len(content)-1] ^= 0xff
+					}
+					_, _ = w.Write(content)
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer mockServer.Close()
+
+			verifier := Verifier{BaseURL: mockServer.URL, PublicKey: publicKey}
+			result, err := verifier.Verify(tt.version, "linux", "amd64", tt.binary)
+			if (err != nil) != tt.expectedError {
+				t.Fatalf("Expected error %v, got %v", tt.expectedError, err)
+			}
+			sum := sha256.Sum256(released)
+			if !tt.expectedError && (result.Archive != "rss2mastodon_Linux_x86_64.tar.gz" || result.SHA256 != hex.EncodeToString(sum[:])) {
+				t.Errorf("Expected the binary verified against the archive, got %+v", result)
+			}
+		})
+	}
+}
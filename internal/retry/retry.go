@@ -0,0 +1,199 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// Settings holding the retry policies of the operations which can be retried
+const (
+	FetchKey  = "fetch_retry"
+	TootKey   = "toot_retry"
+	UploadKey = "upload_retry"
+	NotifyKey = "notify_retry"
+)
+
+// Keys lists the settings holding retry policies
+var Keys = []string{FetchKey, TootKey, UploadKey, NotifyKey}
+
+// Default is the policy of settings left unset, and what set ones override: a single attempt,
+// so failures are only retried once operators ask for it
+var Default = Policy{Attempts: 1, BaseDelay: time.Second, MaxDelay: 30 * time.Second, Jitter: 0.2}
+
+// Policy describes how often and after which delays a failing operation is attempted again
+type Policy struct {
+	// Attempts is how many times the operation is attempted in total, 1 for no retries
+	Attempts int
+	// BaseDelay is the delay before the first retry, doubling for every further one
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between attempts
+	MaxDelay time.Duration
+	// Jitter randomly varies delays by up to this fraction of them, e.g. 0.2 for ±20%, so
+	// clients failing together don't retry together
+	Jitter float64
+}
+
+// Parse parses a policy like "attempts=3,base_delay=1s,max_delay=30s,jitter=0.2", whose
+// unset fields keep the values of defaults, and validates it
+func Parse(value string, defaults Policy) (Policy, error) {
+	policy := defaults
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(field, "=")
+		key, val = strings.TrimSpace(key), strings.TrimSpace(val)
+		if !ok {
+			return policy, fmt.Errorf("invalid retry option %q, expected key=value", field)
+		}
+
+		var err error
+		switch key {
+		case "attempts":
+			policy.Attempts, err = strconv.Atoi(val)
+		case "base_delay":
+			policy.BaseDelay, err = time.ParseDuration(val)
+		case "max_delay":
+			policy.MaxDelay, err = time.ParseDuration(val)
+		case "jitter":
+			policy.Jitter, err = strconv.ParseFloat(val, 64)
+		default:
+			return policy, fmt.Errorf("unknown retry option %q, expected attempts, base_delay, max_delay or jitter", key)
+		}
+		if err != nil {
+			return policy, fmt.Errorf("invalid retry option %s=%s: %w", key, val, err)
+		}
+	}
+	return policy, policy.Validate()
+}
+
+// Validate checks that the policy makes sense
+func (p Policy) Validate() error {
+	switch {
+	case p.Attempts < 1:
+		return fmt.Errorf("retry attempts must be at least 1, got %d", p.Attempts)
+	case p.BaseDelay < 0:
+		return fmt.Errorf("retry base_delay must not be negative, got %s", p.BaseDelay)
+	case p.MaxDelay < p.BaseDelay:
+		return fmt.Errorf("retry max_delay %s must not be below base_delay %s", p.MaxDelay, p.BaseDelay)
+	case p.Jitter < 0 || p.Jitter > 1:
+		return fmt.Errorf("retry jitter must be between 0 and 1, got %g", p.Jitter)
+	}
+	return nil
+}
+
+// String formats the policy the way Parse reads it
+func (p Policy) String() string {
+	return fmt.Sprintf("attempts=%d,base_delay=%s,max_delay=%s,jitter=%g", p.Attempts, p.BaseDelay, p.MaxDelay, p.Jitter)
+}
+
+// Delay returns how long to wait before the given retry, 1 for the first one: BaseDelay doubled
+// for every retry before it, capped at MaxDelay and varied by Jitter
+func (p Policy) Delay(retry int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < retry && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, p.MaxDelay)
+	if p.Jitter > 0 {
+		delay += time.Duration((rand.Float64()*2 - 1) * p.Jitter * float64(delay))
+	}
+	return delay
+}
+
+// Configured returns the policy of a setting, one of Keys. Invalid settings are logged and the
+// default policy used instead; CheckConfig reports them at startup.
+func Configured(key string) Policy {
+	policy, err := Parse(viper.GetString(key), Default)
+	if err != nil {
+		log.Errorf("Ignoring invalid %s: %v", key, err)
+		return Default
+	}
+	return policy
+}
+
+// CheckConfig validates the retry policies of all settings
+func CheckConfig() error {
+	for _, key := range Keys {
+		if _, err := Parse(viper.GetString(key), Default); err != nil {
+			return fmt.Errorf("invalid %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// permanentError marks an error which attempting the operation again won't fix
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks an error, e.g. a rejected request, as not worth retrying
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err}
+}
+
+// sleep waits between attempts, replaced by tests
+var sleep = time.Sleep
+
+// RetryableStatus reports whether a request which failed with the given HTTP status may succeed
+// when sent again: server errors and rate limiting
+func RetryableStatus(code int) bool {
+	return code >= 500 || code == http.StatusTooManyRequests
+}
+
+// Transient reports whether an error sending a request or reading its response may go away
+// when trying again: timeouts, and connections which were refused, reset or closed early.
+// Others, e.g. an unknown host or an invalid certificate, won't.
+func Transient(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return true
+	}
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNABORTED) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTimeout || dnsErr.IsTemporary
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// Do runs op until it succeeds, fails with a Permanent error or has been attempted as often as
+// the policy allows, logging every retry of what it does, and returns op's last error, no longer
+// marked as permanent. A zero policy attempts op once.
+func Do(p Policy, what string, op func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := op()
+		var permanent permanentError
+		if errors.As(err, &permanent) {
+			return permanent.err
+		}
+		if err == nil || attempt >= p.Attempts {
+			return err
+		}
+		delay := p.Delay(attempt)
+		log.Warnf("%s failed (attempt %d of %d), retrying in %s: %v", what, attempt, p.Attempts, delay.Round(time.Millisecond), err)
+		sleep(delay)
+	}
+}
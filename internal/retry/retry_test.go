@@ -0,0 +1,158 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// Table-driven test for parsing retry policies
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     string
+		expected  Policy
+		expectErr bool
+	}{
+		{"Defaults", "", Default, false},
+		{"All options", "attempts=3, base_delay=500ms, max_delay=1m, jitter=0", Policy{Attempts: 3, BaseDelay: 500 * time.Millisecond, MaxDelay: time.Minute}, false},
+		{"Some options", "attempts=5", Policy{Attempts: 5, BaseDelay: time.Second, MaxDelay: 30 * time.Second, Jitter: 0.2}, false},
+		{"No attempts", "attempts=0", Policy{}, true},
+		{"Negative delay", "base_delay=-1s", Policy{}, true},
+		{"Max below base", "base_delay=1m", Policy{}, true},
+		{"Jitter too large", "jitter=1.5", Policy{}, true},
+		{"Invalid duration", "max_delay=soon", Policy{}, true},
+		{"Unknown option", "tries=3", Policy{}, true},
+		{"Missing value", "attempts", Policy{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := Parse(tt.value, Default)
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("Expected an error, got %v", policy)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if policy != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, policy)
+			}
+		})
+	}
+}
+
+// Table-driven test for the delays between attempts
+func TestDelay(t *testing.T) {
+	policy := Policy{Attempts: 10, BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+	tests := []struct {
+		retry    int
+		expected time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 5 * time.Second},
+		{100, 5 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := policy.Delay(tt.retry); got != tt.expected {
+			t.Errorf("Expected retry %d after %v, got %v", tt.retry, tt.expected, got)
+		}
+	}
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if got := policy.Delay(1); got < 500*time.Millisecond || got > 1500*time.Millisecond {
+			t.Fatalf("Expected jittered delay within 50%% of 1s, got %v", got)
+		}
+	}
+}
+
+// Table-driven test for running operations until they succeed or run out of attempts
+func TestDo(t *testing.T) {
+	failure := errors.New("connection reset")
+	tests := []struct {
+		name        string
+		policy      Policy
+		failures    int
+		permanent   bool
+		expectCalls int
+		expectErr   bool
+	}{
+		{"Succeeds", Policy{Attempts: 3}, 0, false, 1, false},
+		{"Succeeds after retries", Policy{Attempts: 3}, 2, false, 3, false},
+		{"Runs out of attempts", Policy{Attempts: 3}, 5, false, 3, true},
+		{"Zero policy", Policy{}, 5, false, 1, true},
+		{"Permanent error", Policy{Attempts: 3}, 5, true, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var slept []time.Duration
+			sleep = func(d time.Duration) { slept = append(slept, d) }
+			defer func() { sleep = time.Sleep }()
+
+			calls := 0
+			err := Do(tt.policy, "Testing", func() error {
+				calls++
+				if calls > tt.failures {
+					return nil
+				}
+				if tt.permanent {
+					return Permanent(failure)
+				}
+				return failure
+			})
+			if calls != tt.expectCalls {
+				t.Errorf("Expected %d calls, got %d", tt.expectCalls, calls)
+			}
+			if len(slept) != calls-1 {
+				t.Errorf("Expected to wait between attempts, waited %v for %d calls", slept, calls)
+			}
+			if tt.expectErr != (err != nil) {
+				t.Errorf("Expected error %v, got %v", tt.expectErr, err)
+			}
+			if err != nil && !errors.Is(err, failure) {
+				t.Errorf("Expected the operation's error, got %v", err)
+			}
+		})
+	}
+}
+
+// Table-driven test for telling errors which may go away when trying again from others
+func TestTransient(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{"Timeout", fmt.Errorf("attempt: %w", context.DeadlineExceeded), true},
+		{"Connection reset", &net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}, true},
+		{"Connection refused", &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, true},
+		{"Closed early", &url.Error{Op: "Get", URL: "https://example.com", Err: io.EOF}, true},
+		{"Cut short", io.ErrUnexpectedEOF, true},
+		{"Temporary DNS failure", &net.DNSError{Err: "server misbehaving", Name: "example.com", IsTemporary: true}, true},
+		{"Unknown host", &net.DNSError{Err: "no such host", Name: "example.invalid", IsNotFound: true}, false},
+		{"Other", errors.New("x509: certificate signed by unknown authority"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Transient(tt.err); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package rss
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+// atomNamespace is the XML namespace of Atom feeds
+const atomNamespace = "http://www.w3.org/2005/Atom"
+
+// atomFeed is an Atom <feed>, as published by Hugo, Jekyll and GitHub among others
+type atomFeed struct {
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Logo    string      `xml:"logo"`
+	Icon    string      `xml:"icon"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+// atomEntry is an Atom <entry>, the equivalent of an RSS <item>
+type atomEntry struct {
+	Title       atomText       `xml:"title"`
+	ID          string         `xml:"id"`
+	Links       []atomLink     `xml:"link"`
+	Published   string         `xml:"published"`
+	Updated     string         `xml:"updated"`
+	Summary     atomText       `xml:"summary"`
+	Content     atomText       `xml:"content"`
+	Categories  []atomCategory `xml:"category"`
+	Media       []MediaContent `xml:"http://search.yahoo.com/mrss/ content"`
+	MediaGroups []MediaGroup   `xml:"http://search.yahoo.com/mrss/ group"`
+}
+
+// atomLink is an Atom <link>, either the entry's page (rel alternate, the default) or an
+// attached file (rel enclosure)
+type atomLink struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr"`
+	Type   string `xml:"type,attr"`
+	Length int64  `xml:"length,attr"`
+}
+
+// atomCategory is an Atom <category>, whose name is in its term attribute
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+// atomText is an Atom text construct, holding text, escaped HTML or inline XHTML
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Text  string `xml:",chardata"`
+	Inner string `xml:",innerxml"`
+}
+
+// String returns the text, or the HTML markup of XHTML text
+func (t atomText) String() string {
+	if t.Type == "xhtml" {
+		return strings.TrimSpace(t.Inner)
+	}
+	return strings.TrimSpace(t.Text)
+}
+
+// isAtom reports whether an element is the root of an Atom feed
+func isAtom(start xml.StartElement) bool {
+	return start.Name.Local == "feed" && (start.Name.Space == atomNamespace || start.Name.Space == "")
+}
+
+// toRSS converts the Atom feed to the RSS structure used everywhere else
+func (f atomFeed) toRSS() *RSSFeed {
+	var feed RSSFeed
+	feed.Channel.Title = strings.TrimSpace(f.Title)
+	feed.Channel.LastBuildDate = strings.TrimSpace(f.Updated)
+	for _, url := range []string{f.Logo, f.Icon} {
+		if url = strings.TrimSpace(url); url != "" {
+			feed.Channel.Images = append(feed.Channel.Images, ChannelImage{URL: url})
+		}
+	}
+	for _, entry := range f.Entries {
+		feed.Channel.Items = append(feed.Channel.Items, entry.toRSS())
+	}
+	return &feed
+}
+
+// toRSS converts the Atom entry to an RSS item: the summary becomes its description (or the
+// content if there is no summary) and the content its content:encoded
+func (e atomEntry) toRSS() RSSItem {
+	item := RSSItem{
+		Title:       e.Title.String(),
+		GUID:        strings.TrimSpace(e.ID),
+		PubDate:     strings.TrimSpace(e.Published),
+		Content:     e.Summary.String(),
+		Encoded:     e.Content.String(),
+		Media:       e.Media,
+		MediaGroups: e.MediaGroups,
+	}
+	if item.PubDate == "" {
+		item.PubDate = strings.TrimSpace(e.Updated)
+	}
+	if item.Content == "" {
+		item.Content = item.Encoded
+	}
+	for _, link := range e.Links {
+		switch link.Rel {
+		case "", "alternate":
+			if item.Link == "" {
+				item.Link = strings.TrimSpace(link.Href)
+			}
+		case "enclosure":
+			item.Enclosures = append(item.Enclosures, Enclosure{URL: strings.TrimSpace(link.Href), Type: link.Type, Length: link.Length})
+		}
+	}
+	for _, category := range e.Categories {
+		if term := strings.TrimSpace(category.Term); term != "" {
+			item.Categories = append(item.Categories, term)
+		}
+	}
+	return item
+}
@@ -0,0 +1,97 @@
+package rss
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Table-driven test for parsing Atom feeds into RSS items, with and without an item limit
+func TestFetchFeed_Atom(t *testing.T) {
+	feedXML := `<?xml version="1.0" encoding="utf-8"?>
+		<feed xmlns="http://www.w3.org/2005/Atom" xmlns:media="http://search.yahoo.com/mrss/">
+			<title>Test Blog</title>
+			<updated>2024-05-06T12:00:00Z</updated>
+			<link href="https://example.com/"/>
+			<author><name>Jane</name></author>
+			<logo>https://example.com/logo.png</logo>
+			<entry>
+				<title type="html">Second &amp;lt;post&amp;gt;</title>
+				<id>tag:example.com,2024:2</id>
+				<link rel="self" href="https://example.com/2.atom"/>
+				<link href="https://example.com/2"/>
+				<link rel="enclosure" href="https://example.com/2.mp3" type="audio/mpeg" length="123"/>
+				<published>2024-05-06T12:00:00+02:00</published>
+				<updated>2024-05-07T12:00:00Z</updated>
+				<summary>Short</summary>
+				<content type="html">&lt;p&gt;Long &lt;img src="https://example.com/2.png" alt="Chart"&gt;&lt;/p&gt;</content>
+				<category term="go"/>
+				<category term="web"/>
+			</entry>
+			<entry>
+				<title>First</title>
+				<id>tag:example.com,2024:1</id>
+				<link rel="alternate" type="text/html" href="https://example.com/1"/>
+				<updated>2024-05-01T12:00:00Z</updated>
+				<content type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml"><p>Inline</p></div></content>
+			</entry>
+		</feed>`
+
+	expected := []RSSItem{
+		{
+			Title:      "Second &lt;post&gt;",
+			Link:       "https://example.com/2",
+			GUID:       "tag:example.com,2024:2",
+			PubDate:    "2024-05-06T12:00:00+02:00",
+			Content:    "Short",
+			Encoded:    `<p>Long <img src="https://example.com/2.png" alt="Chart"></p>`,
+			Enclosures: []Enclosure{{URL: "https://example.com/2.mp3", Type: "audio/mpeg", Length: 123}},
+			Categories: []string{"go", "web"},
+		},
+		{
+			Title:   "First",
+			Link:    "https://example.com/1",
+			GUID:    "tag:example.com,2024:1",
+			PubDate: "2024-05-01T12:00:00Z",
+			Content: `<div xmlns="http://www.w3.org/1999/xhtml"><p>Inline</p></div>`,
+			Encoded: `<div xmlns="http://www.w3.org/1999/xhtml"><p>Inline</p></div>`,
+		},
+	}
+
+	tests := []struct {
+		name     string
+		maxItems int
+		expected []RSSItem
+	}{
+		{"all entries", 0, expected},
+		{"limited entries", 1, expected[:1]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			MaxItems = tt.maxItems
+			defer func() { MaxItems = 0 }()
+			server := mockHTTPServer(feedXML, 200)
+			defer server.Close()
+
+			feed, err := FetchFeed(server.URL)
+			if err != nil {
+				t.Fatalf("Failed to fetch Atom feed: %v", err)
+			}
+			if feed.Channel.Title != "Test Blog" || feed.Channel.LastBuildDate != "2024-05-06T12:00:00Z" {
+				t.Errorf("Unexpected channel %q, %q", feed.Channel.Title, feed.Channel.LastBuildDate)
+			}
+			if feed.ImageURL() != "https://example.com/logo.png" {
+				t.Errorf("Expected the feed logo, got %q", feed.ImageURL())
+			}
+			if !reflect.DeepEqual(feed.Channel.Items, tt.expected) {
+				t.Errorf("Expected items %+v, got %+v", tt.expected, feed.Channel.Items)
+			}
+			if images := feed.Channel.Items[0].Images(); len(images) != 1 || images[0].Alt != "Chart" {
+				t.Errorf("Expected the content's image, got %v", images)
+			}
+			if published, ok := feed.Channel.Items[0].Published(); !ok || published.UTC().Hour() != 10 {
+				t.Errorf("Expected the published date to be parsed, got %v", published)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package rss
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"strings"
+)
+
+// jsonFeedSniffLength is how many bytes are looked at to tell a JSON feed from an XML one
+const jsonFeedSniffLength = 512
+
+// jsonFeed is a JSON Feed (https://jsonfeed.org) of version 1 or 1.1, as published by
+// micro.blog and many static site generators
+type jsonFeed struct {
+	Title   string         `json:"title"`
+	Icon    string         `json:"icon"`
+	Favicon string         `json:"favicon"`
+	Items   []jsonFeedItem `json:"items"`
+}
+
+// jsonFeedItem is an item of a JSON Feed
+type jsonFeedItem struct {
+	// ID is a string, but some feeds use numbers
+	ID            any                  `json:"id"`
+	URL           string               `json:"url"`
+	ExternalURL   string               `json:"external_url"`
+	Title         string               `json:"title"`
+	ContentHTML   string               `json:"content_html"`
+	ContentText   string               `json:"content_text"`
+	Summary       string               `json:"summary"`
+	Image         string               `json:"image"`
+	BannerImage   string               `json:"banner_image"`
+	DatePublished string               `json:"date_published"`
+	DateModified  string               `json:"date_modified"`
+	Tags          []string             `json:"tags"`
+	Attachments   []jsonFeedAttachment `json:"attachments"`
+}
+
+// jsonFeedAttachment is a file attached to a JSON Feed item, e.g. a podcast episode
+type jsonFeedAttachment struct {
+	URL         string `json:"url"`
+	MimeType    string `json:"mime_type"`
+	SizeInBytes int64  `json:"size_in_bytes"`
+}
+
+// isJSONFeed reports whether a response is a JSON feed, by its Content-Type or, as feeds are
+// often served as text/plain or application/octet-stream, by its first bytes
+func isJSONFeed(contentType string, body *bufio.Reader) bool {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		switch mediaType {
+		case "application/feed+json", "application/json":
+			return true
+		case "application/rss+xml", "application/atom+xml", "application/xml", "text/xml":
+			return false
+		}
+	}
+	start, _ := body.Peek(jsonFeedSniffLength)
+	start = bytes.TrimPrefix(start, []byte("\xef\xbb\xbf"))
+	return bytes.HasPrefix(bytes.TrimSpace(start), []byte("{"))
+}
+
+// decodeJSONFeed parses a JSON feed into the RSS structure, keeping at most maxItems items
+// (0 keeps all of them)
+func decodeJSONFeed(body *bufio.Reader, maxItems int) (*RSSFeed, error) {
+	var feed jsonFeed
+	decoder := json.NewDecoder(body)
+	// keep numeric IDs as written
+	decoder.UseNumber()
+	if err := decoder.Decode(&feed); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON feed: %w", err)
+	}
+	if maxItems > 0 && len(feed.Items) > maxItems {
+		feed.Items = feed.Items[:maxItems]
+	}
+	return feed.toRSS(), nil
+}
+
+// toRSS converts the JSON feed to the RSS structure used everywhere else
+func (f jsonFeed) toRSS() *RSSFeed {
+	var feed RSSFeed
+	feed.Channel.Title = strings.TrimSpace(f.Title)
+	for _, url := range []string{f.Icon, f.Favicon} {
+		if url = strings.TrimSpace(url); url != "" {
+			feed.Channel.Images = append(feed.Channel.Images, ChannelImage{URL: url})
+		}
+	}
+	for _, item := range f.Items {
+		feed.Channel.Items = append(feed.Channel.Items, item.toRSS())
+	}
+	return &feed
+}
+
+// toRSS converts the JSON feed item to an RSS item: the summary becomes its description (or
+// the content if there is no summary), the HTML content its content:encoded and the image and
+// banner image its media
+func (i jsonFeedItem) toRSS() RSSItem {
+	item := RSSItem{
+		Title:      strings.TrimSpace(i.Title),
+		Link:       strings.TrimSpace(i.URL),
+		PubDate:    strings.TrimSpace(i.DatePublished),
+		Content:    strings.TrimSpace(i.Summary),
+		Encoded:    strings.TrimSpace(i.ContentHTML),
+		Categories: i.Tags,
+	}
+	if i.ID != nil {
+		item.GUID = fmt.Sprint(i.ID)
+	}
+	if item.Link == "" {
+		item.Link = strings.TrimSpace(i.ExternalURL)
+	}
+	if item.PubDate == "" {
+		item.PubDate = strings.TrimSpace(i.DateModified)
+	}
+	if item.Encoded == "" {
+		item.Encoded = strings.TrimSpace(i.ContentText)
+	}
+	if item.Content == "" {
+		item.Content = item.Encoded
+	}
+	for _, url := range []string{i.Image, i.BannerImage} {
+		if url = strings.TrimSpace(url); url != "" {
+			item.Media = append(item.Media, MediaContent{URL: url, Medium: "image"})
+		}
+	}
+	for _, attachment := range i.Attachments {
+		item.Enclosures = append(item.Enclosures, Enclosure{URL: strings.TrimSpace(attachment.URL), Type: attachment.MimeType, Length: attachment.SizeInBytes})
+	}
+	return item
+}
@@ -0,0 +1,122 @@
+package rss
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Table-driven test for telling JSON feeds apart by Content-Type or their first bytes
+func TestIsJSONFeed(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		expected    bool
+	}{
+		{"JSON Feed content type", "application/feed+json; charset=utf-8", "", true},
+		{"JSON content type", "application/json", "", true},
+		{"RSS content type", "application/rss+xml", `{"version":"https://jsonfeed.org/version/1.1"}`, false},
+		{"Sniffed JSON", "text/plain; charset=utf-8", "\xef\xbb\xbf\n  {\"version\":\"https://jsonfeed.org/version/1\"}", true},
+		{"Sniffed XML", "application/octet-stream", `<?xml version="1.0"?><rss/>`, false},
+		{"No content type", "", `<feed xmlns="http://www.w3.org/2005/Atom"/>`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := bufio.NewReader(strings.NewReader(tt.body))
+			if got := isJSONFeed(tt.contentType, body); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+// Table-driven test for parsing JSON feeds into RSS items, with and without an item limit
+func TestFetchFeed_JSONFeed(t *testing.T) {
+	feedJSON := `{
+		"version": "https://jsonfeed.org/version/1.1",
+		"title": "Test Blog",
+		"home_page_url": "https://example.com/",
+		"icon": "https://example.com/icon.png",
+		"items": [
+			{
+				"id": "https://example.com/2",
+				"url": "https://example.com/2",
+				"title": "Second",
+				"summary": "Short",
+				"content_html": "<p>Long</p>",
+				"image": "https://example.com/2.png",
+				"date_published": "2024-05-06T12:00:00+02:00",
+				"tags": ["go", "web"],
+				"attachments": [{"url": "https://example.com/2.mp3", "mime_type": "audio/mpeg", "size_in_bytes": 123}]
+			},
+			{
+				"id": 1234567890123,
+				"external_url": "https://example.org/linked",
+				"content_text": "A micro.blog post without a title",
+				"date_modified": "2024-05-01T12:00:00Z"
+			}
+		]
+	}`
+
+	expected := []RSSItem{
+		{
+			Title:      "Second",
+			Link:       "https://example.com/2",
+			GUID:       "https://example.com/2",
+			PubDate:    "2024-05-06T12:00:00+02:00",
+			Content:    "Short",
+			Encoded:    "<p>Long</p>",
+			Media:      []MediaContent{{URL: "https://example.com/2.png", Medium: "image"}},
+			Enclosures: []Enclosure{{URL: "https://example.com/2.mp3", Type: "audio/mpeg", Length: 123}},
+			Categories: []string{"go", "web"},
+		},
+		{
+			Link:    "https://example.org/linked",
+			GUID:    "1234567890123",
+			PubDate: "2024-05-01T12:00:00Z",
+			Content: "A micro.blog post without a title",
+			Encoded: "A micro.blog post without a title",
+		},
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		maxItems    int
+		expected    []RSSItem
+	}{
+		{"JSON Feed content type", "application/feed+json", 0, expected},
+		{"sniffed", "", 0, expected},
+		{"limited items", "application/feed+json", 1, expected[:1]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			MaxItems = tt.maxItems
+			defer func() { MaxItems = 0 }()
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.contentType != "" {
+					w.Header().Set("Content-Type", tt.contentType)
+				}
+				_, _ = w.Write([]byte(feedJSON))
+			}))
+			defer server.Close()
+
+			feed, err := FetchFeed(server.URL)
+			if err != nil {
+				t.Fatalf("Failed to fetch JSON feed: %v", err)
+			}
+			if feed.Channel.Title != "Test Blog" || feed.ImageURL() != "https://example.com/icon.png" {
+				t.Errorf("Unexpected channel %q, %q", feed.Channel.Title, feed.ImageURL())
+			}
+			if !reflect.DeepEqual(feed.Channel.Items, tt.expected) {
+				t.Errorf("Expected items %+v, got %+v", tt.expected, feed.Channel.Items)
+			}
+		})
+	}
+}
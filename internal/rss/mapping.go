@@ -0,0 +1,241 @@
+package rss
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Mapping converts API responses which aren't feeds, arbitrary JSON or XML, into feed items.
+// Each field is a path into the response, of object keys or element names separated by dots,
+// e.g. data.posts, or . for the response itself. XML paths start below the root element.
+// Numbers select an element of a list, e.g. links.0.href, and XML attributes are selected with
+// @, e.g. link.@href. Paths going through a list without an index use its first element.
+type Mapping struct {
+	// Items is the path of the list of items, required
+	Items string `mapstructure:"items"`
+	// Title, Link, GUID, Date, Content and Categories are paths within each item
+	Title      string `mapstructure:"item_title"`
+	Link       string `mapstructure:"item_link"`
+	GUID       string `mapstructure:"item_guid"`
+	Date       string `mapstructure:"item_date"`
+	Content    string `mapstructure:"item_content"`
+	Categories string `mapstructure:"item_categories"`
+}
+
+// IsZero reports whether no field of the mapping is set
+func (m Mapping) IsZero() bool {
+	return m == Mapping{}
+}
+
+// Validate checks that the mapping selects the items and their links
+func (m Mapping) Validate() error {
+	if m.IsZero() {
+		return nil
+	}
+	if m.Items == "" {
+		return fmt.Errorf("the items path is required to map a response to items")
+	}
+	if m.Link == "" {
+		return fmt.Errorf("the item_link path is required to map a response to items")
+	}
+	return nil
+}
+
+// decodeMapped parses a JSON or XML response, detected by its first character, and maps it to
+// feed items, at most maxItems of them unless it is 0
+func decodeMapped(body *bufio.Reader, m Mapping, maxItems int) (*RSSFeed, error) {
+	var doc any
+	var err error
+	if first, _ := peekNonSpace(body); first == '{' || first == '[' {
+		decoder := json.NewDecoder(body)
+		// keep numeric IDs as written
+		decoder.UseNumber()
+		err = decoder.Decode(&doc)
+	} else {
+		doc, err = decodeXMLTree(xml.NewDecoder(body))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	var feed RSSFeed
+	for _, entry := range listAt(doc, m.Items) {
+		if maxItems > 0 && len(feed.Channel.Items) >= maxItems {
+			break
+		}
+		item := RSSItem{
+			Title:   textAt(entry, m.Title),
+			Link:    textAt(entry, m.Link),
+			GUID:    textAt(entry, m.GUID),
+			PubDate: dateAt(entry, m.Date),
+			Content: textAt(entry, m.Content),
+		}
+		for _, category := range listAt(entry, m.Categories) {
+			if text := textOf(category); text != "" {
+				item.Categories = append(item.Categories, text)
+			}
+		}
+		feed.Channel.Items = append(feed.Channel.Items, item)
+	}
+	return &feed, nil
+}
+
+// peekNonSpace returns the first character of the body which isn't whitespace, leaving it unread
+func peekNonSpace(body *bufio.Reader) (byte, error) {
+	for {
+		b, err := body.Peek(1)
+		if err != nil {
+			return 0, err
+		}
+		if !strings.ContainsRune(" \t\r\n", rune(b[0])) {
+			return b[0], nil
+		}
+		if _, err := body.Discard(1); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// decodeXMLTree converts an XML document to the structure JSON decodes to: elements holding
+// only text become strings, others maps of their attributes (as @name), child elements (lists
+// if repeated) and text (as #text)
+func decodeXMLTree(decoder *xml.Decoder) (any, error) {
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return decodeXMLElement(decoder, start)
+		}
+	}
+}
+
+func decodeXMLElement(decoder *xml.Decoder, start xml.StartElement) (any, error) {
+	node := map[string]any{}
+	for _, attr := range start.Attr {
+		node["@"+attr.Name.Local] = attr.Value
+	}
+	var text strings.Builder
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			child, err := decodeXMLElement(decoder, token)
+			if err != nil {
+				return nil, err
+			}
+			name := token.Name.Local
+			switch existing := node[name].(type) {
+			case nil:
+				node[name] = child
+			case []any:
+				node[name] = append(existing, child)
+			default:
+				node[name] = []any{existing, child}
+			}
+		case xml.CharData:
+			text.Write(token)
+		case xml.EndElement:
+			content := strings.TrimSpace(text.String())
+			if len(node) == 0 {
+				return content, nil
+			}
+			if content != "" {
+				node["#text"] = content
+			}
+			return node, nil
+		}
+	}
+}
+
+// valueAt returns the value at a path within a decoded response, nil if there is none
+func valueAt(value any, path string) any {
+	switch path {
+	case "":
+		return nil
+	case ".":
+		return value
+	}
+	for _, key := range strings.Split(path, ".") {
+		if list, ok := value.([]any); ok {
+			if index, err := strconv.Atoi(key); err == nil {
+				if index < 0 || index >= len(list) {
+					return nil
+				}
+				value = list[index]
+				continue
+			}
+			if len(list) == 0 {
+				return nil
+			}
+			value = list[0]
+		}
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		value = object[key]
+	}
+	return value
+}
+
+// listAt returns the values of the list at a path, or the single value there as a list
+func listAt(value any, path string) []any {
+	switch value := valueAt(value, path).(type) {
+	case nil:
+		return nil
+	case []any:
+		return value
+	default:
+		return []any{value}
+	}
+}
+
+// textAt returns the text of the value at a path
+func textAt(value any, path string) string {
+	return textOf(valueAt(value, path))
+}
+
+// textOf returns a value's text: strings and numbers as they are, the text of XML elements
+// with attributes, and the text of the first element of lists
+func textOf(value any) string {
+	switch value := value.(type) {
+	case string:
+		return strings.TrimSpace(value)
+	case json.Number:
+		return value.String()
+	case bool:
+		return strconv.FormatBool(value)
+	case map[string]any:
+		return textOf(value["#text"])
+	case []any:
+		if len(value) > 0 {
+			return textOf(value[0])
+		}
+	}
+	return ""
+}
+
+// dateAt returns the date at a path as a pubDate: dates as they are, and numbers as Unix
+// timestamps, in seconds or milliseconds
+func dateAt(value any, path string) string {
+	text := textAt(value, path)
+	seconds, err := strconv.ParseInt(text, 10, 64)
+	if err != nil {
+		return text
+	}
+	// later than 2286 in seconds, so in milliseconds
+	if seconds > 9999999999 {
+		return time.UnixMilli(seconds).UTC().Format(time.RFC1123Z)
+	}
+	return time.Unix(seconds, 0).UTC().Format(time.RFC1123Z)
+}
@@ -0,0 +1,97 @@
+package rss
+
+import (
+	"bufio"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Table-driven test for mapping arbitrary JSON and XML responses to feed items
+func TestDecodeMapped(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		mapping  Mapping
+		maxItems int
+		expected []RSSItem
+	}{
+		{
+			name: "JSON",
+			body: `{"data":{"posts":[
+				{"id":7,"title":"One","url":"https://example.com/1","published":"2024-05-06T12:00:00Z","tags":["go","web"]},
+				{"id":8,"title":"Two","url":"https://example.com/2","published":"2024-05-07T12:00:00Z","tags":"go"}
+			]}}`,
+			mapping: Mapping{Items: "data.posts", Title: "title", Link: "url", GUID: "id", Date: "published", Categories: "tags"},
+			expected: []RSSItem{
+				{Title: "One", Link: "https://example.com/1", GUID: "7", PubDate: "2024-05-06T12:00:00Z", Categories: []string{"go", "web"}},
+				{Title: "Two", Link: "https://example.com/2", GUID: "8", PubDate: "2024-05-07T12:00:00Z", Categories: []string{"go"}},
+			},
+		},
+		{
+			name:     "JSON list with nested fields and timestamps",
+			body:     `  [{"links":[{"href":"https://example.com/a"},{"href":"https://example.com/b"}],"author":{"name":"Ann"},"ts":1715000000}]`,
+			mapping:  Mapping{Items: ".", Link: "links.1.href", Content: "author.name", Date: "ts"},
+			expected: []RSSItem{{Link: "https://example.com/b", Content: "Ann", PubDate: "Mon, 06 May 2024 12:53:20 +0000"}},
+		},
+		{
+			name: "XML",
+			body: `<?xml version="1.0"?><result><entries>
+				<entry id="e1"><name>One</name><link href="https://example.com/1"/><tag>go</tag><tag>web</tag></entry>
+				<entry id="e2"><name lang="en">Two</name><link href="https://example.com/2"/></entry>
+			</entries></result>`,
+			mapping: Mapping{Items: "entries.entry", Title: "name", Link: "link.@href", GUID: "@id", Categories: "tag"},
+			expected: []RSSItem{
+				{Title: "One", Link: "https://example.com/1", GUID: "e1", Categories: []string{"go", "web"}},
+				{Title: "Two", Link: "https://example.com/2", GUID: "e2"},
+			},
+		},
+		{
+			name:     "At most maxItems",
+			body:     `{"items":[{"u":"https://example.com/1"},{"u":"https://example.com/2"}]}`,
+			mapping:  Mapping{Items: "items", Link: "u"},
+			maxItems: 1,
+			expected: []RSSItem{{Link: "https://example.com/1"}},
+		},
+		{
+			name:     "No items",
+			body:     `{"items":[]}`,
+			mapping:  Mapping{Items: "data.items", Link: "u"},
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed, err := decodeMapped(bufio.NewReader(strings.NewReader(tt.body)), tt.mapping, tt.maxItems)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if !reflect.DeepEqual(feed.Channel.Items, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, feed.Channel.Items)
+			}
+		})
+	}
+}
+
+// Table-driven test for validating mappings
+func TestMappingValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		mapping     Mapping
+		expectedErr bool
+	}{
+		{"Unset", Mapping{}, false},
+		{"Items and links", Mapping{Items: "data", Link: "url"}, false},
+		{"Missing items", Mapping{Link: "url"}, true},
+		{"Missing links", Mapping{Items: "data", Title: "title"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.mapping.Validate(); (err != nil) != tt.expectedErr {
+				t.Errorf("Expected error %v, got %v", tt.expectedErr, err)
+			}
+		})
+	}
+}
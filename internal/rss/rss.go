@@ -1,48 +1,472 @@
 package rss
 
 import (
+	"bufio"
 	"crypto/sha256"
 	"encoding/xml"
+	"errors"
 	"fmt"
+	"html"
+	"io"
 	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
 	"time"
+
+	"github.com/toozej/rss2mastodon/internal/httpclient"
+	"github.com/toozej/rss2mastodon/internal/retry"
 )
 
 type RSSFeed struct {
 	Channel struct {
-		Title string    `xml:"title"`
-		Items []RSSItem `xml:"item"`
+		Title         string `xml:"title"`
+		LastBuildDate string `xml:"lastBuildDate"`
+		// Images also matches namespaced images like itunes:image, which have no <url>
+		Images []ChannelImage `xml:"image"`
+		Items  []RSSItem      `xml:"item"`
 	} `xml:"channel"`
 }
 
+// ChannelImage is a channel's <image> element, e.g. the blog's logo
+type ChannelImage struct {
+	URL string `xml:"url"`
+}
+
+// ImageURL returns the URL of the channel's image, if it has one
+func (f *RSSFeed) ImageURL() string {
+	for _, image := range f.Channel.Images {
+		if url := strings.TrimSpace(image.URL); url != "" {
+			return url
+		}
+	}
+	return ""
+}
+
 type RSSItem struct {
-	Title   string `xml:"title"`
-	Link    string `xml:"link"`
-	Content string `xml:"description"`
+	Title       string         `xml:"title"`
+	Link        string         `xml:"link"`
+	GUID        string         `xml:"guid"`
+	PubDate     string         `xml:"pubDate"`
+	Content     string         `xml:"description"`
+	Encoded     string         `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	Enclosures  []Enclosure    `xml:"enclosure"`
+	Media       []MediaContent `xml:"http://search.yahoo.com/mrss/ content"`
+	MediaGroups []MediaGroup   `xml:"http://search.yahoo.com/mrss/ group"`
+	Categories  []string       `xml:"category"`
+	// FeedURL is the URL of the feed the item came from
+	FeedURL string `xml:"-"`
+	// Source is the name of the blog the item came from, set for planet member feeds
+	Source string `xml:"-"`
+	// Announcement replaces the default toot text, set by sources which aren't blogs
+	Announcement string `xml:"-"`
+	// AttachImages attaches the item's images even if attach_images is off, e.g. video thumbnails
+	AttachImages bool `xml:"-"`
+	// Reappeared is set for items which were missing from their feed's previous fetch
+	Reappeared bool `xml:"-"`
+}
+
+// Enclosure is an RSS <enclosure> element, commonly used by podcasts and vlogs
+type Enclosure struct {
+	URL    string `xml:"url,attr"`
+	Type   string `xml:"type,attr"`
+	Length int64  `xml:"length,attr"`
+}
+
+// MediaContent is a Media RSS <media:content> element
+type MediaContent struct {
+	URL         string `xml:"url,attr"`
+	Type        string `xml:"type,attr"`
+	Medium      string `xml:"medium,attr"`
+	Title       string `xml:"http://search.yahoo.com/mrss/ title"`
+	Description string `xml:"http://search.yahoo.com/mrss/ description"`
+}
+
+// MediaGroup is a Media RSS <media:group> element wrapping several <media:content> elements,
+// e.g. renditions of one image, which share the group's title and description
+type MediaGroup struct {
+	Media       []MediaContent `xml:"http://search.yahoo.com/mrss/ content"`
+	Title       string         `xml:"http://search.yahoo.com/mrss/ title"`
+	Description string         `xml:"http://search.yahoo.com/mrss/ description"`
+}
+
+// Image is an image referenced by a feed item, along with its alt text
+type Image struct {
+	URL string
+	Alt string
 }
 
-// CheckRSSFeed fetches and parses the RSS feed from the provided URL
+// dateLayouts are the date formats seen in the wild for RSS pubDate elements
+var dateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 MST",
+	time.RFC3339,
+}
+
+// ErrNotModified is returned for feeds which haven't changed since their last fetch
+var ErrNotModified = errors.New("feed not modified")
+
+// Validators are the ETag and Last-Modified headers of a feed's response, sent back to only
+// download the feed again once it has changed
+type Validators struct {
+	ETag         string
+	LastModified string
+}
+
+// Request customizes the HTTP request fetching a feed, e.g. for APIs queried with POST, and
+// how its response is read
+type Request struct {
+	// Method is the HTTP method, GET if empty
+	Method string
+	// Body is sent with the request, if set
+	Body string
+	// Headers are set on the request, overriding the default ones
+	Headers map[string]string
+	// Mapping converts responses which aren't feeds into items, if set
+	Mapping Mapping
+}
+
+// MaxItems limits how many items of a feed are parsed, 0 parses all of them. Feeds list their
+// newest items first, so in low-memory mode the rest of a long feed is skipped.
+var MaxItems = 0
+
+var (
+	imgTagRegex  = regexp.MustCompile(`(?is)<img\s[^>]*>`)
+	imgAttrRegex = regexp.MustCompile(`(?is)\b(src|alt|title)\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+)
+
+// CheckRSSFeed fetches and parses the RSS, Atom or JSON feed from the provided URL, returning its items
 func CheckRSSFeed(feedURL string) ([]RSSItem, error) {
-	client := http.Client{
-		Timeout: 10 * time.Second,
+	feed, err := FetchFeed(feedURL)
+	if err != nil {
+		return nil, err
 	}
+	return feed.Channel.Items, nil
+}
 
-	resp, err := client.Get(feedURL)
+// FetchFeed fetches and parses the RSS, Atom or JSON feed from the provided URL
+func FetchFeed(feedURL string) (*RSSFeed, error) {
+	feed, _, err := FetchFeedIfModified(feedURL, Validators{})
+	return feed, err
+}
+
+// FetchLimiter spaces feed requests to the same host, e.g. the feeds of a planet hosted together
+var FetchLimiter = httpclient.NewLimiter(0)
+
+// FetchFeedRequest fetches and parses a feed like FetchFeed, with a customized request
+func FetchFeedRequest(feedURL string, request Request) (*RSSFeed, error) {
+	feed, _, err := fetchWithRetries(feedURL, request, Validators{})
+	return feed, err
+}
+
+// FetchFeedIfModified fetches and parses a feed like FetchFeed, unless it hasn't changed since
+// the response the given validators came from, in which case ErrNotModified is returned. It
+// also returns the validators of the response, for the next request.
+func FetchFeedIfModified(feedURL string, cached Validators) (*RSSFeed, Validators, error) {
+	return fetchWithRetries(feedURL, Request{}, cached)
+}
+
+// fetchWithRetries fetches and parses a feed, retrying failed requests, and downloads which
+// broke off, e.g. when the connection was reset, according to the fetch_retry policy
+func fetchWithRetries(feedURL string, request Request, cached Validators) (*RSSFeed, Validators, error) {
+	policy := retry.Configured(retry.FetchKey)
+	client := httpclient.New("rss", 10*time.Second, httpclient.Retry(policy), httpclient.RateLimit(FetchLimiter))
+
+	var feed *RSSFeed
+	validators := cached
+	err := retry.Do(policy, "Reading feed "+feedURL, func() error {
+		var interrupted bool
+		var err error
+		feed, validators, interrupted, err = fetchFeed(client, feedURL, request, cached)
+		// failed requests were retried by the client already
+		if err != nil && !interrupted {
+			return retry.Permanent(err)
+		}
+		return err
+	})
+	return feed, validators, err
+}
+
+// fetchFeed fetches and parses a feed once, also reporting whether reading it failed because
+// its download broke off
+func fetchFeed(client *http.Client, feedURL string, request Request, cached Validators) (*RSSFeed, Validators, bool, error) {
+	method := request.Method
+	if method == "" {
+		method = "GET"
+	}
+	var requestBody io.Reader
+	if request.Body != "" {
+		requestBody = strings.NewReader(request.Body)
+	}
+	req, err := http.NewRequest(method, feedURL, requestBody)
 	if err != nil {
-		return nil, fmt.Errorf("HTTP request failed: %w", err)
+		return nil, cached, false, fmt.Errorf("HTTP request failed: %w", err)
+	}
+	if cached.ETag != "" {
+		req.Header.Set("If-None-Match", cached.ETag)
+	}
+	if cached.LastModified != "" {
+		req.Header.Set("If-Modified-Since", cached.LastModified)
+	}
+	for name, value := range request.Headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, cached, false, fmt.Errorf("HTTP request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, cached, false, ErrNotModified
+	}
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected HTTP status: %d", resp.StatusCode)
+		return nil, cached, false, fmt.Errorf("unexpected HTTP status: %d", resp.StatusCode)
+	}
+	validators := Validators{ETag: resp.Header.Get("ETag"), LastModified: resp.Header.Get("Last-Modified")}
+
+	download := &bodyReader{r: resp.Body}
+	body := bufio.NewReader(download)
+	var feed *RSSFeed
+	switch {
+	case !request.Mapping.IsZero():
+		feed, err = decodeMapped(body, request.Mapping, MaxItems)
+	case isJSONFeed(resp.Header.Get("Content-Type"), body):
+		feed, err = decodeJSONFeed(body, MaxItems)
+	case MaxItems > 0:
+		feed, err = decodeItems(body, MaxItems)
+	default:
+		feed, err = decodeFeed(body)
 	}
+	return feed, validators, err != nil && retry.Transient(download.err), err
+}
+
+// bodyReader remembers the first error reading a response body, to tell downloads which broke
+// off from malformed feeds
+type bodyReader struct {
+	r   io.Reader
+	err error
+}
+
+func (b *bodyReader) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	if err != nil && err != io.EOF && b.err == nil {
+		b.err = err
+	}
+	return n, err
+}
+
+// decodeFeed parses an RSS or Atom feed, detected by its root element. Atom feeds are
+// converted to the RSS structure.
+func decodeFeed(r io.Reader) (*RSSFeed, error) {
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse RSS feed: %w", err)
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
 
+		if isAtom(start) {
+			var feed atomFeed
+			if err := decoder.DecodeElement(&feed, &start); err != nil {
+				return nil, fmt.Errorf("failed to parse Atom feed: %w", err)
+			}
+			return feed.toRSS(), nil
+		}
+		var feed RSSFeed
+		if err := decoder.DecodeElement(&feed, &start); err != nil {
+			return nil, fmt.Errorf("failed to parse RSS feed: %w", err)
+		}
+		return &feed, nil
+	}
+}
+
+// decodeItems parses an RSS or Atom feed element by element, stopping after maxItems items so
+// the rest of the feed is neither read nor held in memory
+func decodeItems(r io.Reader, maxItems int) (*RSSFeed, error) {
 	var feed RSSFeed
-	if err := xml.NewDecoder(resp.Body).Decode(&feed); err != nil {
-		return nil, fmt.Errorf("failed to parse RSS feed: %w", err)
+	decoder := xml.NewDecoder(r)
+	for len(feed.Channel.Items) < maxItems {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse RSS feed: %w", err)
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch start.Name.Local {
+		case "rss", "channel", "feed":
+			// descend into the elements holding the items
+		case "item":
+			var item RSSItem
+			err = decoder.DecodeElement(&item, &start)
+			feed.Channel.Items = append(feed.Channel.Items, item)
+		case "entry":
+			var entry atomEntry
+			err = decoder.DecodeElement(&entry, &start)
+			feed.Channel.Items = append(feed.Channel.Items, entry.toRSS())
+		case "logo", "icon":
+			var url string
+			err = decoder.DecodeElement(&url, &start)
+			feed.Channel.Images = append(feed.Channel.Images, ChannelImage{URL: url})
+		case "image":
+			var image ChannelImage
+			err = decoder.DecodeElement(&image, &start)
+			feed.Channel.Images = append(feed.Channel.Images, image)
+		case "title":
+			err = decoder.DecodeElement(&feed.Channel.Title, &start)
+		case "lastBuildDate", "updated":
+			err = decoder.DecodeElement(&feed.Channel.LastBuildDate, &start)
+		default:
+			err = decoder.Skip()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse RSS feed: %w", err)
+		}
 	}
+	return &feed, nil
+}
 
-	return feed.Channel.Items, nil
+// Published returns the item's parsed pubDate, and false if it is missing or unparseable
+func (item RSSItem) Published() (time.Time, bool) {
+	return ParseDate(item.PubDate)
+}
+
+// ParseDate parses a feed date in any of the commonly used formats
+func ParseDate(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Images returns the images referenced by the item in declared order, first from
+// <media:content> elements, then from image enclosures and then from <img> tags in the item's
+// HTML content. Relative URLs are resolved against the item's link and inline data: images are
+// skipped. Images are described by their media:description, media:title, alt or title
+// attribute, or else the item's title.
+func (item RSSItem) Images() []Image {
+	var images []Image
+	seen := make(map[string]bool)
+	base, _ := url.Parse(item.Link)
+	add := func(imageURL, alt string) {
+		imageURL = strings.TrimSpace(imageURL)
+		if imageURL == "" || strings.HasPrefix(imageURL, "data:") {
+			return
+		}
+		if base != nil {
+			if ref, err := url.Parse(imageURL); err == nil {
+				imageURL = base.ResolveReference(ref).String()
+			}
+		}
+		if seen[imageURL] {
+			return
+		}
+		seen[imageURL] = true
+		if alt = strings.TrimSpace(alt); alt == "" {
+			alt = strings.TrimSpace(html.UnescapeString(item.Title))
+		}
+		images = append(images, Image{URL: imageURL, Alt: alt})
+	}
+
+	for _, m := range item.allMedia() {
+		if m.Medium == "image" || strings.HasPrefix(m.Type, "image/") {
+			alt := m.Description
+			if alt == "" {
+				alt = m.Title
+			}
+			add(m.URL, alt)
+		}
+	}
+	for _, e := range item.Enclosures {
+		if strings.HasPrefix(e.Type, "image/") {
+			add(e.URL, "")
+		}
+	}
+
+	for _, body := range []string{item.Encoded, item.Content} {
+		for _, tag := range imgTagRegex.FindAllString(body, -1) {
+			attrs := make(map[string]string)
+			for _, match := range imgAttrRegex.FindAllStringSubmatch(tag, -1) {
+				attrs[strings.ToLower(match[1])] = html.UnescapeString(match[2] + match[3])
+			}
+			alt := attrs["alt"]
+			if alt == "" {
+				alt = attrs["title"]
+			}
+			add(attrs["src"], alt)
+		}
+	}
+
+	return images
+}
+
+// MediaEnclosures returns the video and audio files attached to the item, first from
+// <enclosure> elements and then from <media:content> elements
+func (item RSSItem) MediaEnclosures() []Enclosure {
+	var enclosures []Enclosure
+	seen := make(map[string]bool)
+	add := func(e Enclosure) {
+		if e.URL == "" || seen[e.URL] {
+			return
+		}
+		seen[e.URL] = true
+		enclosures = append(enclosures, e)
+	}
+
+	for _, e := range item.Enclosures {
+		if isAudioVideo(e.Type, "") {
+			add(e)
+		}
+	}
+	for _, m := range item.allMedia() {
+		if isAudioVideo(m.Type, m.Medium) {
+			add(Enclosure{URL: m.URL, Type: m.Type})
+		}
+	}
+
+	return enclosures
+}
+
+// allMedia returns all <media:content> elements, including those nested in <media:group>
+func (item RSSItem) allMedia() []MediaContent {
+	media := append([]MediaContent{}, item.Media...)
+	for _, group := range item.MediaGroups {
+		for _, m := range group.Media {
+			if m.Title == "" {
+				m.Title = group.Title
+			}
+			if m.Description == "" {
+				m.Description = group.Description
+			}
+			media = append(media, m)
+		}
+	}
+	return media
+}
+
+func isAudioVideo(mimeType string, medium string) bool {
+	return medium == "video" || medium == "audio" ||
+		strings.HasPrefix(mimeType, "video/") || strings.HasPrefix(mimeType, "audio/")
 }
 
 // HashContent creates a SHA-256 hash of the post content
@@ -2,9 +2,15 @@ package rss
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/spf13/viper"
 )
 
 // Test RSS Feed parsing
@@ -39,6 +45,335 @@ func TestCheckRSSFeed(t *testing.T) {
 	}
 }
 
+// Test parsing a limited number of items streams the same feed as parsing all of them
+func TestDecodeItems(t *testing.T) {
+	feedXML := `<?xml version="1.0"?>
+		<rss xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd">
+			<channel>
+				<title>Test Blog</title>
+				<lastBuildDate>Mon, 06 May 2024 12:00:00 +0000</lastBuildDate>
+				<image><title>Logo</title><url>https://example.com/logo.png</url></image>
+				<itunes:image href="https://example.com/cover.jpg"/>
+				<item><title>Third</title><link>https://example.com/3</link></item>
+				<item><title>Second</title><link>https://example.com/2</link><category>go</category></item>
+				<item><title>First</title><link>https://example.com/1</link></item>
+			</channel>
+		</rss>`
+
+	tests := []struct {
+		name          string
+		maxItems      int
+		expectedItems []string
+	}{
+		{"fewer items than the feed", 2, []string{"Third", "Second"}},
+		{"more items than the feed", 10, []string{"Third", "Second", "First"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			MaxItems = tt.maxItems
+			defer func() { MaxItems = 0 }()
+			server := mockHTTPServer(feedXML, 200)
+			defer server.Close()
+
+			feed, err := FetchFeed(server.URL)
+			if err != nil {
+				t.Fatalf("Failed to fetch RSS feed: %v", err)
+			}
+			if feed.Channel.Title != "Test Blog" || feed.Channel.LastBuildDate != "Mon, 06 May 2024 12:00:00 +0000" {
+				t.Errorf("Unexpected channel %q, %q", feed.Channel.Title, feed.Channel.LastBuildDate)
+			}
+			if feed.ImageURL() != "https://example.com/logo.png" {
+				t.Errorf("Expected the channel image, got %q", feed.ImageURL())
+			}
+			var titles []string
+			for _, item := range feed.Channel.Items {
+				titles = append(titles, item.Title)
+			}
+			if strings.Join(titles, ",") != strings.Join(tt.expectedItems, ",") {
+				t.Errorf("Expected items %v, got %v", tt.expectedItems, titles)
+			}
+			if feed.Channel.Items[1].Categories[0] != "go" {
+				t.Errorf("Expected the item's category to be parsed, got %v", feed.Channel.Items[1].Categories)
+			}
+		})
+	}
+}
+
+// Table-driven test for conditional requests with a feed's ETag and Last-Modified
+func TestFetchFeedIfModified(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` || r.Header.Get("If-Modified-Since") == "Mon, 06 May 2024 12:00:00 GMT" {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Last-Modified", "Mon, 06 May 2024 12:00:00 GMT")
+		_, _ = w.Write([]byte(`<rss><channel><item><title>Post</title></item></channel></rss>`))
+	}))
+	defer server.Close()
+
+	current := Validators{ETag: `"v1"`, LastModified: "Mon, 06 May 2024 12:00:00 GMT"}
+	tests := []struct {
+		name        string
+		cached      Validators
+		notModified bool
+	}{
+		{"No validators", Validators{}, false},
+		{"Matching ETag", Validators{ETag: `"v1"`}, true},
+		{"Matching Last-Modified", Validators{LastModified: "Mon, 06 May 2024 12:00:00 GMT"}, true},
+		{"Changed feed", Validators{ETag: `"v0"`}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed, validators, err := FetchFeedIfModified(server.URL, tt.cached)
+			if tt.notModified {
+				if !errors.Is(err, ErrNotModified) || validators != tt.cached {
+					t.Errorf("Expected ErrNotModified with the cached validators, got %v, %+v", err, validators)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if len(feed.Channel.Items) != 1 || validators != current {
+				t.Errorf("Expected the feed with its validators, got %+v, %+v", feed, validators)
+			}
+		})
+	}
+}
+
+// Table-driven test for retrying feeds whose download broke off
+func TestFetchFeedIfModified_Interrupted(t *testing.T) {
+	tests := []struct {
+		name           string
+		retry          string
+		interruptions  int
+		expectRequests int
+		expectErr      bool
+	}{
+		{"Not retried by default", "", 1, 1, true},
+		{"Retried", "attempts=3,base_delay=0s,max_delay=0s", 2, 3, false},
+		{"Out of attempts", "attempts=2,base_delay=0s,max_delay=0s", 2, 2, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed := `<rss><channel><item><title>Post</title></item></channel></rss>`
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				if requests <= tt.interruptions {
+					// promise the whole feed, but only send half of it
+					w.Header().Set("Content-Length", fmt.Sprint(len(feed)))
+					_, _ = w.Write([]byte(feed[:len(feed)/2]))
+					return
+				}
+				_, _ = w.Write([]byte(feed))
+			}))
+			defer server.Close()
+
+			viper.Set("fetch_retry", tt.retry)
+			defer viper.Reset()
+
+			_, _, err := FetchFeedIfModified(server.URL, Validators{})
+			if (err != nil) != tt.expectErr {
+				t.Errorf("Expected error %v, got %v", tt.expectErr, err)
+			}
+			if requests != tt.expectRequests {
+				t.Errorf("Expected %d requests, got %d", tt.expectRequests, requests)
+			}
+		})
+	}
+}
+
+// Test finding the channel image, ignoring namespaced images without a url
+func TestRSSFeedImageURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		channel  string
+		expected string
+	}{
+		{"no image", `<title>Test Blog</title>`, ""},
+		{"rss image", `<image><url> https://example.com/logo.png </url></image>`, "https://example.com/logo.png"},
+		{
+			"itunes image after rss image",
+			`<image><url>https://example.com/logo.png</url></image><itunes:image href="https://example.com/cover.jpg"/>`,
+			"https://example.com/logo.png",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := mockHTTPServer(`<rss xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd"><channel>`+tt.channel+`</channel></rss>`, 200)
+			defer server.Close()
+
+			feed, err := FetchFeed(server.URL)
+			if err != nil {
+				t.Fatalf("Failed to fetch RSS feed: %v", err)
+			}
+			if got := feed.ImageURL(); got != tt.expected {
+				t.Errorf("Expected image URL %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+// Test extracting gallery images from media:content elements, image enclosures and <img> tags
+func TestRSSItemImages(t *testing.T) {
+	rssFeedXML := `
+		<rss xmlns:media="http://search.yahoo.com/mrss/" xmlns:content="http://purl.org/rss/1.0/modules/content/">
+			<channel>
+				<title>Test Blog</title>
+				<item>
+					<title>Gallery &amp;amp; more</title>
+					<link>https://example.com/gallery</link>
+					<description>&lt;p&gt;Photos&lt;/p&gt;&lt;img src="https://example.com/c.jpg" alt="Third &amp;amp; last"&gt;</description>
+					<content:encoded><![CDATA[<img title="Second" src='https://example.com/b.jpg'><img src="https://example.com/a.jpg"><img src="data:image/gif;base64,R0lGODlhAQABAAAAACw=" alt="Spacer"><img src="/images/d.png" alt="Relative"><img src="cover.jpg">]]></content:encoded>
+					<media:content url="https://example.com/a.jpg" medium="image">
+						<media:title>First</media:title>
+					</media:content>
+					<media:content url="https://example.com/video.mp4" type="video/mp4" />
+					<media:group>
+						<media:description>Group photo</media:description>
+						<media:content url="https://example.com/group-large.jpg" medium="image" />
+						<media:content url="https://example.com/group-small.jpg" medium="image">
+							<media:description>Small group photo</media:description>
+						</media:content>
+					</media:group>
+					<enclosure url="https://example.com/cover.jpg" type="image/jpeg" length="99" />
+				</item>
+			</channel>
+		</rss>`
+
+	server := mockHTTPServer(rssFeedXML, 200)
+	defer server.Close()
+
+	posts, err := CheckRSSFeed(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to fetch RSS feed: %v", err)
+	}
+	if len(posts) != 1 {
+		t.Fatalf("Expected 1 post, got %d", len(posts))
+	}
+
+	expected := []Image{
+		{URL: "https://example.com/a.jpg", Alt: "First"},
+		{URL: "https://example.com/group-large.jpg", Alt: "Group photo"},
+		{URL: "https://example.com/group-small.jpg", Alt: "Small group photo"},
+		{URL: "https://example.com/cover.jpg", Alt: "Gallery & more"},
+		{URL: "https://example.com/b.jpg", Alt: "Second"},
+		{URL: "https://example.com/images/d.png", Alt: "Relative"},
+		{URL: "https://example.com/c.jpg", Alt: "Third & last"},
+	}
+	images := posts[0].Images()
+	if len(images) != len(expected) {
+		t.Fatalf("Expected %d images, got %d: %v", len(expected), len(images), images)
+	}
+	for i := range expected {
+		if images[i] != expected[i] {
+			t.Errorf("Expected image %d to be %v, got %v", i, expected[i], images[i])
+		}
+	}
+}
+
+// Test extracting video and audio enclosures
+func TestRSSItemMediaEnclosures(t *testing.T) {
+	rssFeedXML := `
+		<rss xmlns:media="http://search.yahoo.com/mrss/">
+			<channel>
+				<title>Test Podcast</title>
+				<item>
+					<title>Episode 1</title>
+					<link>https://example.com/ep1</link>
+					<enclosure url="https://example.com/ep1.mp3" type="audio/mpeg" length="1234" />
+					<enclosure url="https://example.com/cover.jpg" type="image/jpeg" length="99" />
+					<media:group>
+						<media:content url="https://example.com/ep1.mp4" medium="video" />
+						<media:content url="https://example.com/ep1.mp3" type="audio/mpeg" />
+					</media:group>
+				</item>
+			</channel>
+		</rss>`
+
+	server := mockHTTPServer(rssFeedXML, 200)
+	defer server.Close()
+
+	posts, err := CheckRSSFeed(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to fetch RSS feed: %v", err)
+	}
+	if len(posts) != 1 {
+		t.Fatalf("Expected 1 post, got %d", len(posts))
+	}
+
+	expected := []Enclosure{
+		{URL: "https://example.com/ep1.mp3", Type: "audio/mpeg", Length: 1234},
+		{URL: "https://example.com/ep1.mp4"},
+	}
+	enclosures := posts[0].MediaEnclosures()
+	if len(enclosures) != len(expected) {
+		t.Fatalf("Expected %d enclosures, got %d: %v", len(expected), len(enclosures), enclosures)
+	}
+	for i := range expected {
+		if enclosures[i] != expected[i] {
+			t.Errorf("Expected enclosure %d to be %v, got %v", i, expected[i], enclosures[i])
+		}
+	}
+}
+
+// Table-driven test for parsing pubDate values
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected time.Time
+		ok       bool
+	}{
+		{
+			name:     "RFC1123Z",
+			value:    "Mon, 02 Jan 2006 15:04:05 -0700",
+			expected: time.Date(2006, 1, 2, 22, 4, 5, 0, time.UTC),
+			ok:       true,
+		},
+		{
+			name:     "Single digit day",
+			value:    "Mon, 2 Jan 2006 15:04:05 +0000",
+			expected: time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC),
+			ok:       true,
+		},
+		{
+			name:     "RFC3339",
+			value:    " 2006-01-02T15:04:05Z ",
+			expected: time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC),
+			ok:       true,
+		},
+		{
+			name:  "Empty",
+			value: "",
+			ok:    false,
+		},
+		{
+			name:  "Garbage",
+			value: "yesterday",
+			ok:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, ok := ParseDate(tt.value)
+			if ok != tt.ok {
+				t.Fatalf("Expected ok %v, got %v", tt.ok, ok)
+			}
+			if ok && !result.Equal(tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
 // Test hash content function
 func TestHashContent(t *testing.T) {
 	content := "This is a test post"
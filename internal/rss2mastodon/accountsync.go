@@ -0,0 +1,126 @@
+package rss2mastodon
+
+import (
+	"path"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+	"github.com/toozej/rss2mastodon/internal/mastodon"
+	"github.com/toozej/rss2mastodon/internal/media"
+)
+
+// Profile fields kept up to date by syncAccountProfile
+const (
+	lastPostField  = "Last post"
+	poweredByField = "Powered by"
+	poweredBy      = "rss2mastodon"
+)
+
+// Keys in the state table used to remember the last profile sync
+const (
+	accountSyncLastKey  = "account_sync_last"
+	accountAvatarURLKey = "account_avatar_url"
+)
+
+// syncAccountProfile updates the bot account's profile fields, and optionally its avatar from
+// the feed's channel image, once every account_sync_interval
+func syncAccountProfile() {
+	interval := viper.GetDuration("account_sync_interval")
+	if interval <= 0 {
+		return
+	}
+
+	last, ok, err := db.GetState(accountSyncLastKey)
+	if err != nil {
+		log.Error("Failed to read last profile sync: ", err)
+		return
+	}
+	if ok {
+		if synced, err := time.Parse(time.RFC3339, last); err == nil && time.Since(synced) < interval {
+			return
+		}
+	}
+
+	client := mastodonClient()
+	account, err := client.VerifyCredentials()
+	if err != nil {
+		log.Error("Failed to fetch bot account: ", err)
+		recordError(mastodonKey, err)
+		return
+	}
+
+	fields := account.Source.Fields
+	changed := false
+	if recent, err := db.RecentTootedPosts(1); err != nil {
+		log.Error("Failed to read recent posts: ", err)
+	} else if len(recent) > 0 {
+		var fieldChanged bool
+		fields, fieldChanged = mastodon.SetField(fields, lastPostField, recent[0].Link)
+		changed = changed || fieldChanged
+	}
+	fields, fieldChanged := mastodon.SetField(fields, poweredByField, poweredBy)
+	changed = changed || fieldChanged
+
+	var avatar []byte
+	avatarURL := ""
+	if viper.GetBool("account_sync_avatar") {
+		avatarURL, avatar = newAvatar()
+	}
+
+	if changed || avatar != nil {
+		if err := client.UpdateCredentials(fields, avatar, path.Base(avatarURL)); err != nil {
+			log.Error("Failed to update bot profile: ", err)
+			recordError(mastodonKey, err)
+			return
+		}
+		log.Info("Updated bot profile")
+		if avatar != nil {
+			if err := db.SetState(accountAvatarURLKey, avatarURL); err != nil {
+				log.Error("Failed to record avatar URL: ", err)
+			}
+		}
+	}
+
+	if err := db.SetState(accountSyncLastKey, time.Now().UTC().Format(time.RFC3339)); err != nil {
+		log.Error("Failed to record profile sync: ", err)
+	}
+}
+
+// newAvatar downloads the first configured feed's channel image if it differs from the
+// avatar uploaded last, returning its URL and data, or nil data if there is nothing to upload
+func newAvatar() (string, []byte) {
+	feeds, err := configuredFeeds()
+	if err != nil || len(feeds) == 0 {
+		return "", nil
+	}
+
+	feed, err := feeds[0].fetch(time.Now())
+	if err != nil {
+		log.Error("Failed to fetch feed for its image: ", err)
+		return "", nil
+	}
+	imageURL := feed.ImageURL()
+	if imageURL == "" {
+		return "", nil
+	}
+
+	current, _, err := db.GetState(accountAvatarURLKey)
+	if err != nil {
+		log.Error("Failed to read avatar URL: ", err)
+		return "", nil
+	}
+	if current == imageURL {
+		return "", nil
+	}
+
+	// avatars aren't covered by the instance's media limits
+	data, err := media.Download(imageURL, 0)
+	if err != nil {
+		log.Error("Failed to download feed image: ", err)
+		return "", nil
+	}
+	return imageURL, data
+}
@@ -0,0 +1,113 @@
+package rss2mastodon
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+	"github.com/toozej/rss2mastodon/internal/mastodon"
+)
+
+// Test the bot profile is synced once per interval, and the avatar only when the image changes
+func TestSyncAccountProfile(t *testing.T) {
+	db.InitDB()
+	defer db.CloseDB()
+	defer os.Remove("./tooted_posts.db")
+
+	var avatar bytes.Buffer
+	if err := png.Encode(&avatar, image.NewRGBA(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatalf("Failed to encode image: %v", err)
+	}
+
+	fields := []mastodon.Field{{Name: "Website", Value: "https://example.com"}}
+	var updates []map[string]string
+	avatars := 0
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/feed.xml":
+			_, _ = w.Write([]byte(`<rss><channel><image><url>` + server.URL + `/logo.png</url></image></channel></rss>`))
+		case "/logo.png":
+			_, _ = w.Write(avatar.Bytes())
+		case "/api/v1/accounts/verify_credentials":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"id": "1", "source": map[string]interface{}{"fields": fields}})
+		case "/api/v1/accounts/update_credentials":
+			if err := r.ParseMultipartForm(1 << 20); err != nil {
+				t.Fatalf("Failed to parse form: %v", err)
+			}
+			update := map[string]string{}
+			for key, values := range r.MultipartForm.Value {
+				update[key] = values[0]
+			}
+			updates = append(updates, update)
+			fields = nil
+			for i := 0; update[fmt.Sprintf("fields_attributes[%d][name]", i)] != ""; i++ {
+				fields = append(fields, mastodon.Field{
+					Name:  update[fmt.Sprintf("fields_attributes[%d][name]", i)],
+					Value: update[fmt.Sprintf("fields_attributes[%d][value]", i)],
+				})
+			}
+			if _, _, err := r.FormFile("avatar"); err == nil {
+				avatars++
+			}
+			_, _ = w.Write([]byte(`{"id":"1","acct":"bot"}`))
+		default:
+			t.Errorf("Unexpected request: %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	viper.Reset()
+	viper.Set("mastodon_url", server.URL)
+	viper.Set("mastodon_access_token", "fake-token")
+	viper.Set("feed_url", server.URL+"/feed.xml")
+	viper.Set("account_sync_interval", time.Hour)
+	viper.Set("account_sync_avatar", true)
+	defer viper.Reset()
+
+	if err := db.StoreTootedPost("https://example.com/latest", "content"); err != nil {
+		t.Fatalf("Failed to store post: %v", err)
+	}
+
+	syncAccountProfile()
+	syncAccountProfile()
+
+	if len(updates) != 1 {
+		t.Fatalf("Expected a single profile update, got %d", len(updates))
+	}
+	expected := map[string]string{
+		"fields_attributes[0][name]":  "Website",
+		"fields_attributes[0][value]": "https://example.com",
+		"fields_attributes[1][name]":  lastPostField,
+		"fields_attributes[1][value]": "https://example.com/latest",
+		"fields_attributes[2][name]":  poweredByField,
+		"fields_attributes[2][value]": poweredBy,
+	}
+	for key, value := range expected {
+		if updates[0][key] != value {
+			t.Errorf("Expected %s to be %q, got %q", key, value, updates[0][key])
+		}
+	}
+	if avatars != 1 {
+		t.Errorf("Expected the avatar to be uploaded once, got %d", avatars)
+	}
+
+	// the avatar is already up to date and the fields are unchanged on the next sync
+	if err := db.SetState(accountSyncLastKey, time.Now().Add(-2*time.Hour).UTC().Format(time.RFC3339)); err != nil {
+		t.Fatalf("Failed to set state: %v", err)
+	}
+	syncAccountProfile()
+	if len(updates) != 1 {
+		t.Errorf("Expected no update when nothing changed, got %d", len(updates))
+	}
+}
@@ -0,0 +1,232 @@
+package rss2mastodon
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+	"github.com/toozej/rss2mastodon/internal/notify"
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// requestApproval queues a new post until it is approved, notifying the operator the first time
+func requestApproval(post rss.RSSItem, notBefore time.Time) {
+	pending, err := db.GetPendingPost(post.Link)
+	if err != nil {
+		log.Error("Failed to read pending posts: ", err)
+		return
+	}
+	if pending != nil {
+		return
+	}
+
+	if err := db.QueuePendingPost(post, notBefore, db.PendingApproval); err != nil {
+		log.Error("Failed to queue post for approval: ", err)
+		return
+	}
+
+	pending, err = db.GetPendingPost(post.Link)
+	if err != nil || pending == nil {
+		log.Error("Failed to read queued post: ", err)
+		return
+	}
+
+	message := fmt.Sprintf("%s\n%s\n\nApprove with `rss2mastodon approve %d` or reject with `rss2mastodon reject %d`",
+		post.Title, post.Link, pending.ID, pending.ID)
+	if err := notify.SendWithActions("rss2mastodon: post awaiting approval", message, approvalActions(pending)); err != nil {
+		log.Error("Failed to send approval notification: ", err)
+	}
+}
+
+// approvalTokenHeader carries the single-use token of an approval notification button
+const approvalTokenHeader = "X-Approval-Token"
+
+// approvalSecretKey is the state key of the secret approval tokens are signed with
+const approvalSecretKey = "approval_secret"
+
+// approvalActions returns notification buttons calling the admin listener's approval endpoints,
+// if the listener is reachable at a configured public URL. The buttons carry single-use tokens
+// only valid for this post, never the admin token, as everyone subscribed to the notifications
+// sees them.
+func approvalActions(pending *db.PendingPost) []notify.Action {
+	publicURL := strings.TrimSuffix(viper.GetString("public_url"), "/")
+	if publicURL == "" || viper.GetString("listen_addr") == "" {
+		return nil
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		log.Error("Failed to create approval token: ", err)
+		return nil
+	}
+	if err := db.SetState(approvalNonceKey(pending.ID), hex.EncodeToString(nonce)); err != nil {
+		log.Error("Failed to store approval token: ", err)
+		return nil
+	}
+
+	var actions []notify.Action
+	for _, action := range []string{"approve", "reject"} {
+		token, err := signApproval(action, pending, hex.EncodeToString(nonce))
+		if err != nil {
+			log.Error("Failed to sign approval token: ", err)
+			return nil
+		}
+		actions = append(actions, notify.Action{
+			Label:   strings.ToUpper(action[:1]) + action[1:],
+			URL:     fmt.Sprintf("%s/%s/%d", publicURL, action, pending.ID),
+			Method:  "POST",
+			Headers: map[string]string{approvalTokenHeader: token},
+		})
+	}
+	return actions
+}
+
+// approvalNonceKey is the state key of the nonce of the approval tokens issued for a pending post
+func approvalNonceKey(id int64) string {
+	return fmt.Sprintf("approval_nonce:%d", id)
+}
+
+// signApproval returns the token for the action on the pending post, signed with a secret
+// created on first use and kept in the database
+func signApproval(action string, pending *db.PendingPost, nonce string) (string, error) {
+	secret, ok, err := db.GetState(approvalSecretKey)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		random := make([]byte, 32)
+		if _, err := rand.Read(random); err != nil {
+			return "", err
+		}
+		secret = hex.EncodeToString(random)
+		if err := db.SetState(approvalSecretKey, secret); err != nil {
+			return "", err
+		}
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = fmt.Fprintf(mac, "%s\n%d\n%s\n%s", action, pending.ID, pending.Item.Link, nonce)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// useApprovalToken reports whether token is a valid token for the action on the pending post,
+// using up the tokens of the post so none of its buttons work again
+func useApprovalToken(action string, pending *db.PendingPost, token string) (bool, error) {
+	nonce, ok, err := db.GetState(approvalNonceKey(pending.ID))
+	if err != nil || !ok {
+		return false, err
+	}
+	expected, err := signApproval(action, pending, nonce)
+	if err != nil {
+		return false, err
+	}
+	if !hmac.Equal([]byte(token), []byte(expected)) {
+		return false, nil
+	}
+	// only one of concurrent requests with the token deletes the nonce
+	return db.DeleteState(approvalNonceKey(pending.ID))
+}
+
+// awaitingApproval returns the pending post with the given queue ID or link, if it awaits approval
+func awaitingApproval(idOrLink string) (*db.PendingPost, error) {
+	pending, err := db.GetPendingPost(idOrLink)
+	if err != nil {
+		return nil, err
+	}
+	if pending == nil {
+		return nil, fmt.Errorf("no pending post %s", idOrLink)
+	}
+	if pending.Reason != db.PendingApproval {
+		return nil, fmt.Errorf("pending post %s is not awaiting approval (%s)", idOrLink, pending.Reason)
+	}
+	return pending, nil
+}
+
+// approvePost marks a post awaiting approval as approved so it is announced on the next cycle
+func approvePost(idOrLink string) (*db.PendingPost, error) {
+	pending, err := awaitingApproval(idOrLink)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.SetPendingReason(pending.Item.Link, db.PendingApproved); err != nil {
+		return nil, err
+	}
+	if _, err := db.DeleteState(approvalNonceKey(pending.ID)); err != nil {
+		return nil, err
+	}
+	return pending, nil
+}
+
+// rejectPost removes a post from the pending queue and blocks it from ever being announced
+func rejectPost(idOrLink string) (*db.PendingPost, error) {
+	pending, err := awaitingApproval(idOrLink)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.BlockItem(pending.Item.Link); err != nil {
+		return nil, err
+	}
+	if err := db.RemovePendingPost(pending.Item.Link); err != nil {
+		return nil, err
+	}
+	if _, err := db.DeleteState(approvalNonceKey(pending.ID)); err != nil {
+		return nil, err
+	}
+	return pending, nil
+}
+
+// Approve approves posts awaiting approval, or lists them
+func Approve(cmd *cobra.Command, args []string) {
+	list, _ := cmd.Flags().GetBool("list")
+	if !list && len(args) == 0 {
+		log.Fatal("At least one pending post ID or URL is required")
+	}
+
+	OpenDB()
+	defer db.CloseDB()
+
+	for _, id := range args {
+		pending, err := approvePost(id)
+		if err != nil {
+			log.Fatal("Failed to approve post: ", err)
+		}
+		log.Infof("Approved %s", pending.Item.Link)
+	}
+
+	if list {
+		posts, err := db.ListPendingPosts()
+		if err != nil {
+			log.Fatal("Failed to list pending posts: ", err)
+		}
+		for _, pending := range posts {
+			if pending.Reason == db.PendingApproval {
+				fmt.Printf("%d\t%s\t%s\n", pending.ID, pending.Item.Title, pending.Item.Link)
+			}
+		}
+	}
+}
+
+// Reject rejects posts awaiting approval, adding them to the blocklist
+func Reject(cmd *cobra.Command, args []string) {
+	OpenDB()
+	defer db.CloseDB()
+
+	for _, id := range args {
+		pending, err := rejectPost(id)
+		if err != nil {
+			log.Fatal("Failed to reject post: ", err)
+		}
+		log.Infof("Rejected %s", pending.Item.Link)
+	}
+}
@@ -0,0 +1,114 @@
+package rss2mastodon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// Test posts are only tooted once approved, and rejected posts are blocked
+func TestApprovalWorkflow(t *testing.T) {
+	db.InitDB()
+	defer db.CloseDB()
+	defer os.Remove("./tooted_posts.db")
+
+	var statuses []string
+	var actions []string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ntfy" {
+			actions = append(actions, r.Header.Get("Actions"))
+			return
+		}
+		statuses = append(statuses, r.FormValue("status"))
+		_, _ = w.Write([]byte(`{"id":"1"}`))
+	}))
+	defer mockServer.Close()
+
+	viper.Reset()
+	viper.Set("mastodon_url", mockServer.URL)
+	viper.Set("mastodon_access_token", "fake-token")
+	viper.Set("ntfy_url", mockServer.URL+"/ntfy")
+	viper.Set("require_approval", true)
+	viper.Set("listen_addr", ":8080")
+	viper.Set("public_url", "https://bot.example.com/")
+	viper.Set("admin_token", "admin-secret")
+	defer viper.Reset()
+
+	approved := rss.RSSItem{Title: "Approved", Link: "https://example.com/approved"}
+	rejected := rss.RSSItem{Title: "Rejected", Link: "https://example.com/rejected"}
+
+	// seeing the posts on several cycles only notifies once per post
+	for i := 0; i < 2; i++ {
+		handlePost(feedLogger(approved.FeedURL), approved, newCycleStats())
+		handlePost(feedLogger(rejected.FeedURL), rejected, newCycleStats())
+		processPendingPosts(newCycleStats())
+	}
+	if len(statuses) != 0 {
+		t.Fatalf("Expected no toots before approval, got %v", statuses)
+	}
+	if len(actions) != 2 {
+		t.Fatalf("Expected 2 approval notifications, got %d", len(actions))
+	}
+	if !strings.Contains(actions[0], "https://bot.example.com/approve/") {
+		t.Errorf("Expected approve action button, got '%s'", actions[0])
+	}
+	if strings.Contains(actions[0], "admin-secret") || !strings.Contains(actions[0], "headers."+approvalTokenHeader+"=") {
+		t.Errorf("Expected action buttons with approval tokens instead of the admin token, got '%s'", actions[0])
+	}
+
+	if _, err := approvePost(approved.Link); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := rejectPost(rejected.Link); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := rejectPost(rejected.Link); err == nil {
+		t.Errorf("Expected error rejecting post which is no longer pending")
+	}
+
+	processPendingPosts(newCycleStats())
+	handlePost(feedLogger(rejected.FeedURL), rejected, newCycleStats())
+
+	if len(statuses) != 1 || statuses[0] != "New blog post: https://example.com/approved" {
+		t.Errorf("Expected only the approved post to be tooted, got %v", statuses)
+	}
+	if !isBlocked(rejected) {
+		t.Errorf("Expected rejected post to be blocked")
+	}
+	if pending, _ := db.GetPendingPost(rejected.Link); pending != nil {
+		t.Errorf("Expected rejected post not to be queued again")
+	}
+}
+
+// Test only posts awaiting approval can be approved or rejected
+func TestApprovePost_NotAwaitingApproval(t *testing.T) {
+	db.InitDB()
+	defer db.CloseDB()
+	defer os.Remove("./tooted_posts.db")
+
+	link := "https://example.com/embargoed"
+	if err := db.QueuePendingPost(rss.RSSItem{Link: link}, time.Now().Add(time.Hour), db.PendingEmbargo); err != nil {
+		t.Fatalf("Failed to queue post: %v", err)
+	}
+
+	if _, err := approvePost(link); err == nil {
+		t.Errorf("Expected error approving an embargoed post")
+	}
+	if _, err := rejectPost(link); err == nil {
+		t.Errorf("Expected error rejecting an embargoed post")
+	}
+	if pending, _ := db.GetPendingPost(link); pending == nil || pending.Reason != db.PendingEmbargo {
+		t.Errorf("Expected the post to stay embargoed, got %+v", pending)
+	}
+	if isBlocked(rss.RSSItem{Link: link}) {
+		t.Errorf("Expected the post not to be blocked")
+	}
+}
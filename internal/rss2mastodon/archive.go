@@ -0,0 +1,101 @@
+package rss2mastodon
+
+import (
+	"math/rand"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+	"github.com/toozej/rss2mastodon/internal/mastodon"
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// Defaults for reposting from the archive
+const (
+	defaultArchiveRepostMinAge   = 180 * 24 * time.Hour
+	defaultArchiveRepostCooldown = 365 * 24 * time.Hour
+	defaultArchiveRepostTemplate = "From the archive: {{.Link}}"
+)
+
+// tootedPost is a previously tooted post as available to the archive repost and digest templates
+type tootedPost struct {
+	Link   string
+	Tooted time.Time
+}
+
+// repostFromArchive re-shares a random previously tooted post once every archive_repost_interval.
+// Only posts last tooted more than archive_repost_min_age ago, and not reposted within
+// archive_repost_cooldown, are picked.
+func repostFromArchive(stats *cycleStats) {
+	interval := viper.GetDuration("archive_repost_interval")
+	if interval <= 0 {
+		return
+	}
+
+	last, ok, err := db.LastArchiveRepost()
+	if err != nil {
+		log.Error("Failed to read archive reposts: ", err)
+		return
+	}
+	if ok && time.Since(last) < interval {
+		return
+	}
+
+	minAge := viper.GetDuration("archive_repost_min_age")
+	if minAge <= 0 {
+		minAge = defaultArchiveRepostMinAge
+	}
+	cooldown := viper.GetDuration("archive_repost_cooldown")
+	if cooldown <= 0 {
+		cooldown = defaultArchiveRepostCooldown
+	}
+
+	candidates, err := db.ArchiveRepostCandidates(time.Now().Add(-minAge), time.Now().Add(-cooldown))
+	if err != nil {
+		log.Error("Failed to read archive repost candidates: ", err)
+		return
+	}
+	if len(candidates) == 0 {
+		log.Debug("No posts to repost from the archive")
+		return
+	}
+	post := candidates[rand.Intn(len(candidates))]
+
+	// which feed the post came from isn't known here, so only the global link_params apply
+	item := withLinkParams(rss.RSSItem{Link: post.Link})
+	content, err := archiveRepostContent(tootedPost{Link: item.Link, Tooted: post.Timestamp})
+	if err != nil {
+		log.Error("Invalid archive repost template: ", err)
+		return
+	}
+	content = withFooter(item, content)
+	if !reserveTootFor(log.NewEntry(log.StandardLogger()), "the archive repost of "+post.Link) {
+		return
+	}
+	defer releaseToot()
+	status, err := mastodonClient().ForItem(post.Link).PostStatus(content, mastodon.TootOptions{SpoilerText: contentWarning(item), Language: tootLanguage(""), LocalOnly: tootLocalOnly("")})
+	if err != nil {
+		log.Error("Failed to repost from the archive: ", err)
+		recordError(post.Link, err)
+		stats.failed.Add(1)
+		return
+	}
+	stats.tooted.Add(1)
+	recordStatus(status, post.Link, styleArchiveRepost)
+	log.Infof("Reposted from the archive: %s", post.Link)
+
+	if err := db.RecordArchiveRepost(post.Link); err != nil {
+		log.Error("Failed to record archive repost: ", err)
+	}
+}
+
+// archiveRepostContent renders archive_repost_template for a post
+func archiveRepostContent(post tootedPost) (string, error) {
+	text := viper.GetString("archive_repost_template")
+	if text == "" {
+		text = defaultArchiveRepostTemplate
+	}
+	return renderTemplate("archive_repost_template", text, post)
+}
@@ -0,0 +1,68 @@
+package rss2mastodon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+)
+
+// Test reposting from the archive is rate-limited and uses the template
+func TestRepostFromArchive(t *testing.T) {
+	db.InitDB()
+	defer db.CloseDB()
+	defer os.Remove("./tooted_posts.db")
+
+	var toots []string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		toots = append(toots, r.FormValue("status"))
+		_, _ = w.Write([]byte(`{"id":"1"}`))
+	}))
+	defer mockServer.Close()
+
+	viper.Reset()
+	viper.Set("mastodon_url", mockServer.URL)
+	viper.Set("mastodon_access_token", "fake-token")
+	viper.Set("archive_repost_interval", time.Hour)
+	viper.Set("archive_repost_min_age", time.Nanosecond)
+	viper.Set("archive_repost_template", "ICYMI: {{.Link}}")
+	defer viper.Reset()
+
+	// nothing to repost yet
+	repostFromArchive(newCycleStats())
+	if len(toots) != 0 {
+		t.Fatalf("Expected no reposts without history, got %v", toots)
+	}
+
+	if err := db.StoreTootedPost("https://example.com/old", "content"); err != nil {
+		t.Fatalf("Failed to store post: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	stats := newCycleStats()
+	repostFromArchive(stats)
+	repostFromArchive(stats)
+
+	if len(toots) != 1 || toots[0] != "ICYMI: https://example.com/old" {
+		t.Errorf("Expected a single repost, got %v", toots)
+	}
+	if stats.tooted.Load() != 1 {
+		t.Errorf("Expected 1 toot counted, got %d", stats.tooted.Load())
+	}
+}
+
+// Test an invalid template is reported
+func TestArchiveRepostContent_InvalidTemplate(t *testing.T) {
+	viper.Reset()
+	viper.Set("archive_repost_template", "{{.Link")
+	defer viper.Reset()
+
+	if _, err := archiveRepostContent(tootedPost{Link: "https://example.com"}); err == nil {
+		t.Errorf("Expected an error for an invalid template")
+	}
+}
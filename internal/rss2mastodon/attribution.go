@@ -0,0 +1,26 @@
+package rss2mastodon
+
+import (
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/toozej/rss2mastodon/internal/article"
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// withAuthorAttribution appends a mention of the author's fediverse account, taken from the
+// article's fediverse:creator meta tag, so the announcement credits them
+func withAuthorAttribution(post rss.RSSItem, content string) string {
+	page, err := article.Fetch(post.Link)
+	if err != nil {
+		log.Warnf("Failed to fetch %s for author attribution: %v", post.Link, err)
+		return content
+	}
+
+	creator := strings.TrimPrefix(strings.TrimSpace(page.Meta["fediverse:creator"]), "@")
+	if strings.Count(creator, "@") != 1 {
+		return content
+	}
+	return content + "\n\nby @" + creator
+}
@@ -0,0 +1,48 @@
+package rss2mastodon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// Table-driven test for crediting the author's fediverse account
+func TestWithAuthorAttribution(t *testing.T) {
+	tests := []struct {
+		name     string
+		page     string
+		expected string
+	}{
+		{
+			name:     "fediverse:creator",
+			page:     `<meta name="fediverse:creator" content="@me@example.social">`,
+			expected: "New blog post\n\nby @me@example.social",
+		},
+		{
+			name:     "No fediverse:creator",
+			page:     `<meta property="og:title" content="Hello">`,
+			expected: "New blog post",
+		},
+		{
+			name:     "Invalid fediverse:creator",
+			page:     `<meta name="fediverse:creator" content="me">`,
+			expected: "New blog post",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("<html><head>" + tt.page + "</head></html>"))
+			}))
+			defer mockServer.Close()
+
+			result := withAuthorAttribution(rss.RSSItem{Link: mockServer.URL}, "New blog post")
+			if result != tt.expected {
+				t.Errorf("Expected '%s', got '%s'", tt.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package rss2mastodon
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+	"github.com/toozej/rss2mastodon/internal/httpclient"
+)
+
+// recordAudit appends an outbound request to the audit log, dropping entries older than
+// audit_log_retention
+func recordAudit(entry httpclient.AuditEntry) {
+	err := db.RecordAudit(db.AuditEntry{
+		Timestamp: entry.Timestamp,
+		Client:    entry.Client,
+		Method:    entry.Method,
+		Target:    entry.Target,
+		Endpoint:  entry.Endpoint,
+		Item:      entry.Item,
+		Status:    entry.Status,
+		Outcome:   entry.Outcome,
+	}, viper.GetDuration("audit_log_retention"))
+	if err != nil {
+		log.Error("Failed to record the request in the audit log: ", err)
+	}
+}
+
+// parseAuditTime parses a --since or --until time, a date or an RFC 3339 timestamp, in local time
+func parseAuditTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15:04", value, time.Local); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return t, fmt.Errorf("invalid time %q, expected e.g. 2024-03-05, \"2024-03-05 14:00\" or 2024-03-05T14:00:00Z", value)
+	}
+	return t, nil
+}
+
+// ShowAuditLog lists the outbound requests recorded in the audit log, e.g. those of a day
+func ShowAuditLog(cmd *cobra.Command, args []string) {
+	since, _ := cmd.Flags().GetString("since")
+	until, _ := cmd.Flags().GetString("until")
+	item, _ := cmd.Flags().GetString("item")
+	limit, _ := cmd.Flags().GetInt("limit")
+
+	filter := db.AuditFilter{Item: item, Limit: limit}
+	var err error
+	if filter.Since, err = parseAuditTime(since); err != nil {
+		log.Fatal(err)
+	}
+	if filter.Until, err = parseAuditTime(until); err != nil {
+		log.Fatal(err)
+	}
+
+	OpenDB()
+	defer db.CloseDB()
+
+	entries, err := db.AuditLog(filter)
+	if err != nil {
+		log.Fatal("Failed to read the audit log: ", err)
+	}
+	if len(entries) == 0 {
+		fmt.Println("No requests recorded")
+		return
+	}
+	for _, entry := range entries {
+		fmt.Printf("%s\t%s\t%s %s%s\t%s\t%s\n", entry.Timestamp.Local().Format("2006-01-02 15:04:05"), entry.Client,
+			entry.Method, entry.Target, entry.Endpoint, entry.Outcome, entry.Item)
+	}
+}
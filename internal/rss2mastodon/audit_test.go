@@ -0,0 +1,33 @@
+package rss2mastodon
+
+import (
+	"testing"
+	"time"
+)
+
+// Table-driven test for parsing the times the audit command lists requests between
+func TestParseAuditTime(t *testing.T) {
+	tests := []struct {
+		value         string
+		expected      time.Time
+		expectedError bool
+	}{
+		{value: "", expected: time.Time{}},
+		{value: "2024-03-05", expected: time.Date(2024, 3, 5, 0, 0, 0, 0, time.Local)},
+		{value: "2024-03-05 14:30", expected: time.Date(2024, 3, 5, 14, 30, 0, 0, time.Local)},
+		{value: "2024-03-05T14:30:00Z", expected: time.Date(2024, 3, 5, 14, 30, 0, 0, time.UTC)},
+		{value: "last tuesday", expectedError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseAuditTime(tt.value)
+			if (err != nil) != tt.expectedError {
+				t.Fatalf("Expected error %v, got %v", tt.expectedError, err)
+			}
+			if !tt.expectedError && !got.Equal(tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package rss2mastodon
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+	"github.com/toozej/rss2mastodon/internal/mastodon"
+)
+
+// shouldAutoReply reports whether a mention is a reply to one of the bot's statuses which gets
+// the auto_reply message: each account gets it once per auto_reply_cooldown, and at most
+// auto_reply_max_per_hour accounts get it per hour. Bots are never answered, so two automated
+// accounts can't keep replying to each other.
+func shouldAutoReply(notification mastodon.Notification) bool {
+	if viper.GetString("auto_reply") == "" || notification.Status == nil || notification.Status.InReplyToID == "" || notification.Account.Bot {
+		return false
+	}
+
+	ours, err := db.HasStatus(notification.Status.InReplyToID)
+	if err != nil {
+		log.Error("Failed to look up replied status: ", err)
+		return false
+	}
+	if !ours {
+		return false
+	}
+
+	acct := fullAcct(notification.Account.Acct)
+	replied, ok, err := db.LastAutoReply(acct)
+	if err != nil {
+		log.Error("Failed to look up last auto-reply: ", err)
+		return false
+	}
+	if ok && time.Since(replied) < viper.GetDuration("auto_reply_cooldown") {
+		log.Debugf("Already auto-replied to @%s at %s", acct, replied.Format(time.RFC3339))
+		return false
+	}
+
+	if limit := viper.GetInt("auto_reply_max_per_hour"); limit > 0 {
+		count, err := db.CountAutoRepliesSince(time.Now().Add(-time.Hour))
+		if err != nil {
+			log.Error("Failed to count auto-replies: ", err)
+			return false
+		}
+		if count >= limit {
+			log.Warnf("Not auto-replying to @%s, %d auto-replies were sent in the last hour", acct, count)
+			return false
+		}
+	}
+	return true
+}
+
+// recordAutoReply remembers that an account got the auto_reply message
+func recordAutoReply(notification mastodon.Notification) {
+	if err := db.RecordAutoReply(fullAcct(notification.Account.Acct), notification.Status.InReplyToID); err != nil {
+		log.Error("Failed to record auto-reply: ", err)
+	}
+}
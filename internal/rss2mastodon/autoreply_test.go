@@ -0,0 +1,75 @@
+package rss2mastodon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+	"github.com/toozej/rss2mastodon/internal/mastodon"
+)
+
+// Table-driven test for auto-replying once to replies to the bot's statuses
+func TestAutoReply(t *testing.T) {
+	db.InitDB()
+	defer db.CloseDB()
+	defer os.Remove("./tooted_posts.db")
+	leader.Store(true)
+	defer leader.Store(false)
+
+	var replies []string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		replies = append(replies, strings.Join([]string{r.FormValue("status"), r.FormValue("in_reply_to_id"), r.FormValue("visibility")}, "|"))
+		_, _ = w.Write([]byte(`{"id":"99"}`))
+	}))
+	defer mockServer.Close()
+
+	viper.Reset()
+	viper.Set("mastodon_url", mockServer.URL)
+	viper.Set("mastodon_access_token", "fake-token")
+	viper.Set("auto_reply", "This is an automated mirror, reach the author at @me@example.social")
+	viper.Set("auto_reply_cooldown", "720h")
+	viper.Set("auto_reply_max_per_hour", 2)
+	defer viper.Reset()
+
+	if err := db.RecordStatus("1", "https://bots.example/@bot/1", "https://example.com/post", "new post"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	mention := func(id string, acct string, inReplyTo string, bot bool) mastodon.Notification {
+		return mastodon.Notification{Type: "mention", Account: mastodon.Account{Acct: acct, Bot: bot},
+			Status: &mastodon.Status{ID: id, Content: "<p>@bot Great post!</p>", Visibility: "public", InReplyToID: inReplyTo}}
+	}
+	tests := []struct {
+		name         string
+		notification mastodon.Notification
+		expected     string
+	}{
+		{"Reply to an announcement", mention("10", "fan@example.social", "1", false), "@fan@example.social This is an automated mirror, reach the author at @me@example.social|10|unlisted"},
+		{"Second reply of the same account", mention("11", "fan@example.social", "1", false), ""},
+		{"Mention outside a thread", mention("12", "other@example.social", "", false), ""},
+		{"Reply to another status", mention("13", "other@example.social", "7", false), ""},
+		{"Reply by a bot", mention("14", "echo@example.social", "1", true), ""},
+		{"Reply of another account", mention("15", "critic@example.net", "1", false), "@critic@example.net This is an automated mirror, reach the author at @me@example.social|15|unlisted"},
+		{"Hourly limit reached", mention("16", "late@example.org", "1", false), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replies = nil
+			handleNotification(tt.notification)
+			var expected []string
+			if tt.expected != "" {
+				expected = []string{tt.expected}
+			}
+			if !reflect.DeepEqual(replies, expected) {
+				t.Errorf("Expected the replies %q, got %q", expected, replies)
+			}
+		})
+	}
+}
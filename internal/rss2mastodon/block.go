@@ -0,0 +1,68 @@
+package rss2mastodon
+
+import (
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// Block adds, removes or lists item URLs and GUIDs on the blocklist stored in the database
+func Block(cmd *cobra.Command, args []string) {
+	list, _ := cmd.Flags().GetBool("list")
+	remove, _ := cmd.Flags().GetBool("remove")
+
+	if !list && len(args) == 0 {
+		log.Fatal("At least one item URL or GUID is required")
+	}
+
+	OpenDB()
+	defer db.CloseDB()
+
+	for _, id := range args {
+		if remove {
+			if err := db.UnblockItem(id); err != nil {
+				log.Fatal("Failed to unblock item: ", err)
+			}
+			log.Infof("Unblocked %s", id)
+		} else {
+			if err := db.BlockItem(id); err != nil {
+				log.Fatal("Failed to block item: ", err)
+			}
+			log.Infof("Blocked %s", id)
+		}
+	}
+
+	if list {
+		ids, err := db.ListBlockedItems()
+		if err != nil {
+			log.Fatal("Failed to list blocked items: ", err)
+		}
+		for _, id := range ids {
+			fmt.Println(id)
+		}
+	}
+}
+
+// isBlocked reports whether the post's URL or GUID is on the configured or stored blocklist
+func isBlocked(post rss.RSSItem) bool {
+	for _, id := range strings.Split(viper.GetString("blocklist"), ",") {
+		id = strings.TrimSpace(id)
+		if id != "" && (id == post.Link || id == post.GUID) {
+			return true
+		}
+	}
+
+	blocked, err := db.IsBlocked(post.Link, post.GUID)
+	if err != nil {
+		log.Error("Database error checking blocklist: ", err)
+		// err on the side of not announcing posts which might be blocked
+		return true
+	}
+	return blocked
+}
@@ -0,0 +1,56 @@
+package rss2mastodon
+
+import (
+	"os"
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// Table-driven test for checking posts against the blocklist
+func TestIsBlocked(t *testing.T) {
+	db.InitDB()
+	defer db.CloseDB()
+	defer os.Remove("./tooted_posts.db")
+
+	if err := db.BlockItem("tag:example.com,2024:stored"); err != nil {
+		t.Fatalf("Failed to block item: %v", err)
+	}
+
+	viper.Reset()
+	viper.Set("blocklist", "https://example.com/configured, https://example.com/other")
+	defer viper.Reset()
+
+	tests := []struct {
+		name     string
+		post     rss.RSSItem
+		expected bool
+	}{
+		{
+			name:     "Blocked by configured URL",
+			post:     rss.RSSItem{Link: "https://example.com/configured"},
+			expected: true,
+		},
+		{
+			name:     "Blocked by stored GUID",
+			post:     rss.RSSItem{Link: "https://example.com/post", GUID: "tag:example.com,2024:stored"},
+			expected: true,
+		},
+		{
+			name:     "Not blocked",
+			post:     rss.RSSItem{Link: "https://example.com/allowed", GUID: "tag:example.com,2024:allowed"},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := isBlocked(tt.post); result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
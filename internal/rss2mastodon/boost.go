@@ -0,0 +1,41 @@
+package rss2mastodon
+
+import (
+	log "github.com/sirupsen/logrus"
+
+	"github.com/toozej/rss2mastodon/internal/article"
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// boostAuthorPost looks for the author's own fediverse post of the item, using the article's
+// fediverse:creator and rel="me" metadata, and boosts it. It returns whether a post was boosted,
+// so the item can be announced with a toot instead if not.
+func boostAuthorPost(post rss.RSSItem) bool {
+	page, err := article.Fetch(post.Link)
+	if err != nil {
+		log.Warnf("Failed to fetch %s to find the author's post: %v", post.Link, err)
+		return false
+	}
+
+	client := mastodonClient().ForItem(post.Link)
+	for _, acct := range page.FediverseAccounts() {
+		status, err := client.FindAuthorStatus(acct, post.Link)
+		if err != nil {
+			log.Warn("Failed to search for the author's post: ", err)
+			continue
+		}
+		if status == nil {
+			continue
+		}
+
+		if _, err := client.Boost(status.ID); err != nil {
+			log.Warnf("Failed to boost %s: %v", status.URL, err)
+			return false
+		}
+		log.Infof("Boosted %s's post of %s: %s", acct, post.Link, status.URL)
+		return true
+	}
+
+	log.Debugf("No fediverse post by the author found for %s", post.Link)
+	return false
+}
@@ -0,0 +1,84 @@
+package rss2mastodon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+	"github.com/toozej/rss2mastodon/internal/mastodon"
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// Table-driven test for boosting the author's post instead of tooting a link announcement
+func TestAnnouncePost_Boost(t *testing.T) {
+	tests := []struct {
+		name            string
+		page            string
+		expectedBoosted bool
+	}{
+		{
+			name:            "Author's post found",
+			page:            "/with-creator",
+			expectedBoosted: true,
+		},
+		{
+			name:            "No fediverse metadata",
+			page:            "/without-creator",
+			expectedBoosted: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db.InitDB()
+			defer db.CloseDB()
+			defer os.Remove("./tooted_posts.db")
+
+			var boosted, tooted bool
+			var serverURL string
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/with-creator":
+					_, _ = w.Write([]byte(`<html><head><meta name="fediverse:creator" content="@me@example.social"></head></html>`))
+				case "/without-creator":
+					_, _ = w.Write([]byte(`<html><head></head></html>`))
+				case "/api/v2/search":
+					_, _ = w.Write([]byte(`{"accounts":[{"id":"42","acct":"me@example.social"}]}`))
+				case "/api/v1/accounts/42/statuses":
+					_, _ = w.Write([]byte(`[{"id":"7","content":"<p>I wrote a thing: ` + serverURL + `/with-creator</p>"}]`))
+				case "/api/v1/statuses/7/reblog":
+					boosted = true
+					_, _ = w.Write([]byte(`{"id":"8"}`))
+				case "/api/v1/statuses":
+					tooted = true
+					_, _ = w.Write([]byte(`{"id":"9"}`))
+				}
+			}))
+			defer mockServer.Close()
+			serverURL = mockServer.URL
+
+			viper.Reset()
+			viper.Set("mastodon_url", mockServer.URL)
+			viper.Set("mastodon_access_token", "fake-token")
+			viper.Set("boost_author_posts", true)
+			defer viper.Reset()
+
+			post := rss.RSSItem{Title: "A thing", Link: mockServer.URL + tt.page}
+			if !announcePost(feedLogger(post.FeedURL), post, mastodon.TootOptions{}, newCycleStats()) {
+				t.Fatalf("Expected post to be announced")
+			}
+			if boosted != tt.expectedBoosted || tooted == tt.expectedBoosted {
+				t.Errorf("Expected boosted=%v and tooted=%v, got %v and %v", tt.expectedBoosted, !tt.expectedBoosted, boosted, tooted)
+			}
+
+			exists, _, err := db.HasPostChanged(post.Link, post.Content)
+			if err != nil || !exists {
+				t.Errorf("Expected post to be stored, got %v, %v", exists, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package rss2mastodon
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// tootsReserved counts the statuses being sent right now, which count against the daily toot
+// budget before they are recorded
+var (
+	tootsReservedMu sync.Mutex
+	tootsReserved   int
+)
+
+// startOfDay returns midnight of t's day, in its time zone
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// nextBudgetDay returns when the daily toot budget is renewed after t: midnight of the next day
+func nextBudgetDay(t time.Time) time.Time {
+	return startOfDay(t).AddDate(0, 0, 1)
+}
+
+// reserveToot reserves a toot of today's daily_toot_budget for a status, returning false if
+// the statuses tooted today and those being sent already use it up. Reserved toots have to be
+// released with releaseToot once they were sent, or failed to be.
+func reserveToot() bool {
+	budget := viper.GetInt("daily_toot_budget")
+	if budget <= 0 {
+		return true
+	}
+
+	tootsReservedMu.Lock()
+	defer tootsReservedMu.Unlock()
+	tooted, err := db.CountStatusesSince(startOfDay(time.Now()))
+	if err != nil {
+		// toot rather than hold posts indefinitely because of the database
+		log.Error("Failed to count today's toots: ", err)
+	}
+	if tooted+tootsReserved >= budget {
+		return false
+	}
+	tootsReserved++
+	return true
+}
+
+// releaseToot releases a toot reserved by reserveToot, once it is recorded in the database
+func releaseToot() {
+	if viper.GetInt("daily_toot_budget") <= 0 {
+		return
+	}
+	tootsReservedMu.Lock()
+	defer tootsReservedMu.Unlock()
+	if tootsReserved > 0 {
+		tootsReserved--
+	}
+}
+
+// reserveTootFor reserves a toot of the daily toot budget for a status other than a new post's
+// announcement, e.g. an update toot or a digest, or else logs that it waits for the budget to be
+// renewed. Such statuses aren't queued, they are tried again in the next cycles.
+func reserveTootFor(logger *log.Entry, what string) bool {
+	if reserveToot() {
+		return true
+	}
+	logger.Infof("Holding %s until %s, the daily toot budget of %d is used up", what, nextBudgetDay(time.Now()).Format(time.RFC1123), viper.GetInt("daily_toot_budget"))
+	return false
+}
+
+// holdForBudget reserves a toot of the daily toot budget for a new post, or else queues it until
+// the next day, behind the posts already waiting for it, returning whether it was held
+func holdForBudget(logger *log.Entry, post rss.RSSItem) bool {
+	if reserveToot() {
+		return false
+	}
+	next := nextBudgetDay(time.Now())
+	if err := db.QueuePendingPost(post, next, db.PendingBudget); err != nil {
+		logger.Error("Failed to queue post beyond the daily toot budget: ", err)
+		return true
+	}
+	logger.Infof("Holding post until %s, the daily toot budget of %d is used up: %s", next.Format(time.RFC1123), viper.GetInt("daily_toot_budget"), post.Title)
+	return true
+}
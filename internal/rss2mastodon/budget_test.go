@@ -0,0 +1,127 @@
+package rss2mastodon
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// Test new posts beyond the daily toot budget are held until the next day, in order
+func TestDailyTootBudget(t *testing.T) {
+	db.InitDB()
+	defer db.CloseDB()
+	defer os.Remove("./tooted_posts.db")
+
+	var statuses []string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		statuses = append(statuses, r.FormValue("status"))
+		_, _ = fmt.Fprintf(w, `{"id":"%d"}`, len(statuses))
+	}))
+	defer mockServer.Close()
+
+	viper.Reset()
+	viper.Set("mastodon_url", mockServer.URL)
+	viper.Set("mastodon_access_token", "fake-token")
+	viper.Set("daily_toot_budget", 2)
+	defer viper.Reset()
+
+	var posts []rss.RSSItem
+	for i := 1; i <= 4; i++ {
+		post := rss.RSSItem{Title: fmt.Sprintf("Post %d", i), Link: fmt.Sprintf("https://example.com/%d", i)}
+		posts = append(posts, post)
+		if !handlePost(feedLogger(post.FeedURL), post, newCycleStats()) {
+			t.Errorf("Expected %s to be reported as new", post.Title)
+		}
+	}
+	if len(statuses) != 2 {
+		t.Fatalf("Expected 2 toots within the budget, got %d", len(statuses))
+	}
+
+	tomorrow := nextBudgetDay(time.Now())
+	for _, post := range posts[2:] {
+		pending, err := db.GetPendingPost(post.Link)
+		if err != nil || pending == nil {
+			t.Fatalf("Expected %s to be held, got %v, %v", post.Title, pending, err)
+		}
+		if pending.Reason != db.PendingBudget || !pending.NotBefore.Equal(tomorrow) {
+			t.Errorf("Expected %s to be held for the budget until %v, got %s until %v", post.Title, tomorrow, pending.Reason, pending.NotBefore)
+		}
+	}
+
+	// pretend the next day has come, with room for one more toot
+	for _, post := range posts[2:] {
+		if err := db.DeferPendingPost(post.Link, time.Now().Add(-time.Minute)); err != nil {
+			t.Fatalf("Failed to defer post: %v", err)
+		}
+	}
+	viper.Set("daily_toot_budget", 3)
+	processPendingPosts(newCycleStats())
+
+	if len(statuses) != 3 || !strings.Contains(statuses[2], posts[2].Link) {
+		t.Fatalf("Expected the first held post to be tooted next, got %v", statuses)
+	}
+	pending, err := db.GetPendingPost(posts[3].Link)
+	if err != nil || pending == nil {
+		t.Fatalf("Expected %s to remain held, got %v, %v", posts[3].Title, pending, err)
+	}
+	if !pending.NotBefore.Equal(tomorrow) {
+		t.Errorf("Expected %s to be held until %v, got %v", posts[3].Title, tomorrow, pending.NotBefore)
+	}
+	if tootsReserved != 0 {
+		t.Errorf("Expected no toots to remain reserved, got %d", tootsReserved)
+	}
+}
+
+// Test update toots beyond the daily toot budget are held until the budget allows them
+func TestDailyTootBudget_Updates(t *testing.T) {
+	db.InitDB()
+	defer db.CloseDB()
+	defer os.Remove("./tooted_posts.db")
+
+	var statuses []string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		statuses = append(statuses, r.FormValue("status"))
+		_, _ = fmt.Fprintf(w, `{"id":"%d"}`, len(statuses))
+	}))
+	defer mockServer.Close()
+
+	viper.Reset()
+	viper.Set("mastodon_url", mockServer.URL)
+	viper.Set("mastodon_access_token", "fake-token")
+	viper.Set("daily_toot_budget", 1)
+	defer viper.Reset()
+
+	post := rss.RSSItem{Title: "Post", Link: "https://example.com/post", Content: "First version"}
+	handlePost(feedLogger(post.FeedURL), post, newCycleStats())
+	if len(statuses) != 1 {
+		t.Fatalf("Expected the new post to be tooted, got %v", statuses)
+	}
+
+	post.Content = "Second version"
+	handlePost(feedLogger(post.FeedURL), post, newCycleStats())
+	if len(statuses) != 1 {
+		t.Fatalf("Expected the update toot to be held, got %v", statuses)
+	}
+	if _, updated, err := db.HasPostChanged(post.Link, post.Content); err != nil || !updated {
+		t.Errorf("Expected the held update to be announced later, got %v, %v", updated, err)
+	}
+
+	// the update is tooted once the budget allows it
+	viper.Set("daily_toot_budget", 2)
+	handlePost(feedLogger(post.FeedURL), post, newCycleStats())
+	if len(statuses) != 2 {
+		t.Fatalf("Expected the update to be tooted, got %v", statuses)
+	}
+	if tootsReserved != 0 {
+		t.Errorf("Expected no toots to remain reserved, got %d", tootsReserved)
+	}
+}
@@ -0,0 +1,130 @@
+package rss2mastodon
+
+import (
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+	"github.com/toozej/rss2mastodon/internal/mastodon"
+	"github.com/toozej/rss2mastodon/internal/notify"
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// styleBurstDigest is the announcement style of digests tooted instead of a burst of new items
+const styleBurstDigest = "burst digest"
+
+// feedBurst holds the new items of a feed's fetch, more than burst_threshold of them, which
+// are tooted as a single digest instead of one status each
+type feedBurst struct {
+	// name of the feed, its channel title
+	name  string
+	posts []rss.RSSItem
+}
+
+// isNewItem reports whether an item would be announced as a new post: it isn't blocked,
+// dead-lettered, held in the pending queue or tooted already
+func isNewItem(post rss.RSSItem) bool {
+	if isBlocked(post) {
+		return false
+	}
+	if deadLettered, err := db.IsDeadLettered(post.Link); err != nil || deadLettered {
+		return false
+	}
+	if pending, err := db.GetPendingPost(post.Link); err != nil || pending != nil {
+		return false
+	}
+	exists, _, err := db.HasPostChanged(post.Link, post.Content)
+	return err == nil && !exists
+}
+
+// detectBurst returns the new items among a feed's items if there are more than
+// burst_threshold of them, e.g. after the feed was rebuilt or posts were imported in bulk,
+// and nil otherwise
+func detectBurst(posts []rss.RSSItem) []rss.RSSItem {
+	threshold := viper.GetInt("burst_threshold")
+	if threshold <= 0 || len(posts) <= threshold {
+		return nil
+	}
+
+	var fresh []rss.RSSItem
+	for _, post := range posts {
+		if isNewItem(post) {
+			fresh = append(fresh, post)
+		}
+	}
+	if len(fresh) <= threshold {
+		return nil
+	}
+	return fresh
+}
+
+// burstDigest returns the toot announcing a burst of new posts, linking as many of them as fit
+// within max_characters and counting the others
+func burstDigest(burst *feedBurst) string {
+	content := fmt.Sprintf("%d new posts on %s:", len(burst.posts), burst.name)
+	for i, post := range burst.posts {
+		line := "\n" + withLinkParams(post).Link
+		var rest string
+		if remaining := len(burst.posts) - i - 1; remaining > 0 {
+			rest = fmt.Sprintf("\nand %d more", remaining)
+		}
+		if mastodon.StatusLength(content+line+rest) > maxCharacters() {
+			return content + fmt.Sprintf("\nand %d more", len(burst.posts)-i)
+		}
+		content += line
+	}
+	return content
+}
+
+// handleBurst toots a digest of a burst of new items of the feed instead of announcing each
+// of them, stores them as tooted and notifies the operator, returning whether it was tooted.
+// If tooting fails or the daily toot budget is used up, the items are left as they are, to be
+// detected as a burst again next cycle.
+func handleBurst(logger *log.Entry, feedURL string, burst *feedBurst, stats *cycleStats) bool {
+	logger.Warnf("Feed has %d new items, more than the burst threshold of %d, tooting a digest instead", len(burst.posts), viper.GetInt("burst_threshold"))
+
+	item := rss.RSSItem{FeedURL: feedURL}
+	content := withFooter(item, burstDigest(burst))
+	var links []string
+	for _, post := range burst.posts {
+		links = append(links, post.Link)
+	}
+	opts := mastodon.TootOptions{
+		Visibility:     feedConfig(feedURL).Visibility,
+		SpoilerText:    contentWarning(item),
+		Language:       tootLanguage(feedURL),
+		LocalOnly:      tootLocalOnly(feedURL),
+		IdempotencyKey: idempotencyKey(styleBurstDigest, rss.RSSItem{Link: feedURL, Content: strings.Join(links, "\n")}),
+	}
+	if !reserveTootFor(logger, "the digest of a burst of new posts") {
+		return false
+	}
+	defer releaseToot()
+	status, err := mastodonClient().ForItem(feedURL).PostStatus(content, opts)
+	if err != nil {
+		logger.Error("Failed to toot the digest of a burst of new posts: ", err)
+		recordError(feedURL, err)
+		notify.Failure(mastodonKey, err)
+		stats.failed.Add(1)
+		return false
+	}
+	notify.Success(mastodonKey)
+	stats.tooted.Add(1)
+	recordStatus(status, feedURL, styleBurstDigest)
+
+	for _, post := range burst.posts {
+		if err := db.StoreFeedPost(post.FeedURL, post.Link, post.Content); err != nil {
+			logger.Error("Storing post of a burst digest in database failed: ", err)
+		}
+	}
+
+	message := fmt.Sprintf("%s had %d new items in one cycle, more than the burst threshold of %d, e.g. after the feed was rebuilt. They were tooted as a single digest instead of one status each.",
+		feedURL, len(burst.posts), viper.GetInt("burst_threshold"))
+	if err := notify.Send("rss2mastodon: burst of new posts", message); err != nil {
+		logger.Error("Failed to send burst notification: ", err)
+	}
+	return true
+}
@@ -0,0 +1,117 @@
+package rss2mastodon
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// Table-driven test for the digest of a burst, linking as many posts as fit
+func TestBurstDigest(t *testing.T) {
+	posts := func(n int) []rss.RSSItem {
+		var posts []rss.RSSItem
+		for i := 1; i <= n; i++ {
+			posts = append(posts, rss.RSSItem{Link: fmt.Sprintf("https://example.com/%d", i)})
+		}
+		return posts
+	}
+
+	tests := []struct {
+		name          string
+		maxCharacters int
+		posts         []rss.RSSItem
+		expected      string
+	}{
+		{"All fit", 0, posts(2), "2 new posts on Blog:\nhttps://example.com/1\nhttps://example.com/2"},
+		// every link counts as 23 characters, a line 24
+		{"Some fit", 90, posts(5), "5 new posts on Blog:\nhttps://example.com/1\nhttps://example.com/2\nand 3 more"},
+		{"Last one fits instead of counting it", 92, posts(3), "3 new posts on Blog:\nhttps://example.com/1\nhttps://example.com/2\nhttps://example.com/3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Set("max_characters", tt.maxCharacters)
+			defer viper.Reset()
+
+			if got := burstDigest(&feedBurst{name: "Blog", posts: tt.posts}); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+// Table-driven test for tooting a digest instead of every item when a feed has a burst of new items
+func TestPollFeeds_Burst(t *testing.T) {
+	tests := []struct {
+		name           string
+		threshold      int
+		expectedToots  []string
+		expectedNotify bool
+	}{
+		{"Disabled", 0, []string{"New blog post: https://example.com/2", "New blog post: https://example.com/3", "New blog post: https://example.com/4"}, false},
+		{"Below threshold", 3, []string{"New blog post: https://example.com/2", "New blog post: https://example.com/3", "New blog post: https://example.com/4"}, false},
+		{"Burst", 2, []string{"3 new posts on Blog:\nhttps://example.com/2\nhttps://example.com/3\nhttps://example.com/4"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db.InitDB()
+			defer db.CloseDB()
+			defer os.Remove("./tooted_posts.db")
+			if err := db.StoreTootedPost("https://example.com/1", ""); err != nil {
+				t.Fatalf("Failed to store post: %v", err)
+			}
+
+			var toots []string
+			notified := false
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/feed.xml":
+					_, _ = w.Write([]byte(`<rss><channel><title>Blog</title>
+						<item><title>1</title><link>https://example.com/1</link></item>
+						<item><title>2</title><link>https://example.com/2</link></item>
+						<item><title>3</title><link>https://example.com/3</link></item>
+						<item><title>4</title><link>https://example.com/4</link></item>
+					</channel></rss>`))
+				case "/api/v1/statuses":
+					toots = append(toots, r.FormValue("status"))
+					_, _ = w.Write([]byte(`{"id":"1"}`))
+				case "/ntfy":
+					notified = true
+				}
+			}))
+			defer mockServer.Close()
+
+			viper.Reset()
+			viper.Set("mastodon_url", mockServer.URL)
+			viper.Set("mastodon_access_token", "fake-token")
+			viper.Set("ntfy_url", mockServer.URL+"/ntfy")
+			viper.Set("burst_threshold", tt.threshold)
+			defer viper.Reset()
+
+			feeds := []FeedConfig{{URL: mockServer.URL + "/feed.xml"}}
+			pollFeeds(feeds, newCycleStats())
+			if strings.Join(toots, "|") != strings.Join(tt.expectedToots, "|") {
+				t.Errorf("Expected toots %q, got %q", tt.expectedToots, toots)
+			}
+			if notified != tt.expectedNotify {
+				t.Errorf("Expected notification: %v, got %v", tt.expectedNotify, notified)
+			}
+
+			// the burst's items are stored as tooted
+			toots = nil
+			pollFeeds(feeds, newCycleStats())
+			if len(toots) != 0 {
+				t.Errorf("Expected no further toots, got %q", toots)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package rss2mastodon
+
+import (
+	"sync/atomic"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// defaultMaxCharacters is the character limit of toots on Mastodon instances by default
+const defaultMaxCharacters = 500
+
+// instanceMaxCharacters is the character limit the instance reported at startup, 0 if unknown
+var instanceMaxCharacters atomic.Int64
+
+// maxCharacters returns how many characters toots may have: max_characters if set, or else
+// the limit the instance reported, falling back to Mastodon's default
+func maxCharacters() int {
+	if limit := viper.GetInt("max_characters"); limit > 0 {
+		return limit
+	}
+	if limit := instanceMaxCharacters.Load(); limit > 0 {
+		return int(limit)
+	}
+	return defaultMaxCharacters
+}
+
+// detectCharacterLimit asks the instance for its character limit of toots, which instances
+// raise from Mastodon's 500 up to several thousands, unless max_characters is set
+func detectCharacterLimit() {
+	if viper.GetInt("max_characters") > 0 {
+		return
+	}
+	instance, err := mastodonClient().GetInstance()
+	if err != nil {
+		log.Warnf("Failed to look up the instance's character limit, assuming %d: %v", defaultMaxCharacters, err)
+		return
+	}
+	if limit := instance.MaxCharacters(); limit > 0 {
+		instanceMaxCharacters.Store(int64(limit))
+		log.Infof("The instance allows toots of up to %d characters", limit)
+		return
+	}
+	log.Infof("The instance doesn't report its character limit, assuming %d", defaultMaxCharacters)
+}
@@ -0,0 +1,48 @@
+package rss2mastodon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// Table-driven test for detecting the instance's character limit at startup
+func TestDetectCharacterLimit(t *testing.T) {
+	tests := []struct {
+		name          string
+		maxCharacters int
+		status        int
+		instance      string
+		expected      int
+	}{
+		{"Mastodon", 0, http.StatusOK, `{"configuration":{"statuses":{"max_characters":5000}}}`, 5000},
+		{"Pleroma", 0, http.StatusOK, `{"max_toot_chars":20000}`, 20000},
+		{"Not reported", 0, http.StatusOK, `{}`, defaultMaxCharacters},
+		{"Instance unavailable", 0, http.StatusInternalServerError, ``, defaultMaxCharacters},
+		{"Configured", 1000, http.StatusOK, `{"configuration":{"statuses":{"max_characters":5000}}}`, 1000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.instance))
+			}))
+			defer mockServer.Close()
+
+			viper.Reset()
+			viper.Set("mastodon_url", mockServer.URL)
+			viper.Set("max_characters", tt.maxCharacters)
+			defer viper.Reset()
+			instanceMaxCharacters.Store(0)
+			defer instanceMaxCharacters.Store(0)
+
+			detectCharacterLimit()
+			if got := maxCharacters(); got != tt.expected {
+				t.Errorf("Expected %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
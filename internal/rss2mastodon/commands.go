@@ -0,0 +1,324 @@
+package rss2mastodon
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+	"github.com/toozej/rss2mastodon/internal/mastodon"
+)
+
+// Delays before reconnecting to the streaming API, doubling after every failed attempt
+const (
+	streamReconnectMin = 5 * time.Second
+	streamReconnectMax = 5 * time.Minute
+)
+
+// command is a command the bot accepts in mentions
+type command struct {
+	name  string
+	usage string
+	// admin commands are only accepted from admin accounts, and answered privately
+	admin bool
+	run   func(account mastodon.Account, args []string) string
+}
+
+// commands are the commands accepted in mentions, in the order help lists them
+var commands []command
+
+func init() {
+	commands = []command{
+		{name: "help", usage: "help", run: commandHelp},
+		{name: "latest", usage: "latest", run: commandLatest},
+		{name: "status", usage: "status", admin: true, run: commandStatus},
+		{name: "poll", usage: "poll now|<feed>", admin: true, run: commandPoll},
+		{name: "pause", usage: "pause <feed>", admin: true, run: commandPause},
+		{name: "resume", usage: "resume <feed>", admin: true, run: commandResume},
+	}
+}
+
+// startStreaming listens to the bot account's notifications on the streaming API if streaming
+// is enabled, admin accounts are set or auto_reply is, answering commands in mentions and direct
+// messages and reconnecting whenever the connection drops
+func startStreaming() {
+	if !viper.GetBool("streaming") && len(listSetting("admin_account_ids")) == 0 && viper.GetString("auto_reply") == "" {
+		return
+	}
+	go func() {
+		backoff := streamReconnectMin
+		for {
+			connected := time.Now()
+			err := mastodonClient().StreamNotifications(context.Background(), handleNotification)
+			if time.Since(connected) > streamReconnectMax {
+				backoff = streamReconnectMin
+			}
+			log.Warnf("Streaming API connection lost, reconnecting in %s: %v", backoff, err)
+			time.Sleep(backoff)
+			backoff = min(2*backoff, streamReconnectMax)
+		}
+	}()
+	log.Info("Listening for commands in mentions on the streaming API")
+}
+
+// handleNotification answers the command in a mention of the bot, or replies to the bot's
+// statuses with the auto_reply message. Only the leader answers, so replicas don't all reply.
+func handleNotification(notification mastodon.Notification) {
+	if notification.Type != "mention" || notification.Status == nil || !isLeader() {
+		return
+	}
+	reply, private := runCommand(notification.Account, mastodon.PlainText(notification.Status.Content))
+	autoReply := reply == "" && shouldAutoReply(notification)
+	if autoReply {
+		reply = viper.GetString("auto_reply")
+	}
+	if reply == "" {
+		return
+	}
+
+	// replies to public mentions stay off the public timelines
+	visibility := notification.Status.Visibility
+	if private {
+		visibility = "direct"
+	} else if visibility == "" || visibility == "public" {
+		visibility = "unlisted"
+	}
+	opts := mastodon.TootOptions{InReplyToID: notification.Status.ID, Visibility: visibility}
+	if _, err := mastodonClient().PostStatus("@"+notification.Account.Acct+" "+reply, opts); err != nil {
+		log.Error("Failed to reply to mention: ", err)
+		return
+	}
+	if autoReply {
+		recordAutoReply(notification)
+	}
+}
+
+// runCommand runs the command in the text of a mention, returning the reply or "" to not reply,
+// and whether the reply must be a direct message. Unknown commands and admin commands of other
+// accounts are ignored, as the bot may be mentioned in conversations which aren't meant for it.
+func runCommand(account mastodon.Account, text string) (string, bool) {
+	var words []string
+	for _, word := range strings.Fields(text) {
+		// the bot's handle and any other mentions aren't part of the command
+		if !strings.HasPrefix(word, "@") {
+			words = append(words, word)
+		}
+	}
+	if len(words) == 0 {
+		return "", false
+	}
+
+	name := strings.ToLower(words[0])
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+		if cmd.admin && !isCommandAccount(account) {
+			log.Warnf("Ignoring %s command of @%s, which isn't an admin account", name, account.Acct)
+			return "", false
+		}
+		log.Infof("Running %s command of @%s", name, account.Acct)
+		return cmd.run(account, words[1:]), cmd.admin
+	}
+	if isCommandAccount(account) {
+		return fmt.Sprintf("Unknown command %q, try help", name), false
+	}
+	return "", false
+}
+
+// isCommandAccount reports whether an account may run admin commands: its ID is one of
+// admin_account_ids, or it is listed in command_accounts. Accounts of the bot's own instance
+// may be listed with or without their domain.
+func isCommandAccount(account mastodon.Account) bool {
+	if account.ID != "" {
+		for _, id := range listSetting("admin_account_ids") {
+			if id == account.ID {
+				return true
+			}
+		}
+	}
+	acct := fullAcct(account.Acct)
+	for _, allowed := range listSetting("command_accounts") {
+		if fullAcct(allowed) == acct {
+			return true
+		}
+	}
+	return false
+}
+
+// fullAcct returns an account's user@domain address, adding the bot's own domain to local accounts
+func fullAcct(acct string) string {
+	acct = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(acct), "@"))
+	if strings.Contains(acct, "@") {
+		return acct
+	}
+	if instance, err := url.Parse(viper.GetString("mastodon_url")); err == nil && instance.Hostname() != "" {
+		return acct + "@" + strings.ToLower(instance.Hostname())
+	}
+	return acct
+}
+
+// commandHelp lists the commands the account may run
+func commandHelp(account mastodon.Account, args []string) string {
+	var usages []string
+	for _, cmd := range commands {
+		if !cmd.admin || isCommandAccount(account) {
+			usages = append(usages, cmd.usage)
+		}
+	}
+	return "Commands: " + strings.Join(usages, ", ")
+}
+
+// commandLatest replies with the latest announced post
+func commandLatest(account mastodon.Account, args []string) string {
+	posts, err := db.RecentTootedPosts(1)
+	if err != nil {
+		log.Error("Failed to look up the latest post: ", err)
+		return "Sorry, the latest post couldn't be looked up"
+	}
+	if len(posts) == 0 {
+		return "Nothing has been posted yet"
+	}
+	return "Latest post: " + posts[0].Link
+}
+
+// commandStatus replies with the number of feeds, the posts waiting and the latest cycle's outcome
+func commandStatus(account mastodon.Account, args []string) string {
+	feeds, err := configuredFeeds()
+	if err != nil {
+		return "Invalid feed configuration: " + err.Error()
+	}
+	paused, err := db.PausedFeeds()
+	if err != nil {
+		log.Error("Failed to look up paused feeds: ", err)
+	}
+	pending, err := db.ListPendingPosts()
+	if err != nil {
+		log.Error("Failed to look up pending posts: ", err)
+	}
+	deadLetters, err := db.ListDeadLetters()
+	if err != nil {
+		log.Error("Failed to look up dead letters: ", err)
+	}
+
+	status := fmt.Sprintf("%d feeds", len(feeds))
+	if len(paused) > 0 {
+		urls := make([]string, 0, len(paused))
+		for url := range paused {
+			urls = append(urls, url)
+		}
+		sort.Strings(urls)
+		status += fmt.Sprintf(" (%d paused: %s)", len(paused), strings.Join(urls, ", "))
+	}
+	status += fmt.Sprintf(", %d pending posts, %d dead letters", len(pending), len(deadLetters))
+	if summary := lastCycle.Load(); summary != nil {
+		status += fmt.Sprintf(". Last cycle %s ago: %s", time.Since(summary.finished).Round(time.Second), summary.text)
+	} else {
+		status += ". No cycle has completed yet"
+	}
+	return status
+}
+
+// commandPoll polls all feeds, or the given one, right away
+func commandPoll(account mastodon.Account, args []string) string {
+	query := strings.Join(args, " ")
+	if query == "" || strings.EqualFold(query, "now") {
+		requestPoll("")
+		return "Polling all feeds now"
+	}
+	feed, err := findFeed(query)
+	if err != nil {
+		return err.Error()
+	}
+	requestPoll(feed.URL)
+	return "Polling " + feed.URL + " now"
+}
+
+// commandPause stops polling a feed until it is resumed
+func commandPause(account mastodon.Account, args []string) string {
+	feed, err := findFeed(strings.Join(args, " "))
+	if err != nil {
+		return err.Error()
+	}
+	if err := db.PauseFeed(feed.URL, "@"+account.Acct); err != nil {
+		log.Error("Failed to pause feed: ", err)
+		return "Sorry, " + feed.URL + " couldn't be paused"
+	}
+	return "Paused " + feed.URL
+}
+
+// commandResume polls a paused feed again
+func commandResume(account mastodon.Account, args []string) string {
+	feed, err := findFeed(strings.Join(args, " "))
+	if err != nil {
+		return err.Error()
+	}
+	resumed, err := db.ResumeFeed(feed.URL)
+	if err != nil {
+		log.Error("Failed to resume feed: ", err)
+		return "Sorry, " + feed.URL + " couldn't be resumed"
+	}
+	if !resumed {
+		return feed.URL + " isn't paused"
+	}
+	requestPoll(feed.URL)
+	return "Resumed " + feed.URL
+}
+
+// findFeed returns the configured feed with the given URL, or the only one whose URL contains
+// the given text, e.g. its domain
+func findFeed(query string) (FeedConfig, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return FeedConfig{}, fmt.Errorf("which feed? Give its URL or part of it")
+	}
+	feeds, err := configuredFeeds()
+	if err != nil {
+		return FeedConfig{}, fmt.Errorf("invalid feed configuration: %w", err)
+	}
+
+	var matches []FeedConfig
+	for _, feed := range feeds {
+		if feed.URL == query {
+			return feed, nil
+		}
+		if strings.Contains(strings.ToLower(feed.URL), strings.ToLower(query)) {
+			matches = append(matches, feed)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return FeedConfig{}, fmt.Errorf("no feed matches %q", query)
+	case 1:
+		return matches[0], nil
+	}
+	var urls []string
+	for _, feed := range matches {
+		urls = append(urls, feed.URL)
+	}
+	return FeedConfig{}, fmt.Errorf("%q matches several feeds: %s", query, strings.Join(urls, ", "))
+}
+
+// unpausedFeeds returns the feeds which aren't paused
+func unpausedFeeds(feeds []FeedConfig) []FeedConfig {
+	paused, err := db.PausedFeeds()
+	if err != nil {
+		log.Error("Failed to look up paused feeds: ", err)
+		return feeds
+	}
+	var unpaused []FeedConfig
+	for _, feed := range feeds {
+		if paused[feed.URL] {
+			log.Debugf("Skipping paused feed %s", feed.URL)
+			continue
+		}
+		unpaused = append(unpaused, feed)
+	}
+	return unpaused
+}
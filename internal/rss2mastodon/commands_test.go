@@ -0,0 +1,130 @@
+package rss2mastodon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+	"github.com/toozej/rss2mastodon/internal/mastodon"
+)
+
+// Table-driven test for running commands from mentions
+func TestRunCommand(t *testing.T) {
+	db.InitDB()
+	defer db.CloseDB()
+	defer os.Remove("./tooted_posts.db")
+
+	viper.Reset()
+	viper.Set("mastodon_url", "https://bots.example")
+	viper.Set("command_accounts", "@admin, ops@example.social")
+	viper.Set("admin_account_ids", "109")
+	viper.Set("feeds", "https://example.com/rss,https://example.org/feed.xml,https://blog.example.org/feed.xml")
+	defer viper.Reset()
+
+	if err := db.StoreTootedPost("https://example.com/newest", "content"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	admin := mastodon.Account{Acct: "admin"}
+	ops := mastodon.Account{Acct: "OPS@example.social"}
+	stranger := mastodon.Account{Acct: "stranger@example.net"}
+	// the admin account ID authorizes whatever the account is called
+	owner := mastodon.Account{ID: "109", Acct: "renamed@example.social"}
+	tests := []struct {
+		name     string
+		account  mastodon.Account
+		text     string
+		expected string
+		private  bool
+	}{
+		{"Latest for anyone", stranger, "@bot latest", "Latest post: https://example.com/newest", false},
+		{"Help for anyone", stranger, "@bot help", "Commands: help, latest", false},
+		{"Help for admins", admin, "@bot Help", "Commands: help, latest, status, poll now|<feed>, pause <feed>, resume <feed>", false},
+		{"Pause by a stranger", stranger, "@bot pause example.com", "", false},
+		{"Pause by a local admin", admin, "@bot pause example.com", "Paused https://example.com/rss", true},
+		{"Pause by a remote admin", ops, "@bot pause https://example.org/feed.xml", "Paused https://example.org/feed.xml", true},
+		{"Ambiguous feed", admin, "@bot pause example.org", `"example.org" matches several feeds: https://example.org/feed.xml, https://blog.example.org/feed.xml`, true},
+		{"Unknown feed", admin, "@bot pause gopher", `no feed matches "gopher"`, true},
+		{"Missing feed", admin, "@bot pause", "which feed? Give its URL or part of it", true},
+		{"Resume", admin, "@bot resume example.com", "Resumed https://example.com/rss", true},
+		{"Resume unpaused", admin, "@bot resume example.com", "https://example.com/rss isn't paused", true},
+		{"Status by a stranger", stranger, "@bot status", "", false},
+		{"Status by an admin ID", owner, "@bot status", "3 feeds (1 paused: https://example.org/feed.xml), 0 pending posts, 0 dead letters. No cycle has completed yet", true},
+		{"Poll now", owner, "@bot poll now", "Polling all feeds now", true},
+		{"Poll a feed", owner, "@bot poll blog", "Polling https://blog.example.org/feed.xml now", true},
+		{"Unknown command by an admin", admin, "@bot dance", `Unknown command "dance", try help`, false},
+		{"Unknown command by a stranger", stranger, "@bot nice blog @author", "", false},
+		{"Only mentions", stranger, "@bot @author", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, private := runCommand(tt.account, tt.text)
+			if got != tt.expected || private != tt.private {
+				t.Errorf("Expected %q (private %v), got %q (private %v)", tt.expected, tt.private, got, private)
+			}
+		})
+	}
+
+	// resumed and polled feeds are polled right away
+	requested := takePollRequests()
+	for _, feedURL := range []string{"", "https://example.com/rss", "https://blog.example.org/feed.xml"} {
+		if !requested[feedURL] {
+			t.Errorf("Expected %q to be polled, got %v", feedURL, requested)
+		}
+	}
+	paused, err := db.PausedFeeds()
+	if err != nil || len(paused) != 1 || !paused["https://example.org/feed.xml"] {
+		t.Errorf("Expected only https://example.org/feed.xml to stay paused, got %v (%v)", paused, err)
+	}
+	feeds, _ := configuredFeeds()
+	if unpaused := unpausedFeeds(feeds); len(unpaused) != 2 {
+		t.Errorf("Expected the paused feed to be skipped, got %v", unpaused)
+	}
+}
+
+// Test mentions are answered in their thread, keeping replies to public mentions off the timelines
+// and answering admin commands privately
+func TestHandleNotification(t *testing.T) {
+	db.InitDB()
+	defer db.CloseDB()
+	defer os.Remove("./tooted_posts.db")
+	leader.Store(true)
+	defer leader.Store(false)
+
+	var replies []string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		replies = append(replies, strings.Join([]string{r.FormValue("status"), r.FormValue("in_reply_to_id"), r.FormValue("visibility")}, "|"))
+		_, _ = w.Write([]byte(`{"id":"2"}`))
+	}))
+	defer mockServer.Close()
+
+	viper.Reset()
+	viper.Set("mastodon_url", mockServer.URL)
+	viper.Set("mastodon_access_token", "fake-token")
+	viper.Set("admin_account_ids", "109")
+	defer viper.Reset()
+	defer takePollRequests()
+
+	account := mastodon.Account{ID: "7", Acct: "fan@example.social"}
+	handleNotification(mastodon.Notification{Type: "mention", Account: account,
+		Status: &mastodon.Status{ID: "42", Content: "<p>@bot latest</p>", Visibility: "public"}})
+	handleNotification(mastodon.Notification{Type: "favourite", Account: account,
+		Status: &mastodon.Status{ID: "43", Content: "<p>latest</p>"}})
+	handleNotification(mastodon.Notification{Type: "mention", Account: mastodon.Account{ID: "109", Acct: "owner"},
+		Status: &mastodon.Status{ID: "44", Content: "<p>@bot poll now</p>", Visibility: "public"}})
+
+	expected := []string{
+		"@fan@example.social Nothing has been posted yet|42|unlisted",
+		"@owner Polling all feeds now|44|direct",
+	}
+	if !reflect.DeepEqual(replies, expected) {
+		t.Errorf("Expected the replies %q, got %q", expected, replies)
+	}
+}
@@ -3,24 +3,94 @@ package rss2mastodon
 import (
 	"fmt"
 	"os"
+	"strings"
 
+	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+	"github.com/toozej/rss2mastodon/internal/httpclient"
+	"github.com/toozej/rss2mastodon/internal/mastodon"
+	"github.com/toozej/rss2mastodon/internal/retry"
 )
 
-// Get environment variables
-func getEnvVars() error {
-	if _, err := os.Stat(".env"); err == nil {
-		// Initialize Viper from .env file
-		viper.SetConfigFile(".env") // Specify the name of your .env file
+// envPrefix optionally namespaces environment variables for environments shared with other
+// apps, e.g. RSS2MASTODON_INTERVAL takes precedence over INTERVAL
+const envPrefix = "RSS2MASTODON_"
 
-		// Read the .env file
-		if err := viper.ReadInConfig(); err != nil {
+// bindPrefixedEnv makes viper read prefixed environment variables, falling back to the
+// unprefixed ones if they are not set or empty
+func bindPrefixedEnv() error {
+	viper.SetEnvPrefix(strings.TrimSuffix(envPrefix, "_"))
+	for _, variable := range os.Environ() {
+		name, _, _ := strings.Cut(variable, "=")
+		if name == "" || strings.HasPrefix(name, envPrefix) {
+			continue
+		}
+		if err := viper.BindEnv(strings.ToLower(name), name); err != nil {
 			return err
 		}
 	}
+	return nil
+}
+
+// envValue returns the environment variable setting key, prefixed or not
+func envValue(key string) string {
+	if value := os.Getenv(envPrefix + strings.ToUpper(key)); value != "" {
+		return value
+	}
+	return os.Getenv(strings.ToUpper(key))
+}
+
+// envFileName returns the .env file, or the profile's .env.NAME file
+func envFileName() string {
+	if profile != "" {
+		return ".env." + profile
+	}
+	return ".env"
+}
+
+// LoadConfig reads configuration from the .env file (or the profile's .env.NAME file),
+// if present, and environment variables
+func LoadConfig() error {
+	envFile := envFileName()
+	if profile != "" {
+		if _, err := os.Stat(envFile); err != nil {
+			return fmt.Errorf("profile %s: %w", profile, err)
+		}
+	}
+
+	if data, err := os.ReadFile(envFile); err == nil {
+		// Initialize Viper from .env file, read with legacy names renamed
+		content, changes := migrateEnv(string(data))
+		for _, change := range changes {
+			warnLegacyKey(envFile, change.Key, change.Renamed)
+		}
+		viper.SetConfigType("env")
+		if err := viper.ReadConfig(strings.NewReader(content)); err != nil {
+			return fmt.Errorf("%s: %w", envFile, err)
+		}
+	}
 
 	// Enable reading environment variables
 	viper.AutomaticEnv()
+	if err := bindPrefixedEnv(); err != nil {
+		return err
+	}
+
+	// merge in a mounted config directory (e.g. a Kubernetes ConfigMap), if set
+	if err := loadConfigDir(); err != nil {
+		return err
+	}
+	applyLegacyKeys()
+	return nil
+}
+
+// Get environment variables
+func getEnvVars() error {
+	if err := LoadConfig(); err != nil {
+		return err
+	}
 
 	// get mastodon_url from Viper
 	mastodon_url := viper.GetString("MASTODON_URL")
@@ -28,10 +98,38 @@ func getEnvVars() error {
 		return fmt.Errorf("mastodon_url must be provided")
 	}
 
-	mastodon_token := viper.GetString("MASTODON_TOKEN")
-	if mastodon_token == "" {
-		return fmt.Errorf("mastodon_token must be provided")
+	mastodon_access_token := viper.GetString("MASTODON_ACCESS_TOKEN")
+	if mastodon_access_token == "" {
+		return fmt.Errorf("mastodon_access_token must be provided")
 	}
 
 	return nil
 }
+
+// OpenDB loads the configuration and opens the database, encrypting post contents and error
+// messages with db_encryption_key if set. Outbound requests are recorded in its audit log from
+// then on.
+func OpenDB() {
+	if err := LoadConfig(); err != nil {
+		log.Fatal("Error loading configuration: ", err)
+	}
+	db.EncryptionKey = viper.GetString("db_encryption_key")
+	db.InitDB()
+	httpclient.Auditor = recordAudit
+}
+
+// mastodonClient returns a client for the configured Mastodon account. It is created on every
+// use, so changes to the config directory apply to the next request.
+func mastodonClient() *mastodon.Client {
+	return mastodon.NewClient(mastodon.Config{
+		URL:              viper.GetString("mastodon_url"),
+		AccessToken:      viper.GetString("mastodon_access_token"),
+		Timeout:          viper.GetDuration("mastodon_timeout"),
+		UploadTimeout:    viper.GetDuration("mastodon_upload_timeout"),
+		MaxCharacters:    maxCharacters(),
+		Flavor:           serverFlavor(),
+		TootRetry:        retry.Configured(retry.TootKey),
+		UploadRetry:      retry.Configured(retry.UploadKey),
+		RateLimitReserve: viper.GetInt("mastodon_rate_limit_reserve"),
+	})
+}
@@ -17,6 +17,14 @@ func TestGetEnvVars(t *testing.T) {
 	}{
 		{
 			name: "Valid environment variables",
+			envVars: map[string]string{
+				"MASTODON_URL":          "valid-url",
+				"MASTODON_ACCESS_TOKEN": "valid-token",
+			},
+			expectError: false,
+		},
+		{
+			name: "Legacy MASTODON_TOKEN",
 			envVars: map[string]string{
 				"MASTODON_URL":   "valid-url",
 				"MASTODON_TOKEN": "valid-token",
@@ -25,15 +33,15 @@ func TestGetEnvVars(t *testing.T) {
 		},
 		{
 			name:            "Missing MASTODON_URL",
-			envVars:         map[string]string{"MASTODON_TOKEN": "valid-token"},
+			envVars:         map[string]string{"MASTODON_ACCESS_TOKEN": "valid-token"},
 			expectError:     true,
 			expectErrorText: "mastodon_url must be provided",
 		},
 		{
-			name:            "Missing MASTODON_TOKEN",
+			name:            "Missing MASTODON_ACCESS_TOKEN",
 			envVars:         map[string]string{"MASTODON_URL": "valid-url"},
 			expectError:     true,
-			expectErrorText: "mastodon_token must be provided",
+			expectErrorText: "mastodon_access_token must be provided",
 		},
 		{
 			name:            "No environment variables",
@@ -78,3 +86,29 @@ func TestGetEnvVars(t *testing.T) {
 		})
 	}
 }
+
+// Test prefixed environment variables take precedence over unprefixed ones
+func TestLoadConfig_EnvPrefix(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	t.Setenv("INTERVAL", "10")
+	t.Setenv("RSS2MASTODON_INTERVAL", "30")
+	t.Setenv("FEED_URL", "https://example.com/rss")
+	t.Setenv("RSS2MASTODON_FEED_URL", "")
+	t.Setenv("RSS2MASTODON_MASTODON_URL", "https://mastodon.example")
+
+	if err := LoadConfig(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	expected := map[string]string{
+		"interval":     "30",
+		"feed_url":     "https://example.com/rss",
+		"mastodon_url": "https://mastodon.example",
+	}
+	for key, want := range expected {
+		if got := viper.GetString(key); got != want {
+			t.Errorf("Expected %s=%q, got %q", key, want, got)
+		}
+	}
+}
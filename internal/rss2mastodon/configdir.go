@@ -0,0 +1,127 @@
+package rss2mastodon
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// configDirPollInterval is how often config_dir is checked for changes
+var configDirPollInterval = 10 * time.Second
+
+// configDirHash and configDirKeys describe the last loaded config_dir
+var (
+	configDirHash string
+	configDirKeys = map[string]bool{}
+)
+
+// readConfigDir reads a directory with one file per config key, e.g. a mounted Kubernetes
+// ConfigMap, Secret or downward API volume. File names are matched like environment variables
+// (FEED_URL and feed-url both set feed_url). Hidden files, such as the ..data symlink
+// Kubernetes uses to swap ConfigMap contents atomically, are skipped.
+func readConfigDir(dir string) (map[string]interface{}, string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, "", err
+	}
+
+	values := make(map[string]interface{})
+	var names []string
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		// ConfigMap keys are symlinks into the ..data directory, so stat the target
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, "", err
+		}
+		key := strings.ReplaceAll(strings.ToLower(entry.Name()), "-", "_")
+		values[key] = strings.TrimSpace(string(data))
+		names = append(names, key)
+	}
+
+	sort.Strings(names)
+	hash := sha256.New()
+	for _, name := range names {
+		hash.Write([]byte(name + "=" + values[name].(string) + "\n"))
+	}
+	return values, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// loadConfigDir merges config_dir into the configuration if it changed since it was last loaded.
+// Environment variables and flags still take precedence over it.
+func loadConfigDir() error {
+	dir := viper.GetString("config_dir")
+	if dir == "" {
+		return nil
+	}
+
+	values, hash, err := readConfigDir(dir)
+	if err != nil {
+		return err
+	}
+	if hash == configDirHash {
+		return nil
+	}
+
+	// keys removed from the directory no longer apply
+	for key := range configDirKeys {
+		if _, ok := values[key]; !ok {
+			values[key] = ""
+		}
+	}
+	if err := viper.MergeConfigMap(values); err != nil {
+		return err
+	}
+	applyLegacyKeys()
+
+	if configDirHash != "" {
+		log.Info("Reloaded configuration from ", dir)
+	}
+	configDirHash = hash
+	configDirKeys = make(map[string]bool)
+	for key, value := range values {
+		if value != "" {
+			configDirKeys[key] = true
+		}
+	}
+	return nil
+}
+
+// watchConfigDir starts the next cycle early when config_dir changes, so the new configuration
+// is loaded without waiting for the polling interval
+func watchConfigDir() {
+	dir := viper.GetString("config_dir")
+	if dir == "" {
+		return
+	}
+
+	go func() {
+		last := configDirHash
+		for {
+			time.Sleep(configDirPollInterval)
+			_, hash, err := readConfigDir(dir)
+			if err != nil {
+				log.Debug("Failed to check config directory: ", err)
+				continue
+			}
+			if hash != last {
+				last = hash
+				triggerCycle()
+			}
+		}
+	}()
+}
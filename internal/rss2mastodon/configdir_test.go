@@ -0,0 +1,61 @@
+package rss2mastodon
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// Test loading and reloading configuration from a mounted ConfigMap style directory
+func TestLoadConfigDir(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, value string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(value), 0o644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+	}
+	write("FEED_URL", "https://example.com/feed.xml\n")
+	write("stale-after", "720h")
+	// Kubernetes bookkeeping entries are ignored
+	write("..data", "ignored")
+	if err := os.Mkdir(filepath.Join(dir, "..2024_01_01"), 0o755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	viper.Reset()
+	viper.Set("config_dir", dir)
+	defer viper.Reset()
+	configDirHash, configDirKeys = "", map[string]bool{}
+	defer func() { configDirHash, configDirKeys = "", map[string]bool{} }()
+
+	if err := loadConfigDir(); err != nil {
+		t.Fatalf("loadConfigDir failed: %v", err)
+	}
+	if got := viper.GetString("feed_url"); got != "https://example.com/feed.xml" {
+		t.Errorf("Expected feed_url from the config directory, got '%s'", got)
+	}
+	if got := viper.GetString("stale_after"); got != "720h" {
+		t.Errorf("Expected stale_after from the config directory, got '%s'", got)
+	}
+	if viper.IsSet("..data") {
+		t.Errorf("Expected hidden files to be ignored")
+	}
+
+	// a changed and a removed key are picked up on reload
+	write("FEED_URL", "https://example.com/other.xml")
+	if err := os.Remove(filepath.Join(dir, "stale-after")); err != nil {
+		t.Fatalf("Failed to remove config file: %v", err)
+	}
+	if err := loadConfigDir(); err != nil {
+		t.Fatalf("loadConfigDir failed: %v", err)
+	}
+	if got := viper.GetString("feed_url"); got != "https://example.com/other.xml" {
+		t.Errorf("Expected reloaded feed_url, got '%s'", got)
+	}
+	if got := viper.GetString("stale_after"); got != "" {
+		t.Errorf("Expected removed stale_after to be cleared, got '%s'", got)
+	}
+}
@@ -0,0 +1,122 @@
+package rss2mastodon
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// envOnlyKeys are configuration keys without a command-line flag, which can only be set
+// through the environment, the .env file or the config directory
+var envOnlyKeys = []string{
+	"admin_token",
+	"blocklist",
+	"cw_text",
+	"feeds",
+	"github_token",
+	"gitlab_token",
+	"gotify_token",
+	"gotify_url",
+	"imap_password",
+	"imap_username",
+	"k8s_namespace",
+	"mastodon_access_token",
+	"mastodon_url",
+	"metrics_backend",
+	"ntfy_token",
+	"ntfy_url",
+	"pod_name",
+	"public_url",
+	"push_token",
+	"registry_password",
+	"registry_username",
+	"statsd_addr",
+	"statsd_tags",
+}
+
+// Sources a configuration value can come from, in order of precedence
+const (
+	SourceFlag      = "flag"
+	SourceEnv       = "env"
+	SourceConfigDir = "config dir"
+	SourceDotEnv    = ".env"
+	SourceDefault   = "default"
+	SourceUnset     = "unset"
+)
+
+// ConfigValue is an effective configuration value and the layer it came from
+type ConfigValue struct {
+	Key    string
+	Value  string
+	Source string
+}
+
+// EffectiveConfig returns every known configuration value, sorted by key, along with the
+// layer it came from. Secrets are masked.
+func EffectiveConfig(flags *pflag.FlagSet) []ConfigValue {
+	keys := make(map[string]*pflag.Flag)
+	for _, key := range envOnlyKeys {
+		keys[key] = nil
+	}
+	flags.VisitAll(func(f *pflag.Flag) {
+		keys[strings.ReplaceAll(f.Name, "-", "_")] = f
+	})
+
+	var values []ConfigValue
+	for key, flag := range keys {
+		value := ConfigValue{Key: key, Value: viper.GetString(key), Source: configSource(key, flag)}
+		if flag != nil && (flag.Value.Type() == "stringArray" || flag.Value.Type() == "stringSlice") {
+			value.Value = strings.Join(listSetting(key), ", ")
+		}
+		if isSecret(key) && value.Value != "" {
+			value.Value = "********"
+		}
+		values = append(values, value)
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i].Key < values[j].Key })
+	return values
+}
+
+// configSource returns the layer a key's effective value comes from, following viper's precedence
+func configSource(key string, flag *pflag.Flag) string {
+	switch {
+	case flag != nil && flag.Changed:
+		return SourceFlag
+	case envValue(key) != "":
+		return SourceEnv
+	case configDirKeys[key]:
+		return SourceConfigDir
+	case viper.InConfig(key):
+		return SourceDotEnv
+	case flag != nil:
+		return SourceDefault
+	default:
+		return SourceUnset
+	}
+}
+
+// isSecret reports whether a key holds a credential which must not be printed, including the
+// definitions of further Mastodon accounts, which hold their access tokens
+func isSecret(key string) bool {
+	return strings.Contains(key, "token") || strings.Contains(key, "password") || strings.Contains(key, "secret") ||
+		strings.HasSuffix(key, "_key") || key == "mastodon_account"
+}
+
+// ShowConfig prints the effective configuration and the source of each value to out
+func ShowConfig(out io.Writer, flags *pflag.FlagSet) error {
+	if err := LoadConfig(); err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tVALUE\tSOURCE")
+	for _, value := range EffectiveConfig(flags) {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", strings.ToUpper(value.Key), value.Value, value.Source)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,55 @@
+package rss2mastodon
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// Test the effective configuration reports where each value came from and masks secrets
+func TestEffectiveConfig(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	viper.AutomaticEnv()
+
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.StringArray("feed-url", nil, "")
+	flags.Int("interval", 60, "")
+	flags.Bool("attach-images", false, "")
+	if err := flags.Parse([]string{"--feed-url", "https://example.com/feed.xml", "--feed-url", "https://example.com/other.xml"}); err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+	flags.VisitAll(func(f *pflag.Flag) {
+		_ = viper.BindPFlag(strings.ReplaceAll(f.Name, "-", "_"), f)
+	})
+
+	t.Setenv("MASTODON_URL", "https://mastodon.example")
+	t.Setenv("MASTODON_ACCESS_TOKEN", "super-secret")
+	t.Setenv("ATTACH_IMAGES", "true")
+
+	expected := map[string]ConfigValue{
+		"feed_url":              {Value: "https://example.com/feed.xml, https://example.com/other.xml", Source: SourceFlag},
+		"attach_images":         {Value: "true", Source: SourceEnv},
+		"interval":              {Value: "60", Source: SourceDefault},
+		"mastodon_url":          {Value: "https://mastodon.example", Source: SourceEnv},
+		"mastodon_access_token": {Value: "********", Source: SourceEnv},
+		"ntfy_url":              {Value: "", Source: SourceUnset},
+	}
+
+	found := 0
+	for _, value := range EffectiveConfig(flags) {
+		want, ok := expected[value.Key]
+		if !ok {
+			continue
+		}
+		found++
+		if value.Value != want.Value || value.Source != want.Source {
+			t.Errorf("Expected %s=%q from %s, got %q from %s", value.Key, want.Value, want.Source, value.Value, value.Source)
+		}
+	}
+	if found != len(expected) {
+		t.Errorf("Expected %d keys, found %d", len(expected), found)
+	}
+}
@@ -0,0 +1,43 @@
+package rss2mastodon
+
+import (
+	"strings"
+
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// contentWarning returns the content warning to post a toot about the post behind: the
+// warning of the first content_warning_rule matching it, or else content_warning, falling
+// back to cw_text
+func contentWarning(post rss.RSSItem) string {
+	for _, rule := range listSetting("content_warning_rule") {
+		keyword, warning, ok := strings.Cut(rule, "=")
+		keyword, warning = strings.TrimSpace(keyword), strings.TrimSpace(warning)
+		if !ok || keyword == "" || warning == "" {
+			continue
+		}
+		if matchesKeyword(post, keyword) {
+			return warning
+		}
+	}
+	if warning := viper.GetString("content_warning"); warning != "" {
+		return warning
+	}
+	return viper.GetString("cw_text")
+}
+
+// matchesKeyword reports whether the post's link contains the keyword or one of its
+// categories is the keyword, ignoring case
+func matchesKeyword(post rss.RSSItem, keyword string) bool {
+	if strings.Contains(strings.ToLower(post.Link), strings.ToLower(keyword)) {
+		return true
+	}
+	for _, category := range post.Categories {
+		if strings.EqualFold(strings.TrimSpace(category), keyword) {
+			return true
+		}
+	}
+	return false
+}
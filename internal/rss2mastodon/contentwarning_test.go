@@ -0,0 +1,44 @@
+package rss2mastodon
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// Table-driven test for picking the content warning of a post from the rules and defaults
+func TestContentWarning(t *testing.T) {
+	rules := []string{"politics=US politics", "Food = Food, eye contact", "invalid", "=Empty keyword"}
+	tests := []struct {
+		name           string
+		cwText         string
+		contentWarning string
+		rules          []string
+		post           rss.RSSItem
+		expected       string
+	}{
+		{"None", "", "", nil, rss.RSSItem{Link: "https://example.com/politics/1"}, ""},
+		{"CW_TEXT", "Long post", "", nil, rss.RSSItem{Link: "https://example.com/1"}, "Long post"},
+		{"Flag overrides CW_TEXT", "Long post", "Blog post", nil, rss.RSSItem{Link: "https://example.com/1"}, "Blog post"},
+		{"Rule matching the link", "Long post", "", rules, rss.RSSItem{Link: "https://example.com/Politics/1"}, "US politics"},
+		{"Rule matching a category", "", "", rules, rss.RSSItem{Link: "https://example.com/1", Categories: []string{" food "}}, "Food, eye contact"},
+		{"No rule matching", "Long post", "", rules, rss.RSSItem{Link: "https://example.com/1", Categories: []string{"go"}}, "Long post"},
+		{"Category only matching whole", "", "", rules, rss.RSSItem{Link: "https://example.com/1", Categories: []string{"seafood"}}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			viper.Set("cw_text", tt.cwText)
+			viper.Set("content_warning", tt.contentWarning)
+			viper.Set("content_warning_rule", tt.rules)
+			defer viper.Reset()
+
+			if got := contentWarning(tt.post); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
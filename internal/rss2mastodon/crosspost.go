@@ -0,0 +1,210 @@
+package rss2mastodon
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+	"github.com/toozej/rss2mastodon/internal/mastodon"
+	"github.com/toozej/rss2mastodon/internal/notify"
+	"github.com/toozej/rss2mastodon/internal/retry"
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// mastodonAccount is another Mastodon account, besides MASTODON_URL and MASTODON_ACCESS_TOKEN,
+// which new posts are cross-posted to
+type mastodonAccount struct {
+	// Name identifies the account in the database and logs, the instance's host by default
+	Name        string
+	URL         string
+	AccessToken string
+	// Flavor is the instance's server software, Mastodon if unset
+	Flavor string
+}
+
+// parseAccountFlag parses a --mastodon-account definition of comma-separated key=value options,
+// e.g. "url=https://fosstodon.org,token=TOKEN,name=project,flavor=gotosocial"
+func parseAccountFlag(definition string) (mastodonAccount, error) {
+	var account mastodonAccount
+	for _, option := range strings.Split(definition, ",") {
+		option = strings.TrimSpace(option)
+		if option == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(option, "=")
+		if !ok {
+			return account, fmt.Errorf("invalid account option %q, expected key=value", option)
+		}
+		value = strings.TrimSpace(value)
+
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "url":
+			account.URL = strings.TrimSuffix(value, "/")
+		case "token":
+			account.AccessToken = value
+		case "name":
+			account.Name = value
+		case "flavor":
+			account.Flavor = strings.ToLower(value)
+		default:
+			return account, fmt.Errorf("unknown account option %q", key)
+		}
+	}
+
+	if account.URL == "" || account.AccessToken == "" {
+		return account, fmt.Errorf("account url and token are required")
+	}
+	instance, err := url.Parse(account.URL)
+	if err != nil || instance.Host == "" {
+		return account, fmt.Errorf("invalid account url %q", account.URL)
+	}
+	if account.Name == "" {
+		account.Name = instance.Host
+	}
+	if account.Flavor != "" && !mastodon.IsFlavor(account.Flavor) {
+		return account, fmt.Errorf("unknown server flavor %q, expected one of %s", account.Flavor, strings.Join(mastodon.Flavors, ", "))
+	}
+	return account, nil
+}
+
+// crossPostAccounts returns the accounts configured with --mastodon-account
+func crossPostAccounts() ([]mastodonAccount, error) {
+	var accounts []mastodonAccount
+	names := make(map[string]bool)
+	for _, definition := range viper.GetStringSlice("mastodon_account") {
+		account, err := parseAccountFlag(definition)
+		if err != nil {
+			return nil, err
+		}
+		if names[account.Name] {
+			return nil, fmt.Errorf("account name %s is used more than once, set a distinct name", account.Name)
+		}
+		names[account.Name] = true
+		accounts = append(accounts, account)
+	}
+	return accounts, nil
+}
+
+// client returns a client for the account, configured like the one of the main account
+func (a mastodonAccount) client() *mastodon.Client {
+	return mastodon.NewClient(mastodon.Config{
+		URL:              a.URL,
+		AccessToken:      a.AccessToken,
+		Timeout:          viper.GetDuration("mastodon_timeout"),
+		UploadTimeout:    viper.GetDuration("mastodon_upload_timeout"),
+		MaxCharacters:    maxCharacters(),
+		Flavor:           a.Flavor,
+		TootRetry:        retry.Configured(retry.TootKey),
+		UploadRetry:      retry.Configured(retry.UploadKey),
+		RateLimitReserve: viper.GetInt("mastodon_rate_limit_reserve"),
+	})
+}
+
+// notifyKey identifies the account's toot failures for notification flap suppression
+func (a mastodonAccount) notifyKey() string {
+	return mastodonKey + " " + a.Name
+}
+
+// crossPost records a new post's announcement, as tooted on the main account, for every other
+// account and toots it there. Announcements failing on an account are retried in later cycles.
+func crossPost(logger *log.Entry, post rss.RSSItem, content string, opts mastodon.TootOptions, stats *cycleStats) {
+	accounts, err := crossPostAccounts()
+	if err != nil {
+		logger.Error("Invalid account configuration: ", err)
+		return
+	}
+	if len(accounts) == 0 {
+		return
+	}
+
+	for _, account := range accounts {
+		announcement := db.CrossPost{
+			Link:           post.Link,
+			Account:        account.Name,
+			Content:        content,
+			Visibility:     opts.Visibility,
+			SpoilerText:    opts.SpoilerText,
+			Language:       opts.Language,
+			IdempotencyKey: opts.IdempotencyKey,
+			ScheduledAt:    opts.ScheduledAt,
+		}
+		if err := db.QueueCrossPost(announcement); err != nil {
+			logger.Errorf("Failed to record the announcement for account %s: %v", account.Name, err)
+		}
+	}
+
+	announcements, err := db.LinkCrossPosts(post.Link)
+	if err != nil {
+		logger.Error("Failed to look up the announcements on other accounts: ", err)
+		return
+	}
+	sendCrossPosts(accounts, announcements, stats)
+}
+
+// retryCrossPosts toots the announcements which failed on their account before, until they
+// failed retry_max_attempts times
+func retryCrossPosts(stats *cycleStats) {
+	accounts, err := crossPostAccounts()
+	if err != nil || len(accounts) == 0 {
+		return
+	}
+	announcements, err := db.UnsentCrossPosts(retryMaxAttempts())
+	if err != nil {
+		log.Error("Failed to look up the announcements left to cross-post: ", err)
+		return
+	}
+	sendCrossPosts(accounts, announcements, stats)
+}
+
+// sendCrossPosts toots the announcements not tooted on their account yet. Announcements of
+// accounts no longer configured are left as they are.
+func sendCrossPosts(accounts []mastodonAccount, announcements []db.CrossPost, stats *cycleStats) {
+	byName := make(map[string]mastodonAccount)
+	for _, account := range accounts {
+		byName[account.Name] = account
+	}
+
+	maxAttempts := retryMaxAttempts()
+	for _, announcement := range announcements {
+		account, ok := byName[announcement.Account]
+		if !ok || announcement.StatusID != "" || announcement.Attempts >= maxAttempts {
+			continue
+		}
+		logger := log.WithFields(log.Fields{"link": announcement.Link, "account": account.Name})
+
+		opts := mastodon.TootOptions{
+			ScheduledAt:    announcement.ScheduledAt,
+			Visibility:     announcement.Visibility,
+			SpoilerText:    announcement.SpoilerText,
+			Language:       announcement.Language,
+			IdempotencyKey: announcement.IdempotencyKey,
+		}
+		// announcements retried after their scheduled time has come close are tooted right away
+		if !opts.ScheduledAt.IsZero() && time.Until(opts.ScheduledAt) <= minScheduleDelay {
+			opts.ScheduledAt = time.Time{}
+		}
+
+		status, err := account.client().ForItem(announcement.Link).PostStatus(announcement.Content, opts)
+		if err != nil {
+			attempts, dbErr := db.RecordCrossPostFailure(announcement.Link, account.Name, err.Error())
+			if dbErr != nil {
+				logger.Error("Failed to record the failed cross-post: ", dbErr)
+			}
+			logger.Errorf("Failed to cross-post new post (attempt %d of %d): %v", attempts, maxAttempts, err)
+			recordError(account.notifyKey(), err)
+			notify.Failure(account.notifyKey(), err)
+			stats.failed.Add(1)
+			continue
+		}
+		notify.Success(account.notifyKey())
+		if err := db.RecordCrossPostSuccess(announcement.Link, account.Name, status.ID); err != nil {
+			logger.Error("Failed to record the cross-post: ", err)
+		}
+		logger.Infof("Cross-posted new post as status %s", status.ID)
+	}
+}
@@ -0,0 +1,133 @@
+package rss2mastodon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+)
+
+// Table-driven test for parsing --mastodon-account definitions
+func TestParseAccountFlag(t *testing.T) {
+	tests := []struct {
+		name          string
+		definition    string
+		expected      mastodonAccount
+		expectedError bool
+	}{
+		{
+			name:       "Named account",
+			definition: "url=https://fosstodon.org/,token=secret,name=project",
+			expected:   mastodonAccount{Name: "project", URL: "https://fosstodon.org", AccessToken: "secret"},
+		},
+		{
+			name:       "Named after the instance",
+			definition: "url=https://mastodon.social,token=secret",
+			expected:   mastodonAccount{Name: "mastodon.social", URL: "https://mastodon.social", AccessToken: "secret"},
+		},
+		{
+			name:       "Server flavor",
+			definition: "url=https://gts.example,token=secret,flavor=GoToSocial",
+			expected:   mastodonAccount{Name: "gts.example", URL: "https://gts.example", AccessToken: "secret", Flavor: "gotosocial"},
+		},
+		{
+			name:          "Unknown server flavor",
+			definition:    "url=https://gts.example,token=secret,flavor=misskey",
+			expectedError: true,
+		},
+		{
+			name:          "Missing token",
+			definition:    "url=https://mastodon.social",
+			expectedError: true,
+		},
+		{
+			name:          "Invalid url",
+			definition:    "url=mastodon.social,token=secret",
+			expectedError: true,
+		},
+		{
+			name:          "Unknown option",
+			definition:    "url=https://mastodon.social,token=secret,visibility=public",
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			account, err := parseAccountFlag(tt.definition)
+			if (err != nil) != tt.expectedError {
+				t.Fatalf("Expected error %v, got %v", tt.expectedError, err)
+			}
+			if !tt.expectedError && account != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, account)
+			}
+		})
+	}
+}
+
+// Test cross-posting new posts to further accounts, retrying those failing on one of them
+func TestPollFeeds_CrossPost(t *testing.T) {
+	db.InitDB()
+	defer db.CloseDB()
+	defer os.Remove("./tooted_posts.db")
+
+	var statuses []string
+	mainServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/feed.xml":
+			_, _ = w.Write([]byte(`<rss><channel><title>Blog</title>
+				<item><title>1</title><link>https://example.com/1</link></item>
+			</channel></rss>`))
+		case "/api/v1/statuses":
+			statuses = append(statuses, "main "+r.Header.Get("Authorization"))
+			_, _ = w.Write([]byte(`{"id":"1"}`))
+		}
+	}))
+	defer mainServer.Close()
+
+	projectDown := true
+	projectServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if projectDown {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		statuses = append(statuses, "project "+r.Header.Get("Authorization")+" "+r.FormValue("visibility"))
+		_, _ = w.Write([]byte(`{"id":"7"}`))
+	}))
+	defer projectServer.Close()
+
+	viper.Reset()
+	viper.Set("mastodon_url", mainServer.URL)
+	viper.Set("mastodon_access_token", "main-token")
+	viper.Set("mastodon_account", []string{"url=" + projectServer.URL + ",token=project-token,name=project"})
+	viper.Set("feed", []string{"url=" + mainServer.URL + "/feed.xml,visibility=unlisted"})
+	defer viper.Reset()
+
+	feeds, err := configuredFeeds()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	pollFeeds(feeds, newCycleStats())
+	if len(statuses) != 1 || statuses[0] != "main Bearer main-token" {
+		t.Fatalf("Expected the post tooted on the main account only, got %v", statuses)
+	}
+	announcements, err := db.LinkCrossPosts("https://example.com/1")
+	if err != nil || len(announcements) != 1 || announcements[0].Attempts != 1 || announcements[0].StatusID != "" {
+		t.Fatalf("Expected a failed cross-post, got %+v, %v", announcements, err)
+	}
+
+	projectDown = false
+	retryCrossPosts(newCycleStats())
+	retryCrossPosts(newCycleStats())
+	if len(statuses) != 2 || statuses[1] != "project Bearer project-token unlisted" {
+		t.Fatalf("Expected the post cross-posted once, got %v", statuses)
+	}
+	announcements, err = db.LinkCrossPosts("https://example.com/1")
+	if err != nil || len(announcements) != 1 || announcements[0].StatusID != "7" {
+		t.Errorf("Expected the cross-post recorded, got %+v, %v", announcements, err)
+	}
+}
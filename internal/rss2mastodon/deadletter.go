@@ -0,0 +1,111 @@
+package rss2mastodon
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+	"github.com/toozej/rss2mastodon/internal/notify"
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// Default retry budget of a post which fails to toot
+const (
+	defaultRetryMaxAttempts = 5
+	defaultRetryMaxAge      = 24 * time.Hour
+)
+
+// retryMaxAttempts returns how many times a toot is attempted before giving up on it
+func retryMaxAttempts() int {
+	if maxAttempts := viper.GetInt("retry_max_attempts"); maxAttempts > 0 {
+		return maxAttempts
+	}
+	return defaultRetryMaxAttempts
+}
+
+// recordDeliveryFailure counts a failed attempt to announce a post. Once the post has failed
+// retry_max_attempts times, or has been failing for longer than retry_max_age, it is moved
+// to the dead letters and a notification is sent, instead of being retried every cycle.
+func recordDeliveryFailure(post rss.RSSItem, deliveryErr error) {
+	attempts, firstFailure, err := db.RecordDeliveryFailure(post.Link, deliveryErr.Error())
+	if err != nil {
+		log.Error("Failed to record delivery attempt: ", err)
+		return
+	}
+
+	maxAttempts := retryMaxAttempts()
+	maxAge := viper.GetDuration("retry_max_age")
+	if maxAge <= 0 {
+		maxAge = defaultRetryMaxAge
+	}
+	if attempts < maxAttempts && time.Since(firstFailure) < maxAge {
+		return
+	}
+
+	if err := db.AddDeadLetter(post, attempts, firstFailure, deliveryErr.Error()); err != nil {
+		log.Error("Failed to move post to dead letters: ", err)
+		return
+	}
+	message := fmt.Sprintf("Giving up on %s after %d failed attempts since %s: %v", post.Link, attempts, firstFailure.Format(time.RFC1123), deliveryErr)
+	if err := notify.Send("rss2mastodon: post moved to dead letters", message); err != nil {
+		log.Error("Failed to send dead letter notification: ", err)
+	}
+}
+
+// clearDeliveryFailures resets the retry budget of a post once it has been announced
+func clearDeliveryFailures(post rss.RSSItem) {
+	if err := db.ClearDeliveryAttempts(post.Link); err != nil {
+		log.Error("Failed to clear delivery attempts: ", err)
+	}
+}
+
+// retryDeadLetter moves a dead letter back into the pending queue, due immediately
+func retryDeadLetter(idOrLink string) (*db.DeadLetter, error) {
+	letter, err := db.GetDeadLetter(idOrLink)
+	if err != nil {
+		return nil, err
+	}
+	if letter == nil {
+		return nil, fmt.Errorf("no dead letter %s", idOrLink)
+	}
+
+	if err := db.QueuePendingPost(letter.Item, time.Now(), db.PendingRetry); err != nil {
+		return nil, err
+	}
+	if err := db.RemoveDeadLetter(letter.Item.Link); err != nil {
+		return nil, err
+	}
+	return letter, nil
+}
+
+// ListDeadLetters prints the posts which exhausted their retry budget
+func ListDeadLetters(cmd *cobra.Command, args []string) {
+	OpenDB()
+	defer db.CloseDB()
+
+	letters, err := db.ListDeadLetters()
+	if err != nil {
+		log.Fatal("Failed to list dead letters: ", err)
+	}
+	for _, letter := range letters {
+		fmt.Printf("%d\t%s\t%d attempts\t%s\t%s\n", letter.ID, letter.Item.Link, letter.Attempts, letter.Timestamp.Format(time.RFC3339), letter.LastError)
+	}
+}
+
+// RetryDeadLetters requeues dead letters so the running daemon announces them on its next cycle
+func RetryDeadLetters(cmd *cobra.Command, args []string) {
+	OpenDB()
+	defer db.CloseDB()
+
+	for _, id := range args {
+		letter, err := retryDeadLetter(id)
+		if err != nil {
+			log.Fatal("Failed to retry dead letter: ", err)
+		}
+		log.Infof("Requeued %s", letter.Item.Link)
+	}
+}
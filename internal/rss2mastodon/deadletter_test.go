@@ -0,0 +1,68 @@
+package rss2mastodon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// Test a post which keeps failing is dead-lettered once, skipped, and can be requeued
+func TestDeadLetter(t *testing.T) {
+	db.InitDB()
+	defer db.CloseDB()
+	defer os.Remove("./tooted_posts.db")
+
+	var toots, notifications int
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/statuses":
+			toots++
+			w.WriteHeader(http.StatusInternalServerError)
+		case "/notify":
+			notifications++
+		}
+	}))
+	defer mockServer.Close()
+
+	viper.Reset()
+	viper.Set("mastodon_url", mockServer.URL)
+	viper.Set("mastodon_access_token", "fake-token")
+	viper.Set("ntfy_url", mockServer.URL+"/notify")
+	viper.Set("retry_max_attempts", 3)
+	viper.Set("notify_failure_threshold", 100)
+	defer viper.Reset()
+
+	post := rss.RSSItem{Title: "Failing", Link: "https://example.com/failing"}
+	for i := 0; i < 5; i++ {
+		handlePost(feedLogger(post.FeedURL), post, newCycleStats())
+	}
+
+	if toots != 3 {
+		t.Errorf("Expected 3 toot attempts, got %d", toots)
+	}
+	if notifications != 1 {
+		t.Errorf("Expected 1 dead letter notification, got %d", notifications)
+	}
+
+	letters, err := db.ListDeadLetters()
+	if err != nil || len(letters) != 1 || letters[0].Attempts != 3 {
+		t.Fatalf("Expected a dead letter after 3 attempts, got %v, %v", letters, err)
+	}
+
+	if _, err := retryDeadLetter(letters[0].Item.Link); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	pending, err := db.GetPendingPost(post.Link)
+	if err != nil || pending == nil || pending.Reason != db.PendingRetry {
+		t.Errorf("Expected post to be requeued for retry, got %v, %v", pending, err)
+	}
+	if deadLettered, _ := db.IsDeadLettered(post.Link); deadLettered {
+		t.Errorf("Expected post to no longer be dead-lettered")
+	}
+}
@@ -0,0 +1,66 @@
+package rss2mastodon
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// What tells feed items apart
+const (
+	// dedupLink identifies items by their link
+	dedupLink = "link"
+	// dedupTitleDate identifies items by their normalized title and pubDate, for feeds changing
+	// their links (and GUIDs) on every build, e.g. with cache-busting query strings
+	dedupTitleDate = "title_date"
+)
+
+// dedupMode returns what tells the feed's items apart
+func (f FeedConfig) dedupMode() string {
+	if f.Dedup != "" {
+		return f.Dedup
+	}
+	if mode := strings.ToLower(viper.GetString("dedup")); mode == dedupTitleDate {
+		return mode
+	}
+	return dedupLink
+}
+
+// titleDateKey returns the dedup key of an item from its feed, normalized title and pubDate,
+// and false if the item has no title or date to tell it apart by
+func titleDateKey(feedURL string, post rss.RSSItem) (string, bool) {
+	title := strings.ToLower(strings.Join(strings.Fields(html.UnescapeString(post.Title)), " "))
+	published, ok := post.Published()
+	if title == "" || !ok {
+		return "", false
+	}
+	key := feedURL + "\n" + title + "\n" + published.UTC().Format(time.RFC3339)
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(key))), true
+}
+
+// canonicalizeLink replaces the link of an item of a feed deduplicated by title and date with
+// the link it was first seen with, so the rest of the pipeline recognizes it. Items without a
+// title or date keep their link.
+func canonicalizeLink(feed FeedConfig, post *rss.RSSItem) {
+	if feed.dedupMode() != dedupTitleDate {
+		return
+	}
+	key, ok := titleDateKey(feed.URL, *post)
+	if !ok {
+		return
+	}
+	link, err := db.CanonicalLink(key, post.Link)
+	if err != nil {
+		log.Error("Failed to look up the item's first link: ", err)
+		return
+	}
+	post.Link = link
+}
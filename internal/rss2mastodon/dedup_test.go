@@ -0,0 +1,94 @@
+package rss2mastodon
+
+import (
+	"os"
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// Table-driven test for keeping the first link of items deduplicated by title and date
+func TestCanonicalizeLink(t *testing.T) {
+	db.InitDB()
+	defer db.CloseDB()
+	defer os.Remove("./tooted_posts.db")
+
+	viper.Reset()
+	defer viper.Reset()
+
+	const date = "Mon, 02 Jan 2006 15:04:05 GMT"
+	titleDate := FeedConfig{URL: "https://example.com/rss", Dedup: dedupTitleDate}
+	tests := []struct {
+		name     string
+		feed     FeedConfig
+		post     rss.RSSItem
+		expected string
+	}{
+		{
+			name:     "First build",
+			feed:     titleDate,
+			post:     rss.RSSItem{Title: "Hello &amp; World", PubDate: date, Link: "https://example.com/hello?v=1"},
+			expected: "https://example.com/hello?v=1",
+		},
+		{
+			name:     "Rebuilt with a new link",
+			feed:     titleDate,
+			post:     rss.RSSItem{Title: "  hello &  world ", PubDate: "2006-01-02T15:04:05Z", Link: "https://example.com/hello?v=2"},
+			expected: "https://example.com/hello?v=1",
+		},
+		{
+			name:     "Same title on another date",
+			feed:     titleDate,
+			post:     rss.RSSItem{Title: "Hello & World", PubDate: "Tue, 03 Jan 2006 15:04:05 GMT", Link: "https://example.com/hello-again"},
+			expected: "https://example.com/hello-again",
+		},
+		{
+			name:     "Same title and date in another feed",
+			feed:     FeedConfig{URL: "https://example.org/rss", Dedup: dedupTitleDate},
+			post:     rss.RSSItem{Title: "Hello & World", PubDate: date, Link: "https://example.org/hello"},
+			expected: "https://example.org/hello",
+		},
+		{
+			name:     "No date",
+			feed:     titleDate,
+			post:     rss.RSSItem{Title: "Hello & World", Link: "https://example.com/hello?v=3"},
+			expected: "https://example.com/hello?v=3",
+		},
+		{
+			name:     "Deduplicated by link",
+			feed:     FeedConfig{URL: "https://example.com/rss"},
+			post:     rss.RSSItem{Title: "Hello & World", PubDate: date, Link: "https://example.com/hello?v=4"},
+			expected: "https://example.com/hello?v=4",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			post := tt.post
+			canonicalizeLink(tt.feed, &post)
+			if post.Link != tt.expected {
+				t.Errorf("Expected link %q, got %q", tt.expected, post.Link)
+			}
+		})
+	}
+}
+
+// Test the dedup setting applies to feeds without their own
+func TestDedupMode_Default(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	if mode := (FeedConfig{}).dedupMode(); mode != dedupLink {
+		t.Errorf("Expected %s by default, got %s", dedupLink, mode)
+	}
+	viper.Set("dedup", "title_date")
+	if mode := (FeedConfig{}).dedupMode(); mode != dedupTitleDate {
+		t.Errorf("Expected the dedup setting, got %s", mode)
+	}
+	if mode := (FeedConfig{Dedup: dedupLink}).dedupMode(); mode != dedupLink {
+		t.Errorf("Expected the feed's own dedup option, got %s", mode)
+	}
+}
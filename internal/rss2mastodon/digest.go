@@ -0,0 +1,154 @@
+package rss2mastodon
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+	"github.com/toozej/rss2mastodon/internal/mastodon"
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// Defaults for the weekly digest
+const (
+	defaultDigestTime     = "09:00"
+	defaultDigestTemplate = "This week on the blog:\n{{range .Posts}}\n{{.Link}}{{end}}"
+)
+
+// digestGracePeriod is how late a digest may still be posted, e.g. after downtime,
+// before it is skipped until the following week
+const digestGracePeriod = 24 * time.Hour
+
+// digestLastKey remembers the last digest in the state table
+const digestLastKey = "digest_last"
+
+// styleDigest is the announcement style weekly digests are recorded with
+const styleDigest = "weekly digest"
+
+// weeklyDigest is the data available to digest_template
+type weeklyDigest struct {
+	Start time.Time
+	End   time.Time
+	Posts []tootedPost
+}
+
+// lastDigestTime returns the most recent digest_day at digest_time on or before now,
+// in now's time zone
+func lastDigestTime(now time.Time) (time.Time, error) {
+	day := strings.ToLower(strings.TrimSpace(viper.GetString("digest_day")))
+	weekday := -1
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := strings.ToLower(d.String())
+		if day == name || day == name[:3] {
+			weekday = int(d)
+		}
+	}
+	if weekday < 0 {
+		return time.Time{}, fmt.Errorf("invalid digest day %q", viper.GetString("digest_day"))
+	}
+
+	clock := viper.GetString("digest_time")
+	if clock == "" {
+		clock = defaultDigestTime
+	}
+	at, err := time.Parse("15:04", clock)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid digest time %q, expected e.g. 09:00", clock)
+	}
+
+	daysSince := (int(now.Weekday()) - weekday + 7) % 7
+	last := time.Date(now.Year(), now.Month(), now.Day()-daysSince, at.Hour(), at.Minute(), 0, 0, now.Location())
+	if last.After(now) {
+		last = last.AddDate(0, 0, -7)
+	}
+	return last, nil
+}
+
+// postWeeklyDigest toots a digest of the posts announced since the previous digest, at most
+// a week, once it is digest_day at digest_time. Digests missed by more than a day are skipped.
+func postWeeklyDigest(stats *cycleStats) {
+	if viper.GetString("digest_day") == "" {
+		return
+	}
+
+	now := time.Now()
+	due, err := lastDigestTime(now)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	if now.Sub(due) > digestGracePeriod {
+		return
+	}
+
+	start := now.AddDate(0, 0, -7)
+	last, ok, err := db.GetState(digestLastKey)
+	if err != nil {
+		log.Error("Failed to read last weekly digest: ", err)
+		return
+	}
+	if ok {
+		if posted, err := time.Parse(time.RFC3339, last); err == nil {
+			if !posted.Before(due) {
+				return
+			}
+			if posted.After(start) {
+				start = posted
+			}
+		}
+	}
+
+	posts, err := db.TootedPostsBetween(start, now)
+	if err != nil {
+		log.Error("Failed to read this week's posts: ", err)
+		return
+	}
+
+	if len(posts) == 0 {
+		log.Info("No posts this week, skipping the weekly digest")
+	} else {
+		digest := weeklyDigest{Start: start, End: now}
+		for _, post := range posts {
+			digest.Posts = append(digest.Posts, tootedPost{Link: withLinkParams(rss.RSSItem{Link: post.Link}).Link, Tooted: post.Timestamp})
+		}
+		content, err := digestContent(digest)
+		if err != nil {
+			log.Error("Invalid digest template: ", err)
+			return
+		}
+
+		content = withFooter(rss.RSSItem{}, content)
+		if !reserveTootFor(log.NewEntry(log.StandardLogger()), "the weekly digest") {
+			return
+		}
+		defer releaseToot()
+		status, err := mastodonClient().PostStatus(content, mastodon.TootOptions{SpoilerText: contentWarning(rss.RSSItem{}), Language: tootLanguage(""), LocalOnly: tootLocalOnly("")})
+		if err != nil {
+			// try again next cycle
+			log.Error("Failed to toot the weekly digest: ", err)
+			recordError(mastodonKey, err)
+			stats.failed.Add(1)
+			return
+		}
+		stats.tooted.Add(1)
+		recordStatus(status, "", styleDigest)
+		log.Infof("Tooted the weekly digest of %d posts", len(posts))
+	}
+
+	if err := db.SetState(digestLastKey, now.UTC().Format(time.RFC3339)); err != nil {
+		log.Error("Failed to record weekly digest: ", err)
+	}
+}
+
+// digestContent renders digest_template
+func digestContent(digest weeklyDigest) (string, error) {
+	text := viper.GetString("digest_template")
+	if text == "" {
+		text = defaultDigestTemplate
+	}
+	return renderTemplate("digest_template", text, digest)
+}
@@ -0,0 +1,94 @@
+package rss2mastodon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+)
+
+// Table-driven test for finding the most recent digest time
+func TestLastDigestTime(t *testing.T) {
+	// a Wednesday
+	now := time.Date(2024, 5, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		day         string
+		clock       string
+		expected    time.Time
+		expectError bool
+	}{
+		{"Earlier this week", "sunday", "", time.Date(2024, 5, 12, 9, 0, 0, 0, time.UTC), false},
+		{"Earlier today", "Wed", "08:30", time.Date(2024, 5, 15, 8, 30, 0, 0, time.UTC), false},
+		{"Later today", "wednesday", "18:00", time.Date(2024, 5, 8, 18, 0, 0, 0, time.UTC), false},
+		{"Invalid day", "someday", "", time.Time{}, true},
+		{"Invalid time", "friday", "9am", time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Set("digest_day", tt.day)
+			viper.Set("digest_time", tt.clock)
+			defer viper.Reset()
+
+			got, err := lastDigestTime(now)
+			if (err != nil) != tt.expectError {
+				t.Fatalf("Expected error %v, got %v", tt.expectError, err)
+			}
+			if !got.Equal(tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+// Test the weekly digest is tooted once, with the week's posts
+func TestPostWeeklyDigest(t *testing.T) {
+	db.InitDB()
+	defer db.CloseDB()
+	defer os.Remove("./tooted_posts.db")
+
+	var toots []string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		toots = append(toots, r.FormValue("status"))
+		_, _ = w.Write([]byte(`{"id":"1"}`))
+	}))
+	defer mockServer.Close()
+
+	// the digest is due right now
+	now := time.Now()
+	viper.Reset()
+	viper.Set("mastodon_url", mockServer.URL)
+	viper.Set("mastodon_access_token", "fake-token")
+	viper.Set("digest_day", now.Add(-time.Minute).Weekday().String())
+	viper.Set("digest_time", now.Add(-time.Minute).Format("15:04"))
+	viper.Set("digest_template", "Week of {{.End.Format \"Jan 2\"}}:{{range .Posts}} {{.Link}}{{end}}")
+	defer viper.Reset()
+
+	stats := newCycleStats()
+	postWeeklyDigest(stats)
+	if len(toots) != 0 {
+		t.Fatalf("Expected no digest without posts, got %v", toots)
+	}
+
+	// the empty digest counts as posted, so wait for next week's
+	if err := db.SetState(digestLastKey, now.AddDate(0, 0, -7).UTC().Format(time.RFC3339)); err != nil {
+		t.Fatalf("Failed to set state: %v", err)
+	}
+	if err := db.StoreTootedPost("https://example.com/this-week", "content"); err != nil {
+		t.Fatalf("Failed to store post: %v", err)
+	}
+	postWeeklyDigest(stats)
+	postWeeklyDigest(stats)
+
+	expected := "Week of " + time.Now().Format("Jan 2") + ": https://example.com/this-week"
+	if len(toots) != 1 || toots[0] != expected {
+		t.Errorf("Expected a single digest %q, got %q", expected, toots)
+	}
+}
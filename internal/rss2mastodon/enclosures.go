@@ -0,0 +1,14 @@
+package rss2mastodon
+
+import (
+	"github.com/spf13/viper"
+)
+
+// uploadEnclosures returns whether the video and audio enclosures of the feed's posts are
+// uploaded as attachments: the feed's enclosures option, or else the upload_enclosures setting
+func uploadEnclosures(feedURL string) bool {
+	if enclosures := feedConfig(feedURL).Enclosures; enclosures != nil {
+		return *enclosures
+	}
+	return viper.GetBool("upload_enclosures")
+}
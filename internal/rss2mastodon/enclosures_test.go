@@ -0,0 +1,35 @@
+package rss2mastodon
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// Table-driven test for whether a feed's enclosures are uploaded, falling back to the global setting
+func TestUploadEnclosures(t *testing.T) {
+	tests := []struct {
+		name       string
+		enclosures bool
+		feedURL    string
+		expected   bool
+	}{
+		{"Unset", false, "https://example.com/rss", false},
+		{"Global setting", true, "https://example.com/rss", true},
+		{"Feed enabling it", false, "https://example.com/podcast", true},
+		{"Feed disabling it", true, "https://example.com/blog", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			viper.Set("upload_enclosures", tt.enclosures)
+			viper.Set("feed", []string{"url=https://example.com/podcast,enclosures=true", "url=https://example.com/blog,enclosures=false"})
+			defer viper.Reset()
+
+			if got := uploadEnclosures(tt.feedURL); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,272 @@
+package rss2mastodon
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+	"github.com/toozej/rss2mastodon/internal/mastodon"
+	"github.com/toozej/rss2mastodon/internal/notify"
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// Announcement styles statuses are recorded with, to compare their engagement
+const (
+	styleNewPost       = "new post"
+	styleThoughts      = "thoughts"
+	styleUpdate        = "update"
+	styleArchiveRepost = "archive repost"
+	withMediaSuffix    = " with media"
+)
+
+// engagementWindow is how long after tooting a status's engagement keeps being refreshed
+const engagementWindow = 30 * 24 * time.Hour
+
+// engagementLastKey remembers when engagement was last recorded in the state table
+const engagementLastKey = "engagement_last"
+
+// engagementDigestLastKey remembers when the last engagement digest was sent in the state table
+const engagementDigestLastKey = "engagement_digest_last"
+
+// engagementDigestPosts is how many of the most engaged posts an engagement digest lists
+const engagementDigestPosts = 10
+
+// announcementStyle names the style a new post is announced in
+func announcementStyle(post rss.RSSItem, opts mastodon.TootOptions) string {
+	if opts.Poll != nil {
+		return stylePoll
+	}
+	style := styleNewPost
+	if mastodon.IsThought(post) {
+		style = styleThoughts
+	}
+	if len(opts.MediaIDs) > 0 {
+		style += withMediaSuffix
+	}
+	return style
+}
+
+// recordStatus remembers a tooted status so its engagement can be tracked
+func recordStatus(status *mastodon.Status, link string, style string) {
+	if status == nil || status.ID == "" {
+		return
+	}
+	if err := db.RecordStatus(status.ID, status.URL, link, style); err != nil {
+		log.Error("Failed to record status: ", err)
+	}
+}
+
+// trackEngagement records the bot account's follower count and the favourites, boosts and
+// replies of statuses tooted within the engagement window, once every engagement_interval
+func trackEngagement() {
+	interval := viper.GetDuration("engagement_interval")
+	if interval <= 0 {
+		return
+	}
+
+	last, ok, err := db.GetState(engagementLastKey)
+	if err != nil {
+		log.Error("Failed to read last engagement check: ", err)
+		return
+	}
+	if ok {
+		if checked, err := time.Parse(time.RFC3339, last); err == nil && time.Since(checked) < interval {
+			return
+		}
+	}
+
+	client := mastodonClient()
+	account, err := client.VerifyCredentials()
+	if err != nil {
+		log.Error("Failed to fetch bot account: ", err)
+		recordError(mastodonKey, err)
+		return
+	}
+	if err := db.RecordFollowerCount(account.FollowersCount); err != nil {
+		log.Error("Failed to record follower count: ", err)
+	}
+
+	ids, err := db.RecentStatusIDs(time.Now().Add(-engagementWindow))
+	if err != nil {
+		log.Error("Failed to read recent statuses: ", err)
+		return
+	}
+	for _, id := range ids {
+		status, err := client.GetStatus(id)
+		if err != nil {
+			// e.g. the status was deleted
+			log.Debugf("Failed to fetch status %s: %v", id, err)
+			continue
+		}
+		if err := db.UpdateStatusEngagement(id, status.FavouritesCount, status.ReblogsCount, status.RepliesCount); err != nil {
+			log.Error("Failed to record status engagement: ", err)
+		}
+	}
+	log.Debugf("Recorded %d followers and engagement of %d statuses", account.FollowersCount, len(ids))
+
+	if err := db.SetState(engagementLastKey, time.Now().UTC().Format(time.RFC3339)); err != nil {
+		log.Error("Failed to record engagement check: ", err)
+	}
+	sendEngagementDigest()
+}
+
+// sendEngagementDigest notifies of the favourites, boosts and replies the bot's statuses received
+// and the change in followers, once every engagement_digest_interval. The first digest covers
+// what was received after the digest was enabled.
+func sendEngagementDigest() {
+	interval := viper.GetDuration("engagement_digest_interval")
+	if interval <= 0 {
+		return
+	}
+
+	last, ok, err := db.GetState(engagementDigestLastKey)
+	if err != nil {
+		log.Error("Failed to read last engagement digest: ", err)
+		return
+	}
+	var since time.Time
+	if ok {
+		if since, err = time.Parse(time.RFC3339, last); err == nil && time.Since(since) < interval {
+			return
+		}
+	}
+
+	if ok {
+		changes, err := db.EngagementSinceDigest()
+		if err != nil {
+			log.Error("Failed to read engagement: ", err)
+			return
+		}
+		counts, err := db.FollowerCounts(since)
+		if err != nil {
+			log.Error("Failed to read follower counts: ", err)
+		}
+		if message := engagementDigest(changes, counts); message != "" {
+			if err := notify.Send("rss2mastodon: engagement digest", message); err != nil {
+				// keep the engagement for the next attempt
+				log.Error("Failed to send engagement digest: ", err)
+				return
+			}
+		}
+	}
+
+	if err := db.MarkEngagementDigested(); err != nil {
+		log.Error("Failed to record digested engagement: ", err)
+		return
+	}
+	if err := db.SetState(engagementDigestLastKey, time.Now().UTC().Format(time.RFC3339)); err != nil {
+		log.Error("Failed to record engagement digest: ", err)
+	}
+}
+
+// engagementDigest describes the engagement received by the bot's statuses, most engaged first,
+// and the change in followers, or returns "" if nothing changed
+func engagementDigest(changes []db.EngagementChange, counts []db.FollowerCount) string {
+	var lines []string
+	if len(changes) > 0 {
+		var total db.EngagementChange
+		for _, change := range changes {
+			total.Favourites += change.Favourites
+			total.Reblogs += change.Reblogs
+			total.Replies += change.Replies
+		}
+		lines = append(lines, fmt.Sprintf("%s on %s", engagementCounts(total), plural(len(changes), "post")))
+	}
+	if len(counts) > 0 {
+		first, latest := counts[0].Followers, counts[len(counts)-1].Followers
+		if latest != first {
+			lines = append(lines, fmt.Sprintf("Followers: %d (%+d)", latest, latest-first))
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+
+	for i, change := range changes {
+		if i == engagementDigestPosts {
+			lines = append(lines, fmt.Sprintf("and %d more", len(changes)-i))
+			break
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", change.Link, engagementCounts(change)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// engagementCounts lists the favourites, boosts and replies received, e.g. "3 favourites and 1 boost"
+func engagementCounts(change db.EngagementChange) string {
+	var counts []string
+	for _, count := range []struct {
+		n    int
+		noun string
+	}{{change.Favourites, "favourite"}, {change.Reblogs, "boost"}, {change.Replies, "reply"}} {
+		if count.n > 0 {
+			counts = append(counts, plural(count.n, count.noun))
+		}
+	}
+	if len(counts) < 2 {
+		return strings.Join(counts, "")
+	}
+	return strings.Join(counts[:len(counts)-1], ", ") + " and " + counts[len(counts)-1]
+}
+
+// followerGrowth returns the latest follower count and its change since the oldest count
+// recorded within the given period
+func followerGrowth(counts []db.FollowerCount, period time.Duration) (int, int) {
+	if len(counts) == 0 {
+		return 0, 0
+	}
+	latest := counts[len(counts)-1]
+	for _, count := range counts {
+		if latest.Timestamp.Sub(count.Timestamp) <= period {
+			return latest.Followers, latest.Followers - count.Followers
+		}
+	}
+	return latest.Followers, 0
+}
+
+// ShowStats prints the bot account's follower growth and the engagement per announcement style
+func ShowStats(cmd *cobra.Command, args []string) {
+	OpenDB()
+	defer db.CloseDB()
+
+	counts, err := db.FollowerCounts(time.Now().Add(-engagementWindow))
+	if err != nil {
+		log.Fatal("Failed to read follower counts: ", err)
+	}
+	if len(counts) == 0 {
+		fmt.Println("No follower counts recorded yet, enable --engagement-interval")
+	} else {
+		followers, week := followerGrowth(counts, 7*24*time.Hour)
+		_, month := followerGrowth(counts, engagementWindow)
+		fmt.Printf("Followers: %d (%+d in 7 days, %+d in 30 days)\n", followers, week, month)
+	}
+
+	styles, err := db.EngagementByStyle()
+	if err != nil {
+		log.Fatal("Failed to read engagement: ", err)
+	}
+	if len(styles) == 0 {
+		return
+	}
+	fmt.Println()
+	fmt.Println("Style\tStatuses\tFavourites\tBoosts\tReplies")
+	for _, style := range styles {
+		fmt.Printf("%s\t%d\t%.1f\t%.1f\t%.1f\n", style.Style, style.Statuses, style.Favourites, style.Reblogs, style.Replies)
+	}
+}
+
+// plural returns a count with its noun, e.g. "1 boost", "2 boosts" or "3 replies"
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	if strings.HasSuffix(noun, "y") {
+		noun = strings.TrimSuffix(noun, "y") + "ie"
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
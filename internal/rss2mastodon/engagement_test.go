@@ -0,0 +1,208 @@
+package rss2mastodon
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+	"github.com/toozej/rss2mastodon/internal/mastodon"
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// Table-driven test for naming announcement styles
+func TestAnnouncementStyle(t *testing.T) {
+	tests := []struct {
+		name     string
+		title    string
+		mediaIDs []string
+		expected string
+	}{
+		{"New post", "Hello", nil, "new post"},
+		{"New post with media", "Hello", []string{"1"}, "new post with media"},
+		{"Thoughts", "Thoughts on Go", nil, "thoughts"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			style := announcementStyle(rss.RSSItem{Title: tt.title}, mastodon.TootOptions{MediaIDs: tt.mediaIDs})
+			if style != tt.expected {
+				t.Errorf("Expected '%s', got '%s'", tt.expected, style)
+			}
+		})
+	}
+}
+
+// Table-driven test for follower growth over a period
+func TestFollowerGrowth(t *testing.T) {
+	now := time.Now()
+	counts := []db.FollowerCount{
+		{Followers: 10, Timestamp: now.Add(-20 * 24 * time.Hour)},
+		{Followers: 15, Timestamp: now.Add(-5 * 24 * time.Hour)},
+		{Followers: 18, Timestamp: now},
+	}
+
+	tests := []struct {
+		name              string
+		counts            []db.FollowerCount
+		period            time.Duration
+		expectedFollowers int
+		expectedGrowth    int
+	}{
+		{"No counts", nil, time.Hour, 0, 0},
+		{"Week", counts, 7 * 24 * time.Hour, 18, 3},
+		{"Month", counts, 30 * 24 * time.Hour, 18, 8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			followers, growth := followerGrowth(tt.counts, tt.period)
+			if followers != tt.expectedFollowers || growth != tt.expectedGrowth {
+				t.Errorf("Expected %d (%+d), got %d (%+d)", tt.expectedFollowers, tt.expectedGrowth, followers, growth)
+			}
+		})
+	}
+}
+
+// Test follower counts and status engagement are recorded once per interval
+func TestTrackEngagement(t *testing.T) {
+	db.InitDB()
+	defer db.CloseDB()
+	defer os.Remove("./tooted_posts.db")
+
+	requests := 0
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Path {
+		case "/api/v1/accounts/verify_credentials":
+			_, _ = w.Write([]byte(`{"id":"1","acct":"bot","followers_count":42}`))
+		case "/api/v1/statuses/100":
+			_, _ = w.Write([]byte(`{"id":"100","favourites_count":3,"reblogs_count":2,"replies_count":1}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer mockServer.Close()
+
+	viper.Reset()
+	viper.Set("mastodon_url", mockServer.URL)
+	viper.Set("mastodon_access_token", "fake-token")
+	viper.Set("engagement_interval", time.Hour)
+	defer viper.Reset()
+
+	recordStatus(&mastodon.Status{ID: "100"}, "https://example.com/a", styleNewPost)
+	recordStatus(&mastodon.Status{ID: "101"}, "https://example.com/deleted", styleThoughts)
+	recordStatus(nil, "https://example.com/failed", styleNewPost)
+
+	trackEngagement()
+	trackEngagement()
+
+	if requests != 3 {
+		t.Errorf("Expected 3 requests in a single check, got %d", requests)
+	}
+	counts, err := db.FollowerCounts(time.Now().Add(-time.Hour))
+	if err != nil || len(counts) != 1 || counts[0].Followers != 42 {
+		t.Errorf("Expected a follower count of 42, got %v, %v", counts, err)
+	}
+	styles, err := db.EngagementByStyle()
+	if err != nil || len(styles) != 1 {
+		t.Fatalf("Expected engagement of a single style, got %v, %v", styles, err)
+	}
+	if styles[0].Style != styleNewPost || styles[0].Favourites != 3 || styles[0].Reblogs != 2 || styles[0].Replies != 1 {
+		t.Errorf("Unexpected engagement: %+v", styles[0])
+	}
+}
+
+// Table-driven test for describing the engagement received since the last digest
+func TestEngagementDigest(t *testing.T) {
+	now := time.Now()
+	followers := []db.FollowerCount{{Followers: 40, Timestamp: now.Add(-time.Hour)}, {Followers: 42, Timestamp: now}}
+	unchanged := []db.FollowerCount{{Followers: 40, Timestamp: now}}
+	var many []db.EngagementChange
+	manyLines := []string{"12 favourites on 12 posts"}
+	for i := 0; i < engagementDigestPosts+2; i++ {
+		many = append(many, db.EngagementChange{Link: fmt.Sprintf("https://example.com/%d", i), Favourites: 1})
+		if i < engagementDigestPosts {
+			manyLines = append(manyLines, fmt.Sprintf("https://example.com/%d: 1 favourite", i))
+		}
+	}
+	manyLines = append(manyLines, "and 2 more")
+
+	tests := []struct {
+		name     string
+		changes  []db.EngagementChange
+		counts   []db.FollowerCount
+		expected string
+	}{
+		{"Nothing new", nil, unchanged, ""},
+		{"New followers only", nil, followers, "Followers: 42 (+2)"},
+		{
+			"Engagement",
+			[]db.EngagementChange{
+				{Link: "https://example.com/a", Favourites: 3, Reblogs: 1, Replies: 2},
+				{Link: "https://example.com/b", Replies: 1},
+			},
+			unchanged,
+			"3 favourites, 1 boost and 3 replies on 2 posts\nhttps://example.com/a: 3 favourites, 1 boost and 2 replies\nhttps://example.com/b: 1 reply",
+		},
+		{"Long list", many, nil, strings.Join(manyLines, "\n")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := engagementDigest(tt.changes, tt.counts); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+// Test the first engagement digest only covers engagement received after it was enabled
+func TestSendEngagementDigest(t *testing.T) {
+	db.InitDB()
+	defer db.CloseDB()
+	defer os.Remove("./tooted_posts.db")
+
+	var messages []string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		messages = append(messages, string(body))
+	}))
+	defer mockServer.Close()
+
+	viper.Reset()
+	viper.Set("ntfy_url", mockServer.URL)
+	viper.Set("engagement_digest_interval", time.Hour)
+	defer viper.Reset()
+
+	recordStatus(&mastodon.Status{ID: "200"}, "https://example.com/a", styleNewPost)
+	if err := db.UpdateStatusEngagement("200", 5, 0, 0); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	sendEngagementDigest()
+	if len(messages) != 0 {
+		t.Fatalf("Expected no digest of earlier engagement, got %q", messages)
+	}
+
+	if err := db.UpdateStatusEngagement("200", 7, 1, 0); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// not due yet
+	sendEngagementDigest()
+	if err := db.SetState(engagementDigestLastKey, time.Now().Add(-2*time.Hour).UTC().Format(time.RFC3339)); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	sendEngagementDigest()
+
+	expected := "2 favourites and 1 boost on 1 post\nhttps://example.com/a: 2 favourites and 1 boost"
+	if len(messages) != 1 || messages[0] != expected {
+		t.Errorf("Expected the digest %q, got %q", expected, messages)
+	}
+}
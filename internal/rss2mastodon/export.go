@@ -0,0 +1,118 @@
+package rss2mastodon
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+)
+
+// activityStreamsPublic addresses an activity to everyone
+const activityStreamsPublic = "https://www.w3.org/ns/activitystreams#Public"
+
+// outbox is an ActivityPub outbox collection, as found in a Mastodon account archive's outbox.json
+type outbox struct {
+	Context      string     `json:"@context"`
+	ID           string     `json:"id"`
+	Type         string     `json:"type"`
+	TotalItems   int        `json:"totalItems"`
+	OrderedItems []activity `json:"orderedItems"`
+}
+
+type activity struct {
+	ID        string   `json:"id"`
+	Type      string   `json:"type"`
+	Actor     string   `json:"actor,omitempty"`
+	Published string   `json:"published"`
+	To        []string `json:"to"`
+	Object    note     `json:"object"`
+}
+
+type note struct {
+	ID           string   `json:"id"`
+	Type         string   `json:"type"`
+	AttributedTo string   `json:"attributedTo,omitempty"`
+	Published    string   `json:"published"`
+	URL          string   `json:"url"`
+	To           []string `json:"to"`
+	Content      string   `json:"content"`
+}
+
+// buildOutbox converts the announcement history into an outbox of Create activities, oldest
+// first. Posts are matched with the status which last announced them, if one was recorded.
+func buildOutbox(posts []db.TootedPost, statuses []db.Status, actor string) outbox {
+	announced := map[string]db.Status{}
+	for _, status := range statuses {
+		if status.Link != "" {
+			announced[status.Link] = status
+		}
+	}
+
+	collection := outbox{
+		Context: "https://www.w3.org/ns/activitystreams",
+		ID:      "outbox.json",
+		Type:    "OrderedCollection",
+	}
+	for _, post := range posts {
+		id, published := post.Link, post.Timestamp
+		if status, ok := announced[post.Link]; ok && status.URL != "" {
+			id, published = status.URL, status.CreatedAt
+		}
+		link := html.EscapeString(post.Link)
+		collection.OrderedItems = append(collection.OrderedItems, activity{
+			ID:        id + "/activity",
+			Type:      "Create",
+			Actor:     actor,
+			Published: published.UTC().Format(time.RFC3339),
+			To:        []string{activityStreamsPublic},
+			Object: note{
+				ID:           id,
+				Type:         "Note",
+				AttributedTo: actor,
+				Published:    published.UTC().Format(time.RFC3339),
+				URL:          id,
+				To:           []string{activityStreamsPublic},
+				Content:      fmt.Sprintf(`<p><a href="%s">%s</a></p>`, link, link),
+			},
+		})
+	}
+	collection.TotalItems = len(collection.OrderedItems)
+	return collection
+}
+
+// Export writes the announcement history as an ActivityPub outbox, e.g. to migrate it to another system
+func Export(cmd *cobra.Command, args []string) {
+	output, _ := cmd.Flags().GetString("output")
+	actor, _ := cmd.Flags().GetString("actor")
+
+	OpenDB()
+	defer db.CloseDB()
+
+	posts, err := db.TootedPostsBetween(time.Time{}, time.Now())
+	if err != nil {
+		log.Fatal("Failed to read tooted posts: ", err)
+	}
+	statuses, err := db.ListStatuses()
+	if err != nil {
+		log.Fatal("Failed to read statuses: ", err)
+	}
+
+	data, err := json.MarshalIndent(buildOutbox(posts, statuses, actor), "", "  ")
+	if err != nil {
+		log.Fatal("Failed to encode outbox: ", err)
+	}
+	if output == "-" {
+		fmt.Println(string(data))
+		return
+	}
+	if err := os.WriteFile(output, data, 0o644); err != nil {
+		log.Fatal("Failed to write outbox: ", err)
+	}
+	log.Infof("Exported %d posts to %s", len(posts), output)
+}
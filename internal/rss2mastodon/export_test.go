@@ -0,0 +1,68 @@
+package rss2mastodon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+)
+
+// Test building an outbox from the announcement history
+func TestBuildOutbox(t *testing.T) {
+	tooted := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	announced := tooted.Add(time.Minute)
+	posts := []db.TootedPost{
+		{Link: "https://example.com/a?x=1&y=2", Timestamp: tooted},
+		{Link: "https://example.com/b", Timestamp: tooted},
+	}
+	statuses := []db.Status{
+		{ID: "1", URL: "https://example.social/@bot/1", Link: "https://example.com/a?x=1&y=2", CreatedAt: announced},
+		{ID: "2", Style: styleDigest, CreatedAt: announced},
+	}
+
+	collection := buildOutbox(posts, statuses, "https://example.social/users/bot")
+
+	if collection.Type != "OrderedCollection" || collection.TotalItems != 2 || len(collection.OrderedItems) != 2 {
+		t.Fatalf("Unexpected collection: %+v", collection)
+	}
+
+	tests := []struct {
+		name              string
+		item              activity
+		expectedID        string
+		expectedPublished string
+		expectedContent   string
+	}{
+		{
+			name:              "Post with a recorded status",
+			item:              collection.OrderedItems[0],
+			expectedID:        "https://example.social/@bot/1",
+			expectedPublished: "2024-05-01T12:01:00Z",
+			expectedContent:   `<p><a href="https://example.com/a?x=1&amp;y=2">https://example.com/a?x=1&amp;y=2</a></p>`,
+		},
+		{
+			name:              "Post without a recorded status",
+			item:              collection.OrderedItems[1],
+			expectedID:        "https://example.com/b",
+			expectedPublished: "2024-05-01T12:00:00Z",
+			expectedContent:   `<p><a href="https://example.com/b">https://example.com/b</a></p>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.item.Type != "Create" || tt.item.Object.Type != "Note" || tt.item.Actor != "https://example.social/users/bot" {
+				t.Errorf("Unexpected activity: %+v", tt.item)
+			}
+			if tt.item.Object.ID != tt.expectedID || tt.item.ID != tt.expectedID+"/activity" {
+				t.Errorf("Expected ID '%s', got '%s' (%s)", tt.expectedID, tt.item.Object.ID, tt.item.ID)
+			}
+			if tt.item.Published != tt.expectedPublished {
+				t.Errorf("Expected published '%s', got '%s'", tt.expectedPublished, tt.item.Published)
+			}
+			if tt.item.Object.Content != tt.expectedContent {
+				t.Errorf("Expected content '%s', got '%s'", tt.expectedContent, tt.item.Object.Content)
+			}
+		})
+	}
+}
@@ -0,0 +1,18 @@
+package rss2mastodon
+
+import (
+	log "github.com/sirupsen/logrus"
+
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// feedLogger returns the logger of a feed, whose lines carry the feed's URL, so the lines about
+// its items can be told apart without repeating the feed in every message
+func feedLogger(feedURL string) *log.Entry {
+	return log.WithField("url", feedURL)
+}
+
+// withFeedName adds the name of a fetched feed, its channel title, to the feed's logger
+func withFeedName(logger *log.Entry, feed *rss.RSSFeed, feedURL string) *log.Entry {
+	return logger.WithField("feed", sourceName(feed, feedURL))
+}
@@ -0,0 +1,34 @@
+package rss2mastodon
+
+import (
+	"testing"
+
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// Table-driven test for the fields of feed loggers
+func TestFeedLogger(t *testing.T) {
+	tests := []struct {
+		name         string
+		title        string
+		expectedName string
+	}{
+		{"Channel title", "Example Blog", "Example Blog"},
+		{"No title", "", "example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed := &rss.RSSFeed{}
+			feed.Channel.Title = tt.title
+
+			logger := withFeedName(feedLogger("https://example.com/feed.xml"), feed, "https://example.com/feed.xml")
+			if logger.Data["url"] != "https://example.com/feed.xml" {
+				t.Errorf("Expected the feed's URL, got %v", logger.Data["url"])
+			}
+			if logger.Data["feed"] != tt.expectedName {
+				t.Errorf("Expected feed name %q, got %v", tt.expectedName, logger.Data["feed"])
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package rss2mastodon
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+var (
+	// httpMethodRegex matches HTTP methods, e.g. GET or POST
+	httpMethodRegex = regexp.MustCompile(`^[A-Z]+$`)
+	// headerNameRegex matches HTTP header names, e.g. content-type
+	headerNameRegex = regexp.MustCompile(`^[A-Za-z0-9!#$%&'*+.^_|~-]+$`)
+)
+
+// customRequest reports whether the request fetching the feed is customized with the method,
+// body, body_file or header_ options, or its response is mapped to items
+func (f FeedConfig) customRequest() bool {
+	return f.Method != "" || f.Body != "" || f.BodyFile != "" || len(f.Headers) > 0 || !f.Mapping.IsZero()
+}
+
+// validateRequest checks the options customizing the request fetching the feed
+func (f FeedConfig) validateRequest() error {
+	if !f.customRequest() {
+		return nil
+	}
+	if f.Type != "" && f.Type != "rss" {
+		return fmt.Errorf("the method, body, body_file, header_, items and item_ options only apply to rss feeds")
+	}
+	if f.Method != "" && !httpMethodRegex.MatchString(f.Method) {
+		return fmt.Errorf("invalid method %q, expected e.g. GET or POST", f.Method)
+	}
+	if f.Body != "" && f.BodyFile != "" {
+		return fmt.Errorf("only one of body and body_file may be set")
+	}
+	if f.BodyFile != "" {
+		if _, err := os.Stat(f.BodyFile); err != nil {
+			return fmt.Errorf("invalid body_file: %w", err)
+		}
+	}
+	for name := range f.Headers {
+		if !headerNameRegex.MatchString(name) {
+			return fmt.Errorf("invalid header name %q", name)
+		}
+	}
+	return f.Mapping.Validate()
+}
+
+// request returns the customized request fetching the feed, reading its body_file every time
+// so changes to it are picked up without restarting
+func (f FeedConfig) request() (rss.Request, error) {
+	request := rss.Request{Method: f.Method, Body: f.Body, Headers: f.Headers, Mapping: f.Mapping}
+	if f.BodyFile != "" {
+		body, err := os.ReadFile(f.BodyFile)
+		if err != nil {
+			return request, fmt.Errorf("failed to read the request body: %w", err)
+		}
+		request.Body = string(body)
+	}
+	return request, nil
+}
@@ -0,0 +1,141 @@
+package rss2mastodon
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// Table-driven test for parsing and validating the options customizing a feed's request
+func TestParseFeedFlag_Request(t *testing.T) {
+	bodyFile := filepath.Join(t.TempDir(), "query.json")
+	if err := os.WriteFile(bodyFile, []byte(`{"query":"{ posts { title, url } }"}`), 0o600); err != nil {
+		t.Fatalf("Failed to write body file: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		definition    string
+		expected      FeedConfig
+		expectedError bool
+	}{
+		{
+			name:       "Method, body and headers",
+			definition: "url=https://example.com/api,method=post,body=q=posts,header_Content-Type=application/x-www-form-urlencoded,header_x-api-key=secret",
+			expected: FeedConfig{URL: "https://example.com/api", Method: "POST", Body: "q=posts",
+				Headers: map[string]string{"content-type": "application/x-www-form-urlencoded", "x-api-key": "secret"}},
+		},
+		{
+			name:       "Body file",
+			definition: "url=https://example.com/graphql,method=POST,body_file=" + bodyFile,
+			expected:   FeedConfig{URL: "https://example.com/graphql", Method: "POST", BodyFile: bodyFile},
+		},
+		{
+			name:          "Invalid method",
+			definition:    "url=https://example.com/api,method=PO ST",
+			expectedError: true,
+		},
+		{
+			name:          "Body and body file",
+			definition:    "url=https://example.com/api,body=q,body_file=" + bodyFile,
+			expectedError: true,
+		},
+		{
+			name:          "Missing body file",
+			definition:    "url=https://example.com/api,body_file=/nonexistent/query.json",
+			expectedError: true,
+		},
+		{
+			name:          "Invalid header name",
+			definition:    "url=https://example.com/api,header_x api=secret",
+			expectedError: true,
+		},
+		{
+			name:       "Mapped response",
+			definition: "url=https://example.com/api/posts,items=data.posts,item_title=title,item_link=links.0.href,item_date=published",
+			expected: FeedConfig{URL: "https://example.com/api/posts",
+				Mapping: rss.Mapping{Items: "data.posts", Title: "title", Link: "links.0.href", Date: "published"}},
+		},
+		{
+			name:          "Mapped response without links",
+			definition:    "url=https://example.com/api/posts,items=data.posts,item_title=title",
+			expectedError: true,
+		},
+		{
+			name:          "Other source type",
+			definition:    "url=https://github.com/owner/repo,type=github,method=POST",
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed, err := ParseFeedFlag(tt.definition)
+			if (err != nil) != tt.expectedError {
+				t.Fatalf("Expected error %v, got %v", tt.expectedError, err)
+			}
+			if !tt.expectedError && !reflect.DeepEqual(feed, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, feed)
+			}
+		})
+	}
+}
+
+// Test fetching a feed with a customized request
+func TestFetch_CustomRequest(t *testing.T) {
+	bodyFile := filepath.Join(t.TempDir(), "query.json")
+	if err := os.WriteFile(bodyFile, []byte(`{"query":"{ posts }"}`), 0o600); err != nil {
+		t.Fatalf("Failed to write body file: %v", err)
+	}
+
+	var method, body, contentType, apiKey string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, contentType, apiKey = r.Method, r.Header.Get("Content-Type"), r.Header.Get("X-Api-Key")
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		w.Header().Set("Content-Type", "application/feed+json")
+		_, _ = w.Write([]byte(`{"version":"https://jsonfeed.org/version/1.1","items":[{"id":"1","url":"https://example.com/1","title":"One"}]}`))
+	}))
+	defer mockServer.Close()
+
+	feed := FeedConfig{URL: mockServer.URL, Method: "POST", BodyFile: bodyFile,
+		Headers: map[string]string{"content-type": "application/json", "x-api-key": "secret"}}
+	result, validators, err := feed.fetchIfModified(time.Now())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(result.Channel.Items) != 1 || result.Channel.Items[0].Link != "https://example.com/1" {
+		t.Errorf("Expected the API's item, got %+v", result.Channel.Items)
+	}
+	if validators != nil {
+		t.Errorf("Expected no conditional request, got validators %+v", validators)
+	}
+	if method != "POST" || body != `{"query":"{ posts }"}` || contentType != "application/json" || apiKey != "secret" {
+		t.Errorf("Expected the customized request, got %s %q with Content-Type %q and X-Api-Key %q", method, body, contentType, apiKey)
+	}
+}
+
+// Test fetching an API whose JSON response is mapped to feed items
+func TestFetch_MappedResponse(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":{"posts":[{"id":1,"title":"One","url":"https://example.com/1"}]}}`))
+	}))
+	defer mockServer.Close()
+
+	feed := FeedConfig{URL: mockServer.URL, Mapping: rss.Mapping{Items: "data.posts", Title: "title", Link: "url", GUID: "id"}}
+	result, _, err := feed.fetchIfModified(time.Now())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(result.Channel.Items) != 1 || result.Channel.Items[0].Link != "https://example.com/1" || result.Channel.Items[0].GUID != "1" {
+		t.Errorf("Expected the API's item, got %+v", result.Channel.Items)
+	}
+}
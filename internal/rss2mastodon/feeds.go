@@ -0,0 +1,383 @@
+package rss2mastodon
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+	"github.com/toozej/rss2mastodon/internal/rss"
+	"github.com/toozej/rss2mastodon/internal/source"
+)
+
+// FeedConfig is a feed to poll along with its per-feed options
+type FeedConfig struct {
+	URL string `mapstructure:"url"`
+	// Category only announces items with this RSS category, if set
+	Category string `mapstructure:"category"`
+	// Visibility of the feed's toots (public, unlisted, private or direct), the account default if empty
+	Visibility string `mapstructure:"visibility"`
+	// Planet attributes the feed's posts to the blog they came from
+	Planet bool `mapstructure:"planet"`
+	// Type selects a source other than an RSS feed, e.g. github for a repository's releases
+	Type string `mapstructure:"type"`
+	// Dedup is what tells items apart, link or title_date, the dedup setting if empty
+	Dedup string `mapstructure:"dedup"`
+	// LinkParams are query parameters appended to the links in the feed's toots, the
+	// link_params setting if empty
+	LinkParams string `mapstructure:"link_params"`
+	// Schedule limits the days and hours the feed's posts are announced at, e.g. weekdays 8-22,
+	// the posting_schedule setting if empty
+	Schedule string `mapstructure:"schedule"`
+	// Language is the ISO 639 code the feed's toots are tagged with, the language setting if empty
+	Language string `mapstructure:"language"`
+	// LocalOnly keeps the feed's toots from federating, the local_only setting if unset
+	LocalOnly *bool `mapstructure:"local_only"`
+	// Enclosures uploads the video and audio enclosures of the feed's posts as attachments, the
+	// upload_enclosures setting if unset
+	Enclosures *bool `mapstructure:"enclosures"`
+	// Priority is high, normal or low, selecting how often the feed is polled and posting its
+	// items before those of lower priority feeds; normal if empty
+	Priority string `mapstructure:"priority"`
+	// Method, Body (or BodyFile, a file holding it) and Headers customize the request fetching
+	// an RSS feed, e.g. for APIs queried with POST
+	Method   string            `mapstructure:"method"`
+	Body     string            `mapstructure:"body"`
+	BodyFile string            `mapstructure:"body_file"`
+	Headers  map[string]string `mapstructure:"headers"`
+	// Mapping converts an API's JSON or XML responses which aren't feeds into items
+	Mapping rss.Mapping `mapstructure:",squash"`
+	// Options are the options specific to the source type
+	Options map[string]string `mapstructure:"options"`
+}
+
+// visibilities are the status visibilities Mastodon accepts
+var visibilities = map[string]bool{"public": true, "unlisted": true, "private": true, "direct": true}
+
+// ParseFeedFlag parses a --feed definition of comma-separated key=value options,
+// e.g. "url=https://example.com/rss,category=go,visibility=unlisted". Options other
+// than the common ones are passed to the source type.
+func ParseFeedFlag(definition string) (FeedConfig, error) {
+	var feed FeedConfig
+	for _, option := range strings.Split(definition, ",") {
+		option = strings.TrimSpace(option)
+		if option == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(option, "=")
+		if !ok {
+			return feed, fmt.Errorf("invalid feed option %q, expected key=value", option)
+		}
+		value = strings.TrimSpace(value)
+
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "url":
+			feed.URL = value
+		case "category":
+			feed.Category = value
+		case "visibility":
+			feed.Visibility = strings.ToLower(value)
+		case "planet":
+			feed.Planet = value == "true"
+		case "type":
+			feed.Type = strings.ToLower(value)
+		case "dedup":
+			feed.Dedup = strings.ToLower(value)
+		case "link_params":
+			feed.LinkParams = value
+		case "schedule":
+			feed.Schedule = value
+		case "language":
+			feed.Language = strings.ToLower(value)
+		case "local_only":
+			localOnly, err := strconv.ParseBool(value)
+			if err != nil {
+				return feed, fmt.Errorf("invalid local_only %q, expected true or false", value)
+			}
+			feed.LocalOnly = &localOnly
+		case "enclosures":
+			enclosures, err := strconv.ParseBool(value)
+			if err != nil {
+				return feed, fmt.Errorf("invalid enclosures %q, expected true or false", value)
+			}
+			feed.Enclosures = &enclosures
+		case "priority":
+			feed.Priority = strings.ToLower(value)
+		case "method":
+			feed.Method = strings.ToUpper(value)
+		case "body":
+			feed.Body = value
+		case "body_file":
+			feed.BodyFile = value
+		case "items":
+			feed.Mapping.Items = value
+		case "item_title":
+			feed.Mapping.Title = value
+		case "item_link":
+			feed.Mapping.Link = value
+		case "item_guid":
+			feed.Mapping.GUID = value
+		case "item_date":
+			feed.Mapping.Date = value
+		case "item_content":
+			feed.Mapping.Content = value
+		case "item_categories":
+			feed.Mapping.Categories = value
+		default:
+			if name, ok := strings.CutPrefix(strings.ToLower(strings.TrimSpace(key)), "header_"); ok {
+				if feed.Headers == nil {
+					feed.Headers = map[string]string{}
+				}
+				feed.Headers[name] = value
+				continue
+			}
+			if feed.Options == nil {
+				feed.Options = map[string]string{}
+			}
+			feed.Options[strings.ToLower(strings.TrimSpace(key))] = value
+		}
+	}
+	return feed, feed.validate()
+}
+
+// validate checks the feed's options
+func (f FeedConfig) validate() error {
+	if f.URL == "" {
+		return fmt.Errorf("feed URL is required")
+	}
+	if f.Visibility != "" && !visibilities[f.Visibility] {
+		return fmt.Errorf("invalid visibility %q for %s", f.Visibility, f.URL)
+	}
+	if f.Dedup != "" && f.Dedup != dedupLink && f.Dedup != dedupTitleDate {
+		return fmt.Errorf("invalid dedup %q for %s, expected %s or %s", f.Dedup, f.URL, dedupLink, dedupTitleDate)
+	}
+	if err := validatePriority(f.Priority); err != nil {
+		return fmt.Errorf("%w for %s", err, f.URL)
+	}
+	if f.Language != "" && !languageCodeRegex.MatchString(f.Language) {
+		return fmt.Errorf("invalid language %q for %s, expected an ISO 639 code like en or de", f.Language, f.URL)
+	}
+	if _, err := parseSchedule(f.Schedule); err != nil {
+		return fmt.Errorf("invalid schedule for %s: %w", f.URL, err)
+	}
+	if _, err := url.ParseQuery(f.LinkParams); err != nil {
+		return fmt.Errorf("invalid link_params %q for %s: %w", f.LinkParams, f.URL, err)
+	}
+	if err := f.validateRequest(); err != nil {
+		return fmt.Errorf("%w for %s", err, f.URL)
+	}
+	if f.Type == "" || f.Type == "rss" {
+		for key := range f.Options {
+			return fmt.Errorf("unknown feed option %q", key)
+		}
+		return nil
+	}
+	src, ok := source.Get(f.Type)
+	if !ok {
+		return fmt.Errorf("unknown feed type %q, expected one of rss, %s", f.Type, strings.Join(source.Names(), ", "))
+	}
+	return src.Validate(f.Options)
+}
+
+// fetch fetches the feed, or the items of another type of source as a feed, expanding
+// date placeholders in its URL
+func (f FeedConfig) fetch(now time.Time) (*rss.RSSFeed, error) {
+	feedURL := expandFeedURL(f.URL, now)
+	if (f.Type == "" || f.Type == "rss") && f.customRequest() {
+		request, err := f.request()
+		if err != nil {
+			return nil, err
+		}
+		return rss.FetchFeedRequest(feedURL, request)
+	}
+	if f.Type == "" || f.Type == "rss" {
+		return rss.FetchFeed(feedURL)
+	}
+	src, ok := source.Get(f.Type)
+	if !ok {
+		return nil, fmt.Errorf("unknown feed type %q", f.Type)
+	}
+	return src.Fetch(source.Config{URL: feedURL, Options: f.Options})
+}
+
+// feedValidators are the validators of a feed's response, stored once its items are handled
+type feedValidators struct {
+	requestURL string
+	validators rss.Validators
+}
+
+// fetchIfModified fetches the feed like fetch, but RSS feeds which haven't changed since their
+// last stored response return rss.ErrNotModified, unless conditional_get is off or their request
+// is customized. The validators of the response are returned to be stored once the feed's items
+// have been handled, so items which have to be retried are fetched again.
+func (f FeedConfig) fetchIfModified(now time.Time) (*rss.RSSFeed, *feedValidators, error) {
+	if (f.Type != "" && f.Type != "rss") || !viper.GetBool("conditional_get") || f.customRequest() {
+		feed, err := f.fetch(now)
+		return feed, nil, err
+	}
+	feedURL := expandFeedURL(f.URL, now)
+	cached, err := db.GetFeedValidators(f.URL, feedURL)
+	if err != nil {
+		log.Error("Failed to look up the feed's ETag and Last-Modified: ", err)
+	}
+	feed, validators, err := rss.FetchFeedIfModified(feedURL, cached)
+	return feed, &feedValidators{requestURL: feedURL, validators: validators}, err
+}
+
+// watchedFeeds holds the URLs of the feeds whose source is being watched
+var watchedFeeds = map[string]bool{}
+
+// watchSources starts watching the configured feeds whose source supports it, e.g. local
+// directories, polling each feed as soon as it changes. Feeds already watched are skipped, so
+// it is called every cycle to pick up feeds added to the config directory.
+func watchSources() {
+	feeds, err := configuredFeeds()
+	if err != nil {
+		return
+	}
+	for _, feed := range feeds {
+		src, ok := source.Get(feed.Type)
+		if !ok || src.Watch == nil || watchedFeeds[feed.URL] {
+			continue
+		}
+		watchedFeeds[feed.URL] = true
+
+		feedURL := feed.URL
+		if err := src.Watch(source.Config{URL: feed.URL, Options: feed.Options}, func() { requestPoll(feedURL) }); err != nil {
+			log.Errorf("Failed to watch %s, it is only polled: %v", feed.URL, err)
+			continue
+		}
+		log.Infof("Watching %s for changes", feed.URL)
+	}
+}
+
+// includes reports whether an item of the feed should be announced, based on its category filter
+func (f FeedConfig) includes(post rss.RSSItem) bool {
+	if f.Category == "" {
+		return true
+	}
+	for _, category := range post.Categories {
+		if strings.EqualFold(strings.TrimSpace(category), f.Category) {
+			return true
+		}
+	}
+	return false
+}
+
+// configuredFeeds returns the feeds to poll: every feed_url and feeds URL, the planet member
+// feeds and every --feed definition. A URL listed more than once is only polled once.
+func configuredFeeds() ([]FeedConfig, error) {
+	var feeds []FeedConfig
+	listed := make(map[string]bool)
+	for _, feedURL := range append(listSetting("feed_url"), listSetting("feeds")...) {
+		if !listed[feedURL] {
+			listed[feedURL] = true
+			feeds = append(feeds, FeedConfig{URL: feedURL})
+		}
+	}
+	for _, feedURL := range planetFeeds() {
+		feeds = append(feeds, FeedConfig{URL: feedURL, Planet: true})
+	}
+	for _, definition := range viper.GetStringSlice("feed") {
+		feed, err := ParseFeedFlag(definition)
+		if err != nil {
+			return nil, err
+		}
+		feeds = append(feeds, feed)
+	}
+	return feeds, nil
+}
+
+// listSetting returns the values of a setting holding a list: repeated flags, a YAML list
+// (e.g. in the config directory) or a comma-separated string
+func listSetting(key string) []string {
+	var values []string
+	switch value := viper.Get(key).(type) {
+	case []string:
+		values = value
+	case []any:
+		for _, v := range value {
+			values = append(values, fmt.Sprint(v))
+		}
+	case string:
+		if err := yaml.Unmarshal([]byte(value), &values); err != nil {
+			values = strings.Split(value, ",")
+		}
+	}
+
+	var list []string
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// selectFeeds returns the feeds whose URL is among the given ones
+func selectFeeds(feeds []FeedConfig, urls map[string]bool) []FeedConfig {
+	var selected []FeedConfig
+	for _, feed := range feeds {
+		if urls[feed.URL] {
+			selected = append(selected, feed)
+		}
+	}
+	return selected
+}
+
+// feedConfig returns the options of the feed a post came from
+func feedConfig(feedURL string) FeedConfig {
+	feeds, _ := configuredFeeds()
+	for _, feed := range feeds {
+		if feed.URL == feedURL {
+			return feed
+		}
+	}
+	return FeedConfig{URL: feedURL}
+}
+
+// feedURLPlaceholderRegex matches the date placeholders of a feed URL, like {%Y}. The braces
+// keep them apart from percent-encoding, e.g. %B in m%C3%BCnchen.
+var feedURLPlaceholderRegex = regexp.MustCompile(`\{%([YymdHjbBGV])\}`)
+
+// expandFeedURL expands strftime-style date placeholders in braces in a feed URL, for sites
+// which shard their feeds by period (e.g. https://example.com/archive/{%Y}/{%m}/feed.xml)
+func expandFeedURL(feedURL string, now time.Time) string {
+	if !strings.Contains(feedURL, "{%") {
+		return feedURL
+	}
+
+	return feedURLPlaceholderRegex.ReplaceAllStringFunc(feedURL, func(placeholder string) string {
+		switch placeholder[2] {
+		case 'Y':
+			return now.Format("2006")
+		case 'y':
+			return now.Format("06")
+		case 'm':
+			return now.Format("01")
+		case 'd':
+			return now.Format("02")
+		case 'H':
+			return now.Format("15")
+		case 'j':
+			return fmt.Sprintf("%03d", now.YearDay())
+		case 'b':
+			return now.Format("Jan")
+		case 'B':
+			return now.Format("January")
+		case 'G':
+			year, _ := now.ISOWeek()
+			return fmt.Sprintf("%04d", year)
+		default:
+			_, week := now.ISOWeek()
+			return fmt.Sprintf("%02d", week)
+		}
+	})
+}
@@ -0,0 +1,372 @@
+package rss2mastodon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+)
+
+// Table-driven test for parsing --feed definitions
+func TestParseFeedFlag(t *testing.T) {
+	tests := []struct {
+		name          string
+		definition    string
+		expected      FeedConfig
+		expectedError bool
+	}{
+		{
+			name:       "All options",
+			definition: "url=https://example.com/rss, category=Go ,visibility=Unlisted,planet=true",
+			expected:   FeedConfig{URL: "https://example.com/rss", Category: "Go", Visibility: "unlisted", Planet: true},
+		},
+		{
+			name:       "URL only",
+			definition: "url=https://example.com/rss?a=b",
+			expected:   FeedConfig{URL: "https://example.com/rss?a=b"},
+		},
+		{
+			name:          "Missing URL",
+			definition:    "category=go",
+			expectedError: true,
+		},
+		{
+			name:          "Unknown option",
+			definition:    "url=https://example.com/rss,colour=blue",
+			expectedError: true,
+		},
+		{
+			name:       "Source type with options",
+			definition: "url=https://github.com/owner/repo,type=GitHub,prereleases=true",
+			expected:   FeedConfig{URL: "https://github.com/owner/repo", Type: "github", Options: map[string]string{"prereleases": "true"}},
+		},
+		{
+			name:          "Unknown source option",
+			definition:    "url=https://github.com/owner/repo,type=github,colour=blue",
+			expectedError: true,
+		},
+		{
+			name:          "Unknown source type",
+			definition:    "url=https://example.com/rss,type=gopher",
+			expectedError: true,
+		},
+		{
+			name:          "Invalid visibility",
+			definition:    "url=https://example.com/rss,visibility=secret",
+			expectedError: true,
+		},
+		{
+			name:       "Title and date dedup",
+			definition: "url=https://example.com/rss,dedup=Title_Date",
+			expected:   FeedConfig{URL: "https://example.com/rss", Dedup: "title_date"},
+		},
+		{
+			name:          "Invalid dedup",
+			definition:    "url=https://example.com/rss,dedup=guid",
+			expectedError: true,
+		},
+		{
+			name:       "Link params",
+			definition: "url=https://example.com/rss,link_params=utm_source=mastodon&utm_medium=social",
+			expected:   FeedConfig{URL: "https://example.com/rss", LinkParams: "utm_source=mastodon&utm_medium=social"},
+		},
+		{
+			name:          "Invalid link params",
+			definition:    "url=https://example.com/rss,link_params=utm_source=%zz",
+			expectedError: true,
+		},
+		{
+			name:       "Schedule",
+			definition: "url=https://example.com/rss,schedule=weekdays 8-22|sat 10-14",
+			expected:   FeedConfig{URL: "https://example.com/rss", Schedule: "weekdays 8-22|sat 10-14"},
+		},
+		{
+			name:          "Invalid schedule",
+			definition:    "url=https://example.com/rss,schedule=workdays",
+			expectedError: true,
+		},
+		{
+			name:       "Language",
+			definition: "url=https://example.com/rss,language=DE",
+			expected:   FeedConfig{URL: "https://example.com/rss", Language: "de"},
+		},
+		{
+			name:          "Invalid language",
+			definition:    "url=https://example.com/rss,language=german",
+			expectedError: true,
+		},
+		{
+			name:       "Local only",
+			definition: "url=https://example.com/rss,local_only=false",
+			expected:   FeedConfig{URL: "https://example.com/rss", LocalOnly: new(bool)},
+		},
+		{
+			name:          "Invalid local only",
+			definition:    "url=https://example.com/rss,local_only=maybe",
+			expectedError: true,
+		},
+		{
+			name:       "Enclosures",
+			definition: "url=https://example.com/rss,enclosures=false",
+			expected:   FeedConfig{URL: "https://example.com/rss", Enclosures: new(bool)},
+		},
+		{
+			name:          "Invalid enclosures",
+			definition:    "url=https://example.com/rss,enclosures=some",
+			expectedError: true,
+		},
+		{
+			name:       "Priority",
+			definition: "url=https://example.com/rss,priority=High",
+			expected:   FeedConfig{URL: "https://example.com/rss", Priority: "high"},
+		},
+		{
+			name:          "Invalid priority",
+			definition:    "url=https://example.com/rss,priority=urgent",
+			expectedError: true,
+		},
+		{
+			name:          "Not key=value",
+			definition:    "https://example.com/rss",
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed, err := ParseFeedFlag(tt.definition)
+			if (err != nil) != tt.expectedError {
+				t.Fatalf("Expected error: %v, got: %v", tt.expectedError, err)
+			}
+			if !tt.expectedError && !reflect.DeepEqual(feed, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, feed)
+			}
+		})
+	}
+}
+
+// Test per-feed category filters and visibility
+func TestPollFeeds_FeedOptions(t *testing.T) {
+	db.InitDB()
+	defer db.CloseDB()
+	defer os.Remove("./tooted_posts.db")
+
+	toots := make(map[string]string)
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/feed.xml":
+			_, _ = w.Write([]byte(`<rss><channel>
+				<item><title>Go</title><link>https://example.com/go</link><category>Go</category></item>
+				<item><title>Cooking</title><link>https://example.com/cooking</link><category>Food</category></item>
+			</channel></rss>`))
+		case "/api/v1/statuses":
+			toots[r.FormValue("status")] = r.FormValue("visibility")
+			_, _ = w.Write([]byte(`{"id":"1"}`))
+		}
+	}))
+	defer mockServer.Close()
+
+	viper.Reset()
+	viper.Set("mastodon_url", mockServer.URL)
+	viper.Set("mastodon_access_token", "fake-token")
+	viper.Set("feed", []string{"url=" + mockServer.URL + "/feed.xml,category=go,visibility=unlisted"})
+	defer viper.Reset()
+
+	feeds, err := configuredFeeds()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	pollFeeds(feeds, newCycleStats())
+
+	if len(toots) != 1 {
+		t.Fatalf("Expected only the Go post to be tooted, got %v", toots)
+	}
+	if visibility, ok := toots["New blog post: https://example.com/go"]; !ok || visibility != "unlisted" {
+		t.Errorf("Expected the Go post to be tooted unlisted, got %v", toots)
+	}
+}
+
+// Table-driven test for listing feeds with feed_url and feeds
+func TestConfiguredFeeds_FeedURLs(t *testing.T) {
+	tests := []struct {
+		name     string
+		feedURL  any
+		feeds    any
+		expected []string
+	}{
+		{
+			name:     "Single feed URL",
+			feedURL:  "https://example.com/rss",
+			expected: []string{"https://example.com/rss"},
+		},
+		{
+			name:     "Repeated --feed-url flags",
+			feedURL:  []string{"https://example.com/rss", "https://example.org/feed.xml"},
+			expected: []string{"https://example.com/rss", "https://example.org/feed.xml"},
+		},
+		{
+			name:     "Comma-separated feeds",
+			feeds:    "https://example.com/rss, https://example.org/feed.xml,",
+			expected: []string{"https://example.com/rss", "https://example.org/feed.xml"},
+		},
+		{
+			name:     "YAML list of feeds",
+			feeds:    "- https://example.com/rss\n- https://example.org/feed.xml\n",
+			expected: []string{"https://example.com/rss", "https://example.org/feed.xml"},
+		},
+		{
+			name:     "Feeds from a config file list",
+			feeds:    []any{"https://example.com/rss"},
+			expected: []string{"https://example.com/rss"},
+		},
+		{
+			name:     "Duplicates polled once",
+			feedURL:  "https://example.com/rss",
+			feeds:    "https://example.org/feed.xml,https://example.com/rss",
+			expected: []string{"https://example.com/rss", "https://example.org/feed.xml"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			defer viper.Reset()
+			viper.Set("feed_url", tt.feedURL)
+			viper.Set("feeds", tt.feeds)
+
+			feeds, err := configuredFeeds()
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			var urls []string
+			for _, feed := range feeds {
+				urls = append(urls, feed.URL)
+			}
+			if !reflect.DeepEqual(urls, tt.expected) {
+				t.Errorf("Expected feeds %v, got %v", tt.expected, urls)
+			}
+		})
+	}
+}
+
+// Test several feeds are polled concurrently and their posts recorded with the feed they came from
+func TestPollFeeds_Concurrent(t *testing.T) {
+	db.InitDB()
+	defer db.CloseDB()
+	defer os.Remove("./tooted_posts.db")
+
+	// both feeds have to be requested before either responds
+	var requested sync.WaitGroup
+	requested.Add(2)
+	var tootsMu sync.Mutex
+	toots := 0
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a.xml", "/b.xml":
+			requested.Done()
+			requested.Wait()
+			_, _ = w.Write([]byte(`<rss><channel>
+				<item><title>Shared</title><link>https://example.com/shared</link></item>
+				<item><title>Own</title><link>https://example.com/own` + r.URL.Path + `</link></item>
+			</channel></rss>`))
+		case "/api/v1/statuses":
+			tootsMu.Lock()
+			toots++
+			tootsMu.Unlock()
+			_, _ = w.Write([]byte(`{"id":"1"}`))
+		}
+	}))
+	defer mockServer.Close()
+
+	viper.Reset()
+	viper.Set("mastodon_url", mockServer.URL)
+	viper.Set("mastodon_access_token", "fake-token")
+	viper.Set("feeds", mockServer.URL+"/a.xml,"+mockServer.URL+"/b.xml")
+	defer viper.Reset()
+
+	feeds, err := configuredFeeds()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	done := make(chan struct{})
+	go func() {
+		pollFeeds(feeds, newCycleStats())
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the feeds to be fetched concurrently")
+	}
+
+	if toots != 3 {
+		t.Errorf("Expected the shared post to be tooted once, got %d toots", toots)
+	}
+}
+
+// Table-driven test for expanding date placeholders in feed URLs
+func TestExpandFeedURL(t *testing.T) {
+	now := time.Date(2024, time.March, 5, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		feedURL  string
+		expected string
+	}{
+		{
+			name:     "No placeholders",
+			feedURL:  "https://example.com/feed.xml",
+			expected: "https://example.com/feed.xml",
+		},
+		{
+			name:     "Monthly archive",
+			feedURL:  "https://example.com/archive/{%Y}/{%m}/feed.xml",
+			expected: "https://example.com/archive/2024/03/feed.xml",
+		},
+		{
+			name:     "Day, hour, day of year and month names",
+			feedURL:  "https://example.com/{%y}-{%d}-{%H}/{%j}/{%b}-{%B}.xml",
+			expected: "https://example.com/24-05-09/065/Mar-March.xml",
+		},
+		{
+			name:     "ISO week",
+			feedURL:  "https://example.com/{%G}-W{%V}.xml",
+			expected: "https://example.com/2024-W10.xml",
+		},
+		{
+			name:     "Percent-encoding",
+			feedURL:  "https://example.com/feed.xml?q=100%25&tag=a%20b&x=%",
+			expected: "https://example.com/feed.xml?q=100%25&tag=a%20b&x=%",
+		},
+		{
+			name:     "Percent-encoding looking like placeholders",
+			feedURL:  "https://example.com/tag/m%C3%BCnchen/%db/%Y/feed",
+			expected: "https://example.com/tag/m%C3%BCnchen/%db/%Y/feed",
+		},
+		{
+			name:     "Placeholders next to percent-encoding",
+			feedURL:  "https://example.com/tag/m%C3%BCnchen/{%Y}/feed?x=%7B%25Y%7D",
+			expected: "https://example.com/tag/m%C3%BCnchen/2024/feed?x=%7B%25Y%7D",
+		},
+		{
+			name:     "Unknown placeholder",
+			feedURL:  "https://example.com/{%Q}/feed.xml",
+			expected: "https://example.com/{%Q}/feed.xml",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := expandFeedURL(tt.feedURL, now); result != tt.expected {
+				t.Errorf("Expected '%s', got '%s'", tt.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package rss2mastodon
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/mastodon"
+)
+
+// instanceFlavor is the server flavor detected from the instance at startup, empty if unknown
+var instanceFlavor atomic.Value
+
+// serverFlavor returns the server software of the instance: server_flavor if set, or else the
+// flavor detected at startup, falling back to Mastodon
+func serverFlavor() string {
+	if flavor := strings.ToLower(viper.GetString("server_flavor")); flavor != "" {
+		return flavor
+	}
+	if flavor, _ := instanceFlavor.Load().(string); flavor != "" {
+		return flavor
+	}
+	return mastodon.FlavorMastodon
+}
+
+// checkServerFlavor validates server_flavor
+func checkServerFlavor() error {
+	if flavor := strings.ToLower(viper.GetString("server_flavor")); flavor != "" && !mastodon.IsFlavor(flavor) {
+		return fmt.Errorf("unknown server flavor %q, expected one of %s", flavor, strings.Join(mastodon.Flavors, ", "))
+	}
+	return nil
+}
+
+// detectServerFlavor asks the instance which server software it runs, unless server_flavor is set
+func detectServerFlavor() {
+	if viper.GetString("server_flavor") != "" {
+		return
+	}
+	instance, err := mastodonClient().GetInstance()
+	if err != nil {
+		log.Warnf("Failed to look up the instance's server software, assuming %s: %v", mastodon.FlavorMastodon, err)
+		return
+	}
+	flavor := instance.Flavor()
+	instanceFlavor.Store(flavor)
+	log.Infof("The instance runs %s", flavor)
+}
@@ -0,0 +1,58 @@
+package rss2mastodon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/mastodon"
+)
+
+// Table-driven test for detecting the instance's server software at startup
+func TestDetectServerFlavor(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured string
+		status     int
+		instance   string
+		expected   string
+	}{
+		{"Akkoma", "", http.StatusOK, `{"version":"2.7.2 (compatible; Akkoma 3.13.2)"}`, mastodon.FlavorAkkoma},
+		{"Mastodon", "", http.StatusOK, `{"version":"4.3.2"}`, mastodon.FlavorMastodon},
+		{"Instance unavailable", "", http.StatusInternalServerError, ``, mastodon.FlavorMastodon},
+		{"Configured", "GoToSocial", http.StatusOK, `{"version":"2.7.2 (compatible; Pleroma 2.8.0)"}`, mastodon.FlavorGoToSocial},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.instance))
+			}))
+			defer mockServer.Close()
+
+			viper.Reset()
+			viper.Set("mastodon_url", mockServer.URL)
+			viper.Set("server_flavor", tt.configured)
+			defer viper.Reset()
+			instanceFlavor.Store("")
+			defer instanceFlavor.Store("")
+
+			if err := checkServerFlavor(); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			detectServerFlavor()
+			if got := serverFlavor(); got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+
+	viper.Set("server_flavor", "misskey")
+	defer viper.Reset()
+	if err := checkServerFlavor(); err == nil {
+		t.Error("Expected an error for an unknown server flavor")
+	}
+}
@@ -0,0 +1,36 @@
+package rss2mastodon
+
+import (
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/mastodon"
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// withFooter appends footer, rendered for the post, to a toot. The footer is dropped when the
+// toot would exceed max_characters with it, so it never pushes out the actual content. Like on
+// Mastodon, the toot's content warning counts towards the limit.
+func withFooter(post rss.RSSItem, content string) string {
+	text := viper.GetString("footer")
+	if text == "" {
+		return content
+	}
+	footer, err := renderTemplate("footer", text, post)
+	if err != nil {
+		log.Error("Invalid footer template: ", err)
+		return content
+	}
+	if footer = strings.TrimSpace(footer); footer == "" {
+		return content
+	}
+
+	withFooter := content + "\n\n" + footer
+	if mastodon.StatusLength(withFooter)+mastodon.StatusLength(contentWarning(post)) > maxCharacters() {
+		log.Debugf("Dropping the footer of the toot for %s, which would exceed %d characters", post.Link, maxCharacters())
+		return content
+	}
+	return withFooter
+}
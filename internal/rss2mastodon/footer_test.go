@@ -0,0 +1,52 @@
+package rss2mastodon
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// Table-driven test for appending the footer only while the toot stays within the character limit
+func TestWithFooter(t *testing.T) {
+	post := rss.RSSItem{Title: "Hello", Link: "https://example.com/hello", Source: "Example Blog", PubDate: "Mon, 06 May 2024 12:30:00 +0000"}
+	// the footer is 18 characters, so 480 more fit into the default limit of 500
+	fits := strings.Repeat("a", 480)
+	long := strings.Repeat("a", 481)
+	tests := []struct {
+		name          string
+		footer        string
+		maxCharacters int
+		cw            string
+		content       string
+		expected      string
+	}{
+		{"No footer", "", 0, "", "New blog post", "New blog post"},
+		{"Footer", "🤖 via rss2mastodon", 0, "", "New blog post", "New blog post\n\n🤖 via rss2mastodon"},
+		{"Template", "{{.Source}}: {{.Title}}", 0, "", "New blog post", "New blog post\n\nExample Blog: Hello"},
+		{"Empty rendering", "{{.Author}}", 0, "", "New blog post", "New blog post"},
+		{"Date of the post", `Published {{date "Jan 2, 2006" .PubDate}}`, 0, "", "New blog post", "New blog post\n\nPublished May 6, 2024"},
+		{"Invalid template", "{{.Title", 0, "", "New blog post", "New blog post"},
+		{"Exactly fits", "🤖 via rss2mastodon", 0, "", fits, fits + "\n\n🤖 via rss2mastodon"},
+		{"Links count as 23 characters", "{{.Link}}", 0, "", strings.Repeat("a", 475), strings.Repeat("a", 475) + "\n\nhttps://example.com/hello"},
+		{"Too long", "🤖 via rss2mastodon", 0, "", long, long},
+		{"Content warning counts", "🤖 via rss2mastodon", 0, "CW", fits, fits},
+		{"Larger instance limit", "🤖 via rss2mastodon", 1000, "", long, long + "\n\n🤖 via rss2mastodon"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			viper.Set("footer", tt.footer)
+			viper.Set("max_characters", tt.maxCharacters)
+			viper.Set("cw_text", tt.cw)
+			defer viper.Reset()
+
+			if got := withFooter(post, tt.content); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package rss2mastodon
+
+import (
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/mastodon"
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// categoryHashtags returns the hashtags of up to max categories, CamelCased like "#GoGenerics",
+// leaving out categories without letters, which Mastodon doesn't link as hashtags, and those
+// already given as another category or in the toot's content, ignoring case
+func categoryHashtags(categories []string, content string, max int) []string {
+	seen := make(map[string]bool)
+	for _, word := range strings.Fields(content) {
+		if strings.HasPrefix(word, "#") {
+			seen[strings.ToLower(strings.TrimRight(word, ".,;:!?)"))] = true
+		}
+	}
+
+	var tags []string
+	for _, category := range categories {
+		if len(tags) >= max {
+			break
+		}
+		tag := hashtagize(category)
+		if strings.TrimLeft(tag, "#0123456789") == "" || seen[strings.ToLower(tag)] {
+			continue
+		}
+		seen[strings.ToLower(tag)] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// withCategoryHashtags appends the post's categories to a toot as hashtags, up to
+// category_hashtags of them. Hashtags which would make the toot exceed max_characters are
+// dropped, the last ones first.
+func withCategoryHashtags(post rss.RSSItem, content string) string {
+	max := viper.GetInt("category_hashtags")
+	if max <= 0 {
+		return content
+	}
+	tags := categoryHashtags(post.Categories, content, max)
+	for len(tags) > 0 {
+		withTags := content + "\n\n" + strings.Join(tags, " ")
+		if mastodon.StatusLength(withTags)+mastodon.StatusLength(contentWarning(post)) <= maxCharacters() {
+			return withTags
+		}
+		tags = tags[:len(tags)-1]
+	}
+	if len(post.Categories) > 0 {
+		log.Debugf("Dropping the hashtags of the toot for %s, which would exceed %d characters", post.Link, maxCharacters())
+	}
+	return content
+}
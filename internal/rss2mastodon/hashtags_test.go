@@ -0,0 +1,45 @@
+package rss2mastodon
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// Table-driven test for appending a post's categories to its toot as hashtags
+func TestWithCategoryHashtags(t *testing.T) {
+	tests := []struct {
+		name          string
+		max           int
+		maxCharacters int
+		categories    []string
+		content       string
+		expected      string
+	}{
+		{"Disabled", 0, 500, []string{"go"}, "New blog post", "New blog post"},
+		{"No categories", 3, 500, nil, "New blog post", "New blog post"},
+		{"CamelCased", 3, 500, []string{"go generics", "self-hosting"}, "New blog post", "New blog post\n\n#GoGenerics #SelfHosting"},
+		{"Deduplicated", 3, 500, []string{"Go", "go", "GO!"}, "New blog post", "New blog post\n\n#Go"},
+		{"Already in the content", 3, 500, []string{"go", "rust"}, "Thoughts on #Go.", "Thoughts on #Go.\n\n#Rust"},
+		{"Without letters", 3, 500, []string{"2024", "--", "web3"}, "New blog post", "New blog post\n\n#Web3"},
+		{"Capped", 2, 500, []string{"a", "b", "c"}, "New blog post", "New blog post\n\n#A #B"},
+		{"Last ones dropped when too long", 3, 22, []string{"go", "rust"}, "New blog post", "New blog post\n\n#Go"},
+		{"All dropped when too long", 3, 15, []string{"go"}, "New blog post", "New blog post"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			viper.Set("category_hashtags", tt.max)
+			viper.Set("max_characters", tt.maxCharacters)
+			defer viper.Reset()
+
+			post := rss.RSSItem{Link: "https://example.com/1", Categories: tt.categories}
+			if got := withCategoryHashtags(post, tt.content); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package rss2mastodon
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+	"github.com/toozej/rss2mastodon/internal/notify"
+)
+
+// checkFeedHealth records the outcome of polling a feed and sends a notification once when
+// the feed has produced no new items, or kept the same lastBuildDate, for longer than configured
+func checkFeedHealth(feedURL string, newItems int, lastBuildDate string) {
+	if err := db.RecordFeedCheck(feedURL, newItems, lastBuildDate); err != nil {
+		log.Error("Failed to record feed health: ", err)
+		return
+	}
+
+	staleAfter := viper.GetDuration("stale_after")
+	staleBuildAfter := viper.GetDuration("stale_build_after")
+	if staleAfter <= 0 && staleBuildAfter <= 0 {
+		return
+	}
+
+	health, err := db.GetFeedHealth(feedURL)
+	if err != nil {
+		log.Error("Failed to read feed health: ", err)
+		return
+	}
+	if health == nil {
+		log.Debugf("No health recorded for %s yet", feedURL)
+		return
+	}
+
+	var silentAlerted, buildAlerted bool
+	if staleAfter > 0 && !health.SilentAlerted && time.Since(health.LastNewItem) > staleAfter {
+		message := fmt.Sprintf("%s has not produced a new item since %s", feedURL, health.LastNewItem.Format(time.RFC1123))
+		if err := notify.Send("rss2mastodon: feed is silent", message); err != nil {
+			log.Error("Failed to send stale feed notification: ", err)
+		} else {
+			silentAlerted = true
+		}
+	}
+	if staleBuildAfter > 0 && health.LastBuildDate != "" && !health.BuildAlerted && time.Since(health.LastBuildChanged) > staleBuildAfter {
+		message := fmt.Sprintf("%s has reported lastBuildDate %q since %s", feedURL, health.LastBuildDate, health.LastBuildChanged.Format(time.RFC1123))
+		if err := notify.Send("rss2mastodon: feed build date is stuck", message); err != nil {
+			log.Error("Failed to send stale build date notification: ", err)
+		} else {
+			buildAlerted = true
+		}
+	}
+
+	if silentAlerted || buildAlerted {
+		if err := db.SetFeedAlerted(feedURL, silentAlerted, buildAlerted); err != nil {
+			log.Error("Failed to record feed health alert: ", err)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package rss2mastodon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+)
+
+// Test a silent feed triggers a single notification
+func TestCheckFeedHealth(t *testing.T) {
+	db.InitDB()
+	defer db.CloseDB()
+	defer os.Remove("./tooted_posts.db")
+
+	notifications := 0
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		notifications++
+	}))
+	defer mockServer.Close()
+
+	viper.Reset()
+	viper.Set("ntfy_url", mockServer.URL)
+	viper.Set("stale_after", time.Nanosecond)
+	defer viper.Reset()
+
+	feedURL := "https://example.com/silent.xml"
+	checkFeedHealth(feedURL, 0, "")
+	time.Sleep(time.Millisecond)
+	checkFeedHealth(feedURL, 0, "")
+	checkFeedHealth(feedURL, 0, "")
+
+	if notifications != 1 {
+		t.Errorf("Expected 1 notification, got %d", notifications)
+	}
+}
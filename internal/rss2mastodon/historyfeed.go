@@ -0,0 +1,100 @@
+package rss2mastodon
+
+import (
+	"encoding/xml"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+)
+
+// historyFeedItems is how many of the most recent statuses the history feed lists
+const historyFeedItems = 50
+
+// historyFeed is an RSS 2.0 feed of the statuses the bot tooted
+type historyFeed struct {
+	XMLName xml.Name       `xml:"rss"`
+	Version string         `xml:"version,attr"`
+	Channel historyChannel `xml:"channel"`
+}
+
+type historyChannel struct {
+	Title         string        `xml:"title"`
+	Link          string        `xml:"link"`
+	Description   string        `xml:"description"`
+	LastBuildDate string        `xml:"lastBuildDate"`
+	Items         []historyItem `xml:"item"`
+}
+
+type historyItem struct {
+	Title string `xml:"title"`
+	// Link is the status on Mastodon, or the announced post if the status URL is unknown
+	Link        string      `xml:"link"`
+	Description string      `xml:"description"`
+	GUID        historyGUID `xml:"guid"`
+	PubDate     string      `xml:"pubDate"`
+}
+
+type historyGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// buildHistoryFeed renders the given statuses, newest first, as an RSS feed
+func buildHistoryFeed(statuses []db.Status, now time.Time) ([]byte, error) {
+	feed := historyFeed{
+		Version: "2.0",
+		Channel: historyChannel{
+			Title:         "rss2mastodon announcements",
+			Link:          viper.GetString("mastodon_url"),
+			Description:   "Statuses tooted by rss2mastodon",
+			LastBuildDate: now.UTC().Format(time.RFC1123Z),
+		},
+	}
+	for _, status := range statuses {
+		item := historyItem{
+			Title:       status.Style,
+			Link:        status.URL,
+			Description: status.Link,
+			GUID:        historyGUID{Value: status.ID},
+			PubDate:     status.CreatedAt.UTC().Format(time.RFC1123Z),
+		}
+		if status.Link != "" {
+			item.Title += ": " + status.Link
+		}
+		if item.Link == "" {
+			item.Link = status.Link
+		}
+		feed.Channel.Items = append(feed.Channel.Items, item)
+	}
+
+	data, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+// writeHistoryFeed writes an RSS feed of the bot's recent statuses to history_feed, if set
+func writeHistoryFeed() {
+	path := viper.GetString("history_feed")
+	if path == "" {
+		return
+	}
+
+	statuses, err := db.RecentStatuses(historyFeedItems)
+	if err != nil {
+		log.Error("Failed to read recent statuses: ", err)
+		return
+	}
+	data, err := buildHistoryFeed(statuses, time.Now())
+	if err != nil {
+		log.Error("Failed to encode history feed: ", err)
+		return
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		log.Error("Failed to write history feed: ", err)
+	}
+}
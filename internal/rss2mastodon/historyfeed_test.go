@@ -0,0 +1,60 @@
+package rss2mastodon
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// Test the history feed is written as a parseable RSS feed of the bot's statuses
+func TestWriteHistoryFeed(t *testing.T) {
+	db.InitDB()
+	defer db.CloseDB()
+	defer os.Remove("./tooted_posts.db")
+
+	path := filepath.Join(t.TempDir(), "history.xml")
+	viper.Reset()
+	viper.Set("history_feed", path)
+	defer viper.Reset()
+
+	if err := db.RecordStatus("1", "https://example.social/@bot/1", "https://example.com/post", styleNewPost); err != nil {
+		t.Fatalf("Failed to record status: %v", err)
+	}
+	if err := db.RecordStatus("2", "", "", styleDigest); err != nil {
+		t.Fatalf("Failed to record status: %v", err)
+	}
+
+	writeHistoryFeed()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read history feed: %v", err)
+	}
+	var feed rss.RSSFeed
+	if err := xml.Unmarshal(data, &feed); err != nil {
+		t.Fatalf("Failed to parse history feed: %v", err)
+	}
+
+	items := map[string]rss.RSSItem{}
+	for _, item := range feed.Channel.Items {
+		items[item.GUID] = item
+	}
+	if len(items) != 2 {
+		t.Fatalf("Expected 2 items, got %v", feed.Channel.Items)
+	}
+	if item := items["1"]; item.Link != "https://example.social/@bot/1" || item.Title != "new post: https://example.com/post" {
+		t.Errorf("Unexpected item for the announcement: %+v", item)
+	}
+	if item := items["2"]; item.Title != "weekly digest" {
+		t.Errorf("Unexpected item for the digest: %+v", item)
+	}
+	if _, ok := items["1"].Published(); !ok {
+		t.Errorf("Expected a parseable pubDate, got %q", items["1"].PubDate)
+	}
+}
@@ -0,0 +1,17 @@
+package rss2mastodon
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// idempotencyKey returns the Idempotency-Key of a toot of the given announcement style about a
+// post, derived from its link and content hash. If the process dies after tooting but before
+// the post is stored, the toot sent again when retrying gets the same key, so Mastodon returns
+// the status it already created instead of posting a duplicate.
+func idempotencyKey(style string, post rss.RSSItem) string {
+	key := fmt.Sprintf("%s\n%s\n%x", style, post.Link, rss.HashContent(post.Content))
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(key)))
+}
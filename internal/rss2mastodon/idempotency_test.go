@@ -0,0 +1,38 @@
+package rss2mastodon
+
+import (
+	"testing"
+
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// Table-driven test for deriving idempotency keys from toots' style, link and content
+func TestIdempotencyKey(t *testing.T) {
+	post := rss.RSSItem{Title: "Post", Link: "https://example.com/post", Content: "Content"}
+	key := idempotencyKey(styleNewPost, post)
+
+	tests := []struct {
+		name     string
+		style    string
+		post     rss.RSSItem
+		expected bool
+	}{
+		{"Same post", styleNewPost, post, true},
+		{"Other title", styleNewPost, rss.RSSItem{Title: "Renamed", Link: post.Link, Content: post.Content}, true},
+		{"Update", styleUpdate, post, false},
+		{"Other link", styleNewPost, rss.RSSItem{Link: "https://example.com/other", Content: post.Content}, false},
+		{"Changed content", styleNewPost, rss.RSSItem{Link: post.Link, Content: "Edited"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := idempotencyKey(tt.style, tt.post)
+			if len(got) != 64 {
+				t.Errorf("Expected a 64 character key, got %q", got)
+			}
+			if (got == key) != tt.expected {
+				t.Errorf("Expected the same key: %v, got %q and %q", tt.expected, key, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package rss2mastodon
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"os"
+	"regexp"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// archivedStatus is the part of a Create activity's Note in an outbox.json which is of interest
+type archivedStatus struct {
+	URL     string `json:"url"`
+	Content string `json:"content"`
+}
+
+// hrefRegex matches the links in a status's HTML content
+var hrefRegex = regexp.MustCompile(`href="([^"]+)"`)
+
+// links returns the URLs linked from the status
+func (s archivedStatus) links() []string {
+	var links []string
+	for _, match := range hrefRegex.FindAllStringSubmatch(s.Content, -1) {
+		links = append(links, html.UnescapeString(match[1]))
+	}
+	return links
+}
+
+// readOutbox returns the statuses created in a Mastodon account archive's outbox.json,
+// skipping other activities like boosts
+func readOutbox(path string) ([]archivedStatus, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var collection struct {
+		OrderedItems []struct {
+			Type string `json:"type"`
+			// Object is a Note for Create activities, but only a URL for e.g. Announce activities
+			Object json.RawMessage `json:"object"`
+		} `json:"orderedItems"`
+	}
+	if err := json.Unmarshal(data, &collection); err != nil {
+		return nil, fmt.Errorf("failed to parse outbox: %w", err)
+	}
+
+	var statuses []archivedStatus
+	for _, item := range collection.OrderedItems {
+		if item.Type != "Create" {
+			continue
+		}
+		var status archivedStatus
+		if err := json.Unmarshal(item.Object, &status); err != nil {
+			continue
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// archivedPosts returns the feed items which are linked from any of the archived statuses
+func archivedPosts(items []rss.RSSItem, statuses []archivedStatus) []rss.RSSItem {
+	linked := map[string]bool{}
+	for _, status := range statuses {
+		for _, link := range status.links() {
+			linked[link] = true
+		}
+	}
+
+	var found []rss.RSSItem
+	for _, item := range items {
+		if item.Link != "" && linked[item.Link] {
+			found = append(found, item)
+		}
+	}
+	return found
+}
+
+// ImportArchive seeds the tooted posts with the configured feeds' items which were already
+// announced according to a Mastodon account archive, so they aren't announced again
+func ImportArchive(cmd *cobra.Command, args []string) {
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	if err := LoadConfig(); err != nil {
+		log.Fatal("Error loading configuration: ", err)
+	}
+	feeds, err := configuredFeeds()
+	if err != nil {
+		log.Fatal("Invalid feed configuration: ", err)
+	}
+	if len(feeds) == 0 {
+		log.Fatal("RSS feed URL is required")
+	}
+
+	statuses, err := readOutbox(args[0])
+	if err != nil {
+		log.Fatal("Failed to read archive: ", err)
+	}
+	log.Infof("Read %d statuses from %s", len(statuses), args[0])
+
+	var items []rss.RSSItem
+	for _, source := range feeds {
+		feed, err := source.fetch(time.Now())
+		if err != nil {
+			log.Fatal("Error fetching RSS feed: ", err)
+		}
+		for _, item := range feed.Channel.Items {
+			if source.includes(item) {
+				items = append(items, item)
+			}
+		}
+	}
+
+	OpenDB()
+	defer db.CloseDB()
+
+	seeded := 0
+	for _, post := range archivedPosts(items, statuses) {
+		exists, _, err := db.HasPostChanged(post.Link, post.Content)
+		if err != nil {
+			log.Fatal("Database error: ", err)
+		}
+		if exists {
+			continue
+		}
+		if dryRun {
+			fmt.Println(post.Link)
+			continue
+		}
+		if err := db.StoreTootedPost(post.Link, post.Content); err != nil {
+			log.Fatal("Failed to store tooted post: ", err)
+		}
+		seeded++
+	}
+	if !dryRun {
+		log.Infof("Marked %d posts found in the archive as tooted", seeded)
+	}
+}
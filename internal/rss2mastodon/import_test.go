@@ -0,0 +1,57 @@
+package rss2mastodon
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// Test finding the feed items linked from the statuses of an account archive
+func TestArchivedPosts(t *testing.T) {
+	outboxJSON := `{
+		"type": "OrderedCollection",
+		"orderedItems": [
+			{"type": "Create", "object": {"url": "https://example.social/@bot/1", "content": "<p>New blog post: <a href=\"https://example.com/posts/a?x=1&amp;y=2\" rel=\"nofollow\"><span class=\"invisible\">https://</span>example.com/posts/a?x=1&amp;y=2</a></p>"}},
+			{"type": "Create", "object": {"url": "https://example.social/@bot/2", "content": "<p>Thoughts - <a href=\"https://example.com/posts/ab\">example.com/posts/ab</a></p>"}},
+			{"type": "Announce", "object": "https://other.social/@someone/3"}
+		]
+	}`
+	path := filepath.Join(t.TempDir(), "outbox.json")
+	if err := os.WriteFile(path, []byte(outboxJSON), 0o644); err != nil {
+		t.Fatalf("Failed to write outbox: %v", err)
+	}
+
+	statuses, err := readOutbox(path)
+	if err != nil {
+		t.Fatalf("Failed to read outbox: %v", err)
+	}
+	if len(statuses) != 2 {
+		t.Fatalf("Expected 2 created statuses, got %d", len(statuses))
+	}
+
+	tests := []struct {
+		name     string
+		link     string
+		expected bool
+	}{
+		{"Linked post with escaped query", "https://example.com/posts/a?x=1&y=2", true},
+		{"Linked post", "https://example.com/posts/ab", true},
+		{"Prefix of a linked post", "https://example.com/posts/a", false},
+		{"Post never tooted", "https://example.com/posts/c", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found := archivedPosts([]rss.RSSItem{{Link: tt.link}}, statuses)
+			if (len(found) == 1) != tt.expected {
+				t.Errorf("Expected found %v, got %v", tt.expected, found)
+			}
+		})
+	}
+
+	if _, err := readOutbox(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Errorf("Expected an error for a missing outbox")
+	}
+}
@@ -0,0 +1,21 @@
+package rss2mastodon
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// languageCodeRegex matches ISO 639-1 and 639-3 language codes, which Mastodon accepts
+var languageCodeRegex = regexp.MustCompile(`^[a-z]{2,3}$`)
+
+// tootLanguage returns the ISO 639 code to tag toots about the feed's posts with: the feed's
+// language option, or else the language setting. If neither is set, Mastodon uses the account's
+// default posting language.
+func tootLanguage(feedURL string) string {
+	if language := feedConfig(feedURL).Language; language != "" {
+		return language
+	}
+	return strings.ToLower(strings.TrimSpace(viper.GetString("language")))
+}
@@ -0,0 +1,35 @@
+package rss2mastodon
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// Table-driven test for the language of a feed's toots, falling back to the global one
+func TestTootLanguage(t *testing.T) {
+	tests := []struct {
+		name     string
+		language string
+		feedURL  string
+		expected string
+	}{
+		{"Unset", "", "https://example.com/rss", ""},
+		{"Global language", " FR ", "https://example.com/rss", "fr"},
+		{"Feed language", "fr", "https://example.com/de", "de"},
+		{"Feed language without global one", "", "https://example.com/de", "de"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			viper.Set("language", tt.language)
+			viper.Set("feed", []string{"url=https://example.com/de,language=de"})
+			defer viper.Reset()
+
+			if got := tootLanguage(tt.feedURL); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
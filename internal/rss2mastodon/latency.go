@@ -0,0 +1,60 @@
+package rss2mastodon
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/metrics"
+	"github.com/toozej/rss2mastodon/internal/notify"
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// latencyAlerted holds the feeds already alerted about for their latency, until an item shows
+// up in time again
+var (
+	latencyAlertedMu sync.Mutex
+	latencyAlerted   = map[string]bool{}
+)
+
+// observeLatency records how long after its pubDate a new item was first seen in its feed, e.g.
+// because of CDN caching, and notifies once when it exceeds latency_alert_after. Items without
+// a date, scheduled for later or resurfaced from the archive aren't considered.
+func observeLatency(feedURL string, post rss.RSSItem, firstSeen time.Time) {
+	published, ok := post.Published()
+	if !ok {
+		return
+	}
+	latency := firstSeen.Sub(published)
+	if latency < 0 {
+		return
+	}
+	if resurfacedAfter := viper.GetDuration("resurfaced_after"); resurfacedAfter > 0 && latency > resurfacedAfter {
+		return
+	}
+	metrics.Timing("feed.latency", latency, "feed:"+feedURL)
+	log.Debugf("%s was first seen %s after its pubDate: %s", feedURL, latency.Round(time.Second), post.Link)
+
+	alertAfter := viper.GetDuration("latency_alert_after")
+	if alertAfter <= 0 {
+		return
+	}
+	latencyAlertedMu.Lock()
+	defer latencyAlertedMu.Unlock()
+	if latency <= alertAfter {
+		delete(latencyAlerted, feedURL)
+		return
+	}
+	if latencyAlerted[feedURL] {
+		return
+	}
+	message := fmt.Sprintf("%s was first seen %s after its pubDate in %s, more than %s", post.Link, latency.Round(time.Second), feedURL, alertAfter)
+	if err := notify.Send("rss2mastodon: feed is delayed", message); err != nil {
+		log.Error("Failed to send feed latency notification: ", err)
+		return
+	}
+	latencyAlerted[feedURL] = true
+}
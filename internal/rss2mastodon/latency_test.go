@@ -0,0 +1,53 @@
+package rss2mastodon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// Table-driven test for alerting once about delayed items until a feed catches up
+func TestObserveLatency(t *testing.T) {
+	notifications := 0
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		notifications++
+	}))
+	defer mockServer.Close()
+
+	viper.Reset()
+	viper.Set("ntfy_url", mockServer.URL)
+	viper.Set("latency_alert_after", time.Hour)
+	viper.Set("resurfaced_after", 72*time.Hour)
+	defer viper.Reset()
+
+	feedURL := "https://example.com/cached.xml"
+	now := time.Date(2024, 5, 6, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name                  string
+		pubDate               string
+		expectedNotifications int
+	}{
+		{"In time", now.Add(-10 * time.Minute).Format(time.RFC1123Z), 0},
+		{"Delayed", now.Add(-2 * time.Hour).Format(time.RFC1123Z), 1},
+		{"Still delayed", now.Add(-3 * time.Hour).Format(time.RFC1123Z), 1},
+		{"Resurfaced from the archive", now.Add(-30 * 24 * time.Hour).Format(time.RFC1123Z), 1},
+		{"Scheduled", now.Add(time.Hour).Format(time.RFC1123Z), 1},
+		{"No date", "", 1},
+		{"Caught up", now.Add(-time.Minute).Format(time.RFC1123Z), 1},
+		{"Delayed again", now.Add(-2 * time.Hour).Format(time.RFC1123Z), 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			observeLatency(feedURL, rss.RSSItem{Link: "https://example.com/post", PubDate: tt.pubDate}, now)
+			if notifications != tt.expectedNotifications {
+				t.Errorf("Expected %d notifications, got %d", tt.expectedNotifications, notifications)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package rss2mastodon
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+	"github.com/toozej/rss2mastodon/internal/k8s"
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// defaultLeaseDuration is how long a lock is held without being renewed when not configured
+const defaultLeaseDuration = 30 * time.Second
+
+// locker is a lock shared between replicas, so only the one holding it posts
+type locker interface {
+	TryAcquire() (bool, error)
+}
+
+// leader is whether this replica may post, always true without a lock configured
+var leader atomic.Bool
+
+// isLeader reports whether this replica currently holds the lock
+func isLeader() bool {
+	return leader.Load()
+}
+
+// dbLock is a leader lock stored in the SQLite state database, so it only works between instances
+// sharing the database file, i.e. on the same host or a volume they all mount
+type dbLock struct {
+	name     string
+	holder   string
+	duration time.Duration
+}
+
+func (l *dbLock) TryAcquire() (bool, error) {
+	return db.TryAcquireLeader(l.name, l.holder, l.duration)
+}
+
+// newLocker returns the lock configured for running multiple replicas, or nil for a single instance
+func newLocker() (locker, time.Duration, error) {
+	if viper.GetBool("leader_election") {
+		duration := viper.GetDuration("leader_lease_duration")
+		if duration <= 0 {
+			duration = defaultLeaseDuration
+		}
+		// instances on the same host share a hostname, so include the process ID
+		holder := fmt.Sprintf("%s-%d", replicaIdentity(), os.Getpid())
+		return &dbLock{name: "rss2mastodon", holder: holder, duration: duration}, duration, nil
+	}
+
+	name := viper.GetString("k8s_lease")
+	if name == "" {
+		return nil, 0, nil
+	}
+
+	duration := viper.GetDuration("k8s_lease_duration")
+	if duration <= 0 {
+		duration = defaultLeaseDuration
+	}
+	lease, err := k8s.NewInClusterLease(name, viper.GetString("k8s_namespace"), replicaIdentity(), duration)
+	if err != nil {
+		return nil, 0, err
+	}
+	lease.Labels = map[string]string{"app.kubernetes.io/name": "rss2mastodon"}
+	return lease, duration, nil
+}
+
+// replicaIdentity identifies this replica, preferring the pod name from the downward API
+func replicaIdentity() string {
+	if name := viper.GetString("pod_name"); name != "" {
+		return name
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "rss2mastodon"
+	}
+	return hostname
+}
+
+// startLeaderElection keeps trying to acquire or renew the lock in the background. Followers keep
+// fetching feeds and checking their health without posting, so they can take over as soon as the
+// leader goes away.
+func startLeaderElection() {
+	lock, duration, err := newLocker()
+	if err != nil {
+		log.Fatal("Error setting up leader election: ", err)
+	}
+	if lock == nil {
+		leader.Store(true)
+		return
+	}
+
+	elect := func() {
+		held, err := lock.TryAcquire()
+		if err != nil {
+			log.Warn("Leader election failed: ", err)
+			// stop posting if the lock can't be renewed, another replica may take over
+			held = false
+		}
+		if held != leader.Swap(held) {
+			if held {
+				log.Info("Became the leader, posting is enabled")
+				triggerCycle()
+			} else {
+				log.Info("Lost leadership, posting is disabled")
+			}
+		}
+	}
+
+	elect()
+	go func() {
+		// renew well before the lock expires
+		for {
+			time.Sleep(duration / 3)
+			elect()
+		}
+	}()
+}
+
+// warmFeeds fetches the feeds and checks their health on followers. Which items are new, seen or
+// removed is left to the leader, as it decides what the leader posts: a follower recording it
+// would take the items from the leader.
+func warmFeeds(feeds []FeedConfig, stats *cycleStats) {
+	for _, source := range unpausedFeeds(feeds) {
+		stats.feedsPolled.Add(1)
+		feed, _, err := source.fetchIfModified(time.Now())
+		if errors.Is(err, rss.ErrNotModified) {
+			checkFeedHealth(source.URL, 0, storedBuildDate(source.URL))
+			continue
+		}
+		if err != nil {
+			feedLogger(source.URL).Printf("Error fetching RSS feed: %v", err)
+			recordError(source.URL, err)
+			stats.feedErrors.Add(1)
+			continue
+		}
+		checkFeedHealth(source.URL, 0, feed.Channel.LastBuildDate)
+	}
+}
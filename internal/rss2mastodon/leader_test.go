@@ -0,0 +1,103 @@
+package rss2mastodon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+)
+
+// Test a single instance is always the leader and a lease requires a cluster
+func TestLeaderElection(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	defer leader.Store(false)
+
+	startLeaderElection()
+	if !isLeader() {
+		t.Errorf("Expected a single instance to be the leader")
+	}
+
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+	viper.Set("k8s_lease", "rss2mastodon")
+	if _, _, err := newLocker(); err == nil {
+		t.Errorf("Expected an error using a lease outside a cluster")
+	}
+}
+
+// Test instances sharing the state database elect a single leader
+func TestDBLock(t *testing.T) {
+	db.InitDB()
+	defer db.CloseDB()
+	defer os.Remove("./tooted_posts.db")
+
+	viper.Reset()
+	viper.Set("leader_election", true)
+	defer viper.Reset()
+
+	lock, duration, err := newLocker()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if duration != defaultLeaseDuration {
+		t.Errorf("Expected default lease duration, got %v", duration)
+	}
+	other := &dbLock{name: "rss2mastodon", holder: "other-instance", duration: duration}
+
+	if held, err := lock.TryAcquire(); err != nil || !held {
+		t.Fatalf("Expected to acquire the lock, got %v, %v", held, err)
+	}
+	if held, err := other.TryAcquire(); err != nil || held {
+		t.Errorf("Expected another instance not to acquire the lock, got %v, %v", held, err)
+	}
+}
+
+// Test followers fetch feeds and record their health, but leave posting and tracking items to the leader
+func TestWarmFeeds(t *testing.T) {
+	db.InitDB()
+	defer db.CloseDB()
+	defer os.Remove("./tooted_posts.db")
+
+	var fetches, toots int
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/statuses" {
+			toots++
+			_, _ = w.Write([]byte(`{"id":"1"}`))
+			return
+		}
+		fetches++
+		_, _ = w.Write([]byte(`<rss><channel><lastBuildDate>a</lastBuildDate>
+			<item><title>A1</title><link>https://a.example.com/1</link></item>
+		</channel></rss>`))
+	}))
+	defer mockServer.Close()
+
+	viper.Reset()
+	viper.Set("mastodon_url", mockServer.URL)
+	viper.Set("mastodon_access_token", "fake-token")
+	defer viper.Reset()
+
+	feeds := []FeedConfig{{URL: mockServer.URL + "/feed.xml"}}
+	stats := newCycleStats()
+	warmFeeds(feeds, stats)
+
+	if fetches != 1 || stats.feedsPolled.Load() != 1 {
+		t.Errorf("Expected the feed to be fetched once, got %d (%d counted)", fetches, stats.feedsPolled.Load())
+	}
+	if health, err := db.GetFeedHealth(feeds[0].URL); err != nil || health == nil || health.LastBuildDate != "a" {
+		t.Errorf("Expected the feed's health to be recorded, got %+v, %v", health, err)
+	}
+	if toots != 0 {
+		t.Errorf("Expected followers not to toot, got %d toots", toots)
+	}
+
+	// the leader still finds the item new
+	pollFeeds(feeds, newCycleStats())
+	if toots != 1 {
+		t.Errorf("Expected the leader to toot the item, got %d toots", toots)
+	}
+}
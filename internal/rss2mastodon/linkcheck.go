@@ -0,0 +1,80 @@
+package rss2mastodon
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+	"github.com/toozej/rss2mastodon/internal/httpclient"
+	"github.com/toozej/rss2mastodon/internal/notify"
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// defaultLinkCheckGrace is how long a new post's page may take to go live by default
+const defaultLinkCheckGrace = 30 * time.Minute
+
+// checkLink reports an error if the page at link isn't live, i.e. it can't be fetched or
+// responds with 404 or a server error
+func checkLink(link string) error {
+	client := httpclient.New("linkcheck", 10*time.Second)
+
+	resp, err := client.Head(link)
+	if err == nil && resp.StatusCode == http.StatusMethodNotAllowed {
+		// not every server supports HEAD requests
+		resp.Body.Close()
+		resp, err = client.Get(link)
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode >= 500 {
+		return fmt.Errorf("unexpected HTTP status: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// linkLive reports whether a new post's page is live, if link_check is enabled. Posts whose
+// page isn't live are checked again every cycle until link_check_grace after they were first
+// seen, e.g. while a static site is still deploying, and moved to the dead letters after that.
+// Links which aren't web pages, like the mid: links of emails, aren't checked.
+func linkLive(post rss.RSSItem, firstSeen time.Time) bool {
+	if !viper.GetBool("link_check") || !strings.HasPrefix(post.Link, "http") {
+		return true
+	}
+
+	checkErr := checkLink(post.Link)
+	if checkErr == nil {
+		return true
+	}
+
+	attempts, firstFailure, err := db.RecordDeliveryFailure(post.Link, checkErr.Error())
+	if err != nil {
+		log.Error("Failed to record delivery attempt: ", err)
+	}
+
+	grace := viper.GetDuration("link_check_grace")
+	if grace <= 0 {
+		grace = defaultLinkCheckGrace
+	}
+	if time.Since(firstSeen) < grace {
+		log.Infof("Waiting for %s to go live: %v", post.Link, checkErr)
+		return false
+	}
+
+	if err := db.AddDeadLetter(post, attempts, firstFailure, checkErr.Error()); err != nil {
+		log.Error("Failed to move post to dead letters: ", err)
+		return false
+	}
+	message := fmt.Sprintf("Giving up on %s, its page still isn't live %s after it was first seen: %v", post.Link, grace, checkErr)
+	if err := notify.Send("rss2mastodon: post moved to dead letters", message); err != nil {
+		log.Error("Failed to send dead letter notification: ", err)
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package rss2mastodon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// Table-driven test for checking whether a post's page is live
+func TestCheckLink(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/live":
+			w.WriteHeader(http.StatusOK)
+		case "/forbidden":
+			w.WriteHeader(http.StatusForbidden)
+		case "/no-head":
+			if r.Method == "HEAD" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+		case "/error":
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer mockServer.Close()
+
+	tests := []struct {
+		name        string
+		path        string
+		expectError bool
+	}{
+		{"Live page", "/live", false},
+		{"Other client errors count as live", "/forbidden", false},
+		{"HEAD not allowed", "/no-head", false},
+		{"Not deployed yet", "/missing", true},
+		{"Server error", "/error", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkLink(mockServer.URL + tt.path)
+			if (err != nil) != tt.expectError {
+				t.Errorf("Expected error %v, got %v", tt.expectError, err)
+			}
+		})
+	}
+}
+
+// Test posts whose page isn't live are held during the grace period, then dead-lettered
+func TestLinkLive(t *testing.T) {
+	db.InitDB()
+	defer db.CloseDB()
+	defer os.Remove("./tooted_posts.db")
+
+	mockServer := httptest.NewServer(http.NotFoundHandler())
+	defer mockServer.Close()
+
+	viper.Reset()
+	defer viper.Reset()
+	post := rss.RSSItem{Title: "Not live", Link: mockServer.URL + "/post"}
+
+	if !linkLive(post, time.Now()) {
+		t.Errorf("Expected no check without link_check")
+	}
+
+	viper.Set("link_check", true)
+	viper.Set("link_check_grace", time.Hour)
+	if linkLive(post, time.Now()) {
+		t.Errorf("Expected the post to be held")
+	}
+	if !linkLive(rss.RSSItem{Title: "Email", Link: "mid:issue42@example.com"}, time.Now()) {
+		t.Errorf("Expected no check for links which aren't web pages")
+	}
+	if deadLettered, _ := db.IsDeadLettered(post.Link); deadLettered {
+		t.Errorf("Expected the post not to be dead-lettered within the grace period")
+	}
+
+	if linkLive(post, time.Now().Add(-2*time.Hour)) {
+		t.Errorf("Expected the post to be held")
+	}
+	if deadLettered, _ := db.IsDeadLettered(post.Link); !deadLettered {
+		t.Errorf("Expected the post to be dead-lettered after the grace period")
+	}
+}
@@ -0,0 +1,67 @@
+package rss2mastodon
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// linkParams returns the query parameters to append to the links of the feed's toots, e.g.
+// utm_source=mastodon: the feed's link_params option, or else the link_params setting
+func linkParams(feedURL string) string {
+	if params := feedConfig(feedURL).LinkParams; params != "" {
+		return params
+	}
+	return viper.GetString("link_params")
+}
+
+// withLinkParams returns the post with the query parameters of its feed appended to its link,
+// also where its announcement mentions the link, for the toot about it. The post's original
+// link remains what it is stored and deduplicated by.
+func withLinkParams(post rss.RSSItem) rss.RSSItem {
+	link := appendQuery(post.Link, linkParams(post.FeedURL))
+	if link == post.Link {
+		return post
+	}
+	if post.Announcement != "" {
+		post.Announcement = strings.ReplaceAll(post.Announcement, post.Link, link)
+	}
+	post.Link = link
+	return post
+}
+
+// appendQuery appends query parameters to an HTTP(S) link, keeping its own parameters and
+// fragment. Parameters the link already has aren't appended again, so they aren't overridden.
+func appendQuery(link string, params string) string {
+	if params == "" {
+		return link
+	}
+	u, err := url.Parse(link)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return link
+	}
+	if _, err := url.ParseQuery(params); err != nil {
+		return link
+	}
+
+	existing := u.Query()
+	var appended []string
+	for _, param := range strings.Split(params, "&") {
+		key, _, _ := strings.Cut(param, "=")
+		if name, err := url.QueryUnescape(key); err != nil || name == "" || existing.Has(name) {
+			continue
+		}
+		appended = append(appended, param)
+	}
+	if len(appended) == 0 {
+		return link
+	}
+	if u.RawQuery != "" {
+		appended = append([]string{u.RawQuery}, appended...)
+	}
+	u.RawQuery = strings.Join(appended, "&")
+	return u.String()
+}
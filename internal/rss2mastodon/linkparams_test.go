@@ -0,0 +1,77 @@
+package rss2mastodon
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// Table-driven test for appending query parameters to links
+func TestAppendQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		link     string
+		params   string
+		expected string
+	}{
+		{"No params", "https://example.com/post", "", "https://example.com/post"},
+		{"Params", "https://example.com/post", "utm_source=mastodon&utm_medium=social", "https://example.com/post?utm_source=mastodon&utm_medium=social"},
+		{"Existing query", "https://example.com/post?id=1", "utm_source=mastodon", "https://example.com/post?id=1&utm_source=mastodon"},
+		{"Fragment", "https://example.com/post#comments", "utm_source=mastodon", "https://example.com/post?utm_source=mastodon#comments"},
+		{"Existing param kept", "https://example.com/post?utm_source=rss", "utm_source=mastodon&utm_medium=social", "https://example.com/post?utm_source=rss&utm_medium=social"},
+		{"All params existing", "https://example.com/post?utm_source=rss", "utm_source=mastodon", "https://example.com/post?utm_source=rss"},
+		{"Not HTTP", "mid:abc@example.com", "utm_source=mastodon", "mid:abc@example.com"},
+		{"Invalid params", "https://example.com/post", "utm_source=%zz", "https://example.com/post"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := appendQuery(tt.link, tt.params); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+// Table-driven test for the link params of a post's feed, falling back to the global ones
+func TestWithLinkParams(t *testing.T) {
+	tests := []struct {
+		name                 string
+		post                 rss.RSSItem
+		expectedLink         string
+		expectedAnnouncement string
+	}{
+		{
+			name:         "Feed params",
+			post:         rss.RSSItem{Link: "https://example.com/1", FeedURL: "https://example.com/rss"},
+			expectedLink: "https://example.com/1?utm_source=fediverse",
+		},
+		{
+			name:         "Global params",
+			post:         rss.RSSItem{Link: "https://example.com/1", FeedURL: "https://other.example.com/rss"},
+			expectedLink: "https://example.com/1?utm_source=mastodon",
+		},
+		{
+			name:                 "Announcement",
+			post:                 rss.RSSItem{Link: "https://example.com/1", Announcement: "New release: https://example.com/1"},
+			expectedLink:         "https://example.com/1?utm_source=mastodon",
+			expectedAnnouncement: "New release: https://example.com/1?utm_source=mastodon",
+		},
+	}
+
+	viper.Reset()
+	viper.Set("link_params", "utm_source=mastodon")
+	viper.Set("feed", []string{"url=https://example.com/rss,link_params=utm_source=fediverse"})
+	defer viper.Reset()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := withLinkParams(tt.post)
+			if got.Link != tt.expectedLink || got.Announcement != tt.expectedAnnouncement {
+				t.Errorf("Expected %q and %q, got %q and %q", tt.expectedLink, tt.expectedAnnouncement, got.Link, got.Announcement)
+			}
+		})
+	}
+}
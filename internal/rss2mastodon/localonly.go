@@ -0,0 +1,15 @@
+package rss2mastodon
+
+import (
+	"github.com/spf13/viper"
+)
+
+// tootLocalOnly returns whether toots about the feed's posts are kept on the instance instead of
+// federating: the feed's local_only option, or else the local_only setting. Only instances
+// running glitch-soc, Hometown, GoToSocial, Pleroma or Akkoma honor it.
+func tootLocalOnly(feedURL string) bool {
+	if localOnly := feedConfig(feedURL).LocalOnly; localOnly != nil {
+		return *localOnly
+	}
+	return viper.GetBool("local_only")
+}
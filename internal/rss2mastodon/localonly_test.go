@@ -0,0 +1,35 @@
+package rss2mastodon
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// Table-driven test for whether a feed's toots are local-only, falling back to the global setting
+func TestTootLocalOnly(t *testing.T) {
+	tests := []struct {
+		name      string
+		localOnly bool
+		feedURL   string
+		expected  bool
+	}{
+		{"Unset", false, "https://example.com/rss", false},
+		{"Global setting", true, "https://example.com/rss", true},
+		{"Feed enabling it", false, "https://example.com/local", true},
+		{"Feed disabling it", true, "https://example.com/public", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			viper.Set("local_only", tt.localOnly)
+			viper.Set("feed", []string{"url=https://example.com/local,local_only=true", "url=https://example.com/public,local_only=false"})
+			defer viper.Reset()
+
+			if got := tootLocalOnly(tt.feedURL); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
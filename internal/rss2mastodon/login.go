@@ -0,0 +1,164 @@
+package rss2mastodon
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/mastodon"
+)
+
+// Name and website the app is registered with, shown to the user authorizing it and on toots
+const (
+	loginAppName    = "rss2mastodon"
+	loginAppWebsite = "https://github.com/toozej/rss2mastodon"
+)
+
+// loginScopes are the OAuth scopes the bot asks for: reading its account, statuses and
+// notifications, and posting statuses, media and profile updates
+const loginScopes = "read write"
+
+// Login registers an app with the Mastodon instance, has the user authorize it and writes the
+// instance URL and the resulting access token to the .env file (or the profile's .env.NAME
+// file), or as one file per key to credentialsDir, e.g. for --config-dir. The instance defaults
+// to the configured MASTODON_URL. The authorization code is read from in.
+func Login(instanceURL string, credentialsDir string, in io.Reader, out io.Writer) error {
+	if instanceURL == "" {
+		if err := LoadConfig(); err != nil {
+			return err
+		}
+		instanceURL = viper.GetString("mastodon_url")
+	}
+	if instanceURL == "" {
+		return fmt.Errorf("the instance URL must be given, e.g. rss2mastodon login https://mastodon.social")
+	}
+	if !strings.Contains(instanceURL, "://") {
+		instanceURL = "https://" + instanceURL
+	}
+	instanceURL = strings.TrimSuffix(instanceURL, "/")
+
+	app, err := mastodon.RegisterApp(instanceURL, loginAppName, loginAppWebsite, loginScopes)
+	if err != nil {
+		return fmt.Errorf("failed to register the app with %s: %w", instanceURL, err)
+	}
+
+	fmt.Fprintf(out, "Open this page, log in as the bot account and authorize rss2mastodon:\n\n  %s\n\n", mastodon.AuthorizeURL(instanceURL, app, loginScopes))
+	fmt.Fprint(out, "Then paste the authorization code shown: ")
+	code, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && (err != io.EOF || code == "") {
+		return fmt.Errorf("failed to read the authorization code: %w", err)
+	}
+	if strings.TrimSpace(code) == "" {
+		return fmt.Errorf("no authorization code given")
+	}
+
+	token, err := mastodon.ObtainToken(instanceURL, app, code, loginScopes)
+	if err != nil {
+		return fmt.Errorf("failed to obtain an access token: %w", err)
+	}
+	account, err := mastodon.NewClient(mastodon.Config{URL: instanceURL, AccessToken: token}).VerifyCredentials()
+	if err != nil {
+		return fmt.Errorf("failed to verify the access token: %w", err)
+	}
+
+	values := []envSetting{
+		{Key: "MASTODON_URL", Value: instanceURL},
+		{Key: "MASTODON_ACCESS_TOKEN", Value: token},
+	}
+	var written string
+	if credentialsDir != "" {
+		written = credentialsDir
+		err = writeCredentialsDir(credentialsDir, values)
+	} else {
+		written = envFileName()
+		err = writeEnvValues(written, values)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save the access token: %w", err)
+	}
+	fmt.Fprintf(out, "\nLogged in as @%s, saved the access token to %s\n", account.Acct, written)
+	return nil
+}
+
+// writeCredentialsDir writes values as one file per key to a directory, readable only by the
+// owner, as config_dir reads them
+func writeCredentialsDir(dir string, values []envSetting) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	for _, value := range values {
+		if err := os.WriteFile(filepath.Join(dir, value.Key), []byte(value.Value+"\n"), 0o600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeEnvValues sets values in a .env file, creating it readable only by the owner if it
+// doesn't exist
+func writeEnvValues(envFile string, values []envSetting) error {
+	var content string
+	mode := os.FileMode(0o600)
+	if data, err := os.ReadFile(envFile); err == nil {
+		content = string(data)
+		if info, err := os.Stat(envFile); err == nil {
+			mode = info.Mode().Perm()
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	return os.WriteFile(envFile, []byte(setEnvValues(content, values)), mode)
+}
+
+// envSetting is a KEY=value line of a .env file
+type envSetting struct {
+	Key   string
+	Value string
+}
+
+// setEnvValues sets keys in the contents of a .env file, replacing their lines, legacy names
+// included, and appending those not set yet, keeping comments and order
+func setEnvValues(content string, values []envSetting) string {
+	byName := make(map[string]string)
+	for _, value := range values {
+		byName[value.Key] = value.Value
+	}
+
+	var lines []string
+	if content != "" {
+		lines = strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	}
+	set := make(map[string]bool)
+	var updated []string
+	for _, line := range lines {
+		key, _, ok := envLine(line)
+		if !ok {
+			updated = append(updated, line)
+			continue
+		}
+		renamed, _ := newKeyName(key)
+		name := strings.ToUpper(renamed)
+		value, isSet := byName[name]
+		switch {
+		case !isSet:
+			updated = append(updated, line)
+		case set[name]:
+			// drop further lines setting the key, e.g. under its legacy name
+		default:
+			prefix := line[:strings.Index(line, key)]
+			updated = append(updated, prefix+name+"="+value)
+			set[name] = true
+		}
+	}
+	for _, value := range values {
+		if !set[value.Key] {
+			updated = append(updated, value.Key+"="+value.Value)
+		}
+	}
+	return strings.Join(updated, "\n") + "\n"
+}
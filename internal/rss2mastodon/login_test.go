@@ -0,0 +1,98 @@
+package rss2mastodon
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Table-driven test for setting the credentials in a .env file
+func TestSetEnvValues(t *testing.T) {
+	values := []envSetting{
+		{Key: "MASTODON_URL", Value: "https://mastodon.example"},
+		{Key: "MASTODON_ACCESS_TOKEN", Value: "token"},
+	}
+
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{
+			name:     "New file",
+			content:  "",
+			expected: "MASTODON_URL=https://mastodon.example\nMASTODON_ACCESS_TOKEN=token\n",
+		},
+		{
+			name:     "Keeps other settings and comments",
+			content:  "# bot\nFEED_URL=https://example.com/rss\n",
+			expected: "# bot\nFEED_URL=https://example.com/rss\nMASTODON_URL=https://mastodon.example\nMASTODON_ACCESS_TOKEN=token\n",
+		},
+		{
+			name:     "Replaces existing settings in place",
+			content:  "export MASTODON_ACCESS_TOKEN=old\nINTERVAL=5\nMASTODON_URL=https://old.example",
+			expected: "export MASTODON_ACCESS_TOKEN=token\nINTERVAL=5\nMASTODON_URL=https://mastodon.example\n",
+		},
+		{
+			name:     "Replaces legacy names",
+			content:  "MASTODON_TOKEN=old\nMASTODON_ACCESS_TOKEN=older\n",
+			expected: "MASTODON_ACCESS_TOKEN=token\nMASTODON_URL=https://mastodon.example\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := setEnvValues(tt.content, values); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+// Test logging in and saving the access token to a credentials directory
+func TestLogin(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/apps":
+			_, _ = w.Write([]byte(`{"client_id":"id","client_secret":"secret"}`))
+		case "/oauth/token":
+			if r.FormValue("code") != "code" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			_, _ = w.Write([]byte(`{"access_token":"token"}`))
+		case "/api/v1/accounts/verify_credentials":
+			if r.Header.Get("Authorization") != "Bearer token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`{"id":"1","acct":"bot"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer mockServer.Close()
+
+	dir := filepath.Join(t.TempDir(), "secrets")
+	var out bytes.Buffer
+	if err := Login(mockServer.URL, dir, strings.NewReader("code\n"), &out); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(out.String(), mockServer.URL+"/oauth/authorize?") || !strings.Contains(out.String(), "Logged in as @bot") {
+		t.Errorf("Expected the authorization page and account, got %q", out.String())
+	}
+	for key, expected := range map[string]string{"MASTODON_URL": mockServer.URL, "MASTODON_ACCESS_TOKEN": "token"} {
+		data, err := os.ReadFile(filepath.Join(dir, key))
+		if err != nil || strings.TrimSpace(string(data)) != expected {
+			t.Errorf("Expected %s to be %q, got %q (%v)", key, expected, data, err)
+		}
+	}
+
+	if err := Login(mockServer.URL, dir, strings.NewReader("wrong\n"), &out); err == nil {
+		t.Error("Expected an error for a wrong authorization code")
+	}
+}
@@ -0,0 +1,40 @@
+package rss2mastodon
+
+import (
+	"os"
+	"runtime/debug"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// Limits of low-memory mode, for routers and single board computers with little RAM
+const (
+	// lowMemoryPostQueueSize caps how many fetched items wait for the poster
+	lowMemoryPostQueueSize = 5
+	// lowMemoryMaxItems is how many of the newest items of a feed are parsed
+	lowMemoryMaxItems = 25
+	// lowMemoryCacheKiB is SQLite's page cache per connection
+	lowMemoryCacheKiB = 256
+	// lowMemoryGCPercent collects garbage more often than Go's default of 100
+	lowMemoryGCPercent = 50
+)
+
+// applyLowMemory trades speed and bandwidth for memory if low_memory is set: the post queue
+// is shortened, feeds are parsed as a stream up to their newest items, API responses aren't
+// cached, SQLite's page cache is shrunk and garbage is collected more often
+func applyLowMemory() {
+	if !viper.GetBool("low_memory") {
+		return
+	}
+	rss.MaxItems = lowMemoryMaxItems
+	db.CacheSizeKiB = lowMemoryCacheKiB
+	// an explicit GOGC wins
+	if os.Getenv("GOGC") == "" {
+		debug.SetGCPercent(lowMemoryGCPercent)
+	}
+	log.Info("Low-memory mode enabled")
+}
@@ -0,0 +1,161 @@
+package rss2mastodon
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// legacyKeys maps renamed configuration keys to their new names. The old names are still read,
+// with a deprecation warning, for a few releases.
+var legacyKeys = map[string]string{
+	"mastodon_token": "mastodon_access_token",
+}
+
+// warnedLegacyKeys holds the legacy keys already warned about, as the config is reloaded
+var (
+	warnedLegacyKeysMu sync.Mutex
+	warnedLegacyKeys   = map[string]bool{}
+)
+
+// newKeyName returns the current name of a configuration key, and whether it is a legacy
+// name: renamed keys, or keys spelled like flags (feed-url) as older releases accepted
+func newKeyName(key string) (string, bool) {
+	key = strings.ToLower(key)
+	if renamed, ok := legacyKeys[key]; ok {
+		return renamed, true
+	}
+	if strings.Contains(key, "-") {
+		return strings.ReplaceAll(key, "-", "_"), true
+	}
+	return key, false
+}
+
+// applyLegacyKeys reads values set under legacy names into their new keys, unless the new key
+// is set too. They are set as defaults, so flags, the environment and config files still
+// take precedence.
+func applyLegacyKeys() {
+	keys := viper.AllKeys()
+	for legacy := range legacyKeys {
+		keys = append(keys, legacy)
+	}
+	for _, key := range keys {
+		renamed, isLegacy := newKeyName(key)
+		value := viper.GetString(key)
+		if !isLegacy || value == "" || viper.GetString(renamed) != "" {
+			continue
+		}
+		viper.SetDefault(renamed, value)
+		warnLegacyKey("the environment or config directory", strings.ToUpper(key), renamed)
+	}
+}
+
+// warnLegacyKey warns once that a legacy key was found in the given place
+func warnLegacyKey(where string, key string, renamed string) {
+	warnedLegacyKeysMu.Lock()
+	defer warnedLegacyKeysMu.Unlock()
+	if warnedLegacyKeys[where+key] {
+		return
+	}
+	warnedLegacyKeys[where+key] = true
+	log.Warnf("%s in %s is deprecated and will stop working in a future release, rename it to %s or run `rss2mastodon config migrate`",
+		key, where, strings.ToUpper(renamed))
+}
+
+// envChange is a legacy key renamed in a .env file, or removed as its new key is set too
+type envChange struct {
+	Key     string
+	Renamed string
+	Removed bool
+}
+
+func (c envChange) String() string {
+	if c.Removed {
+		return fmt.Sprintf("removed %s, %s is already set", c.Key, strings.ToUpper(c.Renamed))
+	}
+	return fmt.Sprintf("renamed %s to %s", c.Key, strings.ToUpper(c.Renamed))
+}
+
+// migrateEnv renames legacy keys in the contents of a .env file, keeping comments and order.
+// Legacy keys whose new key is also set are dropped. It returns the new contents and the changes.
+func migrateEnv(content string) (string, []envChange) {
+	lines := strings.Split(content, "\n")
+	present := make(map[string]bool)
+	for _, line := range lines {
+		if key, _, ok := envLine(line); ok {
+			present[strings.ToLower(key)] = true
+		}
+	}
+
+	var migrated []string
+	var changes []envChange
+	for _, line := range lines {
+		key, value, ok := envLine(line)
+		if !ok {
+			migrated = append(migrated, line)
+			continue
+		}
+		renamed, isLegacy := newKeyName(key)
+		switch {
+		case !isLegacy:
+			migrated = append(migrated, line)
+		case present[renamed]:
+			changes = append(changes, envChange{Key: key, Renamed: renamed, Removed: true})
+		default:
+			prefix := line[:strings.Index(line, key)]
+			migrated = append(migrated, prefix+strings.ToUpper(renamed)+"="+value)
+			changes = append(changes, envChange{Key: key, Renamed: renamed})
+		}
+	}
+	return strings.Join(migrated, "\n"), changes
+}
+
+// envLine splits a KEY=value line of a .env file, optionally prefixed with export
+func envLine(line string) (string, string, bool) {
+	trimmed := strings.TrimPrefix(strings.TrimSpace(line), "export ")
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return "", "", false
+	}
+	key, value, ok := strings.Cut(trimmed, "=")
+	key = strings.TrimSpace(key)
+	return key, value, ok && key != ""
+}
+
+// MigrateConfig rewrites the .env file (or the profile's .env.NAME file) to the current key
+// names, keeping the original as a .bak file. With dryRun it only prints what would change.
+func MigrateConfig(dryRun bool) error {
+	envFile := envFileName()
+	data, err := os.ReadFile(envFile)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(envFile)
+	if err != nil {
+		return err
+	}
+
+	migrated, changes := migrateEnv(string(data))
+	if len(changes) == 0 {
+		fmt.Printf("%s is up to date\n", envFile)
+		return nil
+	}
+	for _, change := range changes {
+		fmt.Printf("%s: %s\n", envFile, change)
+	}
+	if dryRun {
+		return nil
+	}
+
+	if err := os.WriteFile(envFile+".bak", data, info.Mode().Perm()); err != nil {
+		return err
+	}
+	if err := os.WriteFile(envFile, []byte(migrated), info.Mode().Perm()); err != nil {
+		return err
+	}
+	fmt.Printf("Migrated %s, the original was saved as %s.bak\n", envFile, envFile)
+	return nil
+}
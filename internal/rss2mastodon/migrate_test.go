@@ -0,0 +1,56 @@
+package rss2mastodon
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Test renaming legacy keys in a .env file
+func TestMigrateEnv(t *testing.T) {
+	tests := []struct {
+		name            string
+		content         string
+		expectedContent string
+		expectedChanges []string
+	}{
+		{
+			name:            "Up to date",
+			content:         "MASTODON_URL=https://mastodon.example\nMASTODON_ACCESS_TOKEN=secret\n",
+			expectedContent: "MASTODON_URL=https://mastodon.example\nMASTODON_ACCESS_TOKEN=secret\n",
+		},
+		{
+			name:            "Renamed key",
+			content:         "# credentials\nMASTODON_TOKEN=secret=with=equals\nINTERVAL=30",
+			expectedContent: "# credentials\nMASTODON_ACCESS_TOKEN=secret=with=equals\nINTERVAL=30",
+			expectedChanges: []string{"renamed MASTODON_TOKEN to MASTODON_ACCESS_TOKEN"},
+		},
+		{
+			name:            "Flag-style key",
+			content:         "export feed-url=https://example.com/rss\n",
+			expectedContent: "export FEED_URL=https://example.com/rss\n",
+			expectedChanges: []string{"renamed feed-url to FEED_URL"},
+		},
+		{
+			name:            "New key already set",
+			content:         "MASTODON_TOKEN=old\nMASTODON_ACCESS_TOKEN=new",
+			expectedContent: "MASTODON_ACCESS_TOKEN=new",
+			expectedChanges: []string{"removed MASTODON_TOKEN, MASTODON_ACCESS_TOKEN is already set"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, migrated := migrateEnv(tt.content)
+			var changes []string
+			for _, change := range migrated {
+				changes = append(changes, change.String())
+			}
+			if content != tt.expectedContent {
+				t.Errorf("Expected content %q, got %q", tt.expectedContent, content)
+			}
+			if !reflect.DeepEqual(changes, tt.expectedChanges) {
+				t.Errorf("Expected changes %v, got %v", tt.expectedChanges, changes)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package rss2mastodon
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+	"github.com/toozej/rss2mastodon/internal/mastodon"
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// Mastodon only accepts scheduled_at values at least 5 minutes in the future
+const minScheduleDelay = 5 * time.Minute
+
+// minCycleDelay keeps a pending post which repeatedly fails to toot from causing a busy loop
+const minCycleDelay = time.Minute
+
+// embargoPost holds a future-dated post until its pubDate, or the first time after it its
+// feed's posting schedule allows, either locally in the pending queue or, if enabled and far
+// enough ahead, as a Mastodon scheduled status
+func embargoPost(logger *log.Entry, post rss.RSSItem, published time.Time, stats *cycleStats) {
+	published = nextPostingTime(post.FeedURL, published)
+	if viper.GetBool("schedule_embargoed") && time.Until(published) > minScheduleDelay {
+		logger.Infof("Scheduling future-dated post for %s: %s", published.Format(time.RFC1123), post.Title)
+		announcePost(logger, post, mastodon.TootOptions{ScheduledAt: published}, stats)
+		return
+	}
+
+	if err := db.QueuePendingPost(post, published, db.PendingEmbargo); err != nil {
+		logger.Error("Failed to queue future-dated post: ", err)
+		return
+	}
+	logger.Debugf("Holding future-dated post until %s: %s", published.Format(time.RFC1123), post.Title)
+}
+
+// processPendingPosts announces queued posts whose time has come
+func processPendingPosts(stats *cycleStats) {
+	due, err := db.DuePendingPosts(time.Now())
+	if err != nil {
+		log.Error("Failed to read pending posts: ", err)
+		return
+	}
+
+	for _, pending := range due {
+		post := pending.Item
+		logger := feedLogger(post.FeedURL)
+		if pending.Reason == db.PendingPushed {
+			handlePushedPost(post, stats)
+			continue
+		}
+
+		exists, _, err := db.HasPostChanged(post.Link, post.Content)
+		if err != nil {
+			logger.Error("Database error: ", err)
+			continue
+		}
+
+		// e.g. embargoed posts published outside their feed's posting schedule
+		if next := nextPostingTime(post.FeedURL, time.Now()); !exists && next.After(time.Now()) {
+			logger.Infof("Holding pending post until %s, allowed by its posting schedule: %s", next.Format(time.RFC1123), post.Title)
+			if err := db.DeferPendingPost(post.Link, next); err != nil {
+				logger.Error("Failed to defer pending post: ", err)
+			}
+			continue
+		}
+
+		if !exists && !isBlocked(post) {
+			// posts beyond the daily toot budget wait for the next day, keeping their order
+			if !reserveToot() {
+				next := nextBudgetDay(time.Now())
+				logger.Infof("Holding pending post until %s, the daily toot budget is used up: %s", next.Format(time.RFC1123), post.Title)
+				if err := db.DeferPendingPost(post.Link, next); err != nil {
+					logger.Error("Failed to defer pending post: ", err)
+				}
+				continue
+			}
+			logger.Infof("Announcing pending post: %s", post.Title)
+			announced := announcePost(logger, post, mastodon.TootOptions{}, stats)
+			releaseToot()
+			if !announced {
+				// keep it queued and try again next cycle
+				continue
+			}
+		}
+
+		if err := db.RemovePendingPost(post.Link); err != nil {
+			logger.Error("Failed to remove pending post: ", err)
+		}
+	}
+}
+
+// untilNextCycle returns how long to sleep before the next cycle: the polling interval,
+// or less if a pending post becomes due sooner
+func untilNextCycle(interval time.Duration) time.Duration {
+	next, ok, err := db.NextPendingPostTime()
+	if err != nil {
+		log.Error("Failed to read pending posts: ", err)
+		return interval
+	}
+	if !ok {
+		return interval
+	}
+
+	return max(minCycleDelay, min(interval, time.Until(next)))
+}
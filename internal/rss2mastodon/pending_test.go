@@ -0,0 +1,144 @@
+package rss2mastodon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// Test future-dated posts are held until their pubDate and then announced
+func TestEmbargoedPost(t *testing.T) {
+	db.InitDB()
+	defer db.CloseDB()
+	defer os.Remove("./tooted_posts.db")
+
+	var statuses []string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		statuses = append(statuses, r.FormValue("status"))
+		_, _ = w.Write([]byte(`{"id":"1"}`))
+	}))
+	defer mockServer.Close()
+
+	viper.Reset()
+	viper.Set("mastodon_url", mockServer.URL)
+	viper.Set("mastodon_access_token", "fake-token")
+	defer viper.Reset()
+
+	published := time.Now().Add(time.Hour)
+	post := rss.RSSItem{
+		Title:   "Embargoed",
+		Link:    "https://example.com/embargoed",
+		PubDate: published.Format(time.RFC1123Z),
+	}
+
+	if !handlePost(feedLogger(post.FeedURL), post, newCycleStats()) {
+		t.Errorf("Expected embargoed post to be reported as new")
+	}
+	if len(statuses) != 0 {
+		t.Fatalf("Expected embargoed post not to be tooted yet, got %v", statuses)
+	}
+
+	if handlePost(feedLogger(post.FeedURL), post, newCycleStats()) {
+		t.Errorf("Expected post already held in the pending queue not to be reported as new again")
+	}
+
+	delay := untilNextCycle(2 * time.Hour)
+	if delay > time.Hour || delay < 59*time.Minute {
+		t.Errorf("Expected to wake up in about an hour, got %v", delay)
+	}
+
+	// pretend the embargo has passed
+	if err := db.QueuePendingPost(post, time.Now().Add(-time.Minute), db.PendingEmbargo); err != nil {
+		t.Fatalf("Failed to requeue post: %v", err)
+	}
+	processPendingPosts(newCycleStats())
+
+	if len(statuses) != 1 || statuses[0] != "New blog post: https://example.com/embargoed" {
+		t.Errorf("Expected embargoed post to be tooted once, got %v", statuses)
+	}
+	if _, ok, _ := db.NextPendingPostTime(); ok {
+		t.Errorf("Expected pending queue to be empty")
+	}
+
+	exists, _, err := db.HasPostChanged(post.Link, post.Content)
+	if err != nil || !exists {
+		t.Errorf("Expected post to be stored as tooted, got %v, %v", exists, err)
+	}
+}
+
+// Test posts already held in the pending queue follow changes of their pubDate in the feed
+func TestEmbargoedPost_PubDateChanged(t *testing.T) {
+	db.InitDB()
+	defer db.CloseDB()
+	defer os.Remove("./tooted_posts.db")
+
+	viper.Reset()
+	defer viper.Reset()
+
+	post := rss.RSSItem{
+		Title:   "Embargoed",
+		Link:    "https://example.com/embargoed",
+		PubDate: time.Now().Add(time.Hour).Format(time.RFC1123Z),
+	}
+	handlePost(feedLogger(post.FeedURL), post, newCycleStats())
+
+	// the feed postpones the post
+	later := time.Now().Add(2 * time.Hour).Truncate(time.Second)
+	post.PubDate = later.Format(time.RFC1123Z)
+	if handlePost(feedLogger(post.FeedURL), post, newCycleStats()) {
+		t.Errorf("Expected post held in the pending queue not to be reported as new again")
+	}
+	pending, err := db.GetPendingPost(post.Link)
+	if err != nil || pending == nil || pending.Reason != db.PendingEmbargo || !pending.NotBefore.Equal(later) {
+		t.Fatalf("Expected the embargo to move to %v, got %+v, %v", later, pending, err)
+	}
+
+	// the feed publishes it right away
+	post.PubDate = time.Now().Add(-time.Minute).Format(time.RFC1123Z)
+	handlePost(feedLogger(post.FeedURL), post, newCycleStats())
+	pending, err = db.GetPendingPost(post.Link)
+	if err != nil || pending == nil || pending.NotBefore.After(time.Now()) {
+		t.Errorf("Expected the post to be due, got %+v, %v", pending, err)
+	}
+}
+
+// Test future-dated posts can be sent as Mastodon scheduled statuses instead
+func TestEmbargoedPost_Scheduled(t *testing.T) {
+	db.InitDB()
+	defer db.CloseDB()
+	defer os.Remove("./tooted_posts.db")
+
+	var scheduledAt string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scheduledAt = r.FormValue("scheduled_at")
+		_, _ = w.Write([]byte(`{"id":"1"}`))
+	}))
+	defer mockServer.Close()
+
+	viper.Reset()
+	viper.Set("mastodon_url", mockServer.URL)
+	viper.Set("mastodon_access_token", "fake-token")
+	viper.Set("schedule_embargoed", true)
+	defer viper.Reset()
+
+	published := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	handlePost(feedLogger(""), rss.RSSItem{
+		Title:   "Scheduled",
+		Link:    "https://example.com/scheduled",
+		PubDate: published.Format(time.RFC1123Z),
+	}, newCycleStats())
+
+	if scheduledAt != published.Format(time.RFC3339) {
+		t.Errorf("Expected scheduled_at '%s', got '%s'", published.Format(time.RFC3339), scheduledAt)
+	}
+	if _, ok, _ := db.NextPendingPostTime(); ok {
+		t.Errorf("Expected scheduled post not to be queued locally")
+	}
+}
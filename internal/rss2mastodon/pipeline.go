@@ -0,0 +1,250 @@
+package rss2mastodon
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+	"github.com/toozej/rss2mastodon/internal/metrics"
+	"github.com/toozej/rss2mastodon/internal/notify"
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// defaultPostQueueSize is how many items can wait for the poster before fetching blocks
+const defaultPostQueueSize = 100
+
+// defaultFeedConcurrency is how many feeds are fetched at the same time when not configured
+const defaultFeedConcurrency = 8
+
+// postJob is a feed item, a burst of new items to toot as a digest, or the items removed from
+// a feed whose statuses to delete, waiting to be handled by the poster. The last job of each
+// feed only carries the feed, so its health is checked once all of its items have been handled.
+type postJob struct {
+	post    *rss.RSSItem
+	burst   *feedBurst
+	removed []db.ItemPresence
+	feed    *feedResult
+}
+
+// feedResult collects the outcome of handling a feed's items
+type feedResult struct {
+	url string
+	// log is the feed's logger, passed on to everything handling its items
+	log           *log.Entry
+	lastBuildDate string
+	// validators of the feed's response, stored once its items have been handled
+	validators *feedValidators
+	// newItems and retry are only touched by the poster
+	newItems int
+	// retry is set when an item has to be handled again, so the feed mustn't be skipped as unchanged
+	retry bool
+}
+
+// pollFeeds polls the feeds by priority, so the items of higher priority feeds are posted
+// before those of lower priority ones
+func pollFeeds(feeds []FeedConfig, stats *cycleStats) {
+	for _, tier := range priorityTiers(unpausedFeeds(feeds)) {
+		pollTier(tier, stats)
+	}
+}
+
+// pollTier fetches the feeds concurrently and hands their items to a separate poster over a
+// bounded queue, so a slow Mastodon instance doesn't delay fetching the other feeds until the
+// queue fills up. Each feed's items are queued in the feeds' order, however fast they are fetched.
+func pollTier(feeds []FeedConfig, stats *cycleStats) {
+	size := viper.GetInt("post_queue_size")
+	if size <= 0 {
+		size = defaultPostQueueSize
+	}
+	if viper.GetBool("low_memory") {
+		size = min(size, lowMemoryPostQueueSize)
+	}
+	queue := make(chan postJob, size)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		postWorker(queue, stats)
+	}()
+
+	// up to feedConcurrency feeds are fetched at a time, each into its own queue, which are
+	// forwarded to the poster one feed after the other
+	feedQueues := make([]chan postJob, len(feeds))
+	for i := range feedQueues {
+		feedQueues[i] = make(chan postJob, size)
+	}
+	next := make(chan int)
+	go func() {
+		defer close(next)
+		for i := range feeds {
+			next <- i
+		}
+	}()
+	var fetchers sync.WaitGroup
+	for range min(feedConcurrency(), len(feeds)) {
+		fetchers.Add(1)
+		go func() {
+			defer fetchers.Done()
+			for i := range next {
+				fetchFeed(feeds[i], feedQueues[i], stats)
+				close(feedQueues[i])
+			}
+		}()
+	}
+	for _, feedQueue := range feedQueues {
+		for job := range feedQueue {
+			queue <- job
+		}
+	}
+	fetchers.Wait()
+	close(queue)
+	<-done
+}
+
+// feedConcurrency returns how many feeds may be fetched at the same time, only one in
+// low-memory mode so a single feed is held in memory at a time
+func feedConcurrency() int {
+	if viper.GetBool("low_memory") {
+		return 1
+	}
+	if concurrency := viper.GetInt("feed_concurrency"); concurrency > 0 {
+		return concurrency
+	}
+	return defaultFeedConcurrency
+}
+
+// fetchFeed fetches a feed and queues each of its items for the poster
+func fetchFeed(source FeedConfig, queue chan<- postJob, stats *cycleStats) {
+	stats.feedsPolled.Add(1)
+	logger := feedLogger(source.URL)
+
+	// the URL as configured identifies the feed, even when it expands to a different URL each period
+	feed, validators, err := source.fetchIfModified(time.Now())
+	if errors.Is(err, rss.ErrNotModified) {
+		logger.Debug("Feed has not changed since it was last fetched")
+		notify.Success(source.URL)
+		queue <- postJob{feed: &feedResult{url: source.URL, log: logger, lastBuildDate: storedBuildDate(source.URL)}}
+		return
+	}
+	if err != nil {
+		logger.Printf("Error fetching RSS feed: %v", err)
+		recordError(source.URL, err)
+		notify.Failure(source.URL, err)
+		stats.failed.Add(1)
+		stats.feedErrors.Add(1)
+		return
+	}
+	notify.Success(source.URL)
+	logger = withFeedName(logger, feed, source.URL)
+	logger.Debugf("Fetched %d items", len(feed.Channel.Items))
+
+	result := &feedResult{url: source.URL, log: logger, lastBuildDate: feed.Channel.LastBuildDate, validators: validators}
+	previous, now := previousFetch(source.URL), time.Now()
+	var posts []rss.RSSItem
+	fresh := 0
+	for _, post := range feed.Channel.Items {
+		stats.itemsSeen.Add(1)
+		if !source.includes(post) {
+			continue
+		}
+		post.FeedURL = source.URL
+		canonicalizeLink(source, &post)
+		// on a feed's first fetch every item is new, however old
+		if trackPresence(source.URL, &post, previous, now) {
+			fresh++
+			if !previous.IsZero() {
+				observeLatency(source.URL, post, now)
+			}
+		}
+		if source.Planet {
+			post.Source = sourceName(feed, source.URL)
+		}
+		posts = append(posts, post)
+	}
+
+	// a burst of new items is tooted as one digest, the feed's other items are handled as usual
+	inBurst := map[string]bool{}
+	if burst := detectBurst(posts); burst != nil {
+		for _, post := range burst {
+			inBurst[post.Link] = true
+		}
+		queue <- postJob{burst: &feedBurst{name: sourceName(feed, source.URL), posts: burst}, feed: result}
+	}
+	for _, post := range posts {
+		if !inBurst[post.Link] {
+			queue <- postJob{post: &post, feed: result}
+		}
+	}
+	if removed := removedItems(logger, source.URL, previous, len(posts), fresh); len(removed) > 0 {
+		queue <- postJob{removed: removed, feed: result}
+	}
+	queue <- postJob{feed: result}
+}
+
+// postWorker handles queued items until the queue is closed
+func postWorker(queue <-chan postJob, stats *cycleStats) {
+	for job := range queue {
+		metrics.Gauge("post_queue.depth", int64(len(queue)))
+
+		if job.burst != nil {
+			if handleBurst(job.feed.log, job.feed.url, job.burst, stats) {
+				job.feed.newItems += len(job.burst.posts)
+			} else {
+				job.feed.retry = true
+			}
+			continue
+		}
+		if job.removed != nil {
+			deleteRemovedItems(job.feed.log, job.removed, stats)
+			continue
+		}
+		if job.post == nil {
+			checkFeedHealth(job.feed.url, job.feed.newItems, job.feed.lastBuildDate)
+			storeValidators(job.feed)
+			continue
+		}
+		failed := stats.failed.Load()
+		if handlePost(job.feed.log, *job.post, stats) {
+			job.feed.newItems++
+		}
+		if job.feed.validators != nil && !job.feed.retry {
+			job.feed.retry = stats.failed.Load() > failed || hasDeliveryFailures(job.post.Link)
+		}
+	}
+}
+
+// storeValidators stores the validators of a feed's response once its items have been handled,
+// unless one of them has to be retried, which requires fetching the feed in full again
+func storeValidators(result *feedResult) {
+	if result.validators == nil || result.retry {
+		return
+	}
+	if err := db.StoreFeedValidators(result.url, result.validators.requestURL, result.validators.validators); err != nil {
+		result.log.Error("Failed to store the feed's ETag and Last-Modified: ", err)
+	}
+}
+
+// hasDeliveryFailures reports whether a post failed to be announced and will be retried, e.g.
+// while its page isn't live yet
+func hasDeliveryFailures(link string) bool {
+	failures, err := db.HasDeliveryFailures(link)
+	if err != nil {
+		log.Error("Database error: ", err)
+		return true
+	}
+	return failures
+}
+
+// storedBuildDate returns the last lastBuildDate recorded for a feed, for feeds which haven't
+// changed since
+func storedBuildDate(feedURL string) string {
+	health, err := db.GetFeedHealth(feedURL)
+	if err != nil || health == nil {
+		return ""
+	}
+	return health.LastBuildDate
+}
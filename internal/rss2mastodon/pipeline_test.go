@@ -0,0 +1,175 @@
+package rss2mastodon
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+)
+
+// Test every feed's items are handled and its health recorded through a small post queue
+func TestPollFeeds_Backpressure(t *testing.T) {
+	db.InitDB()
+	defer db.CloseDB()
+	defer os.Remove("./tooted_posts.db")
+
+	var toots int
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a.xml":
+			_, _ = w.Write([]byte(`<rss><channel><lastBuildDate>a</lastBuildDate>
+				<item><title>A1</title><link>https://a.example.com/1</link></item>
+				<item><title>A2</title><link>https://a.example.com/2</link></item>
+				<item><title>A3</title><link>https://a.example.com/3</link></item>
+			</channel></rss>`))
+		case "/b.xml":
+			_, _ = w.Write([]byte(`<rss><channel><lastBuildDate>b</lastBuildDate></channel></rss>`))
+		case "/api/v1/statuses":
+			// a slow instance
+			time.Sleep(20 * time.Millisecond)
+			toots++
+			_, _ = w.Write([]byte(`{"id":"1"}`))
+		}
+	}))
+	defer mockServer.Close()
+
+	viper.Reset()
+	viper.Set("mastodon_url", mockServer.URL)
+	viper.Set("mastodon_access_token", "fake-token")
+	viper.Set("post_queue_size", 1)
+	defer viper.Reset()
+
+	stats := newCycleStats()
+	pollFeeds([]FeedConfig{{URL: mockServer.URL + "/a.xml"}, {URL: mockServer.URL + "/b.xml"}}, stats)
+
+	if toots != 3 || stats.tooted.Load() != 3 {
+		t.Errorf("Expected 3 toots, got %d (%d counted)", toots, stats.tooted.Load())
+	}
+
+	for _, feed := range []string{"/a.xml", "/b.xml"} {
+		health, err := db.GetFeedHealth(mockServer.URL + feed)
+		if err != nil || health == nil {
+			t.Errorf("Expected health of %s to be recorded, got %v, %v", feed, health, err)
+		}
+	}
+}
+
+// Test unchanged feeds are skipped with conditional requests, unless an item has to be retried
+func TestPollFeeds_ConditionalGet(t *testing.T) {
+	db.InitDB()
+	defer db.CloseDB()
+	defer os.Remove("./tooted_posts.db")
+
+	var conditional []bool
+	tootFails := true
+	toots := 0
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/feed.xml":
+			conditional = append(conditional, r.Header.Get("If-None-Match") != "")
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", `"v1"`)
+			_, _ = w.Write([]byte(`<rss><channel><item><title>Post</title><link>https://example.com/post</link></item></channel></rss>`))
+		case "/api/v1/statuses":
+			if tootFails {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			toots++
+			_, _ = w.Write([]byte(`{"id":"1"}`))
+		}
+	}))
+	defer mockServer.Close()
+
+	viper.Reset()
+	viper.Set("mastodon_url", mockServer.URL)
+	viper.Set("mastodon_access_token", "fake-token")
+	viper.Set("feed_url", mockServer.URL+"/feed.xml")
+	viper.Set("conditional_get", true)
+	defer viper.Reset()
+
+	feeds, err := configuredFeeds()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// the first toot fails, so the feed is fetched in full again to retry it
+	pollFeeds(feeds, newCycleStats())
+	tootFails = false
+	pollFeeds(feeds, newCycleStats())
+	pollFeeds(feeds, newCycleStats())
+
+	expected := []bool{false, false, true}
+	if !reflect.DeepEqual(conditional, expected) {
+		t.Errorf("Expected conditional requests %v, got %v", expected, conditional)
+	}
+	if toots != 1 {
+		t.Errorf("Expected the post to be tooted once, got %d", toots)
+	}
+}
+
+// Test feeds are fetched by a bounded number of fetchers and their items posted in the feeds' order
+func TestPollFeeds_Concurrency(t *testing.T) {
+	db.InitDB()
+	defer db.CloseDB()
+	defer os.Remove("./tooted_posts.db")
+
+	var mu sync.Mutex
+	var fetching, maxFetching int
+	var statuses []string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/statuses" {
+			mu.Lock()
+			statuses = append(statuses, r.FormValue("status"))
+			mu.Unlock()
+			_, _ = w.Write([]byte(`{"id":"1"}`))
+			return
+		}
+		mu.Lock()
+		fetching++
+		maxFetching = max(maxFetching, fetching)
+		mu.Unlock()
+		// the first feed is the slowest
+		if r.URL.Path == "/0.xml" {
+			time.Sleep(50 * time.Millisecond)
+		} else {
+			time.Sleep(10 * time.Millisecond)
+		}
+		mu.Lock()
+		fetching--
+		mu.Unlock()
+		_, _ = fmt.Fprintf(w, `<rss><channel><item><title>Post</title><link>https://example.com%s</link></item></channel></rss>`, r.URL.Path)
+	}))
+	defer mockServer.Close()
+
+	viper.Reset()
+	viper.Set("mastodon_url", mockServer.URL)
+	viper.Set("mastodon_access_token", "fake-token")
+	viper.Set("feed_concurrency", 2)
+	defer viper.Reset()
+
+	var feeds []FeedConfig
+	var expected []string
+	for i := 0; i < 5; i++ {
+		feeds = append(feeds, FeedConfig{URL: fmt.Sprintf("%s/%d.xml", mockServer.URL, i)})
+		expected = append(expected, fmt.Sprintf("New blog post: https://example.com/%d.xml", i))
+	}
+	pollFeeds(feeds, newCycleStats())
+
+	if maxFetching > 2 {
+		t.Errorf("Expected at most 2 feeds fetched at a time, got %d", maxFetching)
+	}
+	if !reflect.DeepEqual(statuses, expected) {
+		t.Errorf("Expected toots in the feeds' order %v, got %v", expected, statuses)
+	}
+}
@@ -0,0 +1,33 @@
+package rss2mastodon
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// planetFeeds returns the member feed URLs of the planet, from the comma-separated planet_feeds
+func planetFeeds() []string {
+	var feeds []string
+	for _, feedURL := range strings.Split(viper.GetString("planet_feeds"), ",") {
+		if feedURL = strings.TrimSpace(feedURL); feedURL != "" {
+			feeds = append(feeds, feedURL)
+		}
+	}
+	return feeds
+}
+
+// sourceName returns the name a planet member's posts are attributed to: its channel title,
+// or the feed's host name if it has none
+func sourceName(feed *rss.RSSFeed, feedURL string) string {
+	if title := strings.TrimSpace(feed.Channel.Title); title != "" {
+		return title
+	}
+	if u, err := url.Parse(feedURL); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return feedURL
+}
@@ -0,0 +1,64 @@
+package rss2mastodon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+)
+
+// Test planet member posts are tooted with their source blog's name
+func TestPollFeed_Planet(t *testing.T) {
+	db.InitDB()
+	defer db.CloseDB()
+	defer os.Remove("./tooted_posts.db")
+
+	var statuses []string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/alice.xml":
+			_, _ = w.Write([]byte(`<rss><channel><title>Alice's Blog</title>
+				<item><title>Hello</title><link>https://alice.example.com/hello</link></item>
+			</channel></rss>`))
+		case "/untitled.xml":
+			_, _ = w.Write([]byte(`<rss><channel>
+				<item><title>Hi</title><link>https://bob.example.com/hi</link></item>
+			</channel></rss>`))
+		case "/api/v1/statuses":
+			statuses = append(statuses, r.FormValue("status"))
+			_, _ = w.Write([]byte(`{"id":"1"}`))
+		}
+	}))
+	defer mockServer.Close()
+
+	viper.Reset()
+	viper.Set("mastodon_url", mockServer.URL)
+	viper.Set("mastodon_access_token", "fake-token")
+	viper.Set("planet_feeds", mockServer.URL+"/alice.xml, "+mockServer.URL+"/untitled.xml,")
+	defer viper.Reset()
+
+	feeds, err := configuredFeeds()
+	if err != nil || len(feeds) != 2 || !feeds[0].Planet || !feeds[1].Planet {
+		t.Fatalf("Expected 2 planet feeds, got %v", feeds)
+	}
+
+	pollFeeds(feeds, newCycleStats())
+
+	host := mockServer.Listener.Addr().String()
+	expected := []string{
+		"Alice's Blog: New blog post: https://alice.example.com/hello",
+		host + ": New blog post: https://bob.example.com/hi",
+	}
+	if len(statuses) != len(expected) {
+		t.Fatalf("Expected toots %v, got %v", expected, statuses)
+	}
+	for i := range expected {
+		if statuses[i] != expected[i] {
+			t.Errorf("Expected toot '%s', got '%s'", expected[i], statuses[i])
+		}
+	}
+}
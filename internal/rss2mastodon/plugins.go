@@ -0,0 +1,99 @@
+package rss2mastodon
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/toozej/rss2mastodon/internal/notify"
+	"github.com/toozej/rss2mastodon/internal/source"
+)
+
+// Kinds of plugins
+const (
+	PluginSource    = "source"
+	PluginPublisher = "publisher"
+	PluginNotifier  = "notifier"
+)
+
+// Plugin describes an adapter compiled into this build: a source items are fetched from, a
+// publisher they are announced on or a notifier alerts are sent to
+type Plugin struct {
+	Kind        string
+	Name        string
+	Description string
+	// Options are the per-feed options of a source
+	Options []string
+	// ConfigKeys are the global configuration keys the plugin reads
+	ConfigKeys   []string
+	Capabilities []string
+}
+
+// Plugins returns the plugins compiled into this build, sources first
+func Plugins() []Plugin {
+	plugins := []Plugin{{
+		Kind:         PluginSource,
+		Name:         "rss",
+		Description:  "RSS feeds, the default type",
+		Capabilities: []string{"fetch"},
+	}}
+	for _, name := range source.Names() {
+		s, _ := source.Get(name)
+		plugins = append(plugins, Plugin{
+			Kind:         PluginSource,
+			Name:         s.Name,
+			Description:  s.Description,
+			Options:      s.Options,
+			ConfigKeys:   s.ConfigKeys,
+			Capabilities: s.Capabilities(),
+		})
+	}
+
+	plugins = append(plugins, Plugin{
+		Kind:         PluginPublisher,
+		Name:         "mastodon",
+		Description:  "Mastodon account toots are posted from",
+		ConfigKeys:   []string{"mastodon_url", "mastodon_access_token"},
+		Capabilities: []string{"post", "media", "boost", "engagement"},
+	})
+
+	for _, n := range notify.Notifiers() {
+		plugins = append(plugins, Plugin{
+			Kind:         PluginNotifier,
+			Name:         n.Name,
+			Description:  n.Description,
+			ConfigKeys:   n.ConfigKeys,
+			Capabilities: n.Capabilities,
+		})
+	}
+	return plugins
+}
+
+// ListPlugins prints the plugins compiled into this build
+func ListPlugins(cmd *cobra.Command, args []string) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tNAME\tCAPABILITIES\tOPTIONS\tCONFIG\tDESCRIPTION")
+	for _, p := range Plugins() {
+		var keys []string
+		for _, key := range p.ConfigKeys {
+			keys = append(keys, strings.ToUpper(key))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", p.Kind, p.Name, strings.Join(p.Capabilities, ","),
+			orDash(strings.Join(p.Options, ",")), orDash(strings.Join(keys, ",")), p.Description)
+	}
+	if err := w.Flush(); err != nil {
+		log.Fatal("Failed to list plugins: ", err)
+	}
+}
+
+// orDash returns value, or a dash if it is empty so table columns stay aligned
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,32 @@
+package rss2mastodon
+
+import (
+	"slices"
+	"testing"
+)
+
+// Test every plugin is described and its config keys are shown by config show
+func TestPlugins(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, p := range Plugins() {
+		id := p.Kind + "/" + p.Name
+		if seen[id] {
+			t.Errorf("%s is listed twice", id)
+		}
+		seen[id] = true
+		if p.Description == "" || len(p.Capabilities) == 0 {
+			t.Errorf("%s has no description or capabilities", id)
+		}
+		for _, key := range p.ConfigKeys {
+			if !slices.Contains(envOnlyKeys, key) {
+				t.Errorf("%s reads %s, which config show doesn't know", id, key)
+			}
+		}
+	}
+
+	for _, expected := range []string{"source/rss", "source/github", "source/youtube", "publisher/mastodon", "notifier/ntfy"} {
+		if !seen[expected] {
+			t.Errorf("%s isn't listed", expected)
+		}
+	}
+}
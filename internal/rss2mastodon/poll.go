@@ -0,0 +1,59 @@
+package rss2mastodon
+
+import (
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/mastodon"
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// stylePoll is the announcement style of posts announced as polls
+const stylePoll = "poll"
+
+// minPollOptions is how many options a poll needs at least
+const minPollOptions = 2
+
+// tootPoll returns the poll to announce the post with: the options of the first poll_rule
+// matching it, e.g. "ideas=Go|Rust|Zig", or nil if none does
+func tootPoll(post rss.RSSItem) *mastodon.Poll {
+	for _, rule := range listSetting("poll_rule") {
+		keyword, choices, ok := strings.Cut(rule, "=")
+		keyword = strings.TrimSpace(keyword)
+		if !ok || keyword == "" || !matchesKeyword(post, keyword) {
+			continue
+		}
+		var options []string
+		for _, option := range strings.Split(choices, "|") {
+			if option = strings.TrimSpace(option); option != "" {
+				options = append(options, option)
+			}
+		}
+		if len(options) < minPollOptions {
+			log.Warnf("Ignoring poll rule %q with fewer than %d options", rule, minPollOptions)
+			continue
+		}
+		return &mastodon.Poll{
+			Options:   options,
+			ExpiresIn: viper.GetDuration("poll_duration"),
+			Multiple:  viper.GetBool("poll_multiple"),
+		}
+	}
+	return nil
+}
+
+// pollContent returns the text of a post's poll announcement rendered from poll_template, or
+// the regular announcement if the template fails
+func pollContent(post rss.RSSItem, content string) string {
+	text, err := renderTemplate("poll", viper.GetString("poll_template"), post)
+	if err != nil {
+		log.Error("Invalid poll template: ", err)
+		return content
+	}
+	if text = strings.TrimSpace(text); text == "" {
+		return content
+	}
+	return text
+}
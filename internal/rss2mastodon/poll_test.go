@@ -0,0 +1,90 @@
+package rss2mastodon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// Table-driven test for picking the poll options of a post from the rules
+func TestTootPoll(t *testing.T) {
+	rules := []string{"ideas=Go | Rust|Zig", "single=Yes", "=No keyword|Yes", "vote = A|B"}
+	tests := []struct {
+		name     string
+		post     rss.RSSItem
+		expected []string
+	}{
+		{"Rule matching the link", rss.RSSItem{Link: "https://example.com/ideas/1"}, []string{"Go", "Rust", "Zig"}},
+		{"Rule matching a category", rss.RSSItem{Link: "https://example.com/1", Categories: []string{"Vote"}}, []string{"A", "B"}},
+		{"Rule with one option ignored", rss.RSSItem{Link: "https://example.com/single"}, nil},
+		{"No rule matching", rss.RSSItem{Link: "https://example.com/1"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			viper.Set("poll_rule", rules)
+			viper.Set("poll_duration", time.Hour)
+			defer viper.Reset()
+
+			poll := tootPoll(tt.post)
+			if tt.expected == nil {
+				if poll != nil {
+					t.Errorf("Expected no poll, got %+v", poll)
+				}
+				return
+			}
+			if poll == nil || !reflect.DeepEqual(poll.Options, tt.expected) || poll.ExpiresIn != time.Hour {
+				t.Errorf("Expected a poll with options %v open for an hour, got %+v", tt.expected, poll)
+			}
+		})
+	}
+}
+
+// Test announcing a post matching a poll rule as a poll rendered from the poll template
+func TestPollFeeds_Poll(t *testing.T) {
+	db.InitDB()
+	defer db.CloseDB()
+	defer os.Remove("./tooted_posts.db")
+
+	var form url.Values
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/feed.xml":
+			_, _ = w.Write([]byte(`<rss><channel><title>Blog</title>
+				<item><title>Next topic</title><link>https://example.com/next-topic</link><category>ideas</category></item>
+			</channel></rss>`))
+		case "/api/v1/statuses":
+			_ = r.ParseForm()
+			form = r.PostForm
+			_, _ = w.Write([]byte(`{"id":"1"}`))
+		}
+	}))
+	defer mockServer.Close()
+
+	viper.Reset()
+	viper.Set("mastodon_url", mockServer.URL)
+	viper.Set("mastodon_access_token", "fake-token")
+	viper.Set("poll_rule", []string{"ideas=Go|Rust"})
+	viper.Set("poll_template", "{{.Title}}: what next?")
+	viper.Set("poll_duration", 24*time.Hour)
+	defer viper.Reset()
+
+	pollFeeds([]FeedConfig{{URL: mockServer.URL + "/feed.xml"}}, newCycleStats())
+	if form.Get("status") != "Next topic: what next?" || len(form["poll[options][]"]) != 2 || form.Get("poll[expires_in]") != "86400" {
+		t.Fatalf("Expected the post announced as a poll, got %v", form)
+	}
+	statuses, err := db.LinkStatuses("https://example.com/next-topic")
+	if err != nil || len(statuses) != 1 || statuses[0].Style != stylePoll {
+		t.Errorf("Expected the status recorded as a poll, got %+v, %v", statuses, err)
+	}
+}
@@ -0,0 +1,53 @@
+package rss2mastodon
+
+import (
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// previousFetch returns when the feed's items were last seen, zero if never
+func previousFetch(feedURL string) time.Time {
+	lastSeen, err := db.FeedLastSeen(feedURL)
+	if err != nil {
+		log.Error("Failed to look up the feed's previous fetch: ", err)
+	}
+	return lastSeen
+}
+
+// trackPresence records that an item was seen in its feed now. Items are identified by their
+// GUID (or link), so an item removed from the feed and added back, even under another link,
+// keeps the link it was first seen with and isn't announced again. Items missing from the
+// feed's previous fetch are flagged as reappeared. It returns whether the item was seen for the
+// first time.
+func trackPresence(feedURL string, post *rss.RSSItem, previous time.Time, now time.Time) bool {
+	id := strings.TrimSpace(post.GUID)
+	if id == "" {
+		id = post.Link
+	}
+	if id == "" {
+		return false
+	}
+	presence, err := db.RecordItemSeen(feedURL, id, post.Link, now)
+	if err != nil {
+		log.Error("Failed to record the item as seen: ", err)
+		return false
+	}
+	// broken feeds reuse a GUID for several items, which are still told apart by their links
+	if presence.LastSeen.Equal(now) {
+		return false
+	}
+	if presence.Link != post.Link {
+		log.Debugf("Item %s was first seen as %s, not %s", id, presence.Link, post.Link)
+		post.Link = presence.Link
+	}
+	if !presence.LastSeen.IsZero() && presence.LastSeen.Before(previous) {
+		post.Reappeared = true
+		log.Infof("Item reappeared in %s after being missing since %s: %s", feedURL, presence.LastSeen.Format(time.RFC1123), post.Link)
+	}
+	return presence.LastSeen.IsZero()
+}
@@ -0,0 +1,63 @@
+package rss2mastodon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+)
+
+// Test items removed from a feed and added back aren't announced again
+func TestPollFeeds_ReappearedItems(t *testing.T) {
+	// the items' presence is kept in the database, which has to start out and be left empty,
+	// including when the test runs again with -count
+	_ = os.Remove("./tooted_posts.db")
+	db.InitDB()
+	t.Cleanup(func() {
+		db.CloseDB()
+		_ = os.Remove("./tooted_posts.db")
+		viper.Reset()
+	})
+
+	// the item is removed on the second fetch and added back re-rendered, under a new link
+	fetches := []string{
+		`<item><title>Post</title><guid>post-1</guid><link>https://example.com/post</link><description>Body</description></item>`,
+		``,
+		`<item><title>Post</title><guid>post-1</guid><link>https://example.com/post?ref=readded</link><description>Re-rendered body</description></item>`,
+	}
+	fetch := 0
+	var toots []string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/feed.xml":
+			_, _ = w.Write([]byte(`<rss><channel>` + fetches[fetch] +
+				`<item><title>Other</title><guid>other</guid><link>https://example.com/other</link></item></channel></rss>`))
+			fetch++
+		case "/api/v1/statuses":
+			toots = append(toots, r.FormValue("status"))
+			_, _ = w.Write([]byte(`{"id":"1"}`))
+		}
+	}))
+	defer mockServer.Close()
+
+	viper.Reset()
+	viper.Set("mastodon_url", mockServer.URL)
+	viper.Set("mastodon_access_token", "fake-token")
+	viper.Set("feed_url", mockServer.URL+"/feed.xml")
+
+	feeds, err := configuredFeeds()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for range fetches {
+		pollFeeds(feeds, newCycleStats())
+	}
+
+	if len(toots) != 2 {
+		t.Errorf("Expected only the first fetch to be announced, got %v", toots)
+	}
+}
@@ -0,0 +1,113 @@
+package rss2mastodon
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Feed priorities, which select how often a feed is polled and whose items are posted first
+const (
+	priorityHigh   = "high"
+	priorityNormal = "normal"
+	priorityLow    = "low"
+)
+
+// priorityRanks orders the priorities, highest first
+var priorityRanks = map[string]int{priorityHigh: 0, priorityNormal: 1, priorityLow: 2}
+
+// validatePriority checks a feed's priority option
+func validatePriority(priority string) error {
+	if _, ok := priorityRanks[priority]; priority != "" && !ok {
+		return fmt.Errorf("invalid priority %q, expected %s, %s or %s", priority, priorityHigh, priorityNormal, priorityLow)
+	}
+	return nil
+}
+
+// rank returns the feed's position in the order feeds are posted in, high priority first
+func (f FeedConfig) rank() int {
+	if rank, ok := priorityRanks[f.Priority]; ok {
+		return rank
+	}
+	return priorityRanks[priorityNormal]
+}
+
+// pollInterval returns how often the feed is polled: every high_priority_interval or
+// low_priority_interval minutes for high and low priority feeds, if set, and every interval
+// minutes otherwise
+func (f FeedConfig) pollInterval() time.Duration {
+	minutes := viper.GetInt("interval")
+	switch f.Priority {
+	case priorityHigh:
+		if high := viper.GetInt("high_priority_interval"); high > 0 {
+			minutes = high
+		}
+	case priorityLow:
+		if low := viper.GetInt("low_priority_interval"); low > 0 {
+			minutes = low
+		}
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// priorityTiers groups feeds by priority, highest first, keeping their order within a tier
+func priorityTiers(feeds []FeedConfig) [][]FeedConfig {
+	sorted := append([]FeedConfig(nil), feeds...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].rank() < sorted[j].rank() })
+
+	var tiers [][]FeedConfig
+	for i, feed := range sorted {
+		if i == 0 || feed.rank() != sorted[i-1].rank() {
+			tiers = append(tiers, nil)
+		}
+		tiers[len(tiers)-1] = append(tiers[len(tiers)-1], feed)
+	}
+	return tiers
+}
+
+// pollSchedule keeps track of when each feed is polled next, according to its priority
+type pollSchedule struct {
+	next map[string]time.Time
+}
+
+// newPollSchedule returns a schedule which polls every feed right away
+func newPollSchedule() *pollSchedule {
+	return &pollSchedule{next: map[string]time.Time{}}
+}
+
+// due returns the feeds to poll now: those whose interval has passed since they were last
+// polled, and those requested to be polled, all of them if the empty URL was requested
+func (s *pollSchedule) due(feeds []FeedConfig, requested map[string]bool, now time.Time) []FeedConfig {
+	var due []FeedConfig
+	for _, feed := range feeds {
+		if next, ok := s.next[feed.URL]; !ok || !now.Before(next) || requested[feed.URL] || requested[""] {
+			due = append(due, feed)
+		}
+	}
+	return due
+}
+
+// polled records that the feeds were polled at the given time
+func (s *pollSchedule) polled(feeds []FeedConfig, now time.Time) {
+	for _, feed := range feeds {
+		s.next[feed.URL] = now.Add(feed.pollInterval())
+	}
+}
+
+// nextPoll returns when the first of the feeds is due to be polled next, or after the
+// interval if there are none
+func (s *pollSchedule) nextPoll(feeds []FeedConfig, now time.Time) time.Time {
+	next := now.Add(time.Duration(viper.GetInt("interval")) * time.Minute)
+	for i, feed := range feeds {
+		due, ok := s.next[feed.URL]
+		if !ok {
+			due = now
+		}
+		if i == 0 || due.Before(next) {
+			next = due
+		}
+	}
+	return next
+}
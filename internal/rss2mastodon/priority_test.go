@@ -0,0 +1,114 @@
+package rss2mastodon
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Table-driven test for grouping feeds into priority tiers
+func TestPriorityTiers(t *testing.T) {
+	blog := FeedConfig{URL: "https://example.com/rss", Priority: priorityHigh}
+	friend := FeedConfig{URL: "https://friend.example.com/rss"}
+	news := FeedConfig{URL: "https://news.example.com/rss", Priority: priorityNormal}
+	archive := FeedConfig{URL: "https://archive.example.com/rss", Priority: priorityLow}
+
+	tests := []struct {
+		name     string
+		feeds    []FeedConfig
+		expected [][]FeedConfig
+	}{
+		{"No feeds", nil, nil},
+		{"Single tier", []FeedConfig{friend, news}, [][]FeedConfig{{friend, news}}},
+		{"Highest first", []FeedConfig{archive, friend, blog, news}, [][]FeedConfig{{blog}, {friend, news}, {archive}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := priorityTiers(tt.feeds); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+// Table-driven test for the poll interval of each priority
+func TestPollInterval(t *testing.T) {
+	tests := []struct {
+		name     string
+		priority string
+		high     int
+		low      int
+		expected time.Duration
+	}{
+		{"Normal", "", 5, 240, time.Hour},
+		{"High", priorityHigh, 5, 240, 5 * time.Minute},
+		{"Low", priorityLow, 5, 240, 4 * time.Hour},
+		{"High without its own interval", priorityHigh, 0, 240, time.Hour},
+		{"Low without its own interval", priorityLow, 5, 0, time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Set("interval", 60)
+			viper.Set("high_priority_interval", tt.high)
+			viper.Set("low_priority_interval", tt.low)
+			defer viper.Reset()
+
+			if got := (FeedConfig{Priority: tt.priority}).pollInterval(); got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+// Test feeds are polled as often as their priority says, and in between on request
+func TestPollSchedule(t *testing.T) {
+	viper.Set("interval", 60)
+	viper.Set("high_priority_interval", 5)
+	defer viper.Reset()
+
+	blog := FeedConfig{URL: "https://example.com/rss", Priority: priorityHigh}
+	news := FeedConfig{URL: "https://news.example.com/rss"}
+	feeds := []FeedConfig{blog, news}
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	schedule := newPollSchedule()
+	if next := schedule.nextPoll(feeds, start); !next.Equal(start) {
+		t.Errorf("Expected unpolled feeds to be due right away, got %v", next)
+	}
+	if due := schedule.due(feeds, nil, start); !reflect.DeepEqual(due, feeds) {
+		t.Fatalf("Expected every feed to be due at first, got %v", due)
+	}
+	schedule.polled(feeds, start)
+
+	tests := []struct {
+		name      string
+		at        time.Duration
+		requested map[string]bool
+		expected  []FeedConfig
+		next      time.Duration
+	}{
+		{"Nothing due", time.Minute, nil, nil, 5 * time.Minute},
+		{"High priority due", 5 * time.Minute, nil, []FeedConfig{blog}, 10 * time.Minute},
+		{"Requested feed", 7 * time.Minute, map[string]bool{news.URL: true}, []FeedConfig{news}, 10 * time.Minute},
+		{"All feeds requested", 8 * time.Minute, map[string]bool{"": true}, feeds, 13 * time.Minute},
+		{"Both due", 68 * time.Minute, nil, feeds, 73 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := start.Add(tt.at)
+			due := schedule.due(feeds, tt.requested, now)
+			if !reflect.DeepEqual(due, tt.expected) {
+				t.Fatalf("Expected %v to be due, got %v", tt.expected, due)
+			}
+			schedule.polled(due, now)
+			if next := schedule.nextPoll(feeds, now); !next.Equal(start.Add(tt.next)) {
+				t.Errorf("Expected the next poll at %v, got %v", start.Add(tt.next), next)
+			}
+		})
+	}
+}
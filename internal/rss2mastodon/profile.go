@@ -0,0 +1,28 @@
+package rss2mastodon
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+)
+
+// profile is the selected deployment profile, empty for the default one
+var profile string
+
+var profileNameRegex = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// UseProfile selects a named profile, so several bots can run from the same directory:
+// configuration is read from .env.NAME instead of .env, and state is kept in tooted_posts.NAME.db
+func UseProfile(name string) error {
+	if name == "" {
+		return nil
+	}
+	if !profileNameRegex.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q, only letters, digits, - and _ are allowed", name)
+	}
+
+	profile = name
+	db.Path = fmt.Sprintf("./tooted_posts.%s.db", name)
+	return nil
+}
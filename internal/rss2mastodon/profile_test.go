@@ -0,0 +1,56 @@
+package rss2mastodon
+
+import (
+	"os"
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+)
+
+// Test a profile reads its own .env file and uses its own database
+func TestUseProfile(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+	defer func() { _ = os.Chdir(wd) }()
+
+	path := db.Path
+	defer func() { db.Path, profile = path, "" }()
+	viper.Reset()
+	defer viper.Reset()
+
+	if err := UseProfile("bad/name"); err == nil {
+		t.Errorf("Expected an error for an invalid profile name")
+	}
+
+	if err := UseProfile("work"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if db.Path != "./tooted_posts.work.db" {
+		t.Errorf("Expected the profile's database, got %s", db.Path)
+	}
+
+	// the profile's .env file is required
+	if err := LoadConfig(); err == nil {
+		t.Errorf("Expected an error without .env.work")
+	}
+
+	if err := os.WriteFile(".env", []byte("FEED_URL=https://example.com/personal.xml\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write .env: %v", err)
+	}
+	if err := os.WriteFile(".env.work", []byte("FEED_URL=https://example.com/work.xml\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write .env.work: %v", err)
+	}
+	if err := LoadConfig(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if got := viper.GetString("feed_url"); got != "https://example.com/work.xml" {
+		t.Errorf("Expected feed_url from .env.work, got %s", got)
+	}
+}
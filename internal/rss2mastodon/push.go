@@ -0,0 +1,127 @@
+package rss2mastodon
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// maxPushBytes caps the size of a request pushing items
+const maxPushBytes = 1024 * 1024
+
+// pushedItem is an item pushed to the admin listener by an external system, e.g. a CMS publish hook
+type pushedItem struct {
+	Title   string `json:"title"`
+	Link    string `json:"link"`
+	Content string `json:"content"`
+	// Published is an RFC 3339 date, items published in the future are embargoed like feed items
+	Published string `json:"published"`
+	Media     []struct {
+		URL  string `json:"url"`
+		Type string `json:"type"`
+		Alt  string `json:"alt"`
+	} `json:"media"`
+}
+
+// parsePushedItems parses a single pushed item or an array of them
+func parsePushedItems(body []byte) ([]rss.RSSItem, error) {
+	var pushed []pushedItem
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '{' {
+		var item pushedItem
+		if err := json.Unmarshal(trimmed, &item); err != nil {
+			return nil, err
+		}
+		pushed = append(pushed, item)
+	} else if err := json.Unmarshal(body, &pushed); err != nil {
+		return nil, err
+	}
+
+	var items []rss.RSSItem
+	for _, p := range pushed {
+		if u, err := url.Parse(p.Link); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid link %q, expected an absolute http(s) URL", p.Link)
+		}
+		item := rss.RSSItem{Title: p.Title, Link: p.Link, GUID: p.Link, Content: p.Content}
+		if p.Published != "" {
+			published, err := time.Parse(time.RFC3339, p.Published)
+			if err != nil {
+				return nil, fmt.Errorf("invalid published date %q, expected RFC 3339", p.Published)
+			}
+			item.PubDate = published.Format(time.RFC3339)
+		}
+		for _, m := range p.Media {
+			item.Media = append(item.Media, rss.MediaContent{URL: m.URL, Type: m.Type, Description: m.Alt})
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// handlePush queues pushed items, which the next cycle (started immediately) runs through
+// the same pipeline as feed items
+func handlePush(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPushBytes))
+	if err != nil {
+		http.Error(w, "request too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	items, err := parsePushedItems(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	for _, item := range items {
+		if err := db.QueuePendingPost(item, time.Now(), db.PendingPushed); err != nil {
+			log.Error("Failed to queue pushed item: ", err)
+			http.Error(w, "failed to queue item", http.StatusInternalServerError)
+			return
+		}
+		log.Infof("Queued %s pushed via admin listener", item.Link)
+	}
+	triggerCycle()
+	w.WriteHeader(http.StatusAccepted)
+	_, _ = fmt.Fprintf(w, "Queued %d items\n", len(items))
+}
+
+// handlePushedPost runs a pushed item through the same pipeline as feed items. It stays queued
+// while it is retried, i.e. its page isn't live yet or tooting it failed, and is removed
+// otherwise, unless the pipeline queued it for another reason like approval.
+func handlePushedPost(post rss.RSSItem, stats *cycleStats) {
+	handlePost(feedLogger(post.FeedURL), post, stats)
+
+	pending, err := db.GetPendingPost(post.Link)
+	if err != nil {
+		log.Error("Database error: ", err)
+		return
+	}
+	if pending == nil || pending.Reason != db.PendingPushed {
+		return
+	}
+
+	retrying, err := db.HasDeliveryFailures(post.Link)
+	if err != nil {
+		log.Error("Database error: ", err)
+		return
+	}
+	deadLettered, err := db.IsDeadLettered(post.Link)
+	if err != nil {
+		log.Error("Database error: ", err)
+		return
+	}
+	if retrying && !deadLettered {
+		return
+	}
+	if err := db.RemovePendingPost(post.Link); err != nil {
+		log.Error("Failed to remove pushed item: ", err)
+	}
+}
@@ -0,0 +1,152 @@
+package rss2mastodon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+)
+
+// Table-driven test for parsing pushed items
+func TestParsePushedItems(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		expectedLinks []string
+		expectError   bool
+	}{
+		{
+			name:          "Single item",
+			body:          `{"title":"Hello","link":"https://example.com/hello","media":[{"url":"https://example.com/hello.png","type":"image/png","alt":"A wave"}]}`,
+			expectedLinks: []string{"https://example.com/hello"},
+		},
+		{
+			name:          "Array of items",
+			body:          ` [{"link":"https://example.com/a"},{"link":"https://example.com/b","published":"2024-05-01T12:00:00+02:00"}]`,
+			expectedLinks: []string{"https://example.com/a", "https://example.com/b"},
+		},
+		{
+			name:        "Relative link",
+			body:        `{"link":"/hello"}`,
+			expectError: true,
+		},
+		{
+			name:        "Invalid date",
+			body:        `{"link":"https://example.com/a","published":"yesterday"}`,
+			expectError: true,
+		},
+		{
+			name:        "Invalid JSON",
+			body:        `{"link":`,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := parsePushedItems([]byte(tt.body))
+			if (err != nil) != tt.expectError {
+				t.Fatalf("Expected error %v, got %v", tt.expectError, err)
+			}
+			var links []string
+			for _, item := range items {
+				links = append(links, item.Link)
+			}
+			if strings.Join(links, ",") != strings.Join(tt.expectedLinks, ",") {
+				t.Errorf("Expected items %v, got %v", tt.expectedLinks, links)
+			}
+		})
+	}
+
+	items, _ := parsePushedItems([]byte(`{"link":"https://example.com/hello","published":"2024-05-01T12:00:00+02:00","media":[{"url":"https://example.com/hello.png","type":"image/png","alt":"A wave"}]}`))
+	if images := items[0].Images(); len(images) != 1 || images[0].Alt != "A wave" {
+		t.Errorf("Expected the pushed image with its alt text, got %+v", images)
+	}
+	if published, ok := items[0].Published(); !ok || !published.Equal(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the published date, got %v", published)
+	}
+}
+
+// Test pushed items are queued by the admin listener and announced by the next cycle
+func TestPushItems(t *testing.T) {
+	db.InitDB()
+	defer db.CloseDB()
+	defer os.Remove("./tooted_posts.db")
+
+	var statuses []string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/down":
+			http.NotFound(w, r)
+			return
+		case "/live":
+			return
+		}
+		statuses = append(statuses, r.FormValue("status"))
+		_, _ = w.Write([]byte(`{"id":"1"}`))
+	}))
+	defer mockServer.Close()
+
+	viper.Reset()
+	viper.Set("admin_token", "secret")
+	viper.Set("push_token", "push")
+	viper.Set("mastodon_url", mockServer.URL)
+	viper.Set("mastodon_access_token", "fake-token")
+	viper.Set("link_check", true)
+	viper.Set("link_check_grace", time.Hour)
+	defer viper.Reset()
+
+	body := `[{"title":"Live","link":"` + mockServer.URL + `/live"},{"title":"Not deployed yet","link":"` + mockServer.URL + `/down"}]`
+	tests := []struct {
+		name           string
+		token          string
+		expectedStatus int
+	}{
+		{name: "Wrong token", token: "wrong", expectedStatus: http.StatusUnauthorized},
+		{name: "Push token", token: "push", expectedStatus: http.StatusAccepted},
+		{name: "Admin token", token: "secret", expectedStatus: http.StatusAccepted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/items", strings.NewReader(body))
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			rec := httptest.NewRecorder()
+			newServeMux().ServeHTTP(rec, req)
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectedStatus, rec.Code, rec.Body.String())
+			}
+		})
+	}
+
+	// the push token doesn't grant access to the other endpoints
+	req := httptest.NewRequest("POST", "/poll", nil)
+	req.Header.Set("Authorization", "Bearer push")
+	rec := httptest.NewRecorder()
+	newServeMux().ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected the push token to be rejected for polls, got %d", rec.Code)
+	}
+
+	processPendingPosts(newCycleStats())
+	if len(statuses) != 1 || statuses[0] != "New blog post: "+mockServer.URL+"/live" {
+		t.Errorf("Expected the live item to be tooted, got %v", statuses)
+	}
+	if pending, _ := db.GetPendingPost(mockServer.URL + "/live"); pending != nil {
+		t.Errorf("Expected the announced item to be removed from the queue")
+	}
+	if pending, _ := db.GetPendingPost(mockServer.URL + "/down"); pending == nil || pending.Reason != db.PendingPushed {
+		t.Errorf("Expected the item whose page isn't live to stay queued, got %+v", pending)
+	}
+
+	processPendingPosts(newCycleStats())
+	if len(statuses) != 1 {
+		t.Errorf("Expected no further toots, got %v", statuses)
+	}
+}
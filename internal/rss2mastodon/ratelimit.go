@@ -0,0 +1,36 @@
+package rss2mastodon
+
+import (
+	"errors"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+	"github.com/toozej/rss2mastodon/internal/mastodon"
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// holdForRateLimit holds a new post which failed to toot because the Mastodon instance's rate
+// limit is used up in the pending queue until the limit resets, instead of counting it as a
+// failed delivery. Posts already pending keep their place in the queue. It returns whether the
+// post was held.
+func holdForRateLimit(logger *log.Entry, post rss.RSSItem, err error) bool {
+	var limited *mastodon.RateLimitError
+	if !errors.As(err, &limited) {
+		return false
+	}
+
+	pending, err := db.GetPendingPost(post.Link)
+	if err == nil && pending != nil {
+		err = db.DeferPendingPost(post.Link, limited.Reset)
+	} else if err == nil {
+		err = db.QueuePendingPost(post, limited.Reset, db.PendingRateLimit)
+	}
+	if err != nil {
+		logger.Error("Failed to queue post held by the Mastodon rate limit: ", err)
+		return false
+	}
+	logger.Infof("Holding post until %s, the Mastodon rate limit is used up: %s", limited.Reset.Format(time.RFC1123), post.Title)
+	return true
+}
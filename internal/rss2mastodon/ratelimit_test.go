@@ -0,0 +1,78 @@
+package rss2mastodon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+)
+
+// Table-driven test for holding new posts in the pending queue while the Mastodon rate limit
+// is used up
+func TestPollFeeds_RateLimit(t *testing.T) {
+	tests := []struct {
+		name           string
+		statusCode     int
+		expectRequests int
+		expectPending  bool
+	}{
+		{"Not limited", http.StatusOK, 2, false},
+		{"Rate limited", http.StatusTooManyRequests, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db.InitDB()
+			defer db.CloseDB()
+			defer os.Remove("./tooted_posts.db")
+
+			requests := 0
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/feed.xml":
+					_, _ = w.Write([]byte(`<rss><channel><title>Blog</title>
+						<item><title>1</title><link>https://example.com/1</link></item>
+						<item><title>2</title><link>https://example.com/2</link></item>
+					</channel></rss>`))
+				case "/api/v1/statuses":
+					requests++
+					w.Header().Set("Retry-After", "3600")
+					w.WriteHeader(tt.statusCode)
+					_, _ = w.Write([]byte(`{"id":"1"}`))
+				}
+			}))
+			defer mockServer.Close()
+
+			viper.Reset()
+			viper.Set("mastodon_url", mockServer.URL)
+			viper.Set("mastodon_access_token", "fake-token")
+			defer viper.Reset()
+
+			pollFeeds([]FeedConfig{{URL: mockServer.URL + "/feed.xml"}}, newCycleStats())
+			if requests != tt.expectRequests {
+				t.Errorf("Expected %d requests, got %d", tt.expectRequests, requests)
+			}
+
+			for _, link := range []string{"https://example.com/1", "https://example.com/2"} {
+				pending, err := db.GetPendingPost(link)
+				if err != nil {
+					t.Fatalf("Expected no error, got %v", err)
+				}
+				if (pending != nil) != tt.expectPending {
+					t.Fatalf("Expected %s pending: %v, got %+v", link, tt.expectPending, pending)
+				}
+				if pending != nil && (pending.Reason != db.PendingRateLimit || pending.NotBefore.Before(time.Now().Add(50*time.Minute))) {
+					t.Errorf("Expected %s held until the rate limit resets, got %+v", link, pending)
+				}
+				if failures, err := db.HasDeliveryFailures(link); err != nil || failures {
+					t.Errorf("Expected no delivery failures of %s, got %v, %v", link, failures, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package rss2mastodon
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+	"github.com/toozej/rss2mastodon/internal/notify"
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// removedItems returns the items removed from the feed since its previous fetch, e.g. retracted
+// posts, if delete_removed is enabled. Feeds only list their newest items, so items are only
+// taken as removed by fetches without new items, which would push older ones out of the feed;
+// nor are they taken as removed from feeds without items, which are more likely broken, or
+// from feeds cut short in low-memory mode.
+func removedItems(logger *log.Entry, feedURL string, previous time.Time, items int, fresh int) []db.ItemPresence {
+	if !viper.GetBool("delete_removed") || previous.IsZero() || fresh > 0 || items == 0 {
+		return nil
+	}
+	if rss.MaxItems > 0 && items >= rss.MaxItems {
+		return nil
+	}
+	missing, err := db.MissingItems(feedURL, previous)
+	if err != nil {
+		logger.Error("Failed to look up the items removed from the feed: ", err)
+		return nil
+	}
+	return missing
+}
+
+// deleteRemovedItems deletes the statuses tooted for items removed from their feed, and drops
+// those still waiting in the pending queue. Items are only found removed once, so statuses
+// failing to be deleted are logged and left as they are.
+func deleteRemovedItems(logger *log.Entry, items []db.ItemPresence, stats *cycleStats) {
+	client := mastodonClient()
+	for _, item := range items {
+		logger.Infof("Item was removed from the feed: %s", item.Link)
+		if err := db.RemovePendingPost(item.Link); err != nil {
+			logger.Error("Failed to drop the removed item from the pending queue: ", err)
+		}
+
+		statuses, err := db.LinkStatuses(item.Link)
+		if err != nil {
+			logger.Error("Failed to look up the statuses of the removed item: ", err)
+			continue
+		}
+		for _, status := range statuses {
+			if err := client.ForItem(item.Link).DeleteStatus(status.ID); err != nil {
+				logger.Errorf("Failed to delete status %s of the removed item: %v", status.ID, err)
+				recordError(mastodonKey, err)
+				notify.Failure(mastodonKey, err)
+				stats.failed.Add(1)
+				continue
+			}
+			notify.Success(mastodonKey)
+			if err := db.MarkStatusDeleted(status.ID); err != nil {
+				logger.Error("Failed to record the status as deleted: ", err)
+			}
+			logger.Infof("Deleted status %s of the removed item", status.ID)
+		}
+	}
+}
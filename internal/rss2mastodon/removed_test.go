@@ -0,0 +1,86 @@
+package rss2mastodon
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+)
+
+// Table-driven test for deleting the toots of items removed from their feed
+func TestPollFeeds_DeleteRemoved(t *testing.T) {
+	tests := []struct {
+		name            string
+		enabled         bool
+		items           []string
+		expectedDeleted []string
+	}{
+		{"Disabled", false, []string{"1", "3"}, nil},
+		{"Removed", true, []string{"1", "3"}, []string{"2"}},
+		{"Pushed out by a new item", true, []string{"1", "3", "4"}, nil},
+		{"Empty feed", true, nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db.InitDB()
+			defer db.CloseDB()
+			defer os.Remove("./tooted_posts.db")
+
+			items := []string{"1", "2", "3"}
+			var deleted []string
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.URL.Path == "/feed.xml":
+					feed := "<rss><channel><title>Blog</title>"
+					for _, item := range items {
+						feed += fmt.Sprintf("<item><title>%s</title><link>https://example.com/%s</link></item>", item, item)
+					}
+					_, _ = w.Write([]byte(feed + "</channel></rss>"))
+				case r.Method == "POST" && r.URL.Path == "/api/v1/statuses":
+					// statuses are numbered like the posts they announce
+					status := r.FormValue("status")
+					_, _ = fmt.Fprintf(w, `{"id":"%s"}`, status[strings.LastIndex(status, "/")+1:])
+				case r.Method == "DELETE":
+					deleted = append(deleted, strings.TrimPrefix(r.URL.Path, "/api/v1/statuses/"))
+				}
+			}))
+			defer mockServer.Close()
+
+			viper.Reset()
+			viper.Set("mastodon_url", mockServer.URL)
+			viper.Set("mastodon_access_token", "fake-token")
+			viper.Set("delete_removed", tt.enabled)
+			defer viper.Reset()
+
+			feeds := []FeedConfig{{URL: mockServer.URL + "/feed.xml"}}
+			pollFeeds(feeds, newCycleStats())
+			items = tt.items
+			pollFeeds(feeds, newCycleStats())
+
+			if strings.Join(deleted, ",") != strings.Join(tt.expectedDeleted, ",") {
+				t.Errorf("Expected deleted statuses %v, got %v", tt.expectedDeleted, deleted)
+			}
+			statuses, err := db.LinkStatuses("https://example.com/2")
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if expected := 1 - len(tt.expectedDeleted); len(statuses) != expected {
+				t.Errorf("Expected %d statuses of the removed item left, got %+v", expected, statuses)
+			}
+
+			// items are only found removed once
+			deleted = nil
+			pollFeeds(feeds, newCycleStats())
+			if len(deleted) != 0 {
+				t.Errorf("Expected no further deletions, got %v", deleted)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package rss2mastodon
+
+import (
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// Actions for resurfaced posts
+const (
+	resurfacedSkip  = "skip"
+	resurfacedLabel = "label"
+)
+
+// archiveLabel prefixes toots of resurfaced posts with the label action
+const archiveLabel = "From the archive: "
+
+// resurfaced reports whether a post was first seen in its feed more than resurfaced_after
+// after its pubDate, e.g. an old post bumped by a WordPress "republish" plugin
+func resurfaced(post rss.RSSItem) bool {
+	threshold := viper.GetDuration("resurfaced_after")
+	if threshold <= 0 {
+		return false
+	}
+	published, ok := post.Published()
+	if !ok {
+		return false
+	}
+
+	firstSeen, err := db.RecordFirstSeen(post.Link)
+	if err != nil {
+		log.Error("Failed to record when the post was first seen: ", err)
+		return false
+	}
+	return firstSeen.Sub(published) > threshold
+}
+
+// resurfacedAction returns what to do with resurfaced posts: skip or label them
+func resurfacedAction() string {
+	return strings.ToLower(viper.GetString("resurfaced_action"))
+}
+
+// skipResurfaced reports whether a new post should be skipped because it resurfaced
+func skipResurfaced(post rss.RSSItem) bool {
+	if resurfacedAction() != resurfacedSkip || !resurfaced(post) {
+		return false
+	}
+	published, _ := post.Published()
+	log.Debugf("Skipping resurfaced post published %s: %s", published.Format(time.RFC1123), post.Link)
+	return true
+}
+
+// withArchiveLabel labels the toot of a resurfaced post if configured
+func withArchiveLabel(post rss.RSSItem, content string) string {
+	if resurfacedAction() != resurfacedLabel || !resurfaced(post) {
+		return content
+	}
+	return archiveLabel + content
+}
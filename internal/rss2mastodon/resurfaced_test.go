@@ -0,0 +1,77 @@
+package rss2mastodon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// Table-driven test for skipping or labelling resurfaced old posts
+func TestHandlePost_Resurfaced(t *testing.T) {
+	tests := []struct {
+		name          string
+		action        string
+		pubDate       time.Time
+		expectedToots []string
+	}{
+		{
+			name:          "Resurfaced post skipped",
+			action:        "skip",
+			pubDate:       time.Now().AddDate(-1, 0, 0),
+			expectedToots: nil,
+		},
+		{
+			name:          "Resurfaced post labelled",
+			action:        "label",
+			pubDate:       time.Now().AddDate(-1, 0, 0),
+			expectedToots: []string{"From the archive: New blog post: https://example.com/old"},
+		},
+		{
+			name:          "Recent post",
+			action:        "skip",
+			pubDate:       time.Now().Add(-time.Hour),
+			expectedToots: []string{"New blog post: https://example.com/old"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db.InitDB()
+			defer db.CloseDB()
+			defer os.Remove("./tooted_posts.db")
+
+			var toots []string
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				toots = append(toots, r.FormValue("status"))
+				_, _ = w.Write([]byte(`{"id":"1"}`))
+			}))
+			defer mockServer.Close()
+
+			viper.Reset()
+			viper.Set("mastodon_url", mockServer.URL)
+			viper.Set("mastodon_access_token", "fake-token")
+			viper.Set("resurfaced_after", "720h")
+			viper.Set("resurfaced_action", tt.action)
+			defer viper.Reset()
+
+			post := rss.RSSItem{Title: "Old", Link: "https://example.com/old", PubDate: tt.pubDate.Format(time.RFC1123Z)}
+			handlePost(feedLogger(post.FeedURL), post, newCycleStats())
+
+			if len(toots) != len(tt.expectedToots) {
+				t.Fatalf("Expected toots %v, got %v", tt.expectedToots, toots)
+			}
+			for i := range toots {
+				if toots[i] != tt.expectedToots[i] {
+					t.Errorf("Expected toot '%s', got '%s'", tt.expectedToots[i], toots[i])
+				}
+			}
+		})
+	}
+}
@@ -1,7 +1,6 @@
 package rss2mastodon
 
 import (
-	"fmt"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -10,76 +9,321 @@ import (
 	"github.com/spf13/viper"
 	"github.com/toozej/rss2mastodon/internal/db"
 	"github.com/toozej/rss2mastodon/internal/mastodon"
+	"github.com/toozej/rss2mastodon/internal/metrics"
+	"github.com/toozej/rss2mastodon/internal/notify"
+	"github.com/toozej/rss2mastodon/internal/retry"
 	"github.com/toozej/rss2mastodon/internal/rss"
 )
 
+// mastodonKey identifies toot failures for notification flap suppression
+const mastodonKey = "Mastodon"
+
 func Run(cmd *cobra.Command, args []string) {
 	err := getEnvVars()
 	if err != nil {
 		log.Fatal("Error gathering required environment variables: ", err)
 	}
+	if viper.GetBool("strict_config") {
+		if err := checkStrictConfig(cmd.Flags()); err != nil {
+			log.Fatal("Strict config: ", err)
+		}
+	}
 
-	feedURL := viper.GetString("feed_url")
-	if feedURL == "" {
+	if err := retry.CheckConfig(); err != nil {
+		log.Fatal("Invalid retry configuration: ", err)
+	}
+	if err := lintTemplates(); err != nil {
+		log.Fatal("Invalid template: ", err)
+	}
+	if err := checkServerFlavor(); err != nil {
+		log.Fatal("Invalid configuration: ", err)
+	}
+	if _, err := crossPostAccounts(); err != nil {
+		log.Fatal("Invalid account configuration: ", err)
+	}
+
+	feeds, err := configuredFeeds()
+	if err != nil {
+		log.Fatal("Invalid feed configuration: ", err)
+	}
+	if len(feeds) == 0 {
 		log.Fatal("RSS feed URL is required")
 	}
 
-	db.InitDB() // Initialize SQLite database
+	applyLowMemory()
+	OpenDB() // Initialize SQLite database
 	defer db.CloseDB()
 
-	// Get interval from environment variable or flag (default to 10 minutes)
-	interval := viper.GetInt("interval")
-	if interval <= 0 {
-		log.Error("Interval must be a positive integer")
+	if err := metrics.Init(); err != nil {
+		log.Fatal("Error setting up metrics: ", err)
 	}
 
+	detectServerFlavor()
+	detectCharacterLimit()
+	startServer()
+	watchConfigDir()
+	startLeaderElection()
+	startStreaming()
+
+	// when each feed is polled next, by its priority; feeds may be polled in between on request
+	schedule := newPollSchedule()
 	for {
-		posts, err := rss.CheckRSSFeed(feedURL)
+		// pick up changes to the mounted config directory
+		if err := loadConfigDir(); err != nil {
+			log.Error("Failed to reload config directory: ", err)
+		}
+		watchSources()
+		rss.FetchLimiter.SetInterval(viper.GetDuration("host_request_interval"))
+
+		// Get interval from environment variable or flag (default to 10 minutes)
+		interval := viper.GetInt("interval")
+		if interval <= 0 {
+			log.Error("Interval must be a positive integer")
+		}
+
+		feeds, err := configuredFeeds()
 		if err != nil {
-			log.Printf("Error fetching RSS feed: %v", err)
-			continue
+			log.Error("Invalid feed configuration: ", err)
 		}
+		due := schedule.due(feeds, takePollRequests(), time.Now())
 
-		for _, post := range posts {
-			handlePost(post)
+		stats := newCycleStats()
+		if isLeader() {
+			processPendingPosts(stats)
+			retryCrossPosts(stats)
+			pollFeeds(due, stats)
+			repostFromArchive(stats)
+			postWeeklyDigest(stats)
+			syncAccountProfile()
+			trackEngagement()
+		} else {
+			log.Debug("Not the leader, only fetching feeds")
+			warmFeeds(due, stats)
 		}
+		stats.log()
+		stats.report()
+		stats.remember()
+		writeStateFile(stats)
+		writeHistoryFeed()
 
-		// Sleep for the configured interval before checking again
-		time.Sleep(time.Duration(interval) * time.Minute)
+		// intervals count from the end of the cycle, so a slow cycle isn't followed by another right away
+		now := time.Now()
+		schedule.polled(due, now)
+		// Sleep until the next feed is due to be polled, or until the next pending post is due, before checking again
+		sleep(untilNextCycle(time.Until(schedule.nextPoll(feeds, now))))
 	}
 }
 
-func handlePost(post rss.RSSItem) {
+// handlePost toots new and updated posts, returning whether the post was new
+func handlePost(logger *log.Entry, post rss.RSSItem, stats *cycleStats) bool {
+	if isBlocked(post) {
+		logger.Debugf("Skipping blocked post: %s", post.Link)
+		return false
+	}
+
+	deadLettered, err := db.IsDeadLettered(post.Link)
+	if err != nil {
+		logger.Error("Database error: ", err)
+		return false
+	}
+	if deadLettered {
+		logger.Debugf("Skipping dead-lettered post: %s", post.Link)
+		return false
+	}
+
 	exists, updated, err := db.HasPostChanged(post.Link, post.Content)
 	if err != nil {
-		log.Error("Database error: ", err)
-		return
+		logger.Error("Database error: ", err)
+		return false
 	}
 
-	if exists && updated {
-		// Post exists but is updated
-		log.Printf("Post has been updated: %s", post.Title)
-		tootContent := fmt.Sprintf("Blog post has been updated: %s", post.Link)
-		err := mastodon.TootPost(tootContent)
+	if exists && updated && post.Reappeared {
+		// posts added back to their feed are often re-rendered, which isn't worth an update toot
+		logger.Debugf("Not announcing changes of reappeared post: %s", post.Link)
+		if err := db.StoreFeedPost(post.FeedURL, post.Link, post.Content); err != nil {
+			logger.Error("Storing reappeared post in database failed: ", err)
+		}
+	} else if exists && updated {
+		// Post exists but is updated, its changes are announced once the daily toot budget allows
+		if !reserveTootFor(logger, "the update toot of "+post.Title) {
+			return false
+		}
+		defer releaseToot()
+		logger.Printf("Post has been updated: %s", post.Title)
+		stats.updated.Add(1)
+		tooted := withLinkParams(post)
+		tootContent := withFooter(tooted, mastodon.GetUpdateTootContent(tooted))
+		opts := mastodon.TootOptions{
+			Visibility:     feedConfig(post.FeedURL).Visibility,
+			SpoilerText:    contentWarning(post),
+			Language:       tootLanguage(post.FeedURL),
+			LocalOnly:      tootLocalOnly(post.FeedURL),
+			IdempotencyKey: idempotencyKey(styleUpdate, post),
+		}
+		status, err := mastodonClient().ForItem(post.Link).PostStatus(tootContent, opts)
 		if err != nil {
-			log.Error("Failed to toot updated post: ", err)
+			logger.Error("Failed to toot updated post: ", err)
+			recordError(post.Link, err)
+			notify.Failure(mastodonKey, err)
+			stats.failed.Add(1)
 		} else {
-			err = db.StoreTootedPost(post.Link, post.Content)
+			notify.Success(mastodonKey)
+			stats.tooted.Add(1)
+			recordStatus(status, post.Link, styleUpdate)
+			err = db.StoreFeedPost(post.FeedURL, post.Link, post.Content)
 			if err != nil {
-				log.Error("Storing updated post toot in database failed: ", err)
+				logger.Error("Storing updated post toot in database failed: ", err)
 			}
 		}
 	} else if !exists {
-		// New post
-		tootContent := mastodon.GetTootContent(post)
-		err := mastodon.TootPost(tootContent)
+		published, ok := post.Published()
+		embargoed := ok && published.After(time.Now())
+
+		// remember when the post showed up, to tell resurfaced old posts from new ones
+		firstSeen, err := db.RecordFirstSeen(post.Link)
 		if err != nil {
-			log.Printf("Failed to toot new post: %v", err)
-		} else {
-			err = db.StoreTootedPost(post.Link, post.Content)
+			logger.Error("Failed to record when the post was first seen: ", err)
+			firstSeen = time.Now()
+		}
+
+		pending, err := db.GetPendingPost(post.Link)
+		if err != nil {
+			logger.Error("Database error: ", err)
+			return false
+		}
+		if pending != nil && pending.Reason != db.PendingPushed {
+			// already held in the pending queue, only keep its embargo in sync with the feed and
+			// its posting schedule
+			if pending.Reason == db.PendingEmbargo && !embargoed {
+				if pending.NotBefore.After(nextPostingTime(post.FeedURL, time.Now())) {
+					err = db.RetryPendingPost(post.Link)
+				}
+			} else if pending.Reason == db.PendingEmbargo {
+				if until := nextPostingTime(post.FeedURL, published); !pending.NotBefore.Equal(until) {
+					err = db.QueuePendingPost(post, until, db.PendingEmbargo)
+				}
+			}
 			if err != nil {
-				log.Error("Storing new post toot in database failed: ", err)
+				logger.Error("Failed to update pending post: ", err)
 			}
+			return false
 		}
+
+		if skipResurfaced(post) {
+			return false
+		}
+
+		// embargoed posts aren't expected to be live before their pubDate
+		if !embargoed && !linkLive(post, firstSeen) {
+			return false
+		}
+
+		// New post
+		stats.newItems.Add(1)
+		if viper.GetBool("require_approval") {
+			// approved posts are still held until their pubDate
+			notBefore := time.Now()
+			if embargoed {
+				notBefore = published
+			}
+			requestApproval(post, notBefore)
+		} else if embargoed {
+			embargoPost(logger, post, published, stats)
+		} else if !holdForPostingTime(logger, post) && !holdForBudget(logger, post) {
+			announcePost(logger, post, mastodon.TootOptions{}, stats)
+			releaseToot()
+		}
+	}
+
+	return !exists
+}
+
+// announcePost toots a new post with its media and records it in the database,
+// returning whether the toot was sent
+func announcePost(logger *log.Entry, post rss.RSSItem, opts mastodon.TootOptions, stats *cycleStats) bool {
+	opts.ScheduledAt = withScheduleDelay(opts.ScheduledAt, post.Title)
+	opts.Visibility = feedConfig(post.FeedURL).Visibility
+	opts.SpoilerText = contentWarning(post)
+	opts.Language = tootLanguage(post.FeedURL)
+	opts.LocalOnly = tootLocalOnly(post.FeedURL)
+	opts.IdempotencyKey = idempotencyKey(styleNewPost, post)
+
+	// prefer boosting the author's own post over a link announcement, once the post is published
+	if viper.GetBool("boost_author_posts") && opts.ScheduledAt.IsZero() && boostAuthorPost(post) {
+		stats.tooted.Add(1)
+		clearDeliveryFailures(post)
+		if err := db.StoreFeedPost(post.FeedURL, post.Link, post.Content); err != nil {
+			logger.Error("Storing boosted post in database failed: ", err)
+		}
+		return true
+	}
+
+	client := mastodonClient().ForItem(post.Link)
+	// the toot links the post with its feed's link_params, everything else uses its original link
+	tooted := withLinkParams(post)
+	tootContent := withArchiveLabel(post, mastodon.GetTootContent(tooted))
+	if viper.GetBool("attribute_author") {
+		tootContent = withAuthorAttribution(post, tootContent)
+	}
+	opts.Poll = tootPoll(post)
+	if opts.Poll != nil {
+		tootContent = pollContent(tooted, tootContent)
+	}
+	tootContent = withCategoryHashtags(post, tootContent)
+	tootContent = withFooter(tooted, tootContent)
+	// statuses with a poll can't have attachments
+	if opts.Poll == nil && uploadEnclosures(post.FeedURL) {
+		opts.MediaIDs = client.UploadEnclosures(post.MediaEnclosures(), post.Title)
+	}
+	// Mastodon doesn't allow mixing images with a video or audio attachment
+	if opts.Poll == nil && len(opts.MediaIDs) == 0 && (viper.GetBool("attach_images") || post.AttachImages) {
+		opts.MediaIDs = client.UploadImages(post.Images())
+	}
+
+	status, threadLength, err := postAnnouncement(logger, client, post, tootContent, opts)
+	if err != nil && holdForRateLimit(logger, post, err) {
+		return false
+	}
+	if err != nil {
+		logger.Printf("Failed to toot new post: %v", err)
+		recordError(post.Link, err)
+		recordDeliveryFailure(post, err)
+		notify.Failure(mastodonKey, err)
+		stats.failed.Add(1)
+		return false
+	}
+	notify.Success(mastodonKey)
+	clearDeliveryFailures(post)
+	stats.tooted.Add(1)
+	// scheduled statuses get a different ID once they are published
+	if opts.ScheduledAt.IsZero() {
+		recordStatus(status, post.Link, announcementStyle(post, opts))
+	}
+
+	err = db.StoreFeedPost(post.FeedURL, post.Link, post.Content)
+	if err != nil {
+		logger.Error("Storing new post toot in database failed: ", err)
+	}
+	crossPost(logger, post, tootContent, opts, stats)
+
+	// preview cards aren't shown on statuses with attachments (or available before scheduled
+	// statuses are published), so only check text-only toots published immediately. Threads
+	// link the post at their end, not in the status starting them. Checking takes several
+	// seconds, so it runs in the background instead of holding up the next posts.
+	if viper.GetBool("verify_link_card") && len(opts.MediaIDs) == 0 && opts.ScheduledAt.IsZero() && threadLength == 1 && status != nil && status.ID != "" {
+		verified := *status
+		go func() {
+			if err := client.VerifyLinkCard(&verified, tootContent, post); err != nil {
+				logger.Warn("Link card verification failed: ", err)
+			}
+		}()
+	}
+	return true
+}
+
+// recordError stores a failure in the error log shown by the TUI
+func recordError(source string, err error) {
+	if dbErr := db.RecordError(source, err.Error()); dbErr != nil {
+		log.Error("Failed to record error: ", dbErr)
 	}
 }
@@ -0,0 +1,138 @@
+package rss2mastodon
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// postingWindow is a rule of a posting schedule: the weekdays and the hours of those days
+// posts may be announced in
+type postingWindow struct {
+	days       [7]bool
+	start, end int
+}
+
+// weekdays maps the names a schedule can use for days of the week
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// weekdayAliases are shorthands for common sets of days
+var weekdayAliases = map[string]string{
+	"daily":    "mon-sun",
+	"weekdays": "mon-fri",
+	"weekends": "sat-sun",
+}
+
+// parseSchedule parses a posting schedule of |-separated rules, each a day or range of days
+// optionally followed by a range of hours, e.g. "weekdays 8-22|sat 10-14". Posts may only be
+// announced on the listed days, within their hours; an empty schedule allows any time.
+func parseSchedule(value string) ([]postingWindow, error) {
+	var windows []postingWindow
+	for _, rule := range strings.Split(value, "|") {
+		fields := strings.Fields(strings.ToLower(rule))
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) > 2 {
+			return nil, fmt.Errorf("invalid schedule rule %q, expected e.g. mon-fri 8-22", strings.TrimSpace(rule))
+		}
+
+		window := postingWindow{start: 0, end: 24}
+		days := fields[0]
+		if alias, ok := weekdayAliases[days]; ok {
+			days = alias
+		}
+		firstName, lastName, isRange := strings.Cut(days, "-")
+		if !isRange {
+			lastName = firstName
+		}
+		first, firstOK := weekdays[firstName]
+		last, lastOK := weekdays[lastName]
+		if !firstOK || !lastOK {
+			return nil, fmt.Errorf("invalid days %q in schedule rule %q, expected e.g. mon, mon-fri or weekdays", fields[0], strings.TrimSpace(rule))
+		}
+		// ranges may wrap around the end of the week, e.g. fri-mon
+		for day := first; ; day = (day + 1) % 7 {
+			window.days[day] = true
+			if day == last {
+				break
+			}
+		}
+
+		if len(fields) == 2 {
+			start, end, err := parseHours(fields[1])
+			if err != nil {
+				return nil, err
+			}
+			window.start, window.end = start, end
+		}
+		windows = append(windows, window)
+	}
+	return windows, nil
+}
+
+// allows reports whether the window includes the given time, by its day of the week and hour
+// in its own time zone
+func (w postingWindow) allows(t time.Time) bool {
+	return w.days[t.Weekday()] && inHours(t.Hour(), w.start, w.end)
+}
+
+// scheduleAllows reports whether any window of a schedule includes the given time
+func scheduleAllows(windows []postingWindow, t time.Time) bool {
+	for _, window := range windows {
+		if window.allows(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// nextScheduledTime returns the first time from t on the schedule allows posting at: t itself
+// if it does, or else the start of the first allowed hour after it, on the wall clock of t's
+// time zone. Hours skipped or repeated by daylight saving time transitions are handled like
+// the clock does.
+func nextScheduledTime(windows []postingWindow, t time.Time) time.Time {
+	if len(windows) == 0 {
+		return t
+	}
+
+	// every window allows at least one hour a week, so this ends within about 170 hours
+	next := t
+	for i := 0; i < 8*24 && !scheduleAllows(windows, next); i++ {
+		candidate := time.Date(next.Year(), next.Month(), next.Day(), next.Hour(), 0, 0, 0, next.Location()).Add(time.Hour)
+		// the second occurrence of an hour repeated when daylight saving time ends has the same
+		// day and hour as the first one, so it isn't allowed either
+		for !candidate.After(next) || candidate.Hour() == next.Hour() {
+			candidate = candidate.Add(time.Hour)
+		}
+		next = candidate
+	}
+	return next
+}
+
+// feedSchedule returns the posting schedule of the feed: its schedule option, or else the
+// posting_schedule setting
+func feedSchedule(feedURL string) ([]postingWindow, error) {
+	value := feedConfig(feedURL).Schedule
+	if value == "" {
+		value = viper.GetString("posting_schedule")
+	}
+	return parseSchedule(value)
+}
+
+// nextPostingTime returns when a post of the feed may be announced at the earliest, from t
+// on, according to the feed's posting schedule. Invalid schedules are logged and ignored.
+func nextPostingTime(feedURL string, t time.Time) time.Time {
+	windows, err := feedSchedule(feedURL)
+	if err != nil {
+		log.Error("Ignoring invalid posting schedule: ", err)
+		return t
+	}
+	return nextScheduledTime(windows, t)
+}
@@ -0,0 +1,121 @@
+package rss2mastodon
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/spf13/viper"
+)
+
+// Table-driven test for parsing posting schedules
+func TestParseSchedule(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       string
+		expected    []postingWindow
+		expectError bool
+	}{
+		{"Empty", "", nil, false},
+		{"Weekdays", "weekdays", []postingWindow{{days: [7]bool{false, true, true, true, true, true, false}, start: 0, end: 24}}, false},
+		{"Day with hours", "Sat 10-14", []postingWindow{{days: [7]bool{6: true}, start: 10, end: 14}}, false},
+		{"Range wrapping the week", "fri-mon 22-2", []postingWindow{{days: [7]bool{true, true, false, false, false, true, true}, start: 22, end: 2}}, false},
+		{"Several rules", "mon-fri 8-22| sun ", []postingWindow{
+			{days: [7]bool{false, true, true, true, true, true, false}, start: 8, end: 22},
+			{days: [7]bool{0: true}, start: 0, end: 24},
+		}, false},
+		{"Unknown day", "monday", nil, true},
+		{"Invalid hours", "mon 8-25", nil, true},
+		{"Too many fields", "mon 8-12 14-18", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			windows, err := parseSchedule(tt.value)
+			if (err != nil) != tt.expectError {
+				t.Fatalf("Expected error %v, got %v", tt.expectError, err)
+			}
+			if len(windows) != len(tt.expected) {
+				t.Fatalf("Expected %v, got %v", tt.expected, windows)
+			}
+			for i := range windows {
+				if windows[i] != tt.expected[i] {
+					t.Errorf("Expected %v, got %v", tt.expected[i], windows[i])
+				}
+			}
+		})
+	}
+}
+
+// Table-driven test for finding the next time a schedule allows posting, across daylight
+// saving time transitions
+func TestNextScheduledTime(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("Failed to load time zone: %v", err)
+	}
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("Failed to load time zone: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		schedule string
+		t        time.Time
+		expected time.Time
+	}{
+		{"No schedule", "", time.Date(2024, 6, 8, 3, 15, 0, 0, berlin), time.Date(2024, 6, 8, 3, 15, 0, 0, berlin)},
+		{"Allowed", "weekdays 8-22", time.Date(2024, 6, 7, 21, 59, 0, 0, berlin), time.Date(2024, 6, 7, 21, 59, 0, 0, berlin)},
+		{"Later the same day", "weekdays 8-22", time.Date(2024, 6, 7, 6, 30, 0, 0, berlin), time.Date(2024, 6, 7, 8, 0, 0, 0, berlin)},
+		{"After the weekend", "weekdays 8-22", time.Date(2024, 6, 7, 22, 0, 0, 0, berlin), time.Date(2024, 6, 10, 8, 0, 0, 0, berlin)},
+		{"Different hours on weekends", "weekdays 8-22|weekends 10-14", time.Date(2024, 6, 7, 23, 0, 0, 0, berlin), time.Date(2024, 6, 8, 10, 0, 0, 0, berlin)},
+		{"Hours wrapping midnight", "sat 22-2", time.Date(2024, 6, 8, 1, 0, 0, 0, berlin), time.Date(2024, 6, 8, 1, 0, 0, 0, berlin)},
+		{"Skipped hour when DST starts", "sun 2-4", time.Date(2024, 3, 31, 1, 30, 0, 0, berlin), time.Date(2024, 3, 31, 3, 0, 0, 0, berlin)},
+		{"Across DST start", "mon 9-17", time.Date(2024, 3, 30, 12, 0, 0, 0, berlin), time.Date(2024, 4, 1, 9, 0, 0, 0, berlin)},
+		{"Repeated hour when DST ends", "sun 3-4", time.Date(2024, 10, 27, 2, 30, 0, 0, berlin), time.Date(2024, 10, 27, 3, 0, 0, 0, berlin)},
+		{"Second occurrence of the repeated hour", "sun 3-4", time.Date(2024, 10, 27, 0, 30, 0, 0, berlin).Add(3 * time.Hour), time.Date(2024, 10, 27, 3, 0, 0, 0, berlin)},
+		{"Across DST end", "weekdays 9-17", time.Date(2024, 11, 2, 18, 0, 0, 0, newYork), time.Date(2024, 11, 4, 9, 0, 0, 0, newYork)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			windows, err := parseSchedule(tt.schedule)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if got := nextScheduledTime(windows, tt.t); !got.Equal(tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+// Table-driven test for the posting schedule of a post's feed, falling back to the global one
+func TestNextPostingTime(t *testing.T) {
+	saturday := time.Date(2024, 6, 8, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		schedule string
+		feedURL  string
+		expected time.Time
+	}{
+		{"Unscheduled", "", "https://example.com/rss", saturday},
+		{"Global schedule", "weekdays", "https://example.com/rss", time.Date(2024, 6, 10, 0, 0, 0, 0, time.UTC)},
+		{"Feed schedule", "weekdays", "https://example.com/weekends", time.Date(2024, 6, 8, 12, 0, 0, 0, time.UTC)},
+		{"Invalid schedule ignored", "someday", "https://example.com/rss", saturday},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			viper.Set("posting_schedule", tt.schedule)
+			viper.Set("feed", []string{"url=https://example.com/weekends,schedule=weekends 9-18"})
+			defer viper.Reset()
+
+			if got := nextPostingTime(tt.feedURL, saturday); !got.Equal(tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
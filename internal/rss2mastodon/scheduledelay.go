@@ -0,0 +1,33 @@
+package rss2mastodon
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// scheduleDelayTime returns when a new post's announcement should be published if schedule_delay
+// is set, giving the operator time to delete it from the scheduled posts before it goes public,
+// and the zero time otherwise. Delays Mastodon wouldn't schedule are raised to the shortest it does.
+func scheduleDelayTime(now time.Time) time.Time {
+	minutes := viper.GetInt("schedule_delay")
+	if minutes <= 0 {
+		return time.Time{}
+	}
+	// leave the request a minute to reach the instance
+	delay := max(time.Duration(minutes)*time.Minute, minScheduleDelay+time.Minute)
+	return now.Add(delay)
+}
+
+// withScheduleDelay schedules a new post's announcement schedule_delay minutes ahead, unless it
+// is scheduled already
+func withScheduleDelay(scheduledAt time.Time, title string) time.Time {
+	if !scheduledAt.IsZero() {
+		return scheduledAt
+	}
+	if scheduledAt = scheduleDelayTime(time.Now()); !scheduledAt.IsZero() {
+		log.Infof("Scheduling announcement for %s, delete it from the scheduled posts to cancel it: %s", scheduledAt.Format(time.RFC1123), title)
+	}
+	return scheduledAt
+}
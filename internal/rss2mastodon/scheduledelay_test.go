@@ -0,0 +1,74 @@
+package rss2mastodon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// Table-driven test for when new posts are scheduled with schedule_delay
+func TestScheduleDelayTime(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		minutes  int
+		expected time.Time
+	}{
+		{"Disabled", 0, time.Time{}},
+		{"Delay", 15, now.Add(15 * time.Minute)},
+		{"Too short for Mastodon", 2, now.Add(6 * time.Minute)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			viper.Set("schedule_delay", tt.minutes)
+			defer viper.Reset()
+
+			if got := scheduleDelayTime(now); !got.Equal(tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+// Test new posts are handed to Mastodon as scheduled statuses with schedule_delay
+func TestScheduleDelay(t *testing.T) {
+	db.InitDB()
+	defer db.CloseDB()
+	defer os.Remove("./tooted_posts.db")
+
+	var scheduledAt string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scheduledAt = r.FormValue("scheduled_at")
+		_, _ = w.Write([]byte(`{"id":"1"}`))
+	}))
+	defer mockServer.Close()
+
+	viper.Reset()
+	viper.Set("mastodon_url", mockServer.URL)
+	viper.Set("mastodon_access_token", "fake-token")
+	viper.Set("schedule_delay", 15)
+	defer viper.Reset()
+
+	if !handlePost(feedLogger(""), rss.RSSItem{Title: "Delayed", Link: "https://example.com/delayed"}, newCycleStats()) {
+		t.Errorf("Expected post to be reported as new")
+	}
+	scheduled, err := time.Parse(time.RFC3339, scheduledAt)
+	if err != nil {
+		t.Fatalf("Expected the announcement to be scheduled, got %q", scheduledAt)
+	}
+	if delay := time.Until(scheduled); delay > 15*time.Minute || delay < 14*time.Minute {
+		t.Errorf("Expected the announcement to be scheduled 15 minutes ahead, got %v", delay)
+	}
+	if statuses, err := db.RecentStatuses(10); err != nil || len(statuses) != 0 {
+		t.Errorf("Expected scheduled status not to be recorded, got %v, %v", statuses, err)
+	}
+}
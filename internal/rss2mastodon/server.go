@@ -0,0 +1,199 @@
+package rss2mastodon
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+)
+
+// wake interrupts the sleep between cycles so queued work is handled immediately
+var wake = make(chan struct{}, 1)
+
+// triggerCycle starts the next cycle early, without blocking if one is already requested
+func triggerCycle() {
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+}
+
+// pollRequests holds the feeds requested to be polled by the next cycle, e.g. by a deploy hook.
+// The empty URL requests all feeds.
+var (
+	pollRequestsMu sync.Mutex
+	pollRequests   = map[string]bool{}
+)
+
+// requestPoll starts the next cycle early, polling the given feed or all feeds if empty
+func requestPoll(feedURL string) {
+	pollRequestsMu.Lock()
+	pollRequests[feedURL] = true
+	pollRequestsMu.Unlock()
+	triggerCycle()
+}
+
+// takePollRequests returns and clears the feeds requested to be polled
+func takePollRequests() map[string]bool {
+	pollRequestsMu.Lock()
+	defer pollRequestsMu.Unlock()
+	requested := pollRequests
+	pollRequests = map[string]bool{}
+	return requested
+}
+
+// sleep waits for the given duration or until a cycle is triggered
+func sleep(d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-wake:
+	}
+}
+
+// startServer starts the admin listener if one is configured. Every endpoint requires
+// the configured admin token as a bearer token, pushing items alternatively the push token.
+// With enable_pprof, the listener also serves net/http/pprof's profiles.
+func startServer() {
+	addr := viper.GetString("listen_addr")
+	if addr == "" {
+		return
+	}
+	if viper.GetString("admin_token") == "" {
+		log.Error("Not starting admin listener: ADMIN_TOKEN must be set")
+		return
+	}
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           newServeMux(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		log.Infof("Admin listener running on %s", addr)
+		if viper.GetBool("enable_pprof") {
+			log.Warnf("Profiling endpoints enabled on %s/debug/pprof/", addr)
+		}
+		if err := server.ListenAndServe(); err != nil {
+			log.Error("Admin listener failed: ", err)
+		}
+	}()
+}
+
+func newServeMux() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /approve/{id}", requireApprovalToken("approve", handleApprove))
+	mux.HandleFunc("POST /reject/{id}", requireApprovalToken("reject", handleReject))
+	mux.HandleFunc("POST /poll", requireToken(handlePoll))
+	mux.HandleFunc("POST /items", requireToken(handlePush, "push_token"))
+	if viper.GetBool("enable_pprof") {
+		// profiles expose internals like the command line, so they need the admin token too
+		mux.HandleFunc("GET /debug/pprof/", requireToken(pprof.Index))
+		mux.HandleFunc("GET /debug/pprof/cmdline", requireToken(pprof.Cmdline))
+		mux.HandleFunc("GET /debug/pprof/profile", requireToken(pprof.Profile))
+		mux.HandleFunc("GET /debug/pprof/symbol", requireToken(pprof.Symbol))
+		mux.HandleFunc("POST /debug/pprof/symbol", requireToken(pprof.Symbol))
+		mux.HandleFunc("GET /debug/pprof/trace", requireToken(pprof.Trace))
+	}
+	return mux
+}
+
+// requireToken rejects requests which don't carry the configured admin token, or one of the
+// additionally accepted tokens, e.g. the push token which only grants access to pushing items
+func requireToken(next http.HandlerFunc, tokenKeys ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authorization := []byte(r.Header.Get("Authorization"))
+		for _, key := range append([]string{"admin_token"}, tokenKeys...) {
+			token := viper.GetString(key)
+			if token != "" && subtle.ConstantTimeCompare(authorization, []byte("Bearer "+token)) == 1 {
+				next(w, r)
+				return
+			}
+		}
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	}
+}
+
+// requireApprovalToken accepts the admin token, or the single-use token of a notification button
+// for the action on the post
+func requireApprovalToken(action string, next http.HandlerFunc) http.HandlerFunc {
+	withAdminToken := requireToken(next)
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get(approvalTokenHeader)
+		if token == "" {
+			withAdminToken(w, r)
+			return
+		}
+
+		pending, err := db.GetPendingPost(r.PathValue("id"))
+		if err == nil && pending != nil {
+			var valid bool
+			valid, err = useApprovalToken(action, pending, token)
+			if valid {
+				next(w, r)
+				return
+			}
+		}
+		if err != nil {
+			log.Error("Admin listener: ", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	}
+}
+
+func handleApprove(w http.ResponseWriter, r *http.Request) {
+	pending, err := approvePost(r.PathValue("id"))
+	respondPending(w, "Approved", pending, err)
+	if err == nil {
+		triggerCycle()
+	}
+}
+
+func handleReject(w http.ResponseWriter, r *http.Request) {
+	pending, err := rejectPost(r.PathValue("id"))
+	respondPending(w, "Rejected", pending, err)
+}
+
+func handlePoll(w http.ResponseWriter, r *http.Request) {
+	feedURL := r.FormValue("feed")
+	if feedURL == "" {
+		log.Info("Poll triggered via admin listener")
+		requestPoll("")
+		_, _ = fmt.Fprintln(w, "Poll triggered")
+		return
+	}
+
+	feeds, _ := configuredFeeds()
+	if len(selectFeeds(feeds, map[string]bool{feedURL: true})) == 0 {
+		http.Error(w, "unknown feed", http.StatusNotFound)
+		return
+	}
+	log.Infof("Poll of %s triggered via admin listener", feedURL)
+	requestPoll(feedURL)
+	// nosemgrep: go.lang.security.audit.xss.no-direct-write-to-responsewriter.no-direct-write-to-responsewriter
+	_, _ = fmt.Fprintf(w, "Poll of %s triggered\n", feedURL)
+}
+
+func respondPending(w http.ResponseWriter, action string, pending *db.PendingPost, err error) {
+	if err != nil {
+		log.Error("Admin listener: ", err)
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	log.Infof("%s %s via admin listener", action, pending.Item.Link)
+	// nosemgrep: go.lang.security.audit.xss.no-direct-write-to-responsewriter.no-direct-write-to-responsewriter
+	_, _ = fmt.Fprintf(w, "%s %s\n", action, pending.Item.Link)
+}
@@ -0,0 +1,262 @@
+package rss2mastodon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// Table-driven test for the admin listener's approval endpoints
+func TestServeMux(t *testing.T) {
+	db.InitDB()
+	defer db.CloseDB()
+	defer os.Remove("./tooted_posts.db")
+
+	viper.Reset()
+	viper.Set("admin_token", "secret")
+	viper.Set("feed_url", "https://example.com/feed.xml")
+	defer viper.Reset()
+
+	for _, link := range []string{"https://example.com/a", "https://example.com/b"} {
+		if err := db.QueuePendingPost(rss.RSSItem{Link: link}, time.Now(), db.PendingApproval); err != nil {
+			t.Fatalf("Failed to queue post: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		token          string
+		expectedStatus int
+	}{
+		{
+			name:           "Missing token",
+			method:         "POST",
+			path:           "/approve/1",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Wrong token",
+			method:         "POST",
+			path:           "/approve/1",
+			token:          "wrong",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Approve",
+			method:         "POST",
+			path:           "/approve/1",
+			token:          "secret",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Reject",
+			method:         "POST",
+			path:           "/reject/2",
+			token:          "secret",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Unknown post",
+			method:         "POST",
+			path:           "/approve/99",
+			token:          "secret",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Poll",
+			method:         "POST",
+			path:           "/poll",
+			token:          "secret",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Poll a single feed",
+			method:         "POST",
+			path:           "/poll?feed=https://example.com/feed.xml",
+			token:          "secret",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Poll an unknown feed",
+			method:         "POST",
+			path:           "/poll?feed=https://example.com/other.xml",
+			token:          "secret",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Wrong method",
+			method:         "GET",
+			path:           "/approve/1",
+			token:          "secret",
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	mux := newServeMux()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+		})
+	}
+
+	requested := takePollRequests()
+	if len(requested) != 2 || !requested[""] || !requested["https://example.com/feed.xml"] {
+		t.Errorf("Expected a poll of all feeds and of the single feed, got %v", requested)
+	}
+	if requested := takePollRequests(); len(requested) != 0 {
+		t.Errorf("Expected poll requests to be cleared, got %v", requested)
+	}
+}
+
+// Test notification buttons approve or reject a single post with single-use tokens
+func TestServeMux_ApprovalTokens(t *testing.T) {
+	db.InitDB()
+	defer db.CloseDB()
+	defer os.Remove("./tooted_posts.db")
+
+	viper.Reset()
+	viper.Set("admin_token", "secret")
+	viper.Set("listen_addr", ":8080")
+	viper.Set("public_url", "https://bot.example.com")
+	defer viper.Reset()
+
+	tokens := make(map[string]string)
+	for _, link := range []string{"https://example.com/a", "https://example.com/b"} {
+		if err := db.QueuePendingPost(rss.RSSItem{Link: link}, time.Now(), db.PendingApproval); err != nil {
+			t.Fatalf("Failed to queue post: %v", err)
+		}
+		pending, _ := db.GetPendingPost(link)
+		for _, action := range approvalActions(pending) {
+			tokens[action.URL] = action.Headers[approvalTokenHeader]
+		}
+	}
+	if err := db.QueuePendingPost(rss.RSSItem{Link: "https://example.com/c"}, time.Now(), db.PendingRetry); err != nil {
+		t.Fatalf("Failed to queue post: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		path           string
+		token          string
+		expectedStatus int
+	}{
+		{
+			name:           "Token of another post",
+			path:           "/approve/1",
+			token:          tokens["https://bot.example.com/approve/2"],
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Token of another action",
+			path:           "/approve/1",
+			token:          tokens["https://bot.example.com/reject/1"],
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Admin token as approval token",
+			path:           "/approve/1",
+			token:          "secret",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Approve",
+			path:           "/approve/1",
+			token:          tokens["https://bot.example.com/approve/1"],
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Token used by another button",
+			path:           "/reject/1",
+			token:          tokens["https://bot.example.com/reject/1"],
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Reject",
+			path:           "/reject/2",
+			token:          tokens["https://bot.example.com/reject/2"],
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Token reused",
+			path:           "/reject/2",
+			token:          tokens["https://bot.example.com/reject/2"],
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Post not awaiting approval",
+			path:           "/approve/3",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	mux := newServeMux()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set(approvalTokenHeader, tt.token)
+			} else {
+				req.Header.Set("Authorization", "Bearer secret")
+			}
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+		})
+	}
+	takePollRequests()
+}
+
+// Test the profiling endpoints are only served when enabled, and require the admin token
+func TestServeMux_Pprof(t *testing.T) {
+	viper.Reset()
+	viper.Set("admin_token", "secret")
+	defer viper.Reset()
+
+	tests := []struct {
+		name           string
+		enabled        bool
+		token          string
+		expectedStatus int
+	}{
+		{"Disabled", false, "secret", http.StatusNotFound},
+		{"Missing token", true, "", http.StatusUnauthorized},
+		{"Enabled", true, "secret", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Set("enable_pprof", tt.enabled)
+			req := httptest.NewRequest("GET", "/debug/pprof/heap?debug=1", nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			newServeMux().ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+		})
+	}
+}
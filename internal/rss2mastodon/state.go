@@ -0,0 +1,81 @@
+package rss2mastodon
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// healthState is written to state_file after every cycle for external monitoring
+type healthState struct {
+	UpdatedAt       time.Time  `json:"updated_at"`
+	LastSuccess     *time.Time `json:"last_success"`
+	LastFeedSuccess *time.Time `json:"last_feed_success"`
+	LastTootSuccess *time.Time `json:"last_toot_success"`
+	// FailedCycles counts consecutive cycles with at least one failure
+	FailedCycles int            `json:"consecutive_failed_cycles"`
+	ErrorsTotal  int64          `json:"errors_total"`
+	LastCycle    map[string]any `json:"last_cycle"`
+}
+
+// state accumulates across cycles since the process started
+var state healthState
+
+// writeStateFile updates the health state with a cycle's stats and writes it to state_file, if set
+func writeStateFile(stats *cycleStats) {
+	path := viper.GetString("state_file")
+	if path == "" {
+		return
+	}
+
+	now := time.Now().UTC()
+	state.UpdatedAt = now
+	if stats.feedsPolled.Load() > stats.feedErrors.Load() {
+		state.LastFeedSuccess = &now
+	}
+	if stats.tooted.Load() > 0 {
+		state.LastTootSuccess = &now
+	}
+	if stats.failed.Load() > 0 {
+		state.FailedCycles++
+		state.ErrorsTotal += stats.failed.Load()
+	} else {
+		state.FailedCycles = 0
+		state.LastSuccess = &now
+	}
+	state.LastCycle = stats.fields()
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		log.Error("Failed to encode state file: ", err)
+		return
+	}
+
+	if err := writeFileAtomic(path, data); err != nil {
+		log.Error("Failed to write state file: ", err)
+	}
+}
+
+// writeFileAtomic writes to a temporary file and renames it, so readers never see a partial file
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0o644); err != nil {
+		log.Warn("Failed to set file permissions: ", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
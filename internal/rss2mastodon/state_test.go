@@ -0,0 +1,67 @@
+package rss2mastodon
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// Test the state file tracks last successes and consecutive failures across cycles
+func TestWriteStateFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	viper.Reset()
+	viper.Set("state_file", path)
+	defer viper.Reset()
+	state = healthState{}
+	defer func() { state = healthState{} }()
+
+	read := func() healthState {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Failed to read state file: %v", err)
+		}
+		var s healthState
+		if err := json.Unmarshal(data, &s); err != nil {
+			t.Fatalf("Failed to decode state file: %v", err)
+		}
+		return s
+	}
+
+	// successful cycle
+	stats := newCycleStats()
+	stats.feedsPolled.Add(1)
+	stats.tooted.Add(1)
+	writeStateFile(stats)
+
+	s := read()
+	if s.LastSuccess == nil || s.LastFeedSuccess == nil || s.LastTootSuccess == nil {
+		t.Fatalf("Expected last success timestamps, got %+v", s)
+	}
+	if s.FailedCycles != 0 || s.ErrorsTotal != 0 {
+		t.Errorf("Expected no failures, got %+v", s)
+	}
+
+	// two cycles where the feed couldn't be fetched
+	for i := 0; i < 2; i++ {
+		stats = newCycleStats()
+		stats.feedsPolled.Add(1)
+		stats.failed.Add(1)
+		stats.feedErrors.Add(1)
+		writeStateFile(stats)
+	}
+
+	s = read()
+	if s.FailedCycles != 2 || s.ErrorsTotal != 2 {
+		t.Errorf("Expected 2 failed cycles and errors, got %d and %d", s.FailedCycles, s.ErrorsTotal)
+	}
+	if s.LastFeedSuccess == nil || !s.LastFeedSuccess.Before(s.UpdatedAt) {
+		t.Errorf("Expected last feed success to stay at the first cycle, got %v", s.LastFeedSuccess)
+	}
+	if s.LastCycle["failed"] != float64(1) {
+		t.Errorf("Expected last cycle failed=1, got %v", s.LastCycle["failed"])
+	}
+}
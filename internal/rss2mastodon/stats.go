@@ -0,0 +1,86 @@
+package rss2mastodon
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/metrics"
+)
+
+// cycleStats counts what happened during one polling cycle
+type cycleStats struct {
+	start       time.Time
+	feedsPolled atomic.Int64
+	itemsSeen   atomic.Int64
+	newItems    atomic.Int64
+	updated     atomic.Int64
+	tooted      atomic.Int64
+	failed      atomic.Int64
+	// feedErrors counts failed feed fetches, which are also included in failed
+	feedErrors atomic.Int64
+}
+
+// cycleSummary describes a completed cycle
+type cycleSummary struct {
+	finished time.Time
+	text     string
+}
+
+// lastCycle is the latest completed cycle, reported by the status command
+var lastCycle atomic.Pointer[cycleSummary]
+
+func newCycleStats() *cycleStats {
+	return &cycleStats{start: time.Now()}
+}
+
+// fields returns the counters as structured log fields
+func (s *cycleStats) fields() log.Fields {
+	return log.Fields{
+		"feeds_polled": s.feedsPolled.Load(),
+		"items_seen":   s.itemsSeen.Load(),
+		"new":          s.newItems.Load(),
+		"updated":      s.updated.Load(),
+		"tooted":       s.tooted.Load(),
+		"failed":       s.failed.Load(),
+		"duration":     time.Since(s.start).Round(time.Millisecond).String(),
+	}
+}
+
+// remember records the cycle as the latest completed one
+func (s *cycleStats) remember() {
+	text := fmt.Sprintf("%d feeds polled, %d new, %d updated, %d tooted, %d failed in %s",
+		s.feedsPolled.Load(), s.newItems.Load(), s.updated.Load(), s.tooted.Load(), s.failed.Load(),
+		time.Since(s.start).Round(time.Millisecond))
+	lastCycle.Store(&cycleSummary{finished: time.Now(), text: text})
+}
+
+// changed reports whether anything happened during the cycle besides polling
+func (s *cycleStats) changed() bool {
+	return s.newItems.Load() > 0 || s.updated.Load() > 0 || s.tooted.Load() > 0 || s.failed.Load() > 0
+}
+
+// log emits the cycle summary as a single structured INFO line. With log_changes_only
+// set, no-op cycles are logged at DEBUG level instead so they stay silent by default.
+func (s *cycleStats) log() {
+	entry := log.WithFields(s.fields())
+	if viper.GetBool("log_changes_only") && !s.changed() {
+		entry.Debug("Cycle complete")
+		return
+	}
+	entry.Info("Cycle complete")
+}
+
+// report pushes the cycle's counters and duration to the metrics backend
+func (s *cycleStats) report() {
+	metrics.Count("feeds_polled", s.feedsPolled.Load())
+	metrics.Count("items_seen", s.itemsSeen.Load())
+	metrics.Count("new", s.newItems.Load())
+	metrics.Count("updated", s.updated.Load())
+	metrics.Count("tooted", s.tooted.Load())
+	metrics.Count("failed", s.failed.Load())
+	metrics.Timing("cycle.duration", time.Since(s.start))
+}
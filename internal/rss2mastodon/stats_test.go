@@ -0,0 +1,128 @@
+package rss2mastodon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+)
+
+// Test a cycle's counters and its summary log line
+func TestCycleStats(t *testing.T) {
+	db.InitDB()
+	defer db.CloseDB()
+	defer os.Remove("./tooted_posts.db")
+
+	if err := db.StoreTootedPost("https://example.com/updated", "old content"); err != nil {
+		t.Fatalf("Failed to store post: %v", err)
+	}
+
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/feed.xml":
+			_, _ = w.Write([]byte(`<rss><channel>
+				<item><title>New</title><link>https://example.com/new</link><description>new</description></item>
+				<item><title>Updated</title><link>https://example.com/updated</link><description>new content</description></item>
+			</channel></rss>`))
+		case "/missing.xml":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			_, _ = w.Write([]byte(`{"id":"1"}`))
+		}
+	}))
+	defer mockServer.Close()
+
+	viper.Reset()
+	viper.Set("mastodon_url", mockServer.URL)
+	viper.Set("mastodon_access_token", "fake-token")
+	defer viper.Reset()
+
+	stats := newCycleStats()
+	pollFeeds([]FeedConfig{{URL: mockServer.URL + "/feed.xml"}, {URL: mockServer.URL + "/missing.xml"}}, stats)
+
+	hook := test.NewGlobal()
+	stats.log()
+
+	entry := hook.LastEntry()
+	if entry == nil || entry.Level != log.InfoLevel || entry.Message != "Cycle complete" {
+		t.Fatalf("Expected an INFO 'Cycle complete' entry, got %v", entry)
+	}
+
+	expected := map[string]int64{
+		"feeds_polled": 2,
+		"items_seen":   2,
+		"new":          1,
+		"updated":      1,
+		"tooted":       2,
+		"failed":       1,
+	}
+	for field, value := range expected {
+		if entry.Data[field] != value {
+			t.Errorf("Expected %s=%d, got %v", field, value, entry.Data[field])
+		}
+	}
+	if _, ok := entry.Data["duration"]; !ok {
+		t.Errorf("Expected duration field")
+	}
+
+	stats.remember()
+	defer lastCycle.Store(nil)
+	if summary := lastCycle.Load(); summary == nil || !strings.HasPrefix(summary.text, "2 feeds polled, 1 new, 1 updated, 2 tooted, 1 failed in ") {
+		t.Errorf("Expected the cycle to be remembered, got %+v", summary)
+	}
+}
+
+// Table-driven test for silencing no-op cycles
+func TestCycleStats_LogChangesOnly(t *testing.T) {
+	tests := []struct {
+		name          string
+		changesOnly   bool
+		failed        int64
+		expectedLevel log.Level
+	}{
+		{
+			name:          "No-op cycle",
+			changesOnly:   false,
+			expectedLevel: log.InfoLevel,
+		},
+		{
+			name:          "No-op cycle with changes only",
+			changesOnly:   true,
+			expectedLevel: log.DebugLevel,
+		},
+		{
+			name:          "Failed cycle with changes only",
+			changesOnly:   true,
+			failed:        1,
+			expectedLevel: log.InfoLevel,
+		},
+	}
+
+	level := log.GetLevel()
+	log.SetLevel(log.DebugLevel)
+	defer log.SetLevel(level)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			viper.Set("log_changes_only", tt.changesOnly)
+			defer viper.Reset()
+
+			stats := newCycleStats()
+			stats.failed.Add(tt.failed)
+
+			hook := test.NewGlobal()
+			stats.log()
+			if entry := hook.LastEntry(); entry == nil || entry.Level != tt.expectedLevel {
+				t.Errorf("Expected %v entry, got %v", tt.expectedLevel, entry)
+			}
+		})
+	}
+}
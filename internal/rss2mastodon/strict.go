@@ -0,0 +1,119 @@
+package rss2mastodon
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// strictNamespaces are prefixes of environment variables which can only be meant for this bot,
+// besides envPrefix
+var strictNamespaces = []string{"mastodon_", "gotify_", "ntfy_"}
+
+// maxTypoDistance is how many edits apart from a known key an environment variable is
+// considered a typo of it, e.g. MASTADON_URL
+const maxTypoDistance = 2
+
+// knownKeys returns every configuration key the bot reads, including legacy ones
+func knownKeys(flags *pflag.FlagSet) map[string]bool {
+	keys := make(map[string]bool)
+	for _, key := range envOnlyKeys {
+		keys[key] = true
+	}
+	for legacy := range legacyKeys {
+		keys[legacy] = true
+	}
+	flags.VisitAll(func(f *pflag.Flag) {
+		keys[strings.ReplaceAll(f.Name, "-", "_")] = true
+	})
+	return keys
+}
+
+// checkStrictConfig returns an error listing the settings which aren't recognized: every
+// unknown key of the .env file and config directory, and environment variables prefixed with
+// envPrefix, in one of the bot's namespaces or resembling a known key
+func checkStrictConfig(flags *pflag.FlagSet) error {
+	known := knownKeys(flags)
+	var problems []string
+	unknown := func(where string, name string, key string) {
+		problem := fmt.Sprintf("unknown setting %s in %s", name, where)
+		if suggestion := closestKey(key, known); suggestion != "" {
+			problem += fmt.Sprintf(", did you mean %s?", strings.ToUpper(suggestion))
+		}
+		problems = append(problems, problem)
+	}
+
+	for _, key := range viper.AllKeys() {
+		if viper.InConfig(key) && !known[key] {
+			unknown("the .env file", strings.ToUpper(key), key)
+		}
+	}
+	for key := range configDirKeys {
+		if !known[key] {
+			unknown("the config directory", strings.ToUpper(key), key)
+		}
+	}
+
+	for _, variable := range os.Environ() {
+		name, _, _ := strings.Cut(variable, "=")
+		key, prefixed := strings.CutPrefix(name, envPrefix)
+		key = strings.ToLower(key)
+		if known[key] {
+			continue
+		}
+		inNamespace := prefixed
+		for _, namespace := range strictNamespaces {
+			inNamespace = inNamespace || strings.HasPrefix(key, namespace)
+		}
+		if inNamespace || closestKey(key, known) != "" {
+			unknown("the environment", name, key)
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	sort.Strings(problems)
+	return errors.New(strings.Join(problems, "; "))
+}
+
+// closestKey returns the known key a few edits away from key, or "" if there is none
+func closestKey(key string, known map[string]bool) string {
+	best, bestDistance := "", maxTypoDistance+1
+	for candidate := range known {
+		// keys this short are too easily confused, e.g. a generic DEBUG_X
+		if len(candidate) < 6 {
+			continue
+		}
+		if distance := editDistance(key, candidate); distance < bestDistance || (distance == bestDistance && candidate < best) {
+			best, bestDistance = candidate, distance
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a string, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
@@ -0,0 +1,86 @@
+package rss2mastodon
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// Test strict mode rejects unknown settings in the bot's namespaces and likely typos
+func TestCheckStrictConfig(t *testing.T) {
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.String("feed-url", "", "")
+	flags.Int("interval", 60, "")
+
+	tests := []struct {
+		name          string
+		env           map[string]string
+		expectedError string
+	}{
+		{
+			name: "Known settings",
+			env:  map[string]string{"MASTODON_URL": "https://mastodon.example", "FEED_URL": "https://example.com/rss", "MASTODON_TOKEN": "legacy"},
+		},
+		{
+			name: "Prefixed settings",
+			env:  map[string]string{"RSS2MASTODON_INTERVAL": "30", "RSS2MASTODON_MASTODON_URL": "https://mastodon.example"},
+		},
+		{
+			name: "Unrelated variables",
+			env:  map[string]string{"EDITOR": "vi", "OTHER_APP_URL": "https://example.com"},
+		},
+		{
+			name:          "Typo",
+			env:           map[string]string{"MASTADON_URL": "https://mastodon.example"},
+			expectedError: "unknown setting MASTADON_URL in the environment, did you mean MASTODON_URL?",
+		},
+		{
+			name:          "Unknown variable in a namespace",
+			env:           map[string]string{"NTFY_PRIORITY": "high"},
+			expectedError: "unknown setting NTFY_PRIORITY in the environment",
+		},
+		{
+			name:          "Unknown prefixed variable",
+			env:           map[string]string{"RSS2MASTODON_INTERVALL": "30"},
+			expectedError: "unknown setting RSS2MASTODON_INTERVALL in the environment, did you mean INTERVAL?",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			defer viper.Reset()
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			err := checkStrictConfig(flags)
+			switch {
+			case tt.expectedError == "" && err != nil:
+				t.Errorf("Expected no error, got %v", err)
+			case tt.expectedError != "" && (err == nil || !strings.Contains(err.Error(), tt.expectedError)):
+				t.Errorf("Expected error %q, got %v", tt.expectedError, err)
+			}
+		})
+	}
+}
+
+// Test unknown keys of the .env file are rejected with a suggestion
+func TestCheckStrictConfig_EnvFile(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	viper.SetConfigType("env")
+	if err := viper.ReadConfig(strings.NewReader("INTERVAL=30\nINTERVALL=60\n")); err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.Int("interval", 60, "")
+	err := checkStrictConfig(flags)
+	expected := "unknown setting INTERVALL in the .env file, did you mean INTERVAL?"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error %q, got %v", expected, err)
+	}
+}
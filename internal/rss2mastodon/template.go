@@ -0,0 +1,86 @@
+package rss2mastodon
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+
+	"github.com/toozej/rss2mastodon/internal/mastodon"
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// templateFuncs are the functions available to user supplied templates. They only depend on
+// their arguments, so a template renders the same toot for the same data.
+var templateFuncs = template.FuncMap{
+	"truncate":   truncateText,
+	"stripHTML":  mastodon.PlainText,
+	"hashtagize": hashtagize,
+	"upper":      strings.ToUpper,
+	"lower":      strings.ToLower,
+	"date":       formatDate,
+	"urlescape":  url.QueryEscape,
+}
+
+// renderTemplate parses a user supplied Go template and executes it with the given data
+func renderTemplate(name string, text string, data interface{}) (string, error) {
+	tmpl, err := template.New(name).Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// truncateText shortens text to at most length characters, ending it with an ellipsis if it
+// was cut. It takes the text last, so it can be used in pipelines like {{.Title | truncate 50}}.
+func truncateText(length int, text string) string {
+	runes := []rune(text)
+	if length < 0 || len(runes) <= length {
+		return text
+	}
+	if length == 0 {
+		return ""
+	}
+	return strings.TrimRightFunc(string(runes[:length-1]), unicode.IsSpace) + "…"
+}
+
+// hashtagize turns text into a hashtag of its letters and digits, capitalizing each word,
+// e.g. "Go generics & more" into "#GoGenericsMore", or nothing if it has none
+func hashtagize(text string) string {
+	var tag strings.Builder
+	for _, word := range strings.FieldsFunc(text, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		tag.WriteString(string(runes))
+	}
+	if tag.Len() == 0 {
+		return ""
+	}
+	return "#" + tag.String()
+}
+
+// formatDate formats a time, or a date string like an item's pubDate, with a Go layout, e.g.
+// {{date "2006-01-02" .PubDate}}, or returns nothing for the zero time or an unparseable date
+func formatDate(layout string, value interface{}) (string, error) {
+	var t time.Time
+	switch v := value.(type) {
+	case time.Time:
+		t = v
+	case string:
+		t, _ = rss.ParseDate(v)
+	default:
+		return "", fmt.Errorf("date expects a time or a date string, got %T", value)
+	}
+	if t.IsZero() {
+		return "", nil
+	}
+	return t.Format(layout), nil
+}
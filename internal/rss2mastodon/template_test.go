@@ -0,0 +1,85 @@
+package rss2mastodon
+
+import (
+	"testing"
+	"time"
+)
+
+// Table-driven test for rendering user supplied templates
+func TestRenderTemplate(t *testing.T) {
+	tests := []struct {
+		name        string
+		text        string
+		expected    string
+		expectError bool
+	}{
+		{"Plain text", "Hello", "Hello", false},
+		{"Field", "New: {{.Link}}", "New: https://example.com", false},
+		{"Parse error", "{{.Link", "", true},
+		{"Execution error", "{{.Missing}}", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := renderTemplate("test", tt.text, tootedPost{Link: "https://example.com"})
+			if (err != nil) != tt.expectError {
+				t.Fatalf("Expected error %v, got %v", tt.expectError, err)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected '%s', got '%s'", tt.expected, got)
+			}
+		})
+	}
+}
+
+// Table-driven test for the functions available to templates
+func TestTemplateFuncs(t *testing.T) {
+	data := struct {
+		Title  string
+		HTML   string
+		Link   string
+		Tooted time.Time
+		Never  time.Time
+		Date   string
+	}{
+		Title:  "Go generics & more",
+		HTML:   "<p>Hello <b>world</b> &amp; friends</p>",
+		Link:   "https://example.com/a b?c=d",
+		Tooted: time.Date(2024, 5, 6, 12, 30, 0, 0, time.UTC),
+		Date:   "Mon, 06 May 2024 12:30:00 +0000",
+	}
+
+	tests := []struct {
+		name     string
+		text     string
+		expected string
+	}{
+		{"Truncate", "{{.Title | truncate 10}}", "Go generi…"},
+		{"Truncate at a space", "{{truncate 4 .Title}}", "Go…"},
+		{"Truncate short text", "{{.Title | truncate 50}}", "Go generics & more"},
+		{"Truncate to nothing", "{{.Title | truncate 0}}", ""},
+		{"Strip HTML", "{{stripHTML .HTML}}", "Hello world & friends"},
+		{"Hashtagize", "{{hashtagize .Title}}", "#GoGenericsMore"},
+		{"Hashtagize nothing", `{{hashtagize " & "}}`, ""},
+		{"Upper", "{{upper .Title}}", "GO GENERICS & MORE"},
+		{"Lower", "{{.Title | lower}}", "go generics & more"},
+		{"Date", `{{date "2006-01-02" .Tooted}}`, "2024-05-06"},
+		{"Date in a pipeline", `{{.Tooted | date "Jan 2, 15:04"}}`, "May 6, 12:30"},
+		{"Zero date", `{{date "2006-01-02" .Never}}`, ""},
+		{"Date string", `{{date "2006-01-02" .Date}}`, "2024-05-06"},
+		{"Unparseable date string", `{{date "2006-01-02" "soon"}}`, ""},
+		{"URL escape", "{{urlescape .Link}}", "https%3A%2F%2Fexample.com%2Fa+b%3Fc%3Dd"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := renderTemplate("test", tt.text, data)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected '%s', got '%s'", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package rss2mastodon
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// lintItem is the synthetic post templates are rendered for by lintTemplates, with every field
+// set so templates using them are exercised
+var lintItem = rss.RSSItem{
+	Title:      "Example post",
+	Link:       "https://example.com/posts/example",
+	GUID:       "https://example.com/posts/example",
+	PubDate:    "Mon, 06 May 2024 12:00:00 +0000",
+	Content:    "<p>An <b>example</b> post</p>",
+	Encoded:    "<p>An <b>example</b> post, in full</p>",
+	Categories: []string{"example"},
+	FeedURL:    "https://example.com/feed.xml",
+	Source:     "Example Blog",
+}
+
+// lintTemplates renders every configured template for a synthetic post, so mistakes are
+// reported at startup, with the template's name and the position of the mistake, instead of
+// when the first real post is announced
+func lintTemplates() error {
+	tooted := tootedPost{Link: lintItem.Link, Tooted: time.Date(2024, 5, 6, 12, 0, 0, 0, time.UTC)}
+	if text := viper.GetString("footer"); text != "" {
+		if _, err := renderTemplate("footer", text, lintItem); err != nil {
+			return err
+		}
+		// archive reposts and digests render the footer without a post
+		if _, err := renderTemplate("footer", text, rss.RSSItem{Link: lintItem.Link}); err != nil {
+			return fmt.Errorf("%w (without a post, for archive reposts and digests)", err)
+		}
+	}
+	if len(listSetting("poll_rule")) > 0 {
+		if _, err := renderTemplate("poll", viper.GetString("poll_template"), lintItem); err != nil {
+			return err
+		}
+	}
+	if _, err := archiveRepostContent(tooted); err != nil {
+		return err
+	}
+	_, err := digestContent(weeklyDigest{Start: tooted.Tooted.AddDate(0, 0, -7), End: tooted.Tooted, Posts: []tootedPost{tooted}})
+	return err
+}
@@ -0,0 +1,52 @@
+package rss2mastodon
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// Table-driven test for checking the configured templates at startup
+func TestLintTemplates(t *testing.T) {
+	tests := []struct {
+		name        string
+		key         string
+		text        string
+		expectedErr string
+	}{
+		{"Defaults", "", "", ""},
+		{"Valid footer", "footer", "via {{.Source | upper}} {{.Title | hashtagize}}", ""},
+		{"Footer parse error", "footer", "via {{.Source", "template: footer:1: unclosed action"},
+		{"Footer unknown field", "footer", "via {{.Blog}}", `template: footer:1:6: executing "footer" at <.Blog>`},
+		{"Footer needing a post", "footer", "{{index .Categories 0}}", "without a post"},
+		{"Archive repost template", "archive_repost_template", "Again: {{.Link}} from {{.Tooted | date \"2006\"}}", ""},
+		{"Archive repost unknown function", "archive_repost_template", "{{shout .Link}}", `template: archive_repost_template:1: function "shout" not defined`},
+		{"Digest template", "digest_template", "{{range .Posts}}{{.Link}}\n{{end}}", ""},
+		{"Poll template", "poll_template", "{{.Title}}: which next?", ""},
+		{"Poll template parse error", "poll_template", "{{.Title", "template: poll:1: unclosed action"},
+		{"Digest execution error", "digest_template", "{{range .Posts}}{{.Title}}{{end}}", "template: digest_template:1:18"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			defer viper.Reset()
+			viper.Set("poll_rule", []string{"ideas=Go|Rust"})
+			if tt.key != "" {
+				viper.Set(tt.key, tt.text)
+			}
+
+			err := lintTemplates()
+			if tt.expectedErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+				t.Errorf("Expected an error containing %q, got %v", tt.expectedErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,238 @@
+package rss2mastodon
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// Restart backoff of tenant processes: doubling from tenantMinBackoff up to tenantMaxBackoff,
+// and reset once a process ran for tenantStableAfter
+const (
+	tenantMinBackoff  = 5 * time.Second
+	tenantMaxBackoff  = 5 * time.Minute
+	tenantStableAfter = 10 * time.Minute
+)
+
+// tenantStopTimeout is how long tenant processes may take to exit before they are killed
+const tenantStopTimeout = 10 * time.Second
+
+// tenantSystemEnv are the environment variables tenant processes inherit, besides their own
+// settings, so no other configuration leaks from the environment into a tenant
+var tenantSystemEnv = []string{
+	"PATH", "HOME", "USER", "TZ", "LANG", "LC_ALL", "TMPDIR", "SSL_CERT_FILE", "SSL_CERT_DIR",
+	"HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY", "http_proxy", "https_proxy", "no_proxy", "SYSTEMROOT",
+}
+
+// tenantsConfig is the file configuring the tenants, with the settings of each one as the keys
+// and values of a .env file
+type tenantsConfig struct {
+	// Defaults are the settings every tenant starts from, e.g. the polling interval
+	Defaults map[string]interface{}            `yaml:"defaults"`
+	Tenants  map[string]map[string]interface{} `yaml:"tenants"`
+}
+
+// tenant is a bot run in its own process, directory and database, sharing nothing with the
+// other tenants
+type tenant struct {
+	Name string
+	// Settings are the tenant's settings merged over the defaults, by environment variable name
+	Settings map[string]string
+}
+
+// loadTenants reads the tenants configured in a YAML file, in the order of their names
+func loadTenants(path string) ([]tenant, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var config tenantsConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(config.Tenants) == 0 {
+		return nil, fmt.Errorf("%s configures no tenants", path)
+	}
+
+	defaults, err := tenantSettings(config.Defaults)
+	if err != nil {
+		return nil, fmt.Errorf("defaults: %w", err)
+	}
+	var tenants []tenant
+	for name, values := range config.Tenants {
+		if !profileNameRegex.MatchString(name) {
+			return nil, fmt.Errorf("invalid tenant name %q, only letters, digits, - and _ are allowed", name)
+		}
+		settings, err := tenantSettings(values)
+		if err != nil {
+			return nil, fmt.Errorf("tenant %s: %w", name, err)
+		}
+		merged := make(map[string]string)
+		for key, value := range defaults {
+			merged[key] = value
+		}
+		for key, value := range settings {
+			merged[key] = value
+		}
+		if merged["CONFIG_DIR"] == "" && (merged["MASTODON_URL"] == "" || merged["MASTODON_ACCESS_TOKEN"] == "") {
+			return nil, fmt.Errorf("tenant %s: MASTODON_URL and MASTODON_ACCESS_TOKEN must be set", name)
+		}
+		tenants = append(tenants, tenant{Name: name, Settings: merged})
+	}
+	sort.Slice(tenants, func(i, j int) bool { return tenants[i].Name < tenants[j].Name })
+	return tenants, nil
+}
+
+// tenantSettings converts settings given in any case and with dashes, e.g. feed-url, to
+// environment variables like FEED_URL. Lists aren't accepted, as their format depends on the
+// setting; they are written like in the .env file.
+func tenantSettings(values map[string]interface{}) (map[string]string, error) {
+	settings := make(map[string]string)
+	for key, value := range values {
+		name := strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(key), "-", "_"))
+		switch v := value.(type) {
+		case nil:
+			settings[name] = ""
+		case string, bool, int, float64:
+			settings[name] = fmt.Sprint(v)
+		default:
+			return nil, fmt.Errorf("%s must be a single value, written like in the .env file", name)
+		}
+	}
+	return settings, nil
+}
+
+// env returns the environment of the tenant's process: the system variables of the
+// supervisor's environment and the tenant's settings
+func (t tenant) env() []string {
+	var env []string
+	for _, name := range tenantSystemEnv {
+		if value, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+value)
+		}
+	}
+	names := make([]string, 0, len(t.Settings))
+	for name := range t.Settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		env = append(env, name+"="+t.Settings[name])
+	}
+	return env
+}
+
+// prefixWriter writes every line written to it to out, prefixed with the tenant's name
+type prefixWriter struct {
+	prefix string
+	out    io.Writer
+	// mu serializes the lines of every tenant writing to out
+	mu  *sync.Mutex
+	buf []byte
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		w.mu.Lock()
+		_, err := fmt.Fprintf(w.out, "%s%s\n", w.prefix, w.buf[:i])
+		w.mu.Unlock()
+		w.buf = w.buf[i+1:]
+		if err != nil {
+			return len(p), err
+		}
+	}
+}
+
+// runTenant runs the tenant's process in its directory until it exits or ctx is done, when it
+// is asked to stop with SIGTERM. Its output is written to out, every line prefixed with the
+// tenant's name.
+func runTenant(ctx context.Context, t tenant, dir string, out io.Writer, mu *sync.Mutex, command string, args ...string) error {
+	cmd := exec.CommandContext(ctx, command, args...)
+	cmd.Dir = dir
+	cmd.Env = t.env()
+	writer := &prefixWriter{prefix: "[" + t.Name + "] ", out: out, mu: mu}
+	cmd.Stdout = writer
+	cmd.Stderr = writer
+	cmd.Cancel = func() error { return cmd.Process.Signal(syscall.SIGTERM) }
+	cmd.WaitDelay = tenantStopTimeout
+	return cmd.Run()
+}
+
+// superviseTenant keeps the tenant's process running until ctx is done, restarting it with a
+// growing delay when it exits
+func superviseTenant(ctx context.Context, t tenant, dir string, mu *sync.Mutex, command string) {
+	logger := log.WithField("tenant", t.Name)
+	backoff := tenantMinBackoff
+	for {
+		start := time.Now()
+		logger.Info("Starting tenant")
+		err := runTenant(ctx, t, dir, os.Stderr, mu, command)
+		if ctx.Err() != nil {
+			logger.Info("Stopped tenant")
+			return
+		}
+		if time.Since(start) >= tenantStableAfter {
+			backoff = tenantMinBackoff
+		}
+		logger.Errorf("Tenant exited (%v), restarting in %s", err, backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, tenantMaxBackoff)
+	}
+}
+
+// RunTenants runs a bot for every tenant configured in the file given as argument, each in its
+// own process with its own directory (and so database) below --dir, restarting those which exit
+// until the supervisor is stopped
+func RunTenants(cmd *cobra.Command, args []string) {
+	baseDir, _ := cmd.Flags().GetString("dir")
+	tenants, err := loadTenants(args[0])
+	if err != nil {
+		log.Fatal("Invalid tenants configuration: ", err)
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		log.Fatal("Error finding the rss2mastodon binary: ", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, t := range tenants {
+		dir := filepath.Join(baseDir, t.Name)
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			log.Fatalf("Error creating the directory of tenant %s: %v", t.Name, err)
+		}
+		wg.Add(1)
+		go func(t tenant, dir string) {
+			defer wg.Done()
+			superviseTenant(ctx, t, dir, &mu, executable)
+		}(t, dir)
+	}
+	log.Infof("Running %d tenants", len(tenants))
+	wg.Wait()
+}
@@ -0,0 +1,105 @@
+package rss2mastodon
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Table-driven test for reading the tenants file, merging every tenant's settings over the defaults
+func TestLoadTenants(t *testing.T) {
+	tests := []struct {
+		name          string
+		content       string
+		expected      []tenant
+		expectedError bool
+	}{
+		{
+			name: "Tenants with defaults",
+			content: `defaults:
+  poll-interval: 30m
+  attach_images: true
+tenants:
+  bob:
+    MASTODON_URL: https://fosstodon.org
+    MASTODON_ACCESS_TOKEN: bob-token
+    FEED_URL: https://bob.example/rss
+  alice:
+    MASTODON_URL: https://mastodon.social
+    MASTODON_ACCESS_TOKEN: alice-token
+    POLL_INTERVAL: 1h
+`,
+			expected: []tenant{
+				{Name: "alice", Settings: map[string]string{"MASTODON_URL": "https://mastodon.social", "MASTODON_ACCESS_TOKEN": "alice-token", "POLL_INTERVAL": "1h", "ATTACH_IMAGES": "true"}},
+				{Name: "bob", Settings: map[string]string{"MASTODON_URL": "https://fosstodon.org", "MASTODON_ACCESS_TOKEN": "bob-token", "FEED_URL": "https://bob.example/rss", "POLL_INTERVAL": "30m", "ATTACH_IMAGES": "true"}},
+			},
+		},
+		{
+			name:     "Config directory instead of credentials",
+			content:  "tenants:\n  alice:\n    CONFIG_DIR: /etc/rss2mastodon/alice\n",
+			expected: []tenant{{Name: "alice", Settings: map[string]string{"CONFIG_DIR": "/etc/rss2mastodon/alice"}}},
+		},
+		{
+			name:          "No tenants",
+			content:       "defaults:\n  POLL_INTERVAL: 30m\n",
+			expectedError: true,
+		},
+		{
+			name:          "Missing credentials",
+			content:       "tenants:\n  alice:\n    MASTODON_URL: https://mastodon.social\n",
+			expectedError: true,
+		},
+		{
+			name:          "Invalid name",
+			content:       "tenants:\n  ../alice:\n    MASTODON_URL: https://mastodon.social\n    MASTODON_ACCESS_TOKEN: token\n",
+			expectedError: true,
+		},
+		{
+			name:          "List value",
+			content:       "tenants:\n  alice:\n    MASTODON_URL: https://mastodon.social\n    MASTODON_ACCESS_TOKEN: token\n    FEED_URL: [https://a.example/rss]\n",
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "tenants.yml")
+			if err := os.WriteFile(path, []byte(tt.content), 0600); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			tenants, err := loadTenants(path)
+			if (err != nil) != tt.expectedError {
+				t.Fatalf("Expected error %v, got %v", tt.expectedError, err)
+			}
+			if !tt.expectedError && !reflect.DeepEqual(tenants, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, tenants)
+			}
+		})
+	}
+}
+
+// Test running a tenant's process in its directory with only its own settings
+func TestRunTenant(t *testing.T) {
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skip("no /bin/sh")
+	}
+	t.Setenv("MASTODON_ACCESS_TOKEN", "supervisor-token")
+	dir := t.TempDir()
+
+	var out strings.Builder
+	alice := tenant{Name: "alice", Settings: map[string]string{"MASTODON_URL": "https://mastodon.social"}}
+	err := runTenant(context.Background(), alice, dir, &out, &sync.Mutex{}, "/bin/sh", "-c", `echo "$MASTODON_URL token=$MASTODON_ACCESS_TOKEN"; pwd`)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	realDir, _ := filepath.EvalSymlinks(dir)
+	expected := "[alice] https://mastodon.social token=\n[alice] " + realDir + "\n"
+	if out.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, out.String())
+	}
+}
@@ -0,0 +1,31 @@
+package rss2mastodon
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/mastodon"
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// postAnnouncement toots the announcement of a new post, splitting "Thoughts" posts exceeding the
+// character limit into a numbered reply chain if thread_thoughts is set, instead of truncating
+// them. It returns the status starting the thread and how many statuses it has. Failing to post
+// the rest of a thread is only logged, as its start already announces the post.
+func postAnnouncement(logger *log.Entry, client *mastodon.Client, post rss.RSSItem, content string, opts mastodon.TootOptions) (*mastodon.Status, int, error) {
+	if !mastodon.IsThought(post) || !viper.GetBool("thread_thoughts") {
+		status, err := client.PostStatus(content, opts)
+		return status, 1, err
+	}
+
+	statuses, err := client.PostThread(content, opts)
+	if len(statuses) == 0 {
+		return nil, 0, err
+	}
+	if err != nil {
+		logger.Errorf("Thread for %s is incomplete: %v", post.Link, err)
+	} else if len(statuses) > 1 {
+		logger.Infof("Tooted %s as a thread of %d statuses", post.Link, len(statuses))
+	}
+	return statuses[0], len(statuses), nil
+}
@@ -0,0 +1,56 @@
+package rss2mastodon
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/mastodon"
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// Table-driven test for tooting long "Thoughts" posts as a thread
+func TestPostAnnouncement(t *testing.T) {
+	tests := []struct {
+		name           string
+		title          string
+		threadThoughts bool
+		expected       []string
+	}{
+		{"Thread", "Thoughts on threads", true, []string{"one two 1/3", "three 2/3", "four five 3/3"}},
+		{"Threads disabled", "Thoughts on threads", false, []string{"one two…"}},
+		{"Not a thought", "Threads", true, []string{"one two…"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var statuses []string
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				statuses = append(statuses, r.FormValue("status"))
+				_, _ = fmt.Fprintf(w, `{"id":"%d"}`, len(statuses))
+			}))
+			defer mockServer.Close()
+
+			viper.Reset()
+			viper.Set("thread_thoughts", tt.threadThoughts)
+			defer viper.Reset()
+
+			client := mastodon.NewClient(mastodon.Config{URL: mockServer.URL, AccessToken: "fake-token", MaxCharacters: 13})
+			post := rss.RSSItem{Title: tt.title, Link: "https://example.com/threads"}
+			status, threadLength, err := postAnnouncement(feedLogger(post.FeedURL), client, post, "one two three four five", mastodon.TootOptions{})
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if status.ID != "1" || threadLength != len(tt.expected) {
+				t.Errorf("Expected a thread of %d starting with status 1, got %d starting with %s", len(tt.expected), threadLength, status.ID)
+			}
+			if !reflect.DeepEqual(statuses, tt.expected) {
+				t.Errorf("Expected statuses %q, got %q", tt.expected, statuses)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package rss2mastodon
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// minHourSamples is how many statuses an hour needs before its engagement is trusted
+const minHourSamples = 3
+
+// defaultMaxPostingDelay bounds how long a post is held for a better posting hour
+const defaultMaxPostingDelay = 12 * time.Hour
+
+// postingHours parses posting_hours, e.g. "8-22", into the first and the end (exclusive) hour
+// posts may be shifted into. Ranges may wrap around midnight, and all hours are allowed if unset.
+func postingHours() (int, int, error) {
+	value := strings.TrimSpace(viper.GetString("posting_hours"))
+	if value == "" {
+		return 0, 24, nil
+	}
+	return parseHours(value)
+}
+
+// parseHours parses a range of hours like "8-22" into its first and end (exclusive) hour
+func parseHours(value string) (int, int, error) {
+	startValue, endValue, ok := strings.Cut(value, "-")
+	start, startErr := strconv.Atoi(strings.TrimSpace(startValue))
+	end, endErr := strconv.Atoi(strings.TrimSpace(endValue))
+	if !ok || startErr != nil || endErr != nil || start < 0 || start > 23 || end < 1 || end > 24 || start == end {
+		return 0, 0, fmt.Errorf("invalid posting hours %q, expected e.g. 8-22", value)
+	}
+	return start, end, nil
+}
+
+// inHours reports whether an hour of the day lies within start (inclusive) and end (exclusive)
+func inHours(hour, start, end int) bool {
+	if start < end {
+		return hour >= start && hour < end
+	}
+	return hour >= start || hour < end
+}
+
+// bestPostingHour returns the hour of the day, in now's time zone and within posting_hours,
+// whose statuses historically received the most engagement on average
+func bestPostingHour(statuses []db.StatusEngagement, now time.Time) (hour int, average float64, ok bool, err error) {
+	start, end, err := postingHours()
+	if err != nil {
+		return 0, 0, false, err
+	}
+
+	var totals, counts [24]int
+	for _, status := range statuses {
+		h := status.CreatedAt.In(now.Location()).Hour()
+		totals[h] += status.Engagement
+		counts[h]++
+	}
+
+	for h := 0; h < 24; h++ {
+		if counts[h] < minHourSamples || !inHours(h, start, end) {
+			continue
+		}
+		if avg := float64(totals[h]) / float64(counts[h]); !ok || avg > average {
+			hour, average, ok = h, avg, true
+		}
+	}
+	return hour, average, ok, nil
+}
+
+// preferredPostingTime returns when a new post should be announced to make the most of
+// historical engagement, and false if it should be announced right away
+func preferredPostingTime(now time.Time) (time.Time, bool) {
+	if !viper.GetBool("optimize_posting_time") {
+		return time.Time{}, false
+	}
+
+	statuses, err := db.CheckedStatusEngagement()
+	if err != nil {
+		log.Error("Failed to read status engagement: ", err)
+		return time.Time{}, false
+	}
+	hour, average, ok, err := bestPostingHour(statuses, now)
+	if err != nil {
+		log.Error(err)
+		return time.Time{}, false
+	}
+	if !ok {
+		log.Debug("Not enough engagement data to pick a posting time yet, posting now")
+		return time.Time{}, false
+	}
+	if now.Hour() == hour {
+		log.Infof("Posting now, within the best posting hour %02d:00 (%.1f average engagement)", hour, average)
+		return time.Time{}, false
+	}
+
+	next := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	maxDelay := viper.GetDuration("max_posting_delay")
+	if maxDelay <= 0 {
+		maxDelay = defaultMaxPostingDelay
+	}
+	if next.Sub(now) > maxDelay {
+		log.Infof("Posting now, the best posting hour %02d:00 (%.1f average engagement) is more than %s away", hour, average, maxDelay)
+		return time.Time{}, false
+	}
+
+	log.Infof("Holding post until %s, the best posting hour (%.1f average engagement)", next.Format(time.RFC1123), average)
+	return next, true
+}
+
+// holdForPostingTime queues a new post until its preferred posting time, or the next time its
+// feed's posting schedule allows, returning whether it was held
+func holdForPostingTime(logger *log.Entry, post rss.RSSItem) bool {
+	now := time.Now()
+	notBefore, ok := preferredPostingTime(now)
+	if !ok {
+		notBefore = now
+	}
+	if scheduled := nextPostingTime(post.FeedURL, notBefore); !scheduled.Equal(notBefore) {
+		logger.Infof("Holding post until %s, allowed by its posting schedule: %s", scheduled.Format(time.RFC1123), post.Title)
+		notBefore, ok = scheduled, true
+	}
+	if !ok {
+		return false
+	}
+	if err := db.QueuePendingPost(post, notBefore, db.PendingTiming); err != nil {
+		logger.Error("Failed to queue post for its posting time: ", err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,141 @@
+package rss2mastodon
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+)
+
+// Table-driven test for parsing posting hours
+func TestPostingHours(t *testing.T) {
+	tests := []struct {
+		name          string
+		value         string
+		expectedStart int
+		expectedEnd   int
+		expectError   bool
+	}{
+		{"Unset", "", 0, 24, false},
+		{"Daytime", "8-22", 8, 22, false},
+		{"Wraps midnight", "22 - 2", 22, 2, false},
+		{"Missing end", "8", 0, 0, true},
+		{"Out of range", "8-25", 0, 0, true},
+		{"Empty range", "8-8", 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Set("posting_hours", tt.value)
+			defer viper.Reset()
+
+			start, end, err := postingHours()
+			if (err != nil) != tt.expectError {
+				t.Fatalf("Expected error %v, got %v", tt.expectError, err)
+			}
+			if start != tt.expectedStart || end != tt.expectedEnd {
+				t.Errorf("Expected %d-%d, got %d-%d", tt.expectedStart, tt.expectedEnd, start, end)
+			}
+		})
+	}
+}
+
+// engagementAt returns n statuses tooted at the given hour with the given engagement
+func engagementAt(hour int, engagement int, n int) []db.StatusEngagement {
+	var statuses []db.StatusEngagement
+	for i := 0; i < n; i++ {
+		statuses = append(statuses, db.StatusEngagement{
+			CreatedAt:  time.Date(2024, 1, 1+i, hour, 30, 0, 0, time.UTC),
+			Engagement: engagement,
+		})
+	}
+	return statuses
+}
+
+// Table-driven test for picking the best posting hour within the configured bounds
+func TestBestPostingHour(t *testing.T) {
+	statuses := append(append(append(
+		engagementAt(9, 2, 3),
+		engagementAt(18, 10, 3)...),
+		engagementAt(23, 50, 3)...),
+		engagementAt(3, 100, 2)...) // too few samples to count
+
+	tests := []struct {
+		name         string
+		hours        string
+		expectedHour int
+		expectedOK   bool
+	}{
+		{"Any hour", "", 23, true},
+		{"Daytime", "8-22", 18, true},
+		{"Morning only", "6-12", 9, true},
+		{"No data in bounds", "12-17", 0, false},
+	}
+
+	now := time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Set("posting_hours", tt.hours)
+			defer viper.Reset()
+
+			hour, _, ok, err := bestPostingHour(statuses, now)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if ok != tt.expectedOK || hour != tt.expectedHour {
+				t.Errorf("Expected %d (%v), got %d (%v)", tt.expectedHour, tt.expectedOK, hour, ok)
+			}
+		})
+	}
+}
+
+// Test new posts are held until the best posting hour, unless it is too far away
+func TestPreferredPostingTime(t *testing.T) {
+	db.InitDB()
+	defer db.CloseDB()
+	defer os.Remove("./tooted_posts.db")
+
+	// statuses are recorded as tooted now, making the current hour the best one
+	for _, id := range []string{"1", "2", "3"} {
+		if err := db.RecordStatus(id, "", "https://example.com/"+id, styleNewPost); err != nil {
+			t.Fatalf("Failed to record status: %v", err)
+		}
+		if err := db.UpdateStatusEngagement(id, 5, 0, 0); err != nil {
+			t.Fatalf("Failed to update engagement: %v", err)
+		}
+	}
+	best := time.Now().UTC().Truncate(time.Hour)
+	earlier := best.Add(-90 * time.Minute)
+
+	tests := []struct {
+		name         string
+		optimize     bool
+		maxDelay     time.Duration
+		now          time.Time
+		expectedHold bool
+	}{
+		{"Disabled", false, 0, earlier, false},
+		{"Within the best hour", true, 0, best.Add(time.Minute), false},
+		{"Before the best hour", true, 0, earlier, true},
+		{"Best hour too far away", true, time.Hour, earlier, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Set("optimize_posting_time", tt.optimize)
+			viper.Set("max_posting_delay", tt.maxDelay)
+			defer viper.Reset()
+
+			next, ok := preferredPostingTime(tt.now)
+			if ok != tt.expectedHold {
+				t.Fatalf("Expected hold %v, got %v", tt.expectedHold, ok)
+			}
+			if ok && !next.Equal(best) {
+				t.Errorf("Expected to hold until %v, got %v", best, next)
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package source
+
+import (
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// hackerNewsSearch is the Algolia search API of Hacker News, used for news.ycombinator.com URLs
+var hackerNewsSearch = "https://hn.algolia.com/api/v1/search_by_date?tags=story&hitsPerPage=100"
+
+// submission is a link aggregator submission
+type submission struct {
+	Title      string
+	URL        string
+	Discussion string
+	Created    string
+}
+
+func init() {
+	Register(Source{Name: "hackernews", Description: "Hacker News submissions", Options: []string{"keywords", "domains"}, Fetch: fetchHackerNews})
+	Register(Source{Name: "lobsters", Description: "Lobsters submissions", Options: []string{"keywords", "domains"}, Fetch: fetchLobsters})
+}
+
+// fetchHackerNews fetches the newest Hacker News stories matching the keywords and domains
+// options. news.ycombinator.com URLs use the newest stories, other URLs are taken as Algolia
+// search API URLs, e.g. https://hn.algolia.com/api/v1/search?tags=front_page.
+func fetchHackerNews(cfg Config) (*rss.RSSFeed, error) {
+	apiURL := cfg.URL
+	if u, err := url.Parse(cfg.URL); err == nil && u.Host == "news.ycombinator.com" {
+		apiURL = hackerNewsSearch
+	}
+
+	var response struct {
+		Hits []struct {
+			ObjectID  string `json:"objectID"`
+			Title     string `json:"title"`
+			URL       string `json:"url"`
+			CreatedAt string `json:"created_at"`
+		} `json:"hits"`
+	}
+	if err := getJSON(apiURL, nil, &response); err != nil {
+		return nil, err
+	}
+
+	var submissions []submission
+	for _, hit := range response.Hits {
+		submissions = append(submissions, submission{
+			Title:      hit.Title,
+			URL:        hit.URL,
+			Discussion: "https://news.ycombinator.com/item?id=" + hit.ObjectID,
+			Created:    hit.CreatedAt,
+		})
+	}
+	return submissionsFeed("Hacker News", submissions, cfg.Options), nil
+}
+
+// fetchLobsters fetches the newest Lobsters stories matching the keywords and domains options,
+// from the site's JSON pages, e.g. https://lobste.rs for the newest stories or
+// https://lobste.rs/t/go for a tag
+func fetchLobsters(cfg Config) (*rss.RSSFeed, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+	path := strings.TrimSuffix(u.Path, "/")
+	switch {
+	case strings.HasSuffix(path, ".json"):
+	case path == "":
+		u.Path = "/newest.json"
+	default:
+		u.Path = path + ".json"
+	}
+
+	var response []struct {
+		Title      string `json:"title"`
+		URL        string `json:"url"`
+		ShortIDURL string `json:"short_id_url"`
+		CreatedAt  string `json:"created_at"`
+	}
+	if err := getJSON(u.String(), nil, &response); err != nil {
+		return nil, err
+	}
+
+	var submissions []submission
+	for _, story := range response {
+		submissions = append(submissions, submission{
+			Title:      story.Title,
+			URL:        story.URL,
+			Discussion: story.ShortIDURL,
+			Created:    story.CreatedAt,
+		})
+	}
+	return submissionsFeed("Lobsters", submissions, cfg.Options), nil
+}
+
+// submissionsFeed returns the submissions matching any of the |-separated keywords (in their
+// title) or domains (of their URL) options as a feed, or all submissions if neither is set
+func submissionsFeed(title string, submissions []submission, options map[string]string) *rss.RSSFeed {
+	keywords := splitList(options["keywords"])
+	domains := splitList(options["domains"])
+
+	feed := &rss.RSSFeed{}
+	feed.Channel.Title = title
+	for _, s := range submissions {
+		if s.Discussion == "" || !matchesWatch(s, keywords, domains) {
+			continue
+		}
+		item := rss.RSSItem{
+			Title:        s.Title,
+			Link:         s.Discussion,
+			GUID:         s.Discussion,
+			Announcement: discussionAnnouncement(s.Title, s.URL, s.Discussion),
+		}
+		if created, err := time.Parse(time.RFC3339, s.Created); err == nil {
+			item.PubDate = created.UTC().Format(time.RFC3339)
+		}
+		feed.Channel.Items = append(feed.Channel.Items, item)
+	}
+	return feed
+}
+
+// matchesWatch reports whether a submission's title contains one of the keywords as a whole
+// word, or its URL is on one of the domains or their subdomains
+func matchesWatch(s submission, keywords []string, domains []string) bool {
+	if len(keywords) == 0 && len(domains) == 0 {
+		return true
+	}
+	if matchesAnyWord(s.Title, keywords) {
+		return true
+	}
+	if u, err := url.Parse(s.URL); err == nil && u.Host != "" {
+		host := strings.ToLower(strings.TrimPrefix(u.Hostname(), "www."))
+		for _, domain := range domains {
+			domain = strings.ToLower(strings.TrimPrefix(domain, "www."))
+			if host == domain || strings.HasSuffix(host, "."+domain) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// containsWord reports whether text contains word case-insensitively, not as part of a longer word
+func containsWord(text string, word string) bool {
+	text, word = strings.ToLower(text), strings.ToLower(word)
+	isWordRune := func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }
+	for offset := 0; ; {
+		i := strings.Index(text[offset:], word)
+		if i < 0 {
+			return false
+		}
+		start, end := offset+i, offset+i+len(word)
+		before, _ := utf8.DecodeLastRuneInString(text[:start])
+		after, _ := utf8.DecodeRuneInString(text[end:])
+		if !isWordRune(before) && !isWordRune(after) {
+			return true
+		}
+		offset = start + 1
+	}
+}
+
+// matchesAnyWord reports whether text contains one of the words
+func matchesAnyWord(text string, words []string) bool {
+	for _, word := range words {
+		if containsWord(text, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitList splits a |-separated option value, as commas separate --feed options
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, "|") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// discussionAnnouncement announces a link shared on a community site along with its discussion
+func discussionAnnouncement(title string, link string, discussion string) string {
+	announcement := title + "\n\n"
+	if link != "" && link != discussion {
+		announcement += link + "\n\n"
+	}
+	return announcement + "Discussion: " + discussion
+}
@@ -0,0 +1,107 @@
+package source
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Test watching Hacker News and Lobsters for submissions matching keywords or domains
+func TestFetchSubmissions(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/search_by_date":
+			_, _ = w.Write([]byte(`{"hits":[
+				{"objectID":"1","title":"Show HN: A Mastodon bot for RSS","url":"https://example.com/bot","created_at":"2024-05-01T12:00:00.000Z"},
+				{"objectID":"2","title":"Goodbye, old friend","url":"https://blog.example.org/bye","created_at":"2024-05-01T13:00:00.000Z"},
+				{"objectID":"3","title":"Ask HN: Go or Rust?","created_at":"2024-05-01T14:00:00.000Z"},
+				{"objectID":"4","title":"Unrelated","url":"https://unrelated.com/go","created_at":"2024-05-01T15:00:00.000Z"}
+			]}`))
+		case "/t/go.json":
+			_, _ = w.Write([]byte(`[
+				{"title":"Generics in Go","url":"https://go.dev/blog/generics","short_id_url":"https://lobste.rs/s/abc123","created_at":"2024-05-01T09:00:00.000-05:00"},
+				{"title":"Something else","url":"https://example.net/else","short_id_url":"https://lobste.rs/s/def456","created_at":"2024-05-01T10:00:00.000-05:00"}
+			]`))
+		default:
+			t.Errorf("Unexpected request: %s", r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	defer mockServer.Close()
+
+	tests := []struct {
+		name          string
+		source        string
+		url           string
+		options       map[string]string
+		expectedLinks []string
+	}{
+		{
+			name:          "Hacker News keywords",
+			source:        "hackernews",
+			url:           "/api/v1/search_by_date?tags=story",
+			options:       map[string]string{"keywords": "mastodon|go"},
+			expectedLinks: []string{"https://news.ycombinator.com/item?id=1", "https://news.ycombinator.com/item?id=3"},
+		},
+		{
+			name:          "Hacker News domains",
+			source:        "hackernews",
+			url:           "/api/v1/search_by_date?tags=story",
+			options:       map[string]string{"domains": "example.org"},
+			expectedLinks: []string{"https://news.ycombinator.com/item?id=2"},
+		},
+		{
+			name:          "Lobsters tag without filters",
+			source:        "lobsters",
+			url:           "/t/go",
+			expectedLinks: []string{"https://lobste.rs/s/abc123", "https://lobste.rs/s/def456"},
+		},
+		{
+			name:          "Lobsters domains",
+			source:        "lobsters",
+			url:           "/t/go/",
+			options:       map[string]string{"domains": "go.dev"},
+			expectedLinks: []string{"https://lobste.rs/s/abc123"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src, _ := Get(tt.source)
+			feed, err := src.Fetch(Config{URL: mockServer.URL + tt.url, Options: tt.options})
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			var links []string
+			for _, item := range feed.Channel.Items {
+				links = append(links, item.Link)
+			}
+			if strings.Join(links, ",") != strings.Join(tt.expectedLinks, ",") {
+				t.Errorf("Expected submissions %v, got %v", tt.expectedLinks, links)
+			}
+		})
+	}
+}
+
+// Test announcing submissions with their link and discussion
+func TestSubmissionsFeed(t *testing.T) {
+	feed := submissionsFeed("Lobsters", []submission{
+		{Title: "Generics in Go", URL: "https://go.dev/blog/generics", Discussion: "https://lobste.rs/s/abc123", Created: "2024-05-01T09:00:00.000-05:00"},
+		{Title: "Ask: C++ or C?", Discussion: "https://lobste.rs/s/def456"},
+	}, map[string]string{"keywords": "c++|generics"})
+
+	if len(feed.Channel.Items) != 2 {
+		t.Fatalf("Expected 2 items, got %+v", feed.Channel.Items)
+	}
+	first := feed.Channel.Items[0]
+	if first.Announcement != "Generics in Go\n\nhttps://go.dev/blog/generics\n\nDiscussion: https://lobste.rs/s/abc123" {
+		t.Errorf("Unexpected announcement %q", first.Announcement)
+	}
+	if first.PubDate != "2024-05-01T14:00:00Z" {
+		t.Errorf("Expected the date in UTC, got %q", first.PubDate)
+	}
+	if feed.Channel.Items[1].Announcement != "Ask: C++ or C?\n\nDiscussion: https://lobste.rs/s/def456" {
+		t.Errorf("Unexpected announcement %q", feed.Channel.Items[1].Announcement)
+	}
+}
@@ -0,0 +1,298 @@
+package source
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/httpclient"
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// maxTagPages is how many pages of a repository's tag list are read at most
+const maxTagPages = 10
+
+var (
+	semverRegex    = regexp.MustCompile(`^v?(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)(?:-([0-9A-Za-z.-]+))?(?:\+[0-9A-Za-z.-]+)?$`)
+	authParamRegex = regexp.MustCompile(`(\w+)="([^"]*)"`)
+	nextLinkRegex  = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+)
+
+// semver is a semantic version parsed from an image tag
+type semver struct {
+	Tag                 string
+	Major, Minor, Patch int
+	Prerelease          string
+}
+
+func init() {
+	Register(Source{Name: "container", Description: "Tags of a container image repository", Options: []string{"prereleases", "series", "all_tags"},
+		ConfigKeys: []string{"registry_username", "registry_password"}, Fetch: fetchContainerTags})
+}
+
+// imageRepository splits an image reference like ghcr.io/owner/image or nginx into the
+// registry's API base URL, the repository and the reference to show
+func imageRepository(image string) (string, string, string) {
+	scheme := "https"
+	if u, err := url.Parse(image); err == nil && u.Scheme != "" && u.Host != "" {
+		scheme, image = u.Scheme, u.Host+u.Path
+	}
+	image = strings.Trim(image, "/")
+
+	registry, repo := "docker.io", image
+	if first, rest, ok := strings.Cut(image, "/"); ok && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		registry, repo = first, rest
+	}
+	if registry != "docker.io" {
+		return scheme + "://" + registry, repo, registry + "/" + repo
+	}
+	if !strings.Contains(repo, "/") {
+		repo = "library/" + repo
+	}
+	return "https://registry-1.docker.io", repo, "docker.io/" + strings.TrimPrefix(repo, "library/")
+}
+
+// fetchContainerTags fetches the tags of a container image repository and returns its 10
+// most recent semantic versions, excluding prereleases unless prereleases=true and limited to
+// a series like 2 or 2.1 if set. all_tags=true returns every tag instead, in registry order.
+// Credentials for private repositories are taken from registry_username and registry_password.
+func fetchContainerTags(cfg Config) (*rss.RSSFeed, error) {
+	api, repo, ref := imageRepository(cfg.URL)
+	tags, err := listTags(api, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Options["all_tags"] != "true" {
+		var versions []semver
+		for _, tag := range tags {
+			v, ok := parseSemver(tag)
+			if !ok || (v.Prerelease != "" && cfg.Options["prereleases"] != "true") || !v.inSeries(cfg.Options["series"]) {
+				continue
+			}
+			versions = append(versions, v)
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[j].less(versions[i]) })
+		tags = nil
+		for _, v := range versions {
+			tags = append(tags, v.Tag)
+		}
+		tags = tags[:min(len(tags), releasesPerPage)]
+	}
+
+	feed := &rss.RSSFeed{}
+	feed.Channel.Title = ref
+	for _, tag := range tags {
+		image := ref + ":" + tag
+		announcement := image + " is out"
+		// registries have no common web interface, so tags are linked by their image reference
+		link := "oci://" + image
+		if page := imagePage(ref, tag); page != "" {
+			link = page
+			announcement += "\n\n" + page
+		}
+		feed.Channel.Items = append(feed.Channel.Items, rss.RSSItem{
+			Title:        image,
+			Link:         link,
+			GUID:         image,
+			Announcement: announcement,
+		})
+	}
+	return feed, nil
+}
+
+// imagePage returns the web page of an image tag, if its registry has a known one
+func imagePage(ref string, tag string) string {
+	repo, ok := strings.CutPrefix(ref, "docker.io/")
+	if !ok {
+		return ""
+	}
+	if !strings.Contains(repo, "/") {
+		return fmt.Sprintf("https://hub.docker.com/_/%s/tags?name=%s", repo, url.QueryEscape(tag))
+	}
+	return fmt.Sprintf("https://hub.docker.com/r/%s/tags?name=%s", repo, url.QueryEscape(tag))
+}
+
+// listTags lists the tags of a repository with the OCI distribution API, following pagination
+func listTags(api string, repo string) ([]string, error) {
+	client := httpclient.New("source", 10*time.Second)
+	next := fmt.Sprintf("%s/v2/%s/tags/list?n=1000", api, repo)
+	token := ""
+	var tags []string
+	for page := 0; next != "" && page < maxTagPages; page++ {
+		resp, err := registryGet(client, next, token)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode == http.StatusUnauthorized && token == "" {
+			challenge := resp.Header.Get("WWW-Authenticate")
+			resp.Body.Close()
+			if token, err = registryToken(client, challenge); err != nil {
+				return nil, err
+			}
+			page--
+			continue
+		}
+
+		var list struct {
+			Tags []string `json:"tags"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&list)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("unexpected HTTP status: %d", resp.StatusCode)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse tag list: %w", err)
+		}
+		tags = append(tags, list.Tags...)
+
+		next = ""
+		if match := nextLinkRegex.FindStringSubmatch(resp.Header.Get("Link")); match != nil {
+			if u, err := url.Parse(api); err == nil {
+				if nextURL, err := u.Parse(match[1]); err == nil {
+					next = nextURL.String()
+				}
+			}
+		}
+	}
+	return tags, nil
+}
+
+// registryGet requests a registry API URL, with a bearer token if one was issued
+func registryGet(client *http.Client, apiURL string, token string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", apiURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+	return resp, nil
+}
+
+// registryToken requests a bearer token as described by a registry's WWW-Authenticate
+// challenge, anonymously or with registry_username and registry_password
+func registryToken(client *http.Client, challenge string) (string, error) {
+	if !strings.HasPrefix(strings.ToLower(challenge), "bearer ") {
+		return "", fmt.Errorf("registry requires unsupported authentication %q", challenge)
+	}
+	params := map[string]string{}
+	for _, match := range authParamRegex.FindAllStringSubmatch(challenge, -1) {
+		params[strings.ToLower(match[1])] = match[2]
+	}
+	tokenURL, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return "", fmt.Errorf("invalid registry authentication challenge %q", challenge)
+	}
+	query := tokenURL.Query()
+	for _, key := range []string{"service", "scope"} {
+		if params[key] != "" {
+			query.Set(key, params[key])
+		}
+	}
+	tokenURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequest("GET", tokenURL.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if username := viper.GetString("registry_username"); username != "" {
+		req.SetBasicAuth(username, viper.GetString("registry_password"))
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("registry token request failed with HTTP status %d", resp.StatusCode)
+	}
+
+	var response struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return "", fmt.Errorf("failed to parse registry token: %w", err)
+	}
+	if response.Token == "" {
+		return response.AccessToken, nil
+	}
+	return response.Token, nil
+}
+
+// parseSemver parses a tag like v1.2.3 or 1.2.3-rc.1, floating tags like 1.2 aren't versions
+func parseSemver(tag string) (semver, bool) {
+	match := semverRegex.FindStringSubmatch(tag)
+	if match == nil {
+		return semver{}, false
+	}
+	v := semver{Tag: tag, Prerelease: match[4]}
+	v.Major, _ = strconv.Atoi(match[1])
+	v.Minor, _ = strconv.Atoi(match[2])
+	v.Patch, _ = strconv.Atoi(match[3])
+	return v, true
+}
+
+// inSeries reports whether the version belongs to a series like 2 or 2.1, or any if empty
+func (v semver) inSeries(series string) bool {
+	if series == "" {
+		return true
+	}
+	parts := []int{v.Major, v.Minor, v.Patch}
+	for i, part := range strings.Split(strings.TrimPrefix(series, "v"), ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil || i >= len(parts) || parts[i] != n {
+			return false
+		}
+	}
+	return true
+}
+
+// less reports whether v has a lower precedence than other, as defined by semver 2.0.0
+func (v semver) less(other semver) bool {
+	if v.Major != other.Major {
+		return v.Major < other.Major
+	}
+	if v.Minor != other.Minor {
+		return v.Minor < other.Minor
+	}
+	if v.Patch != other.Patch {
+		return v.Patch < other.Patch
+	}
+	if v.Prerelease == "" || other.Prerelease == "" {
+		return v.Prerelease != "" && other.Prerelease == ""
+	}
+
+	a, b := strings.Split(v.Prerelease, "."), strings.Split(other.Prerelease, ".")
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] == b[i] {
+			continue
+		}
+		x, errX := strconv.Atoi(a[i])
+		y, errY := strconv.Atoi(b[i])
+		switch {
+		case errX == nil && errY == nil:
+			return x < y
+		case errX == nil || errY == nil:
+			// numeric identifiers have lower precedence than alphanumeric ones
+			return errX == nil
+		default:
+			return a[i] < b[i]
+		}
+	}
+	return len(a) < len(b)
+}
@@ -0,0 +1,141 @@
+package source
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Test splitting image references into registry API, repository and reference
+func TestImageRepository(t *testing.T) {
+	tests := []struct {
+		image        string
+		expectedAPI  string
+		expectedRepo string
+		expectedRef  string
+	}{
+		{"ghcr.io/owner/image", "https://ghcr.io", "owner/image", "ghcr.io/owner/image"},
+		{"nginx", "https://registry-1.docker.io", "library/nginx", "docker.io/nginx"},
+		{"docker.io/owner/image", "https://registry-1.docker.io", "owner/image", "docker.io/owner/image"},
+		{"owner/image", "https://registry-1.docker.io", "owner/image", "docker.io/owner/image"},
+		{"localhost:5000/image", "https://localhost:5000", "image", "localhost:5000/image"},
+		{"http://127.0.0.1:5000/owner/image/", "http://127.0.0.1:5000", "owner/image", "127.0.0.1:5000/owner/image"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			api, repo, ref := imageRepository(tt.image)
+			if api != tt.expectedAPI || repo != tt.expectedRepo || ref != tt.expectedRef {
+				t.Errorf("imageRepository(%q) = %q, %q, %q, want %q, %q, %q", tt.image, api, repo, ref, tt.expectedAPI, tt.expectedRepo, tt.expectedRef)
+			}
+		})
+	}
+}
+
+// Test ordering semantic versions by precedence
+func TestSemverLess(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected bool
+	}{
+		{"1.2.3", "1.2.4", true},
+		{"v1.10.0", "1.9.0", false},
+		{"2.0.0-rc.1", "2.0.0", true},
+		{"2.0.0", "2.0.0-rc.1", false},
+		{"1.0.0-alpha", "1.0.0-alpha.1", true},
+		{"1.0.0-alpha.1", "1.0.0-beta", true},
+		{"1.0.0-beta.2", "1.0.0-beta.11", true},
+		{"1.0.0-1", "1.0.0-alpha", true},
+		{"1.0.0+build.1", "1.0.0", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+" < "+tt.b, func(t *testing.T) {
+			a, okA := parseSemver(tt.a)
+			b, okB := parseSemver(tt.b)
+			if !okA || !okB {
+				t.Fatalf("Failed to parse %q or %q", tt.a, tt.b)
+			}
+			if result := a.less(b); result != tt.expected {
+				t.Errorf("%q.less(%q) = %v, want %v", tt.a, tt.b, result, tt.expected)
+			}
+		})
+	}
+}
+
+// Test announcing the tags of a registry requiring an anonymous bearer token
+func TestFetchContainerTags(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/token":
+			if r.URL.Query().Get("scope") != "repository:owner/image:pull" {
+				http.Error(w, "bad scope", http.StatusBadRequest)
+				return
+			}
+			fmt.Fprint(w, `{"token": "anonymous"}`)
+		case r.Header.Get("Authorization") != "Bearer anonymous":
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="registry",scope="repository:owner/image:pull"`, server.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Query().Get("last") == "":
+			w.Header().Set("Link", `</v2/owner/image/tags/list?n=1000&last=v1.2.0>; rel="next"`)
+			fmt.Fprint(w, `{"name": "owner/image", "tags": ["latest", "1", "1.2", "v1.0.0", "v1.2.0"]}`)
+		default:
+			fmt.Fprint(w, `{"name": "owner/image", "tags": ["v1.10.0", "v2.0.0-rc.1", "v1.9.3", "sha-abc123"]}`)
+		}
+	}))
+	defer server.Close()
+	ref := strings.TrimPrefix(server.URL, "http://") + "/owner/image"
+
+	tests := []struct {
+		name         string
+		options      map[string]string
+		expectedTags []string
+	}{
+		{
+			name:         "Releases",
+			expectedTags: []string{"v1.10.0", "v1.9.3", "v1.2.0", "v1.0.0"},
+		},
+		{
+			name:         "Prereleases",
+			options:      map[string]string{"prereleases": "true"},
+			expectedTags: []string{"v2.0.0-rc.1", "v1.10.0", "v1.9.3", "v1.2.0", "v1.0.0"},
+		},
+		{
+			name:         "Series",
+			options:      map[string]string{"series": "1.9"},
+			expectedTags: []string{"v1.9.3"},
+		},
+		{
+			name:         "All tags",
+			options:      map[string]string{"all_tags": "true"},
+			expectedTags: []string{"latest", "1", "1.2", "v1.0.0", "v1.2.0", "v1.10.0", "v2.0.0-rc.1", "v1.9.3", "sha-abc123"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed, err := fetchContainerTags(Config{URL: server.URL + "/owner/image", Options: tt.options})
+			if err != nil {
+				t.Fatalf("fetchContainerTags() error = %v", err)
+			}
+			var tags []string
+			for _, item := range feed.Channel.Items {
+				tags = append(tags, strings.TrimPrefix(item.Title, ref+":"))
+			}
+			if !reflect.DeepEqual(tags, tt.expectedTags) {
+				t.Errorf("tags = %v, want %v", tags, tt.expectedTags)
+			}
+			first := feed.Channel.Items[0]
+			if expected := ref + ":" + tt.expectedTags[0] + " is out"; first.Announcement != expected {
+				t.Errorf("Announcement = %q, want %q", first.Announcement, expected)
+			}
+			if expected := "oci://" + ref + ":" + tt.expectedTags[0]; first.Link != expected {
+				t.Errorf("Link = %q, want %q", first.Link, expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,272 @@
+//go:build !no_directory
+
+package source
+
+import (
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// watchDebounce is how long a watched directory has to be quiet before a change is reported,
+// so a site build writing many files results in a single poll
+var watchDebounce = 2 * time.Second
+
+// frontMatterDateLayouts are the date formats accepted in front matter
+var frontMatterDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+func init() {
+	Register(Source{Name: "directory", Description: "Pages of a local static site directory", Options: []string{"base_url", "pattern"}, Fetch: fetchDirectory, Watch: watchDirectory})
+}
+
+// directoryRoot returns the local directory of a directory source URL, a path or file:// URL
+func directoryRoot(dirURL string) string {
+	return filepath.FromSlash(strings.TrimPrefix(dirURL, "file://"))
+}
+
+// fetchDirectory returns the HTML and Markdown files of a local directory, e.g. a static site's
+// output or content, as items linked below base_url. Only files whose slash-separated path
+// relative to the directory matches pattern are included if set, drafts are skipped.
+func fetchDirectory(cfg Config) (*rss.RSSFeed, error) {
+	base, err := url.Parse(cfg.Options["base_url"])
+	if err != nil || base.Host == "" {
+		return nil, fmt.Errorf("the base_url option is required for directory feeds, e.g. base_url=https://example.com/")
+	}
+	if !strings.HasSuffix(base.Path, "/") {
+		base.Path += "/"
+	}
+	pattern := cfg.Options["pattern"]
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+
+	root := directoryRoot(cfg.URL)
+	feed := &rss.RSSFeed{}
+	feed.Channel.Title = filepath.Base(root)
+	err = filepath.WalkDir(root, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(root, file)
+		rel = filepath.ToSlash(rel)
+		if entry.IsDir() || !isPageFile(rel) {
+			return nil
+		}
+		if matched, _ := path.Match(pattern, rel); pattern != "" && !matched {
+			return nil
+		}
+
+		item, ok, err := pageItem(file, rel, base)
+		if err != nil {
+			return err
+		}
+		if ok {
+			feed.Channel.Items = append(feed.Channel.Items, item)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return feed, nil
+}
+
+// isPageFile reports whether a file is an HTML or Markdown page
+func isPageFile(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".html", ".htm", ".md", ".markdown":
+		return true
+	}
+	return false
+}
+
+// pageItem reads a page file into an item, reporting false for drafts
+func pageItem(file string, rel string, base *url.URL) (rss.RSSItem, bool, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return rss.RSSItem{}, false, err
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		return rss.RSSItem{}, false, err
+	}
+
+	meta, body := parseFrontMatter(string(data))
+	if meta["draft"] == "true" {
+		return rss.RSSItem{}, false, nil
+	}
+
+	item := rss.RSSItem{Title: meta["title"], Content: strings.TrimSpace(body)}
+	ext := strings.ToLower(path.Ext(rel))
+	if ext == ".html" || ext == ".htm" {
+		page := parseHTML(body)
+		if item.Title == "" {
+			for _, selector := range []string{"title", "h1"} {
+				s, _ := parseSelector(selector)
+				if node := page.selectFirst(s); node != nil && node.text() != "" {
+					item.Title = node.text()
+					break
+				}
+			}
+		}
+		if meta["date"] == "" {
+			s, _ := parseSelector(`meta[property="article:published_time"]`)
+			if node := page.selectFirst(s); node != nil {
+				meta["date"] = node.Attrs["content"]
+			}
+		}
+		item.Content = page.text()
+	}
+	if item.Title == "" {
+		item.Title = strings.TrimSuffix(path.Base(rel), path.Ext(rel))
+	}
+
+	// pages without a date are dated by their last change, so old pages count as resurfaced
+	item.PubDate = info.ModTime().UTC().Format(time.RFC3339)
+	for _, layout := range frontMatterDateLayouts {
+		if t, err := time.Parse(layout, meta["date"]); err == nil {
+			item.PubDate = t.UTC().Format(time.RFC3339)
+			break
+		}
+	}
+
+	item.Link = base.ResolveReference(&url.URL{Path: pagePath(rel, meta)}).String()
+	item.GUID = item.Link
+	return item, true, nil
+}
+
+// pagePath returns the URL path of a page relative to the site root: index pages and Markdown
+// files are served as directories, e.g. posts/hello.md as posts/hello/. The front matter's
+// url (relative to the site root) or slug (replacing the file name) override it.
+func pagePath(rel string, meta map[string]string) string {
+	if u := meta["url"]; u != "" {
+		return strings.TrimPrefix(u, "/")
+	}
+
+	dir, name := path.Split(rel)
+	ext := path.Ext(name)
+	name = strings.TrimSuffix(name, ext)
+	isMarkdown := ext == ".md" || ext == ".markdown"
+	if slug := meta["slug"]; slug != "" && name != "index" && name != "_index" {
+		name = slug
+	}
+	switch {
+	case name == "index" || name == "_index":
+		return dir
+	case isMarkdown:
+		return dir + name + "/"
+	}
+	return dir + name + ext
+}
+
+// parseFrontMatter splits a YAML (---) or TOML (+++) front matter block off a page, returning
+// its top-level scalar values by key. Nested values and lists are ignored.
+func parseFrontMatter(content string) (map[string]string, string) {
+	meta := make(map[string]string)
+	content = strings.TrimPrefix(content, "\ufeff")
+	lines := strings.SplitAfter(content, "\n")
+	var separator string
+	switch delimiter := strings.TrimSpace(lines[0]); delimiter {
+	case "---":
+		separator = ":"
+	case "+++":
+		separator = "="
+	default:
+		return meta, content
+	}
+
+	offset := len(lines[0])
+	for _, line := range lines[1:] {
+		offset += len(line)
+		if strings.TrimSpace(line) == strings.TrimSpace(lines[0]) {
+			return meta, content[offset:]
+		}
+		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, separator)
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		meta[strings.ToLower(strings.TrimSpace(key))] = value
+	}
+	// no closing delimiter, so it wasn't front matter after all
+	return map[string]string{}, content
+}
+
+// watchDirectory watches a directory and its subdirectories, calling changed once the
+// directory has been quiet for watchDebounce after pages were written, moved or removed
+func watchDirectory(cfg Config, changed func()) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	addDirs := func(root string) error {
+		return filepath.WalkDir(root, func(dir string, entry fs.DirEntry, err error) error {
+			if err != nil || !entry.IsDir() {
+				return err
+			}
+			return watcher.Add(dir)
+		})
+	}
+	if err := addDirs(directoryRoot(cfg.URL)); err != nil {
+		watcher.Close()
+		return err
+	}
+
+	go func() {
+		defer watcher.Close()
+		var timer *time.Timer
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				newDir := false
+				if event.Has(fsnotify.Create) {
+					if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+						// pages may have been written to it before it is watched
+						newDir = true
+						if err := addDirs(event.Name); err != nil {
+							log.Error("Failed to watch new directory: ", err)
+						}
+					}
+				}
+				if !newDir && (!isPageFile(event.Name) || event.Has(fsnotify.Chmod)) {
+					continue
+				}
+				if timer != nil {
+					timer.Stop()
+				}
+				timer = time.AfterFunc(watchDebounce, changed)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Error("Directory watch failed: ", err)
+			}
+		}
+	}()
+	return nil
+}
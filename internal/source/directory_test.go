@@ -0,0 +1,170 @@
+//go:build !no_directory
+
+package source
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test parsing YAML and TOML front matter
+func TestParseFrontMatter(t *testing.T) {
+	tests := []struct {
+		name         string
+		content      string
+		expectedMeta map[string]string
+		expectedBody string
+	}{
+		{
+			name:         "YAML",
+			content:      "---\r\ntitle: \"Hello: World\"\r\ndate: 2024-05-01T12:00:00Z\r\ntags:\r\n  - go\r\n---\r\nBody\r\n",
+			expectedMeta: map[string]string{"title": "Hello: World", "date": "2024-05-01T12:00:00Z", "tags": ""},
+			expectedBody: "Body\r\n",
+		},
+		{
+			name:         "TOML",
+			content:      "+++\ntitle = 'Hello'\ndraft = true\n+++\nBody",
+			expectedMeta: map[string]string{"title": "Hello", "draft": "true"},
+			expectedBody: "Body",
+		},
+		{
+			name:         "No front matter",
+			content:      "# Hello\n---\n",
+			expectedMeta: map[string]string{},
+			expectedBody: "# Hello\n---\n",
+		},
+		{
+			name:         "Unterminated",
+			content:      "---\ntitle: Hello\n",
+			expectedMeta: map[string]string{},
+			expectedBody: "---\ntitle: Hello\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta, body := parseFrontMatter(tt.content)
+			if len(meta) != len(tt.expectedMeta) {
+				t.Errorf("Expected %v, got %v", tt.expectedMeta, meta)
+			}
+			for key, value := range tt.expectedMeta {
+				if meta[key] != value {
+					t.Errorf("Expected %s %q, got %q", key, value, meta[key])
+				}
+			}
+			if body != tt.expectedBody {
+				t.Errorf("Expected body %q, got %q", tt.expectedBody, body)
+			}
+		})
+	}
+}
+
+// Test mapping page files to their URL paths
+func TestPagePath(t *testing.T) {
+	tests := []struct {
+		rel      string
+		meta     map[string]string
+		expected string
+	}{
+		{rel: "posts/hello/index.html", expected: "posts/hello/"},
+		{rel: "about.html", expected: "about.html"},
+		{rel: "content/posts/hello.md", expected: "content/posts/hello/"},
+		{rel: "posts/_index.md", expected: "posts/"},
+		{rel: "posts/2024-05-01-hello.md", meta: map[string]string{"slug": "hello"}, expected: "posts/hello/"},
+		{rel: "posts/hello.md", meta: map[string]string{"url": "/blog/hello-world/"}, expected: "blog/hello-world/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rel, func(t *testing.T) {
+			if got := pagePath(tt.rel, tt.meta); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+// Test announcing the pages of a directory and watching it for changes
+func TestDirectorySource(t *testing.T) {
+	dir := t.TempDir()
+	write := func(rel string, content string) {
+		file := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+	write("posts/hello/index.html", `<html><head><title>Hello</title><meta property="article:published_time" content="2024-05-01T12:00:00+02:00"></head><body><p>Hi there</p></body></html>`)
+	write("posts/second.md", "---\ntitle: Second post\ndate: 2024-05-02\n---\nMore words")
+	write("posts/draft.md", "---\ntitle: Draft\ndraft: true\n---\nNot yet")
+	write("tags/go/index.html", "<h1>Posts tagged go</h1>")
+	write("style.css", "body {}")
+
+	src, _ := Get("directory")
+	tests := []struct {
+		name          string
+		options       map[string]string
+		expectedItems []string
+		expectError   bool
+	}{
+		{
+			name:    "All pages",
+			options: map[string]string{"base_url": "https://example.com/blog"},
+			expectedItems: []string{
+				"Hello https://example.com/blog/posts/hello/ 2024-05-01T10:00:00Z",
+				"Second post https://example.com/blog/posts/second/ 2024-05-02T00:00:00Z",
+				"Posts tagged go https://example.com/blog/tags/go/",
+			},
+		},
+		{
+			name:          "Pattern",
+			options:       map[string]string{"base_url": "https://example.com/", "pattern": "posts/*/index.html"},
+			expectedItems: []string{"Hello https://example.com/posts/hello/ 2024-05-01T10:00:00Z"},
+		},
+		{
+			name:        "Missing base URL",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed, err := src.Fetch(Config{URL: "file://" + filepath.ToSlash(dir), Options: tt.options})
+			if (err != nil) != tt.expectError {
+				t.Fatalf("Expected error %v, got %v", tt.expectError, err)
+			}
+			if tt.expectError {
+				return
+			}
+			var items []string
+			for _, item := range feed.Channel.Items {
+				summary := item.Title + " " + item.Link
+				// pages without a date are dated by their modification time
+				if !strings.HasPrefix(item.Title, "Posts tagged") {
+					summary += " " + item.PubDate
+				}
+				items = append(items, summary)
+			}
+			if strings.Join(items, "\n") != strings.Join(tt.expectedItems, "\n") {
+				t.Errorf("Expected items\n%s\ngot\n%s", strings.Join(tt.expectedItems, "\n"), strings.Join(items, "\n"))
+			}
+		})
+	}
+
+	watchDebounce = 10 * time.Millisecond
+	defer func() { watchDebounce = 2 * time.Second }()
+	changed := make(chan struct{}, 10)
+	if err := src.Watch(Config{URL: dir}, func() { changed <- struct{}{} }); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	write("posts/third/index.html", "<title>Third</title>")
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Errorf("Expected a new page in a new directory to be reported")
+	}
+}
@@ -0,0 +1,184 @@
+//go:build !no_git
+
+package source
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// gitLogLimit is how many of the most recent commits or tags are considered
+const gitLogLimit = 50
+
+// gitCacheDir is where repositories are mirrored, replaced in tests
+var gitCacheDir = func() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "rss2mastodon", "git")
+}
+
+func init() {
+	Register(Source{Name: "git", Description: "Commits or tags of a git repository", Options: []string{"branch", "paths", "message", "tags", "web_url"}, Fetch: fetchGit})
+}
+
+// fetchGit mirrors a git repository and returns its recent commits touching one of the
+// |-separated paths and containing one of the |-separated message keywords, or its tags if
+// tags=true. The git binary is used, so any URL it can clone from works.
+func fetchGit(cfg Config) (*rss.RSSFeed, error) {
+	dir, err := mirrorRepository(cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	webURL := strings.TrimSuffix(cfg.Options["web_url"], "/")
+	if webURL == "" {
+		if u, err := url.Parse(cfg.URL); err == nil && (u.Scheme == "https" || u.Scheme == "http") {
+			u.User = nil
+			webURL = strings.TrimSuffix(strings.TrimSuffix(u.String(), "/"), ".git")
+		}
+	}
+	name := repositoryName(cfg.URL)
+
+	feed := &rss.RSSFeed{}
+	feed.Channel.Title = name
+	if cfg.Options["tags"] == "true" {
+		out, err := git(dir, "for-each-ref", "--sort=-creatordate", fmt.Sprintf("--count=%d", gitLogLimit),
+			"--format=%(refname:short)%1f%(creatordate:iso-strict)%1f%(subject)%1e", "refs/tags")
+		if err != nil {
+			return nil, err
+		}
+		for _, fields := range gitRecords(out) {
+			tag, date, subject := fields[0], fields[1], fields[2]
+			link := gitLink(cfg.URL, webURL, "/tree/", tag)
+			announcement := fmt.Sprintf("%s %s tagged", name, tag)
+			if subject != "" && subject != tag {
+				announcement += ": " + subject
+			}
+			feed.Channel.Items = append(feed.Channel.Items, rss.RSSItem{
+				Title:        tag,
+				Link:         link,
+				GUID:         name + "@" + tag,
+				PubDate:      gitDate(date),
+				Announcement: announcement + "\n\n" + link,
+			})
+		}
+		return feed, nil
+	}
+
+	branch := cfg.Options["branch"]
+	if branch == "" {
+		branch = "HEAD"
+	}
+	args := []string{"log", fmt.Sprintf("-n%d", gitLogLimit), "--format=%H%x1f%aI%x1f%s%x1f%b%x1e", branch, "--"}
+	args = append(args, splitList(cfg.Options["paths"])...)
+	out, err := git(dir, args...)
+	if err != nil {
+		return nil, err
+	}
+	keywords := splitList(cfg.Options["message"])
+	for _, fields := range gitRecords(out) {
+		hash, date, subject, body := fields[0], fields[1], fields[2], fields[3]
+		if len(keywords) > 0 && !matchesAnyWord(subject+"\n"+body, keywords) {
+			continue
+		}
+		link := gitLink(cfg.URL, webURL, "/commit/", hash)
+		feed.Channel.Items = append(feed.Channel.Items, rss.RSSItem{
+			Title:        subject,
+			Link:         link,
+			GUID:         hash,
+			PubDate:      gitDate(date),
+			Content:      strings.TrimSpace(body),
+			Announcement: fmt.Sprintf("New commit to %s: %s\n\n%s", name, subject, link),
+		})
+	}
+	return feed, nil
+}
+
+// mirrorRepository clones a repository into the cache without file contents, or updates
+// the existing mirror, returning its directory
+func mirrorRepository(repoURL string) (string, error) {
+	dir := filepath.Join(gitCacheDir(), fmt.Sprintf("%x", sha256.Sum256([]byte(repoURL))))
+	if _, err := os.Stat(filepath.Join(dir, "HEAD")); err == nil {
+		_, err := git(dir, "fetch", "--prune", "--tags", "origin")
+		return dir, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dir), 0o700); err != nil {
+		return "", err
+	}
+	// clone next to the final directory, so an interrupted clone isn't mistaken for a mirror
+	tmp, err := os.MkdirTemp(filepath.Dir(dir), "clone-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmp)
+	if _, err := git("", "clone", "--mirror", "--filter=blob:none", "--quiet", repoURL, tmp); err != nil {
+		return "", err
+	}
+	return dir, os.Rename(tmp, dir)
+}
+
+// git runs a git command in dir and returns its output
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	// never wait for credentials on a terminal
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s failed: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// gitRecords splits git output formatted with %x1e record and %x1f field separators
+func gitRecords(out string) [][]string {
+	var records [][]string
+	for _, record := range strings.Split(out, "\x1e") {
+		if record = strings.TrimLeft(record, "\n"); record != "" {
+			records = append(records, strings.Split(record, "\x1f"))
+		}
+	}
+	return records
+}
+
+// gitLink links a commit or tag on the repository's web interface, or the repository URL with
+// a fragment if there is none, e.g. for SSH URLs
+func gitLink(repoURL string, webURL string, kind string, ref string) string {
+	if webURL == "" {
+		return repoURL + "#" + url.QueryEscape(ref)
+	}
+	return webURL + kind + url.PathEscape(ref)
+}
+
+// gitDate converts a strict ISO 8601 date of git to RFC 3339 in UTC
+func gitDate(date string) string {
+	t, err := time.Parse(time.RFC3339, date)
+	if err != nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// repositoryName returns the owner/name of a repository URL, e.g. git@github.com:owner/repo.git
+func repositoryName(repoURL string) string {
+	name := strings.TrimSuffix(strings.TrimSuffix(repoURL, "/"), ".git")
+	name = strings.ReplaceAll(name, ":", "/")
+	parts := strings.Split(name, "/")
+	if len(parts) >= 2 {
+		return parts[len(parts)-2] + "/" + parts[len(parts)-1]
+	}
+	return name
+}
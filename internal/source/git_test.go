@@ -0,0 +1,136 @@
+//go:build !no_git
+
+package source
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Test announcing the commits and tags of a git repository
+func TestFetchGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	cacheDir := t.TempDir()
+	originalCacheDir := gitCacheDir
+	gitCacheDir = func() string { return cacheDir }
+	defer func() { gitCacheDir = originalCacheDir }()
+
+	repo := filepath.Join(t.TempDir(), "blog")
+	run := func(args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=Test", "GIT_AUTHOR_EMAIL=test@example.com", "GIT_COMMITTER_NAME=Test",
+			"GIT_COMMITTER_EMAIL=test@example.com", "GIT_AUTHOR_DATE=2024-05-01T12:00:00+02:00", "GIT_COMMITTER_DATE=2024-05-01T12:00:00+02:00")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v: %s", args, err, out)
+		}
+	}
+	commit := func(file string, message string) {
+		path := filepath.Join(repo, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(message), 0o600); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		run("add", ".")
+		run("commit", "-q", "-m", message)
+	}
+	if err := os.MkdirAll(repo, 0o755); err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	run("init", "-q", "-b", "main")
+	commit("README.md", "Initial commit")
+	commit("content/posts/hello.md", "Add hello post")
+	commit("CHANGELOG.md", "Update changelog for release")
+	run("tag", "-a", "v1.0.0", "-m", "First release")
+
+	tests := []struct {
+		name          string
+		options       map[string]string
+		expectedTitle []string
+	}{
+		{
+			name:          "All commits",
+			expectedTitle: []string{"Update changelog for release", "Add hello post", "Initial commit"},
+		},
+		{
+			name:          "Path filter",
+			options:       map[string]string{"paths": "content/posts|drafts"},
+			expectedTitle: []string{"Add hello post"},
+		},
+		{
+			name:          "Message filter",
+			options:       map[string]string{"message": "changelog|release"},
+			expectedTitle: []string{"Update changelog for release"},
+		},
+		{
+			name:          "Tags",
+			options:       map[string]string{"tags": "true", "web_url": "https://example.com/owner/blog/"},
+			expectedTitle: []string{"v1.0.0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed, err := fetchGit(Config{URL: repo, Options: tt.options})
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			var titles []string
+			for _, item := range feed.Channel.Items {
+				titles = append(titles, item.Title)
+			}
+			if strings.Join(titles, ",") != strings.Join(tt.expectedTitle, ",") {
+				t.Errorf("Expected %v, got %v", tt.expectedTitle, titles)
+			}
+		})
+	}
+
+	// new commits are picked up by updating the mirror
+	commit("content/posts/second.md", "Add second post")
+	feed, err := fetchGit(Config{URL: repo, Options: map[string]string{"paths": "content/posts", "web_url": "https://example.com/owner/blog"}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(feed.Channel.Items) != 2 {
+		t.Fatalf("Expected the new commit to be fetched, got %+v", feed.Channel.Items)
+	}
+	item := feed.Channel.Items[0]
+	if !strings.HasPrefix(item.Link, "https://example.com/owner/blog/commit/") || item.PubDate != "2024-05-01T10:00:00Z" {
+		t.Errorf("Unexpected link %q or date %q", item.Link, item.PubDate)
+	}
+	if item.Announcement != "New commit to "+repositoryName(repo)+": Add second post\n\n"+item.Link {
+		t.Errorf("Unexpected announcement %q", item.Announcement)
+	}
+
+	tags, _ := fetchGit(Config{URL: repo, Options: map[string]string{"tags": "true", "web_url": "https://example.com/owner/blog"}})
+	if tags.Channel.Items[0].Announcement != repositoryName(repo)+" v1.0.0 tagged: First release\n\nhttps://example.com/owner/blog/tree/v1.0.0" {
+		t.Errorf("Unexpected tag announcement %q", tags.Channel.Items[0].Announcement)
+	}
+}
+
+// Test deriving repository names from clone URLs
+func TestRepositoryName(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{url: "https://github.com/owner/repo.git", expected: "owner/repo"},
+		{url: "git@github.com:owner/repo.git", expected: "owner/repo"},
+		{url: "https://gitlab.example.com/group/sub/project/", expected: "sub/project"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			if got := repositoryName(tt.url); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,324 @@
+package source
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// htmlNode is an element or text node of a parsed HTML page
+type htmlNode struct {
+	// Tag is the lowercase element name, empty for text nodes
+	Tag      string
+	Attrs    map[string]string
+	Text     string
+	Parent   *htmlNode
+	Children []*htmlNode
+}
+
+var (
+	htmlTokenRegex = regexp.MustCompile(`(?s)<!--.*?-->|<![^>]*>|<\?[^>]*>|</?([a-zA-Z][a-zA-Z0-9-]*)((?:[^>"']|"[^"]*"|'[^']*')*)>`)
+	htmlAttrRegex  = regexp.MustCompile(`(?s)([^\s"'=/>]+)(?:\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+)))?`)
+)
+
+// voidElements never have children or closing tags
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "source": true, "track": true, "wbr": true,
+}
+
+// rawTextElements contain text which isn't parsed as HTML
+var rawTextElements = map[string]bool{"script": true, "style": true, "textarea": true, "title": true}
+
+// impliedEnd is an element a start tag ends when it's still open, like an unclosed <li>
+// ended by the next <li>. The search for it stops at the scope elements, so a nested list
+// doesn't end the item it's in.
+type impliedEnd struct {
+	closes map[string]bool
+	scope  map[string]bool
+}
+
+// tagSet builds a set of element names, always including the elements that contain
+// their own flow of content and so scope every implied end tag
+func tagSet(tags ...string) map[string]bool {
+	set := map[string]bool{"caption": true, "html": true, "object": true, "table": true, "td": true, "template": true, "th": true}
+	for _, tag := range tags {
+		set[tag] = true
+	}
+	return set
+}
+
+// impliedEnds maps start tags to the open elements they end, following the HTML
+// specification's optional end tags
+var impliedEnds = func() map[string][]impliedEnd {
+	ends := map[string][]impliedEnd{
+		"li":       {{closes: map[string]bool{"li": true}, scope: tagSet("ul", "ol", "menu")}},
+		"dt":       {{closes: map[string]bool{"dt": true, "dd": true}, scope: tagSet("dl")}},
+		"dd":       {{closes: map[string]bool{"dt": true, "dd": true}, scope: tagSet("dl")}},
+		"option":   {{closes: map[string]bool{"option": true}, scope: tagSet("select", "datalist", "optgroup")}},
+		"optgroup": {{closes: map[string]bool{"optgroup": true}, scope: tagSet("select")}, {closes: map[string]bool{"option": true}, scope: tagSet("select")}},
+		"td":       {{closes: map[string]bool{"td": true, "th": true}, scope: map[string]bool{"tr": true, "table": true}}},
+		"th":       {{closes: map[string]bool{"td": true, "th": true}, scope: map[string]bool{"tr": true, "table": true}}},
+		"tr":       {{closes: map[string]bool{"tr": true}, scope: map[string]bool{"tbody": true, "thead": true, "tfoot": true, "table": true}}},
+		"tbody":    {{closes: map[string]bool{"tbody": true, "thead": true, "tfoot": true}, scope: map[string]bool{"table": true}}},
+		"thead":    {{closes: map[string]bool{"tbody": true, "thead": true, "tfoot": true}, scope: map[string]bool{"table": true}}},
+		"tfoot":    {{closes: map[string]bool{"tbody": true, "thead": true, "tfoot": true}, scope: map[string]bool{"table": true}}},
+	}
+	// block elements end an open paragraph
+	endParagraph := impliedEnd{closes: map[string]bool{"p": true}, scope: tagSet("button")}
+	for _, tag := range []string{
+		"address", "article", "aside", "blockquote", "details", "dialog", "dd", "div", "dl", "dt",
+		"fieldset", "figcaption", "figure", "footer", "form", "h1", "h2", "h3", "h4", "h5", "h6",
+		"header", "hgroup", "hr", "li", "main", "menu", "nav", "ol", "p", "pre", "section", "table", "ul",
+	} {
+		ends[tag] = append([]impliedEnd{endParagraph}, ends[tag]...)
+	}
+	return ends
+}()
+
+// parseHTML builds a tree from an HTML page. It is lenient rather than spec compliant:
+// start tags end the elements whose end tag HTML lets pages leave out, like <li> and
+// <p>, closing tags close the nearest open element of the same name and stray ones are
+// ignored, which is good enough to select elements of real-world pages.
+func parseHTML(body string) *htmlNode {
+	root := &htmlNode{Tag: "#document"}
+	current := root
+	addText := func(text string) {
+		if text != "" {
+			current.Children = append(current.Children, &htmlNode{Text: html.UnescapeString(text), Parent: current})
+		}
+	}
+
+	pos := 0
+	for pos < len(body) {
+		loc := htmlTokenRegex.FindStringSubmatchIndex(body[pos:])
+		if loc == nil {
+			addText(body[pos:])
+			break
+		}
+		addText(body[pos : pos+loc[0]])
+		token := body[pos+loc[0] : pos+loc[1]]
+		pos += loc[1]
+		if loc[2] < 0 {
+			// comment, doctype or processing instruction
+			continue
+		}
+
+		tag := strings.ToLower(token[loc[2]-loc[0] : loc[3]-loc[0]])
+		if strings.HasPrefix(token, "</") {
+			for n := current; n != root; n = n.Parent {
+				if n.Tag == tag {
+					current = n.Parent
+					break
+				}
+			}
+			continue
+		}
+
+		for _, end := range impliedEnds[tag] {
+			for n := current; n != root; n = n.Parent {
+				if end.closes[n.Tag] {
+					current = n.Parent
+					break
+				}
+				if end.scope[n.Tag] {
+					break
+				}
+			}
+		}
+
+		node := &htmlNode{Tag: tag, Attrs: parseHTMLAttrs(token[loc[4]-loc[0] : loc[5]-loc[0]]), Parent: current}
+		current.Children = append(current.Children, node)
+		if voidElements[tag] || strings.HasSuffix(token, "/>") {
+			continue
+		}
+		if rawTextElements[tag] {
+			end := strings.Index(strings.ToLower(body[pos:]), "</"+tag)
+			if end < 0 {
+				end = len(body) - pos
+			}
+			current = node
+			addText(body[pos : pos+end])
+			current = node.Parent
+			pos += end
+			if closing := strings.IndexByte(body[pos:], '>'); closing >= 0 {
+				pos += closing + 1
+			}
+			continue
+		}
+		current = node
+	}
+	return root
+}
+
+// parseHTMLAttrs parses the attributes of a start tag, lowercasing their names
+func parseHTMLAttrs(text string) map[string]string {
+	attrs := make(map[string]string)
+	for _, match := range htmlAttrRegex.FindAllStringSubmatch(text, -1) {
+		name := strings.ToLower(match[1])
+		if _, ok := attrs[name]; !ok {
+			attrs[name] = html.UnescapeString(match[2] + match[3] + match[4])
+		}
+	}
+	return attrs
+}
+
+// text returns the node's text content with whitespace collapsed
+func (n *htmlNode) text() string {
+	var b strings.Builder
+	var walk func(*htmlNode)
+	walk = func(n *htmlNode) {
+		if n.Tag == "" {
+			b.WriteString(n.Text)
+			b.WriteString(" ")
+		}
+		if n.Tag == "script" || n.Tag == "style" {
+			return
+		}
+		for _, child := range n.Children {
+			walk(child)
+		}
+	}
+	walk(n)
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// cssCompound is a compound CSS selector like a.title[href], which matches a single element
+type cssCompound struct {
+	tag     string
+	id      string
+	classes []string
+	// attrs maps attribute names to their required value, or nil for [attr] selectors
+	attrs map[string]*string
+	// child means the compound must match the parent of the following compound rather
+	// than any ancestor
+	child bool
+}
+
+// cssSelector is a subset of CSS selectors: tag names, #id, .class, [attr] and [attr=value],
+// combined with descendant and child (>) combinators
+type cssSelector []cssCompound
+
+var cssCompoundRegex = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9-]*|\*)?((?:#[\w-]+|\.[\w-]+|\[[\w-]+(?:=(?:"[^"]*"|'[^']*'|[^\]]*))?\])*)$`)
+var cssPartRegex = regexp.MustCompile(`#[\w-]+|\.[\w-]+|\[([\w-]+)(?:=("[^"]*"|'[^']*'|[^\]]*))?\]`)
+
+// parseSelector parses a CSS selector in the subset supported by cssSelector
+func parseSelector(selector string) (cssSelector, error) {
+	var parsed cssSelector
+	child := false
+	for _, part := range strings.Fields(strings.ReplaceAll(selector, ">", " > ")) {
+		if part == ">" {
+			if len(parsed) == 0 || child {
+				return nil, fmt.Errorf("invalid selector %q", selector)
+			}
+			child = true
+			continue
+		}
+		match := cssCompoundRegex.FindStringSubmatch(part)
+		if match == nil || part == "" {
+			return nil, fmt.Errorf("invalid or unsupported selector %q", selector)
+		}
+		compound := cssCompound{tag: strings.ToLower(strings.TrimPrefix(match[1], "*"))}
+		for _, m := range cssPartRegex.FindAllStringSubmatch(match[2], -1) {
+			switch m[0][0] {
+			case '#':
+				compound.id = m[0][1:]
+			case '.':
+				compound.classes = append(compound.classes, m[0][1:])
+			default:
+				if compound.attrs == nil {
+					compound.attrs = map[string]*string{}
+				}
+				var value *string
+				if strings.Contains(m[0], "=") {
+					v := strings.Trim(m[2], `"'`)
+					value = &v
+				}
+				compound.attrs[strings.ToLower(m[1])] = value
+			}
+		}
+		if child {
+			parsed[len(parsed)-1].child = true
+			child = false
+		}
+		parsed = append(parsed, compound)
+	}
+	if len(parsed) == 0 || child {
+		return nil, fmt.Errorf("invalid selector %q", selector)
+	}
+	return parsed, nil
+}
+
+// matches reports whether an element matches the compound selector
+func (c cssCompound) matches(n *htmlNode) bool {
+	if n.Tag == "" || n.Tag == "#document" || (c.tag != "" && n.Tag != c.tag) {
+		return false
+	}
+	if c.id != "" && n.Attrs["id"] != c.id {
+		return false
+	}
+	classes := strings.Fields(n.Attrs["class"])
+	for _, class := range c.classes {
+		found := false
+		for _, have := range classes {
+			found = found || have == class
+		}
+		if !found {
+			return false
+		}
+	}
+	for name, value := range c.attrs {
+		have, ok := n.Attrs[name]
+		if !ok || (value != nil && have != *value) {
+			return false
+		}
+	}
+	return true
+}
+
+// matches reports whether an element matches the selector, only considering ancestors
+// below scope (nil for the whole document)
+func (s cssSelector) matches(n *htmlNode, scope *htmlNode) bool {
+	last := len(s) - 1
+	if !s[last].matches(n) {
+		return false
+	}
+	if last == 0 {
+		return true
+	}
+	rest := s[:last]
+	for ancestor := n.Parent; ancestor != nil && ancestor != scope; ancestor = ancestor.Parent {
+		if rest.matches(ancestor, scope) {
+			return true
+		}
+		if rest[len(rest)-1].child {
+			break
+		}
+	}
+	return false
+}
+
+// selectAll returns the descendants of n matching the selector, in document order
+func (n *htmlNode) selectAll(s cssSelector) []*htmlNode {
+	var found []*htmlNode
+	var walk func(*htmlNode)
+	walk = func(node *htmlNode) {
+		for _, child := range node.Children {
+			if s.matches(child, n) {
+				found = append(found, child)
+			}
+			walk(child)
+		}
+	}
+	walk(n)
+	return found
+}
+
+// selectFirst returns the first descendant of n matching the selector, or nil
+func (n *htmlNode) selectFirst(s cssSelector) *htmlNode {
+	if found := n.selectAll(s); len(found) > 0 {
+		return found[0]
+	}
+	return nil
+}
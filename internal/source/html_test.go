@@ -0,0 +1,119 @@
+package source
+
+import (
+	"strings"
+	"testing"
+)
+
+// Test selecting elements of a parsed page with CSS selectors
+func TestSelectAll(t *testing.T) {
+	page := parseHTML(`<!DOCTYPE html>
+<html><head><title>News &amp; Updates</title><script>if (a < b) { document.write("<div class='post'>") }</script></head>
+<body>
+  <!-- <div class="post">commented out</div> -->
+  <ul id="posts">
+    <li class="post featured"><a href="/one" data-kind=news>First</a><br></li>
+    <li class="post"><span><a href='/two'>Second</a></span></li>
+  </ul>
+  <div class="sidebar"><li class="post"><a href="/three">Third</a></li></div>
+</body></html>`)
+
+	tests := []struct {
+		selector string
+		expected []string
+	}{
+		{selector: "title", expected: []string{"News & Updates"}},
+		{selector: "li.post a", expected: []string{"First", "Second", "Third"}},
+		{selector: "#posts > li > a", expected: []string{"First"}},
+		{selector: "ul li.post.featured", expected: []string{"First"}},
+		{selector: "a[data-kind=news]", expected: []string{"First"}},
+		{selector: `a[href="/two"]`, expected: []string{"Second"}},
+		{selector: ".sidebar [href]", expected: []string{"Third"}},
+		{selector: "div.post", expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			selector, err := parseSelector(tt.selector)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			var texts []string
+			for _, node := range page.selectAll(selector) {
+				texts = append(texts, node.text())
+			}
+			if strings.Join(texts, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("Expected %v, got %v", tt.expected, texts)
+			}
+		})
+	}
+
+	for _, invalid := range []string{"", "> a", "a >", "a:first-child", "a ~ b"} {
+		if _, err := parseSelector(invalid); err == nil {
+			t.Errorf("Expected an error for selector %q", invalid)
+		}
+	}
+}
+
+// Table-driven test of elements whose end tag the page leaves out
+func TestParseHTML_ImpliedEndTags(t *testing.T) {
+	tests := []struct {
+		name     string
+		page     string
+		selector string
+		expected []string
+	}{
+		{
+			name:     "unclosed list items",
+			page:     `<ul class=posts><li>A<li>B<li>C</ul>`,
+			selector: "ul.posts > li",
+			expected: []string{"A", "B", "C"},
+		},
+		{
+			name:     "nested list inside an unclosed item",
+			page:     `<ul id=outer><li>A<ul><li>A1<li>A2</ul><li>B</ul>`,
+			selector: "#outer > li",
+			expected: []string{"A A1 A2", "B"},
+		},
+		{
+			name:     "unclosed paragraphs",
+			page:     `<div class=entry><p>One<p>Two <b>bold</b><div>Three</div></div>`,
+			selector: ".entry > p",
+			expected: []string{"One", "Two bold"},
+		},
+		{
+			name:     "block element ends a paragraph",
+			page:     `<p>Intro<ul><li>Item</ul>`,
+			selector: "p li",
+			expected: nil,
+		},
+		{
+			name:     "unclosed table cells and rows",
+			page:     `<table><tr><td>1<td>2<tr><td>3</table>`,
+			selector: "tr > td",
+			expected: []string{"1", "2", "3"},
+		},
+		{
+			name:     "unclosed definition terms",
+			page:     `<dl><dt>Term<dd>Definition<dt>Other</dl>`,
+			selector: "dl > dt",
+			expected: []string{"Term", "Other"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selector, err := parseSelector(tt.selector)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			var texts []string
+			for _, node := range parseHTML(tt.page).selectAll(selector) {
+				texts = append(texts, node.text())
+			}
+			if strings.Join(texts, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("Expected %v, got %v", tt.expected, texts)
+			}
+		})
+	}
+}
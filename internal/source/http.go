@@ -0,0 +1,76 @@
+package source
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/httpclient"
+)
+
+// cachedResponse is an API response kept to answer conditional requests
+type cachedResponse struct {
+	etag string
+	body []byte
+}
+
+// responseCache holds the last response of every API URL which returned an ETag
+var (
+	responseCacheMu sync.Mutex
+	responseCache   = map[string]cachedResponse{}
+)
+
+// getJSON fetches and decodes an API response. Responses with an ETag are cached and
+// revalidated, so unchanged responses don't count against rate limits.
+func getJSON(url string, headers map[string]string, out any) error {
+	client := httpclient.New("source", 10*time.Second)
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	responseCacheMu.Lock()
+	cached, ok := responseCache[url]
+	responseCacheMu.Unlock()
+	if ok {
+		req.Header.Set("If-None-Match", cached.etag)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body []byte
+	switch {
+	case resp.StatusCode == http.StatusNotModified && ok:
+		body = cached.body
+	case resp.StatusCode == http.StatusOK:
+		if body, err = io.ReadAll(resp.Body); err != nil {
+			return fmt.Errorf("failed to read response: %w", err)
+		}
+		// in low-memory mode responses aren't kept, trading rate limit budget for memory
+		if etag := resp.Header.Get("ETag"); etag != "" && !viper.GetBool("low_memory") {
+			responseCacheMu.Lock()
+			responseCache[url] = cachedResponse{etag: etag, body: body}
+			responseCacheMu.Unlock()
+		}
+	default:
+		return fmt.Errorf("unexpected HTTP status: %d", resp.StatusCode)
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package source
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// Test API responses are revalidated with their ETag
+func TestGetJSON_ETag(t *testing.T) {
+	requests := 0
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("X-Test") != "yes" {
+			t.Errorf("Expected custom header to be sent")
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte(`{"name":"cached"}`))
+	}))
+	defer mockServer.Close()
+
+	for i := 0; i < 2; i++ {
+		var out struct {
+			Name string `json:"name"`
+		}
+		if err := getJSON(mockServer.URL, map[string]string{"X-Test": "yes"}, &out); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if out.Name != "cached" {
+			t.Errorf("Expected the cached response on request %d, got %+v", i+1, out)
+		}
+	}
+	if requests != 2 {
+		t.Errorf("Expected 2 requests, got %d", requests)
+	}
+}
+
+// Test API responses aren't kept in low-memory mode
+func TestGetJSON_LowMemory(t *testing.T) {
+	viper.Set("low_memory", true)
+	defer viper.Set("low_memory", false)
+
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") != "" {
+			t.Errorf("Expected no conditional request without a cached response")
+		}
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte(`{"name":"uncached"}`))
+	}))
+	defer mockServer.Close()
+
+	for i := 0; i < 2; i++ {
+		var out struct {
+			Name string `json:"name"`
+		}
+		if err := getJSON(mockServer.URL, nil, &out); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	if _, ok := responseCache[mockServer.URL]; ok {
+		t.Errorf("Expected the response not to be cached")
+	}
+}
@@ -0,0 +1,242 @@
+package source
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/toozej/rss2mastodon/internal/httpclient"
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// defaultEventDays is how many days in advance events are announced by default
+const defaultEventDays = 7
+
+// maxCalendarBytes caps how much of a calendar is read
+const maxCalendarBytes = 5 * 1024 * 1024
+
+// event is a VEVENT of an iCalendar file
+type event struct {
+	UID         string
+	Start       time.Time
+	AllDay      bool
+	Summary     string
+	Location    string
+	URL         string
+	Description string
+}
+
+func init() {
+	Register(Source{Name: "ics", Description: "Upcoming events of an iCalendar file", Options: []string{"days"}, Fetch: fetchCalendar})
+}
+
+// fetchCalendar fetches an iCalendar file and returns the events starting within the next
+// days (default 7) as items, each keyed on its UID and start so that moved events and
+// occurrences of the same event are announced separately
+func fetchCalendar(cfg Config) (*rss.RSSFeed, error) {
+	days := defaultEventDays
+	if value := cfg.Options["days"]; value != "" {
+		var err error
+		if days, err = strconv.Atoi(value); err != nil || days < 0 {
+			return nil, fmt.Errorf("invalid days %q, expected a number of days", value)
+		}
+	}
+
+	client := httpclient.New("source", 10*time.Second)
+	resp, err := client.Get(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected HTTP status: %d", resp.StatusCode)
+	}
+
+	name, events, err := parseCalendar(io.LimitReader(resp.Body, maxCalendarBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse calendar: %w", err)
+	}
+
+	current := now()
+	until := current.AddDate(0, 0, days)
+	feed := &rss.RSSFeed{}
+	feed.Channel.Title = name
+	for _, e := range events {
+		// all-day events are announced until the end of their day
+		end := e.Start
+		if e.AllDay {
+			end = end.AddDate(0, 0, 1)
+		}
+		if e.UID == "" || !end.After(current) || e.Start.After(until) {
+			continue
+		}
+
+		link := e.URL
+		if link == "" {
+			link = cfg.URL
+		}
+		key := e.UID + "@" + e.Start.UTC().Format("20060102T150405Z")
+		unique := strings.SplitN(link, "#", 2)[0] + "#" + url.QueryEscape(key)
+		feed.Channel.Items = append(feed.Channel.Items, rss.RSSItem{
+			Title:        e.Summary,
+			Link:         unique,
+			GUID:         key,
+			Content:      e.Description,
+			Announcement: eventAnnouncement(e, current),
+		})
+	}
+	return feed, nil
+}
+
+// eventAnnouncement announces an event relative to the current time, e.g.
+// "Happening Saturday at 19:00: Summary"
+func eventAnnouncement(e event, current time.Time) string {
+	current = current.In(e.Start.Location())
+	today := time.Date(current.Year(), current.Month(), current.Day(), 0, 0, 0, 0, current.Location())
+	day := time.Date(e.Start.Year(), e.Start.Month(), e.Start.Day(), 0, 0, 0, 0, current.Location())
+
+	var when string
+	switch daysAhead := int(day.Sub(today).Hours()+12) / 24; {
+	case daysAhead <= 0:
+		when = "today"
+	case daysAhead == 1:
+		when = "tomorrow"
+	case daysAhead < 7:
+		when = e.Start.Format("Monday")
+	default:
+		when = e.Start.Format("Monday, 2 January")
+	}
+	if !e.AllDay {
+		when += e.Start.Format(" at 15:04")
+	}
+
+	announcement := fmt.Sprintf("Happening %s: %s", when, e.Summary)
+	if e.Location != "" {
+		announcement += "\n\n" + e.Location
+	}
+	if e.URL != "" {
+		announcement += "\n\n" + e.URL
+	}
+	return announcement
+}
+
+// parseCalendar parses the name and events of an iCalendar (RFC 5545) file. Recurrence rules
+// aren't expanded, only each event's first occurrence (or occurrences listed as separate
+// events with a RECURRENCE-ID) is returned.
+func parseCalendar(r io.Reader) (string, []event, error) {
+	lines, err := unfoldLines(r)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var name string
+	var events []event
+	var current *event
+	for _, line := range lines {
+		property, params, value := parseContentLine(line)
+		switch {
+		case property == "BEGIN" && value == "VEVENT":
+			current = &event{}
+		case property == "END" && value == "VEVENT" && current != nil:
+			if !current.Start.IsZero() {
+				events = append(events, *current)
+			}
+			current = nil
+		case property == "X-WR-CALNAME" && current == nil:
+			name = unescapeText(value)
+		case current == nil:
+		case property == "UID":
+			current.UID = value
+		case property == "DTSTART":
+			current.Start, current.AllDay = parseCalendarTime(value, params)
+		case property == "SUMMARY":
+			current.Summary = unescapeText(value)
+		case property == "LOCATION":
+			current.Location = unescapeText(value)
+		case property == "URL":
+			current.URL = value
+		case property == "DESCRIPTION":
+			current.Description = unescapeText(value)
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Start.Before(events[j].Start) })
+	return name, events, nil
+}
+
+// unfoldLines reads the lines of an iCalendar file, joining folded lines which continue
+// on the next line starting with a space or tab
+func unfoldLines(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxCalendarBytes)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// parseContentLine splits a content line like DTSTART;TZID=Europe/Berlin:20240501T190000 into
+// its uppercase property name, parameters and value
+func parseContentLine(line string) (string, map[string]string, string) {
+	// parameter values may be quoted and contain colons
+	split := -1
+	quoted := false
+	for i, r := range line {
+		if r == '"' {
+			quoted = !quoted
+		} else if r == ':' && !quoted {
+			split = i
+			break
+		}
+	}
+	if split < 0 {
+		return "", nil, ""
+	}
+
+	parts := strings.Split(line[:split], ";")
+	params := make(map[string]string)
+	for _, param := range parts[1:] {
+		if key, value, ok := strings.Cut(param, "="); ok {
+			params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+		}
+	}
+	return strings.ToUpper(parts[0]), params, line[split+1:]
+}
+
+// parseCalendarTime parses a DATE or DATE-TIME value, in UTC (Z suffix), the TZID parameter's
+// time zone or local time, reporting whether it is a date of an all-day event
+func parseCalendarTime(value string, params map[string]string) (time.Time, bool) {
+	loc := time.Local
+	if tzid := params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+
+	if params["VALUE"] == "DATE" || len(value) == len("20060102") {
+		t, err := time.ParseInLocation("20060102", value, loc)
+		return t, err == nil
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, _ := time.Parse("20060102T150405Z", value)
+		return t.In(time.Local), false
+	}
+	t, _ := time.ParseInLocation("20060102T150405", value, loc)
+	return t, false
+}
+
+// unescapeText unescapes an iCalendar TEXT value
+func unescapeText(value string) string {
+	return strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(value)
+}
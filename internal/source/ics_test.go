@@ -0,0 +1,160 @@
+package source
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// calendar is an iCalendar file with a timed, an all-day, a past, a far future and a moved event
+const calendar = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"X-WR-CALNAME:Meetups\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:meetup-1\r\n" +
+	"DTSTART;TZID=Europe/Berlin:20240504T190000\r\n" +
+	"SUMMARY:Go meetup\\, with pizza\r\n" +
+	"LOCATION:Community hall\r\n" +
+	"URL:https://example.com/events/go\r\n" +
+	"DESCRIPTION:A long descrip\r\n" +
+	" tion\\nover two lines\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:festival\r\n" +
+	"DTSTART;VALUE=DATE:20240501\r\n" +
+	"SUMMARY:Festival\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:past\r\n" +
+	"DTSTART:20240430T100000Z\r\n" +
+	"SUMMARY:Over\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:later\r\n" +
+	"DTSTART;TZID=Europe/Berlin:20240520T190000\r\n" +
+	"SUMMARY:Far away\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:meetup-1\r\n" +
+	"RECURRENCE-ID;TZID=Europe/Berlin:20240511T190000\r\n" +
+	"DTSTART;TZID=Europe/Berlin:20240510T190000\r\n" +
+	"SUMMARY:Go meetup\\, with pizza\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+// Test announcing the upcoming events of a calendar
+func TestFetchCalendar(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("Time zone data not available: ", err)
+	}
+	// Wednesday
+	current := time.Date(2024, 5, 1, 10, 0, 0, 0, berlin)
+	now = func() time.Time { return current }
+	defer func() { now = time.Now }()
+
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(calendar))
+	}))
+	defer mockServer.Close()
+
+	tests := []struct {
+		name          string
+		options       map[string]string
+		expectedTitle []string
+		expectError   bool
+	}{
+		{
+			name:          "Default of a week",
+			expectedTitle: []string{"Festival", "Go meetup, with pizza"},
+		},
+		{
+			name:          "Two weeks",
+			options:       map[string]string{"days": "14"},
+			expectedTitle: []string{"Festival", "Go meetup, with pizza", "Go meetup, with pizza"},
+		},
+		{
+			name:        "Invalid days",
+			options:     map[string]string{"days": "soon"},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed, err := fetchCalendar(Config{URL: mockServer.URL + "/meetups.ics", Options: tt.options})
+			if (err != nil) != tt.expectError {
+				t.Fatalf("Expected error %v, got %v", tt.expectError, err)
+			}
+			if tt.expectError {
+				return
+			}
+			var titles []string
+			links := map[string]bool{}
+			for _, item := range feed.Channel.Items {
+				titles = append(titles, item.Title)
+				links[item.Link] = true
+			}
+			if strings.Join(titles, ",") != strings.Join(tt.expectedTitle, ",") {
+				t.Errorf("Expected events %v, got %v", tt.expectedTitle, titles)
+			}
+			if len(links) != len(titles) {
+				t.Errorf("Expected every occurrence to have its own link, got %v", links)
+			}
+		})
+	}
+
+	feed, _ := fetchCalendar(Config{URL: mockServer.URL + "/meetups.ics"})
+	if feed.Channel.Title != "Meetups" {
+		t.Errorf("Expected the calendar name, got %q", feed.Channel.Title)
+	}
+	meetup := feed.Channel.Items[1]
+	if meetup.Link != "https://example.com/events/go#meetup-1%4020240504T170000Z" {
+		t.Errorf("Expected a link keyed on UID and start, got %q", meetup.Link)
+	}
+	if meetup.Content != "A long description\nover two lines" {
+		t.Errorf("Expected the unfolded description, got %q", meetup.Content)
+	}
+}
+
+// Test announcing events relative to the current day
+func TestEventAnnouncement(t *testing.T) {
+	current := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		event    event
+		expected string
+	}{
+		{
+			name:     "Today",
+			event:    event{Summary: "Festival", Start: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), AllDay: true},
+			expected: "Happening today: Festival",
+		},
+		{
+			name:     "Tomorrow",
+			event:    event{Summary: "Talk", Start: time.Date(2024, 5, 2, 18, 30, 0, 0, time.UTC), Location: "Room 1"},
+			expected: "Happening tomorrow at 18:30: Talk\n\nRoom 1",
+		},
+		{
+			name:     "This week",
+			event:    event{Summary: "Meetup", Start: time.Date(2024, 5, 4, 19, 0, 0, 0, time.UTC), URL: "https://example.com/meetup"},
+			expected: "Happening Saturday at 19:00: Meetup\n\nhttps://example.com/meetup",
+		},
+		{
+			name:     "Later",
+			event:    event{Summary: "Conference", Start: time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC), AllDay: true},
+			expected: "Happening Friday, 10 May: Conference",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := eventAnnouncement(tt.event, current); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
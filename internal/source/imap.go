@@ -0,0 +1,392 @@
+//go:build !no_imap
+
+package source
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// defaultMailDays is how many days back messages are considered by default
+const defaultMailDays = 7
+
+// maxMessages is how many of the most recent matching messages are fetched
+const maxMessages = 50
+
+// maxSummaryRunes is how much of a message's text is quoted in its announcement
+const maxSummaryRunes = 400
+
+// maxMessageSize limits the size of the literals read from the server, e.g. fetched messages
+const maxMessageSize = 25 << 20
+
+// imapLiteralRegex matches the announcement of a literal at the end of an IMAP response line
+var imapLiteralRegex = regexp.MustCompile(`\{(\d+)\}\r\n$`)
+
+// imapResponse is a response line of an IMAP server along with its literals
+type imapResponse struct {
+	Text     string
+	Literals [][]byte
+}
+
+// imapClient is a minimal IMAP4rev1 client, supporting just what's needed to read a folder
+type imapClient struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	tag    int
+}
+
+func init() {
+	Register(Source{Name: "imap", Description: "Messages of a mail folder", Options: []string{"from", "subject", "days"},
+		ConfigKeys: []string{"imap_username", "imap_password"}, Fetch: fetchMail})
+}
+
+// fetchMail fetches the recent messages of an IMAP folder matching the from and subject
+// options, e.g. imaps://imap.example.com/Newsletters. Credentials are taken from the URL or
+// imap_username and imap_password. The folder is opened read-only, so messages stay unread.
+func fetchMail(cfg Config) (*rss.RSSFeed, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+	days := defaultMailDays
+	if value := cfg.Options["days"]; value != "" {
+		if days, err = strconv.Atoi(value); err != nil || days <= 0 {
+			return nil, fmt.Errorf("invalid days %q, expected a number of days", value)
+		}
+	}
+
+	username, password := viper.GetString("imap_username"), viper.GetString("imap_password")
+	if u.User != nil {
+		username = u.User.Username()
+		if p, ok := u.User.Password(); ok {
+			password = p
+		}
+	}
+	folder := strings.Trim(u.Path, "/")
+	if folder == "" {
+		folder = "INBOX"
+	}
+
+	quotedUsername, err := imapQuote(username)
+	if err != nil {
+		return nil, fmt.Errorf("invalid IMAP username: %w", err)
+	}
+	quotedPassword, err := imapQuote(password)
+	if err != nil {
+		return nil, fmt.Errorf("invalid IMAP password: %w", err)
+	}
+	quotedFolder, err := imapQuote(folder)
+	if err != nil {
+		return nil, fmt.Errorf("invalid folder %q: %w", folder, err)
+	}
+
+	client, err := dialIMAP(u)
+	if err != nil {
+		return nil, err
+	}
+	defer client.close()
+
+	if _, err := client.command("LOGIN %s %s", quotedUsername, quotedPassword); err != nil {
+		return nil, fmt.Errorf("IMAP login failed: %w", err)
+	}
+	if _, err := client.command("EXAMINE %s", quotedFolder); err != nil {
+		return nil, fmt.Errorf("failed to open folder %q: %w", folder, err)
+	}
+
+	since := now().AddDate(0, 0, -days).Format("2-Jan-2006")
+	responses, err := client.command("UID SEARCH SINCE %s", since)
+	if err != nil {
+		return nil, fmt.Errorf("IMAP search failed: %w", err)
+	}
+	var uids []string
+	for _, response := range responses {
+		if fields := strings.Fields(response.Text); len(fields) > 2 && strings.EqualFold(fields[1], "SEARCH") {
+			uids = append(uids, fields[2:]...)
+		}
+	}
+	if len(uids) > maxMessages {
+		uids = uids[len(uids)-maxMessages:]
+	}
+
+	feed := &rss.RSSFeed{}
+	feed.Channel.Title = folder
+	if len(uids) == 0 {
+		return feed, nil
+	}
+	// fetch the messages one at a time, so at most one of them, up to maxMessageSize, is held
+	// in memory rather than a whole batch of them
+	for _, uid := range uids {
+		responses, err = client.command("UID FETCH %s (BODY.PEEK[])", uid)
+		if err != nil {
+			return nil, fmt.Errorf("IMAP fetch failed: %w", err)
+		}
+		for _, response := range responses {
+			if len(response.Literals) == 0 {
+				continue
+			}
+			item, sender, err := mailItem(response.Literals[0])
+			if err != nil || !matchesMailFilters(item, sender, cfg.Options) {
+				continue
+			}
+			feed.Channel.Items = append(feed.Channel.Items, item)
+		}
+	}
+	return feed, nil
+}
+
+// dialIMAP connects to the IMAP server of an imaps:// URL, or an imap:// URL without
+// encryption, e.g. for a local mail bridge
+func dialIMAP(u *url.URL) (*imapClient, error) {
+	host := u.Host
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	var conn net.Conn
+	var err error
+	switch u.Scheme {
+	case "imaps":
+		if u.Port() == "" {
+			host += ":993"
+		}
+		conn, err = tls.DialWithDialer(dialer, "tcp", host, &tls.Config{ServerName: u.Hostname()})
+	case "imap":
+		if u.Port() == "" {
+			host += ":143"
+		}
+		conn, err = dialer.Dial("tcp", host)
+	default:
+		return nil, fmt.Errorf("invalid IMAP URL %q, expected e.g. imaps://imap.example.com/INBOX", u.Redacted())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to IMAP server: %w", err)
+	}
+	_ = conn.SetDeadline(time.Now().Add(time.Minute))
+
+	client := &imapClient{conn: conn, reader: bufio.NewReader(conn)}
+	greeting, err := client.readResponse()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if !strings.HasPrefix(greeting.Text, "* OK") && !strings.HasPrefix(greeting.Text, "* PREAUTH") {
+		conn.Close()
+		return nil, fmt.Errorf("unexpected IMAP greeting: %s", strings.TrimSpace(greeting.Text))
+	}
+	return client, nil
+}
+
+// command sends a command and returns its untagged responses, or an error if it didn't succeed
+func (c *imapClient) command(format string, args ...any) ([]imapResponse, error) {
+	c.tag++
+	tag := fmt.Sprintf("a%d", c.tag)
+	if _, err := fmt.Fprintf(c.conn, "%s %s\r\n", tag, fmt.Sprintf(format, args...)); err != nil {
+		return nil, err
+	}
+
+	var responses []imapResponse
+	for {
+		response, err := c.readResponse()
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(response.Text, tag+" ") {
+			responses = append(responses, response)
+			continue
+		}
+		status := strings.TrimSpace(strings.TrimPrefix(response.Text, tag+" "))
+		if !strings.HasPrefix(strings.ToUpper(status), "OK") {
+			return nil, fmt.Errorf("%s", status)
+		}
+		return responses, nil
+	}
+}
+
+// readResponse reads a response line, including any literals it contains
+func (c *imapClient) readResponse() (imapResponse, error) {
+	var response imapResponse
+	for {
+		line, err := c.reader.ReadString('\n')
+		if err != nil {
+			return response, fmt.Errorf("failed to read IMAP response: %w", err)
+		}
+		response.Text += line
+		match := imapLiteralRegex.FindStringSubmatch(line)
+		if match == nil {
+			return response, nil
+		}
+		size, err := strconv.Atoi(match[1])
+		if err != nil || size > maxMessageSize {
+			return response, fmt.Errorf("IMAP literal of %s bytes is larger than %d bytes", match[1], maxMessageSize)
+		}
+		literal := make([]byte, size)
+		if _, err := io.ReadFull(c.reader, literal); err != nil {
+			return response, fmt.Errorf("failed to read IMAP literal: %w", err)
+		}
+		response.Literals = append(response.Literals, literal)
+	}
+}
+
+// close logs out and closes the connection
+func (c *imapClient) close() {
+	_, _ = c.command("LOGOUT")
+	c.conn.Close()
+}
+
+// imapQuote quotes a string argument of an IMAP command. Quoted strings can't contain line
+// breaks, which would end the command and start another one.
+func imapQuote(value string) (string, error) {
+	if strings.ContainsAny(value, "\r\n\x00") {
+		return "", fmt.Errorf("line breaks and NUL characters are not allowed")
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`, nil
+}
+
+// mailItem turns a message into an item announcing its subject and a summary of its text,
+// also returning its sender. Messages have no URL of their own, so they are linked by their
+// Message-ID (RFC 2392).
+func mailItem(raw []byte) (rss.RSSItem, string, error) {
+	msg, err := mail.ReadMessage(strings.NewReader(string(raw)))
+	if err != nil {
+		return rss.RSSItem{}, "", err
+	}
+	messageID := strings.Trim(msg.Header.Get("Message-Id"), "<> ")
+	if messageID == "" {
+		return rss.RSSItem{}, "", fmt.Errorf("message without Message-ID")
+	}
+
+	decoder := new(mime.WordDecoder)
+	subject, err := decoder.DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		subject = msg.Header.Get("Subject")
+	}
+	sender := msg.Header.Get("From")
+	if from, err := mail.ParseAddress(sender); err == nil {
+		sender = from.Address
+	}
+
+	text := messageText(msg.Header, msg.Body)
+	item := rss.RSSItem{
+		Title:        subject,
+		Link:         "mid:" + url.PathEscape(messageID),
+		GUID:         messageID,
+		Content:      text,
+		Announcement: subject,
+	}
+	if summary := excerpt(text, maxSummaryRunes); summary != "" {
+		item.Announcement += "\n\n" + summary
+	}
+	if date, err := msg.Header.Date(); err == nil {
+		item.PubDate = date.UTC().Format(time.RFC3339)
+	}
+	return item, sender, nil
+}
+
+// mimeHeader is the part of a message or MIME part header needed to decode its body
+type mimeHeader interface {
+	Get(key string) string
+}
+
+// messageText returns the text of a message or MIME part, preferring the HTML version of
+// multipart/alternative messages (converted to plain text) as newsletters' plain text
+// versions tend to be cluttered with URLs
+func messageText(header mimeHeader, body io.Reader) string {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType = "text/plain"
+	}
+	body = decodeTransferEncoding(header.Get("Content-Transfer-Encoding"), body)
+
+	switch {
+	case strings.HasPrefix(mediaType, "multipart/"):
+		var plain, html string
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextRawPart()
+			if err != nil {
+				break
+			}
+			partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+			if strings.HasPrefix(part.Header.Get("Content-Disposition"), "attachment") {
+				continue
+			}
+			text := messageText(part.Header, part)
+			switch {
+			case partType == "text/html" && html == "":
+				html = text
+			case plain == "":
+				plain = text
+			}
+		}
+		if html != "" {
+			return html
+		}
+		return plain
+	case mediaType == "text/html":
+		data, _ := io.ReadAll(body)
+		return parseHTML(string(data)).text()
+	case mediaType == "text/plain":
+		data, _ := io.ReadAll(body)
+		return strings.TrimSpace(string(data))
+	}
+	return ""
+}
+
+// decodeTransferEncoding decodes a quoted-printable or base64 encoded body
+func decodeTransferEncoding(encoding string, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, &newlineStripper{reader: body})
+	}
+	return body
+}
+
+// newlineStripper drops line breaks, which base64 encoded bodies are wrapped with
+type newlineStripper struct {
+	reader io.Reader
+}
+
+func (s *newlineStripper) Read(p []byte) (int, error) {
+	n, err := s.reader.Read(p)
+	kept := 0
+	for _, b := range p[:n] {
+		if b != '\r' && b != '\n' {
+			p[kept] = b
+			kept++
+		}
+	}
+	return kept, err
+}
+
+// matchesMailFilters reports whether a message's sender contains one of the |-separated from
+// addresses or domains and its subject one of the |-separated subject keywords, each filter
+// matching every message if not set
+func matchesMailFilters(item rss.RSSItem, sender string, options map[string]string) bool {
+	if senders := splitList(options["from"]); len(senders) > 0 {
+		matched := false
+		for _, from := range senders {
+			matched = matched || strings.Contains(strings.ToLower(sender), strings.ToLower(from))
+		}
+		if !matched {
+			return false
+		}
+	}
+	keywords := splitList(options["subject"])
+	return len(keywords) == 0 || matchesAnyWord(item.Title, keywords)
+}
@@ -0,0 +1,230 @@
+//go:build !no_imap
+
+package source
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newsletter is a multipart/alternative message with a quoted-printable HTML version
+const newsletter = "From: Weekly News <news@example.com>\r\n" +
+	"To: bot@example.org\r\n" +
+	"Subject: =?UTF-8?Q?Weekly_issue_=E2=84=9642?=\r\n" +
+	"Date: Wed, 01 May 2024 08:00:00 +0200\r\n" +
+	"Message-ID: <issue42@example.com>\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/alternative; boundary=\"sep\"\r\n" +
+	"\r\n" +
+	"--sep\r\n" +
+	"Content-Type: text/plain; charset=utf-8\r\n" +
+	"\r\n" +
+	"Plain text version https://example.com/track?id=1\r\n" +
+	"--sep\r\n" +
+	"Content-Type: text/html; charset=utf-8\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"<html><head><style>p { color: red; }</style></head><body><h1>This week</h1><p>Lots of=\r\n" +
+	" <a href=3D\"https://example.com\">news</a> &amp; more.</p></body></html>\r\n" +
+	"--sep--\r\n"
+
+// receipt is a base64 encoded plain text message
+const receipt = "From: shop@example.net\r\n" +
+	"Subject: Your receipt\r\n" +
+	"Message-ID: <receipt1@example.net>\r\n" +
+	"Content-Type: text/plain; charset=utf-8\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"VGhhbmtzIGZvciB5b3Vy\r\n" +
+	"IG9yZGVyLg==\r\n"
+
+// Test turning messages into items
+func TestMailItem(t *testing.T) {
+	tests := []struct {
+		name                 string
+		message              string
+		expectedSender       string
+		expectedLink         string
+		expectedAnnouncement string
+		expectedDate         string
+	}{
+		{
+			name:                 "HTML newsletter",
+			message:              newsletter,
+			expectedSender:       "news@example.com",
+			expectedLink:         "mid:issue42@example.com",
+			expectedAnnouncement: "Weekly issue №42\n\nThis week Lots of news & more.",
+			expectedDate:         "2024-05-01T06:00:00Z",
+		},
+		{
+			name:                 "Base64 plain text",
+			message:              receipt,
+			expectedSender:       "shop@example.net",
+			expectedLink:         "mid:receipt1@example.net",
+			expectedAnnouncement: "Your receipt\n\nThanks for your order.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item, sender, err := mailItem([]byte(tt.message))
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if sender != tt.expectedSender || item.Link != tt.expectedLink || item.PubDate != tt.expectedDate {
+				t.Errorf("Expected sender %q, link %q and date %q, got %q, %q and %q", tt.expectedSender, tt.expectedLink, tt.expectedDate, sender, item.Link, item.PubDate)
+			}
+			if item.Announcement != tt.expectedAnnouncement {
+				t.Errorf("Expected announcement %q, got %q", tt.expectedAnnouncement, item.Announcement)
+			}
+		})
+	}
+
+	if _, _, err := mailItem([]byte("Subject: No ID\r\n\r\nHello")); err == nil {
+		t.Errorf("Expected an error for a message without Message-ID")
+	}
+}
+
+// Test fetching matching messages from an IMAP folder
+func TestFetchMail(t *testing.T) {
+	current := time.Date(2024, 5, 8, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return current }
+	defer func() { now = time.Now }()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+	go serveIMAP(t, listener)
+
+	tests := []struct {
+		name          string
+		options       map[string]string
+		expectedLinks []string
+	}{
+		{
+			name:          "All messages",
+			expectedLinks: []string{"mid:issue42@example.com", "mid:receipt1@example.net"},
+		},
+		{
+			name:          "From filter",
+			options:       map[string]string{"from": "example.com|news@example.org"},
+			expectedLinks: []string{"mid:issue42@example.com"},
+		},
+		{
+			name:          "From and subject filters",
+			options:       map[string]string{"from": "example.com", "subject": "receipt"},
+			expectedLinks: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed, err := fetchMail(Config{URL: "imap://bot:se%22cret@" + listener.Addr().String() + "/Newsletters", Options: tt.options})
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			var links []string
+			for _, item := range feed.Channel.Items {
+				links = append(links, item.Link)
+			}
+			if strings.Join(links, ",") != strings.Join(tt.expectedLinks, ",") {
+				t.Errorf("Expected messages %v, got %v", tt.expectedLinks, links)
+			}
+		})
+	}
+
+	if _, err := fetchMail(Config{URL: "imap://bot:wrong@" + listener.Addr().String()}); err == nil {
+		t.Errorf("Expected an error for a failed login")
+	}
+}
+
+// Table-driven test for quoting IMAP arguments, refusing line breaks which would inject commands
+func TestIMAPQuote(t *testing.T) {
+	tests := []struct {
+		name          string
+		value         string
+		expected      string
+		expectedError bool
+	}{
+		{name: "Plain", value: "Newsletters", expected: `"Newsletters"`},
+		{name: "Quotes and backslashes", value: `se"c\ret`, expected: `"se\"c\\ret"`},
+		{name: "Line break", value: "INBOX\r\na2 DELETE INBOX", expectedError: true},
+		{name: "Newline", value: "INBOX\n", expectedError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quoted, err := imapQuote(tt.value)
+			if (err != nil) != tt.expectedError {
+				t.Fatalf("Expected error %v, got %v", tt.expectedError, err)
+			}
+			if quoted != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, quoted)
+			}
+		})
+	}
+}
+
+// Test literals larger than maxMessageSize are refused instead of being allocated
+func TestIMAPReadResponse_LiteralSize(t *testing.T) {
+	client := &imapClient{reader: bufio.NewReader(strings.NewReader(fmt.Sprintf("* 1 FETCH (UID 7 BODY[] {%d}\r\n", maxMessageSize+1)))}
+	if _, err := client.readResponse(); err == nil {
+		t.Errorf("Expected an error for a literal larger than %d bytes", maxMessageSize)
+	}
+
+	client = &imapClient{reader: bufio.NewReader(strings.NewReader("* 1 FETCH (UID 7 BODY[] {5}\r\nhello)\r\n"))}
+	response, err := client.readResponse()
+	if err != nil || len(response.Literals) != 1 || string(response.Literals[0]) != "hello" {
+		t.Errorf("Expected the literal, got %+v, %v", response, err)
+	}
+}
+
+// serveIMAP answers IMAP connections with the newsletter and receipt messages
+func serveIMAP(t *testing.T, listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go func(conn net.Conn) {
+			defer conn.Close()
+			reader := bufio.NewReader(conn)
+			fmt.Fprint(conn, "* OK IMAP4rev1 ready\r\n")
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				tag, command, _ := strings.Cut(strings.TrimSpace(line), " ")
+				switch {
+				case command == `LOGIN "bot" "se\"cret"`:
+					fmt.Fprintf(conn, "%s OK LOGIN completed\r\n", tag)
+				case strings.HasPrefix(command, "LOGIN"):
+					fmt.Fprintf(conn, "%s NO LOGIN failed\r\n", tag)
+				case command == `EXAMINE "Newsletters"`:
+					fmt.Fprintf(conn, "* 2 EXISTS\r\n%s OK [READ-ONLY] EXAMINE completed\r\n", tag)
+				case command == "UID SEARCH SINCE 1-May-2024":
+					fmt.Fprintf(conn, "* SEARCH 7 9\r\n%s OK SEARCH completed\r\n", tag)
+				case command == "UID FETCH 7 (BODY.PEEK[])":
+					fmt.Fprintf(conn, "* 1 FETCH (UID 7 BODY[] {%d}\r\n%s)\r\n", len(newsletter), newsletter)
+					fmt.Fprintf(conn, "%s OK FETCH completed\r\n", tag)
+				case command == "UID FETCH 9 (BODY.PEEK[])":
+					fmt.Fprintf(conn, "* 2 FETCH (UID 9 BODY[] {%d}\r\n%s)\r\n", len(receipt), receipt)
+					fmt.Fprintf(conn, "%s OK FETCH completed\r\n", tag)
+				case command == "LOGOUT":
+					fmt.Fprintf(conn, "* BYE\r\n%s OK LOGOUT completed\r\n", tag)
+					return
+				default:
+					t.Errorf("Unexpected IMAP command: %s", command)
+					fmt.Fprintf(conn, "%s BAD unexpected command\r\n", tag)
+				}
+			}
+		}(conn)
+	}
+}
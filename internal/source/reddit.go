@@ -0,0 +1,121 @@
+package source
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// redditUserAgent identifies requests to Reddit, which throttles generic user agents
+const redditUserAgent = "rss2mastodon (+https://github.com/toozej/rss2mastodon)"
+
+// maxSelftextRunes is how much of a text post is kept as its content
+const maxSelftextRunes = 500
+
+func init() {
+	Register(Source{Name: "reddit", Description: "Posts of a subreddit", Options: []string{"min_score", "min_age"}, Fetch: fetchReddit})
+}
+
+// redditListingURL returns the JSON listing URL of a subreddit URL, e.g.
+// https://www.reddit.com/r/golang/top or https://www.reddit.com/r/golang/.rss
+func redditListingURL(pageURL string) (string, error) {
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return "", err
+	}
+	path := strings.TrimSuffix(u.Path, "/")
+	path = strings.TrimSuffix(strings.TrimSuffix(path, ".rss"), ".json")
+	path = strings.TrimSuffix(path, "/")
+	if u.Host == "" || !strings.HasPrefix(path, "/r/") {
+		return "", fmt.Errorf("invalid subreddit URL %q, expected e.g. https://www.reddit.com/r/golang", pageURL)
+	}
+	u.Path = path + ".json"
+
+	query := u.Query()
+	if query.Get("limit") == "" {
+		query.Set("limit", "100")
+	}
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// fetchReddit fetches the posts of a subreddit listing. With min_score, posts are only
+// returned once they are min_age old (default 0) and have at least min_score upvotes, so
+// they are picked up by a later poll once they qualify.
+func fetchReddit(cfg Config) (*rss.RSSFeed, error) {
+	listingURL, err := redditListingURL(cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	minScore := 0
+	if value := cfg.Options["min_score"]; value != "" {
+		if minScore, err = strconv.Atoi(value); err != nil {
+			return nil, fmt.Errorf("invalid min_score %q: %w", value, err)
+		}
+	}
+	var minAge time.Duration
+	if value := cfg.Options["min_age"]; value != "" {
+		if minAge, err = time.ParseDuration(value); err != nil {
+			return nil, fmt.Errorf("invalid min_age %q: %w", value, err)
+		}
+	}
+
+	var listing struct {
+		Data struct {
+			Children []struct {
+				Data struct {
+					Title      string  `json:"title"`
+					Permalink  string  `json:"permalink"`
+					URL        string  `json:"url"`
+					Selftext   string  `json:"selftext"`
+					Score      int     `json:"score"`
+					CreatedUTC float64 `json:"created_utc"`
+					IsSelf     bool    `json:"is_self"`
+					Stickied   bool    `json:"stickied"`
+					Subreddit  string  `json:"subreddit"`
+				} `json:"data"`
+			} `json:"children"`
+		} `json:"data"`
+	}
+	if err := getJSON(listingURL, map[string]string{"User-Agent": redditUserAgent}, &listing); err != nil {
+		return nil, err
+	}
+
+	u, _ := url.Parse(listingURL)
+	feed := &rss.RSSFeed{}
+	for _, child := range listing.Data.Children {
+		post := child.Data
+		// stickied posts are moderator announcements rather than community content
+		if post.Stickied || post.Permalink == "" {
+			continue
+		}
+		created := time.Unix(int64(post.CreatedUTC), 0).UTC()
+		if now().Sub(created) < minAge || post.Score < minScore {
+			continue
+		}
+		if feed.Channel.Title == "" {
+			feed.Channel.Title = "r/" + post.Subreddit
+		}
+
+		permalink := u.Scheme + "://" + u.Host + post.Permalink
+		link := post.URL
+		if post.IsSelf {
+			link = ""
+		}
+
+		feed.Channel.Items = append(feed.Channel.Items, rss.RSSItem{
+			Title:        post.Title,
+			Link:         permalink,
+			GUID:         permalink,
+			PubDate:      created.Format(time.RFC3339),
+			Content:      excerpt(post.Selftext, maxSelftextRunes),
+			Announcement: discussionAnnouncement(post.Title, link, permalink),
+		})
+	}
+	return feed, nil
+}
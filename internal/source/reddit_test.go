@@ -0,0 +1,124 @@
+package source
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test converting subreddit URLs to JSON listing URLs
+func TestRedditListingURL(t *testing.T) {
+	tests := []struct {
+		name        string
+		url         string
+		expected    string
+		expectError bool
+	}{
+		{
+			name:     "Subreddit",
+			url:      "https://www.reddit.com/r/golang/",
+			expected: "https://www.reddit.com/r/golang.json?limit=100",
+		},
+		{
+			name:     "RSS listing",
+			url:      "https://www.reddit.com/r/golang/top/.rss?t=week",
+			expected: "https://www.reddit.com/r/golang/top.json?limit=100&t=week",
+		},
+		{
+			name:     "JSON listing",
+			url:      "https://old.reddit.com/r/golang/new.json?limit=25",
+			expected: "https://old.reddit.com/r/golang/new.json?limit=25",
+		},
+		{
+			name:        "Not a subreddit",
+			url:         "https://www.reddit.com/user/someone",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := redditListingURL(tt.url)
+			if (err != nil) != tt.expectError {
+				t.Fatalf("Expected error %v, got %v", tt.expectError, err)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+// Test filtering subreddit posts by score and age
+func TestFetchReddit(t *testing.T) {
+	current := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return current }
+	defer func() { now = time.Now }()
+
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/r/golang.json" {
+			t.Errorf("Unexpected request: %s", r.URL.Path)
+		}
+		if !strings.HasPrefix(r.Header.Get("User-Agent"), "rss2mastodon") {
+			t.Errorf("Expected a descriptive user agent, got %q", r.Header.Get("User-Agent"))
+		}
+		_, _ = w.Write([]byte(`{"data":{"children":[
+			{"data":{"title":"Rules","permalink":"/r/golang/comments/0/rules/","score":500,"created_utc":1714500000,"is_self":true,"stickied":true,"subreddit":"golang"}},
+			{"data":{"title":"Too new","permalink":"/r/golang/comments/1/new/","score":200,"created_utc":1714563000,"is_self":true,"subreddit":"golang"}},
+			{"data":{"title":"Too few upvotes","permalink":"/r/golang/comments/2/few/","score":10,"created_utc":1714500000,"is_self":true,"subreddit":"golang"}},
+			{"data":{"title":"Go 1.23 is out","permalink":"/r/golang/comments/3/go/","url":"https://go.dev/blog/go1.23","score":150,"created_utc":1714500000,"subreddit":"golang"}},
+			{"data":{"title":"Show r/golang","permalink":"/r/golang/comments/4/show/","selftext":"I made a thing","score":120,"created_utc":1714500000,"is_self":true,"subreddit":"golang"}}
+		]}}`))
+	}))
+	defer mockServer.Close()
+
+	tests := []struct {
+		name           string
+		options        map[string]string
+		expectedTitles []string
+		expectError    bool
+	}{
+		{
+			name:           "No threshold",
+			expectedTitles: []string{"Too new", "Too few upvotes", "Go 1.23 is out", "Show r/golang"},
+		},
+		{
+			name:           "Score threshold after a minimum age",
+			options:        map[string]string{"min_score": "100", "min_age": "6h"},
+			expectedTitles: []string{"Go 1.23 is out", "Show r/golang"},
+		},
+		{
+			name:        "Invalid threshold",
+			options:     map[string]string{"min_score": "many"},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed, err := fetchReddit(Config{URL: mockServer.URL + "/r/golang", Options: tt.options})
+			if (err != nil) != tt.expectError {
+				t.Fatalf("Expected error %v, got %v", tt.expectError, err)
+			}
+			if tt.expectError {
+				return
+			}
+
+			var titles []string
+			for _, item := range feed.Channel.Items {
+				titles = append(titles, item.Title)
+			}
+			if strings.Join(titles, ",") != strings.Join(tt.expectedTitles, ",") {
+				t.Errorf("Expected posts %v, got %v", tt.expectedTitles, titles)
+			}
+		})
+	}
+
+	feed, _ := fetchReddit(Config{URL: mockServer.URL + "/r/golang", Options: map[string]string{"min_score": "100"}})
+	expected := "Go 1.23 is out\n\nhttps://go.dev/blog/go1.23\n\nDiscussion: " + mockServer.URL + "/r/golang/comments/3/go/"
+	if feed.Channel.Title != "r/golang" || feed.Channel.Items[1].Announcement != expected {
+		t.Errorf("Expected announcement %q, got %q", expected, feed.Channel.Items[1].Announcement)
+	}
+}
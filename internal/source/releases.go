@@ -0,0 +1,155 @@
+package source
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// maxChangelogRunes is how much of a release's changelog is quoted in its announcement
+const maxChangelogRunes = 280
+
+// releasesPerPage is how many of the most recent releases are fetched
+const releasesPerPage = 10
+
+// release is a GitHub or GitLab release
+type release struct {
+	Tag        string
+	Name       string
+	Changelog  string
+	URL        string
+	Published  string
+	Prerelease bool
+}
+
+func init() {
+	Register(Source{Name: "github", Description: "Releases of a GitHub repository", Options: []string{"prereleases"},
+		ConfigKeys: []string{"github_token"}, Fetch: fetchGitHubReleases})
+	Register(Source{Name: "gitlab", Description: "Releases of a GitLab project", ConfigKeys: []string{"gitlab_token"}, Fetch: fetchGitLabReleases})
+}
+
+// repository splits a repository URL like https://github.com/owner/repo into its base URL
+// (scheme and host) and path
+func repository(repoURL string) (string, string, error) {
+	u, err := url.Parse(repoURL)
+	if err != nil {
+		return "", "", err
+	}
+	path := strings.Trim(strings.TrimSuffix(u.Path, ".git"), "/")
+	if u.Host == "" || !strings.Contains(path, "/") {
+		return "", "", fmt.Errorf("invalid repository URL %q, expected e.g. https://github.com/owner/repo", repoURL)
+	}
+	return u.Scheme + "://" + u.Host, path, nil
+}
+
+// fetchGitHubReleases fetches the releases of a GitHub (or GitHub Enterprise) repository,
+// authenticating with github_token if set
+func fetchGitHubReleases(cfg Config) (*rss.RSSFeed, error) {
+	base, repo, err := repository(cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+	api := base + "/api/v3"
+	if base == "https://github.com" {
+		api = "https://api.github.com"
+	}
+
+	headers := map[string]string{"Accept": "application/vnd.github+json"}
+	if token := viper.GetString("github_token"); token != "" {
+		headers["Authorization"] = "Bearer " + token
+	}
+	var response []struct {
+		TagName     string `json:"tag_name"`
+		Name        string `json:"name"`
+		Body        string `json:"body"`
+		HTMLURL     string `json:"html_url"`
+		PublishedAt string `json:"published_at"`
+		Draft       bool   `json:"draft"`
+		Prerelease  bool   `json:"prerelease"`
+	}
+	if err := getJSON(fmt.Sprintf("%s/repos/%s/releases?per_page=%d", api, repo, releasesPerPage), headers, &response); err != nil {
+		return nil, err
+	}
+
+	var releases []release
+	for _, r := range response {
+		if r.Draft || (r.Prerelease && cfg.Options["prereleases"] != "true") {
+			continue
+		}
+		releases = append(releases, release{Tag: r.TagName, Name: r.Name, Changelog: r.Body, URL: r.HTMLURL, Published: r.PublishedAt})
+	}
+	return releasesFeed(repo, releases), nil
+}
+
+// fetchGitLabReleases fetches the releases of a GitLab project, authenticating with
+// gitlab_token if set
+func fetchGitLabReleases(cfg Config) (*rss.RSSFeed, error) {
+	base, project, err := repository(cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := map[string]string{}
+	if token := viper.GetString("gitlab_token"); token != "" {
+		headers["PRIVATE-TOKEN"] = token
+	}
+	var response []struct {
+		TagName         string `json:"tag_name"`
+		Name            string `json:"name"`
+		Description     string `json:"description"`
+		ReleasedAt      string `json:"released_at"`
+		UpcomingRelease bool   `json:"upcoming_release"`
+		Links           struct {
+			Self string `json:"self"`
+		} `json:"_links"`
+	}
+	endpoint := fmt.Sprintf("%s/api/v4/projects/%s/releases?per_page=%d", base, url.PathEscape(project), releasesPerPage)
+	if err := getJSON(endpoint, headers, &response); err != nil {
+		return nil, err
+	}
+
+	var releases []release
+	for _, r := range response {
+		if r.UpcomingRelease {
+			continue
+		}
+		releases = append(releases, release{Tag: r.TagName, Name: r.Name, Changelog: r.Description, URL: r.Links.Self, Published: r.ReleasedAt})
+	}
+	return releasesFeed(project, releases), nil
+}
+
+// releasesFeed turns a repository's releases into feed items announcing the tag, name and
+// an excerpt of the changelog
+func releasesFeed(repo string, releases []release) *rss.RSSFeed {
+	feed := &rss.RSSFeed{}
+	feed.Channel.Title = repo
+	for _, r := range releases {
+		title := r.Name
+		if title == "" {
+			title = r.Tag
+		}
+
+		announcement := fmt.Sprintf("%s %s released", repo, r.Tag)
+		if r.Name != "" && r.Name != r.Tag {
+			announcement += ": " + r.Name
+		}
+		if changelog := excerpt(r.Changelog, maxChangelogRunes); changelog != "" {
+			announcement += "\n\n" + changelog
+		}
+		announcement += "\n\n" + r.URL
+
+		feed.Channel.Items = append(feed.Channel.Items, rss.RSSItem{
+			Title:        title,
+			Link:         r.URL,
+			GUID:         repo + "@" + r.Tag,
+			PubDate:      r.Published,
+			Content:      r.Changelog,
+			Announcement: announcement,
+		})
+	}
+	return feed
+}
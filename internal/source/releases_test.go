@@ -0,0 +1,99 @@
+package source
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// Test announcing GitHub Enterprise and GitLab releases
+func TestFetchReleases(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v3/repos/owner/repo/releases":
+			if r.Header.Get("Authorization") != "Bearer gh-token" {
+				t.Errorf("Expected the GitHub token to be sent")
+			}
+			_, _ = w.Write([]byte(`[
+				{"tag_name":"v2.0.0-rc1","name":"","body":"","html_url":"https://example.com/rc1","prerelease":true},
+				{"tag_name":"v1.1.0","name":"","body":"Draft","html_url":"https://example.com/draft","draft":true},
+				{"tag_name":"v1.0.0","name":"First release","body":"Lots of bug fixes","html_url":"https://example.com/v1.0.0","published_at":"2024-05-01T12:00:00Z"}
+			]`))
+		case "/api/v4/projects/group/project/releases":
+			if r.Header.Get("PRIVATE-TOKEN") != "gl-token" {
+				t.Errorf("Expected the GitLab token to be sent")
+			}
+			_, _ = w.Write([]byte(`[
+				{"tag_name":"v3.0.0","upcoming_release":true},
+				{"tag_name":"v2.0.0","name":"v2.0.0","description":"Breaking changes","released_at":"2024-05-02T12:00:00Z","_links":{"self":"https://example.com/v2.0.0"}}
+			]`))
+		default:
+			t.Errorf("Unexpected request: %s", r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	defer mockServer.Close()
+
+	viper.Set("github_token", "gh-token")
+	viper.Set("gitlab_token", "gl-token")
+	defer viper.Reset()
+
+	tests := []struct {
+		name                 string
+		source               string
+		options              map[string]string
+		expectedTags         []string
+		expectedAnnouncement string
+	}{
+		{
+			name:                 "GitHub",
+			source:               "github",
+			expectedTags:         []string{"v1.0.0"},
+			expectedAnnouncement: "owner/repo v1.0.0 released: First release\n\nLots of bug fixes\n\nhttps://example.com/v1.0.0",
+		},
+		{
+			name:         "GitHub with prereleases",
+			source:       "github",
+			options:      map[string]string{"prereleases": "true"},
+			expectedTags: []string{"v2.0.0-rc1", "v1.0.0"},
+		},
+		{
+			name:                 "GitLab",
+			source:               "gitlab",
+			expectedTags:         []string{"v2.0.0"},
+			expectedAnnouncement: "group/project v2.0.0 released\n\nBreaking changes\n\nhttps://example.com/v2.0.0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src, _ := Get(tt.source)
+			repo := "/owner/repo"
+			if tt.source == "gitlab" {
+				repo = "/group/project"
+			}
+			feed, err := src.Fetch(Config{URL: mockServer.URL + repo, Options: tt.options})
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			var tags []string
+			for _, item := range feed.Channel.Items {
+				tags = append(tags, item.GUID[strings.Index(item.GUID, "@")+1:])
+			}
+			if strings.Join(tags, ",") != strings.Join(tt.expectedTags, ",") {
+				t.Errorf("Expected tags %v, got %v", tt.expectedTags, tags)
+			}
+			if tt.expectedAnnouncement != "" && feed.Channel.Items[0].Announcement != tt.expectedAnnouncement {
+				t.Errorf("Expected announcement %q, got %q", tt.expectedAnnouncement, feed.Channel.Items[0].Announcement)
+			}
+		})
+	}
+
+	if _, err := fetchGitHubReleases(Config{URL: "https://github.com/owner"}); err == nil {
+		t.Errorf("Expected an error for a URL without a repository")
+	}
+}
@@ -0,0 +1,138 @@
+//go:build !no_scraper
+
+package source
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/toozej/rss2mastodon/internal/httpclient"
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// maxScrapeBytes caps how much of a scraped page is read
+const maxScrapeBytes = 5 * 1024 * 1024
+
+func init() {
+	Register(Source{Name: "html", Description: "Items scraped from an HTML page", Options: []string{"item", "title", "link", "date", "date_format"}, Fetch: fetchScraped})
+}
+
+// scrapeSelectors are the parsed selectors of an html source
+type scrapeSelectors struct {
+	item, title, link, date cssSelector
+}
+
+// parseScrapeSelectors parses the selector options of an html source. Only item is required:
+// the link defaults to the item's first link and the title to the link's text.
+func parseScrapeSelectors(options map[string]string) (scrapeSelectors, error) {
+	var selectors scrapeSelectors
+	if options["item"] == "" {
+		return selectors, fmt.Errorf("the item option is required for html feeds")
+	}
+	for _, s := range []struct {
+		option string
+		target *cssSelector
+		def    string
+	}{
+		{"item", &selectors.item, ""},
+		{"title", &selectors.title, ""},
+		{"link", &selectors.link, "a[href]"},
+		{"date", &selectors.date, ""},
+	} {
+		value := options[s.option]
+		if value == "" {
+			value = s.def
+		}
+		if value == "" {
+			continue
+		}
+		parsed, err := parseSelector(value)
+		if err != nil {
+			return selectors, fmt.Errorf("%s: %w", s.option, err)
+		}
+		*s.target = parsed
+	}
+	return selectors, nil
+}
+
+// fetchScraped scrapes the items of a page without a feed, selecting each item's container
+// with the item CSS selector and its title, link and date within it
+func fetchScraped(cfg Config) (*rss.RSSFeed, error) {
+	selectors, err := parseScrapeSelectors(cfg.Options)
+	if err != nil {
+		return nil, err
+	}
+	base, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	client := httpclient.New("source", 10*time.Second)
+	resp, err := client.Get(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected HTTP status: %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxScrapeBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read page: %w", err)
+	}
+
+	page := parseHTML(string(body))
+	feed := &rss.RSSFeed{}
+	if title, _ := parseSelector("title"); title != nil {
+		if node := page.selectFirst(title); node != nil {
+			feed.Channel.Title = node.text()
+		}
+	}
+
+	for _, container := range page.selectAll(selectors.item) {
+		linkNode := container
+		if !(selectors.link.matches(container, nil) && container.Attrs["href"] != "") {
+			linkNode = container.selectFirst(selectors.link)
+		}
+		if linkNode == nil {
+			continue
+		}
+		href, err := base.Parse(strings.TrimSpace(linkNode.Attrs["href"]))
+		if err != nil || linkNode.Attrs["href"] == "" {
+			continue
+		}
+
+		item := rss.RSSItem{Link: href.String(), GUID: href.String(), Title: linkNode.text()}
+		if selectors.title != nil {
+			if node := container.selectFirst(selectors.title); node != nil {
+				item.Title = node.text()
+			}
+		}
+		if selectors.date != nil {
+			if node := container.selectFirst(selectors.date); node != nil {
+				item.PubDate = scrapedDate(node, cfg.Options["date_format"])
+			}
+		}
+		feed.Channel.Items = append(feed.Channel.Items, item)
+	}
+	return feed, nil
+}
+
+// scrapedDate returns a date element's date, from the datetime attribute of <time> elements
+// or the element's text, in RFC 3339 if it is parsed with the date_format layout
+func scrapedDate(node *htmlNode, layout string) string {
+	value := node.Attrs["datetime"]
+	if value == "" {
+		value = node.text()
+	}
+	if layout != "" {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.Format(time.RFC3339)
+		}
+	}
+	return value
+}
@@ -0,0 +1,95 @@
+//go:build !no_scraper
+
+package source
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Test scraping the items of a page without a feed
+func TestFetchScraped(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<html><head><title>Example News</title></head><body>
+<article class="entry">
+  <h2><a href="/news/one">Read more</a></h2>
+  <h3 class="headline">First story</h3>
+  <time datetime="2024-05-01T12:00:00Z">May 1</time>
+</article>
+<article class="entry">
+  <h2><a href="https://other.example.com/two">Second story</a></h2>
+  <span class="date">02.05.2024</span>
+</article>
+<article class="entry"><p>No link</p></article>
+</body></html>`))
+	}))
+	defer mockServer.Close()
+
+	tests := []struct {
+		name          string
+		options       map[string]string
+		expectedItems []rssItemSummary
+		expectError   bool
+	}{
+		{
+			name:    "Item selector only",
+			options: map[string]string{"item": "article.entry"},
+			expectedItems: []rssItemSummary{
+				{title: "Read more", link: mockServer.URL + "/news/one"},
+				{title: "Second story", link: "https://other.example.com/two"},
+			},
+		},
+		{
+			name:    "Title and date selectors",
+			options: map[string]string{"item": "article.entry", "title": ".headline", "link": "h2 > a", "date": ".date", "date_format": "02.01.2006"},
+			expectedItems: []rssItemSummary{
+				{title: "First story", link: mockServer.URL + "/news/one"},
+				{title: "Second story", link: "https://other.example.com/two", date: "2024-05-02T00:00:00Z"},
+			},
+		},
+		{
+			name:    "Date from datetime attribute",
+			options: map[string]string{"item": "article.entry", "date": "time"},
+			expectedItems: []rssItemSummary{
+				{title: "Read more", link: mockServer.URL + "/news/one", date: "2024-05-01T12:00:00Z"},
+				{title: "Second story", link: "https://other.example.com/two"},
+			},
+		},
+		{
+			name:        "Missing item selector",
+			options:     map[string]string{"title": "h2"},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed, err := fetchScraped(Config{URL: mockServer.URL + "/news/", Options: tt.options})
+			if (err != nil) != tt.expectError {
+				t.Fatalf("Expected error %v, got %v", tt.expectError, err)
+			}
+			if tt.expectError {
+				return
+			}
+			if feed.Channel.Title != "Example News" {
+				t.Errorf("Expected the page title, got %q", feed.Channel.Title)
+			}
+			if len(feed.Channel.Items) != len(tt.expectedItems) {
+				t.Fatalf("Expected %d items, got %+v", len(tt.expectedItems), feed.Channel.Items)
+			}
+			for i, expected := range tt.expectedItems {
+				item := feed.Channel.Items[i]
+				got := rssItemSummary{title: item.Title, link: item.Link, date: item.PubDate}
+				if got != expected {
+					t.Errorf("Expected item %+v, got %+v", expected, got)
+				}
+			}
+		})
+	}
+}
+
+// rssItemSummary holds the scraped fields of an item for comparison
+type rssItemSummary struct {
+	title, link, date string
+}
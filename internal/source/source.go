@@ -0,0 +1,118 @@
+// Package source fetches items from sources other than RSS feeds, e.g. release pages, as feeds
+// so they go through the same pipeline as feed items.
+//
+// Optional sources which pull in dependencies or need external tools can be left out of
+// minimal builds with build tags, e.g. go build -tags no_imap,no_scraper:
+//
+//	Tag            Leaves out         Source file    Notes
+//	no_directory   directory source   directory.go   watches directories with fsnotify
+//	no_git         git source         git.go         runs the git binary
+//	no_imap        imap source        imap.go        IMAP client and MIME decoding
+//	no_scraper     html source        scrape.go      CSS selector based scraping
+//
+// Every other source is always built. The HTML parser in html.go is shared with other
+// sources and stays in. Sources left out aren't registered, so feeds using them fail
+// validation with an unknown type, and the plugins command shows what a build includes.
+// New heavy sources should follow the same no_NAME convention and be added here.
+//
+// Together these tags only save a few hundred KB. Most of a minimal build's savings come from
+// the no_tui tag of cmd/rss2mastodon/tui.go, which leaves out the tui command and its terminal
+// UI libraries.
+package source
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// Config is a source's URL and the source-specific options of its --feed definition
+type Config struct {
+	URL     string
+	Options map[string]string
+}
+
+// Source is a kind of source, selected with the type option of a --feed definition
+type Source struct {
+	Name string
+	// Description briefly describes what the source fetches, e.g. for the plugins command
+	Description string
+	// Options are the source-specific --feed options the source accepts
+	Options []string
+	// ConfigKeys are the global configuration keys the source reads, e.g. credentials
+	ConfigKeys []string
+	// Fetch returns the source's current items
+	Fetch func(cfg Config) (*rss.RSSFeed, error)
+	// Watch optionally starts watching the source in the background, calling changed when its
+	// items may have changed so it can be polled right away
+	Watch func(cfg Config, changed func()) error
+}
+
+// now returns the current time, replaced in tests
+var now = time.Now
+
+// registry holds the available sources by name
+var registry = map[string]Source{}
+
+// Register makes a source available, sources register themselves on init
+func Register(s Source) {
+	registry[s.Name] = s
+}
+
+// Get returns the source with the given name
+func Get(name string) (Source, bool) {
+	s, ok := registry[name]
+	return s, ok
+}
+
+// Names returns the names of all available sources, sorted
+func Names() []string {
+	var names []string
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Capabilities returns what the source supports: fetching, and watching if it can
+func (s Source) Capabilities() []string {
+	capabilities := []string{"fetch"}
+	if s.Watch != nil {
+		capabilities = append(capabilities, "watch")
+	}
+	return capabilities
+}
+
+// Validate checks the source-specific options of a --feed definition
+func (s Source) Validate(options map[string]string) error {
+	for key := range options {
+		known := false
+		for _, option := range s.Options {
+			known = known || key == option
+		}
+		if !known {
+			return fmt.Errorf("unknown option %q for %s feeds", key, s.Name)
+		}
+	}
+	return nil
+}
+
+// excerpt shortens text to at most maxRunes, cutting at a word boundary
+func excerpt(text string, maxRunes int) string {
+	text = strings.TrimSpace(strings.ReplaceAll(text, "\r", ""))
+	if utf8.RuneCountInString(text) <= maxRunes {
+		return text
+	}
+
+	runes := []rune(text)[:maxRunes]
+	cut := string(runes)
+	if i := strings.LastIndexAny(cut, " \n"); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimSpace(cut) + "…"
+}
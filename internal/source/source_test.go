@@ -0,0 +1,43 @@
+package source
+
+import (
+	"testing"
+)
+
+// Table-driven test for shortening text at a word boundary
+func TestExcerpt(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		maxRunes int
+		expected string
+	}{
+		{"Short text", "  Bug fixes\r\n", 20, "Bug fixes"},
+		{"Cut at a word", "Lots of bug fixes", 12, "Lots of bug…"},
+		{"Multibyte", "Grüße aus Köln", 9, "Grüße…"},
+		{"No word boundary", "abcdefghij", 4, "abcd…"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := excerpt(tt.text, tt.maxRunes); got != tt.expected {
+				t.Errorf("Expected '%s', got '%s'", tt.expected, got)
+			}
+		})
+	}
+}
+
+// Test validating source-specific options
+func TestSourceValidate(t *testing.T) {
+	s := Source{Name: "test", Options: []string{"token"}}
+
+	if err := s.Validate(map[string]string{"token": "x"}); err != nil {
+		t.Errorf("Expected no error for a known option, got %v", err)
+	}
+	if err := s.Validate(map[string]string{"tokn": "x"}); err == nil {
+		t.Errorf("Expected an error for an unknown option")
+	}
+	if _, ok := Get("github"); !ok {
+		t.Errorf("Expected the github source to be registered")
+	}
+}
@@ -0,0 +1,105 @@
+package source
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/toozej/rss2mastodon/internal/httpclient"
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+// youtubeFeeds is where YouTube serves the Atom feeds of channels and playlists
+var youtubeFeeds = "https://www.youtube.com/feeds/videos.xml"
+
+// youtubeFeed is a YouTube channel or playlist Atom feed
+type youtubeFeed struct {
+	Title   string `xml:"http://www.w3.org/2005/Atom title"`
+	Entries []struct {
+		VideoID   string `xml:"http://www.youtube.com/xml/schemas/2015 videoId"`
+		Title     string `xml:"http://www.w3.org/2005/Atom title"`
+		Published string `xml:"http://www.w3.org/2005/Atom published"`
+		Group     struct {
+			Description string `xml:"http://search.yahoo.com/mrss/ description"`
+			Thumbnail   struct {
+				URL string `xml:"url,attr"`
+			} `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+		} `xml:"http://search.yahoo.com/mrss/ group"`
+	} `xml:"http://www.w3.org/2005/Atom entry"`
+}
+
+func init() {
+	Register(Source{Name: "youtube", Description: "Videos of a YouTube channel or playlist", Fetch: fetchYouTube})
+}
+
+// youtubeFeedURL returns the feed URL of a YouTube channel or playlist URL, e.g.
+// https://www.youtube.com/channel/UC... or https://www.youtube.com/playlist?list=PL...
+// Feed URLs are used as they are.
+func youtubeFeedURL(pageURL string) (string, error) {
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return "", err
+	}
+	query := u.Query()
+
+	switch {
+	case query.Get("channel_id") != "" || query.Get("playlist_id") != "":
+		return pageURL, nil
+	case strings.HasPrefix(u.Path, "/channel/"):
+		channel := strings.Trim(strings.TrimPrefix(u.Path, "/channel/"), "/")
+		return youtubeFeeds + "?channel_id=" + url.QueryEscape(strings.Split(channel, "/")[0]), nil
+	case u.Path == "/playlist" && query.Get("list") != "":
+		return youtubeFeeds + "?playlist_id=" + url.QueryEscape(query.Get("list")), nil
+	}
+	return "", fmt.Errorf("unsupported YouTube URL %q, expected a /channel/ or /playlist?list= URL", pageURL)
+}
+
+// fetchYouTube fetches the videos of a YouTube channel or playlist, announced with a clean
+// watch URL and their thumbnail attached
+func fetchYouTube(cfg Config) (*rss.RSSFeed, error) {
+	feedURL, err := youtubeFeedURL(cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	client := httpclient.New("source", 10*time.Second)
+	resp, err := client.Get(feedURL)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected HTTP status: %d", resp.StatusCode)
+	}
+
+	var yt youtubeFeed
+	if err := xml.NewDecoder(resp.Body).Decode(&yt); err != nil {
+		return nil, fmt.Errorf("failed to parse YouTube feed: %w", err)
+	}
+
+	feed := &rss.RSSFeed{}
+	feed.Channel.Title = yt.Title
+	for _, entry := range yt.Entries {
+		if entry.VideoID == "" {
+			continue
+		}
+		watchURL := "https://www.youtube.com/watch?v=" + url.QueryEscape(entry.VideoID)
+		item := rss.RSSItem{
+			Title:        entry.Title,
+			Link:         watchURL,
+			GUID:         "yt:video:" + entry.VideoID,
+			PubDate:      entry.Published,
+			Content:      entry.Group.Description,
+			Announcement: fmt.Sprintf("New video: %s\n\n%s", entry.Title, watchURL),
+			AttachImages: true,
+		}
+		if thumbnail := entry.Group.Thumbnail.URL; thumbnail != "" {
+			item.Media = []rss.MediaContent{{URL: thumbnail, Medium: "image", Title: entry.Title}}
+		}
+		feed.Channel.Items = append(feed.Channel.Items, item)
+	}
+	return feed, nil
+}
@@ -0,0 +1,97 @@
+package source
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Test converting YouTube channel and playlist URLs to feed URLs
+func TestYouTubeFeedURL(t *testing.T) {
+	tests := []struct {
+		name        string
+		url         string
+		expected    string
+		expectError bool
+	}{
+		{
+			name:     "Channel",
+			url:      "https://www.youtube.com/channel/UC123/videos",
+			expected: "https://www.youtube.com/feeds/videos.xml?channel_id=UC123",
+		},
+		{
+			name:     "Playlist",
+			url:      "https://www.youtube.com/playlist?list=PL456",
+			expected: "https://www.youtube.com/feeds/videos.xml?playlist_id=PL456",
+		},
+		{
+			name:     "Feed URL",
+			url:      "https://www.youtube.com/feeds/videos.xml?channel_id=UC123",
+			expected: "https://www.youtube.com/feeds/videos.xml?channel_id=UC123",
+		},
+		{
+			name:        "Handle",
+			url:         "https://www.youtube.com/@someone",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := youtubeFeedURL(tt.url)
+			if (err != nil) != tt.expectError {
+				t.Fatalf("Expected error %v, got %v", tt.expectError, err)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+// Test announcing the videos of a YouTube channel feed
+func TestFetchYouTube(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns:yt="http://www.youtube.com/xml/schemas/2015" xmlns:media="http://search.yahoo.com/mrss/" xmlns="http://www.w3.org/2005/Atom">
+  <title>Some Channel</title>
+  <entry>
+    <id>yt:video:abc123</id>
+    <yt:videoId>abc123</yt:videoId>
+    <title>My first video</title>
+    <link rel="alternate" href="https://www.youtube.com/watch?v=abc123&amp;feature=youtu.be"/>
+    <published>2024-05-01T12:00:00+00:00</published>
+    <media:group>
+      <media:title>My first video</media:title>
+      <media:thumbnail url="https://i.ytimg.com/vi/abc123/hqdefault.jpg" width="480" height="360"/>
+      <media:description>All about it</media:description>
+    </media:group>
+  </entry>
+</feed>`))
+	}))
+	defer mockServer.Close()
+
+	src, _ := Get("youtube")
+	feed, err := src.Fetch(Config{URL: mockServer.URL + "?channel_id=UC123"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if feed.Channel.Title != "Some Channel" || len(feed.Channel.Items) != 1 {
+		t.Fatalf("Expected one video from Some Channel, got %+v", feed.Channel)
+	}
+
+	item := feed.Channel.Items[0]
+	if item.Link != "https://www.youtube.com/watch?v=abc123" {
+		t.Errorf("Expected a clean watch URL, got %q", item.Link)
+	}
+	if item.Announcement != "New video: My first video\n\nhttps://www.youtube.com/watch?v=abc123" {
+		t.Errorf("Unexpected announcement %q", item.Announcement)
+	}
+	if item.Content != "All about it" || item.PubDate != "2024-05-01T12:00:00+00:00" {
+		t.Errorf("Unexpected content %q or date %q", item.Content, item.PubDate)
+	}
+	images := item.Images()
+	if !item.AttachImages || len(images) != 1 || images[0].URL != "https://i.ytimg.com/vi/abc123/hqdefault.jpg" {
+		t.Errorf("Expected the thumbnail to be attached, got %+v", images)
+	}
+}
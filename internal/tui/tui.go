@@ -0,0 +1,286 @@
+package tui
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+	"github.com/toozej/rss2mastodon/internal/rss2mastodon"
+)
+
+// views shown by the TUI, switched between with tab
+const (
+	viewFeeds = iota
+	viewPending
+	viewToots
+	viewErrors
+	viewCount
+)
+
+var viewNames = []string{"Feeds", "Pending", "Recent toots", "Errors"}
+
+// how many recent toots and errors are shown
+const historyLimit = 50
+
+// how often the TUI reloads its data from the database
+const refreshInterval = 5 * time.Second
+
+type refreshMsg struct{}
+
+// model is the bubbletea model of the TUI
+type model struct {
+	view    int
+	cursor  int
+	status  string
+	feeds   []db.FeedHealth
+	pending []db.PendingPost
+	toots   []db.TootedPost
+	errors  []db.ErrorLogEntry
+}
+
+// Run starts the interactive TUI for managing the queue and history
+func Run(cmd *cobra.Command, args []string) {
+	rss2mastodon.OpenDB()
+	defer db.CloseDB()
+
+	m := &model{}
+	m.load()
+	if _, err := tea.NewProgram(m, tea.WithAltScreen()).Run(); err != nil {
+		log.Fatal("Error running TUI: ", err)
+	}
+}
+
+func (m *model) Init() tea.Cmd {
+	return tick()
+}
+
+func tick() tea.Cmd {
+	return tea.Tick(refreshInterval, func(time.Time) tea.Msg { return refreshMsg{} })
+}
+
+// load reloads everything shown from the database
+func (m *model) load() {
+	var err error
+	if m.feeds, err = db.ListFeedHealth(); err != nil {
+		m.status = "Error loading feeds: " + err.Error()
+	}
+	if m.pending, err = db.ListPendingPosts(); err != nil {
+		m.status = "Error loading pending posts: " + err.Error()
+	}
+	if m.toots, err = db.RecentTootedPosts(historyLimit); err != nil {
+		m.status = "Error loading toots: " + err.Error()
+	}
+	if m.errors, err = db.RecentErrors(historyLimit); err != nil {
+		m.status = "Error loading errors: " + err.Error()
+	}
+	m.cursor = min(m.cursor, max(0, m.rows()-1))
+}
+
+// rows returns the number of rows in the current view
+func (m *model) rows() int {
+	switch m.view {
+	case viewFeeds:
+		return len(m.feeds)
+	case viewPending:
+		return len(m.pending)
+	case viewToots:
+		return len(m.toots)
+	default:
+		return len(m.errors)
+	}
+}
+
+func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case refreshMsg:
+		m.load()
+		return m, tick()
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "q", "ctrl+c":
+			return m, tea.Quit
+		case "tab":
+			m.view = (m.view + 1) % viewCount
+			m.cursor = 0
+		case "shift+tab":
+			m.view = (m.view + viewCount - 1) % viewCount
+			m.cursor = 0
+		case "up", "k":
+			m.cursor = max(0, m.cursor-1)
+		case "down", "j":
+			m.cursor = min(max(0, m.rows()-1), m.cursor+1)
+		case "a":
+			m.approve()
+		case "r":
+			m.retry()
+		case "f":
+			m.forget()
+		case "p":
+			m.status = forcePoll()
+		case "R":
+			m.load()
+			m.status = "Refreshed"
+		}
+	}
+	return m, nil
+}
+
+// approve approves the selected post awaiting approval
+func (m *model) approve() {
+	if m.view != viewPending || len(m.pending) == 0 {
+		return
+	}
+	pending := m.pending[m.cursor]
+	if pending.Reason != db.PendingApproval {
+		m.status = "Post is not awaiting approval"
+		return
+	}
+	if err := db.SetPendingReason(pending.Item.Link, db.PendingApproved); err != nil {
+		m.status = "Error approving post: " + err.Error()
+		return
+	}
+	m.status = "Approved " + pending.Item.Link
+	m.load()
+}
+
+// retry makes the selected pending post due immediately
+func (m *model) retry() {
+	if m.view != viewPending || len(m.pending) == 0 {
+		return
+	}
+	pending := m.pending[m.cursor]
+	if err := db.RetryPendingPost(pending.Item.Link); err != nil {
+		m.status = "Error retrying post: " + err.Error()
+		return
+	}
+	m.status = "Retrying " + pending.Item.Link + " on the next cycle"
+	m.load()
+}
+
+// forget removes the selected pending post from the queue, or the selected toot from
+// the history so the post is announced again if it is still in the feed
+func (m *model) forget() {
+	var link string
+	var err error
+	switch {
+	case m.view == viewPending && len(m.pending) > 0:
+		link = m.pending[m.cursor].Item.Link
+		err = db.RemovePendingPost(link)
+	case m.view == viewToots && len(m.toots) > 0:
+		link = m.toots[m.cursor].Link
+		err = db.ForgetTootedPost(link)
+	default:
+		return
+	}
+
+	if err != nil {
+		m.status = "Error forgetting post: " + err.Error()
+		return
+	}
+	m.status = "Forgot " + link
+	m.load()
+}
+
+// forcePoll asks the running daemon to poll immediately via its admin listener
+func forcePoll() string {
+	adminURL := adminURL()
+	if adminURL == "" {
+		return "Force poll requires the admin listener (LISTEN_ADDR and ADMIN_TOKEN)"
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	req, err := http.NewRequest("POST", adminURL+"/poll", nil)
+	if err != nil {
+		return "Error forcing poll: " + err.Error()
+	}
+	req.Header.Set("Authorization", "Bearer "+viper.GetString("admin_token"))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "Error forcing poll: " + err.Error()
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Sprintf("Error forcing poll: unexpected HTTP status: %d", resp.StatusCode)
+	}
+	return "Poll triggered"
+}
+
+// adminURL returns the base URL of the daemon's admin listener, or "" if it isn't configured
+func adminURL() string {
+	if publicURL := viper.GetString("public_url"); publicURL != "" {
+		return strings.TrimSuffix(publicURL, "/")
+	}
+	addr := viper.GetString("listen_addr")
+	if addr == "" {
+		return ""
+	}
+	if strings.HasPrefix(addr, ":") {
+		addr = "localhost" + addr
+	}
+	return "http://" + addr
+}
+
+func (m *model) View() string {
+	var b strings.Builder
+
+	for i, name := range viewNames {
+		if i == m.view {
+			fmt.Fprintf(&b, "[%s] ", name)
+		} else {
+			fmt.Fprintf(&b, " %s  ", name)
+		}
+	}
+	b.WriteString("\n\n")
+
+	var lines []string
+	switch m.view {
+	case viewFeeds:
+		for _, feed := range m.feeds {
+			lines = append(lines, fmt.Sprintf("%s  last new item %s", feed.FeedURL, formatTime(feed.LastNewItem)))
+		}
+	case viewPending:
+		for _, pending := range m.pending {
+			lines = append(lines, fmt.Sprintf("#%d  %-8s  %s  %s (%s)", pending.ID, pending.Reason, formatTime(pending.NotBefore), pending.Item.Title, pending.Item.Link))
+		}
+	case viewToots:
+		for _, toot := range m.toots {
+			lines = append(lines, fmt.Sprintf("%s  %s", formatTime(toot.Timestamp), toot.Link))
+		}
+	case viewErrors:
+		for _, entry := range m.errors {
+			lines = append(lines, fmt.Sprintf("%s  %s: %s", formatTime(entry.Timestamp), entry.Source, entry.Message))
+		}
+	}
+
+	if len(lines) == 0 {
+		b.WriteString("  (empty)\n")
+	}
+	for i, line := range lines {
+		if i == m.cursor {
+			b.WriteString("> ")
+		} else {
+			b.WriteString("  ")
+		}
+		b.WriteString(line + "\n")
+	}
+
+	b.WriteString("\n" + m.status + "\n")
+	b.WriteString("tab: switch view  j/k: move  a: approve  r: retry  f: forget  p: force poll  R: refresh  q: quit\n")
+	return b.String()
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
@@ -0,0 +1,100 @@
+package tui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spf13/viper"
+
+	"github.com/toozej/rss2mastodon/internal/db"
+	"github.com/toozej/rss2mastodon/internal/rss"
+)
+
+func key(s string) tea.KeyMsg {
+	switch s {
+	case "tab":
+		return tea.KeyMsg{Type: tea.KeyTab}
+	default:
+		return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+	}
+}
+
+// Test approving, retrying and forgetting posts via keybindings
+func TestModelActions(t *testing.T) {
+	db.InitDB()
+	defer db.CloseDB()
+	defer os.Remove("./tooted_posts.db")
+
+	draft := rss.RSSItem{Title: "Draft", Link: "https://example.com/draft"}
+	embargoed := rss.RSSItem{Title: "Embargoed", Link: "https://example.com/embargoed"}
+	if err := db.QueuePendingPost(draft, time.Now(), db.PendingApproval); err != nil {
+		t.Fatalf("Failed to queue post: %v", err)
+	}
+	if err := db.QueuePendingPost(embargoed, time.Now().Add(time.Hour), db.PendingEmbargo); err != nil {
+		t.Fatalf("Failed to queue post: %v", err)
+	}
+	if err := db.StoreTootedPost("https://example.com/tooted", "content"); err != nil {
+		t.Fatalf("Failed to store post: %v", err)
+	}
+
+	m := &model{}
+	m.load()
+
+	// switch to the pending view and approve the draft
+	m.Update(key("tab"))
+	if !strings.Contains(m.View(), "Draft") {
+		t.Errorf("Expected pending view to list the draft, got:\n%s", m.View())
+	}
+	m.Update(key("a"))
+	if pending, _ := db.GetPendingPost(draft.Link); pending == nil || pending.Reason != db.PendingApproved {
+		t.Errorf("Expected draft to be approved, got %v", pending)
+	}
+
+	// retry the embargoed post
+	m.Update(key("j"))
+	m.Update(key("r"))
+	if due, _ := db.DuePendingPosts(time.Now().Add(time.Second)); len(due) != 2 {
+		t.Errorf("Expected both posts to be due, got %v", due)
+	}
+
+	// forget the tooted post
+	m.Update(key("tab"))
+	m.Update(key("f"))
+	if exists, _, _ := db.HasPostChanged("https://example.com/tooted", "content"); exists {
+		t.Errorf("Expected tooted post to be forgotten")
+	}
+
+	if _, cmd := m.Update(key("q")); cmd == nil {
+		t.Errorf("Expected quit command")
+	}
+}
+
+// Test force polling via the admin listener
+func TestForcePoll(t *testing.T) {
+	var authorization string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+	}))
+	defer mockServer.Close()
+
+	viper.Reset()
+	defer viper.Reset()
+
+	if status := forcePoll(); !strings.Contains(status, "requires the admin listener") {
+		t.Errorf("Expected force poll to require the admin listener, got '%s'", status)
+	}
+
+	viper.Set("public_url", mockServer.URL)
+	viper.Set("admin_token", "secret")
+	if status := forcePoll(); status != "Poll triggered" {
+		t.Errorf("Expected 'Poll triggered', got '%s'", status)
+	}
+	if authorization != "Bearer secret" {
+		t.Errorf("Expected admin token to be sent, got '%s'", authorization)
+	}
+}